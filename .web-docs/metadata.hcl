@@ -13,6 +13,16 @@ integration {
     name = "Google Cloud Platform"
     slug = "googlecompute"
   }
+  component {
+    type = "builder"
+    name = "Google Cloud Platform Disk"
+    slug = "googlecompute-disk"
+  }
+  component {
+    type = "builder"
+    name = "Google Cloud Platform Instance Template"
+    slug = "googlecompute-instance-template"
+  }
   component {
     type = "post-processor"
     name = "Google Cloud Platform Image Import"
@@ -23,4 +33,94 @@ integration {
     name = "Google Cloud Platform Image Exporter"
     slug = "googlecompute-export"
   }
+  component {
+    type = "post-processor"
+    name = "Google Cloud Platform Instance Template"
+    slug = "googlecompute-instance-template"
+  }
+  component {
+    type = "post-processor"
+    name = "Google Cloud Platform Managed Instance Group Update"
+    slug = "googlecompute-mig-update"
+  }
+  component {
+    type = "post-processor"
+    name = "Google Cloud Platform Image Publication"
+    slug = "googlecompute-publish"
+  }
+  component {
+    type = "post-processor"
+    name = "Google Cloud Platform Image Catalog"
+    slug = "googlecompute-catalog"
+  }
+  component {
+    type = "post-processor"
+    name = "Google Cloud Platform Vulnerability Scan"
+    slug = "googlecompute-vulnerability-scan"
+  }
+  component {
+    type = "data-source"
+    name = "Google Cloud Platform Image"
+    slug = "image"
+  }
+  component {
+    type = "data-source"
+    name = "Google Cloud Platform Snapshot"
+    slug = "snapshot"
+  }
+  component {
+    type = "data-source"
+    name = "Google Cloud Platform Secret Manager"
+    slug = "secretsmanager"
+  }
+  component {
+    type = "data-source"
+    name = "Google Cloud Platform Parameter Manager"
+    slug = "parametermanager"
+  }
+  component {
+    type = "data-source"
+    name = "Google Cloud Platform Network"
+    slug = "network"
+  }
+  component {
+    type = "data-source"
+    name = "Google Cloud Platform Zones"
+    slug = "zones"
+  }
+  component {
+    type = "data-source"
+    name = "Google Cloud Platform Default Service Account"
+    slug = "default-service-account"
+  }
+  component {
+    type = "data-source"
+    name = "Google Cloud Platform KMS Key"
+    slug = "kms-key"
+  }
+  component {
+    type = "data-source"
+    name = "Google Cloud Platform Instance"
+    slug = "instance"
+  }
+  component {
+    type = "data-source"
+    name = "Google Cloud Platform Image Family"
+    slug = "image-family"
+  }
+  component {
+    type = "data-source"
+    name = "Google Cloud Platform GCS Object"
+    slug = "gcs-object"
+  }
+  component {
+    type = "data-source"
+    name = "Google Cloud Platform Project Metadata"
+    slug = "project-metadata"
+  }
+  component {
+    type = "data-source"
+    name = "Google Cloud Platform Regions"
+    slug = "regions"
+  }
 }
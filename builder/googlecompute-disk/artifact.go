@@ -0,0 +1,99 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecomputedisk
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// Artifact describes the disks built, and their snapshots.
+type Artifact struct {
+	driver common.ComputeDriver
+	config *Config
+	// disks are the kept disks, none with delete_disks.
+	disks []common.BlockDevice
+	// snapshots are the names of the snapshots of the disks.
+	snapshots []string
+	// StateData should store data such as GeneratedData
+	// to be shared with post-processors
+	StateData map[string]interface{}
+}
+
+var _ packersdk.Artifact = new(Artifact)
+
+// BuilderId returns the builder Id.
+func (*Artifact) BuilderId() string {
+	return BuilderId
+}
+
+func (a *Artifact) diskNames() []string {
+	names := make([]string, 0, len(a.disks))
+	for _, disk := range a.disks {
+		names = append(names, disk.DiskName)
+	}
+	return names
+}
+
+// Destroy deletes the snapshots and the disks.
+func (a *Artifact) Destroy() error {
+	var errs error
+	for _, snapshot := range a.snapshots {
+		log.Printf("Destroying snapshot: %s", snapshot)
+		if err := a.driver.DeleteSnapshot(snapshot); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, err)
+		}
+	}
+	for _, disk := range a.disks {
+		log.Printf("Destroying disk: %s", disk.DiskName)
+		if err := <-a.driver.DeleteDisk(diskLocation(disk), disk.DiskName); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, err)
+		}
+	}
+	return errs
+}
+
+// Files returns the files represented by the artifact.
+func (*Artifact) Files() []string {
+	return nil
+}
+
+// Id returns the comma-separated names of the disks, or of the snapshots
+// once the disks are deleted.
+func (a *Artifact) Id() string {
+	if len(a.disks) == 0 {
+		return strings.Join(a.snapshots, ",")
+	}
+	return strings.Join(a.diskNames(), ",")
+}
+
+// String returns the string representation of the artifact.
+func (a *Artifact) String() string {
+	var parts []string
+	if len(a.disks) > 0 {
+		parts = append(parts, fmt.Sprintf("disks in zone %s: %s", a.config.Zone, strings.Join(a.diskNames(), ", ")))
+	}
+	if len(a.snapshots) > 0 {
+		parts = append(parts, fmt.Sprintf("snapshots: %s", strings.Join(a.snapshots, ", ")))
+	}
+	return fmt.Sprintf("Disks built in the '%v' project, %s", a.config.ProjectId, strings.Join(parts, "; "))
+}
+
+func (a *Artifact) State(name string) interface{} {
+	switch name {
+	case "Disks":
+		return a.diskNames()
+	case "Snapshots":
+		return a.snapshots
+	case "ProjectId":
+		return a.config.ProjectId
+	case "BuildZone":
+		return a.config.Zone
+	}
+	return a.StateData[name]
+}
@@ -0,0 +1,129 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+// The googlecomputedisk package contains a packersdk.Builder implementation
+// that builds persistent disks, and their snapshots, for Google Compute
+// Engine.
+package googlecomputedisk
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-googlecompute/builder/googlecompute"
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"google.golang.org/api/compute/v1"
+)
+
+// The unique ID for this builder.
+const BuilderId = "packer.googlecompute-disk"
+
+// Builder builds persistent disks: it provisions the disks attached to a
+// googlecompute build instance, keeps them once the instance is deleted, and
+// optionally snapshots them.
+type Builder struct {
+	config Config
+}
+
+func (b *Builder) ConfigSpec() hcldec.ObjectSpec { return b.config.FlatMapstructure().HCL2Spec() }
+
+func (b *Builder) Prepare(raws ...interface{}) ([]string, []string, error) {
+	warnings, errs := b.config.Prepare(raws...)
+	if errs != nil {
+		return nil, warnings, errs
+	}
+	generatedDataKeys := []string{
+		"SourceImageName",
+		"SourceImageProjectId",
+		"SourceImageSelfLink",
+		"InstanceName",
+		"InstanceZone",
+		"InstanceInternalIP",
+		"InstanceExternalIP",
+		"StepTimings",
+	}
+	for _, forward := range b.config.IAPPortForwards {
+		generatedDataKeys = append(generatedDataKeys, forward.GeneratedDataKey())
+	}
+	return generatedDataKeys, warnings, nil
+}
+
+// Run builds the disks with the googlecompute builder, then snapshots them.
+func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook) (packersdk.Artifact, error) {
+	built, err := googlecompute.NewBuilder(b.config.Config).Run(ctx, ui, hook)
+	if err != nil || b.config.PreflightOnly {
+		return nil, err
+	}
+
+	cfg := &common.GCEDriverConfig{
+		Ui:              ui,
+		ProjectId:       b.config.ProjectId,
+		PollMinInterval: b.config.OperationPollMinInterval,
+		PollMaxInterval: b.config.OperationPollMaxInterval,
+	}
+	b.config.Authentication.ApplyDriverConfig(cfg)
+	driver, err := common.NewDriverGCE(*cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	artifact := &Artifact{
+		driver:    driver,
+		config:    &b.config,
+		disks:     b.config.disks(),
+		StateData: map[string]interface{}{},
+	}
+	if instance, ok := built.(*googlecompute.InstanceArtifact); ok {
+		artifact.StateData = instance.StateData
+	}
+	if b.config.CreateSnapshot {
+		if err := createSnapshots(driver, ui, &b.config, artifact); err != nil {
+			return nil, err
+		}
+	}
+	return artifact, nil
+}
+
+// createSnapshots snapshots the disks of the artifact, and deletes them with
+// delete_disks. The snapshots and disks left behind by a failure are
+// reported.
+func createSnapshots(driver common.ComputeDriver, ui packersdk.Ui, c *Config, artifact *Artifact) error {
+	labels := map[string]string{}
+	for k, v := range c.Labels {
+		labels[k] = v
+	}
+	for k, v := range c.SnapshotLabels {
+		labels[k] = v
+	}
+
+	for _, disk := range artifact.disks {
+		ui.Say(fmt.Sprintf("Creating snapshot of disk %s...", disk.DiskName))
+		err := driver.CreateSnapshot(diskLocation(disk), disk.DiskName, &compute.Snapshot{
+			Name:        disk.DiskName,
+			Description: fmt.Sprintf("Snapshot of disk %s created by Packer", disk.DiskName),
+			Labels:      labels,
+		})
+		if err != nil {
+			return common.EnrichError(fmt.Errorf("Error creating snapshot of disk %s, keeping %s: %w",
+				disk.DiskName, artifact.Id(), err))
+		}
+		artifact.snapshots = append(artifact.snapshots, disk.DiskName)
+	}
+
+	if !c.DeleteDisks {
+		return nil
+	}
+	disks := artifact.disks
+	artifact.disks = nil
+	for i, disk := range disks {
+		ui.Say(fmt.Sprintf("Deleting disk %s...", disk.DiskName))
+		if err := <-driver.DeleteDisk(diskLocation(disk), disk.DiskName); err != nil {
+			artifact.disks = disks[i:]
+			return common.EnrichError(fmt.Errorf("Error deleting disk %s, keeping %s: %w",
+				disk.DiskName, artifact.Id(), err))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecomputedisk
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuilder_ImplementsBuilder(t *testing.T) {
+	var _ packersdk.Builder = new(Builder)
+}
+
+func testArtifact(t *testing.T, raw map[string]interface{}) (*Artifact, *common.ComputeDriverMock) {
+	var c Config
+	if _, err := c.Prepare(raw); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	driver := new(common.ComputeDriverMock)
+	return &Artifact{driver: driver, config: &c, disks: c.disks()}, driver
+}
+
+func TestCreateSnapshots(t *testing.T) {
+	raw := testConfig()
+	raw["create_snapshot"] = true
+	raw["labels"] = map[string]string{"team": "data"}
+	raw["snapshot_labels"] = map[string]string{"kind": "dataset"}
+	artifact, driver := testArtifact(t, raw)
+
+	err := createSnapshots(driver, packersdk.TestUi(t), artifact.config, artifact)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"data"}, driver.CreateSnapshotNames)
+	assert.Equal(t, "us-east1-a", driver.CreateSnapshotZone)
+	assert.Equal(t, map[string]string{"team": "data", "kind": "dataset"}, driver.CreateSnapshotSnapshot.Labels)
+	assert.Empty(t, driver.DeleteDiskName, "the disks should be kept")
+	assert.Equal(t, "data", artifact.Id())
+	assert.Equal(t, []string{"data"}, artifact.State("Snapshots"))
+}
+
+func TestCreateSnapshots_deleteDisks(t *testing.T) {
+	raw := testConfig()
+	raw["create_snapshot"] = true
+	raw["delete_disks"] = true
+	raw["disk_attachment"] = []map[string]interface{}{
+		{"volume_type": "pd-ssd", "volume_size": 100, "disk_name": "data",
+			"replica_zones": []string{"us-east1-b"}},
+	}
+	artifact, driver := testArtifact(t, raw)
+
+	err := createSnapshots(driver, packersdk.TestUi(t), artifact.config, artifact)
+	assert.NoError(t, err)
+	assert.Equal(t, "us-east1", driver.CreateSnapshotZone, "regional disks should be snapshotted in their region")
+	assert.Equal(t, "data", driver.DeleteDiskName)
+	assert.Equal(t, "us-east1", driver.DeleteDiskZone)
+	assert.Empty(t, artifact.State("Disks"))
+	assert.Equal(t, "data", artifact.Id(), "the snapshots should identify the artifact")
+}
+
+func TestCreateSnapshots_error(t *testing.T) {
+	raw := testConfig()
+	raw["create_snapshot"] = true
+	raw["delete_disks"] = true
+	artifact, driver := testArtifact(t, raw)
+	driver.CreateSnapshotErr = errors.New("quota exceeded")
+
+	err := createSnapshots(driver, packersdk.TestUi(t), artifact.config, artifact)
+	assert.Error(t, err)
+	assert.Empty(t, driver.DeleteDiskName, "the disks should be kept when they are not snapshotted")
+	assert.Equal(t, []string{"data"}, artifact.State("Disks"))
+}
+
+func TestArtifactDestroy(t *testing.T) {
+	artifact, driver := testArtifact(t, testConfig())
+	artifact.snapshots = []string{"data"}
+
+	assert.NoError(t, artifact.Destroy())
+	assert.Equal(t, []string{"data"}, driver.DeleteSnapshotNames)
+	assert.Equal(t, "data", driver.DeleteDiskName)
+}
@@ -0,0 +1,76 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+package googlecomputedisk
+
+import (
+	"errors"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/builder/googlecompute"
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// Config is the configuration of the googlecompute-disk builder: the one of
+// the googlecompute builder, whose persistent `disk_attachment`s are kept
+// instead of creating an image.
+type Config struct {
+	googlecompute.Config `mapstructure:",squash"`
+
+	// Snapshot the disks once the instance is deleted. The snapshots are
+	// named after their disk.
+	CreateSnapshot bool `mapstructure:"create_snapshot" required:"false"`
+	// Key/value pair labels to apply to the snapshots, in addition to
+	// `labels`.
+	SnapshotLabels map[string]string `mapstructure:"snapshot_labels" required:"false"`
+	// Delete the disks once they are snapshotted, keeping only the
+	// snapshots. Requires `create_snapshot`.
+	DeleteDisks bool `mapstructure:"delete_disks" required:"false"`
+}
+
+func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
+	if err := c.Config.Decode(c, raws...); err != nil {
+		return nil, err
+	}
+
+	// The disks are the artifact, no image is created.
+	c.SkipCreateImage = true
+	for i, disk := range c.ExtraBlockDevices {
+		if disk.VolumeType != common.LocalScratch && disk.SourceVolume == "" {
+			c.ExtraBlockDevices[i].KeepDevice = true
+		}
+	}
+
+	warnings, errs := c.Config.Validate()
+	if len(c.disks()) == 0 {
+		errs = packersdk.MultiErrorAppend(errs,
+			errors.New("at least one persistent disk_attachment without source_volume is required"))
+	}
+	if c.DeleteDisks && !c.CreateSnapshot {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("delete_disks requires create_snapshot"))
+	}
+	return warnings, errs
+}
+
+// disks returns the disks created for the build, which are its artifact.
+func (c *Config) disks() []common.BlockDevice {
+	var disks []common.BlockDevice
+	for _, disk := range c.ExtraBlockDevices {
+		if disk.VolumeType != common.LocalScratch && disk.SourceVolume == "" {
+			disks = append(disks, disk)
+		}
+	}
+	return disks
+}
+
+// diskLocation returns the zone of disk, or its region if it is replicated.
+func diskLocation(disk common.BlockDevice) string {
+	if len(disk.ReplicaZones) != 0 {
+		region, _ := common.GetRegionFromZone(disk.Zone)
+		return region
+	}
+	return disk.Zone
+}
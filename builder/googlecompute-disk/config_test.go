@@ -0,0 +1,64 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecomputedisk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"project_id":   "hashicorp",
+		"source_image": "foo",
+		"ssh_username": "root",
+		"zone":         "us-east1-a",
+		"disk_attachment": []map[string]interface{}{
+			{"volume_type": "pd-ssd", "volume_size": 100, "disk_name": "data"},
+			{"volume_type": "scratch", "volume_size": 375},
+		},
+	}
+}
+
+func TestConfigPrepare(t *testing.T) {
+	var c Config
+	_, err := c.Prepare(testConfig())
+	assert.NoError(t, err)
+
+	assert.True(t, c.SkipCreateImage, "no image should be created")
+	assert.True(t, c.ExtraBlockDevices[0].KeepDevice, "the persistent disk should be kept")
+	assert.False(t, c.ExtraBlockDevices[1].KeepDevice, "the scratch disk cannot be kept")
+	if assert.Len(t, c.disks(), 1) {
+		assert.Equal(t, "data", c.disks()[0].DiskName)
+		assert.Equal(t, "us-east1-a", c.disks()[0].Zone)
+	}
+}
+
+func TestConfigPrepare_errors(t *testing.T) {
+	cases := map[string]func(raw map[string]interface{}){
+		"no persistent disk": func(raw map[string]interface{}) {
+			raw["disk_attachment"] = []map[string]interface{}{
+				{"volume_type": "scratch", "volume_size": 375},
+			}
+		},
+		"source volume only": func(raw map[string]interface{}) {
+			raw["disk_attachment"] = []map[string]interface{}{
+				{"source_volume": "projects/hashicorp/zones/us-east1-a/disks/existing"},
+			}
+		},
+		"delete_disks without create_snapshot": func(raw map[string]interface{}) {
+			raw["delete_disks"] = true
+		},
+	}
+	for name, setup := range cases {
+		t.Run(name, func(t *testing.T) {
+			raw := testConfig()
+			setup(raw)
+			var c Config
+			_, err := c.Prepare(raw)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecomputeinstancetemplate
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// Artifact represents the instance template of the image built, which is
+// kept with it.
+type Artifact struct {
+	template  string
+	selfLink  string
+	projectId string
+	// image is the artifact of the image.
+	image  packersdk.Artifact
+	driver common.InstanceTemplateDriver
+}
+
+var _ packersdk.Artifact = new(Artifact)
+
+// BuilderId returns the builder Id.
+func (*Artifact) BuilderId() string {
+	return BuilderId
+}
+
+// Id returns the name of the instance template.
+func (a *Artifact) Id() string {
+	return a.template
+}
+
+// Files returns the files represented by the artifact.
+func (*Artifact) Files() []string {
+	return nil
+}
+
+// String returns the string representation of the artifact.
+func (a *Artifact) String() string {
+	return fmt.Sprintf("An instance template was created in the '%v' project: %v, from image %v",
+		a.projectId, a.template, a.image.Id())
+}
+
+// State returns the state of the instance template, or else the one of the
+// image, e.g. its "generated_data".
+func (a *Artifact) State(name string) interface{} {
+	switch name {
+	case "TemplateName":
+		return a.template
+	case "TemplateSelfLink":
+		return a.selfLink
+	case "ProjectId":
+		return a.projectId
+	}
+	return a.image.State(name)
+}
+
+// Destroy deletes the instance template, then the image.
+func (a *Artifact) Destroy() error {
+	log.Printf("Destroying instance template: %s", a.template)
+	if err := <-a.driver.DeleteInstanceTemplate(a.projectId, a.template); err != nil {
+		return err
+	}
+	return a.image.Destroy()
+}
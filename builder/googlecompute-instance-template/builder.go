@@ -0,0 +1,186 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+// The googlecomputeinstancetemplate package contains a packersdk.Builder
+// implementation that builds instance templates of new images for Google
+// Compute Engine.
+package googlecomputeinstancetemplate
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-googlecompute/builder/googlecompute"
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"google.golang.org/api/compute/v1"
+)
+
+// The unique ID for this builder.
+const BuilderId = "packer.googlecompute-instance-template"
+
+// Builder builds an image with the googlecompute builder, and an instance
+// template creating instances from it.
+type Builder struct {
+	config Config
+	// builder is the googlecompute builder of the image.
+	builder *googlecompute.Builder
+}
+
+func (b *Builder) ConfigSpec() hcldec.ObjectSpec { return b.config.FlatMapstructure().HCL2Spec() }
+
+func (b *Builder) Prepare(raws ...interface{}) ([]string, []string, error) {
+	warnings, errs := b.config.Prepare(raws...)
+	if errs != nil {
+		return nil, warnings, errs
+	}
+	b.builder = googlecompute.NewBuilder(b.config.Config)
+	generatedDataKeys := append([]string{
+		"SourceImageName",
+		"SourceImageProjectId",
+		"SourceImageSelfLink",
+		"InstanceName",
+		"InstanceZone",
+		"InstanceInternalIP",
+		"InstanceExternalIP",
+		"StepTimings",
+		"ImageChecksum",
+	}, googlecompute.ImageGeneratedDataKeys...)
+	for _, forward := range b.config.IAPPortForwards {
+		generatedDataKeys = append(generatedDataKeys, forward.GeneratedDataKey())
+	}
+	return generatedDataKeys, warnings, nil
+}
+
+// Run builds the image with the googlecompute builder, then creates the
+// instance template. The image is deleted if the template cannot be created.
+func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook) (packersdk.Artifact, error) {
+	image, err := b.builder.Run(ctx, ui, hook)
+	if err != nil || image == nil {
+		return image, err
+	}
+
+	cfg := &common.GCEDriverConfig{
+		Ui:              ui,
+		ProjectId:       b.config.ProjectId,
+		PollMinInterval: b.config.OperationPollMinInterval,
+		PollMaxInterval: b.config.OperationPollMaxInterval,
+	}
+	b.config.Authentication.ApplyDriverConfig(cfg)
+	driver, err := common.NewDriverGCE(*cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	spec, err := templateSpec(&b.config, image)
+	if err == nil {
+		ui.Say(fmt.Sprintf("Creating instance template %s...", spec.Name))
+		templateCh, errCh := driver.CreateInstanceTemplate(b.config.ProjectId, spec)
+		// templateCh is closed once the error, if any, has been sent.
+		template, ok := <-templateCh
+		if !ok {
+			err = <-errCh
+		}
+		if err == nil {
+			return &Artifact{
+				template:  template.Name,
+				selfLink:  template.SelfLink,
+				projectId: b.config.ProjectId,
+				image:     image,
+				driver:    driver,
+			}, nil
+		}
+	}
+
+	err = common.EnrichError(fmt.Errorf("Error creating instance template: %w", err))
+	ui.Error(err.Error())
+	ui.Say(fmt.Sprintf("Deleting image %s...", image.Id()))
+	if destroyErr := image.Destroy(); destroyErr != nil {
+		ui.Error(fmt.Sprintf("Error deleting image %s: %s", image.Id(), destroyErr))
+	}
+	return nil, err
+}
+
+// templateSpec returns the instance template of the image of the build,
+// with its shape and network.
+func templateSpec(c *Config, image packersdk.Artifact) (*compute.InstanceTemplate, error) {
+	imageName, _ := image.State("ImageName").(string)
+	imageSelfLink, _ := image.State("ImageSelfLink").(string)
+
+	network, subnetwork, err := common.GetNetworking(&common.InstanceConfig{
+		Network:          c.Network,
+		NetworkProjectId: c.NetworkProjectId,
+		Subnetwork:       c.Subnetwork,
+		Region:           c.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+	networkInterface := &compute.NetworkInterface{
+		Network:    network,
+		Subnetwork: subnetwork,
+	}
+	if !c.OmitExternalIP {
+		networkInterface.AccessConfigs = []*compute.AccessConfig{{
+			Name: "External NAT",
+			Type: "ONE_TO_ONE_NAT",
+		}}
+	}
+
+	keys := make([]string, 0, len(c.TemplateMetadata))
+	for k := range c.TemplateMetadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	metadata := make([]*compute.MetadataItems, 0, len(keys))
+	for _, k := range keys {
+		v := c.TemplateMetadata[k]
+		metadata = append(metadata, &compute.MetadataItems{Key: k, Value: &v})
+	}
+
+	var serviceAccounts []*compute.ServiceAccount
+	if c.ServiceAccountEmail != "" || !c.DisableDefaultServiceAccount {
+		email := c.ServiceAccountEmail
+		if email == "" {
+			email = "default"
+		}
+		serviceAccounts = []*compute.ServiceAccount{{Email: email, Scopes: c.Scopes}}
+	}
+
+	description := c.TemplateDescription
+	if description == "" {
+		description = fmt.Sprintf("Created by Packer from image %s", imageName)
+	}
+
+	properties := &compute.InstanceProperties{
+		MachineType: c.TemplateMachineType,
+		Disks: []*compute.AttachedDisk{{
+			AutoDelete: true,
+			Boot:       true,
+			InitializeParams: &compute.AttachedDiskInitializeParams{
+				DiskType:    c.TemplateDiskType,
+				SourceImage: imageSelfLink,
+			},
+		}},
+		Labels:            c.TemplateLabels,
+		Metadata:          &compute.Metadata{Items: metadata},
+		NetworkInterfaces: []*compute.NetworkInterface{networkInterface},
+		ServiceAccounts:   serviceAccounts,
+		Tags:              &compute.Tags{Items: c.Tags},
+	}
+	if c.EnableSecureBoot || c.EnableVtpm || c.EnableIntegrityMonitoring {
+		properties.ShieldedInstanceConfig = &compute.ShieldedInstanceConfig{
+			EnableSecureBoot:          c.EnableSecureBoot,
+			EnableVtpm:                c.EnableVtpm,
+			EnableIntegrityMonitoring: c.EnableIntegrityMonitoring,
+		}
+	}
+
+	return &compute.InstanceTemplate{
+		Name:        c.TemplateName,
+		Description: description,
+		Properties:  properties,
+	}, nil
+}
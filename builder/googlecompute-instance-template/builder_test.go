@@ -0,0 +1,98 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecomputeinstancetemplate
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuilder_ImplementsBuilder(t *testing.T) {
+	var _ packersdk.Builder = new(Builder)
+}
+
+func testImageArtifact() *packersdk.MockArtifact {
+	return &packersdk.MockArtifact{
+		IdValue: "app-v1",
+		StateValues: map[string]interface{}{
+			"ImageName":     "app-v1",
+			"ImageSelfLink": "https://www.googleapis.com/compute/v1/projects/hashicorp/global/images/app-v1",
+		},
+	}
+}
+
+func TestTemplateSpec(t *testing.T) {
+	raw := testConfig()
+	raw["tags"] = []string{"web"}
+	raw["omit_external_ip"] = true
+	raw["use_internal_ip"] = true
+	raw["enable_secure_boot"] = true
+	raw["metadata"] = map[string]string{"startup-script": "build"}
+	raw["template_metadata"] = map[string]string{"role": "web", "env": "prod"}
+	var c Config
+	if _, err := c.Prepare(raw); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	spec, err := templateSpec(&c, testImageArtifact())
+	assert.NoError(t, err)
+	assert.Equal(t, "app-v1", spec.Name)
+	assert.Equal(t, "Created by Packer from image app-v1", spec.Description)
+
+	p := spec.Properties
+	assert.Equal(t, "e2-standard-2", p.MachineType)
+	assert.Equal(t, "https://www.googleapis.com/compute/v1/projects/hashicorp/global/images/app-v1",
+		p.Disks[0].InitializeParams.SourceImage)
+	assert.Equal(t, "pd-balanced", p.Disks[0].InitializeParams.DiskType)
+	assert.Equal(t, []string{"web"}, p.Tags.Items)
+	assert.Equal(t, map[string]string{"app": "web"}, p.Labels)
+	assert.Equal(t, "global/networks/default", p.NetworkInterfaces[0].Network)
+	assert.Empty(t, p.NetworkInterfaces[0].AccessConfigs, "no external IP should be configured")
+	assert.True(t, p.ShieldedInstanceConfig.EnableSecureBoot)
+	if assert.Len(t, p.Metadata.Items, 2, "only the template metadata should be set") {
+		assert.Equal(t, "env", p.Metadata.Items[0].Key)
+		assert.Equal(t, "role", p.Metadata.Items[1].Key)
+	}
+	if assert.Len(t, p.ServiceAccounts, 1) {
+		assert.Equal(t, "default", p.ServiceAccounts[0].Email)
+		assert.Equal(t, c.Scopes, p.ServiceAccounts[0].Scopes)
+	}
+}
+
+func TestTemplateSpec_noServiceAccount(t *testing.T) {
+	raw := testConfig()
+	raw["disable_default_service_account"] = true
+	var c Config
+	if _, err := c.Prepare(raw); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	spec, err := templateSpec(&c, testImageArtifact())
+	assert.NoError(t, err)
+	assert.Empty(t, spec.Properties.ServiceAccounts)
+}
+
+func TestArtifact(t *testing.T) {
+	image := testImageArtifact()
+	driver := new(common.InstanceTemplateDriverMock)
+	artifact := &Artifact{
+		template:  "app-v1",
+		selfLink:  "https://www.googleapis.com/compute/v1/projects/hashicorp/global/instanceTemplates/app-v1",
+		projectId: "hashicorp",
+		image:     image,
+		driver:    driver,
+	}
+
+	assert.Equal(t, "app-v1", artifact.Id())
+	assert.Equal(t, artifact.selfLink, artifact.State("TemplateSelfLink"))
+	assert.Equal(t, image.StateValues["ImageSelfLink"], artifact.State("ImageSelfLink"),
+		"the state of the image should be available")
+
+	assert.NoError(t, artifact.Destroy())
+	assert.Equal(t, "app-v1", driver.DeleteInstanceTemplateName)
+	assert.True(t, image.DestroyCalled, "the image should be deleted with the template")
+}
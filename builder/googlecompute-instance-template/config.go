@@ -0,0 +1,66 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+package googlecomputeinstancetemplate
+
+import (
+	"errors"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/builder/googlecompute"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// Config is the configuration of the googlecompute-instance-template
+// builder: the one of the googlecompute builder, whose shape and network
+// are the defaults of the instance template.
+type Config struct {
+	googlecompute.Config `mapstructure:",squash"`
+
+	// The name of the instance template. Instance templates cannot be
+	// updated, so a new one is created for every image: defaults to
+	// `image_name`, which is unique.
+	TemplateName string `mapstructure:"template_name" required:"false"`
+	// The description of the instance template. Defaults to
+	// `Created by Packer from image ((image_name))`.
+	TemplateDescription string `mapstructure:"template_description" required:"false"`
+	// The machine type of the instances. Defaults to `machine_type`.
+	TemplateMachineType string `mapstructure:"template_machine_type" required:"false"`
+	// The type of the boot disk of the instances, like `pd-ssd` or
+	// `pd-balanced`. Defaults to `pd-balanced`. The boot disk has the size
+	// of the image.
+	TemplateDiskType string `mapstructure:"template_disk_type" required:"false"`
+	// Key/value pair labels applied to the instances. Defaults to
+	// `image_labels`.
+	TemplateLabels map[string]string `mapstructure:"template_labels" required:"false"`
+	// Metadata applied to the instances. The `metadata` of the build
+	// instance, e.g. its startup script, is not used by the template.
+	TemplateMetadata map[string]string `mapstructure:"template_metadata" required:"false"`
+}
+
+func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
+	if err := c.Config.Decode(c, raws...); err != nil {
+		return nil, err
+	}
+
+	warnings, errs := c.Config.Validate()
+	if c.SkipCreateImage {
+		errs = packersdk.MultiErrorAppend(errs,
+			errors.New("skip_create_image cannot be used, the instance template needs the image"))
+	}
+	if c.TemplateName == "" {
+		c.TemplateName = c.ImageName
+	}
+	if c.TemplateMachineType == "" {
+		c.TemplateMachineType = c.MachineType
+	}
+	if c.TemplateDiskType == "" {
+		c.TemplateDiskType = "pd-balanced"
+	}
+	if c.TemplateLabels == nil {
+		c.TemplateLabels = c.ImageLabels
+	}
+	return warnings, errs
+}
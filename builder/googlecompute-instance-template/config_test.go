@@ -0,0 +1,42 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecomputeinstancetemplate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"project_id":   "hashicorp",
+		"source_image": "foo",
+		"ssh_username": "root",
+		"zone":         "us-east1-a",
+		"image_name":   "app-v1",
+		"machine_type": "e2-standard-2",
+		"image_labels": map[string]string{"app": "web"},
+	}
+}
+
+func TestConfigPrepare(t *testing.T) {
+	var c Config
+	_, err := c.Prepare(testConfig())
+	assert.NoError(t, err)
+
+	assert.Equal(t, "app-v1", c.TemplateName, "the template should be named after the image")
+	assert.Equal(t, "e2-standard-2", c.TemplateMachineType)
+	assert.Equal(t, "pd-balanced", c.TemplateDiskType)
+	assert.Equal(t, map[string]string{"app": "web"}, c.TemplateLabels)
+}
+
+func TestConfigPrepare_skipCreateImage(t *testing.T) {
+	raw := testConfig()
+	raw["skip_create_image"] = true
+
+	var c Config
+	_, err := c.Prepare(raw)
+	assert.Error(t, err)
+}
@@ -6,13 +6,78 @@ package googlecompute
 import (
 	"fmt"
 	"log"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
 	registryimage "github.com/hashicorp/packer-plugin-sdk/packer/registry/image"
+	compute "google.golang.org/api/compute/v1"
 )
 
+// ImageGeneratedDataKeys are the generated data describing the image, set
+// once it is created, for the post-processors.
+var ImageGeneratedDataKeys = []string{
+	"ImageName",
+	"ImageId",
+	"ImageProjectId",
+	"ImageSelfLink",
+	"ImageFamily",
+	"ImageSizeGb",
+	"ImageLabels",
+	"ImageLicenses",
+	"ImageStorageLocations",
+	"ImageDeprecationState",
+	"ImageDeprecated",
+	"ImageObsolete",
+	"ImageDeleted",
+	"ImageReplacement",
+}
+
+// imageGeneratedData returns the generated data describing the image. Lists
+// are comma-separated, and labels are sorted key=value pairs.
+func imageGeneratedData(image *common.Image, config *Config) map[string]string {
+	family := image.Family
+	if family == "" {
+		family = config.ImageFamily
+	}
+	labels := make([]string, 0, len(image.Labels))
+	for k, v := range image.Labels {
+		labels = append(labels, k+"="+v)
+	}
+	sort.Strings(labels)
+	deprecation := image.Deprecation
+	if deprecation == nil {
+		deprecation = new(compute.DeprecationStatus)
+	}
+
+	return map[string]string{
+		"ImageName":             image.Name,
+		"ImageId":               strconv.FormatUint(image.Id, 10),
+		"ImageProjectId":        imageProjectId(image, config),
+		"ImageSelfLink":         image.SelfLink,
+		"ImageFamily":           family,
+		"ImageSizeGb":           strconv.FormatInt(image.SizeGb, 10),
+		"ImageLabels":           strings.Join(labels, ","),
+		"ImageLicenses":         strings.Join(image.Licenses, ","),
+		"ImageStorageLocations": strings.Join(image.StorageLocations, ","),
+		"ImageDeprecationState": image.DeprecationState(),
+		"ImageDeprecated":       deprecation.Deprecated,
+		"ImageObsolete":         deprecation.Obsolete,
+		"ImageDeleted":          deprecation.Deleted,
+		"ImageReplacement":      deprecation.Replacement,
+	}
+}
+
+// imageProjectId returns the project of image, which may be a copy outside
+// of image_project_id.
+func imageProjectId(image *common.Image, config *Config) string {
+	if image.ProjectId != "" {
+		return image.ProjectId
+	}
+	return config.ImageProjectId
+}
+
 // Artifact represents a GCE image as the result of a Packer build.
 type Artifact struct {
 	image  *common.Image
@@ -31,10 +96,14 @@ func (*Artifact) BuilderId() string {
 // Destroy destroys the GCE image represented by the artifact.
 func (a *Artifact) Destroy() error {
 	log.Printf("Destroying image: %s", a.image.Name)
-	errCh := a.driver.DeleteImage(a.config.ImageProjectId, a.image.Name)
+	errCh := a.driver.DeleteImage(a.projectId(), a.image.Name)
 	return <-errCh
 }
 
+func (a *Artifact) projectId() string {
+	return imageProjectId(a.image, a.config)
+}
+
 // Files returns the files represented by the artifact.
 func (*Artifact) Files() []string {
 	return nil
@@ -48,99 +117,141 @@ func (a *Artifact) Id() string {
 // String returns the string representation of the artifact.
 func (a *Artifact) String() string {
 	return fmt.Sprintf("A disk image was created in the '%v' project: %v",
-		a.config.ImageProjectId, a.image.Name)
+		a.projectId(), a.image.Name)
 }
 
-func (a *Artifact) State(name string) interface{} {
-	if name == registryimage.ArtifactStateURI {
-		img, _ := registryimage.FromArtifact(a,
-			registryimage.WithID(a.Id()),
-			registryimage.WithProvider("gce"),
-			registryimage.WithRegion(a.config.Zone),
-		)
-
-		labels := map[string]string{
-			"self_link":    a.image.SelfLink,
-			"project_id":   a.image.ProjectId,
-			"disk_size_gb": strconv.FormatInt(a.image.SizeGb, 10),
-			"machine_type": a.config.MachineType,
-			"licenses":     strings.Join(a.image.Licenses, ","),
-		}
-
-		// Set source image and/or family as labels
-		if a.config.SourceImage != "" {
-			labels["source_image"] = a.config.SourceImage
-		}
-		if a.config.SourceImageFamily != "" {
-			labels["source_image_family"] = a.config.SourceImageFamily
-		}
-
-		// Set PARtifact's source image name from state; this is set regardless
-		// of whether image or image family were used:
-		data, ok := a.StateData["generated_data"].(map[string]interface{})
-		if ok {
-			img.SourceImageID = data["SourceImageName"].(string)
-		}
+// encryptionKeyDescription describes the key encrypting a resource, without
+// revealing a customer-supplied one.
+func encryptionKeyDescription(key *common.CustomerEncryptionKey) string {
+	switch {
+	case key == nil:
+		return "google-managed"
+	case key.KmsKeyName != "":
+		return key.KmsKeyName
+	case key.CustomerSupplied():
+		return "customer-supplied"
+	}
+	return "google-managed"
+}
 
-		if len(a.config.SourceImageProjectId) > 0 {
-			labels["source_image_project_ids"] = strings.Join(a.config.SourceImageProjectId, ",")
-		}
+// registryImage returns the HCP Packer registry metadata of the image,
+// recording its provenance: the source image, the shape of the build
+// instance and the encryption keys.
+func (a *Artifact) registryImage() *registryimage.Image {
+	img, _ := registryimage.FromArtifact(a,
+		registryimage.WithID(a.Id()),
+		registryimage.WithProvider("gce"),
+		registryimage.WithRegion(a.config.Zone),
+	)
 
-		for k, v := range a.image.Labels {
-			labels["tags"] = labels["tags"] + fmt.Sprintf("%s:%s", k, v)
+	labels := map[string]string{
+		"self_link":                 a.image.SelfLink,
+		"project_id":                a.image.ProjectId,
+		"disk_size_gb":              strconv.FormatInt(a.image.SizeGb, 10),
+		"machine_type":              a.config.MachineType,
+		"licenses":                  strings.Join(a.image.Licenses, ","),
+		"build_zone":                a.config.Zone,
+		"build_disk_type":           a.config.DiskType,
+		"build_disk_size_gb":        strconv.FormatInt(a.config.DiskSizeGb, 10),
+		"image_encryption_key":      encryptionKeyDescription(a.config.ImageEncryptionKey),
+		"build_disk_encryption_key": encryptionKeyDescription(a.config.DiskEncryptionKey),
+		"storage_locations":         strings.Join(a.image.StorageLocations, ","),
+		"deprecation_state":         a.image.DeprecationState(),
+	}
+	if a.config.AcceleratorType != "" {
+		labels["accelerator_type"] = a.config.AcceleratorType
+		labels["accelerator_count"] = strconv.FormatInt(a.config.AcceleratorCount, 10)
+	}
+	if a.config.MinCpuPlatform != "" {
+		labels["min_cpu_platform"] = a.config.MinCpuPlatform
+	}
+	if d := a.image.Deprecation; d != nil {
+		for label, value := range map[string]string{
+			"deprecated":  d.Deprecated,
+			"obsolete":    d.Obsolete,
+			"deleted":     d.Deleted,
+			"replacement": d.Replacement,
+		} {
+			if value != "" {
+				labels[label] = value
+			}
 		}
-
-		img.Labels = labels
-		return img
 	}
 
-	if name == registryimage.ArtifactStateURI {
-		img, _ := registryimage.FromArtifact(a,
-			registryimage.WithID(a.Id()),
-			registryimage.WithProvider("gce"),
-			registryimage.WithRegion(a.config.Zone),
-		)
-
-		labels := map[string]string{
-			"self_link":    a.image.SelfLink,
-			"project_id":   a.image.ProjectId,
-			"disk_size_gb": strconv.FormatInt(a.image.SizeGb, 10),
-			"machine_type": a.config.MachineType,
-			"licenses":     strings.Join(a.image.Licenses, ","),
-		}
+	// Set source image and/or family as labels
+	if a.config.SourceImage != "" {
+		labels["source_image"] = a.config.SourceImage
+	}
+	if a.config.SourceImageFamily != "" {
+		labels["source_image_family"] = a.config.SourceImageFamily
+	}
 
-		// Set source image and/or family as labels
-		if a.config.SourceImage != "" {
-			labels["source_image"] = a.config.SourceImage
+	// Set PARtifact's source image name from state; this is set regardless
+	// of whether image or image family were used:
+	data, ok := a.StateData["generated_data"].(map[string]interface{})
+	if ok {
+		img.SourceImageID, _ = data["SourceImageName"].(string)
+		// The image the family resolved to, and where it comes from.
+		if selfLink, _ := data["SourceImageSelfLink"].(string); selfLink != "" {
+			labels["source_image_self_link"] = selfLink
 		}
-		if a.config.SourceImageFamily != "" {
-			labels["source_image_family"] = a.config.SourceImageFamily
+		if project, _ := data["SourceImageProjectId"].(string); project != "" {
+			labels["source_image_project_id"] = project
 		}
+	}
 
-		// Set PARtifact's source image name from state; this is set regardless
-		// of whether image or image family were used:
-		data, ok := a.StateData["generated_data"].(map[string]interface{})
-		if ok {
-			img.SourceImageID = data["SourceImageName"].(string)
-		}
+	if len(a.config.SourceImageProjectId) > 0 {
+		labels["source_image_project_ids"] = strings.Join(a.config.SourceImageProjectId, ",")
+	}
 
-		if len(a.config.SourceImageProjectId) > 0 {
-			labels["source_image_project_ids"] = strings.Join(a.config.SourceImageProjectId, ",")
-		}
+	for k, v := range a.image.Labels {
+		labels["tags"] = labels["tags"] + fmt.Sprintf("%s:%s", k, v)
+	}
 
-		for k, v := range a.image.Labels {
-			labels["tags"] = labels["tags"] + fmt.Sprintf("%s:%s", k, v)
-		}
+	img.Labels = labels
+	return img
+}
 
-		img.Labels = labels
-		return img
+func (a *Artifact) State(name string) interface{} {
+	if name == registryimage.ArtifactStateURI {
+		return a.registryImage()
 	}
 
 	switch name {
 	case "ImageName":
 		return a.image.Name
+	case "ImageId":
+		return a.image.Id
+	case "ImageProjectId":
+		return a.projectId()
+	case "ImageFamily":
+		if a.image.Family != "" {
+			return a.image.Family
+		}
+		return a.config.ImageFamily
+	case "ImageLicenses":
+		return a.image.Licenses
+	case "ImageStorageLocations":
+		return a.image.StorageLocations
+	case "ImageDeprecationState":
+		return a.image.DeprecationState()
+	case "ImageDeprecation":
+		// The schedule of the deprecation, nil when not deprecated.
+		return a.image.Deprecation
+	case "ImageSelfLink":
+		return a.image.SelfLink
+	case "ImageLabels":
+		return a.image.Labels
 	case "ImageSizeGb":
 		return a.image.SizeGb
+	case "MachineType":
+		return a.config.MachineType
+	case "Network":
+		return a.config.Network
+	case "NetworkProjectId":
+		return a.config.NetworkProjectId
+	case "Subnetwork":
+		return a.config.Subnetwork
 	case "ProjectId":
 		return a.config.ProjectId
 	case "BuildZone":
@@ -0,0 +1,107 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	registryimage "github.com/hashicorp/packer-plugin-sdk/packer/registry/image"
+)
+
+// ImagesArtifact represents several GCE images as the result of a Packer
+// build, e.g. the images of several disks, or copies of an image in other
+// projects. The first image is the primary one.
+type ImagesArtifact struct {
+	// Images are the artifacts of each image.
+	Images []*Artifact
+	// StateData should store data such as GeneratedData
+	// to be shared with post-processors
+	StateData map[string]interface{}
+}
+
+var _ packersdk.Artifact = new(ImagesArtifact)
+
+// newImagesArtifact returns the artifact of the images, each being
+// described by its own Artifact.
+func newImagesArtifact(images []*common.Image, driver common.Driver, config *Config, stateData map[string]interface{}) *ImagesArtifact {
+	artifacts := make([]*Artifact, 0, len(images))
+	for _, image := range images {
+		artifacts = append(artifacts, &Artifact{
+			image:     image,
+			driver:    driver,
+			config:    config,
+			StateData: stateData,
+		})
+	}
+	return &ImagesArtifact{Images: artifacts, StateData: stateData}
+}
+
+// BuilderId returns the builder Id.
+func (*ImagesArtifact) BuilderId() string {
+	return BuilderId
+}
+
+// Destroy destroys all the images.
+func (a *ImagesArtifact) Destroy() error {
+	var errs error
+	for _, image := range a.Images {
+		if err := image.Destroy(); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, err)
+		}
+	}
+	return errs
+}
+
+// Files returns the files represented by the artifact.
+func (*ImagesArtifact) Files() []string {
+	return nil
+}
+
+// Id returns the comma-separated images, as project:name.
+func (a *ImagesArtifact) Id() string {
+	ids := make([]string, 0, len(a.Images))
+	for _, image := range a.Images {
+		ids = append(ids, fmt.Sprintf("%s:%s", image.projectId(), image.Id()))
+	}
+	return strings.Join(ids, ",")
+}
+
+// String returns the string representation of the artifact.
+func (a *ImagesArtifact) String() string {
+	images := make([]string, 0, len(a.Images))
+	for _, image := range a.Images {
+		images = append(images, fmt.Sprintf("%s: %s", image.projectId(), image.Id()))
+	}
+	return fmt.Sprintf("Disk images were created:\n\n%s", strings.Join(images, "\n"))
+}
+
+// State returns the registry metadata of every image, the state of each
+// image with "Images", or else the state of the primary image.
+func (a *ImagesArtifact) State(name string) interface{} {
+	switch name {
+	case registryimage.ArtifactStateURI:
+		images := make([]*registryimage.Image, 0, len(a.Images))
+		for _, image := range a.Images {
+			images = append(images, image.registryImage())
+		}
+		return images
+	case "Images":
+		states := make([]map[string]string, 0, len(a.Images))
+		for _, image := range a.Images {
+			states = append(states, imageGeneratedData(image.image, image.config))
+		}
+		return states
+	}
+
+	if len(a.Images) > 0 {
+		return a.Images[0].State(name)
+	}
+	if _, ok := a.StateData[name]; ok {
+		return a.StateData[name]
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	registryimage "github.com/hashicorp/packer-plugin-sdk/packer/registry/image"
+)
+
+func TestImagesArtifact(t *testing.T) {
+	driver := new(common.DriverMock)
+	config := &Config{ImageProjectId: "project", Zone: "us1"}
+	images := []*common.Image{
+		{Name: "boot", ProjectId: "project"},
+		{Name: "boot", ProjectId: "other-project"},
+	}
+	artifact := newImagesArtifact(images, driver, config, map[string]interface{}{"generated_data": map[string]interface{}{}})
+
+	if id := artifact.Id(); id != "project:boot,other-project:boot" {
+		t.Errorf("Bad: unexpected Id %q", id)
+	}
+	if name := artifact.State("ImageName"); name != "boot" {
+		t.Errorf("Bad: the state should be the one of the primary image, got %v", name)
+	}
+
+	states := artifact.State("Images").([]map[string]string)
+	if len(states) != 2 || states[1]["ImageProjectId"] != "other-project" {
+		t.Errorf("Bad: unexpected per-image state %v", states)
+	}
+
+	registryImages := artifact.State(registryimage.ArtifactStateURI).([]*registryimage.Image)
+	if len(registryImages) != 2 {
+		t.Errorf("Bad: every image should be registered, got %d", len(registryImages))
+	}
+
+	if err := artifact.Destroy(); err != nil {
+		t.Fatalf("Bad: unexpected error %s", err)
+	}
+	if driver.DeleteProjectId != "other-project" {
+		t.Errorf("Bad: the copy should be deleted from its project, got %q", driver.DeleteProjectId)
+	}
+}
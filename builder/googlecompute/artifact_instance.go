@@ -0,0 +1,115 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// InstanceArtifact describes what a build with skip_create_image leaves
+// behind: the disks kept with keep_device, while the instance itself is
+// deleted.
+type InstanceArtifact struct {
+	driver common.Driver
+	config *Config
+	// disks are the kept disks.
+	disks []common.BlockDevice
+	// StateData should store data such as GeneratedData
+	// to be shared with post-processors
+	StateData map[string]interface{}
+}
+
+var _ packersdk.Artifact = new(InstanceArtifact)
+
+// newInstanceArtifact returns the artifact of a build with config.
+func newInstanceArtifact(driver common.Driver, config *Config, generatedData interface{}) *InstanceArtifact {
+	var disks []common.BlockDevice
+	for _, disk := range config.ExtraBlockDevices {
+		if disk.KeepDevice && disk.VolumeType != common.LocalScratch {
+			disks = append(disks, disk)
+		}
+	}
+	return &InstanceArtifact{
+		driver:    driver,
+		config:    config,
+		disks:     disks,
+		StateData: map[string]interface{}{"generated_data": generatedData},
+	}
+}
+
+// BuilderId returns the builder Id.
+func (*InstanceArtifact) BuilderId() string {
+	return BuilderId
+}
+
+func (a *InstanceArtifact) diskNames() []string {
+	names := make([]string, 0, len(a.disks))
+	for _, disk := range a.disks {
+		names = append(names, disk.DiskName)
+	}
+	return names
+}
+
+// Destroy deletes the disks created by the build; the existing disks
+// attached with source_volume are left alone.
+func (a *InstanceArtifact) Destroy() error {
+	var errs error
+	for _, disk := range a.disks {
+		if disk.SourceVolume != "" {
+			continue
+		}
+		log.Printf("Destroying disk: %s", disk.DiskName)
+		if err := <-a.driver.DeleteDisk(disk.Zone, disk.DiskName); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, err)
+		}
+	}
+	return errs
+}
+
+// Files returns the files represented by the artifact.
+func (*InstanceArtifact) Files() []string {
+	return nil
+}
+
+// Id returns the comma-separated names of the kept disks, or the name of
+// the build instance when none is kept.
+func (a *InstanceArtifact) Id() string {
+	if len(a.disks) == 0 {
+		return a.config.InstanceName
+	}
+	return strings.Join(a.diskNames(), ",")
+}
+
+// String returns the string representation of the artifact.
+func (a *InstanceArtifact) String() string {
+	if len(a.disks) == 0 {
+		return fmt.Sprintf("No image was created, and the instance %s was deleted", a.config.InstanceName)
+	}
+	return fmt.Sprintf("No image was created, the disks were kept in the '%v' project, zone %s: %s",
+		a.config.ProjectId, a.config.Zone, strings.Join(a.diskNames(), ", "))
+}
+
+func (a *InstanceArtifact) State(name string) interface{} {
+	switch name {
+	case "InstanceName":
+		return a.config.InstanceName
+	case "Disks":
+		return a.diskNames()
+	case "ProjectId":
+		return a.config.ProjectId
+	case "BuildZone":
+		return a.config.Zone
+	}
+
+	if _, ok := a.StateData[name]; ok {
+		return a.StateData[name]
+	}
+
+	return nil
+}
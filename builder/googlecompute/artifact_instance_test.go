@@ -0,0 +1,48 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+)
+
+func TestInstanceArtifact(t *testing.T) {
+	driver := new(common.DriverMock)
+	config := &Config{
+		InstanceName: "packer-instance",
+		ProjectId:    "project",
+		Zone:         "us-central1-a",
+		ExtraBlockDevices: []common.BlockDevice{
+			{DiskName: "data", KeepDevice: true, Zone: "us-central1-a"},
+			{DiskName: "existing", SourceVolume: "existing", KeepDevice: true, Zone: "us-central1-a"},
+			{DiskName: "scratch", VolumeType: common.LocalScratch, KeepDevice: true},
+			{DiskName: "temporary"},
+		},
+	}
+
+	artifact := newInstanceArtifact(driver, config, map[string]interface{}{"SourceImageName": "debian"})
+	if artifact.Id() != "data,existing" {
+		t.Errorf("Bad: the artifact should list the kept disks, got %q", artifact.Id())
+	}
+	if artifact.State("InstanceName") != "packer-instance" {
+		t.Errorf("Bad: unexpected InstanceName %v", artifact.State("InstanceName"))
+	}
+	if _, ok := artifact.State("generated_data").(map[string]interface{}); !ok {
+		t.Error("Bad: the artifact should hold the generated data")
+	}
+
+	if err := artifact.Destroy(); err != nil {
+		t.Fatalf("Bad: unexpected error %s", err)
+	}
+	if driver.DeleteDiskName != "data" {
+		t.Errorf("Bad: only the created disk should be deleted, got %q", driver.DeleteDiskName)
+	}
+
+	artifact = newInstanceArtifact(driver, &Config{InstanceName: "packer-instance"}, nil)
+	if artifact.Id() != "packer-instance" {
+		t.Errorf("Bad: the artifact should name the instance without kept disks, got %q", artifact.Id())
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type ArtifactTemplate
+
+package googlecompute
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
+
+// ArtifactTemplate is a file rendered with the attributes of the image at
+// the end of the build, e.g. the Terraform variables or the gcloud command
+// deploying it.
+type ArtifactTemplate struct {
+	// The path of the template to render. Either `source` or `content` must
+	// be set.
+	Source string `mapstructure:"source" required:"false"`
+	// The template to render, inline.
+	Content string `mapstructure:"content" required:"false"`
+	// The path of the file the template is rendered to.
+	Destination string `mapstructure:"destination" required:"true"`
+}
+
+// Prepare checks the template, with the functions of ctx.
+func (t *ArtifactTemplate) Prepare(ctx *interpolate.Context) []error {
+	var errs []error
+	if t.Destination == "" {
+		errs = append(errs, errors.New("artifact_template: destination must be set"))
+	}
+
+	switch {
+	case t.Source != "" && t.Content != "":
+		errs = append(errs, errors.New("artifact_template: only one of source or content can be set"))
+	case t.Source != "":
+		data, err := os.ReadFile(t.Source)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("artifact_template: %s", err))
+			break
+		}
+		if err := interpolate.Validate(string(data), ctx); err != nil {
+			errs = append(errs, fmt.Errorf("artifact_template: invalid template %s: %s", t.Source, err))
+		}
+	case t.Content != "":
+		if err := interpolate.Validate(t.Content, ctx); err != nil {
+			errs = append(errs, fmt.Errorf("artifact_template: invalid template: %s", err))
+		}
+	default:
+		errs = append(errs, errors.New("artifact_template: one of source or content must be set"))
+	}
+	return errs
+}
+
+// Render renders the template with data, the generated data of the build,
+// and writes it to its destination.
+func (t *ArtifactTemplate) Render(ctx interpolate.Context, data interface{}) error {
+	content := t.Content
+	if t.Source != "" {
+		raw, err := os.ReadFile(t.Source)
+		if err != nil {
+			return fmt.Errorf("Error reading the artifact template %s: %s", t.Source, err)
+		}
+		content = string(raw)
+	}
+
+	ctx.Data = data
+	rendered, err := interpolate.Render(content, &ctx)
+	if err != nil {
+		return fmt.Errorf("Error rendering the artifact template to %s: %s", t.Destination, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(t.Destination), 0755); err != nil {
+		return fmt.Errorf("Error writing %s: %s", t.Destination, err)
+	}
+	if err := os.WriteFile(t.Destination, []byte(rendered), 0644); err != nil {
+		return fmt.Errorf("Error writing %s: %s", t.Destination, err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
+
+func TestArtifactTemplate_Prepare(t *testing.T) {
+	ctx := &interpolate.Context{Funcs: TemplateFuncs}
+	source := filepath.Join(t.TempDir(), "image.tmpl")
+	if err := os.WriteFile(source, []byte("{{ .ImageName }}"), 0644); err != nil {
+		t.Fatalf("Bad: unexpected error %s", err)
+	}
+
+	for name, c := range map[string]struct {
+		template ArtifactTemplate
+		ok       bool
+	}{
+		"content":        {ArtifactTemplate{Content: "{{ .ImageName }}", Destination: "out"}, true},
+		"source":         {ArtifactTemplate{Source: source, Destination: "out"}, true},
+		"no destination": {ArtifactTemplate{Content: "{{ .ImageName }}"}, false},
+		"no template":    {ArtifactTemplate{Destination: "out"}, false},
+		"both":           {ArtifactTemplate{Source: source, Content: "x", Destination: "out"}, false},
+		"missing source": {ArtifactTemplate{Source: "no-such-file", Destination: "out"}, false},
+		"invalid":        {ArtifactTemplate{Content: "{{ .ImageName", Destination: "out"}, false},
+	} {
+		errs := c.template.Prepare(ctx)
+		if c.ok && len(errs) > 0 {
+			t.Errorf("%s: unexpected errors %v", name, errs)
+		}
+		if !c.ok && len(errs) == 0 {
+			t.Errorf("%s: should error", name)
+		}
+	}
+}
+
+func TestArtifactTemplate_Render(t *testing.T) {
+	destination := filepath.Join(t.TempDir(), "deploy", "image.auto.tfvars")
+	template := ArtifactTemplate{
+		Content:     `image = "{{ .ImageSelfLink }}" # {{ clean_resource_name .SourceImageName }}`,
+		Destination: destination,
+	}
+	data := map[string]interface{}{
+		"ImageSelfLink":   "projects/project/global/images/my-image",
+		"SourceImageName": "Debian_12",
+	}
+
+	if err := template.Render(interpolate.Context{Funcs: TemplateFuncs}, data); err != nil {
+		t.Fatalf("Bad: unexpected error %s", err)
+	}
+	rendered, err := os.ReadFile(destination)
+	if err != nil {
+		t.Fatalf("Bad: unexpected error %s", err)
+	}
+	if expected := `image = "projects/project/global/images/my-image" # debian-12`; string(rendered) != expected {
+		t.Errorf("Bad: rendered %q, expected %q", rendered, expected)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	registryimage "github.com/hashicorp/packer-plugin-sdk/packer/registry/image"
 	"github.com/mitchellh/mapstructure"
+	compute "google.golang.org/api/compute/v1"
 )
 
 func TestArtifact_impl(t *testing.T) {
@@ -73,3 +74,112 @@ func TestArtifactState_RegistryImageMetadata(t *testing.T) {
 	}
 
 }
+
+func TestArtifactState_RegistryImageProvenance(t *testing.T) {
+	artifact := &Artifact{
+		config: &Config{
+			Zone:               "us1",
+			SourceImageFamily:  "debian-12",
+			MachineType:        "e2-medium",
+			DiskType:           "pd-ssd",
+			DiskSizeGb:         20,
+			ImageEncryptionKey: &common.CustomerEncryptionKey{KmsKeyName: "projects/p/locations/l/keyRings/r/cryptoKeys/k"},
+			DiskEncryptionKey:  &common.CustomerEncryptionKey{RawKey: "secret"},
+		},
+		image: &common.Image{Name: "test-image", ProjectId: "5678"},
+		StateData: map[string]interface{}{"generated_data": map[string]interface{}{
+			"SourceImageName":      "debian-12-bookworm-v20240110",
+			"SourceImageProjectId": "debian-cloud",
+			"SourceImageSelfLink":  "https://www.googleapis.com/compute/v1/projects/debian-cloud/global/images/debian-12-bookworm-v20240110",
+		}},
+	}
+
+	var image registryimage.Image
+	if err := mapstructure.Decode(artifact.State(registryimage.ArtifactStateURI), &image); err != nil {
+		t.Fatalf("Bad: unexpected error when trying to decode state into registryimage.Image %v", err)
+	}
+
+	if image.SourceImageID != "debian-12-bookworm-v20240110" {
+		t.Errorf("Bad: unexpected SourceImageID %q", image.SourceImageID)
+	}
+	for label, expected := range map[string]string{
+		"source_image_family":       "debian-12",
+		"source_image_project_id":   "debian-cloud",
+		"source_image_self_link":    "https://www.googleapis.com/compute/v1/projects/debian-cloud/global/images/debian-12-bookworm-v20240110",
+		"machine_type":              "e2-medium",
+		"build_disk_type":           "pd-ssd",
+		"image_encryption_key":      "projects/p/locations/l/keyRings/r/cryptoKeys/k",
+		"build_disk_encryption_key": "customer-supplied",
+	} {
+		if image.Labels[label] != expected {
+			t.Errorf("Bad: label %s was %q, expected %q", label, image.Labels[label], expected)
+		}
+	}
+}
+
+func TestArtifactState_ImageMetadata(t *testing.T) {
+	artifact := &Artifact{
+		config: &Config{ImageProjectId: "project", ImageFamily: "configured"},
+		image: &common.Image{
+			Name:             "test-image",
+			Id:               1234,
+			Family:           "family",
+			Licenses:         []string{"license"},
+			StorageLocations: []string{"eu"},
+		},
+	}
+
+	if id := artifact.State("ImageId"); id != uint64(1234) {
+		t.Errorf("Bad: unexpected ImageId %v", id)
+	}
+	if family := artifact.State("ImageFamily"); family != "family" {
+		t.Errorf("Bad: the family of the image should take precedence, got %v", family)
+	}
+	if locations := artifact.State("ImageStorageLocations").([]string); len(locations) != 1 || locations[0] != "eu" {
+		t.Errorf("Bad: unexpected ImageStorageLocations %v", locations)
+	}
+	if state := artifact.State("ImageDeprecationState"); state != "ACTIVE" {
+		t.Errorf("Bad: an image without deprecation should be ACTIVE, got %v", state)
+	}
+
+	artifact.image.Deprecation = &compute.DeprecationStatus{State: "DEPRECATED", Obsolete: "2030-01-01T00:00:00Z"}
+	if state := artifact.State("ImageDeprecationState"); state != "DEPRECATED" {
+		t.Errorf("Bad: unexpected ImageDeprecationState %v", state)
+	}
+	if deprecation := artifact.State("ImageDeprecation").(*compute.DeprecationStatus); deprecation.Obsolete != "2030-01-01T00:00:00Z" {
+		t.Errorf("Bad: unexpected ImageDeprecation %v", deprecation)
+	}
+	data := imageGeneratedData(artifact.image, artifact.config)
+	if data["ImageObsolete"] != "2030-01-01T00:00:00Z" || data["ImageDeprecationState"] != "DEPRECATED" {
+		t.Errorf("Bad: unexpected deprecation generated data %v", data)
+	}
+}
+
+func TestImageGeneratedData(t *testing.T) {
+	image := &common.Image{
+		Name:             "test-image",
+		Id:               1234,
+		Labels:           map[string]string{"b": "2", "a": "1"},
+		Licenses:         []string{"l1", "l2"},
+		SizeGb:           10,
+		StorageLocations: []string{"eu"},
+	}
+	data := imageGeneratedData(image, &Config{ImageProjectId: "project", ImageFamily: "family"})
+
+	for _, key := range ImageGeneratedDataKeys {
+		if _, ok := data[key]; !ok {
+			t.Errorf("Bad: missing generated data %s", key)
+		}
+	}
+	for key, expected := range map[string]string{
+		"ImageId":       "1234",
+		"ImageFamily":   "family",
+		"ImageSizeGb":   "10",
+		"ImageLabels":   "a=1,b=2",
+		"ImageLicenses": "l1,l2",
+	} {
+		if data[key] != expected {
+			t.Errorf("Bad: %s was %q, expected %q", key, data[key], expected)
+		}
+	}
+}
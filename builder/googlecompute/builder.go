@@ -7,8 +7,10 @@ package googlecompute
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/hashicorp/hcl/v2/hcldec"
 	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
@@ -26,6 +28,15 @@ const BuilderId = "packer.googlecompute"
 type Builder struct {
 	config Config
 	runner multistep.Runner
+	// configDigest is the digest of the raw configuration, for the
+	// provenance.
+	configDigest string
+}
+
+// NewBuilder returns a builder of config, already prepared, for the builders
+// reusing the build instance of this one.
+func NewBuilder(config Config) *Builder {
+	return &Builder{config: config}
 }
 
 func (b *Builder) ConfigSpec() hcldec.ObjectSpec { return b.config.FlatMapstructure().HCL2Spec() }
@@ -35,11 +46,24 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, []string, error) {
 	if errs != nil {
 		return nil, warnings, errs
 	}
+	b.configDigest = configDigest(raws...)
 	generatedDataKeys := []string{
 		// This will be set with the source image name even if the config
 		// uses source image family instead of source image id.
 		"SourceImageName",
+		"SourceImageProjectId",
+		"SourceImageSelfLink",
+		"InstanceName",
+		"InstanceZone",
+		"InstanceInternalIP",
+		"InstanceExternalIP",
+		"StepTimings",
+		"ImageChecksum",
+	}
+	for _, forward := range b.config.IAPPortForwards {
+		generatedDataKeys = append(generatedDataKeys, forward.GeneratedDataKey())
 	}
+	generatedDataKeys = append(generatedDataKeys, ImageGeneratedDataKeys...)
 
 	return generatedDataKeys, warnings, nil
 }
@@ -48,8 +72,10 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, []string, error) {
 // representing a GCE machine image.
 func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook) (packersdk.Artifact, error) {
 	cfg := &common.GCEDriverConfig{
-		Ui:        ui,
-		ProjectId: b.config.ProjectId,
+		Ui:              ui,
+		ProjectId:       b.config.ProjectId,
+		PollMinInterval: b.config.OperationPollMinInterval,
+		PollMaxInterval: b.config.OperationPollMaxInterval,
 	}
 	b.config.Authentication.ApplyDriverConfig(cfg)
 
@@ -58,6 +84,10 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 		return nil, err
 	}
 
+	// A failure seen in the serial port output aborts the build.
+	ctx, abort := context.WithCancel(ctx)
+	defer abort()
+
 	// Set up the state.
 	state := new(multistep.BasicStateBag)
 	state.Put("config", &b.config)
@@ -66,22 +96,52 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 	state.Put("ui", ui)
 	generatedData := &packerbuilderdata.GeneratedData{State: state}
 
+	if b.config.PreflightOnly {
+		b.runner = commonsteps.NewRunner([]multistep.Step{new(StepPreflight)}, b.config.PackerConfig, ui)
+		b.runner.Run(ctx, state)
+		if rawErr, ok := state.GetOk("error"); ok {
+			return nil, rawErr.(error)
+		}
+		ui.Say("Preflight checks passed, nothing was created.")
+		return nil, nil
+	}
+
+	var customConnect map[string]multistep.Step
+	if b.config.SerialPortFallback {
+		customConnect = map[string]multistep.Step{
+			"ssh": &StepConnectSerialFallback{
+				Host:      communicator.CommHost(b.config.Comm.Host(), "instance_ip"),
+				SSHConfig: b.config.Comm.SSHConfigFunc(),
+			},
+		}
+	}
+
 	// Build the steps.
 	steps := []multistep.Step{
+		multistep.If(b.config.PermissionsPrecheck, new(StepCheckPermissions)),
 		new(StepCheckExistingImage),
-		&communicator.StepSSHKeyGen{
+		multistep.If(b.config.QuotaPrecheck, new(StepCheckQuotas)),
+		multistep.If(b.config.GoogleAccess != "", new(StepCheckGoogleAccess)),
+		// With an SSH agent, no key material is written to the instance.
+		multistep.If(!b.config.UseSSHAgent, &communicator.StepSSHKeyGen{
 			CommConf:            &b.config.Comm,
 			SSHTemporaryKeyPair: b.config.Comm.SSH.SSHTemporaryKeyPair,
-		},
-		multistep.If(b.config.PackerDebug && b.config.Comm.SSHPrivateKeyFile == "",
+		}),
+		multistep.If(b.config.PackerDebug && b.config.Comm.SSHPrivateKeyFile == "" && !b.config.UseSSHAgent,
 			&communicator.StepDumpSSHKey{
 				Path: fmt.Sprintf("gce_%s.pem", b.config.PackerBuildName),
 				SSH:  &b.config.Comm.SSH,
 			},
 		),
-		&StepCreateDisks{
+	}
+	// The steps building on the instance, which are run again when it is
+	// preempted.
+	instanceSteps := []multistep.Step{
+		// The disks already created are skipped when trying again, those
+		// left by a failed creation are used if ready, or deleted.
+		retryTransient(&StepCreateDisks{
 			DiskConfiguration: b.config.ExtraBlockDevices,
-		},
+		}),
 		&StepImportOSLoginSSHKey{
 			Debug: b.config.PackerDebug,
 		},
@@ -89,54 +149,172 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 			Debug:         b.config.PackerDebug,
 			GeneratedData: generatedData,
 		},
+		multistep.If(len(b.config.SerialOutputFailurePatterns) > 0, &StepWatchSerialOutput{Abort: abort}),
+		new(StepWaitWindowsReady),
 		&StepCreateWindowsPassword{
 			Debug:        b.config.PackerDebug,
 			DebugKeyPath: fmt.Sprintf("gce_windows_%s.pem", b.config.PackerBuildName),
 		},
 		&StepInstanceInfo{
-			Debug: b.config.PackerDebug,
+			Debug:         b.config.PackerDebug,
+			GeneratedData: generatedData,
+		},
+		new(StepWaitWinRMCertificate),
+		commonsteps.HTTPServerFromHTTPConfig(&b.config.HTTPConfig),
+		&StepHTTPReverseTunnel{
+			CommConf: &b.config.Comm,
 		},
 		&StepStartTunnel{
-			IAPConf:            &b.config.IAPConfig,
-			CommConf:           &b.config.Comm,
-			AccountFile:        b.config.AccountFile,
-			ImpersonateAccount: b.config.ImpersonateServiceAccount,
-			ProjectId:          b.config.ProjectId,
+			IAPConf:       &b.config.IAPConfig,
+			CommConf:      &b.config.Comm,
+			ProjectId:     b.config.ProjectId,
+			GeneratedData: generatedData,
+			Debug:         b.config.PackerDebug,
+		},
+		&StepStartSSHProxy{
+			ProxyURL: b.config.SSHProxyURL,
+			CommConf: &b.config.Comm,
 		},
 		&communicator.StepConnect{
-			Config:      &b.config.Comm,
-			Host:        communicator.CommHost(b.config.Comm.Host(), "instance_ip"),
-			SSHConfig:   b.config.Comm.SSHConfigFunc(),
-			WinRMConfig: winrmConfig,
+			Config:        &b.config.Comm,
+			Host:          communicator.CommHost(b.config.Comm.Host(), "instance_ip"),
+			SSHConfig:     b.config.Comm.SSHConfigFunc(),
+			WinRMConfig:   winrmConfig,
+			CustomConnect: customConnect,
 		},
+		&StepSSHReconnect{
+			CommConf: &b.config.Comm,
+			Disable:  b.config.DisableSSHReconnect,
+		},
+		multistep.If(b.config.CheckpointName != "", new(StepCheckpoint)),
 		new(commonsteps.StepProvision),
 		&commonsteps.StepCleanupTempKeys{
 			Comm: &b.config.Comm,
 		},
 	}
 	if _, exists := b.config.Metadata[StartupScriptKey]; exists || b.config.StartupScriptFile != "" {
-		steps = append(steps, new(StepWaitStartupScript))
+		instanceSteps = append(instanceSteps, new(StepWaitStartupScript))
+	}
+	if b.config.ForceCreateImage {
+		// The image is created from the disk of the running instance.
+		instanceSteps = append(instanceSteps, retryTransient(new(StepCreateImage)), new(StepTeardownInstance))
+	} else {
+		instanceSteps = append(instanceSteps, new(StepTeardownInstance), retryTransient(new(StepCreateImage)))
+	}
+	imageSteps := []multistep.Step{
+		multistep.If(len(b.config.ImageReplicaLocations) > 0, new(StepReplicateImage)),
+		multistep.If(b.config.WaitImageReady, new(StepWaitImageReady)),
+		multistep.If(b.config.DeprecateFamilyImages || len(b.config.DeprecateImages) > 0, new(StepDeprecateImages)),
+		multistep.If(b.config.ImageChecksum, &StepImageChecksum{Debug: b.config.PackerDebug}),
 	}
-	steps = append(steps, new(StepTeardownInstance), new(StepCreateImage))
 
-	// Run the steps.
+	// Run the steps, timing each of them.
+	timings := new(stepTimings)
+	steps = timings.wrap(steps)
+	if b.config.PreemptionMaxRestarts > 0 {
+		steps = append(steps, &StepRestartOnPreemption{
+			Steps:       timings.wrap(instanceSteps),
+			MaxRestarts: b.config.PreemptionMaxRestarts,
+		})
+	} else {
+		steps = append(steps, timings.wrap(instanceSteps)...)
+	}
+	steps = append(steps, timings.wrap(imageSteps)...)
 	b.runner = commonsteps.NewRunner(steps, b.config.PackerConfig, ui)
+	started := time.Now()
 	b.runner.Run(ctx, state)
+	finished := time.Now()
+
+	// Summarize the build even when it fails, for support cases.
+	ui.Say(fmt.Sprintf("Build steps:\n%s", timings))
+	generatedData.Put("StepTimings", timings.JSON())
 
 	// Report any errors.
 	if rawErr, ok := state.GetOk("error"); ok {
-		return nil, rawErr.(error)
+		return nil, common.EnrichError(rawErr.(error))
+	}
+	if b.config.SkipCreateImage {
+		b.renderArtifactTemplates(ui, state)
+		// Still give a handle to what the build leaves behind.
+		artifact := newInstanceArtifact(driver, &b.config, state.Get("generated_data"))
+		artifact.StateData["StepTimings"] = timings.list()
+		return artifact, nil
 	}
 	if _, ok := state.GetOk("image"); !ok {
 		log.Println("Failed to find image in state. Bug?")
 		return nil, nil
 	}
 
+	image := state.Get("image").(*common.Image)
+	// Describe the image to the post-processors, and in the machine-readable
+	// output.
+	imageData := imageGeneratedData(image, &b.config)
+	for _, key := range ImageGeneratedDataKeys {
+		generatedData.Put(key, imageData[key])
+		ui.Machine("artifact-metadata", key, imageData[key])
+	}
+	checksum, _ := state.Get("image_checksum").(string)
+	generatedData.Put("ImageChecksum", checksum)
+	b.renderArtifactTemplates(ui, state)
+
+	images, _ := state.Get("images").([]*common.Image)
+	if len(images) == 0 {
+		images = []*common.Image{image}
+	}
+
+	stateData := map[string]interface{}{
+		"generated_data": state.Get("generated_data"),
+		"StepTimings":    timings.list(),
+	}
+	if checksum != "" {
+		stateData["ImageChecksum"] = checksum
+		// Like the checksums of the exported images, e.g. for the catalog.
+		stateData["checksums"] = map[string]string{image.SelfLink: checksum}
+	}
+
+	// Attest where the images come from.
+	data, _ := state.Get("generated_data").(map[string]interface{})
+	provenance := &buildProvenance{
+		config:        &b.config,
+		configDigest:  b.configDigest,
+		generatedData: data,
+		operations:    driver.Operations(),
+		started:       started,
+		finished:      finished,
+	}
+	statement, err := json.MarshalIndent(provenance.statement(images), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("Error encoding the provenance: %s", err)
+	}
+	stateData["Provenance"] = string(statement)
+	if b.config.ProvenanceFile != "" {
+		ui.Say(fmt.Sprintf("Writing the provenance of the image to %s...", b.config.ProvenanceFile))
+		if err := writeProvenance(b.config.ProvenanceFile, statement); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(images) > 1 {
+		return newImagesArtifact(images, driver, &b.config, stateData), nil
+	}
+
 	artifact := &Artifact{
-		image:     state.Get("image").(*common.Image),
+		image:     image,
 		driver:    driver,
 		config:    &b.config,
-		StateData: map[string]interface{}{"generated_data": state.Get("generated_data")},
+		StateData: stateData,
 	}
 	return artifact, nil
 }
+
+// renderArtifactTemplates renders the artifact templates with the generated
+// data of the build. A failure is reported without failing the build, so
+// that the artifact is still returned.
+func (b *Builder) renderArtifactTemplates(ui packersdk.Ui, state multistep.StateBag) {
+	for _, t := range b.config.ArtifactTemplates {
+		ui.Say(fmt.Sprintf("Writing %s...", t.Destination))
+		if err := t.Render(b.config.ctx, state.Get("generated_data")); err != nil {
+			ui.Error(err.Error())
+		}
+	}
+}
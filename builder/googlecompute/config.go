@@ -9,23 +9,23 @@ package googlecompute
 import (
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"regexp"
-	"runtime"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
 	sdk_common "github.com/hashicorp/packer-plugin-sdk/common"
 	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep/commonsteps"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer-plugin-sdk/template/config"
 	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
 	"github.com/hashicorp/packer-plugin-sdk/uuid"
 )
 
-// used for ImageName and ImageFamily
-var validImageName = regexp.MustCompile(`^[a-z]([-a-z0-9]{0,61}[a-z0-9])?$`)
-
 // Config is the configuration structure for the GCE builder. It stores
 // both the publicly settable state as well as the privately generated
 // state of the config object.
@@ -35,6 +35,8 @@ type Config struct {
 
 	Comm communicator.Config `mapstructure:",squash"`
 
+	commonsteps.HTTPConfig `mapstructure:",squash"`
+
 	// The project ID that will be used to launch instances and store images.
 	ProjectId string `mapstructure:"project_id" required:"true"`
 	// Full or partial URL of the guest accelerator type. GPU accelerators can
@@ -47,6 +49,46 @@ type Config struct {
 	// The name of a pre-allocated static external IP address. Note, must be
 	// the name and not the actual IP address.
 	Address string `mapstructure:"address" required:"false"`
+	// The settings of the boot disk, as a block instead of the `disk_*`
+	// keys. Refer to the [Boot disk](#boot-disk) section for more
+	// information.
+	BootDisk *BootDisk `mapstructure:"boot_disk" required:"false"`
+	// If true, the build instance is created in a single bulk insert request
+	// with the identical instances of the other builds of the template
+	// started within `bulk_insert_window`, e.g. the builds of a matrix
+	// differing only by their provisioners. The SSH keys are added to the
+	// instances once they are created. Instances with a static `address`, a
+	// custom `disk_name` or persistent `disk_attachment`s are created on
+	// their own. The builds coordinate through files in the
+	// `packer-googlecompute-<run ID>` directory of the system temporary
+	// directory. Defaults to `false`.
+	BulkInsert bool `mapstructure:"bulk_insert" required:"false"`
+	// The time the first of the identical builds waits for the others before
+	// creating their instances with `bulk_insert`. Defaults to "15s".
+	BulkInsertWindow time.Duration `mapstructure:"bulk_insert_window" required:"false"`
+	// Experimental. The number of build instances kept stopped in a warm
+	// pool between builds, in `zone`. A build reuses an idle instance of the
+	// pool created from the same source image with the same shape, e.g. the
+	// same `machine_type`, network and service account, attaching it a new
+	// boot disk, instead of creating an instance. Once the build is done,
+	// successfully or not, the instance is stopped and returned to the
+	// pool, unless it already has as many idle instances.
+	//
+	// The instances of the pools are labeled `packer-warm-pool`, and get the
+	// `labels` of the build using them. Packer deletes an instance instead of
+	// returning it to a full pool, or when it fails to stop it. Cannot be
+	// used with `disk_attachment`, `address` or `bulk_insert`. Defaults to
+	// `0`: no pool.
+	WarmPoolSize int `mapstructure:"warm_pool_size" required:"false"`
+	// If true, once the image is created, the other images of `image_family`
+	// in `image_project_id` which are not deprecated yet are deprecated, with
+	// the new image as their replacement, so that users of `gcloud` and
+	// Terraform are pointed to it. Requires `image_family`. Defaults to
+	// `false`.
+	DeprecateFamilyImages bool `mapstructure:"deprecate_family_images" required:"false"`
+	// The names of images of `image_project_id` to deprecate once the image
+	// is created, with the new image as their replacement.
+	DeprecateImages []string `mapstructure:"deprecate_images" required:"false"`
 	// If true, the default service account will not be used if
 	// service_account_email is not specified. Set this value to true and omit
 	// service_account_email to provision a VM with no service account.
@@ -61,6 +103,7 @@ type Config struct {
 	// Disk encryption key to apply to the created boot disk. Possible values:
 	// * kmsKeyName -  The name of the encryption key that is stored in Google Cloud KMS.
 	// * RawKey: - A 256-bit customer-supplied encryption key, encodes in RFC 4648 base64.
+	// * rsa_encrypted_key: - A 256-bit customer-supplied encryption key, wrapped with the RSA public key of Google.
 	//
 	// examples:
 	//
@@ -114,7 +157,17 @@ type Config struct {
 	// Whether to use an IAP proxy.
 	IAPConfig `mapstructure:",squash"`
 	// Skip creating the image. Useful for setting to `true` during a build test stage. Defaults to `false`.
+	// The artifact then describes the disks kept with `keep_device`, or the
+	// deleted instance when none is kept.
 	SkipCreateImage bool `mapstructure:"skip_create_image" required:"false"`
+	// Create the image while the instance is still running, instead of
+	// deleting the instance first. This saves the time it takes to stop the
+	// instance, but the image is only crash-consistent: the writes the guest
+	// did not flush to the disk yet are lost, and the file systems are
+	// captured as if the instance lost power. Only use it if the image is
+	// known to recover from this, e.g. after a last provisioner running
+	// `sync`. Defaults to `false`.
+	ForceCreateImage bool `mapstructure:"force_create_image" required:"false"`
 	// The unique name of the resulting image. Defaults to
 	// `packer-{{timestamp}}`.
 	ImageName string `mapstructure:"image_name" required:"false"`
@@ -123,6 +176,7 @@ type Config struct {
 	// Image encryption key to apply to the created image. Possible values:
 	// * kmsKeyName -  The name of the encryption key that is stored in Google Cloud KMS.
 	// * RawKey: - A 256-bit customer-supplied encryption key, encodes in RFC 4648 base64.
+	// * rsa_encrypted_key: - A 256-bit customer-supplied encryption key, wrapped with the RSA public key of Google.
 	//
 	// examples:
 	//
@@ -151,6 +205,22 @@ type Config struct {
 	ImageLicenses []string `mapstructure:"image_licenses" required:"false"`
 	// Guest OS features to apply to the created image.
 	ImageGuestOsFeatures []string `mapstructure:"image_guest_os_features" required:"false"`
+	// The platform key of the Secure Boot chain of the created image, which
+	// establishes the trust between the platform owner and the firmware. It
+	// must be a single X.509 certificate file. The image needs the
+	// `UEFI_COMPATIBLE` guest OS feature, e.g. from its source image, to be
+	// booted with Secure Boot.
+	ImagePlatformKey string `mapstructure:"image_platform_key" required:"false"`
+	// The key exchange key files of the Secure Boot chain of the created
+	// image, which establish the trust between the firmware and the OS.
+	ImageKeyExchangeKey []string `mapstructure:"image_key_exchange_key" required:"false"`
+	// The certificate or EFI signature list files trusted to sign the boot
+	// files of the created image.
+	ImageSignaturesDB []string `mapstructure:"image_signatures_db" required:"false"`
+	// The certificate or EFI signature list files of revoked signatures, which
+	// stop the created image from booting if a boot file is signed with one
+	// of them.
+	ImageForbiddenSignaturesDB []string `mapstructure:"image_forbidden_signatures_db" required:"false"`
 	// The project ID to push the build image into. Defaults to project_id.
 	ImageProjectId string `mapstructure:"image_project_id" required:"false"`
 	// Storage location, either regional or multi-regional, where snapshot
@@ -172,6 +242,35 @@ type Config struct {
 	//  }
 	//  ```
 	ImageStorageLocations []string `mapstructure:"image_storage_locations" required:"false"`
+	// Storage locations, regional or multi-regional, to replicate the image
+	// to once it is created. Each replica is an image named
+	// `<image_name>-<location>`, created from the image and stored in the
+	// location, with the same description, labels and licenses but no
+	// family. The replicas are part of the artifact.
+	//
+	//  ```hcl
+	//  image_replica_locations = ["europe-west1", "asia-east1"]
+	//  ```
+	ImageReplicaLocations []string `mapstructure:"image_replica_locations" required:"false"`
+	// The number of replicas of the image created at once. Defaults to 4.
+	ImageReplicationParallelism int `mapstructure:"image_replication_parallelism" required:"false"`
+	// If true, wait for the image, and each of its replicas, to be `READY`
+	// in its storage locations before the build succeeds, so that the
+	// deployments following the build in other regions do not find it not
+	// ready yet. Defaults to `false`.
+	WaitImageReady bool `mapstructure:"wait_image_ready" required:"false"`
+	// The time to wait for the images to be ready with `wait_image_ready`,
+	// before failing the build. Defaults to `30m`.
+	ImageReadyTimeout time.Duration `mapstructure:"image_ready_timeout" required:"false"`
+	// If true, compute the SHA-256 of the contents of the image once it is
+	// created, so that its consumers can detect drift or tampering. A
+	// short-lived instance, in the zone and network of the build, hashes a
+	// read-only disk created from the image. The checksum is available as the
+	// `ImageChecksum` generated data and artifact state, as `sha256:<hex>`.
+	// Defaults to `false`.
+	ImageChecksum bool `mapstructure:"image_checksum" required:"false"`
+	// The time to wait for the checksum of the image. Defaults to "30m".
+	ImageChecksumTimeout time.Duration `mapstructure:"image_checksum_timeout" required:"false"`
 	// A name to give the launched instance. Beware that this must be unique.
 	// Defaults to `packer-{{uuid}}`.
 	InstanceName string `mapstructure:"instance_name" required:"false"`
@@ -200,6 +299,10 @@ type Config struct {
 	// The project ID for the network and subnetwork to use for launched
 	// instance. Defaults to project_id.
 	NetworkProjectId string `mapstructure:"network_project_id" required:"false"`
+	// The network settings of the instance, as a block instead of the flat
+	// network keys. Refer to the [Network interface](#network-interface)
+	// section for more information.
+	NetworkInterface *NetworkInterface `mapstructure:"network_interface" required:"false"`
 	// If true, the instance will not have an external IP. use_internal_ip must
 	// be true if this property is true.
 	OmitExternalIP bool `mapstructure:"omit_external_ip" required:"false"`
@@ -212,7 +315,45 @@ type Config struct {
 	OnHostMaintenance string `mapstructure:"on_host_maintenance" required:"false"`
 	// If true, launch a preemptible instance.
 	Preemptible bool `mapstructure:"preemptible" required:"false"`
+	// The provisioning model of the launched instance. Valid choices are
+	// `STANDARD` and `SPOT`. [Spot VMs](https://cloud.google.com/compute/docs/instances/spot)
+	// are cheaper, but may be preempted at any time. If set to `SPOT`,
+	// `on_host_maintenance` can only be `TERMINATE`. This option cannot be
+	// used together with `preemptible`.
+	ProvisioningModel string `mapstructure:"provisioning_model" required:"false"`
+	// If true, and `provisioning_model` is `SPOT`, the build instance is
+	// created again with the `STANDARD` provisioning model whenever the Spot
+	// instance cannot be created, or is preempted before it reaches the
+	// `RUNNING` state. Defaults to `false`.
+	SpotFallback bool `mapstructure:"spot_fallback" required:"false"`
+	// The number of times the build is restarted from the creation of the
+	// instance when a `SPOT` or `preemptible` instance is preempted, e.g.
+	// during provisioning. The instance and its disks are deleted and
+	// created again, and the provisioners run again from the start. Builds
+	// failing for another reason are not restarted, nor with
+	// `-on-error=abort`. Defaults to `0`.
+	PreemptionMaxRestarts int `mapstructure:"preemption_max_restarts" required:"false"`
+	// The name of a provisioning phase whose result is cached in a snapshot
+	// of the boot disk, to speed up slow early provisioning. The commands of
+	// `checkpoint_inline` are run on the instance before the provisioners,
+	// then the boot disk is snapshotted as `packer-<name>-<fingerprint>`.
+	// The fingerprint hashes the name, the source image and the commands:
+	// the later builds with the same fingerprint create the boot disk from
+	// the snapshot instead of the source image, and skip the commands.
+	//
+	// The snapshot of the running instance is crash-consistent, the
+	// commands should flush their writes, e.g. with `sync`. The snapshots
+	// are kept by Packer; delete one to run its phase again. Must be made of
+	// lowercase letters, digits and dashes, and start with a letter. Cannot
+	// be used with `disk_encryption_key`.
+	CheckpointName string `mapstructure:"checkpoint_name" required:"false"`
+	// The shell commands of the checkpoint phase, run with the communicator
+	// one after the other. The phase fails if one of them exits with a
+	// non-zero status. Required with `checkpoint_name`.
+	CheckpointInline []string `mapstructure:"checkpoint_inline" required:"false"`
 	// Sets a node affinity label for the launched instance (eg. for sole tenancy).
+	// Several `node_affinity` blocks can be set, the instance is placed on a
+	// node meeting all of them.
 	// Please see [Provisioning VMs on
 	// sole-tenant nodes](https://cloud.google.com/compute/docs/nodes/provisioning-sole-tenant-vms)
 	// for more information.
@@ -227,6 +368,56 @@ type Config struct {
 	NodeAffinities []common.NodeAffinity `mapstructure:"node_affinity" required:"false"`
 	// The time to wait for instance state changes. Defaults to "5m".
 	StateTimeout time.Duration `mapstructure:"state_timeout" required:"false"`
+	// The minimum time to wait between two polls of a long-running operation,
+	// such as an image creation. Defaults to "2s".
+	OperationPollMinInterval time.Duration `mapstructure:"operation_poll_min_interval" required:"false"`
+	// The maximum time to wait between two polls of a long-running operation.
+	// The interval doubles after each poll, with some jitter, from
+	// `operation_poll_min_interval` up to this value. Defaults to "30s".
+	OperationPollMaxInterval time.Duration `mapstructure:"operation_poll_max_interval" required:"false"`
+	// If true, check the region and project quotas before creating any
+	// resource, and fail early if the CPUs, disk space or in-use IP
+	// addresses needed by the build are not available. Defaults to `false`.
+	QuotaPrecheck bool `mapstructure:"quota_precheck" required:"false"`
+	// If true, check that the account used by Packer has the IAM permissions
+	// needed by the configured features, e.g. the IAP tunnel, OS Login or the
+	// image creation, before creating any resource. The result of each check
+	// is printed, and the build fails early if any is missing. Defaults to
+	// `false`.
+	//
+	// The permissions are tested on the project only: permissions granted on
+	// individual resources, e.g. on a subnetwork of a shared VPC, are reported
+	// as missing.
+	PermissionsPrecheck bool `mapstructure:"permissions_precheck" required:"false"`
+	// If true, only run the preflight checks, without creating anything: the
+	// credentials, the IAM permissions, the firewall rules letting the
+	// communicator reach the instance, Private Google Access on the
+	// subnetwork when the instance has no external IP, and the quotas. The
+	// results are printed as a table, and the build fails if any check
+	// fails. No artifact is produced. Defaults to `false`.
+	PreflightOnly bool `mapstructure:"preflight_only" required:"false"`
+	// The path of a file the provenance of the image is written to, as an
+	// [in-toto](https://in-toto.io) statement with a [SLSA
+	// provenance](https://slsa.dev/spec/v1.0/provenance) predicate. It
+	// records the digest of the builder configuration, the source image, the
+	// start and end times of the build, and the IDs of the Compute Engine
+	// operations it waited for. The statement is also available to the
+	// post-processors, e.g. `googlecompute-catalog` can upload it next to the
+	// image descriptor.
+	ProvenanceFile string `mapstructure:"provenance_file" required:"false"`
+	// Files rendered with the attributes of the new image at the end of the
+	// build, e.g. to hand it off to a deployment repository:
+	//
+	// ```hcl
+	// artifact_template {
+	//   content     = "image = \"{{ .ImageSelfLink }}\"\n"
+	//   destination = "deploy/image.auto.tfvars"
+	// }
+	// ```
+	//
+	// Refer to the [Artifact templates](#artifact-templates) section for more
+	// information.
+	ArtifactTemplates []ArtifactTemplate `mapstructure:"artifact_template" required:"false"`
 	// The region in which to launch the instance. Defaults to the region
 	// hosting the specified zone.
 	Region string `mapstructure:"region" required:"false"`
@@ -251,12 +442,30 @@ type Config struct {
 	// Example: "debian-8-jessie-v20161027"
 	SourceImage string `mapstructure:"source_image" required:"true"`
 	// The source image family to use to create the new image from. The image
-	// family always returns its latest image that is not deprecated. Example:
-	// "debian-8".
+	// family always returns its latest image that is not deprecated. The
+	// family is resolved once per run of Packer: the builds from the same
+	// family start from the same image, recorded as `SourceImageName`.
+	// The image is shared through the `packer-googlecompute-<run ID>`
+	// directory of the system temporary directory, e.g. `/tmp`, which the
+	// later runs remove once it is a day old.
+	// Example: "debian-8".
 	SourceImageFamily string `mapstructure:"source_image_family" required:"true"`
 	// A list of project IDs to search for the source image. Packer will search the first
 	// project ID in the list first, and fall back to the next in the list, until it finds the source image.
 	SourceImageProjectId []string `mapstructure:"source_image_project_id" required:"false"`
+	// The key decrypting the source image, if it is encrypted with a
+	// customer-supplied key, with `rawKey` or `rsa_encrypted_key`. The build
+	// fails early if the source image needs a key and none, or a raw key not
+	// matching it, is set.
+	//
+	//  ```hcl
+	//   source_image_encryption_key {
+	//     rawKey = "SGVsbG8gZnJvbSBHb29nbGUgQ2xvdWQgUGxhdGZvcm0="
+	//   }
+	//  ```
+	//
+	// Refer to the [Customer Encryption Key](#customer-encryption-key) section for more information on the contents of this block.
+	SourceImageEncryptionKey *common.CustomerEncryptionKey `mapstructure:"source_image_encryption_key" required:"false"`
 	// The path to a startup script to run on the launched instance from which the image will
 	// be made. When set, the contents of the startup script file will be added to the instance metadata
 	// under the `"startup_script"` metadata property. See [Providing startup script contents directly](https://cloud.google.com/compute/docs/startupscript#providing_startup_script_contents_directly) for more details.
@@ -268,6 +477,28 @@ type Config struct {
 	StartupScriptFile string `mapstructure:"startup_script_file" required:"false"`
 	// The time to wait for windows password to be retrieved. Defaults to "3m".
 	WindowsPasswordTimeout time.Duration `mapstructure:"windows_password_timeout" required:"false"`
+	// The time to wait for a Windows instance connected to with WinRM to
+	// report that it is ready, before creating its password and connecting to
+	// it. The instance reports it through a guest attribute, once the guest
+	// agent runs its startup scripts. The build goes on after this time even
+	// if it did not. Defaults to "10m".
+	WindowsReadyTimeout time.Duration `mapstructure:"windows_ready_timeout" required:"false"`
+	// If true, a Windows startup script generates a self-signed certificate
+	// and replaces the plain-HTTP WinRM listener by an HTTPS one using it.
+	// The certificate is published through guest attributes, and the WinRM
+	// communicator only trusts that certificate. Sets `winrm_use_ssl`;
+	// `winrm_insecure` is not needed. Requires the `winrm` communicator.
+	WinRMHTTPSBootstrap bool `mapstructure:"winrm_https_bootstrap" required:"false"`
+	// The Active Directory domain of `winrm_username`, for images joining a
+	// domain before Packer connects, e.g. from their startup script, whose
+	// local accounts are no longer allowed to log in. The user authenticates
+	// as `<winrm_domain>\<winrm_username>` with NTLM, and `winrm_password`
+	// is required since the generated password is a local one.
+	WinRMDomain string `mapstructure:"winrm_domain" required:"false"`
+	// The WinRM authentication, `basic` or `ntlm`. Defaults to `ntlm` with
+	// `winrm_domain` or `winrm_use_ntlm`, `basic` otherwise. Kerberos is not
+	// supported by the WinRM client, NTLM authenticates domain users as well.
+	WinRMTransport string `mapstructure:"winrm_transport" required:"false"`
 	// For backwards compatibility this option defaults to `"true"` in the future it will default to `"false"`.
 	// If "true", the contents of `startup_script_file` or `"startup_script"` in the instance metadata
 	// is wrapped in a Packer specific script that tracks the execution and completion of the provided
@@ -275,6 +506,15 @@ type Config struct {
 	// - The use of the wrapped script file requires that the user or service account
 	// running the build has the compute.instance.Metadata role.
 	WrapStartupScriptFile config.Trilean `mapstructure:"wrap_startup_script" required:"false"`
+	// The path of a script replacing the Packer wrapper of the startup
+	// script, for images whose own orchestration conflicts with it. The
+	// script is run as the `startup-script` of the instance, with the
+	// wrapped startup script in the `packer-wrapped-startup-script` metadata,
+	// and must set the `startup-script-status` metadata of the instance to
+	// `done` or `error` once it finishes, as the build waits for it. Sets
+	// `wrap_startup_script` to true, set it to false instead to run the
+	// startup script as is, without waiting for it.
+	StartupScriptWrapperFile string `mapstructure:"startup_script_wrapper_file" required:"false"`
 	// The Google Compute subnetwork id or URL to use for the launched
 	// instance. Only required if the network has been created with custom
 	// subnetting. Note, the region of the subnetwork must match the region or
@@ -285,8 +525,16 @@ type Config struct {
 	// Assign network tags to apply firewall rules to VM instance.
 	Tags []string `mapstructure:"tags" required:"false"`
 	// If true, use the instance's internal IP instead of its external IP
-	// during building.
+	// during building. Set to true when connecting through a
+	// `ssh_bastion_host`, which together with `omit_external_ip` lets builds
+	// reach instances without an external IP when IAP isn't allowed.
 	UseInternalIP bool `mapstructure:"use_internal_ip" required:"false"`
+	// The URL of the proxy to dial the SSH connection to the instance
+	// through, e.g. `socks5://proxy.example.com:1080`, or
+	// `http://proxy.example.com:3128` for an HTTP proxy supporting the
+	// CONNECT method. Credentials can be set in the URL. Defaults to
+	// `proxy_url` when it is a `socks5` proxy.
+	SSHProxyURL string `mapstructure:"ssh_proxy_url" required:"false"`
 	// If true, OSLogin will be used to manage SSH access to the compute instance by
 	// dynamically importing a temporary SSH key to the Google account's login profile,
 	// and setting the `enable-oslogin` to `TRUE` in the instance metadata.
@@ -331,6 +579,43 @@ type Config struct {
 	//    fingerprint: 000000000000000000000000000000000000000000000000000000000000000a
 	//```
 	UseOSLogin bool `mapstructure:"use_os_login" required:"false"`
+	// If true, authenticate with the keys loaded in the local SSH agent, which
+	// must already be registered through OS Login or the project metadata.
+	// No temporary key pair is generated, and no key is added to the instance
+	// metadata or imported to the OS Login profile. Sets `ssh_agent_auth`.
+	UseSSHAgent bool `mapstructure:"use_ssh_agent" required:"false"`
+	// If true, fall back to the interactive serial console
+	// (ssh-serialport.googleapis.com) when no SSH connection to the instance
+	// can be established within `serial_port_fallback_timeout`, e.g. in a
+	// subnet without any network path from Packer nor IAP. A startup script
+	// serves a root shell on the second serial port, through which the
+	// provisioners run their commands and transfer their files.
+	//
+	// The serial console is slow, about 10KB/s, the output of the commands is
+	// only shown once they exit, and the serial port access must not be
+	// disabled by the `compute.disableSerialPortAccess` organization policy.
+	// Linux only, requires the ssh communicator.
+	SerialPortFallback bool `mapstructure:"serial_port_fallback" required:"false"`
+	// The time to wait for the SSH connection before falling back to the
+	// serial console. Defaults to `5m`.
+	SerialPortFallbackTimeout time.Duration `mapstructure:"serial_port_fallback_timeout" required:"false"`
+	// Regular expressions which abort the build as soon as a line of the
+	// serial port output of the instance matches one of them, instead of
+	// waiting for the communicator or the provisioners to time out, e.g.
+	// `["Kernel panic", "cloud-init.*failed"]`. The output is checked every
+	// few seconds, until the instance is deleted. The serial port logging
+	// must not be disabled by the `compute.disableSerialPortLogging`
+	// organization policy.
+	SerialOutputFailurePatterns []string `mapstructure:"serial_output_failure_patterns" required:"false"`
+	// If true, do not re-establish the SSH connection when it is lost, e.g.
+	// when a provisioner reboots the instance or the host goes through
+	// maintenance. By default, the failing operations are retried up to
+	// `ssh_handshake_attempts` times, 10 when unset, and `ssh_read_write_timeout`
+	// defaults to `1m` so that a connection to a rebooted instance is detected
+	// as dead; the keepalives keep the connection active during long commands.
+	// A command interrupted by the disconnection is not run again, set
+	// `expect_disconnect` on the provisioner which reboots.
+	DisableSSHReconnect bool `mapstructure:"disable_ssh_reconnect" required:"false"`
 	// The time to wait between the creation of the instance used to create the image,
 	// and the addition of SSH configuration, including SSH keys, to that instance.
 	// The delay is intended to protect packer from anything in the instance boot
@@ -338,36 +623,56 @@ type Config struct {
 	// (e.g. SSH user creation, SSH key creation) on the instance.
 	// Note: All other instance metadata, including startup scripts, are still added to the instance
 	// during it's creation.
+	// The wait is skipped when there is no SSH key to add to the metadata, or
+	// with `use_os_login`, as the key is imported in the login profile
+	// instead.
 	// Example value: `5m`.
 	WaitToAddSSHKeys time.Duration `mapstructure:"wait_to_add_ssh_keys"`
 	// The zone in which to launch the instance used to create the image.
 	// Example: "us-central1-a"
 	Zone string `mapstructure:"zone" required:"true"`
 
-	ctx                interpolate.Context
-	imageSourceDisk    string
-	imageAlreadyExists bool
+	ctx                         interpolate.Context
+	imageSourceDisk             string
+	imageAlreadyExists          bool
+	serialOutputFailurePatterns []*regexp.Regexp
 }
 
 func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
+	if err := c.Decode(c, raws...); err != nil {
+		return nil, err
+	}
+	return c.Validate()
+}
+
+// Decode decodes raws into target, c or a configuration embedding it, with
+// the template context of c.
+func (c *Config) Decode(target interface{}, raws ...interface{}) error {
 	c.ctx.Funcs = TemplateFuncs
-	err := config.Decode(c, &config.DecodeOpts{
+	return config.Decode(target, &config.DecodeOpts{
 		PluginType:         BuilderId,
 		Interpolate:        true,
 		InterpolateContext: &c.ctx,
 		InterpolateFilter: &interpolate.RenderFilter{
 			Exclude: []string{
 				"run_command",
+				"artifact_template",
 			},
 		},
 	}, raws...)
-	if err != nil {
-		return nil, err
-	}
+}
 
+// Validate sets the defaults of the decoded configuration, and validates
+// it.
+func (c *Config) Validate() ([]string, error) {
 	var warnings []string
 	var errs error
 
+	// The nested blocks set the flat keys, which the build uses.
+	if es := append(c.applyNetworkInterface(), c.applyBootDisk()...); len(es) > 0 {
+		errs = packersdk.MultiErrorAppend(errs, es...)
+	}
+
 	for i, bd := range c.ExtraBlockDevices {
 		err := bd.Prepare()
 		if err != nil {
@@ -416,10 +721,43 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 		errs = packersdk.MultiErrorAppend(errs,
 			errors.New("on_host_maintenance must be TERMINATE when using preemptible instances."))
 	}
+
+	c.ProvisioningModel = strings.ToUpper(c.ProvisioningModel)
+	switch c.ProvisioningModel {
+	case "", "STANDARD", "SPOT":
+	default:
+		errs = packersdk.MultiErrorAppend(errs,
+			errors.New("provisioning_model must be one of STANDARD or SPOT."))
+	}
+
+	if c.ProvisioningModel != "" && c.Preemptible {
+		errs = packersdk.MultiErrorAppend(errs,
+			errors.New("provisioning_model cannot be used with preemptible instances, use \"SPOT\" instead."))
+	}
+
+	if c.PreemptionMaxRestarts < 0 {
+		errs = packersdk.MultiErrorAppend(errs,
+			errors.New("preemption_max_restarts must be positive"))
+	}
+	if c.PreemptionMaxRestarts > 0 && !c.Preemptible && c.ProvisioningModel != "SPOT" {
+		errs = packersdk.MultiErrorAppend(errs,
+			errors.New("preemption_max_restarts needs a SPOT provisioning_model or a preemptible instance"))
+	}
+
+	if c.OnHostMaintenance == "MIGRATE" && c.ProvisioningModel == "SPOT" {
+		errs = packersdk.MultiErrorAppend(errs,
+			errors.New("on_host_maintenance must be TERMINATE when using spot instances."))
+	}
+
+	if c.SpotFallback && c.ProvisioningModel != "SPOT" {
+		errs = packersdk.MultiErrorAppend(errs,
+			errors.New("spot_fallback can only be enabled when provisioning_model is SPOT."))
+	}
+
 	// Setting OnHostMaintenance Correct Defaults
 	//   "MIGRATE" : Possible and default if Preemptible is false
-	//   "TERMINATE": Required if Preemptible is true
-	if c.Preemptible {
+	//   "TERMINATE": Required if Preemptible is true or ProvisioningModel is SPOT
+	if c.Preemptible || c.ProvisioningModel == "SPOT" {
 		c.OnHostMaintenance = "TERMINATE"
 	} else {
 		if c.OnHostMaintenance == "" {
@@ -433,6 +771,35 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 			errors.New("on_host_maintenance must be one of MIGRATE or TERMINATE."))
 	}
 
+	affinitiesValid := true
+	for i := range c.NodeAffinities {
+		for _, err := range c.NodeAffinities[i].Prepare() {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("node_affinity %d: %s", i+1, err))
+			affinitiesValid = false
+		}
+	}
+	if affinitiesValid {
+		for _, err := range common.CheckNodeAffinities(c.NodeAffinities) {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("node_affinity: %s", err))
+		}
+	}
+
+	for _, key := range []struct {
+		name string
+		key  *common.CustomerEncryptionKey
+	}{
+		{"disk_encryption_key", c.DiskEncryptionKey},
+		{"image_encryption_key", c.ImageEncryptionKey},
+		{"source_image_encryption_key", c.SourceImageEncryptionKey},
+	} {
+		if key.key == nil {
+			continue
+		}
+		for _, err := range key.key.Prepare() {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("%s: %s", key.name, err))
+		}
+	}
+
 	if c.ImageName == "" {
 		img, err := interpolate.Render("packer-{{timestamp}}", nil)
 		if err != nil {
@@ -451,7 +818,7 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 			errors.New("Invalid image name: Must not be longer than 63 characters"))
 	}
 
-	if !validImageName.MatchString(c.ImageName) {
+	if !common.ValidImageName.MatchString(c.ImageName) {
 		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf(imageErrorText, "name", c.ImageName))
 	}
 
@@ -461,16 +828,71 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 	}
 
 	if c.ImageFamily != "" {
-		if !validImageName.MatchString(c.ImageFamily) {
+		if !common.ValidImageName.MatchString(c.ImageFamily) {
 			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf(imageErrorText, "family", c.ImageFamily))
 		}
 	}
 
+	secureBootFiles := append([]string{}, c.ImageKeyExchangeKey...)
+	secureBootFiles = append(secureBootFiles, c.ImageSignaturesDB...)
+	secureBootFiles = append(secureBootFiles, c.ImageForbiddenSignaturesDB...)
+	if c.ImagePlatformKey != "" {
+		secureBootFiles = append(secureBootFiles, c.ImagePlatformKey)
+	}
+	for _, path := range secureBootFiles {
+		if _, err := os.Stat(path); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("Invalid Secure Boot key: %s", err))
+		}
+	}
+
 	if len(c.ImageStorageLocations) > 1 {
 		errs = packersdk.MultiErrorAppend(errs,
 			errors.New("Invalid image storage locations: Must not have more than 1 region"))
 	}
 
+	for _, location := range c.ImageReplicaLocations {
+		name := imageReplicaName(c.ImageName, location)
+		if len(name) > 63 || !common.ValidImageName.MatchString(name) {
+			errs = packersdk.MultiErrorAppend(errs,
+				fmt.Errorf("Invalid image replica location %q: the replica would be named %q, which is not a valid image name", location, name))
+		}
+	}
+	if c.ImageReplicationParallelism == 0 {
+		c.ImageReplicationParallelism = 4
+	}
+	if c.ImageReadyTimeout == 0 {
+		c.ImageReadyTimeout = 30 * time.Minute
+	}
+	if c.WaitImageReady && c.SkipCreateImage {
+		errs = packersdk.MultiErrorAppend(errs,
+			errors.New("wait_image_ready cannot be used with skip_create_image"))
+	}
+	if c.ImageReplicationParallelism < 0 {
+		errs = packersdk.MultiErrorAppend(errs,
+			errors.New("image_replication_parallelism must be positive"))
+	}
+	if c.DeprecateFamilyImages && c.ImageFamily == "" {
+		errs = packersdk.MultiErrorAppend(errs,
+			errors.New("deprecate_family_images requires image_family"))
+	}
+	if (c.DeprecateFamilyImages || len(c.DeprecateImages) > 0) && c.SkipCreateImage {
+		errs = packersdk.MultiErrorAppend(errs,
+			errors.New("deprecate_family_images and deprecate_images cannot be used with skip_create_image"))
+	}
+	if len(c.ImageReplicaLocations) > 0 && c.SkipCreateImage {
+		errs = packersdk.MultiErrorAppend(errs,
+			errors.New("image_replica_locations cannot be used with skip_create_image"))
+	}
+	if c.ForceCreateImage {
+		if c.SkipCreateImage {
+			errs = packersdk.MultiErrorAppend(errs,
+				errors.New("force_create_image cannot be used with skip_create_image"))
+		}
+		warnings = append(warnings, "force_create_image is set: the image is created "+
+			"while the instance runs, and is only crash-consistent. Data not flushed "+
+			"to the disk by the guest is lost.")
+	}
+
 	if c.InstanceName == "" {
 		c.InstanceName = fmt.Sprintf("packer-%s", uuid.TimeOrderedUUID())
 	}
@@ -503,23 +925,144 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 		c.StateTimeout = 5 * time.Minute
 	}
 
+	if c.ImageChecksumTimeout == 0 {
+		c.ImageChecksumTimeout = 30 * time.Minute
+	}
+
+	if c.OperationPollMinInterval == 0 {
+		c.OperationPollMinInterval = common.DefaultPollMinInterval
+	}
+
+	if c.OperationPollMaxInterval == 0 {
+		c.OperationPollMaxInterval = common.DefaultPollMaxInterval
+	}
+
+	if c.OperationPollMaxInterval < c.OperationPollMinInterval {
+		errs = packersdk.MultiErrorAppend(errs,
+			fmt.Errorf("operation_poll_max_interval (%s) cannot be lower than operation_poll_min_interval (%s)",
+				c.OperationPollMaxInterval, c.OperationPollMinInterval))
+	}
+
+	if c.ProvenanceFile != "" && c.SkipCreateImage {
+		errs = packersdk.MultiErrorAppend(errs,
+			errors.New("provenance_file cannot be used with skip_create_image, as no image is created"))
+	}
+
+	for i := range c.ArtifactTemplates {
+		if es := c.ArtifactTemplates[i].Prepare(&c.ctx); len(es) > 0 {
+			errs = packersdk.MultiErrorAppend(errs, es...)
+		}
+	}
+
+	if c.WinRMHTTPSBootstrap {
+		if c.Comm.Type != "winrm" {
+			errs = packersdk.MultiErrorAppend(errs,
+				errors.New("winrm_https_bootstrap requires the winrm communicator"))
+		}
+		c.Comm.WinRMUseSSL = true
+		c.Comm.WinRMInsecure = false
+	}
+
+	if c.WinRMDomain != "" || c.WinRMTransport != "" {
+		if c.Comm.Type != "winrm" {
+			errs = packersdk.MultiErrorAppend(errs,
+				errors.New("winrm_domain and winrm_transport require the winrm communicator"))
+		}
+	}
+	if c.WinRMTransport == "" && (c.WinRMDomain != "" || c.Comm.WinRMUseNTLM) {
+		c.WinRMTransport = "ntlm"
+	}
+	switch c.WinRMTransport {
+	case "", "basic":
+		if c.WinRMDomain != "" {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("winrm_domain requires the ntlm winrm_transport"))
+		}
+		if c.Comm.WinRMUseNTLM {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("winrm_use_ntlm cannot be used with the basic winrm_transport"))
+		}
+	case "ntlm":
+		c.Comm.WinRMUseNTLM = true
+	case "kerberos":
+		errs = packersdk.MultiErrorAppend(errs,
+			errors.New("the kerberos winrm_transport is not supported, use ntlm to authenticate domain users"))
+	default:
+		errs = packersdk.MultiErrorAppend(errs,
+			fmt.Errorf("winrm_transport must be basic or ntlm, got %q", c.WinRMTransport))
+	}
+	if c.WinRMDomain != "" && c.Comm.WinRMPassword == "" {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("winrm_domain requires winrm_password"))
+	}
+
+	if c.UseSSHAgent {
+		// The communicator defaults to ssh.
+		if c.Comm.Type != "" && c.Comm.Type != "ssh" {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("use_ssh_agent requires the ssh communicator"))
+		}
+		if c.Comm.SSHPrivateKeyFile != "" {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("use_ssh_agent cannot be used with ssh_private_key_file"))
+		}
+		c.Comm.SSHAgentAuth = true
+	}
+
+	for _, pattern := range c.SerialOutputFailurePatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			errs = packersdk.MultiErrorAppend(errs,
+				fmt.Errorf("Invalid serial_output_failure_patterns %q: %s", pattern, err))
+			continue
+		}
+		c.serialOutputFailurePatterns = append(c.serialOutputFailurePatterns, re)
+	}
+
+	if c.SerialPortFallback {
+		if c.Comm.Type != "" && c.Comm.Type != "ssh" {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("serial_port_fallback requires the ssh communicator"))
+		}
+		if c.UseSSHAgent {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("serial_port_fallback cannot be used with use_ssh_agent"))
+		}
+		if c.SerialPortFallbackTimeout == 0 {
+			c.SerialPortFallbackTimeout = 5 * time.Minute
+		}
+	}
+
+	// Hardened images may refuse RSA keys signed with SHA-1, so the
+	// temporary key pair is an ed25519 one unless told otherwise. Setting
+	// only the bits still asks for an RSA key, as it always did.
+	switch c.Comm.SSHTemporaryKeyPairType {
+	case "":
+		c.Comm.SSHTemporaryKeyPairType = "ed25519"
+		if c.Comm.SSHTemporaryKeyPairBits != 0 {
+			c.Comm.SSHTemporaryKeyPairType = "rsa"
+		}
+	case "ed25519", "rsa", "ecdsa":
+	default:
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf(
+			"temporary_key_pair_type must be one of ed25519, rsa or ecdsa, got %q", c.Comm.SSHTemporaryKeyPairType))
+	}
+	if c.Comm.SSHTemporaryKeyPairType == "ed25519" && c.Comm.SSHTemporaryKeyPairBits != 0 {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("temporary_key_pair_bits cannot be set for ed25519 keys"))
+	}
+
 	// Set up communicator
 	if es := c.Comm.Prepare(&c.ctx); len(es) > 0 {
 		errs = packersdk.MultiErrorAppend(errs, es...)
 	}
 
-	// set defaults for IAP
-	if c.IAPConfig.IAPHashBang == "" {
-		if runtime.GOOS == "windows" {
-			c.IAPConfig.IAPHashBang = ""
-		} else {
-			c.IAPConfig.IAPHashBang = "/bin/sh"
-		}
+	if es := c.HTTPConfig.Prepare(&c.ctx); len(es) > 0 {
+		errs = packersdk.MultiErrorAppend(errs, es...)
 	}
-	if c.IAPConfig.IAPExt == "" {
-		if runtime.GOOS == "windows" {
-			c.IAPConfig.IAPExt = ".cmd"
-		}
+
+	// Detect the connections to a rebooted instance, the keepalives keep
+	// them busy otherwise.
+	if c.Comm.Type == "ssh" && !c.DisableSSHReconnect && c.Comm.SSHReadWriteTimeout == 0 && c.Comm.SSHKeepAliveInterval > 0 {
+		c.Comm.SSHReadWriteTimeout = time.Minute
+	}
+
+	// set defaults for IAP
+	if c.IAPConfig.IAPHashBang != "" || c.IAPConfig.IAPExt != "" {
+		warnings = append(warnings, "iap_hashbang and iap_ext are deprecated and ignored: "+
+			"the IAP tunnel no longer runs gcloud through a script.")
 	}
 	if c.IAPConfig.IAPTunnelLaunchWait == 0 {
 		if c.Comm.Type == "winrm" {
@@ -531,6 +1074,26 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 		}
 	}
 
+	if c.IAPConfig.IAPImpersonateServiceAccount != "" && !c.IAPConfig.IAP {
+		errs = packersdk.MultiErrorAppend(errs,
+			errors.New("iap_impersonate_service_account requires use_iap"))
+	}
+	if len(c.IAPConfig.IAPPortForwards) > 0 && !c.IAPConfig.IAP {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("iap_port_forward requires use_iap"))
+	}
+	remotePorts := map[int]bool{}
+	for _, forward := range c.IAPConfig.IAPPortForwards {
+		if forward.RemotePort < 1 || forward.RemotePort > 65535 {
+			errs = packersdk.MultiErrorAppend(errs,
+				fmt.Errorf("iap_port_forward: invalid remote_port %d", forward.RemotePort))
+		}
+		if remotePorts[forward.RemotePort] {
+			errs = packersdk.MultiErrorAppend(errs,
+				fmt.Errorf("iap_port_forward: remote_port %d is forwarded twice", forward.RemotePort))
+		}
+		remotePorts[forward.RemotePort] = true
+	}
+
 	// Configure IAP: Update SSH config to use localhost proxy instead
 	if c.IAPConfig.IAP {
 		if !SupportsIAPTunnel(&c.Comm) {
@@ -546,6 +1109,16 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 		c.Comm.WinRMHost = "localhost"
 	}
 
+	// A bastion reaches the instance within its network, so there is no need
+	// for an external IP.
+	if c.Comm.Type == "ssh" && c.Comm.SSHBastionHost != "" {
+		if c.IAPConfig.IAP {
+			errs = packersdk.MultiErrorAppend(errs,
+				errors.New("ssh_bastion_host cannot be used with use_iap"))
+		}
+		c.UseInternalIP = true
+	}
+
 	// Process required parameters.
 	if c.ProjectId == "" {
 		errs = packersdk.MultiErrorAppend(
@@ -583,6 +1156,34 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 		warnings = append(warnings, warns...)
 	}
 
+	if c.SSHProxyURL != "" {
+		u, err := common.ParseProxyURL(c.SSHProxyURL)
+		switch {
+		case err != nil:
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("ssh_proxy_url: %s", err))
+		case c.Comm.Type != "ssh":
+			errs = packersdk.MultiErrorAppend(errs, errors.New("ssh_proxy_url requires the ssh communicator"))
+		case c.IAPConfig.IAP || c.Comm.SSHProxyHost != "" || c.Comm.SSHBastionHost != "":
+			errs = packersdk.MultiErrorAppend(errs,
+				errors.New("ssh_proxy_url cannot be used with use_iap, ssh_proxy_host or ssh_bastion_host"))
+		case u.Scheme == "socks5":
+			applySSHProxy(&c.Comm, u)
+		default:
+			// The communicator only supports SOCKS5 proxies, so it connects
+			// to a local tunnel through the HTTP proxy instead, see
+			// StepStartSSHProxy.
+			c.Comm.SSHHost = "localhost"
+		}
+	} else if c.ProxyURL != "" && c.Comm.Type == "ssh" && !c.IAPConfig.IAP &&
+		c.Comm.SSHProxyHost == "" && c.Comm.SSHBastionHost == "" {
+		// SSH goes through a SOCKS5 proxy_url, unless told otherwise. With
+		// IAP, the SSH connection is local and the tunnel uses the proxy
+		// instead.
+		if u, err := common.ParseProxyURL(c.ProxyURL); err == nil && u.Scheme == "socks5" {
+			applySSHProxy(&c.Comm, u)
+		}
+	}
+
 	if c.OmitExternalIP && c.Address != "" {
 		errs = packersdk.MultiErrorAppend(fmt.Errorf("you can not specify an external address when 'omit_external_ip' is true"))
 	}
@@ -591,6 +1192,12 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 		errs = packersdk.MultiErrorAppend(fmt.Errorf("'use_internal_ip' must be true if 'omit_external_ip' is true"))
 	}
 
+	// Private Google Access only applies to instances without an external IP.
+	if c.GoogleAccess != "" && !c.OmitExternalIP {
+		warnings = append(warnings, "google_access is set but omit_external_ip is not: "+
+			"the instance reaches the Google APIs through its external IP")
+	}
+
 	if c.AcceleratorCount > 0 && len(c.AcceleratorType) == 0 {
 		errs = packersdk.MultiErrorAppend(fmt.Errorf("'accelerator_type' must be set when 'accelerator_count' is more than 0"))
 	}
@@ -604,6 +1211,17 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 		errs = packersdk.MultiErrorAppend(fmt.Errorf("you may not specify a 'service_account_email' when 'disable_default_service_account' is true"))
 	}
 
+	if c.StartupScriptWrapperFile != "" {
+		if _, err := os.Stat(c.StartupScriptWrapperFile); err != nil {
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("startup_script_wrapper_file: %v", err))
+		}
+		if c.WrapStartupScriptFile.False() {
+			errs = packersdk.MultiErrorAppend(errs,
+				errors.New("startup_script_wrapper_file cannot be used with wrap_startup_script set to false"))
+		}
+		c.WrapStartupScriptFile = config.TriTrue
+	}
 	if c.StartupScriptFile != "" {
 		if _, err := os.Stat(c.StartupScriptFile); err != nil {
 			errs = packersdk.MultiErrorAppend(
@@ -618,6 +1236,42 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 	if c.WindowsPasswordTimeout == 0 {
 		c.WindowsPasswordTimeout = 3 * time.Minute
 	}
+	if c.BulkInsertWindow == 0 {
+		c.BulkInsertWindow = 15 * time.Second
+	}
+	if c.WarmPoolSize < 0 {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("warm_pool_size must be positive"))
+	}
+	if c.WarmPoolSize > 0 {
+		if len(c.ExtraBlockDevices) > 0 {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("warm_pool_size cannot be used with disk_attachment"))
+		}
+		if c.Address != "" {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("warm_pool_size cannot be used with address"))
+		}
+		if c.BulkInsert {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("warm_pool_size cannot be used with bulk_insert"))
+		}
+	}
+	if c.CheckpointName != "" {
+		if !validCheckpointName.MatchString(c.CheckpointName) {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("checkpoint_name %q must be made of at most 40 lowercase letters, digits and dashes, starting with a letter", c.CheckpointName))
+		}
+		if len(c.CheckpointInline) == 0 {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("checkpoint_inline is required with checkpoint_name"))
+		}
+		if c.Comm.Type == "none" {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("checkpoint_name requires a communicator"))
+		}
+		if c.DiskEncryptionKey != nil {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("checkpoint_name cannot be used with disk_encryption_key"))
+		}
+	} else if len(c.CheckpointInline) > 0 {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("checkpoint_inline requires checkpoint_name"))
+	}
+	if c.WindowsReadyTimeout == 0 {
+		c.WindowsReadyTimeout = 10 * time.Minute
+	}
 
 	return warnings, errs
 }
@@ -631,6 +1285,17 @@ func SupportsIAPTunnel(c *communicator.Config) bool {
 	}
 }
 
+// applySSHProxy makes the SSH communicator dial through the SOCKS5 proxy u.
+func applySSHProxy(c *communicator.Config, u *url.URL) {
+	host, port := common.ProxyHostPort(u)
+	c.SSHProxyHost = host
+	c.SSHProxyPort, _ = strconv.Atoi(port)
+	if u.User != nil {
+		c.SSHProxyUsername = u.User.Username()
+		c.SSHProxyPassword, _ = u.User.Password()
+	}
+}
+
 func ApplyIAPTunnel(c *communicator.Config, port int) error {
 	switch c.Type {
 	case "ssh":
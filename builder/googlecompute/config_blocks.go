@@ -0,0 +1,123 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type NetworkInterface,BootDisk
+
+package googlecompute
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+)
+
+// NetworkInterface groups the network settings of the build instance. Each
+// of them can also be set with the flat key named after it, but not both.
+type NetworkInterface struct {
+	// The Google Compute network id or URL, like `network`.
+	Network string `mapstructure:"network" required:"false"`
+	// The Google Compute subnetwork id or URL, like `subnetwork`.
+	Subnetwork string `mapstructure:"subnetwork" required:"false"`
+	// The project of the network and subnetwork, like `network_project_id`.
+	ProjectId string `mapstructure:"project_id" required:"false"`
+	// The name of a pre-allocated static external IP address, like
+	// `address`.
+	Address string `mapstructure:"address" required:"false"`
+	// If true, the instance will not have an external IP, like
+	// `omit_external_ip`.
+	OmitExternalIP bool `mapstructure:"omit_external_ip" required:"false"`
+	// If true, connect to the internal IP of the instance, like
+	// `use_internal_ip`.
+	UseInternalIP bool `mapstructure:"use_internal_ip" required:"false"`
+}
+
+// BootDisk groups the settings of the boot disk of the build instance.
+// Each of them can also be set with the flat key named after it, but not
+// both.
+type BootDisk struct {
+	// The name of the disk, like `disk_name`.
+	Name string `mapstructure:"name" required:"false"`
+	// The size of the disk in GB, like `disk_size`.
+	SizeGb int64 `mapstructure:"size" required:"false"`
+	// The type of the disk, like `disk_type`.
+	Type string `mapstructure:"type" required:"false"`
+	// The key encrypting the disk, like `disk_encryption_key`.
+	EncryptionKey *common.CustomerEncryptionKey `mapstructure:"encryption_key" required:"false"`
+}
+
+// applyNetworkInterface sets the flat network settings of c from its
+// network_interface block.
+func (c *Config) applyNetworkInterface() []error {
+	ni := c.NetworkInterface
+	if ni == nil {
+		return nil
+	}
+	var errs []error
+	for _, field := range []struct {
+		key, flat string
+		value     string
+		target    *string
+	}{
+		{"network", "network", ni.Network, &c.Network},
+		{"subnetwork", "subnetwork", ni.Subnetwork, &c.Subnetwork},
+		{"project_id", "network_project_id", ni.ProjectId, &c.NetworkProjectId},
+		{"address", "address", ni.Address, &c.Address},
+	} {
+		if err := mergeBlockSetting("network_interface", field.key, field.flat, field.value, field.target); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	c.OmitExternalIP = c.OmitExternalIP || ni.OmitExternalIP
+	c.UseInternalIP = c.UseInternalIP || ni.UseInternalIP
+	return errs
+}
+
+// applyBootDisk sets the flat boot disk settings of c from its boot_disk
+// block.
+func (c *Config) applyBootDisk() []error {
+	bd := c.BootDisk
+	if bd == nil {
+		return nil
+	}
+	var errs []error
+	for _, field := range []struct {
+		key, flat string
+		value     string
+		target    *string
+	}{
+		{"name", "disk_name", bd.Name, &c.DiskName},
+		{"type", "disk_type", bd.Type, &c.DiskType},
+	} {
+		if err := mergeBlockSetting("boot_disk", field.key, field.flat, field.value, field.target); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	switch {
+	case bd.SizeGb != 0 && c.DiskSizeGb != 0:
+		errs = append(errs, errors.New("boot_disk: size cannot be used with disk_size"))
+	case bd.SizeGb != 0:
+		c.DiskSizeGb = bd.SizeGb
+	}
+	switch {
+	case bd.EncryptionKey != nil && c.DiskEncryptionKey != nil:
+		errs = append(errs, errors.New("boot_disk: encryption_key cannot be used with disk_encryption_key"))
+	case bd.EncryptionKey != nil:
+		c.DiskEncryptionKey = bd.EncryptionKey
+	}
+	return errs
+}
+
+// mergeBlockSetting sets target, the flat key of a block setting, to value
+// if set in the block, unless the flat key is set too.
+func mergeBlockSetting(block, key, flat, value string, target *string) error {
+	if value == "" {
+		return nil
+	}
+	if *target != "" {
+		return fmt.Errorf("%s: %s cannot be used with %s", block, key, flat)
+	}
+	*target = value
+	return nil
+}
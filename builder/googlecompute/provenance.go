@@ -0,0 +1,192 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-googlecompute/version"
+)
+
+const (
+	// provenanceStatementType is the type of the in-toto statements.
+	provenanceStatementType = "https://in-toto.io/Statement/v1"
+	// provenancePredicateType is the type of the SLSA provenance predicates.
+	provenancePredicateType = "https://slsa.dev/provenance/v1"
+	// provenanceBuildType describes the parameters of the builds.
+	provenanceBuildType = "https://github.com/hashicorp/packer-plugin-googlecompute/provenance/v1"
+	// provenanceBuilderId identifies the builder producing the images.
+	provenanceBuilderId = "https://github.com/hashicorp/packer-plugin-googlecompute"
+)
+
+// provenanceStatement is an in-toto statement attesting the provenance of
+// the images of a build.
+type provenanceStatement struct {
+	Type          string               `json:"_type"`
+	Subject       []provenanceResource `json:"subject"`
+	PredicateType string               `json:"predicateType"`
+	Predicate     slsaProvenance       `json:"predicate"`
+}
+
+// provenanceResource is a SLSA resource descriptor.
+type provenanceResource struct {
+	Name        string                 `json:"name,omitempty"`
+	URI         string                 `json:"uri,omitempty"`
+	Digest      map[string]string      `json:"digest,omitempty"`
+	Annotations map[string]interface{} `json:"annotations,omitempty"`
+}
+
+type slsaProvenance struct {
+	BuildDefinition slsaBuildDefinition `json:"buildDefinition"`
+	RunDetails      slsaRunDetails      `json:"runDetails"`
+}
+
+type slsaBuildDefinition struct {
+	BuildType            string                 `json:"buildType"`
+	ExternalParameters   map[string]interface{} `json:"externalParameters"`
+	ResolvedDependencies []provenanceResource   `json:"resolvedDependencies,omitempty"`
+}
+
+type slsaRunDetails struct {
+	Builder    slsaBuilder          `json:"builder"`
+	Metadata   slsaBuildMetadata    `json:"metadata"`
+	Byproducts []provenanceResource `json:"byproducts,omitempty"`
+}
+
+type slsaBuilder struct {
+	Id      string            `json:"id"`
+	Version map[string]string `json:"version,omitempty"`
+}
+
+type slsaBuildMetadata struct {
+	InvocationId string `json:"invocationId,omitempty"`
+	StartedOn    string `json:"startedOn"`
+	FinishedOn   string `json:"finishedOn"`
+}
+
+// configDigest returns the SHA-256 digest of the raw builder configuration,
+// or "" if it cannot be encoded. The configuration is only recorded as a
+// digest, as it may hold secrets.
+func configDigest(raws ...interface{}) string {
+	data, err := json.Marshal(raws)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// buildProvenance describes what a build needs to attest the provenance of
+// its images.
+type buildProvenance struct {
+	config       *Config
+	configDigest string
+	// generatedData is the generated data of the build, for the source
+	// image.
+	generatedData map[string]interface{}
+	operations    []common.Operation
+	started       time.Time
+	finished      time.Time
+}
+
+// statement returns the provenance statement of images.
+func (p *buildProvenance) statement(images []*common.Image) *provenanceStatement {
+	subjects := make([]provenanceResource, 0, len(images))
+	for _, image := range images {
+		subjects = append(subjects, provenanceResource{
+			Name:   image.SelfLink,
+			Digest: map[string]string{"gce_image_id": strconv.FormatUint(image.Id, 10)},
+		})
+	}
+
+	params := map[string]interface{}{
+		"project_id":   p.config.ProjectId,
+		"zone":         p.config.Zone,
+		"machine_type": p.config.MachineType,
+	}
+	if p.configDigest != "" {
+		params["config_digest"] = map[string]string{"sha256": p.configDigest}
+	}
+	if p.config.SourceImage != "" {
+		params["source_image"] = p.config.SourceImage
+	}
+	if p.config.SourceImageFamily != "" {
+		params["source_image_family"] = p.config.SourceImageFamily
+	}
+	if len(p.config.SourceImageProjectId) > 0 {
+		params["source_image_project_id"] = p.config.SourceImageProjectId
+	}
+
+	var dependencies []provenanceResource
+	if selfLink, _ := p.generatedData["SourceImageSelfLink"].(string); selfLink != "" {
+		name, _ := p.generatedData["SourceImageName"].(string)
+		dependencies = append(dependencies, provenanceResource{Name: name, URI: selfLink})
+	}
+
+	byproducts := make([]provenanceResource, 0, len(p.operations))
+	for _, op := range p.operations {
+		byproducts = append(byproducts, provenanceResource{
+			Name: op.Name,
+			URI:  op.Target,
+			Annotations: map[string]interface{}{
+				"operation_id":   strconv.FormatUint(op.Id, 10),
+				"operation_type": op.Type,
+				"scope":          op.Scope,
+			},
+		})
+	}
+
+	builderVersion := map[string]string{
+		"packer-plugin-googlecompute": version.PluginVersion.FormattedVersion(),
+	}
+	if p.config.PackerCoreVersion != "" {
+		builderVersion["packer"] = p.config.PackerCoreVersion
+	}
+
+	return &provenanceStatement{
+		Type:          provenanceStatementType,
+		Subject:       subjects,
+		PredicateType: provenancePredicateType,
+		Predicate: slsaProvenance{
+			BuildDefinition: slsaBuildDefinition{
+				BuildType:            provenanceBuildType,
+				ExternalParameters:   params,
+				ResolvedDependencies: dependencies,
+			},
+			RunDetails: slsaRunDetails{
+				Builder: slsaBuilder{
+					Id:      provenanceBuilderId,
+					Version: builderVersion,
+				},
+				Metadata: slsaBuildMetadata{
+					InvocationId: p.config.InstanceName,
+					StartedOn:    p.started.UTC().Format(time.RFC3339),
+					FinishedOn:   p.finished.UTC().Format(time.RFC3339),
+				},
+				Byproducts: byproducts,
+			},
+		},
+	}
+}
+
+// writeProvenance writes the provenance statement data to path.
+func writeProvenance(path string, data []byte) error {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("Error writing the provenance to %s: %s", path, err)
+		}
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("Error writing the provenance to %s: %s", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+)
+
+func TestConfigDigest(t *testing.T) {
+	a := configDigest(map[string]interface{}{"zone": "us-central1-a", "project_id": "project"})
+	b := configDigest(map[string]interface{}{"project_id": "project", "zone": "us-central1-a"})
+	if a == "" || a != b {
+		t.Errorf("Bad: the digest should not depend on the order of the keys: %q != %q", a, b)
+	}
+	if c := configDigest(map[string]interface{}{"zone": "us-central1-b", "project_id": "project"}); c == a {
+		t.Errorf("Bad: different configurations should have different digests")
+	}
+}
+
+func TestBuildProvenance_statement(t *testing.T) {
+	started := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	p := &buildProvenance{
+		config: &Config{
+			ProjectId:         "project",
+			Zone:              "us-central1-a",
+			MachineType:       "e2-standard-2",
+			SourceImageFamily: "debian-12",
+			InstanceName:      "packer-123",
+		},
+		configDigest: "abc",
+		generatedData: map[string]interface{}{
+			"SourceImageName":     "debian-12-bookworm-v20240110",
+			"SourceImageSelfLink": "https://www.googleapis.com/compute/v1/projects/debian-cloud/global/images/debian-12-bookworm-v20240110",
+		},
+		operations: []common.Operation{
+			{Id: 42, Name: "operation-1", Type: "insert", Target: "https://www.googleapis.com/compute/v1/projects/project/global/images/my-image", Scope: "global"},
+		},
+		started:  started,
+		finished: started.Add(10 * time.Minute),
+	}
+	images := []*common.Image{
+		{Id: 1234, Name: "my-image", SelfLink: "https://www.googleapis.com/compute/v1/projects/project/global/images/my-image"},
+	}
+
+	data, err := json.Marshal(p.statement(images))
+	if err != nil {
+		t.Fatalf("Bad: unexpected error %s", err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Bad: unexpected error %s", err)
+	}
+
+	if got["_type"] != provenanceStatementType || got["predicateType"] != provenancePredicateType {
+		t.Errorf("Bad: unexpected statement type %v %v", got["_type"], got["predicateType"])
+	}
+	subject := got["subject"].([]interface{})[0].(map[string]interface{})
+	if subject["name"] != images[0].SelfLink || subject["digest"].(map[string]interface{})["gce_image_id"] != "1234" {
+		t.Errorf("Bad: unexpected subject %v", subject)
+	}
+
+	predicate := got["predicate"].(map[string]interface{})
+	definition := predicate["buildDefinition"].(map[string]interface{})
+	params := definition["externalParameters"].(map[string]interface{})
+	if params["config_digest"].(map[string]interface{})["sha256"] != "abc" || params["source_image_family"] != "debian-12" {
+		t.Errorf("Bad: unexpected parameters %v", params)
+	}
+	dependency := definition["resolvedDependencies"].([]interface{})[0].(map[string]interface{})
+	if dependency["name"] != "debian-12-bookworm-v20240110" {
+		t.Errorf("Bad: unexpected dependency %v", dependency)
+	}
+
+	details := predicate["runDetails"].(map[string]interface{})
+	metadata := details["metadata"].(map[string]interface{})
+	if metadata["startedOn"] != "2024-01-02T03:04:05Z" || metadata["finishedOn"] != "2024-01-02T03:14:05Z" {
+		t.Errorf("Bad: unexpected metadata %v", metadata)
+	}
+	byproduct := details["byproducts"].([]interface{})[0].(map[string]interface{})
+	if byproduct["name"] != "operation-1" || byproduct["annotations"].(map[string]interface{})["operation_id"] != "42" {
+		t.Errorf("Bad: unexpected byproduct %v", byproduct)
+	}
+}
+
+func TestWriteProvenance(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out", "provenance.json")
+	if err := writeProvenance(path, []byte("{}")); err != nil {
+		t.Fatalf("Bad: unexpected error %s", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "{}" {
+		t.Errorf("Bad: unexpected content %q, %v", data, err)
+	}
+}
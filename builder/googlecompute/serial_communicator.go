@@ -0,0 +1,334 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// serialUploadChunkSize is the size of the chunks the uploads are split in,
+// each sent as a command line.
+const serialUploadChunkSize = 32 * 1024
+
+// serialComm is a communicator running the commands in the shell which
+// SerialPortStartupScript serves on a serial port of the instance.
+//
+// Each command is written as a base64 encoded script, and its output sent
+// back base64 encoded between markers once it exits, so that the console
+// does not mangle it. The commands run one at a time.
+type serialComm struct {
+	// busy holds a token from the writing of a command until its output is
+	// read, even when the caller gave up waiting for it, so that two
+	// readers never share stdout.
+	busy   chan struct{}
+	stdin  io.Writer
+	stdout *bufio.Reader
+	seq    atomic.Int64
+}
+
+var _ packersdk.Communicator = new(serialComm)
+
+func newSerialComm(stdin io.Writer, stdout io.Reader) *serialComm {
+	return &serialComm{busy: make(chan struct{}, 1), stdin: stdin, stdout: bufio.NewReader(stdout)}
+}
+
+// shellQuote quotes s for sh.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// run runs script with sh, and returns its stdout, stderr and exit status.
+func (c *serialComm) run(ctx context.Context, script string) ([]byte, []byte, int, error) {
+	// The previous command may still be running if its caller was
+	// cancelled, wait for its output to be read.
+	select {
+	case c.busy <- struct{}{}:
+	case <-ctx.Done():
+		return nil, nil, 0, ctx.Err()
+	}
+
+	id := fmt.Sprintf("packer-serial-%d-%d", os.Getpid(), c.seq.Add(1))
+	tmp := "/tmp/" + id
+	line := fmt.Sprintf("echo %s | base64 -d > %[2]s.sh; sh %[2]s.sh < /dev/null > %[2]s.out 2> %[2]s.err; "+
+		"RC=$?; echo %[3]s-BEGIN; base64 %[2]s.out; echo %[3]s-SPLIT; base64 %[2]s.err; echo %[3]s-END $RC; rm -f %[2]s.*\n",
+		base64.StdEncoding.EncodeToString([]byte(script)), tmp, id)
+	if _, err := io.WriteString(c.stdin, line); err != nil {
+		<-c.busy
+		return nil, nil, 0, fmt.Errorf("Error writing to the serial port: %s", err)
+	}
+
+	type result struct {
+		stdout, stderr []byte
+		status         int
+		err            error
+	}
+	done := make(chan result, 1)
+	go func() {
+		var r result
+		r.stdout, r.stderr, r.status, r.err = c.readResult(id)
+		<-c.busy
+		done <- r
+	}()
+
+	select {
+	case r := <-done:
+		return r.stdout, r.stderr, r.status, r.err
+	case <-ctx.Done():
+		return nil, nil, 0, ctx.Err()
+	}
+}
+
+// readResult reads the output of the command id.
+func (c *serialComm) readResult(id string) ([]byte, []byte, int, error) {
+	var out [2]strings.Builder
+	section := -1
+	for {
+		line, err := c.stdout.ReadString('\n')
+		if err != nil {
+			return nil, nil, 0, fmt.Errorf("Error reading from the serial port: %s", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == id+"-BEGIN":
+			section = 0
+		case line == id+"-SPLIT" && section == 0:
+			section = 1
+		case strings.HasPrefix(line, id+"-END ") && section == 1:
+			status, err := strconv.Atoi(strings.TrimPrefix(line, id+"-END "))
+			if err != nil {
+				return nil, nil, 0, fmt.Errorf("invalid exit status: %q", line)
+			}
+			stdout, err := base64.StdEncoding.DecodeString(out[0].String())
+			if err != nil {
+				return nil, nil, 0, fmt.Errorf("invalid output: %s", err)
+			}
+			stderr, err := base64.StdEncoding.DecodeString(out[1].String())
+			if err != nil {
+				return nil, nil, 0, fmt.Errorf("invalid output: %s", err)
+			}
+			return stdout, stderr, status, nil
+		case section >= 0:
+			out[section].WriteString(line)
+		default:
+			// The console may show anything before the output, e.g. kernel
+			// messages.
+			log.Printf("[DEBUG] serial port: %s", line)
+		}
+	}
+}
+
+// runOK runs script, failing if it exits with a non-zero status.
+func (c *serialComm) runOK(ctx context.Context, script string) ([]byte, error) {
+	stdout, stderr, status, err := c.run(ctx, script)
+	if err != nil {
+		return nil, err
+	}
+	if status != 0 {
+		return nil, fmt.Errorf("exit status %d: %s", status, bytes.TrimSpace(stderr))
+	}
+	return stdout, nil
+}
+
+func (c *serialComm) Start(ctx context.Context, cmd *packersdk.RemoteCmd) error {
+	log.Printf("[DEBUG] serial port: starting remote command: %s", cmd.Command)
+	go func() {
+		stdout, stderr, status, err := c.run(ctx, cmd.Command)
+		if err != nil {
+			log.Printf("[ERROR] serial port: remote command failed: %s", err)
+			cmd.SetExited(packersdk.CmdDisconnect)
+			return
+		}
+		if cmd.Stdout != nil {
+			_, _ = cmd.Stdout.Write(stdout)
+		}
+		if cmd.Stderr != nil {
+			_, _ = cmd.Stderr.Write(stderr)
+		}
+		cmd.SetExited(status)
+	}()
+	return nil
+}
+
+func (c *serialComm) Upload(dst string, r io.Reader, fi *os.FileInfo) error {
+	ctx := context.TODO()
+	tmp := fmt.Sprintf("/tmp/packer-serial-upload-%d-%d", os.Getpid(), c.seq.Add(1))
+	if _, err := c.runOK(ctx, fmt.Sprintf(": > %s", tmp)); err != nil {
+		return fmt.Errorf("Error uploading %s: %s", dst, err)
+	}
+
+	buf := make([]byte, serialUploadChunkSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			chunk := base64.StdEncoding.EncodeToString(buf[:n])
+			if _, err := c.runOK(ctx, fmt.Sprintf("echo %s | base64 -d >> %s", chunk, tmp)); err != nil {
+				return fmt.Errorf("Error uploading %s: %s", dst, err)
+			}
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("Error uploading %s: %s", dst, err)
+		}
+	}
+
+	script := fmt.Sprintf("mv %s %s", tmp, shellQuote(dst))
+	if fi != nil {
+		script = fmt.Sprintf("chmod %o %s && %s", (*fi).Mode().Perm(), tmp, script)
+	}
+	if _, err := c.runOK(ctx, script); err != nil {
+		return fmt.Errorf("Error uploading %s: %s", dst, err)
+	}
+	return nil
+}
+
+// excluded tells whether the relative path name matches one of the
+// exclude patterns.
+func excluded(name string, exclude []string) bool {
+	for _, pattern := range exclude {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// UploadDir uploads the directory src to dst, or its contents when src ends
+// with a slash, like the other communicators.
+func (c *serialComm) UploadDir(dst string, src string, exclude []string) error {
+	root := src
+	prefix := filepath.Base(src)
+	if strings.HasSuffix(src, "/") {
+		prefix = ""
+	}
+
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		if rel != "." && excluded(rel, exclude) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		name := filepath.ToSlash(filepath.Join(prefix, rel))
+		if name == "." {
+			return nil
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = name
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err == nil {
+		err = tw.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("Error archiving %s: %s", src, err)
+	}
+
+	tmp := fmt.Sprintf("/tmp/packer-serial-upload-%d-%d.tar", os.Getpid(), c.seq.Add(1))
+	if err := c.Upload(tmp, &archive, nil); err != nil {
+		return err
+	}
+	script := fmt.Sprintf("mkdir -p %[1]s && tar -xf %[2]s -C %[1]s; RC=$?; rm -f %[2]s; exit $RC", shellQuote(dst), tmp)
+	if _, err := c.runOK(context.TODO(), script); err != nil {
+		return fmt.Errorf("Error uploading %s: %s", src, err)
+	}
+	return nil
+}
+
+func (c *serialComm) Download(src string, w io.Writer) error {
+	data, err := c.runOK(context.TODO(), fmt.Sprintf("cat %s", shellQuote(src)))
+	if err != nil {
+		return fmt.Errorf("Error downloading %s: %s", src, err)
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func (c *serialComm) DownloadDir(src string, dst string, exclude []string) error {
+	data, err := c.runOK(context.TODO(), fmt.Sprintf("tar -cf - -C %s .", shellQuote(src)))
+	if err != nil {
+		return fmt.Errorf("Error downloading %s: %s", src, err)
+	}
+
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("Error downloading %s: %s", src, err)
+		}
+		name := path.Clean(header.Name)
+		if name == "." || excluded(name, exclude) {
+			continue
+		}
+		if name == ".." || strings.HasPrefix(name, "../") || path.IsAbs(name) {
+			return fmt.Errorf("Error downloading %s: invalid path %s", src, header.Name)
+		}
+		target := filepath.Join(dst, filepath.FromSlash(name))
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, os.FileMode(header.Mode).Perm())
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		default:
+			log.Printf("[WARN] serial port: skipping %s, which is not a regular file", name)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// testSerialComm returns a serialComm talking to a local shell, like the
+// one SerialPortStartupScript serves on the serial port.
+func testSerialComm(t *testing.T) *serialComm {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash is required")
+	}
+	cmd := exec.Command("bash", "--norc", "--noprofile")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		stdin.Close()
+		_ = cmd.Wait()
+	})
+	return newSerialComm(stdin, stdout)
+}
+
+func TestSerialComm_Start(t *testing.T) {
+	comm := testSerialComm(t)
+
+	var stdout, stderr bytes.Buffer
+	cmd := &packersdk.RemoteCmd{
+		Command: "echo 'hello'\necho error >&2\nexit 3",
+		Stdout:  &stdout,
+		Stderr:  &stderr,
+	}
+	if err := cmd.RunWithUi(context.Background(), comm, packersdk.TestUi(t)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if status := cmd.ExitStatus(); status != 3 {
+		t.Errorf("bad exit status: %d", status)
+	}
+	if stdout.String() != "hello\n" {
+		t.Errorf("bad stdout: %q", stdout.String())
+	}
+	if stderr.String() != "error\n" {
+		t.Errorf("bad stderr: %q", stderr.String())
+	}
+}
+
+func TestSerialComm_cancel(t *testing.T) {
+	comm := testSerialComm(t)
+
+	// The command is still running on the instance when it is cancelled.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, _, _, err := comm.run(ctx, "sleep 1; echo first"); err == nil {
+		t.Fatal("the cancelled command should fail")
+	}
+
+	stdout, _, status, err := comm.run(context.Background(), "echo second")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if status != 0 || string(stdout) != "second\n" {
+		t.Errorf("bad output of the next command: %d %q", status, stdout)
+	}
+}
+
+func TestSerialComm_UploadDownload(t *testing.T) {
+	comm := testSerialComm(t)
+	dir := t.TempDir()
+
+	// Larger than a chunk, and not text.
+	data := bytes.Repeat([]byte{0, 1, 2, 0xff, '\n'}, serialUploadChunkSize/2)
+	dst := filepath.Join(dir, "it's uploaded")
+	if err := comm.Upload(dst, bytes.NewReader(data), nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var downloaded bytes.Buffer
+	if err := comm.Download(dst, &downloaded); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !bytes.Equal(downloaded.Bytes(), data) {
+		t.Errorf("downloaded %d bytes, uploaded %d", downloaded.Len(), len(data))
+	}
+
+	if err := comm.Download(filepath.Join(dir, "missing"), &downloaded); err == nil {
+		t.Error("should fail to download a missing file")
+	}
+}
+
+func TestSerialComm_UploadDirDownloadDir(t *testing.T) {
+	comm := testSerialComm(t)
+
+	src := filepath.Join(t.TempDir(), "src")
+	if err := os.MkdirAll(filepath.Join(src, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"a.txt": "a", "sub/b.txt": "b", "skip.log": "skip"} {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	remote := t.TempDir()
+	if err := comm.UploadDir(remote, src, []string{"*.log"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := os.Stat(filepath.Join(remote, "src", "sub", "b.txt")); err != nil {
+		t.Errorf("the directory should be uploaded: %s", err)
+	}
+	if _, err := os.Stat(filepath.Join(remote, "src", "skip.log")); err == nil {
+		t.Error("the excluded files should not be uploaded")
+	}
+
+	contents := t.TempDir()
+	if err := comm.UploadDir(contents, src+"/", nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := os.Stat(filepath.Join(contents, "a.txt")); err != nil {
+		t.Errorf("the contents of the directory should be uploaded: %s", err)
+	}
+
+	local := t.TempDir()
+	if err := comm.DownloadDir(filepath.Join(remote, "src"), local, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	b, err := os.ReadFile(filepath.Join(local, "sub", "b.txt"))
+	if err != nil || strings.TrimSpace(string(b)) != "b" {
+		t.Errorf("bad downloaded file: %q, %v", b, err)
+	}
+}
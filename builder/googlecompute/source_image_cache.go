@@ -0,0 +1,131 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/filelock"
+)
+
+// sourceImageCache shares the source images resolved from their family
+// between the builds of a Packer run, which each run in their own plugin
+// process. This saves the lookups, and makes sure the builds start from the
+// same image even if a new one is published in the family meanwhile.
+type sourceImageCache struct {
+	dir string
+}
+
+// newSourceImageCache returns the cache of the Packer run with the given
+// ID, or nil without one, e.g. outside of Packer.
+func newSourceImageCache(runUUID string) *sourceImageCache {
+	if runUUID == "" {
+		return nil
+	}
+	return &sourceImageCache{dir: runTempDir(runUUID)}
+}
+
+// runTempDir returns the directory the builds of the Packer run with the
+// given ID share.
+func runTempDir(runUUID string) string {
+	return filepath.Join(os.TempDir(), "packer-googlecompute-"+runUUID)
+}
+
+// runTempDirMaxAge is the age after which the directories of the past
+// Packer runs are removed.
+const runTempDirMaxAge = 24 * time.Hour
+
+var pruneRunTempDirsOnce sync.Once
+
+// pruneRunTempDirs removes, once per process, the directories of the Packer
+// runs next to dir which were not modified for runTempDirMaxAge: nothing
+// knows which build of a run is the last one to remove them.
+func pruneRunTempDirs(dir string) {
+	pruneRunTempDirsOnce.Do(func() {
+		removeStaleRunTempDirs(filepath.Dir(dir), dir, time.Now().Add(-runTempDirMaxAge))
+	})
+}
+
+// removeStaleRunTempDirs removes the directories of the Packer runs in
+// tempDir, except keep, last modified before the given time.
+func removeStaleRunTempDirs(tempDir, keep string, before time.Time) {
+	dirs, err := filepath.Glob(filepath.Join(tempDir, "packer-googlecompute-*"))
+	if err != nil {
+		return
+	}
+	for _, dir := range dirs {
+		info, err := os.Stat(dir)
+		if err != nil || !info.IsDir() || dir == keep || !info.ModTime().Before(before) {
+			continue
+		}
+		log.Printf("[DEBUG] Removing the directory of a past Packer run: %s", dir)
+		if err := os.RemoveAll(dir); err != nil {
+			log.Printf("[WARN] Error removing the directory of a past Packer run: %s", err)
+		}
+	}
+}
+
+// sharedSourceImages is the cache of the current Packer run.
+var sharedSourceImages = newSourceImageCache(os.Getenv("PACKER_RUN_UUID"))
+
+// resolve returns the image cached for key, or the one returned by lookup,
+// caching it. A nil cache always looks the image up.
+func (c *sourceImageCache) resolve(key string, lookup func() (*common.Image, error)) (*common.Image, error) {
+	if c == nil {
+		return lookup()
+	}
+	pruneRunTempDirs(c.dir)
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		log.Printf("[WARN] Not sharing the source image: %s", err)
+		return lookup()
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	path := filepath.Join(c.dir, hex.EncodeToString(sum[:8])+".json")
+
+	// Hold the lock during the lookup, so that the builds resolving the same
+	// image wait for the first one instead of looking it up too.
+	lock := filelock.New(path + ".lock")
+	if err := lock.Lock(); err != nil {
+		log.Printf("[WARN] Not sharing the source image: %s", err)
+		return lookup()
+	}
+	defer lock.Unlock()
+
+	if data, err := os.ReadFile(path); err == nil {
+		image := new(common.Image)
+		if err := json.Unmarshal(data, image); err == nil {
+			log.Printf("[INFO] Using the source image %s resolved by another build for %s", image.Name, key)
+			return image, nil
+		}
+	}
+
+	image, err := lookup()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(image)
+	if err == nil {
+		err = os.WriteFile(path, data, 0600)
+	}
+	if err != nil {
+		log.Printf("[WARN] Not sharing the source image: %s", err)
+	}
+	return image, nil
+}
+
+// sourceImageCacheKey returns the key of the family of images in the given
+// projects.
+func sourceImageCacheKey(projects []string, family string) string {
+	return fmt.Sprintf("%q/%s", projects, family)
+}
@@ -0,0 +1,99 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSourceImageCache(t *testing.T) {
+	cache := &sourceImageCache{dir: t.TempDir()}
+	key := sourceImageCacheKey([]string{"debian-cloud"}, "debian-12")
+
+	lookups := 0
+	lookup := func(name string) func() (*common.Image, error) {
+		return func() (*common.Image, error) {
+			lookups++
+			return &common.Image{Name: name, ProjectId: "debian-cloud"}, nil
+		}
+	}
+
+	image, err := cache.resolve(key, lookup("debian-12-v1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "debian-12-v1", image.Name)
+
+	// A new image published meanwhile is not used.
+	image, err = cache.resolve(key, lookup("debian-12-v2"))
+	assert.NoError(t, err)
+	assert.Equal(t, "debian-12-v1", image.Name)
+	assert.Equal(t, "debian-cloud", image.ProjectId)
+	assert.Equal(t, 1, lookups)
+
+	other := sourceImageCacheKey([]string{"debian-cloud"}, "debian-11")
+	image, err = cache.resolve(other, lookup("debian-11-v1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "debian-11-v1", image.Name)
+	assert.Equal(t, 2, lookups)
+}
+
+func TestSourceImageCache_error(t *testing.T) {
+	cache := &sourceImageCache{dir: t.TempDir()}
+	key := sourceImageCacheKey(nil, "family")
+
+	_, err := cache.resolve(key, func() (*common.Image, error) {
+		return nil, errors.New("not found")
+	})
+	assert.Error(t, err)
+
+	// Failures are not cached.
+	image, err := cache.resolve(key, func() (*common.Image, error) {
+		return &common.Image{Name: "image"}, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "image", image.Name)
+}
+
+func TestSourceImageCache_disabled(t *testing.T) {
+	cache := newSourceImageCache("")
+	assert.Nil(t, cache)
+
+	lookups := 0
+	for i := 0; i < 2; i++ {
+		_, err := cache.resolve("key", func() (*common.Image, error) {
+			lookups++
+			return &common.Image{Name: "image"}, nil
+		})
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 2, lookups)
+}
+
+func TestRemoveStaleRunTempDirs(t *testing.T) {
+	tempDir := t.TempDir()
+	stale := filepath.Join(tempDir, "packer-googlecompute-stale")
+	current := filepath.Join(tempDir, "packer-googlecompute-current")
+	recent := filepath.Join(tempDir, "packer-googlecompute-recent")
+	other := filepath.Join(tempDir, "other")
+	for _, dir := range []string{stale, current, recent, other} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(dir, "bulk-insert"), 0700))
+	}
+	old := time.Now().Add(-2 * runTempDirMaxAge)
+	for _, dir := range []string{stale, current, other} {
+		assert.NoError(t, os.Chtimes(dir, old, old))
+	}
+
+	removeStaleRunTempDirs(tempDir, current, time.Now().Add(-runTempDirMaxAge))
+
+	assert.NoDirExists(t, stale)
+	assert.DirExists(t, current)
+	assert.DirExists(t, recent)
+	assert.DirExists(t, other)
+}
@@ -11,6 +11,8 @@ const StartupScriptKey string = "startup-script"
 const StartupScriptStatusKey string = "startup-script-status"
 const StartupWrappedScriptKey string = "packer-wrapped-startup-script"
 const EnableOSLoginKey string = "enable-oslogin"
+const SerialPortEnableKey string = "serial-port-enable"
+const SerialWrappedStartupScriptKey string = "packer-serial-wrapped-startup-script"
 
 const StartupScriptStatusDone string = "done"
 const StartupScriptStatusError string = "error"
@@ -67,3 +69,21 @@ exit $RETVAL
 `, StartupWrappedScriptKey, StartupScriptStatusKey, StartupScriptStatusDone, StartupScriptStatusError)
 
 var StartupScriptWindows string = ""
+
+// SerialPortStartupScript serves a shell on the second serial port, for the
+// serial console fallback, then runs the startup script it replaced.
+var SerialPortStartupScript string = fmt.Sprintf(`#!/usr/bin/env bash
+stty -F /dev/ttyS1 raw -echo
+setsid bash --norc --noprofile <> /dev/ttyS1 >&0 2>&1 &
+
+STARTUPSCRIPT=$(curl -f -H "Metadata-Flavor: Google" http://metadata.google.internal/computeMetadata/v1/instance/attributes/%[1]s 2> /dev/null)
+if [[ ! -z $STARTUPSCRIPT ]]; then
+  STARTUPSCRIPTPATH=$(mktemp)
+  echo "${STARTUPSCRIPT}" > ${STARTUPSCRIPTPATH}
+  chmod +x ${STARTUPSCRIPTPATH}
+  ${STARTUPSCRIPTPATH}
+  RETVAL=$?
+  rm ${STARTUPSCRIPTPATH}
+  exit $RETVAL
+fi
+`, SerialWrappedStartupScriptKey)
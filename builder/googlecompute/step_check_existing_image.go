@@ -19,7 +19,7 @@ type StepCheckExistingImage int
 // Run executes the Packer build step that checks if the image already exists.
 func (s *StepCheckExistingImage) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
 	c := state.Get("config").(*Config)
-	d := state.Get("driver").(common.Driver)
+	d := state.Get("driver").(common.ImageDriver)
 	ui := state.Get("ui").(packersdk.Ui)
 
 	ui.Say("Checking image does not exist...")
@@ -0,0 +1,107 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// StepCheckGoogleAccess represents a Packer build step that checks that the
+// subnetwork of the instance has Private Google Access enabled, as needed by
+// google_access.
+type StepCheckGoogleAccess int
+
+// Run executes the Packer build step that checks the subnetwork.
+func (s *StepCheckGoogleAccess) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c := state.Get("config").(*Config)
+	d := state.Get("driver").(common.ComputeDriver)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	_, _, name := subnetworkRef(c)
+	ui.Say(fmt.Sprintf("Checking Private Google Access on subnetwork %s...", name))
+
+	err := checkGoogleAccess(c, d)
+	if errors.Is(err, errSubnetworkUnknown) {
+		ui.Message(fmt.Sprintf("Skipping the check: %s", err))
+		return multistep.ActionContinue
+	}
+	if err != nil {
+		err = common.EnrichError(err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+// errSubnetworkUnknown is returned by checkGoogleAccess when the subnetwork
+// of the instance is not set and cannot be guessed from the network.
+var errSubnetworkUnknown = errors.New("the subnetwork of the instance is not known")
+
+// checkGoogleAccess returns an error if Private Google Access is disabled on
+// the subnetwork of the instance.
+func checkGoogleAccess(c *Config, d common.ComputeDriver) error {
+	project, region, name := subnetworkRef(c)
+	if c.Subnetwork == "" {
+		// Only auto mode networks have a subnetwork named after them in
+		// each region.
+		network, err := d.GetNetwork(project, name)
+		if err != nil {
+			return fmt.Errorf("Error getting network %s: %w", name, err)
+		}
+		if !network.AutoCreateSubnetworks {
+			return fmt.Errorf("%w: network %s is not in auto mode, set subnetwork for its Private Google Access to be checked",
+				errSubnetworkUnknown, name)
+		}
+	}
+	subnetwork, err := d.GetSubnetwork(project, region, name)
+	if err != nil {
+		return fmt.Errorf("Error getting subnetwork %s: %w", name, err)
+	}
+	if !subnetwork.PrivateIpGoogleAccess {
+		vip := "private"
+		if c.GoogleAccess != "" {
+			vip = c.GoogleAccess
+		}
+		return fmt.Errorf("Private Google Access is disabled on subnetwork %s, "+
+			"the instance will not be able to reach the Google APIs through the %s.googleapis.com VIP. "+
+			"Enable it with:\n\n"+
+			"  gcloud compute networks subnets update %s --project %s --region %s --enable-private-ip-google-access",
+			name, vip, name, project, region)
+	}
+	return nil
+}
+
+// Cleanup.
+func (s *StepCheckGoogleAccess) Cleanup(state multistep.StateBag) {}
+
+// subnetworkRef returns the project, region and name of the subnetwork of the
+// instance. Without a subnetwork, this is the subnetwork of the network in
+// the region, named after it in auto mode networks.
+func subnetworkRef(c *Config) (project, region, name string) {
+	project, region = c.NetworkProjectId, c.Region
+
+	ref := c.Subnetwork
+	if ref == "" {
+		ref = c.Network
+	}
+	parts := strings.Split(ref, "/")
+	for i := 0; i+1 < len(parts); i++ {
+		switch parts[i] {
+		case "projects":
+			project = parts[i+1]
+		case "regions":
+			region = parts[i+1]
+		}
+	}
+	return project, region, parts[len(parts)-1]
+}
@@ -0,0 +1,106 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	compute "google.golang.org/api/compute/v1"
+)
+
+func TestStepCheckGoogleAccess_impl(t *testing.T) {
+	var _ multistep.Step = new(StepCheckGoogleAccess)
+}
+
+func TestStepCheckGoogleAccess(t *testing.T) {
+	state := testState(t)
+	step := new(StepCheckGoogleAccess)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.GoogleAccess = "restricted"
+	config.Subnetwork = "builders"
+	driver := state.Get("driver").(*common.DriverMock)
+	driver.GetSubnetworkResult = &compute.Subnetwork{PrivateIpGoogleAccess: true}
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if driver.GetSubnetworkProject != config.NetworkProjectId || driver.GetSubnetworkRegion != config.Region || driver.GetSubnetworkName != "builders" {
+		t.Fatalf("bad subnetwork: %s/%s/%s", driver.GetSubnetworkProject, driver.GetSubnetworkRegion, driver.GetSubnetworkName)
+	}
+}
+
+func TestStepCheckGoogleAccess_disabled(t *testing.T) {
+	state := testState(t)
+	step := new(StepCheckGoogleAccess)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.GoogleAccess = "private"
+	driver := state.Get("driver").(*common.DriverMock)
+	driver.GetNetworkResult = &compute.Network{AutoCreateSubnetworks: true}
+	driver.GetSubnetworkResult = &compute.Subnetwork{}
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	err, ok := state.GetOk("error")
+	if !ok {
+		t.Fatal("should have error")
+	}
+	if !strings.Contains(err.(error).Error(), "--enable-private-ip-google-access") {
+		t.Errorf("error should tell how to enable Private Google Access: %s", err)
+	}
+}
+
+func TestStepCheckGoogleAccess_customModeNetwork(t *testing.T) {
+	state := testState(t)
+	step := new(StepCheckGoogleAccess)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.GoogleAccess = "private"
+	config.Network = "projects/host-project/global/networks/shared"
+	driver := state.Get("driver").(*common.DriverMock)
+	driver.GetNetworkResult = &compute.Network{}
+
+	// The subnetwork is not guessed from the network name.
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if driver.GetNetworkProject != "host-project" || driver.GetNetworkName != "shared" {
+		t.Errorf("bad network: %s/%s", driver.GetNetworkProject, driver.GetNetworkName)
+	}
+	if driver.GetSubnetworkName != "" {
+		t.Errorf("no subnetwork should be checked, got %s", driver.GetSubnetworkName)
+	}
+}
+
+func TestSubnetworkRef(t *testing.T) {
+	cases := []struct {
+		Network, Subnetwork   string
+		Project, Region, Name string
+	}{
+		{"default", "", "hashicorp", "us-east1", "default"},
+		{"", "builders", "hashicorp", "us-east1", "builders"},
+		{"", "projects/host-project/regions/us-west1/subnetworks/builders", "host-project", "us-west1", "builders"},
+		{"", "https://www.googleapis.com/compute/v1/projects/host-project/regions/us-west1/subnetworks/builders", "host-project", "us-west1", "builders"},
+		{"projects/host-project/global/networks/shared", "", "host-project", "us-east1", "shared"},
+	}
+
+	for _, tc := range cases {
+		c := testConfigStruct(t)
+		c.Network, c.Subnetwork = tc.Network, tc.Subnetwork
+		project, region, name := subnetworkRef(c)
+		if project != tc.Project || region != tc.Region || name != tc.Name {
+			t.Errorf("%q/%q: expected %s/%s/%s, got %s/%s/%s", tc.Network, tc.Subnetwork,
+				tc.Project, tc.Region, tc.Name, project, region, name)
+		}
+	}
+}
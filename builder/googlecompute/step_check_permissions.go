@@ -0,0 +1,122 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// StepCheckPermissions represents a Packer build step that checks that the
+// account used by Packer has the IAM permissions needed by the build.
+type StepCheckPermissions int
+
+// Run executes the Packer build step that checks the permissions.
+func (s *StepCheckPermissions) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c := state.Get("config").(*Config)
+	d := state.Get("driver").(common.IAMDriver)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	ui.Say("Checking permissions...")
+
+	for _, project := range permissionProjects(c) {
+		reqs := permissionRequirements(c, project)
+		err := common.CheckPermissions(ui, "project "+project, reqs, func(permissions []string) ([]string, error) {
+			return d.TestProjectPermissions(project, permissions)
+		})
+		if err != nil {
+			err = common.EnrichError(err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+// Cleanup.
+func (s *StepCheckPermissions) Cleanup(state multistep.StateBag) {}
+
+// permissionProjects returns the projects the build creates resources in.
+func permissionProjects(c *Config) []string {
+	projects := []string{c.ProjectId}
+	if !c.SkipCreateImage && c.ImageProjectId != c.ProjectId {
+		projects = append(projects, c.ImageProjectId)
+	}
+	return projects
+}
+
+// permissionRequirements returns the permissions needed on project by the
+// features of the build.
+func permissionRequirements(c *Config, project string) []common.PermissionRequirement {
+	var reqs []common.PermissionRequirement
+
+	if project == c.ProjectId {
+		instance := []string{
+			"compute.disks.create",
+			"compute.instances.create",
+			"compute.instances.delete",
+			"compute.instances.get",
+			"compute.instances.setMetadata",
+			"compute.zoneOperations.get",
+		}
+		if len(c.Labels) > 0 {
+			instance = append(instance, "compute.instances.setLabels")
+		}
+		if c.NetworkProjectId == c.ProjectId && c.Subnetwork != "" {
+			instance = append(instance, "compute.subnetworks.use")
+			if !c.OmitExternalIP {
+				instance = append(instance, "compute.subnetworks.useExternalIp")
+			}
+		}
+		reqs = append(reqs, common.PermissionRequirement{Feature: "instance", Permissions: instance})
+
+		if !c.DisableDefaultServiceAccount {
+			reqs = append(reqs, common.PermissionRequirement{
+				Feature:     "service account",
+				Permissions: []string{"iam.serviceAccounts.actAs"},
+			})
+		}
+
+		// A separate service account authenticates the tunnel otherwise.
+		if c.IAPConfig.IAP && c.IAPConfig.IAPImpersonateServiceAccount == "" {
+			reqs = append(reqs, common.PermissionRequirement{
+				Feature:     "IAP tunnel",
+				Permissions: []string{"iap.tunnelInstances.accessViaIAP"},
+			})
+		}
+
+		if c.UseOSLogin {
+			reqs = append(reqs, common.PermissionRequirement{
+				Feature:     "OS Login",
+				Permissions: []string{"compute.instances.osAdminLogin"},
+			})
+		}
+
+		if c.WinRMHTTPSBootstrap {
+			reqs = append(reqs, common.PermissionRequirement{
+				Feature:     "WinRM HTTPS bootstrap",
+				Permissions: []string{"compute.instances.getGuestAttributes"},
+			})
+		}
+	}
+
+	if !c.SkipCreateImage && project == c.ImageProjectId {
+		reqs = append(reqs, common.PermissionRequirement{
+			Feature: "image",
+			Permissions: []string{
+				"compute.disks.useReadOnly",
+				"compute.globalOperations.get",
+				"compute.images.create",
+				"compute.images.get",
+			},
+		})
+	}
+
+	return reqs
+}
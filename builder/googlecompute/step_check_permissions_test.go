@@ -0,0 +1,131 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestStepCheckPermissions_impl(t *testing.T) {
+	var _ multistep.Step = new(StepCheckPermissions)
+}
+
+func TestStepCheckPermissions(t *testing.T) {
+	state := testState(t)
+	step := new(StepCheckPermissions)
+	defer step.Cleanup(state)
+
+	ui := &packersdk.MockUi{}
+	state.Put("ui", ui)
+	config := state.Get("config").(*Config)
+	config.IAPConfig.IAP = true
+	driver := state.Get("driver").(*common.DriverMock)
+	// Grant everything that is asked for.
+	driver.TestProjectPermissionsResult = nil
+	for _, req := range permissionRequirements(config, config.ProjectId) {
+		driver.TestProjectPermissionsResult = append(driver.TestProjectPermissionsResult, req.Permissions...)
+	}
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if driver.TestProjectPermissionsProject != config.ProjectId {
+		t.Fatalf("bad project: %s", driver.TestProjectPermissionsProject)
+	}
+
+	var out string
+	for _, msg := range ui.SayMessages {
+		out += msg.Message + "\n"
+	}
+	if !strings.Contains(out, "PASS") || !strings.Contains(out, "iap.tunnelInstances.accessViaIAP") {
+		t.Errorf("expected a table of the checked permissions, got:\n%s", out)
+	}
+}
+
+func TestPermissionRequirements_IAPImpersonation(t *testing.T) {
+	state := testState(t)
+	config := state.Get("config").(*Config)
+	config.IAPConfig.IAP = true
+	config.IAPConfig.IAPImpersonateServiceAccount = "iap-tunnel@project.iam.gserviceaccount.com"
+
+	for _, req := range permissionRequirements(config, config.ProjectId) {
+		if req.Feature == "IAP tunnel" {
+			t.Errorf("the build credentials do not need IAP permissions with a tunnel service account")
+		}
+	}
+}
+
+func TestStepCheckPermissions_missing(t *testing.T) {
+	state := testState(t)
+	step := new(StepCheckPermissions)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.UseOSLogin = true
+	driver := state.Get("driver").(*common.DriverMock)
+	driver.TestProjectPermissionsResult = []string{"compute.instances.create"}
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	err, ok := state.GetOk("error")
+	if !ok {
+		t.Fatal("should have error")
+	}
+	if !strings.Contains(err.(error).Error(), "compute.instances.osAdminLogin") {
+		t.Errorf("error should list the missing permissions: %s", err)
+	}
+	if strings.Contains(err.(error).Error(), "compute.instances.create,") {
+		t.Errorf("error should not list the granted permissions: %s", err)
+	}
+}
+
+func TestStepCheckPermissions_error(t *testing.T) {
+	state := testState(t)
+	step := new(StepCheckPermissions)
+	defer step.Cleanup(state)
+
+	driver := state.Get("driver").(*common.DriverMock)
+	driver.TestProjectPermissionsErr = errors.New("error")
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+}
+
+func TestPermissionRequirements(t *testing.T) {
+	c := testConfigStruct(t)
+	c.SkipCreateImage = false
+	c.ImageProjectId = "images-project"
+
+	projects := permissionProjects(c)
+	if len(projects) != 2 || projects[1] != "images-project" {
+		t.Fatalf("expected the image project to be checked too, got %v", projects)
+	}
+
+	for _, req := range permissionRequirements(c, c.ProjectId) {
+		if req.Feature == "image" {
+			t.Errorf("image permissions should be checked on the image project only")
+		}
+	}
+	reqs := permissionRequirements(c, "images-project")
+	if len(reqs) != 1 || reqs[0].Feature != "image" {
+		t.Errorf("expected only the image permissions on the image project, got %v", reqs)
+	}
+
+	c.SkipCreateImage = true
+	if projects := permissionProjects(c); len(projects) != 1 {
+		t.Errorf("the image project should not be checked when skipping the image creation, got %v", projects)
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	compute "google.golang.org/api/compute/v1"
+)
+
+// localSSDSizeGb is the size of a single local SSD partition.
+const localSSDSizeGb = 375
+
+// StepCheckQuotas represents a Packer build step that checks that the region
+// and project quotas leave enough room for the resources the build creates.
+type StepCheckQuotas int
+
+// quotaRequirement is the amount of a quota metric consumed by the build.
+type quotaRequirement struct {
+	// Scope is either the region name, or "project" for project-wide quotas.
+	Scope  string
+	Metric string
+	Amount float64
+}
+
+// quotaShortfall is a requirement that cannot be fulfilled.
+type quotaShortfall struct {
+	quotaRequirement
+	Limit float64
+	Usage float64
+}
+
+func (s quotaShortfall) String() string {
+	return fmt.Sprintf("%s %s: needs %g, only %g available (limit %g, in use %g)",
+		s.Scope, s.Metric, s.Amount, s.Limit-s.Usage, s.Limit, s.Usage)
+}
+
+// Run executes the Packer build step that checks the quotas.
+func (s *StepCheckQuotas) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c := state.Get("config").(*Config)
+	d := state.Get("driver").(common.ComputeDriver)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	ui.Say("Checking quotas...")
+
+	if err := checkQuotas(c, d); err != nil {
+		err = common.EnrichError(err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+// checkQuotas returns an error listing the quotas short of what the build
+// consumes.
+func checkQuotas(c *Config, d common.ComputeDriver) error {
+	machineType, err := d.GetMachineType(c.Zone, c.MachineType)
+	if err != nil {
+		return fmt.Errorf("Error checking quotas: %w", err)
+	}
+	regionQuotas, err := d.GetRegionQuotas(c.Region)
+	if err != nil {
+		return fmt.Errorf("Error checking quotas: %w", err)
+	}
+	projectQuotas, err := d.GetProjectQuotas()
+	if err != nil {
+		return fmt.Errorf("Error checking quotas: %w", err)
+	}
+
+	reqs := quotaRequirements(c, machineType, regionQuotas)
+	shortfalls := quotaShortfalls(reqs, c.Region, regionQuotas, projectQuotas)
+	if len(shortfalls) > 0 {
+		lines := make([]string, 0, len(shortfalls))
+		for _, sf := range shortfalls {
+			lines = append(lines, "  "+sf.String())
+		}
+		return fmt.Errorf("Not enough quota to run this build:\n%s\n"+
+			"Free up resources or request a quota increase, then try again.",
+			strings.Join(lines, "\n"))
+	}
+	return nil
+}
+
+// Cleanup.
+func (s *StepCheckQuotas) Cleanup(state multistep.StateBag) {}
+
+// quotaRequirements lists what the build instance and its disks consume.
+// Requirements for the same metric are summed up.
+func quotaRequirements(c *Config, machineType *compute.MachineType, regionQuotas []*compute.Quota) []quotaRequirement {
+	var reqs []quotaRequirement
+	add := func(scope, metric string, amount float64) {
+		if amount <= 0 {
+			return
+		}
+		for i := range reqs {
+			if reqs[i].Scope == scope && reqs[i].Metric == metric {
+				reqs[i].Amount += amount
+				return
+			}
+		}
+		reqs = append(reqs, quotaRequirement{Scope: scope, Metric: metric, Amount: amount})
+	}
+
+	cpus := float64(machineType.GuestCpus)
+	cpuMetric := "CPUS"
+	if c.Preemptible || c.ProvisioningModel == "SPOT" {
+		// Preemptible CPUs are accounted separately only when the project
+		// has a dedicated quota for them.
+		if q := findQuota(regionQuotas, "PREEMPTIBLE_CPUS"); q != nil && q.Limit > 0 {
+			cpuMetric = "PREEMPTIBLE_CPUS"
+		}
+	}
+	add(c.Region, cpuMetric, cpus)
+	if cpuMetric == "CPUS" {
+		// Most machine families also have a quota of their own, e.g. N2_CPUS.
+		family := strings.ToUpper(strings.SplitN(c.MachineType, "-", 2)[0]) + "_CPUS"
+		if findQuota(regionQuotas, family) != nil {
+			add(c.Region, family, cpus)
+		}
+	}
+	add("project", "CPUS_ALL_REGIONS", cpus)
+
+	add(c.Region, diskQuotaMetric(c.DiskType), float64(c.DiskSizeGb))
+	for _, bd := range c.ExtraBlockDevices {
+		if bd.VolumeType == common.LocalScratch {
+			size := bd.VolumeSize
+			if size == 0 {
+				size = localSSDSizeGb
+			}
+			add(c.Region, "LOCAL_SSD_TOTAL_GB", float64(size))
+			continue
+		}
+		add(c.Region, diskQuotaMetric(string(bd.VolumeType)), float64(bd.VolumeSize))
+	}
+
+	if !c.OmitExternalIP && c.Address == "" {
+		add(c.Region, "IN_USE_ADDRESSES", 1)
+	}
+
+	return reqs
+}
+
+// diskQuotaMetric returns the quota metric a disk type is accounted against,
+// or an empty string if the disk type is not covered by the precheck.
+func diskQuotaMetric(diskType string) string {
+	switch diskType {
+	case "pd-standard":
+		return "DISKS_TOTAL_GB"
+	case "pd-balanced", "pd-ssd", "pd-extreme":
+		return "SSD_TOTAL_GB"
+	}
+	return ""
+}
+
+// quotaShortfalls returns the requirements that exceed what is left of their
+// quota. Requirements without a matching quota are ignored.
+func quotaShortfalls(reqs []quotaRequirement, region string, regionQuotas, projectQuotas []*compute.Quota) []quotaShortfall {
+	var shortfalls []quotaShortfall
+	for _, req := range reqs {
+		if req.Metric == "" {
+			continue
+		}
+		quotas := projectQuotas
+		if req.Scope == region {
+			quotas = regionQuotas
+		}
+		q := findQuota(quotas, req.Metric)
+		if q == nil {
+			log.Printf("[DEBUG] No %s quota found for %s, skipping", req.Metric, req.Scope)
+			continue
+		}
+		if q.Usage+req.Amount > q.Limit {
+			shortfalls = append(shortfalls, quotaShortfall{
+				quotaRequirement: req,
+				Limit:            q.Limit,
+				Usage:            q.Usage,
+			})
+		}
+	}
+	return shortfalls
+}
+
+func findQuota(quotas []*compute.Quota, metric string) *compute.Quota {
+	for _, q := range quotas {
+		if q.Metric == metric {
+			return q
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	compute "google.golang.org/api/compute/v1"
+)
+
+func TestStepCheckQuotas_impl(t *testing.T) {
+	var _ multistep.Step = new(StepCheckQuotas)
+}
+
+func TestStepCheckQuotas(t *testing.T) {
+	state := testState(t)
+	step := new(StepCheckQuotas)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(*common.DriverMock)
+	driver.GetMachineTypeResult = &compute.MachineType{GuestCpus: 2}
+	driver.GetRegionQuotasResult = []*compute.Quota{
+		{Metric: "CPUS", Limit: 24, Usage: 10},
+		{Metric: "DISKS_TOTAL_GB", Limit: 4096, Usage: 100},
+		{Metric: "IN_USE_ADDRESSES", Limit: 8, Usage: 2},
+	}
+	driver.GetProjectQuotasResult = []*compute.Quota{
+		{Metric: "CPUS_ALL_REGIONS", Limit: 32, Usage: 10},
+	}
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if driver.GetMachineTypeName != config.MachineType {
+		t.Fatalf("bad machine type: %s", driver.GetMachineTypeName)
+	}
+	if driver.GetRegionQuotasRegion != config.Region {
+		t.Fatalf("bad region: %s", driver.GetRegionQuotasRegion)
+	}
+}
+
+func TestStepCheckQuotas_shortfall(t *testing.T) {
+	state := testState(t)
+	step := new(StepCheckQuotas)
+	defer step.Cleanup(state)
+
+	driver := state.Get("driver").(*common.DriverMock)
+	driver.GetMachineTypeResult = &compute.MachineType{GuestCpus: 2}
+	driver.GetRegionQuotasResult = []*compute.Quota{
+		{Metric: "CPUS", Limit: 24, Usage: 23},
+		{Metric: "DISKS_TOTAL_GB", Limit: 4096, Usage: 100},
+		{Metric: "IN_USE_ADDRESSES", Limit: 8, Usage: 8},
+	}
+	driver.GetProjectQuotasResult = []*compute.Quota{
+		{Metric: "CPUS_ALL_REGIONS", Limit: 32, Usage: 10},
+	}
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	err, ok := state.GetOk("error")
+	if !ok {
+		t.Fatal("should have error")
+	}
+	msg := err.(error).Error()
+	for _, metric := range []string{"CPUS", "IN_USE_ADDRESSES"} {
+		if !strings.Contains(msg, metric) {
+			t.Errorf("shortfall report should mention %s: %s", metric, msg)
+		}
+	}
+	if strings.Contains(msg, "DISKS_TOTAL_GB") {
+		t.Errorf("shortfall report should not mention DISKS_TOTAL_GB: %s", msg)
+	}
+}
+
+func TestQuotaRequirements(t *testing.T) {
+	c := &Config{
+		Region:         "us-east1",
+		MachineType:    "n2-standard-4",
+		DiskType:       "pd-balanced",
+		DiskSizeGb:     20,
+		OmitExternalIP: true,
+		Preemptible:    true,
+		ExtraBlockDevices: []common.BlockDevice{
+			{VolumeType: common.LocalScratch, VolumeSize: 375},
+			{VolumeType: "pd-ssd", VolumeSize: 100},
+			{VolumeType: "pd-standard", VolumeSize: 50},
+		},
+	}
+	regionQuotas := []*compute.Quota{
+		{Metric: "N2_CPUS", Limit: 24},
+		{Metric: "PREEMPTIBLE_CPUS", Limit: 0},
+	}
+
+	reqs := quotaRequirements(c, &compute.MachineType{GuestCpus: 4}, regionQuotas)
+	expected := map[string]float64{
+		"us-east1/CPUS":               4,
+		"us-east1/N2_CPUS":            4,
+		"project/CPUS_ALL_REGIONS":    4,
+		"us-east1/SSD_TOTAL_GB":       120,
+		"us-east1/DISKS_TOTAL_GB":     50,
+		"us-east1/LOCAL_SSD_TOTAL_GB": 375,
+	}
+	if len(reqs) != len(expected) {
+		t.Fatalf("expected %d requirements, got %#v", len(expected), reqs)
+	}
+	for _, req := range reqs {
+		key := req.Scope + "/" + req.Metric
+		if expected[key] != req.Amount {
+			t.Errorf("%s: expected %g, got %g", key, expected[key], req.Amount)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"google.golang.org/api/compute/v1"
+)
+
+// CheckpointLabel labels the checkpoint snapshots with the name of their
+// phase.
+const CheckpointLabel = "packer-checkpoint"
+
+var validCheckpointName = regexp.MustCompile(`^[a-z][-a-z0-9]{0,39}$`)
+
+// checkpointSnapshotName returns the name of the snapshot of the checkpoint
+// phase of c, run on an instance created from sourceImage. The name changes
+// with the fingerprint of the phase.
+func checkpointSnapshotName(c *Config, sourceImage *common.Image) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n", c.CheckpointName, sourceImage.SelfLink)
+	for _, command := range c.CheckpointInline {
+		fmt.Fprintf(h, "%q\n", command)
+	}
+	return fmt.Sprintf("packer-%s-%s", c.CheckpointName, hex.EncodeToString(h.Sum(nil))[:16])
+}
+
+// findCheckpointSnapshot returns the self link of the ready snapshot with
+// the given name, or "" if there is none.
+func findCheckpointSnapshot(d common.ComputeDriver, c *Config, name string) (string, error) {
+	snapshots, err := d.ListSnapshots(c.ProjectId, fmt.Sprintf("name = %q", name))
+	if err != nil {
+		return "", err
+	}
+	for _, snapshot := range snapshots {
+		if snapshot.Name == name && snapshot.Status == "READY" {
+			return snapshot.SelfLink, nil
+		}
+	}
+	return "", nil
+}
+
+// StepCheckpoint runs the commands of the checkpoint phase, and snapshots
+// the boot disk once they succeed. The phase is skipped if the instance was
+// created from its snapshot.
+type StepCheckpoint struct{}
+
+// Run executes the Packer build step that runs the checkpoint phase. Failing
+// to snapshot the boot disk does not fail the build, the phase runs again
+// next time.
+func (s *StepCheckpoint) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c := state.Get("config").(*Config)
+	driver := state.Get("driver").(common.ComputeDriver)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	name, ok := state.Get("checkpoint_snapshot").(string)
+	if !ok {
+		return multistep.ActionContinue
+	}
+	if restored, _ := state.Get("checkpoint_restored").(bool); restored {
+		ui.Say(fmt.Sprintf("Skipping checkpoint phase %s, the instance started from snapshot %s.", c.CheckpointName, name))
+		return multistep.ActionContinue
+	}
+
+	ui.Say(fmt.Sprintf("Running checkpoint phase %s...", c.CheckpointName))
+	comm := state.Get("communicator").(packersdk.Communicator)
+	for _, command := range c.CheckpointInline {
+		cmd := &packersdk.RemoteCmd{Command: command}
+		err := cmd.RunWithUi(ctx, comm, ui)
+		if err == nil && cmd.ExitStatus() != 0 {
+			err = fmt.Errorf("%q exited with status %d", command, cmd.ExitStatus())
+		}
+		if err != nil {
+			err := fmt.Errorf("Error running checkpoint phase %s: %s", c.CheckpointName, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	ui.Say(fmt.Sprintf("Creating checkpoint snapshot %s...", name))
+	labels := map[string]string{}
+	for k, v := range c.Labels {
+		labels[k] = v
+	}
+	labels[CheckpointLabel] = c.CheckpointName
+	err := driver.CreateSnapshot(c.Zone, c.DiskName, &compute.Snapshot{
+		Name:        name,
+		Description: fmt.Sprintf("Checkpoint %s created by Packer", c.CheckpointName),
+		Labels:      labels,
+	})
+	if err != nil {
+		ui.Error(common.EnrichError(fmt.Errorf("Error creating checkpoint snapshot %s, going on: %w", name, err)).Error())
+		return multistep.ActionContinue
+	}
+	ui.Message(fmt.Sprintf("Checkpoint snapshot %s created.", name))
+	return multistep.ActionContinue
+}
+
+// Cleanup.
+func (s *StepCheckpoint) Cleanup(state multistep.StateBag) {}
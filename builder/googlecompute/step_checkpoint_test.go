@@ -0,0 +1,136 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/compute/v1"
+)
+
+func TestCheckpointSnapshotName(t *testing.T) {
+	c := &Config{CheckpointName: "base", CheckpointInline: []string{"make deps"}}
+	image := &common.Image{SelfLink: "https://compute/images/debian"}
+
+	name := checkpointSnapshotName(c, image)
+	assert.Regexp(t, `^packer-base-[0-9a-f]{16}$`, name)
+	assert.Equal(t, name, checkpointSnapshotName(c, image), "the name should be stable")
+
+	c.CheckpointInline = []string{"make", "deps"}
+	assert.NotEqual(t, name, checkpointSnapshotName(c, image), "the commands should be fingerprinted")
+	c.CheckpointInline = []string{"make deps"}
+	assert.NotEqual(t, name, checkpointSnapshotName(c, &common.Image{SelfLink: "https://compute/images/ubuntu"}),
+		"the source image should be fingerprinted")
+}
+
+func TestStepCreateInstance_checkpoint(t *testing.T) {
+	cases := map[string]struct {
+		snapshots []*compute.Snapshot
+		restored  bool
+	}{
+		"none":    {nil, false},
+		"pending": {[]*compute.Snapshot{{Name: "packer-base", Status: "CREATING", SelfLink: "link"}}, false},
+		"ready":   {[]*compute.Snapshot{{Name: "packer-base", Status: "READY", SelfLink: "link"}}, true},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			state := testState(t)
+			c := state.Get("config").(*Config)
+			c.CheckpointName = "base"
+			c.CheckpointInline = []string{"make deps"}
+			d := state.Get("driver").(*common.DriverMock)
+			d.GetImageResult = StubImage("test-image", "test-project", []string{}, 100)
+			snapshot := checkpointSnapshotName(c, d.GetImageResult)
+			for _, s := range tc.snapshots {
+				s.Name = snapshot
+			}
+			d.ListSnapshotsResult = tc.snapshots
+
+			step := new(StepCreateInstance)
+			defer step.Cleanup(state)
+			assert.Equal(t, multistep.ActionContinue, step.Run(context.Background(), state))
+
+			assert.Equal(t, snapshot, state.Get("checkpoint_snapshot"))
+			assert.Equal(t, tc.restored, state.Get("checkpoint_restored"))
+			if tc.restored {
+				assert.Equal(t, "link", d.RunInstanceConfig.SourceSnapshot)
+			} else {
+				assert.Empty(t, d.RunInstanceConfig.SourceSnapshot)
+			}
+		})
+	}
+}
+
+func TestStepCheckpoint(t *testing.T) {
+	state := testState(t)
+	c := state.Get("config").(*Config)
+	c.CheckpointName = "base"
+	c.CheckpointInline = []string{"make deps", "sync"}
+	c.DiskName = "disk"
+	c.Labels = map[string]string{"team": "images"}
+	comm := new(packersdk.MockCommunicator)
+	state.Put("communicator", comm)
+	state.Put("checkpoint_snapshot", "packer-base-0123")
+	state.Put("checkpoint_restored", false)
+	d := state.Get("driver").(*common.DriverMock)
+
+	step := new(StepCheckpoint)
+	defer step.Cleanup(state)
+	assert.Equal(t, multistep.ActionContinue, step.Run(context.Background(), state))
+
+	assert.Equal(t, "sync", comm.StartCmd.Command, "the commands should run in order")
+	assert.Equal(t, "disk", d.CreateSnapshotDisk)
+	assert.Equal(t, c.Zone, d.CreateSnapshotZone)
+	assert.Equal(t, "packer-base-0123", d.CreateSnapshotSnapshot.Name)
+	assert.Equal(t, map[string]string{"team": "images", CheckpointLabel: "base"}, d.CreateSnapshotSnapshot.Labels)
+}
+
+func TestStepCheckpoint_restored(t *testing.T) {
+	state := testState(t)
+	c := state.Get("config").(*Config)
+	c.CheckpointName = "base"
+	c.CheckpointInline = []string{"make deps"}
+	comm := new(packersdk.MockCommunicator)
+	state.Put("communicator", comm)
+	state.Put("checkpoint_snapshot", "packer-base-0123")
+	state.Put("checkpoint_restored", true)
+	d := state.Get("driver").(*common.DriverMock)
+
+	step := new(StepCheckpoint)
+	defer step.Cleanup(state)
+	assert.Equal(t, multistep.ActionContinue, step.Run(context.Background(), state))
+
+	assert.False(t, comm.StartCalled, "the phase should be skipped")
+	assert.Nil(t, d.CreateSnapshotSnapshot)
+}
+
+func TestStepCheckpoint_failures(t *testing.T) {
+	state := testState(t)
+	c := state.Get("config").(*Config)
+	c.CheckpointName = "base"
+	c.CheckpointInline = []string{"make deps"}
+	comm := &packersdk.MockCommunicator{StartExitStatus: 2}
+	state.Put("communicator", comm)
+	state.Put("checkpoint_snapshot", "packer-base-0123")
+	d := state.Get("driver").(*common.DriverMock)
+
+	step := new(StepCheckpoint)
+	defer step.Cleanup(state)
+	assert.Equal(t, multistep.ActionHalt, step.Run(context.Background(), state))
+	assert.Nil(t, d.CreateSnapshotSnapshot, "no snapshot should be created when the phase fails")
+
+	// Failing to snapshot the disk does not fail the build.
+	state.Remove("error")
+	comm.StartExitStatus = 0
+	d.CreateSnapshotErr = errors.New("quota exceeded")
+	assert.Equal(t, multistep.ActionContinue, step.Run(context.Background(), state))
+	_, failed := state.GetOk("error")
+	assert.False(t, failed)
+}
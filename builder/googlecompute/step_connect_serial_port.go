@@ -0,0 +1,165 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"golang.org/x/crypto/ssh"
+)
+
+// SerialPortAddress is the address of the interactive serial console.
+const SerialPortAddress = "ssh-serialport.googleapis.com:9600"
+
+// serialPortNumber is the serial port SerialPortStartupScript serves the
+// shell on.
+const serialPortNumber = 2
+
+// StepConnectSerialFallback connects to the instance with SSH, and falls
+// back to the shell SerialPortStartupScript serves on the serial port when
+// SSH is not available within serial_port_fallback_timeout.
+type StepConnectSerialFallback struct {
+	Host      func(multistep.StateBag) (string, error)
+	SSHConfig func(multistep.StateBag) (*ssh.ClientConfig, error)
+
+	sshStep *communicator.StepConnectSSH
+	client  *ssh.Client
+	session *ssh.Session
+}
+
+// Run executes the Packer build step that connects to the instance.
+func (s *StepConnectSerialFallback) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	// The step runs again after pause_before_connect.
+	if s.session != nil {
+		return multistep.ActionContinue
+	}
+
+	if s.sshStep == nil {
+		// Copied once the tunnels have set their ports.
+		sshConf := config.Comm
+		sshConf.SSHTimeout = config.SerialPortFallbackTimeout
+		s.sshStep = &communicator.StepConnectSSH{
+			Config:    &sshConf,
+			Host:      s.Host,
+			SSHConfig: s.SSHConfig,
+		}
+	}
+	if action := s.sshStep.Run(ctx, state); action == multistep.ActionContinue || ctx.Err() != nil {
+		return action
+	}
+	state.Remove("error")
+
+	ui.Say("Falling back to the serial console...")
+	instanceName := state.Get("instance_name").(string)
+	comm, err := s.connect(ctx, config, instanceName)
+	if err != nil {
+		err = fmt.Errorf("Error connecting through the serial console: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	ui.Say("Connected through the serial console!")
+	state.Put("communicator", comm)
+
+	return multistep.ActionContinue
+}
+
+// connect connects to the serial port until the shell answers, or the
+// ssh_timeout expires.
+func (s *StepConnectSerialFallback) connect(ctx context.Context, config *Config, instanceName string) (packersdk.Communicator, error) {
+	if len(config.Comm.SSHPrivateKey) == 0 {
+		return nil, errors.New("the serial console requires an SSH private key")
+	}
+	signer, err := ssh.ParsePrivateKey(config.Comm.SSHPrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing the SSH private key: %s", err)
+	}
+	clientConfig := &ssh.ClientConfig{
+		User: fmt.Sprintf("%s.%s.%s.port=%d", config.ProjectId, config.Zone, instanceName, serialPortNumber),
+		Auth: []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		// Like the SSH communicator, the host key is not checked.
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         30 * time.Second,
+	}
+
+	if config.Comm.SSHTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.Comm.SSHTimeout)
+		defer cancel()
+	}
+	for {
+		comm, err := s.dial(ctx, clientConfig)
+		if err == nil {
+			return comm, nil
+		}
+		log.Printf("[DEBUG] serial port: %s", err)
+		s.close()
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%s: %s", ctx.Err(), err)
+		case <-time.After(10 * time.Second):
+		}
+	}
+}
+
+// dial opens a session to the serial port, and checks the shell answers.
+func (s *StepConnectSerialFallback) dial(ctx context.Context, clientConfig *ssh.ClientConfig) (packersdk.Communicator, error) {
+	client, err := ssh.Dial("tcp", SerialPortAddress, clientConfig)
+	if err != nil {
+		return nil, err
+	}
+	s.client = client
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	s.session = session
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := session.Shell(); err != nil {
+		return nil, err
+	}
+
+	comm := newSerialComm(stdin, stdout)
+	// The startup script may not have run yet.
+	probeCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	if _, err := comm.runOK(probeCtx, "true"); err != nil {
+		return nil, fmt.Errorf("the shell on the serial port does not answer: %s", err)
+	}
+	return comm, nil
+}
+
+func (s *StepConnectSerialFallback) close() {
+	if s.session != nil {
+		s.session.Close()
+		s.session = nil
+	}
+	if s.client != nil {
+		s.client.Close()
+		s.client = nil
+	}
+}
+
+// Cleanup closes the serial console connection.
+func (s *StepConnectSerialFallback) Cleanup(state multistep.StateBag) {
+	s.close()
+}
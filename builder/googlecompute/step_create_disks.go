@@ -17,6 +17,10 @@ import (
 
 type StepCreateDisks struct {
 	DiskConfiguration []common.BlockDevice
+
+	// tried is set once the disks were requested, for the disks left by
+	// failed creations to be handled when the step is run again.
+	tried bool
 }
 
 func (s *StepCreateDisks) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
@@ -27,9 +31,16 @@ func (s *StepCreateDisks) Run(ctx context.Context, state multistep.StateBag) mul
 		return multistep.ActionContinue
 	}
 
-	driver := state.Get("driver").(common.Driver)
+	driver := state.Get("driver").(common.ComputeDriver)
 	config := state.Get("config").(*Config)
 
+	// Start creating all the disks before waiting for any of them, they are
+	// then attached when the instance is inserted.
+	type diskCreation struct {
+		index int
+		errCh <-chan error
+	}
+	var creations []diskCreation
 	for i, disk := range s.DiskConfiguration {
 		if disk.VolumeType == common.LocalScratch {
 			continue
@@ -39,42 +50,106 @@ func (s *StepCreateDisks) Run(ctx context.Context, state multistep.StateBag) mul
 			continue
 		}
 
+		if s.tried {
+			ready, err := s.leftoverDisk(driver, ui, config, disk)
+			if err != nil {
+				err := common.EnrichError(fmt.Errorf("failed to check for disk %s of the previous try: %w", disk.DiskName, err))
+				state.Put("error", err)
+				ui.Error(err.Error())
+				return multistep.ActionHalt
+			}
+			if ready {
+				s.setSourceVolume(i, config)
+				continue
+			}
+		}
+
 		ui.Say(fmt.Sprintf("Creating persistent disk %s", disk.DiskName))
 
 		_, errCh := driver.CreateDisk(disk)
+		creations = append(creations, diskCreation{index: i, errCh: errCh})
+	}
+	s.tried = true
+
+	waitCtx, cancel := context.WithTimeout(ctx, config.StateTimeout)
+	defer cancel()
+
+	var firstErr error
+	for _, creation := range creations {
+		disk := s.DiskConfiguration[creation.index]
 
 		var err error
 		select {
-		case err = <-errCh:
-		case <-time.After(config.StateTimeout):
+		case err = <-creation.errCh:
+		case <-waitCtx.Done():
 			err = errors.New("time out while waiting for disk to create")
 		}
 		if err != nil {
-			err := fmt.Errorf("failed to create disk: %s", err)
+			err := common.EnrichError(fmt.Errorf("failed to create disk %s: %w", disk.DiskName, err))
 			ui.Say(err.Error())
-			state.Put("error", err)
-			return multistep.ActionHalt
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
 		}
 
-		if len(disk.ReplicaZones) != 0 {
-			region, _ := common.GetRegionFromZone(config.Zone)
-			// Generate the source URI for attachment later
-			s.DiskConfiguration[i].SourceVolume = fmt.Sprintf("projects/%s/regions/%s/disks/%s",
-				config.ProjectId,
-				region,
-				disk.DiskName)
-		} else {
-			// Generate the source URI for attachment later
-			s.DiskConfiguration[i].SourceVolume = fmt.Sprintf("projects/%s/zones/%s/disks/%s",
-				config.ProjectId,
-				config.Zone,
-				disk.DiskName)
-		}
+		s.setSourceVolume(creation.index, config)
+	}
+	if firstErr != nil {
+		state.Put("error", firstErr)
+		return multistep.ActionHalt
 	}
 
 	return multistep.ActionContinue
 }
 
+// setSourceVolume sets the source URI of the created disk i, for it to be
+// attached to the instance.
+func (s *StepCreateDisks) setSourceVolume(i int, config *Config) {
+	disk := s.DiskConfiguration[i]
+	if len(disk.ReplicaZones) != 0 {
+		region, _ := common.GetRegionFromZone(config.Zone)
+		s.DiskConfiguration[i].SourceVolume = fmt.Sprintf("projects/%s/regions/%s/disks/%s",
+			config.ProjectId,
+			region,
+			disk.DiskName)
+	} else {
+		s.DiskConfiguration[i].SourceVolume = fmt.Sprintf("projects/%s/zones/%s/disks/%s",
+			config.ProjectId,
+			config.Zone,
+			disk.DiskName)
+	}
+}
+
+// leftoverDisk returns whether the disk of the previous try of the step is
+// ready, and deletes it otherwise, e.g. if it failed.
+func (s *StepCreateDisks) leftoverDisk(driver common.ComputeDriver, ui packersdk.Ui, config *Config, disk common.BlockDevice) (bool, error) {
+	zone := config.Zone
+	if len(disk.ReplicaZones) != 0 {
+		zone, _ = common.GetRegionFromZone(zone)
+	}
+
+	gceDisk, err := driver.GetDisk(zone, disk.DiskName)
+	if err != nil {
+		if strings.Contains(err.Error(), "googleapi: Error 404") {
+			return false, nil
+		}
+		return false, err
+	}
+	if gceDisk.Status == "READY" {
+		ui.Say(fmt.Sprintf("Persistent disk %s was created by the previous try, using it", disk.DiskName))
+		return true, nil
+	}
+
+	ui.Say(fmt.Sprintf("Deleting persistent disk %s left %s by the previous try", disk.DiskName, gceDisk.Status))
+	select {
+	case err = <-driver.DeleteDisk(zone, disk.DiskName):
+	case <-time.After(config.StateTimeout):
+		err = errors.New("time out while waiting for disk to delete")
+	}
+	return false, err
+}
+
 func (s *StepCreateDisks) needToCreateDisks() bool {
 	for _, cfg := range s.DiskConfiguration {
 		if cfg.VolumeType == common.LocalScratch {
@@ -94,7 +169,7 @@ func (s *StepCreateDisks) needToCreateDisks() bool {
 func (s *StepCreateDisks) Cleanup(state multistep.StateBag) {
 	ui := state.Get("ui").(packersdk.Ui)
 	config := state.Get("config").(*Config)
-	driver := state.Get("driver").(common.Driver)
+	driver := state.Get("driver").(common.ComputeDriver)
 
 	for _, gceDisk := range s.DiskConfiguration {
 		if gceDisk.KeepDevice {
@@ -0,0 +1,110 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/compute/v1"
+)
+
+func TestStepCreateDisks(t *testing.T) {
+	state := testState(t)
+	c := state.Get("config").(*Config)
+	driver := state.Get("driver").(*common.DriverMock)
+	step := &StepCreateDisks{
+		DiskConfiguration: []common.BlockDevice{
+			{DiskName: "data-1", VolumeType: common.ZonalBalanced},
+			{DiskName: "data-2", VolumeType: common.ZonalBalanced, ReplicaZones: []string{"us-central1-a", "us-central1-b"}},
+			{DiskName: "scratch", VolumeType: common.LocalScratch},
+			{DiskName: "existing", SourceVolume: "projects/p/zones/z/disks/existing"},
+		},
+	}
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	assert.Len(t, driver.CreateDiskConfigs, 2)
+	region, _ := common.GetRegionFromZone(c.Zone)
+	assert.Equal(t, "projects/"+c.ProjectId+"/zones/"+c.Zone+"/disks/data-1", step.DiskConfiguration[0].SourceVolume)
+	assert.Equal(t, "projects/"+c.ProjectId+"/regions/"+region+"/disks/data-2", step.DiskConfiguration[1].SourceVolume)
+	assert.Empty(t, step.DiskConfiguration[2].SourceVolume)
+	assert.Equal(t, "projects/p/zones/z/disks/existing", step.DiskConfiguration[3].SourceVolume)
+}
+
+func TestStepCreateDisks_error(t *testing.T) {
+	state := testState(t)
+	driver := state.Get("driver").(*common.DriverMock)
+	errCh := make(chan error, 1)
+	errCh <- errors.New("quota exceeded")
+	close(errCh)
+	driver.CreateDiskErrCh = errCh
+	step := &StepCreateDisks{
+		DiskConfiguration: []common.BlockDevice{
+			{DiskName: "data-1", VolumeType: common.ZonalBalanced},
+			{DiskName: "data-2", VolumeType: common.ZonalBalanced},
+		},
+	}
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+	// All the disks are waited for, so that the others can be cleaned up.
+	assert.Len(t, driver.CreateDiskConfigs, 2)
+	assert.Empty(t, step.DiskConfiguration[0].SourceVolume)
+	assert.NotEmpty(t, step.DiskConfiguration[1].SourceVolume)
+}
+
+func TestStepCreateDisks_retry(t *testing.T) {
+	cases := map[string]struct {
+		status  string
+		deleted bool
+		created bool
+	}{
+		"none":   {"", false, true},
+		"ready":  {"READY", false, false},
+		"failed": {"FAILED", true, true},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			state := testState(t)
+			driver := state.Get("driver").(*common.DriverMock)
+			errCh := make(chan error, 1)
+			errCh <- errors.New("internal error")
+			close(errCh)
+			driver.CreateDiskErrCh = errCh
+			step := &StepCreateDisks{
+				DiskConfiguration: []common.BlockDevice{
+					{DiskName: "data-1", VolumeType: common.ZonalBalanced},
+					{DiskName: "data-2", VolumeType: common.ZonalBalanced},
+				},
+			}
+			assert.Equal(t, multistep.ActionHalt, step.Run(context.Background(), state))
+
+			// Only the disk that failed is checked for.
+			state.Remove("error")
+			driver.CreateDiskErrCh = nil
+			driver.CreateDiskConfigs = nil
+			if tc.status == "" {
+				driver.GetDiskErr = errors.New("googleapi: Error 404: not found")
+			} else {
+				driver.GetDiskResult = &compute.Disk{Name: "data-1", Status: tc.status}
+			}
+			assert.Equal(t, multistep.ActionContinue, step.Run(context.Background(), state))
+			assert.Equal(t, "data-1", driver.GetDiskName)
+			assert.Equal(t, tc.deleted, driver.DeleteDiskName == "data-1", "bad deletion")
+			assert.Equal(t, tc.created, len(driver.CreateDiskConfigs) == 1, "bad creation")
+			assert.NotEmpty(t, step.DiskConfiguration[0].SourceVolume)
+		})
+	}
+}
@@ -7,25 +7,32 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
 )
 
 // StepCreateImage represents a Packer build step that creates GCE machine
 // images.
-type StepCreateImage int
+type StepCreateImage struct {
+	// tried is set once the image was requested, for the image left by a
+	// failed creation to be handled when the step is run again.
+	tried bool
+}
 
 // Run executes the Packer build step that creates a GCE machine image.
 //
 // The image is created from the persistent disk used by the instance. The
-// instance must be deleted and the disk retained before doing this step.
+// instance must be deleted and the disk retained before doing this step,
+// unless force_create_image is set.
 func (s *StepCreateImage) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
 	config := state.Get("config").(*Config)
-	driver := state.Get("driver").(common.Driver)
+	driver := state.Get("driver").(common.ImageDriver)
 	ui := state.Get("ui").(packersdk.Ui)
 
 	if config.SkipCreateImage {
@@ -39,13 +46,30 @@ func (s *StepCreateImage) Run(ctx context.Context, state multistep.StateBag) mul
 		errCh := driver.DeleteImage(config.ImageProjectId, config.ImageName)
 		err := <-errCh
 		if err != nil {
-			err := fmt.Errorf("Error deleting image: %s", err)
+			err := common.EnrichError(fmt.Errorf("Error deleting image: %w", err))
 			state.Put("error", err)
 			ui.Error(err.Error())
 			return multistep.ActionHalt
 		}
+		// Not to delete it again if the step is run again.
+		config.imageAlreadyExists = false
 	}
 
+	if s.tried {
+		image, err := s.leftoverImage(driver, ui, config)
+		if err != nil {
+			err := common.EnrichError(fmt.Errorf("Error checking for the image of the previous try: %w", err))
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		if image != nil {
+			putImage(state, image)
+			return multistep.ActionContinue
+		}
+	}
+	s.tried = true
+
 	ui.Say("Creating image...")
 
 	sourceDiskURI := fmt.Sprintf("/compute/v1/projects/%s/zones/%s/disks/%s", config.ProjectId, config.Zone, config.imageSourceDisk)
@@ -56,20 +80,33 @@ func (s *StepCreateImage) Run(ctx context.Context, state multistep.StateBag) mul
 			Type: v,
 		})
 	}
+	shieldedState, err := common.ShieldedInitialState(config.ImagePlatformKey, config.ImageKeyExchangeKey,
+		config.ImageSignaturesDB, config.ImageForbiddenSignaturesDB)
+	if err != nil {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
 	imagePayload := &compute.Image{
-		Description:        config.ImageDescription,
-		Name:               config.ImageName,
-		Family:             config.ImageFamily,
-		Labels:             config.ImageLabels,
-		Licenses:           config.ImageLicenses,
-		GuestOsFeatures:    imageFeatures,
-		ImageEncryptionKey: config.ImageEncryptionKey.ComputeType(),
-		SourceDisk:         sourceDiskURI,
-		SourceType:         "RAW",
-		StorageLocations:   config.ImageStorageLocations,
+		Description:                  config.ImageDescription,
+		Name:                         config.ImageName,
+		Family:                       config.ImageFamily,
+		Labels:                       config.ImageLabels,
+		Licenses:                     config.ImageLicenses,
+		GuestOsFeatures:              imageFeatures,
+		ImageEncryptionKey:           config.ImageEncryptionKey.ComputeType(),
+		SourceDisk:                   sourceDiskURI,
+		SourceType:                   "RAW",
+		StorageLocations:             config.ImageStorageLocations,
+		ShieldedInstanceInitialState: shieldedState,
 	}
-	imageCh, errCh := driver.CreateImage(config.ImageProjectId, imagePayload)
-	var err error
+	createImage := driver.CreateImage
+	if config.ForceCreateImage {
+		ui.Message("Creating the image from the disk of the running instance, it is only crash-consistent.")
+		createImage = driver.ForceCreateImage
+	}
+	imageCh, errCh := createImage(config.ImageProjectId, imagePayload)
 	select {
 	case err = <-errCh:
 	case <-time.After(config.StateTimeout):
@@ -77,15 +114,45 @@ func (s *StepCreateImage) Run(ctx context.Context, state multistep.StateBag) mul
 	}
 
 	if err != nil {
-		err := fmt.Errorf("Error waiting for image: %s", err)
+		err := common.EnrichError(fmt.Errorf("Error waiting for image: %w", err))
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
 	}
 
-	state.Put("image", <-imageCh)
+	putImage(state, <-imageCh)
 	return multistep.ActionContinue
 }
 
+// leftoverImage returns the image of the previous try of the step if it is
+// ready, and deletes it otherwise, e.g. if it failed.
+func (s *StepCreateImage) leftoverImage(driver common.ImageDriver, ui packersdk.Ui, config *Config) (*common.Image, error) {
+	image, err := driver.GetImageFromProject(config.ImageProjectId, config.ImageName, false)
+	if err != nil {
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if image.Status == "READY" {
+		ui.Say(fmt.Sprintf("Image %s was created by the previous try, using it...", image.Name))
+		return image, nil
+	}
+
+	ui.Say(fmt.Sprintf("Deleting image %s left %s by the previous try...", image.Name, image.Status))
+	if err := <-driver.DeleteImage(config.ImageProjectId, config.ImageName); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func putImage(state multistep.StateBag, image *common.Image) {
+	state.Put("image", image)
+	// The artifact holds every image of the build, the primary one first.
+	images, _ := state.Get("images").([]*common.Image)
+	state.Put("images", append([]*common.Image{image}, images...))
+}
+
 // Cleanup.
 func (s *StepCreateImage) Cleanup(state multistep.StateBag) {}
@@ -6,11 +6,14 @@ package googlecompute
 import (
 	"context"
 	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/googleapi"
 )
 
 func TestStepCreateImage_impl(t *testing.T) {
@@ -50,6 +53,31 @@ func TestStepCreateImage(t *testing.T) {
 	assert.Equal(t, c.ProjectId, d.CreateImageProjectId, "Incorrect project ID passed to driver.")
 }
 
+func TestStepCreateImage_secureBootKeys(t *testing.T) {
+	state := testState(t)
+	step := new(StepCreateImage)
+	defer step.Cleanup(state)
+
+	db := filepath.Join(t.TempDir(), "db.esl")
+	if err := os.WriteFile(db, []byte("EFI signature list"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	c := state.Get("config").(*Config)
+	c.ImageSignaturesDB = []string{db}
+	d := state.Get("driver").(*common.DriverMock)
+
+	action := step.Run(context.Background(), state)
+	assert.Equal(t, action, multistep.ActionContinue, "Step did not pass.")
+
+	initialState := d.CreateImageSpec.ShieldedInstanceInitialState
+	if initialState == nil || len(initialState.Dbs) != 1 {
+		t.Fatalf("the signature database should be set, got %#v", initialState)
+	}
+	assert.Equal(t, "BIN", initialState.Dbs[0].FileType, "EFI signature lists should be sent as binary data")
+	assert.Nil(t, initialState.Pk, "No platform key should be set")
+}
+
 func TestStepCreateImage_errorOnChannel(t *testing.T) {
 	state := testState(t)
 	step := new(StepCreateImage)
@@ -69,3 +97,61 @@ func TestStepCreateImage_errorOnChannel(t *testing.T) {
 	_, ok = state.GetOk("image_name")
 	assert.False(t, ok, "State should not have a resulting image.")
 }
+
+func TestStepCreateImage_force(t *testing.T) {
+	state := testState(t)
+	step := new(StepCreateImage)
+	defer step.Cleanup(state)
+
+	c := state.Get("config").(*Config)
+	d := state.Get("driver").(*common.DriverMock)
+
+	action := step.Run(context.Background(), state)
+	assert.Equal(t, multistep.ActionContinue, action, "Step did not pass.")
+	assert.False(t, d.CreateImageForced, "The image should not be forced by default.")
+
+	c.ForceCreateImage = true
+	step = new(StepCreateImage)
+	action = step.Run(context.Background(), state)
+	assert.Equal(t, multistep.ActionContinue, action, "Step did not pass.")
+	assert.True(t, d.CreateImageForced, "The image should be forced.")
+}
+
+func TestStepCreateImage_retry(t *testing.T) {
+	cases := map[string]struct {
+		status  string
+		deleted bool
+		created bool
+	}{
+		"none":   {"", false, true},
+		"ready":  {"READY", false, false},
+		"failed": {"FAILED", true, true},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			state := testState(t)
+			step := new(StepCreateImage)
+			c := state.Get("config").(*Config)
+			d := state.Get("driver").(*common.DriverMock)
+
+			errCh := make(chan error, 1)
+			errCh <- &googleapi.Error{Code: 503}
+			d.CreateImageErrCh = errCh
+			assert.Equal(t, multistep.ActionHalt, step.Run(context.Background(), state))
+
+			// The image left by the failed creation is checked for.
+			state.Remove("error")
+			d.CreateImageErrCh = nil
+			d.CreateImageSpecs = nil
+			if tc.status == "" {
+				d.GetImageFromProjectErr = &googleapi.Error{Code: 404}
+			} else {
+				d.GetImageFromProjectResult = &common.Image{Name: c.ImageName, Status: tc.status}
+			}
+			assert.Equal(t, multistep.ActionContinue, step.Run(context.Background(), state))
+			assert.Equal(t, tc.deleted, d.DeleteImageName == c.ImageName, "bad deletion")
+			assert.Equal(t, tc.created, len(d.CreateImageSpecs) == 1, "bad creation")
+			assert.Equal(t, c.ImageName, state.Get("image").(*common.Image).Name)
+		})
+	}
+}
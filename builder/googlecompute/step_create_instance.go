@@ -9,6 +9,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -16,6 +18,7 @@ import (
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer-plugin-sdk/packerbuilderdata"
+	"github.com/hashicorp/packer-plugin-sdk/retry"
 )
 
 // StepCreateInstance represents a Packer build step that creates GCE instances.
@@ -43,7 +46,9 @@ func (c *Config) createInstanceMetadata(sourceImage *common.Image, sshPublicKey
 		}
 	}
 
-	if c.Comm.SSHPrivateKeyFile == "" && sshPublicKey != "" {
+	// With OS Login, the key is imported in the login profile of the account
+	// instead, the guest ignores the keys of the metadata.
+	if c.Comm.SSHPrivateKeyFile == "" && sshPublicKey != "" && !c.UseOSLogin {
 		sshMetaKey := "ssh-keys"
 		sshPublicKey = strings.TrimSuffix(sshPublicKey, "\n")
 		sshKeys := fmt.Sprintf("%s:%s %s", c.Comm.SSHUsername, sshPublicKey, c.Comm.SSHUsername)
@@ -64,9 +69,19 @@ func (c *Config) createInstanceMetadata(sourceImage *common.Image, sshPublicKey
 	}
 	instanceMetadataNoSSHKeys[StartupScriptKey] = startupScript
 
-	// Wrap any found startup script with our own startup script wrapper.
+	// Wrap any found startup script with our own startup script wrapper, or
+	// the one of the configuration.
 	if startupScript != "" && c.WrapStartupScriptFile.True() {
-		instanceMetadataNoSSHKeys[StartupScriptKey] = StartupScriptLinux
+		wrapper := StartupScriptLinux
+		if c.StartupScriptWrapperFile != "" {
+			var content []byte
+			content, err = ioutil.ReadFile(c.StartupScriptWrapperFile)
+			if err != nil {
+				return nil, instanceMetadataNoSSHKeys, err
+			}
+			wrapper = string(content)
+		}
+		instanceMetadataNoSSHKeys[StartupScriptKey] = wrapper
 		instanceMetadataNoSSHKeys[StartupWrappedScriptKey] = startupScript
 		instanceMetadataNoSSHKeys[StartupScriptStatusKey] = StartupScriptStatusNotDone
 	}
@@ -77,6 +92,37 @@ func (c *Config) createInstanceMetadata(sourceImage *common.Image, sshPublicKey
 		instanceMetadataNoSSHKeys[StartupScriptStatusKey] = StartupScriptStatusDone
 	}
 
+	// Serve a shell on the serial port before running the startup script,
+	// wrapped or not.
+	if c.SerialPortFallback && !sourceImage.IsWindows() {
+		if startupScript := instanceMetadataNoSSHKeys[StartupScriptKey]; startupScript != "" {
+			instanceMetadataNoSSHKeys[SerialWrappedStartupScriptKey] = startupScript
+		}
+		instanceMetadataNoSSHKeys[StartupScriptKey] = SerialPortStartupScript
+		instanceMetadataNoSSHKeys[SerialPortEnableKey] = "TRUE"
+	}
+
+	// Run the WinRM HTTPS bootstrap before any user-provided Windows
+	// startup script.
+	if c.WinRMHTTPSBootstrap {
+		script := fmt.Sprintf(WinRMHTTPSBootstrapScript, c.Comm.WinRMPort)
+		if userScript := instanceMetadataNoSSHKeys[WindowsStartupScriptKey]; userScript != "" {
+			script = script + "\n" + userScript
+		}
+		instanceMetadataNoSSHKeys[WindowsStartupScriptKey] = script
+		instanceMetadataNoSSHKeys[EnableGuestAttributesKey] = "TRUE"
+	}
+
+	// Report when Windows is ready, before any other startup script.
+	if sourceImage.IsWindows() && c.Comm.Type == "winrm" {
+		script := WindowsReadyScript
+		if userScript := instanceMetadataNoSSHKeys[WindowsStartupScriptKey]; userScript != "" {
+			script = script + userScript
+		}
+		instanceMetadataNoSSHKeys[WindowsStartupScriptKey] = script
+		instanceMetadataNoSSHKeys[EnableGuestAttributesKey] = "TRUE"
+	}
+
 	// If UseOSLogin is true, force `enable-oslogin` in metadata
 	// In the event that `enable-oslogin` is not enabled at project level
 	if c.UseOSLogin {
@@ -105,11 +151,40 @@ func getImage(c *Config, d common.Driver) (*common.Image, error) {
 		name = c.SourceImage
 		fromFamily = false
 	}
-	if len(c.SourceImageProjectId) == 0 {
-		return d.GetImage(name, fromFamily)
-	} else {
+	lookup := func() (*common.Image, error) {
+		if len(c.SourceImageProjectId) == 0 {
+			return d.GetImage(name, fromFamily)
+		}
 		return d.GetImageFromProjects(c.SourceImageProjectId, name, fromFamily)
 	}
+	if !fromFamily {
+		return lookup()
+	}
+
+	// Parallel builds from the same family start from the same image.
+	projects := c.SourceImageProjectId
+	if len(projects) == 0 {
+		projects = []string{c.ProjectId}
+	}
+	return sharedSourceImages.resolve(sourceImageCacheKey(projects, name), lookup)
+}
+
+// checkSourceImageKey checks that the source image encrypted with a
+// customer-supplied key is given one, instead of failing to create the
+// instance with a bare 400 from the API. Only raw keys can be matched with
+// the digest of the key of the image.
+func checkSourceImageKey(c *Config, image *common.Image) error {
+	if image.EncryptionKeySha256 == "" {
+		return nil
+	}
+	key := c.SourceImageEncryptionKey
+	if key == nil {
+		return fmt.Errorf("Image %s is encrypted with a customer-supplied key, set source_image_encryption_key to that key.", image.Name)
+	}
+	if key.RawKey != "" && key.RawKeySha256() != image.EncryptionKeySha256 {
+		return fmt.Errorf("The source_image_encryption_key does not match the key encrypting image %s.", image.Name)
+	}
+	return nil
 }
 
 // Run executes the Packer build step that creates a GCE instance.
@@ -121,7 +196,7 @@ func (s *StepCreateInstance) Run(ctx context.Context, state multistep.StateBag)
 
 	sourceImage, err := getImage(c, d)
 	if err != nil {
-		err := fmt.Errorf("Error getting source image for instance creation: %s", err)
+		err := common.EnrichError(fmt.Errorf("Error getting source image for instance creation: %w", err))
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
@@ -132,6 +207,8 @@ func (s *StepCreateInstance) Run(ctx context.Context, state multistep.StateBag)
 	if s.GeneratedData != nil {
 		// Store source image name for use in PARtifact.
 		s.GeneratedData.Put("SourceImageName", sourceImage.Name)
+		s.GeneratedData.Put("SourceImageProjectId", sourceImage.ProjectId)
+		s.GeneratedData.Put("SourceImageSelfLink", sourceImage.SelfLink)
 	}
 
 	if c.EnableSecureBoot && !sourceImage.IsSecureBootCompatible() {
@@ -141,16 +218,38 @@ func (s *StepCreateInstance) Run(ctx context.Context, state multistep.StateBag)
 		return multistep.ActionHalt
 	}
 
+	if err := checkSourceImageKey(c, sourceImage); err != nil {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
 	ui.Say(fmt.Sprintf("Using image: %s", sourceImage.Name))
 
-	if sourceImage.IsWindows() && c.Comm.Type == "winrm" && c.Comm.WinRMPassword == "" {
-		state.Put("create_windows_password", true)
+	var sourceSnapshot string
+	if c.CheckpointName != "" {
+		snapshot := checkpointSnapshotName(c, sourceImage)
+		state.Put("checkpoint_snapshot", snapshot)
+		sourceSnapshot, err = findCheckpointSnapshot(d, c, snapshot)
+		if err != nil {
+			ui.Error(fmt.Sprintf("Error looking for checkpoint snapshot %s, not using it: %s", snapshot, err))
+		}
+		if sourceSnapshot != "" {
+			ui.Say(fmt.Sprintf("Using checkpoint snapshot: %s", snapshot))
+		}
+		state.Put("checkpoint_restored", sourceSnapshot != "")
+	}
+
+	if sourceImage.IsWindows() && c.Comm.Type == "winrm" {
+		state.Put("wait_windows_ready", true)
+		if c.Comm.WinRMPassword == "" {
+			state.Put("create_windows_password", true)
+		}
 	}
 
 	ui.Say("Creating instance...")
 	name := c.InstanceName
 
-	var errCh <-chan error
 	var metadataNoSSHKeys map[string]string
 	var metadataSSHKeys map[string]string
 	metadataForInstance := make(map[string]string)
@@ -162,7 +261,13 @@ func (s *StepCreateInstance) Run(ctx context.Context, state multistep.StateBag)
 		return multistep.ActionHalt
 	}
 
-	if c.WaitToAddSSHKeys > 0 {
+	waitToAddSSHKeys := c.WaitToAddSSHKeys
+	if reason := sshKeysWaitSkipReason(c, metadataSSHKeys); waitToAddSSHKeys > 0 && reason != "" {
+		ui.Say(fmt.Sprintf("Not waiting %s to add SSH keys: %s", waitToAddSSHKeys, reason))
+		waitToAddSSHKeys = 0
+	}
+
+	if waitToAddSSHKeys > 0 {
 		log.Printf("[DEBUG] Adding metadata during instance creation, but not SSH keys...")
 		metadataForInstance = metadataNoSSHKeys
 	} else {
@@ -173,16 +278,18 @@ func (s *StepCreateInstance) Run(ctx context.Context, state multistep.StateBag)
 		addmap(metadataForInstance, metadataNoSSHKeys)
 	}
 
-	errCh, err = d.RunInstance(&common.InstanceConfig{
+	instanceConfig := &common.InstanceConfig{
 		AcceleratorType:              c.AcceleratorType,
 		AcceleratorCount:             c.AcceleratorCount,
 		Address:                      c.Address,
+		BulkInsert:                   bulkInsertCoordinator(ctx, c),
 		Description:                  "New instance created by Packer",
 		DisableDefaultServiceAccount: c.DisableDefaultServiceAccount,
 		DiskName:                     c.DiskName,
 		DiskSizeGb:                   c.DiskSizeGb,
 		DiskType:                     c.DiskType,
 		DiskEncryptionKey:            c.DiskEncryptionKey,
+		SourceImageEncryptionKey:     c.SourceImageEncryptionKey,
 		EnableNestedVirtualization:   c.EnableNestedVirtualization,
 		EnableSecureBoot:             c.EnableSecureBoot,
 		EnableVtpm:                   c.EnableVtpm,
@@ -199,26 +306,57 @@ func (s *StepCreateInstance) Run(ctx context.Context, state multistep.StateBag)
 		OmitExternalIP:               c.OmitExternalIP,
 		OnHostMaintenance:            c.OnHostMaintenance,
 		Preemptible:                  c.Preemptible,
+		ProvisioningModel:            c.ProvisioningModel,
 		NodeAffinities:               c.NodeAffinities,
 		Region:                       c.Region,
 		ServiceAccountEmail:          c.ServiceAccountEmail,
 		Scopes:                       c.Scopes,
+		SourceSnapshot:               sourceSnapshot,
 		Subnetwork:                   c.Subnetwork,
 		Tags:                         c.Tags,
 		Zone:                         c.Zone,
-	})
+	}
 
-	if err == nil {
-		ui.Message("Waiting for creation operation to complete...")
-		select {
-		case err = <-errCh:
-		case <-time.After(c.StateTimeout):
-			err = errors.New("time out while waiting for instance to create")
+	warm := false
+	if c.WarmPoolSize > 0 {
+		instanceConfig.WarmPool = common.WarmPoolKey(instanceConfig)
+		state.Put("warm_pool", instanceConfig.WarmPool)
+		warmName, err := d.ClaimWarmInstance(c.Zone, instanceConfig.WarmPool)
+		if err != nil {
+			ui.Error(fmt.Sprintf("Error looking for a warm instance, creating one: %s", err))
+		}
+		if warmName != "" {
+			ui.Say(fmt.Sprintf("Reusing warm instance %s...", warmName))
+			warm = true
+			name = warmName
+			c.InstanceName = warmName
+			instanceConfig.Name = warmName
+		}
+	}
+
+	inserted := false
+	if warm {
+		err = d.StartWarmInstance(name, instanceConfig)
+		if err != nil {
+			releaseInstance(d, ui, c, name, instanceConfig.WarmPool)
+		}
+	} else {
+		inserted, err = s.runInstance(ctx, d, ui, c, instanceConfig)
+	}
+	if err != nil && !warm && c.SpotFallback && instanceConfig.ProvisioningModel == "SPOT" {
+		ui.Error(fmt.Sprintf("Spot instance could not be provisioned: %s", err))
+		ui.Say("Falling back to a STANDARD instance...")
+		// Nothing was created if the insert request itself failed, e.g.
+		// for lack of quota.
+		if inserted {
+			deleteInstance(d, ui, c, name)
 		}
+		instanceConfig.ProvisioningModel = "STANDARD"
+		_, err = s.runInstance(ctx, d, ui, c, instanceConfig)
 	}
 
 	if err != nil {
-		err := fmt.Errorf("Error creating instance: %s", err)
+		err := common.EnrichError(fmt.Errorf("Error creating instance: %w", err))
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
@@ -237,21 +375,25 @@ func (s *StepCreateInstance) Run(ctx context.Context, state multistep.StateBag)
 	// instance_id is the generic term used so that users can have access to the
 	// instance id inside of the provisioners, used in step_provision.
 	state.Put("instance_id", name)
+	if s.GeneratedData != nil {
+		s.GeneratedData.Put("InstanceName", name)
+		s.GeneratedData.Put("InstanceZone", c.Zone)
+	}
 
-	if c.WaitToAddSSHKeys > 0 {
+	if waitToAddSSHKeys > 0 {
 		ui.Message(fmt.Sprintf("Waiting %s before adding SSH keys...",
-			c.WaitToAddSSHKeys.String()))
-		cancelled := s.waitForBoot(ctx, c.WaitToAddSSHKeys)
+			waitToAddSSHKeys.String()))
+		cancelled := s.waitForBoot(ctx, waitToAddSSHKeys)
 		if cancelled {
 			return multistep.ActionHalt
 		}
 
 		log.Printf("[DEBUG] %s wait is over. Adding SSH keys to existing instance...",
-			c.WaitToAddSSHKeys.String())
+			waitToAddSSHKeys.String())
 		err = d.AddToInstanceMetadata(c.Zone, name, metadataSSHKeys)
 
 		if err != nil {
-			err := fmt.Errorf("Error adding SSH keys to existing instance: %s", err)
+			err := common.EnrichError(fmt.Errorf("Error adding SSH keys to existing instance: %w", err))
 			state.Put("error", err)
 			ui.Error(err.Error())
 			return multistep.ActionHalt
@@ -261,6 +403,35 @@ func (s *StepCreateInstance) Run(ctx context.Context, state multistep.StateBag)
 	return multistep.ActionContinue
 }
 
+// bulkInsertCoordinator returns the coordinator of the bulk inserts of the
+// builds of the Packer run, or nil if the instance is created on its own.
+// The build gives up on the bulk insert with ctx, or after state_timeout.
+func bulkInsertCoordinator(ctx context.Context, c *Config) *common.BulkInsertCoordinator {
+	runUUID := os.Getenv("PACKER_RUN_UUID")
+	if !c.BulkInsert || runUUID == "" {
+		return nil
+	}
+	pruneRunTempDirs(runTempDir(runUUID))
+	return &common.BulkInsertCoordinator{
+		Dir:     filepath.Join(runTempDir(runUUID), "bulk-insert"),
+		Window:  c.BulkInsertWindow,
+		Context: ctx,
+		Timeout: c.StateTimeout,
+	}
+}
+
+// sshKeysWaitSkipReason returns why adding the SSH keys of the metadata
+// after wait_to_add_ssh_keys is unnecessary, or "" if it is.
+func sshKeysWaitSkipReason(c *Config, metadataSSHKeys map[string]string) string {
+	switch {
+	case c.UseOSLogin:
+		return "OS Login is used, the guest ignores the SSH keys of the metadata"
+	case len(metadataSSHKeys) == 0:
+		return "there is no SSH key to add to the metadata"
+	}
+	return ""
+}
+
 func (s *StepCreateInstance) waitForBoot(ctx context.Context, waitLen time.Duration) bool {
 	// Use a select to determine if we get cancelled during the wait
 	select {
@@ -287,12 +458,76 @@ func (s *StepCreateInstance) Cleanup(state multistep.StateBag) {
 	driver := state.Get("driver").(common.Driver)
 	ui := state.Get("ui").(packersdk.Ui)
 
+	if pool, _ := state.Get("warm_pool").(string); pool != "" {
+		releaseInstance(driver, ui, config, name, pool)
+	} else {
+		deleteInstance(driver, ui, config, name)
+	}
+	state.Put("instance_name", "")
+}
+
+// errSpotPreempted is returned when a Spot instance stops before it reaches
+// the RUNNING state.
+var errSpotPreempted = errors.New("spot instance was preempted before it started running")
+
+// runInstance creates the instance and waits for the creation operation to
+// complete. Spot instances that may fall back to the STANDARD provisioning
+// model are also watched until they are running, so that an early preemption
+// can be caught here. It returns whether the insert request was accepted,
+// i.e. whether an instance may exist despite an error.
+func (s *StepCreateInstance) runInstance(ctx context.Context, d common.Driver, ui packersdk.Ui, c *Config, instanceConfig *common.InstanceConfig) (bool, error) {
+	errCh, err := d.RunInstance(instanceConfig)
+	if err != nil {
+		return false, err
+	}
+
+	ui.Message("Waiting for creation operation to complete...")
+	select {
+	case err = <-errCh:
+	case <-time.After(c.StateTimeout):
+		err = errors.New("time out while waiting for instance to create")
+	}
+	if err != nil {
+		return true, err
+	}
+
+	if !c.SpotFallback || instanceConfig.ProvisioningModel != "SPOT" {
+		return true, nil
+	}
+
+	ui.Message("Waiting for the spot instance to start running...")
+	return true, retry.Config{
+		StartTimeout: c.StateTimeout,
+		ShouldRetry: func(err error) bool {
+			return !errors.Is(err, errSpotPreempted)
+		},
+		RetryDelay: (&retry.Backoff{InitialBackoff: 2 * time.Second, MaxBackoff: 10 * time.Second, Multiplier: 2}).Linear,
+	}.Run(ctx, func(ctx context.Context) error {
+		status, err := d.GetInstanceStatus(c.Zone, instanceConfig.Name)
+		if err != nil {
+			return err
+		}
+
+		switch status {
+		case "RUNNING":
+			return nil
+		case "STOPPING", "STOPPED", "SUSPENDING", "SUSPENDED", "TERMINATED":
+			return errSpotPreempted
+		default:
+			return fmt.Errorf("instance is %s", status)
+		}
+	})
+}
+
+// deleteInstance deletes the instance and its boot disk, which is not removed
+// along with the instance.
+func deleteInstance(d common.Driver, ui packersdk.Ui, c *Config, name string) {
 	ui.Say("Deleting instance...")
-	errCh, err := driver.DeleteInstance(config.Zone, name)
+	errCh, err := d.DeleteInstance(c.Zone, name)
 	if err == nil {
 		select {
 		case err = <-errCh:
-		case <-time.After(config.StateTimeout):
+		case <-time.After(c.StateTimeout):
 			err = errors.New("time out while waiting for instance to delete")
 		}
 	}
@@ -305,15 +540,35 @@ func (s *StepCreateInstance) Cleanup(state multistep.StateBag) {
 	}
 
 	ui.Message("Instance has been deleted!")
-	state.Put("instance_name", "")
 
-	// Deleting the instance does not remove the boot disk. This cleanup removes
-	// the disk.
+	deleteDisk(d, ui, c)
+}
+
+// releaseInstance returns the instance to the warm pool, and deletes its boot
+// disk. The instance is deleted instead if the pool is full, or if it could
+// not be released, its state being unknown.
+func releaseInstance(d common.Driver, ui packersdk.Ui, c *Config, name, pool string) {
+	ui.Say("Returning instance to the warm pool...")
+	kept, err := d.ReleaseWarmInstance(c.Zone, name, pool, c.WarmPoolSize)
+	if err != nil {
+		ui.Error(fmt.Sprintf("Error returning instance to the warm pool: %s", err))
+	}
+	if !kept {
+		deleteInstance(d, ui, c, name)
+		return
+	}
+	ui.Message("Instance has been stopped in the warm pool!")
+
+	deleteDisk(d, ui, c)
+}
+
+func deleteDisk(d common.Driver, ui packersdk.Ui, c *Config) {
 	ui.Say("Deleting disk...")
-	errCh = driver.DeleteDisk(config.Zone, config.DiskName)
+	var err error
+	errCh := d.DeleteDisk(c.Zone, c.DiskName)
 	select {
 	case err = <-errCh:
-	case <-time.After(config.StateTimeout):
+	case <-time.After(c.StateTimeout):
 		err = errors.New("time out while waiting for disk to delete")
 	}
 
@@ -321,7 +576,7 @@ func (s *StepCreateInstance) Cleanup(state multistep.StateBag) {
 		ui.Error(fmt.Sprintf(
 			"Error deleting disk. Please delete it manually.\n\n"+
 				"Name: %s\n"+
-				"Error: %s", config.InstanceName, err))
+				"Error: %s", c.InstanceName, err))
 	}
 
 	ui.Message("Disk has been deleted!")
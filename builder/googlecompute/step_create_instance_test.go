@@ -7,6 +7,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -271,6 +273,53 @@ func TestStepCreateInstance_errorTimeout(t *testing.T) {
 	assert.False(t, ok, "State should not have an instance name.")
 }
 
+func TestStepCreateInstance_spotFallback(t *testing.T) {
+	state := testState(t)
+	step := new(StepCreateInstance)
+	defer step.Cleanup(state)
+
+	state.Put("ssh_public_key", "key")
+	generatedData := &packerbuilderdata.GeneratedData{State: state}
+	step.GeneratedData = generatedData
+
+	c := state.Get("config").(*Config)
+	c.ProvisioningModel = "SPOT"
+	c.SpotFallback = true
+	d := state.Get("driver").(*common.DriverMock)
+	d.GetImageResult = StubImage("test-image", "test-project", []string{}, 100)
+	d.GetInstanceStatusResult = "TERMINATED"
+
+	// run the step
+	assert.Equal(t, step.Run(context.Background(), state), multistep.ActionContinue, "Step should have passed and continued.")
+
+	// The preempted spot instance should have been deleted, and recreated as a standard instance.
+	assert.Equal(t, c.InstanceName, d.GetInstanceStatusName, "Incorrect instance name passed to driver.")
+	assert.Equal(t, c.InstanceName, d.DeleteInstanceName, "Preempted spot instance should have been deleted.")
+	assert.Equal(t, "STANDARD", d.RunInstanceConfig.ProvisioningModel, "Instance should have been recreated as a standard instance.")
+}
+
+func TestStepCreateInstance_spotFallbackInsertError(t *testing.T) {
+	state := testState(t)
+	step := new(StepCreateInstance)
+	defer step.Cleanup(state)
+
+	state.Put("ssh_public_key", "key")
+	step.GeneratedData = &packerbuilderdata.GeneratedData{State: state}
+
+	c := state.Get("config").(*Config)
+	c.ProvisioningModel = "SPOT"
+	c.SpotFallback = true
+	d := state.Get("driver").(*common.DriverMock)
+	d.GetImageResult = StubImage("test-image", "test-project", []string{}, 100)
+	d.RunInstanceErr = errors.New("Quota 'PREEMPTIBLE_CPUS' exceeded")
+
+	assert.Equal(t, multistep.ActionHalt, step.Run(context.Background(), state), "Step should have failed and halted.")
+
+	// The rejected insert created nothing to delete.
+	assert.Equal(t, "", d.DeleteInstanceName, "No instance should have been deleted.")
+	assert.Equal(t, "STANDARD", d.RunInstanceConfig.ProvisioningModel, "Instance should have been retried as a standard instance.")
+}
+
 func TestStepCreateInstance_noServiceAccount(t *testing.T) {
 	state := testState(t)
 	step := new(StepCreateInstance)
@@ -370,6 +419,38 @@ func TestCreateInstanceMetadata_metadataFile(t *testing.T) {
 	assert.Equal(t, metadataNoSSHKeys["user-data"], content, "user-data field of the instance metadata should have been updated.")
 }
 
+func TestCreateInstanceMetadata_winRMHTTPSBootstrap(t *testing.T) {
+	state := testState(t)
+	c := state.Get("config").(*Config)
+	image := StubImage("test-image", "test-project", []string{"windows"}, 100)
+	c.WinRMHTTPSBootstrap = true
+	c.Comm.WinRMPort = 5986
+	c.Metadata = map[string]string{WindowsStartupScriptKey: "Write-Output 'user script'"}
+
+	metadataNoSSHKeys, _, err := c.createInstanceMetadata(image, "")
+
+	assert.True(t, err == nil, "Metadata creation should have succeeded.")
+	script := metadataNoSSHKeys[WindowsStartupScriptKey]
+	assert.Contains(t, script, "-Transport HTTPS -Address * -Port 5986", "The bootstrap script should create the HTTPS listener.")
+	assert.True(t, strings.HasSuffix(script, "\nWrite-Output 'user script'"), "The user-provided script should run after the bootstrap script.")
+	assert.Equal(t, "TRUE", metadataNoSSHKeys[EnableGuestAttributesKey], "The guest attributes should be enabled.")
+}
+
+func TestCreateInstanceMetadata_serialPortFallback(t *testing.T) {
+	state := testState(t)
+	c := state.Get("config").(*Config)
+	image := StubImage("test-image", "test-project", []string{}, 100)
+	c.SerialPortFallback = true
+	c.Metadata = map[string]string{StartupScriptKey: "echo user script"}
+
+	metadataNoSSHKeys, _, err := c.createInstanceMetadata(image, "")
+
+	assert.True(t, err == nil, "Metadata creation should have succeeded.")
+	assert.Equal(t, SerialPortStartupScript, metadataNoSSHKeys[StartupScriptKey], "The startup script should serve the serial port shell.")
+	assert.Equal(t, "echo user script", metadataNoSSHKeys[SerialWrappedStartupScriptKey], "The user-provided script should run after the serial port shell.")
+	assert.Equal(t, "TRUE", metadataNoSSHKeys[SerialPortEnableKey], "The serial port access should be enabled.")
+}
+
 func TestCreateInstanceMetadata_withWrapStartupScript(t *testing.T) {
 	tt := []struct {
 		WrapStartupScript            config.Trilean
@@ -411,6 +492,26 @@ func TestCreateInstanceMetadata_withWrapStartupScript(t *testing.T) {
 	}
 }
 
+func TestCreateInstanceMetadata_withStartupScriptWrapperFile(t *testing.T) {
+	state := testState(t)
+	image := StubImage("test-image", "test-project", []string{}, 100)
+	c := state.Get("config").(*Config)
+	c.StartupScriptFile = testMetadataFile(t)
+	c.StartupScriptWrapperFile = filepath.Join(t.TempDir(), "wrapper.sh")
+	c.WrapStartupScriptFile = config.TriTrue
+	wrapper := "#!/bin/bash\n/opt/orchestrator run-startup-script packer-wrapped-startup-script\n"
+	if err := os.WriteFile(c.StartupScriptWrapperFile, []byte(wrapper), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	metadataNoSSHKeys, _, err := c.createInstanceMetadata(image, "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, wrapper, metadataNoSSHKeys[StartupScriptKey], "The wrapper of the configuration should replace the Packer one.")
+	assert.Equal(t, testMetadataFileContent, metadataNoSSHKeys[StartupWrappedScriptKey])
+	assert.Equal(t, StartupScriptStatusNotDone, metadataNoSSHKeys[StartupScriptStatusKey])
+}
+
 func TestCreateInstanceMetadataWaitToAddSSHKeys(t *testing.T) {
 	state := testState(t)
 	c := state.Get("config").(*Config)
@@ -520,3 +621,180 @@ func TestImage_IsWindows(t *testing.T) {
 	i = StubImage("foo", "foo-project", []string{"license-foo", "windows-license"}, 100)
 	assert.True(t, i.IsWindows())
 }
+
+func TestCreateInstanceMetadataOSLogin(t *testing.T) {
+	state := testState(t)
+	c := state.Get("config").(*Config)
+	image := StubImage("test-image", "test-project", []string{}, 100)
+	c.UseOSLogin = true
+
+	metadataNoSSHKeys, metadataSSHKeys, err := c.createInstanceMetadata(image, "abcdefgh12345678")
+
+	assert.True(t, err == nil, "Metadata creation should have succeeded.")
+	assert.Empty(t, metadataSSHKeys, "Instance metadata should not contain the SSH key with OS Login")
+	assert.Equal(t, "TRUE", metadataNoSSHKeys[EnableOSLoginKey])
+}
+
+func TestSSHKeysWaitSkipReason(t *testing.T) {
+	sshKeys := map[string]string{"ssh-keys": "user:ssh-ed25519 AAAA user"}
+
+	assert.Empty(t, sshKeysWaitSkipReason(&Config{}, sshKeys))
+	assert.Contains(t, sshKeysWaitSkipReason(&Config{UseOSLogin: true}, sshKeys), "OS Login")
+	assert.Contains(t, sshKeysWaitSkipReason(&Config{}, map[string]string{}), "no SSH key")
+}
+
+func TestStepCreateInstanceWaitToAddSSHKeysOSLogin(t *testing.T) {
+	state := testState(t)
+	step := new(StepCreateInstance)
+	defer step.Cleanup(state)
+
+	c := state.Get("config").(*Config)
+	d := state.Get("driver").(*common.DriverMock)
+	d.GetImageResult = StubImage("test-image", "test-project", []string{}, 100)
+
+	c.WaitToAddSSHKeys = time.Hour
+	c.UseOSLogin = true
+	c.Comm.SSHPublicKey = []byte("abcdefgh12345678")
+
+	// The step would be cancelled if it waited.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	assert.Equal(t, multistep.ActionContinue, step.Run(ctx, state), "Step should not wait to add SSH keys.")
+	assert.Empty(t, d.AddToInstanceMetadataKVPairs, "No SSH key should be added to the instance.")
+}
+
+func TestCreateInstanceMetadata_windowsReady(t *testing.T) {
+	state := testState(t)
+	c := state.Get("config").(*Config)
+	image := StubImage("test-image", "test-project", []string{"windows"}, 100)
+	c.Comm.Type = "winrm"
+	c.Metadata = map[string]string{WindowsStartupScriptKey: "Write-Output 'user script'"}
+
+	metadataNoSSHKeys, _, err := c.createInstanceMetadata(image, "")
+
+	assert.True(t, err == nil, "Metadata creation should have succeeded.")
+	script := metadataNoSSHKeys[WindowsStartupScriptKey]
+	assert.True(t, strings.HasPrefix(script, WindowsReadyScript), "Windows should report it is ready first.")
+	assert.True(t, strings.HasSuffix(script, "Write-Output 'user script'"), "The user-provided script should still run.")
+	assert.Equal(t, "TRUE", metadataNoSSHKeys[EnableGuestAttributesKey], "The guest attributes should be enabled.")
+
+	c.Comm.Type = "ssh"
+	metadataNoSSHKeys, _, _ = c.createInstanceMetadata(image, "")
+	assert.Equal(t, "Write-Output 'user script'", metadataNoSSHKeys[WindowsStartupScriptKey])
+}
+
+func TestStepCreateInstance_warmPool(t *testing.T) {
+	state := testState(t)
+	step := new(StepCreateInstance)
+	defer step.Cleanup(state)
+
+	state.Put("ssh_public_key", "key")
+	c := state.Get("config").(*Config)
+	c.WarmPoolSize = 2
+	d := state.Get("driver").(*common.DriverMock)
+	d.GetImageResult = StubImage("test-image", "test-project", []string{}, 100)
+
+	// Without an idle instance, one is created in the pool.
+	assert.Equal(t, multistep.ActionContinue, step.Run(context.Background(), state))
+	pool := state.Get("warm_pool").(string)
+	assert.NotEmpty(t, pool)
+	assert.Equal(t, pool, d.ClaimWarmInstancePool)
+	assert.Equal(t, pool, d.RunInstanceConfig.WarmPool, "the instance should join the pool")
+	assert.Equal(t, c.InstanceName, state.Get("instance_name"))
+
+	// An idle instance is reused.
+	state.Put("instance_name", "")
+	d.RunInstanceConfig = nil
+	d.ClaimWarmInstanceResult = "packer-warm"
+	assert.Equal(t, multistep.ActionContinue, step.Run(context.Background(), state))
+	assert.Nil(t, d.RunInstanceConfig, "no instance should be created")
+	assert.Equal(t, "packer-warm", d.StartWarmInstanceName)
+	assert.Equal(t, c.DiskName, d.StartWarmInstanceConfig.DiskName)
+	assert.Equal(t, "packer-warm", c.InstanceName)
+	assert.Equal(t, "packer-warm", state.Get("instance_name"))
+}
+
+func TestStepCreateInstance_warmPoolStartError(t *testing.T) {
+	state := testState(t)
+	step := new(StepCreateInstance)
+	defer step.Cleanup(state)
+
+	state.Put("ssh_public_key", "key")
+	c := state.Get("config").(*Config)
+	c.WarmPoolSize = 2
+	d := state.Get("driver").(*common.DriverMock)
+	d.GetImageResult = StubImage("test-image", "test-project", []string{}, 100)
+	d.ClaimWarmInstanceResult = "packer-warm"
+	d.StartWarmInstanceErr = errors.New("error")
+	d.ReleaseWarmInstanceResult = true
+
+	// The instance goes back to the pool, only its new boot disk is deleted.
+	assert.Equal(t, multistep.ActionHalt, step.Run(context.Background(), state))
+	assert.Equal(t, "packer-warm", d.ReleaseWarmInstanceName)
+	assert.Equal(t, state.Get("warm_pool"), d.ReleaseWarmInstancePool)
+	assert.Empty(t, d.DeleteInstanceName)
+	assert.Equal(t, c.DiskName, d.DeleteDiskName)
+
+	// It is deleted if it could not be released.
+	d.ReleaseWarmInstanceResult = false
+	d.ReleaseWarmInstanceErr = errors.New("error")
+	assert.Equal(t, multistep.ActionHalt, step.Run(context.Background(), state))
+	assert.Equal(t, "packer-warm", d.DeleteInstanceName)
+}
+
+func TestStepCreateInstance_warmPoolCleanup(t *testing.T) {
+	state := testState(t)
+	step := new(StepCreateInstance)
+
+	state.Put("ssh_public_key", "key")
+	c := state.Get("config").(*Config)
+	c.WarmPoolSize = 2
+	d := state.Get("driver").(*common.DriverMock)
+	d.GetImageResult = StubImage("test-image", "test-project", []string{}, 100)
+	d.ClaimWarmInstanceResult = "packer-warm"
+	d.ReleaseWarmInstanceResult = true
+
+	// A failed build returns the instance to the pool.
+	assert.Equal(t, multistep.ActionContinue, step.Run(context.Background(), state))
+	step.Cleanup(state)
+	assert.Equal(t, "packer-warm", d.ReleaseWarmInstanceName)
+	assert.Empty(t, d.DeleteInstanceName)
+	assert.Equal(t, c.DiskName, d.DeleteDiskName)
+	assert.Equal(t, "", state.Get("instance_name"))
+}
+
+func TestStepCreateInstance_sourceImageEncryptionKey(t *testing.T) {
+	key := &common.CustomerEncryptionKey{RawKey: "SGVsbG8gZnJvbSBHb29nbGUgQ2xvdWQgUGxhdGZvcm0="}
+	image := StubImage("test-image", "test-project", []string{}, 100)
+	image.EncryptionKeySha256 = key.RawKeySha256()
+
+	cases := map[string]struct {
+		key  *common.CustomerEncryptionKey
+		want multistep.StepAction
+	}{
+		"no key":          {nil, multistep.ActionHalt},
+		"wrong raw key":   {&common.CustomerEncryptionKey{RawKey: "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}, multistep.ActionHalt},
+		"raw key":         {key, multistep.ActionContinue},
+		"RSA-wrapped key": {&common.CustomerEncryptionKey{RsaEncryptedKey: "ieCx"}, multistep.ActionContinue},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			state := testState(t)
+			step := new(StepCreateInstance)
+			defer step.Cleanup(state)
+			state.Put("ssh_public_key", "key")
+
+			c := state.Get("config").(*Config)
+			c.SourceImageEncryptionKey = tc.key
+			d := state.Get("driver").(*common.DriverMock)
+			d.GetImageResult = image
+
+			assert.Equal(t, tc.want, step.Run(context.Background(), state))
+			if tc.want == multistep.ActionContinue {
+				assert.Equal(t, tc.key, d.RunInstanceConfig.SourceImageEncryptionKey, "the key should be passed to the driver")
+			} else {
+				assert.Nil(t, d.RunInstanceConfig, "the instance should not be created")
+			}
+		})
+	}
+}
@@ -30,7 +30,7 @@ type StepCreateWindowsPassword struct {
 // Run executes the Packer build step that sets the windows password on a Windows GCE instance.
 func (s *StepCreateWindowsPassword) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
 	ui := state.Get("ui").(packersdk.Ui)
-	d := state.Get("driver").(common.Driver)
+	d := state.Get("driver").(common.ComputeDriver)
 	c := state.Get("config").(*Config)
 	name := state.Get("instance_name").(string)
 
@@ -103,7 +103,7 @@ func (s *StepCreateWindowsPassword) Run(ctx context.Context, state multistep.Sta
 	}
 
 	if err != nil {
-		err := fmt.Errorf("Error creating windows password: %s", err)
+		err := common.EnrichError(fmt.Errorf("Error creating windows password: %w", err))
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
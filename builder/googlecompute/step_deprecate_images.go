@@ -0,0 +1,98 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"google.golang.org/api/compute/v1"
+)
+
+// StepDeprecateImages deprecates the previous images of the family, and the
+// images listed in the configuration, with the new image as their
+// replacement.
+type StepDeprecateImages struct{}
+
+// Run executes the Packer build step that deprecates the images replaced by
+// the new one. A failure is reported without failing the build, as the
+// image is already created.
+func (s *StepDeprecateImages) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(common.ImageDriver)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	image, ok := state.Get("image").(*common.Image)
+	if !ok {
+		return multistep.ActionContinue
+	}
+
+	names, err := imagesToDeprecate(driver, config, state)
+	if err != nil {
+		ui.Error(common.EnrichError(fmt.Errorf("Error listing the images of family %s, not deprecating them: %w", config.ImageFamily, err)).Error())
+	}
+	if len(names) == 0 {
+		return multistep.ActionContinue
+	}
+
+	ui.Say(fmt.Sprintf("Deprecating the images replaced by %s...", image.Name))
+	for _, name := range names {
+		err := driver.DeprecateImage(config.ImageProjectId, name, &compute.DeprecationStatus{
+			State:       "DEPRECATED",
+			Replacement: image.SelfLink,
+		})
+		if err != nil {
+			ui.Error(common.EnrichError(fmt.Errorf("Error deprecating image %s: %w", name, err)).Error())
+			continue
+		}
+		ui.Message(fmt.Sprintf("Image %s deprecated", name))
+	}
+	return multistep.ActionContinue
+}
+
+// imagesToDeprecate returns the names of the images to deprecate: those of
+// deprecate_images, then the images of the family not deprecated yet, but
+// the images of the build.
+func imagesToDeprecate(driver common.ImageDriver, config *Config, state multistep.StateBag) ([]string, error) {
+	built := map[string]bool{}
+	if image, ok := state.Get("image").(*common.Image); ok {
+		built[image.Name] = true
+	}
+	images, _ := state.Get("images").([]*common.Image)
+	for _, image := range images {
+		built[image.Name] = true
+	}
+
+	var names []string
+	seen := map[string]bool{}
+	add := func(name string) {
+		if !built[name] && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for _, name := range config.DeprecateImages {
+		add(name)
+	}
+	if !config.DeprecateFamilyImages {
+		return names, nil
+	}
+
+	family, err := driver.ListImages(config.ImageProjectId, fmt.Sprintf("family = %q", config.ImageFamily))
+	if err != nil {
+		return names, err
+	}
+	for _, image := range family {
+		if image.Deprecated == nil || image.Deprecated.State == "" || image.Deprecated.State == "ACTIVE" {
+			add(image.Name)
+		}
+	}
+	return names, nil
+}
+
+// Cleanup.
+func (s *StepDeprecateImages) Cleanup(state multistep.StateBag) {}
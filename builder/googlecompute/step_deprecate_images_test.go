@@ -0,0 +1,60 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/compute/v1"
+)
+
+func TestStepDeprecateImages(t *testing.T) {
+	state := testState(t)
+	step := new(StepDeprecateImages)
+	defer step.Cleanup(state)
+
+	c := state.Get("config").(*Config)
+	c.ImageFamily = "app"
+	c.DeprecateFamilyImages = true
+	c.DeprecateImages = []string{"app-legacy", "app-v1"}
+	image := StubImage("app-v3", c.ImageProjectId, []string{}, 10)
+	state.Put("image", image)
+
+	d := state.Get("driver").(*common.DriverMock)
+	d.ListImagesResult = []*compute.Image{
+		{Name: "app-v1"},
+		{Name: "app-v2", Deprecated: &compute.DeprecationStatus{State: "ACTIVE"}},
+		{Name: "app-v0", Deprecated: &compute.DeprecationStatus{State: "DEPRECATED"}},
+		{Name: "app-v3"},
+	}
+
+	assert.Equal(t, multistep.ActionContinue, step.Run(context.Background(), state))
+	assert.Equal(t, `family = "app"`, d.ListImagesFilter)
+	assert.Equal(t, c.ImageProjectId, d.DeprecateImageProject)
+	assert.Equal(t, []string{"app-legacy", "app-v1", "app-v2"}, d.DeprecateImageNames, "the deprecated images and the new one should be skipped")
+	assert.Equal(t, &compute.DeprecationStatus{State: "DEPRECATED", Replacement: image.SelfLink}, d.DeprecateImageStatus)
+}
+
+func TestStepDeprecateImages_failure(t *testing.T) {
+	state := testState(t)
+	step := new(StepDeprecateImages)
+	defer step.Cleanup(state)
+
+	c := state.Get("config").(*Config)
+	c.DeprecateImages = []string{"app-v1"}
+	state.Put("image", StubImage("app-v2", c.ImageProjectId, []string{}, 10))
+
+	d := state.Get("driver").(*common.DriverMock)
+	d.DeprecateImageErr = errors.New("forbidden")
+
+	assert.Equal(t, multistep.ActionContinue, step.Run(context.Background(), state), "a failure should not fail the build")
+	_, ok := state.GetOk("error")
+	assert.False(t, ok)
+	assert.Empty(t, d.ListImagesFilter, "the family should not be listed")
+}
@@ -0,0 +1,46 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// StepHTTPReverseTunnel exposes the HTTP server to the instance on the same
+// port of its localhost, with a reverse SSH tunnel, since the instance
+// usually cannot reach the Packer host, e.g. through IAP or without a
+// public IP.
+type StepHTTPReverseTunnel struct {
+	CommConf *communicator.Config
+}
+
+// Run executes the Packer build step that adds the reverse tunnel.
+func (s *StepHTTPReverseTunnel) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	port, ok := state.GetOk("http_port")
+	if !ok || port.(int) == 0 {
+		return multistep.ActionContinue
+	}
+	if s.CommConf.Type != "ssh" {
+		ui := state.Get("ui").(packersdk.Ui)
+		ui.Say(fmt.Sprintf("The instance may not reach the HTTP server on port %d, "+
+			"it is only forwarded to the instance with the ssh communicator.", port))
+		return multistep.ActionContinue
+	}
+
+	tunnel := fmt.Sprintf("%[1]d:localhost:%[1]d", port)
+	log.Printf("Forwarding the HTTP server to the instance: %s", tunnel)
+	s.CommConf.SSHRemoteTunnels = append(s.CommConf.SSHRemoteTunnels, tunnel)
+	state.Put("http_ip", "127.0.0.1")
+
+	return multistep.ActionContinue
+}
+
+// Cleanup does nothing, the tunnel closes with the SSH connection.
+func (s *StepHTTPReverseTunnel) Cleanup(state multistep.StateBag) {}
@@ -0,0 +1,29 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStepHTTPReverseTunnel(t *testing.T) {
+	state := testState(t)
+	c := state.Get("config").(*Config)
+	c.Comm.Type = "ssh"
+	step := &StepHTTPReverseTunnel{CommConf: &c.Comm}
+	defer step.Cleanup(state)
+
+	state.Put("http_port", 0)
+	assert.Equal(t, multistep.ActionContinue, step.Run(context.Background(), state))
+	assert.Empty(t, c.Comm.SSHRemoteTunnels, "Nothing should be forwarded without an HTTP server.")
+
+	state.Put("http_port", 8123)
+	assert.Equal(t, multistep.ActionContinue, step.Run(context.Background(), state))
+	assert.Equal(t, []string{"8123:localhost:8123"}, c.Comm.SSHRemoteTunnels)
+	assert.Equal(t, "127.0.0.1", state.Get("http_ip"), "The provisioners should fetch through the tunnel.")
+}
@@ -0,0 +1,176 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+)
+
+// ImageChecksumMetadataKey is the metadata of the verifier instance holding
+// the SHA-256 of the image, set by ImageChecksumScript.
+const ImageChecksumMetadataKey = "packer-image-sha256"
+
+// imageChecksumImageKey is the metadata of the verifier instance holding
+// the image to hash.
+const imageChecksumImageKey = "packer-image"
+
+var validSHA256 = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// ImageChecksumScript hashes a read-only disk created from the image, and
+// sets the checksum in the metadata of the instance. The disk is deleted
+// with the instance.
+var ImageChecksumScript string = fmt.Sprintf(`#!/bin/bash
+GetMetadata () {
+  echo "$(curl -f -H "Metadata-Flavor: Google" http://metadata.google.internal/computeMetadata/v1/instance/$1 2> /dev/null)"
+}
+
+ZONE=$(basename $(GetMetadata zone))
+NAME=$(GetMetadata name)
+IMAGE=$(GetMetadata attributes/%[1]s)
+DISKNAME=${NAME}-image
+
+echo "Creating disk from image ${IMAGE}..."
+if ! gcloud compute disks create ${DISKNAME} --image ${IMAGE} --zone ${ZONE}; then
+  echo "Failed to create disk."
+  exit 1
+fi
+
+echo "Attaching disk..."
+if ! gcloud compute instances attach-disk ${NAME} --disk ${DISKNAME} --device-name image --mode ro --zone ${ZONE}; then
+  echo "Failed to attach disk."
+  gcloud compute disks delete ${DISKNAME} --zone ${ZONE} --quiet
+  exit 1
+fi
+gcloud compute instances set-disk-auto-delete ${NAME} --disk ${DISKNAME} --auto-delete --zone ${ZONE}
+
+DEVICE=/dev/disk/by-id/google-image
+for i in $(seq 30); do
+  [ -b ${DEVICE} ] && break
+  sleep 2
+done
+
+echo "Computing SHA-256 of ${IMAGE}..."
+if ! SHA256=$(set -o pipefail; sha256sum ${DEVICE} | cut -d' ' -f1); then
+  echo "Failed to compute SHA-256."
+  exit 1
+fi
+echo "SHA-256 - ${SHA256}"
+
+gcloud compute instances add-metadata ${NAME} --metadata %[2]s=${SHA256} --zone ${ZONE}
+`, imageChecksumImageKey, ImageChecksumMetadataKey)
+
+// StepImageChecksum computes the SHA-256 of the contents of the image with
+// a short-lived verifier instance, like the export post-processor does to
+// export it.
+type StepImageChecksum struct {
+	Debug bool
+}
+
+// verifierConfig returns the configuration of the instance hashing image.
+func verifierConfig(c *Config, image *common.Image) *Config {
+	name := c.InstanceName + "-checksum"
+	if len(name) > 63 {
+		name = name[len(name)-63:]
+	}
+	return &Config{
+		DiskName:             name,
+		DiskSizeGb:           10,
+		DiskType:             "pd-balanced",
+		InstanceName:         name,
+		Labels:               c.Labels,
+		MachineType:          c.MachineType,
+		Metadata:             map[string]string{StartupScriptKey: ImageChecksumScript, imageChecksumImageKey: image.SelfLink},
+		Network:              c.Network,
+		NetworkProjectId:     c.NetworkProjectId,
+		OmitExternalIP:       c.OmitExternalIP,
+		Region:               c.Region,
+		ServiceAccountEmail:  c.ServiceAccountEmail,
+		SourceImageFamily:    "debian-12",
+		SourceImageProjectId: []string{"debian-cloud"},
+		StateTimeout:         c.StateTimeout,
+		Subnetwork:           c.Subnetwork,
+		Tags:                 c.Tags,
+		UseInternalIP:        c.UseInternalIP,
+		Zone:                 c.Zone,
+		Scopes: []string{
+			"https://www.googleapis.com/auth/compute",
+			"https://www.googleapis.com/auth/userinfo.email",
+		},
+		WrapStartupScriptFile: config.TriTrue,
+	}
+}
+
+// Run executes the Packer build step that computes the checksum of the
+// image. A failure is reported without failing the build, as the image is
+// already created.
+func (s *StepImageChecksum) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c := state.Get("config").(*Config)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	image, ok := state.Get("image").(*common.Image)
+	if !c.ImageChecksum || !ok {
+		return multistep.ActionContinue
+	}
+
+	ui.Say(fmt.Sprintf("Computing the checksum of image %s...", image.Name))
+	verifierState := new(multistep.BasicStateBag)
+	verifierState.Put("config", verifierConfig(c, image))
+	verifierState.Put("driver", state.Get("driver"))
+	verifierState.Put("ui", ui)
+
+	ctx, cancel := context.WithTimeout(ctx, c.ImageChecksumTimeout)
+	defer cancel()
+	runner := &multistep.BasicRunner{Steps: []multistep.Step{
+		&StepCreateInstance{Debug: s.Debug},
+		new(StepWaitStartupScript),
+		new(stepReadImageChecksum),
+		&StepTeardownInstance{Debug: s.Debug},
+	}}
+	runner.Run(ctx, verifierState)
+
+	if _, ok := verifierState.GetOk("error"); ok {
+		ui.Error("The checksum of the image could not be computed, the image is kept.")
+		return multistep.ActionContinue
+	}
+	checksum := verifierState.Get("image_checksum").(string)
+	ui.Message(fmt.Sprintf("Image checksum: %s", checksum))
+	state.Put("image_checksum", checksum)
+	return multistep.ActionContinue
+}
+
+// Cleanup does nothing, the verifier instance is cleaned up by its steps.
+func (s *StepImageChecksum) Cleanup(state multistep.StateBag) {}
+
+// stepReadImageChecksum reads the checksum the verifier instance set in
+// its metadata.
+type stepReadImageChecksum struct{}
+
+func (s *stepReadImageChecksum) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c := state.Get("config").(*Config)
+	driver := state.Get("driver").(common.ComputeDriver)
+	ui := state.Get("ui").(packersdk.Ui)
+	instanceName := state.Get("instance_name").(string)
+
+	sum, err := driver.GetInstanceMetadata(c.Zone, instanceName, ImageChecksumMetadataKey)
+	if err == nil && !validSHA256.MatchString(sum) {
+		err = fmt.Errorf("invalid SHA-256 %q", sum)
+	}
+	if err != nil {
+		err := fmt.Errorf("Error reading the checksum of the image: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	state.Put("image_checksum", "sha256:"+sum)
+	return multistep.ActionContinue
+}
+
+func (s *stepReadImageChecksum) Cleanup(state multistep.StateBag) {}
@@ -0,0 +1,97 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifierConfig(t *testing.T) {
+	c := &Config{
+		InstanceName: "packer-123",
+		MachineType:  "e2-medium",
+		Network:      "default",
+		Zone:         "us-central1-a",
+	}
+	image := &common.Image{Name: "image", SelfLink: "https://compute/images/image"}
+
+	verifier := verifierConfig(c, image)
+	assert.Equal(t, "packer-123-checksum", verifier.InstanceName)
+	assert.Equal(t, verifier.InstanceName, verifier.DiskName)
+	assert.Equal(t, "default", verifier.Network)
+	assert.Equal(t, image.SelfLink, verifier.Metadata[imageChecksumImageKey])
+	assert.Equal(t, ImageChecksumScript, verifier.Metadata[StartupScriptKey])
+
+	c.InstanceName = strings.Repeat("a", 63)
+	verifier = verifierConfig(c, image)
+	assert.Len(t, verifier.InstanceName, 63)
+	assert.True(t, strings.HasSuffix(verifier.InstanceName, "-checksum"))
+}
+
+func TestStepImageChecksum_disabled(t *testing.T) {
+	state := testState(t)
+	state.Put("image", &common.Image{Name: "image"})
+	step := new(StepImageChecksum)
+	defer step.Cleanup(state)
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("image_checksum"); ok {
+		t.Fatal("should not compute the checksum")
+	}
+}
+
+func TestStepReadImageChecksum(t *testing.T) {
+	sum := strings.Repeat("ab", 32)
+	state := testState(t)
+	state.Put("instance_name", "verifier")
+	driver := state.Get("driver").(*common.DriverMock)
+	driver.GetInstanceMetadataResult = sum
+	step := new(stepReadImageChecksum)
+	defer step.Cleanup(state)
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	assert.Equal(t, "verifier", driver.GetInstanceMetadataName)
+	assert.Equal(t, ImageChecksumMetadataKey, driver.GetInstanceMetadataKey)
+	assert.Equal(t, "sha256:"+sum, state.Get("image_checksum"))
+}
+
+func TestStepReadImageChecksum_invalid(t *testing.T) {
+	cases := map[string]struct {
+		result string
+		err    error
+	}{
+		"invalid": {result: "not a checksum"},
+		"error":   {err: errors.New("metadata not found")},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			state := testState(t)
+			state.Put("instance_name", "verifier")
+			driver := state.Get("driver").(*common.DriverMock)
+			driver.GetInstanceMetadataResult = tc.result
+			driver.GetInstanceMetadataErr = tc.err
+
+			if action := new(stepReadImageChecksum).Run(context.Background(), state); action != multistep.ActionHalt {
+				t.Fatalf("bad action: %#v", action)
+			}
+			if _, ok := state.GetOk("error"); !ok {
+				t.Fatal("should have error")
+			}
+			if _, ok := state.GetOk("image_checksum"); ok {
+				t.Fatal("should not have a checksum")
+			}
+		})
+	}
+}
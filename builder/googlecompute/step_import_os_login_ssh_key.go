@@ -31,7 +31,7 @@ type StepImportOSLoginSSHKey struct {
 // The key pairs are added to the ssh config
 func (s *StepImportOSLoginSSHKey) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
 	config := state.Get("config").(*Config)
-	driver := state.Get("driver").(common.Driver)
+	driver := state.Get("driver").(common.OSLoginDriver)
 	ui := state.Get("ui").(packersdk.Ui)
 
 	if !config.UseOSLogin {
@@ -83,6 +83,11 @@ func (s *StepImportOSLoginSSHKey) Run(ctx context.Context, state multistep.State
 		s.accountEmail = s.GCEUserFunc()
 	}
 
+	// When impersonating, the OSLogin user is the impersonated account.
+	if s.accountEmail == "" && config.ImpersonateServiceAccount != "" {
+		s.accountEmail = config.ImpersonateServiceAccount
+	}
+
 	if s.accountEmail == "" {
 		err := fmt.Errorf("All options for deriving the OSLogin user have been exhausted")
 		state.Put("error", err)
@@ -92,7 +97,7 @@ func (s *StepImportOSLoginSSHKey) Run(ctx context.Context, state multistep.State
 
 	loginProfile, err := driver.ImportOSLoginSSHKey(s.accountEmail, string(config.Comm.SSHPublicKey))
 	if err != nil {
-		err := fmt.Errorf("Error importing SSH public key for OSLogin: %s", err)
+		err := common.EnrichError(fmt.Errorf("Error importing SSH public key for OSLogin: %w", err))
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
@@ -124,7 +129,7 @@ func (s *StepImportOSLoginSSHKey) Run(ctx context.Context, state multistep.State
 
 // Cleanup the SSH Key that we added to the POSIX account
 func (s *StepImportOSLoginSSHKey) Cleanup(state multistep.StateBag) {
-	driver := state.Get("driver").(common.Driver)
+	driver := state.Get("driver").(common.OSLoginDriver)
 	ui := state.Get("ui").(packersdk.Ui)
 
 	fingerprint, ok := state.Get("ssh_key_public_sha256").(string)
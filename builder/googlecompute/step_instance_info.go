@@ -7,23 +7,28 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/packerbuilderdata"
 )
 
 // stepInstanceInfo represents a Packer build step that gathers GCE instance info.
 type StepInstanceInfo struct {
 	Debug bool
+	// GeneratedData receives the InstanceInternalIP and InstanceExternalIP
+	// of the instance.
+	GeneratedData *packerbuilderdata.GeneratedData
 }
 
 // Run executes the Packer build step that gathers GCE instance info.
 // This adds "instance_ip" to the multistep state.
 func (s *StepInstanceInfo) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
 	config := state.Get("config").(*Config)
-	driver := state.Get("driver").(common.Driver)
+	driver := state.Get("driver").(common.ComputeDriver)
 	ui := state.Get("ui").(packersdk.Ui)
 
 	instanceName := state.Get("instance_name").(string)
@@ -38,7 +43,7 @@ func (s *StepInstanceInfo) Run(ctx context.Context, state multistep.StateBag) mu
 	}
 
 	if err != nil {
-		err := fmt.Errorf("Error waiting for instance: %s", err)
+		err := common.EnrichError(fmt.Errorf("Error waiting for instance: %w", err))
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
@@ -47,7 +52,7 @@ func (s *StepInstanceInfo) Run(ctx context.Context, state multistep.StateBag) mu
 	if config.UseInternalIP {
 		ip, err := driver.GetInternalIP(config.Zone, instanceName)
 		if err != nil {
-			err := fmt.Errorf("Error retrieving instance internal ip address: %s", err)
+			err := common.EnrichError(fmt.Errorf("Error retrieving instance internal ip address: %w", err))
 			state.Put("error", err)
 			ui.Error(err.Error())
 			return multistep.ActionHalt
@@ -60,11 +65,12 @@ func (s *StepInstanceInfo) Run(ctx context.Context, state multistep.StateBag) mu
 		}
 		ui.Message(fmt.Sprintf("IP: %s", ip))
 		state.Put("instance_ip", ip)
+		s.putAddresses(driver, config.Zone, instanceName, ip, "")
 		return multistep.ActionContinue
 	} else {
 		ip, err := driver.GetNatIP(config.Zone, instanceName)
 		if err != nil {
-			err := fmt.Errorf("Error retrieving instance nat ip address: %s", err)
+			err := common.EnrichError(fmt.Errorf("Error retrieving instance nat ip address: %w", err))
 			state.Put("error", err)
 			ui.Error(err.Error())
 			return multistep.ActionHalt
@@ -77,9 +83,30 @@ func (s *StepInstanceInfo) Run(ctx context.Context, state multistep.StateBag) mu
 		}
 		ui.Message(fmt.Sprintf("IP: %s", ip))
 		state.Put("instance_ip", ip)
+		s.putAddresses(driver, config.Zone, instanceName, "", ip)
 		return multistep.ActionContinue
 	}
 }
 
+// putAddresses puts both addresses of the instance in the generated data,
+// getting the one not already known. The instance may not have an external
+// one.
+func (s *StepInstanceInfo) putAddresses(driver common.ComputeDriver, zone, name, internalIP, natIP string) {
+	if s.GeneratedData == nil {
+		return
+	}
+	var err error
+	if internalIP == "" {
+		internalIP, err = driver.GetInternalIP(zone, name)
+	} else {
+		natIP, err = driver.GetNatIP(zone, name)
+	}
+	if err != nil {
+		log.Printf("[WARN] Error retrieving the addresses of the instance: %s", err)
+	}
+	s.GeneratedData.Put("InstanceInternalIP", internalIP)
+	s.GeneratedData.Put("InstanceExternalIP", natIP)
+}
+
 // Cleanup.
 func (s *StepInstanceInfo) Cleanup(state multistep.StateBag) {}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/packerbuilderdata"
 )
 
 func TestStepInstanceInfo_impl(t *testing.T) {
@@ -55,6 +56,30 @@ func TestStepInstanceInfo(t *testing.T) {
 	}
 }
 
+func TestStepInstanceInfo_generatedData(t *testing.T) {
+	state := testState(t)
+	step := &StepInstanceInfo{GeneratedData: &packerbuilderdata.GeneratedData{State: state}}
+	defer step.Cleanup(state)
+
+	state.Put("instance_name", "foo")
+
+	driver := state.Get("driver").(*common.DriverMock)
+	driver.GetNatIPResult = "1.2.3.4"
+	driver.GetInternalIPResult = "5.6.7.8"
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	data := state.Get("generated_data").(map[string]interface{})
+	if data["InstanceExternalIP"] != "1.2.3.4" {
+		t.Fatalf("bad external ip: %#v", data["InstanceExternalIP"])
+	}
+	if data["InstanceInternalIP"] != "5.6.7.8" {
+		t.Fatalf("bad internal ip: %#v", data["InstanceInternalIP"])
+	}
+}
+
 func TestStepInstanceInfo_InternalIP(t *testing.T) {
 	state := testState(t)
 	step := new(StepInstanceInfo)
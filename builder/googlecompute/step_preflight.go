@@ -0,0 +1,254 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"google.golang.org/api/compute/v1"
+)
+
+// iapSourceRange is the range the IAP TCP forwarding connects from.
+const iapSourceRange = "35.235.240.0/20"
+
+// errPreflightSkipped means that a preflight check does not apply to the
+// build.
+var errPreflightSkipped = errors.New("skipped")
+
+// preflightCheck is a check of the preflight mode. It returns the details
+// of its success, errPreflightSkipped, or the reason it failed.
+type preflightCheck struct {
+	Name  string
+	Check func(c *Config, d common.Driver, ui packersdk.Ui) (string, error)
+}
+
+// preflightChecks are the checks of preflight_only, in order.
+var preflightChecks = []preflightCheck{
+	{"credentials", checkCredentials},
+	{"permissions", func(c *Config, d common.Driver, ui packersdk.Ui) (string, error) {
+		for _, project := range permissionProjects(c) {
+			err := common.CheckPermissions(ui, "project "+project, permissionRequirements(c, project), func(permissions []string) ([]string, error) {
+				return d.TestProjectPermissions(project, permissions)
+			})
+			if err != nil {
+				return "", err
+			}
+		}
+		return strings.Join(permissionProjects(c), ", "), nil
+	}},
+	{"firewall", checkFirewall},
+	{"private Google access", func(c *Config, d common.Driver, ui packersdk.Ui) (string, error) {
+		if c.GoogleAccess == "" && !c.OmitExternalIP {
+			return "", errPreflightSkipped
+		}
+		_, _, name := subnetworkRef(c)
+		err := checkGoogleAccess(c, d)
+		if errors.Is(err, errSubnetworkUnknown) {
+			return name, errPreflightSkipped
+		}
+		return name, err
+	}},
+	{"quotas", func(c *Config, d common.Driver, ui packersdk.Ui) (string, error) {
+		return c.Region, checkQuotas(c, d)
+	}},
+}
+
+// StepPreflight runs the preflight checks, and reports their results as a
+// table. All the checks are run, even once one failed.
+type StepPreflight struct{}
+
+// Run executes the Packer build step that runs the preflight checks.
+func (s *StepPreflight) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c := state.Get("config").(*Config)
+	d := state.Get("driver").(common.Driver)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	ui.Say("Running preflight checks...")
+	var table bytes.Buffer
+	w := tabwriter.NewWriter(&table, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CHECK\tRESULT\tDETAILS")
+	var failed []string
+	for _, check := range preflightChecks {
+		details, err := check.Check(c, d, ui)
+		result := "PASS"
+		switch {
+		case errors.Is(err, errPreflightSkipped):
+			result = "SKIP"
+		case err != nil:
+			result = "FAIL"
+			details = strings.SplitN(common.EnrichError(err).Error(), "\n", 2)[0]
+			failed = append(failed, check.Name)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", check.Name, result, details)
+	}
+	w.Flush()
+	ui.Say(fmt.Sprintf("Preflight checks:\n%s", strings.TrimRight(table.String(), "\n")))
+
+	if len(failed) > 0 {
+		err := fmt.Errorf("Preflight checks failed: %s", strings.Join(failed, ", "))
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	return multistep.ActionContinue
+}
+
+// Cleanup.
+func (s *StepPreflight) Cleanup(state multistep.StateBag) {}
+
+// checkCredentials checks that the credentials are valid, and returns the
+// account they authenticate.
+func checkCredentials(c *Config, d common.Driver, ui packersdk.Ui) (string, error) {
+	info, err := d.GetTokenInfo()
+	if err != nil {
+		return "", fmt.Errorf("Error getting the token information: %w", err)
+	}
+	if info.Email == "" {
+		return "authenticated", nil
+	}
+	return info.Email, nil
+}
+
+// checkFirewall checks that the firewall rules of the network let the
+// communicator reach the instance: from the IAP range with use_iap, or from
+// anywhere else otherwise, as where Packer runs from is not known.
+func checkFirewall(c *Config, d common.Driver, ui packersdk.Ui) (string, error) {
+	port := c.Comm.Port()
+	if c.Comm.Type == "none" || port == 0 {
+		return "", errPreflightSkipped
+	}
+
+	network := c.Network
+	if c.Subnetwork != "" {
+		project, region, name := subnetworkRef(c)
+		subnetwork, err := d.GetSubnetwork(project, region, name)
+		if err != nil {
+			return "", fmt.Errorf("Error getting subnetwork %s: %w", name, err)
+		}
+		network = subnetwork.Network
+	}
+	network = network[strings.LastIndex(network, "/")+1:]
+
+	firewalls, err := d.ListFirewalls(c.NetworkProjectId)
+	if err != nil {
+		return "", fmt.Errorf("Error listing the firewall rules: %w", err)
+	}
+	source := ""
+	if c.IAPConfig.IAP {
+		source = iapSourceRange
+	}
+	rule := matchingFirewall(firewalls, c, network, port, source)
+	switch {
+	case rule == nil:
+		return "", fmt.Errorf("no firewall rule of network %s allows TCP port %d to the instance", network, port)
+	case len(rule.Denied) > 0:
+		return "", fmt.Errorf("firewall rule %s of network %s denies TCP port %d to the instance", rule.Name, network, port)
+	}
+	return fmt.Sprintf("%s allows TCP port %d", rule.Name, port), nil
+}
+
+// matchingFirewall returns the enabled ingress rule of network with the
+// highest priority applying to TCP port of the instance, from source if not
+// empty, or nil if none does.
+func matchingFirewall(firewalls []*compute.Firewall, c *Config, network string, port int, source string) *compute.Firewall {
+	var match *compute.Firewall
+	for _, rule := range firewalls {
+		if rule.Disabled || (rule.Direction != "" && rule.Direction != "INGRESS") ||
+			rule.Network[strings.LastIndex(rule.Network, "/")+1:] != network {
+			continue
+		}
+		if !firewallTargets(rule, c) || (source != "" && !firewallSources(rule, source)) {
+			continue
+		}
+		rules := rule.Allowed
+		if len(rule.Denied) > 0 {
+			rules = nil
+			for _, denied := range rule.Denied {
+				rules = append(rules, &compute.FirewallAllowed{IPProtocol: denied.IPProtocol, Ports: denied.Ports})
+			}
+		}
+		if !firewallPorts(rules, port) {
+			continue
+		}
+		if match == nil || rule.Priority < match.Priority ||
+			(rule.Priority == match.Priority && len(rule.Denied) > 0) {
+			match = rule
+		}
+	}
+	return match
+}
+
+// firewallTargets returns whether rule applies to the instance.
+func firewallTargets(rule *compute.Firewall, c *Config) bool {
+	if len(rule.TargetTags) == 0 && len(rule.TargetServiceAccounts) == 0 {
+		return true
+	}
+	for _, tag := range rule.TargetTags {
+		for _, t := range c.Tags {
+			if tag == t {
+				return true
+			}
+		}
+	}
+	for _, account := range rule.TargetServiceAccounts {
+		if account == c.ServiceAccountEmail {
+			return true
+		}
+	}
+	return false
+}
+
+// firewallSources returns whether rule applies to the range source.
+func firewallSources(rule *compute.Firewall, source string) bool {
+	_, want, err := net.ParseCIDR(source)
+	if err != nil {
+		return false
+	}
+	for _, r := range rule.SourceRanges {
+		_, allowed, err := net.ParseCIDR(r)
+		if err != nil {
+			continue
+		}
+		ones, _ := allowed.Mask.Size()
+		wantOnes, _ := want.Mask.Size()
+		if ones <= wantOnes && allowed.Contains(want.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// firewallPorts returns whether rules cover TCP port.
+func firewallPorts(rules []*compute.FirewallAllowed, port int) bool {
+	for _, r := range rules {
+		if r.IPProtocol != "tcp" && r.IPProtocol != "all" {
+			continue
+		}
+		if len(r.Ports) == 0 {
+			return true
+		}
+		for _, p := range r.Ports {
+			from, to, isRange := strings.Cut(p, "-")
+			if !isRange {
+				to = from
+			}
+			low, err1 := strconv.Atoi(from)
+			high, err2 := strconv.Atoi(to)
+			if err1 == nil && err2 == nil && low <= port && port <= high {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/compute/v1"
+	oauth2_svc "google.golang.org/api/oauth2/v2"
+)
+
+func testPreflightState(t *testing.T) (multistep.StateBag, *common.DriverMock) {
+	state := testState(t)
+	driver := state.Get("driver").(*common.DriverMock)
+	driver.GetTokenInfoResult = &oauth2_svc.Tokeninfo{Email: "packer@hashicorp.iam.gserviceaccount.com"}
+	driver.TestProjectPermissionsResult = nil
+	driver.ListFirewallsResult = []*compute.Firewall{{
+		Name:         "default-allow-ssh",
+		Network:      "https://www.googleapis.com/compute/v1/projects/hashicorp/global/networks/default",
+		Priority:     65534,
+		SourceRanges: []string{"0.0.0.0/0"},
+		Allowed:      []*compute.FirewallAllowed{{IPProtocol: "tcp", Ports: []string{"22"}}},
+	}}
+	driver.GetMachineTypeResult = &compute.MachineType{GuestCpus: 2}
+	return state, driver
+}
+
+func TestStepPreflight(t *testing.T) {
+	state, driver := testPreflightState(t)
+	c := state.Get("config").(*Config)
+	c.PermissionsPrecheck = true
+	driver.TestProjectPermissionsErr = nil
+	driver.TestProjectPermissionsResult = nil
+
+	// Grant every permission asked for.
+	for _, project := range permissionProjects(c) {
+		for _, req := range permissionRequirements(c, project) {
+			driver.TestProjectPermissionsResult = append(driver.TestProjectPermissionsResult, req.Permissions...)
+		}
+	}
+
+	step := new(StepPreflight)
+	defer step.Cleanup(state)
+	assert.Equal(t, multistep.ActionContinue, step.Run(context.Background(), state))
+	assert.Equal(t, c.NetworkProjectId, driver.ListFirewallsProject)
+}
+
+func TestStepPreflight_failures(t *testing.T) {
+	state, driver := testPreflightState(t)
+	c := state.Get("config").(*Config)
+	driver.GetTokenInfoErr = errors.New("invalid_grant")
+	driver.GetTokenInfoResult = &oauth2_svc.Tokeninfo{}
+	driver.ListFirewallsResult = nil
+
+	step := new(StepPreflight)
+	defer step.Cleanup(state)
+	assert.Equal(t, multistep.ActionHalt, step.Run(context.Background(), state))
+
+	err, ok := state.GetOk("error")
+	if assert.True(t, ok) {
+		assert.Contains(t, err.(error).Error(), "credentials")
+		assert.Contains(t, err.(error).Error(), "firewall")
+	}
+	assert.Equal(t, c.Region, driver.GetRegionQuotasRegion, "the checks should go on after a failure")
+}
+
+func TestMatchingFirewall(t *testing.T) {
+	network := "projects/hashicorp/global/networks/default"
+	config := &Config{Tags: []string{"packer"}}
+	cases := map[string]struct {
+		rules  []*compute.Firewall
+		source string
+		want   string
+	}{
+		"allowed": {
+			rules: []*compute.Firewall{
+				{Name: "ssh", Network: network, Allowed: []*compute.FirewallAllowed{{IPProtocol: "tcp", Ports: []string{"22"}}}},
+			},
+			want: "ssh",
+		},
+		"port range": {
+			rules: []*compute.Firewall{
+				{Name: "range", Network: network, Allowed: []*compute.FirewallAllowed{{IPProtocol: "tcp", Ports: []string{"20-30"}}}},
+			},
+			want: "range",
+		},
+		"other port": {
+			rules: []*compute.Firewall{
+				{Name: "rdp", Network: network, Allowed: []*compute.FirewallAllowed{{IPProtocol: "tcp", Ports: []string{"3389"}}}},
+			},
+		},
+		"other network": {
+			rules: []*compute.Firewall{
+				{Name: "ssh", Network: "projects/hashicorp/global/networks/other", Allowed: []*compute.FirewallAllowed{{IPProtocol: "all"}}},
+			},
+		},
+		"other tag": {
+			rules: []*compute.Firewall{
+				{Name: "ssh", Network: network, TargetTags: []string{"web"}, Allowed: []*compute.FirewallAllowed{{IPProtocol: "tcp"}}},
+			},
+		},
+		"instance tag": {
+			rules: []*compute.Firewall{
+				{Name: "ssh", Network: network, TargetTags: []string{"packer"}, Allowed: []*compute.FirewallAllowed{{IPProtocol: "tcp"}}},
+			},
+			want: "ssh",
+		},
+		"disabled": {
+			rules: []*compute.Firewall{
+				{Name: "ssh", Network: network, Disabled: true, Allowed: []*compute.FirewallAllowed{{IPProtocol: "tcp"}}},
+			},
+		},
+		"higher priority deny": {
+			rules: []*compute.Firewall{
+				{Name: "ssh", Network: network, Priority: 1000, Allowed: []*compute.FirewallAllowed{{IPProtocol: "tcp"}}},
+				{Name: "deny", Network: network, Priority: 100, Denied: []*compute.FirewallDenied{{IPProtocol: "tcp", Ports: []string{"22"}}}},
+			},
+			want: "deny",
+		},
+		"IAP range": {
+			rules: []*compute.Firewall{
+				{Name: "office", Network: network, SourceRanges: []string{"203.0.113.0/24"}, Allowed: []*compute.FirewallAllowed{{IPProtocol: "tcp"}}},
+				{Name: "iap", Network: network, SourceRanges: []string{iapSourceRange}, Allowed: []*compute.FirewallAllowed{{IPProtocol: "tcp"}}},
+			},
+			source: iapSourceRange,
+			want:   "iap",
+		},
+		"no IAP range": {
+			rules: []*compute.Firewall{
+				{Name: "office", Network: network, SourceRanges: []string{"203.0.113.0/24"}, Allowed: []*compute.FirewallAllowed{{IPProtocol: "tcp"}}},
+			},
+			source: iapSourceRange,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			rule := matchingFirewall(tc.rules, config, "default", 22, tc.source)
+			if tc.want == "" {
+				assert.Nil(t, rule)
+				return
+			}
+			if assert.NotNil(t, rule) {
+				assert.Equal(t, tc.want, rule.Name)
+			}
+		})
+	}
+}
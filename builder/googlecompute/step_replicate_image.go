@@ -0,0 +1,130 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"google.golang.org/api/compute/v1"
+)
+
+// imageReplicaName returns the name of the replica of image name in
+// location.
+func imageReplicaName(name, location string) string {
+	return fmt.Sprintf("%s-%s", name, strings.ToLower(location))
+}
+
+// StepReplicateImage represents a Packer build step that copies the image to
+// other storage locations, image_replication_parallelism at a time.
+type StepReplicateImage int
+
+// Run executes the Packer build step that replicates the image. The
+// replicas are added to the images of the build, in the order of their
+// locations.
+func (s *StepReplicateImage) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(common.ImageDriver)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	image, ok := state.Get("image").(*common.Image)
+	if !ok || len(config.ImageReplicaLocations) == 0 {
+		return multistep.ActionContinue
+	}
+
+	ui.Say(fmt.Sprintf("Replicating image %s to %s...", image.Name,
+		strings.Join(config.ImageReplicaLocations, ", ")))
+
+	replicas := make([]*common.Image, len(config.ImageReplicaLocations))
+	errs := make([]error, len(config.ImageReplicaLocations))
+	slots := make(chan struct{}, config.ImageReplicationParallelism)
+	var wg sync.WaitGroup
+
+	// The driver is called from this goroutine only, the copies are waited
+	// for concurrently.
+	for i, location := range config.ImageReplicaLocations {
+		name := imageReplicaName(config.ImageName, location)
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			errs[i] = fmt.Errorf("Error replicating image to %s: %w", location, ctx.Err())
+			continue
+		}
+
+		if config.PackerForce && driver.ImageExists(config.ImageProjectId, name) {
+			ui.Message(fmt.Sprintf("Deleting previous replica %s...", name))
+			if err := <-driver.DeleteImage(config.ImageProjectId, name); err != nil {
+				errs[i] = common.EnrichError(fmt.Errorf("Error deleting replica %s: %w", name, err))
+				<-slots
+				continue
+			}
+		}
+
+		ui.Message(fmt.Sprintf("Creating replica %s in %s...", name, location))
+		imageCh, errCh := driver.CreateImage(config.ImageProjectId, &compute.Image{
+			Description:              config.ImageDescription,
+			Name:                     name,
+			Labels:                   image.Labels,
+			Licenses:                 image.Licenses,
+			GuestOsFeatures:          image.GuestOsFeatures,
+			ImageEncryptionKey:       config.ImageEncryptionKey.ComputeType(),
+			SourceImage:              image.SelfLink,
+			SourceImageEncryptionKey: config.ImageEncryptionKey.ComputeType(),
+			StorageLocations:         []string{location},
+		})
+
+		wg.Add(1)
+		go func(i int, location string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			var err error
+			select {
+			case err = <-errCh:
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+			if err == nil {
+				replicas[i] = <-imageCh
+				if replicas[i] == nil {
+					err = errors.New("the replica was not found once created")
+				}
+			}
+			if err != nil {
+				errs[i] = common.EnrichError(fmt.Errorf("Error replicating image to %s: %w", location, err))
+			}
+		}(i, location)
+	}
+	wg.Wait()
+
+	var failed []string
+	images, _ := state.Get("images").([]*common.Image)
+	for i, location := range config.ImageReplicaLocations {
+		if errs[i] != nil {
+			ui.Error(errs[i].Error())
+			failed = append(failed, location)
+			continue
+		}
+		ui.Message(fmt.Sprintf("Replica %s created in %s", replicas[i].Name, location))
+		images = append(images, replicas[i])
+	}
+	state.Put("images", images)
+
+	if len(failed) > 0 {
+		err := fmt.Errorf("Error replicating image to %s", strings.Join(failed, ", "))
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+	return multistep.ActionContinue
+}
+
+// Cleanup.
+func (s *StepReplicateImage) Cleanup(state multistep.StateBag) {}
@@ -0,0 +1,68 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStepReplicateImage(t *testing.T) {
+	state := testState(t)
+	c := state.Get("config").(*Config)
+	c.ImageReplicaLocations = []string{"europe-west1", "asia"}
+	c.ImageReplicationParallelism = 1
+	driver := state.Get("driver").(*common.DriverMock)
+	image := &common.Image{Name: c.ImageName, SelfLink: "https://compute/images/" + c.ImageName}
+	state.Put("image", image)
+	state.Put("images", []*common.Image{image})
+
+	step := new(StepReplicateImage)
+	defer step.Cleanup(state)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	specs := driver.CreateImageSpecs
+	if assert.Len(t, specs, 2) {
+		assert.Equal(t, c.ImageName+"-europe-west1", specs[0].Name)
+		assert.Equal(t, []string{"europe-west1"}, specs[0].StorageLocations)
+		assert.Equal(t, image.SelfLink, specs[0].SourceImage)
+		assert.Empty(t, specs[0].Family)
+		assert.Equal(t, c.ImageName+"-asia", specs[1].Name)
+	}
+
+	images := state.Get("images").([]*common.Image)
+	if assert.Len(t, images, 3) {
+		assert.Equal(t, image, images[0])
+		assert.Equal(t, c.ImageName+"-europe-west1", images[1].Name)
+		assert.Equal(t, c.ImageName+"-asia", images[2].Name)
+	}
+}
+
+func TestStepReplicateImage_error(t *testing.T) {
+	state := testState(t)
+	c := state.Get("config").(*Config)
+	c.ImageReplicaLocations = []string{"europe-west1"}
+	driver := state.Get("driver").(*common.DriverMock)
+	errCh := make(chan error, 1)
+	errCh <- errors.New("quota exceeded")
+	driver.CreateImageErrCh = errCh
+	image := &common.Image{Name: c.ImageName}
+	state.Put("image", image)
+	state.Put("images", []*common.Image{image})
+
+	if action := new(StepReplicateImage).Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+	assert.Len(t, state.Get("images"), 1)
+}
@@ -0,0 +1,67 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/multistep/commonsteps"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// StepRestartOnPreemption runs the steps building on the instance, and runs
+// them again, up to MaxRestarts times, when they fail because the instance
+// was preempted.
+//
+// The steps are cleaned up once they ran, deleting the instance and its
+// disks, before the next steps of the build run.
+type StepRestartOnPreemption struct {
+	Steps       []multistep.Step
+	MaxRestarts int
+}
+
+// Run executes the steps until they succeed, fail for another reason than
+// a preemption, or were restarted MaxRestarts times.
+func (s *StepRestartOnPreemption) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(common.ComputeDriver)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	for restarts := 0; ; restarts++ {
+		started := time.Now()
+		commonsteps.NewRunner(s.Steps, config.PackerConfig, ui).Run(ctx, state)
+
+		if _, ok := state.GetOk(multistep.StateCancelled); ok {
+			return multistep.ActionHalt
+		}
+		if _, ok := state.GetOk(multistep.StateHalted); !ok {
+			return multistep.ActionContinue
+		}
+		// Aborted builds keep the instance for debugging.
+		if restarts == s.MaxRestarts || config.PackerOnError == "abort" {
+			return multistep.ActionHalt
+		}
+
+		preempted, err := driver.InstancePreemptedSince(config.Zone, config.InstanceName, started)
+		if err != nil {
+			ui.Error(fmt.Sprintf("Error checking if the instance was preempted: %s", err))
+			return multistep.ActionHalt
+		}
+		if !preempted {
+			return multistep.ActionHalt
+		}
+
+		ui.Say(fmt.Sprintf("The instance was preempted, restarting the build from its creation (%d/%d)...",
+			restarts+1, s.MaxRestarts))
+		state.Remove("error")
+		state.Remove(multistep.StateHalted)
+	}
+}
+
+// Cleanup does nothing, the steps are cleaned up once they ran.
+func (s *StepRestartOnPreemption) Cleanup(state multistep.StateBag) {}
@@ -0,0 +1,59 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/stretchr/testify/assert"
+)
+
+var errConnectionLost = errors.New("connection lost")
+
+func TestStepRestartOnPreemption(t *testing.T) {
+	state := testState(t)
+	driver := state.Get("driver").(*common.DriverMock)
+	driver.InstancePreemptedSinceResult = true
+	c := state.Get("config").(*Config)
+
+	inner := &failingStep{errs: []error{errConnectionLost}}
+	step := &StepRestartOnPreemption{Steps: []multistep.Step{inner}, MaxRestarts: 2}
+	action := step.Run(context.Background(), state)
+
+	assert.Equal(t, multistep.ActionContinue, action, "the build should succeed once restarted")
+	assert.Equal(t, 2, inner.runs)
+	assert.Equal(t, 2, inner.cleanups, "each run should be cleaned up")
+	assert.Equal(t, c.InstanceName, driver.InstancePreemptedSinceName)
+	_, ok := state.GetOk("error")
+	assert.False(t, ok, "the error of the preempted run should be cleared")
+}
+
+func TestStepRestartOnPreemption_maxRestarts(t *testing.T) {
+	state := testState(t)
+	state.Get("driver").(*common.DriverMock).InstancePreemptedSinceResult = true
+
+	inner := &failingStep{errs: []error{errConnectionLost, errConnectionLost, errConnectionLost, errConnectionLost}}
+	step := &StepRestartOnPreemption{Steps: []multistep.Step{inner}, MaxRestarts: 2}
+	action := step.Run(context.Background(), state)
+
+	assert.Equal(t, multistep.ActionHalt, action)
+	assert.Equal(t, 3, inner.runs, "the build should be restarted twice")
+	_, ok := state.GetOk("error")
+	assert.True(t, ok, "the last error should be kept")
+}
+
+func TestStepRestartOnPreemption_notPreempted(t *testing.T) {
+	state := testState(t)
+
+	inner := &failingStep{errs: []error{errConnectionLost}}
+	step := &StepRestartOnPreemption{Steps: []multistep.Step{inner}, MaxRestarts: 2}
+	action := step.Run(context.Background(), state)
+
+	assert.Equal(t, multistep.ActionHalt, action)
+	assert.Equal(t, 1, inner.runs, "other failures should not restart the build")
+}
@@ -0,0 +1,65 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// transientRetryDelay is the delay before running a step again after a
+// transient error, multiplied by the number of tries so far.
+var transientRetryDelay = 10 * time.Second
+
+// retryTransient returns step, run again when it fails with a transient
+// error of the Compute Engine API, e.g. an internal error late in a long
+// build. The step must be safe to run again after failing.
+func retryTransient(step multistep.Step) multistep.Step {
+	return &retryStep{Step: step, tries: common.TransientErrorTries}
+}
+
+// retryStep runs a step up to tries times, as long as it fails with
+// transient errors.
+type retryStep struct {
+	multistep.Step
+
+	tries int
+}
+
+var _ multistep.StepWrapper = new(retryStep)
+
+// InnerStepName returns the name of the retried step, for the debug runner.
+func (s *retryStep) InnerStepName() string {
+	if wrapper, ok := s.Step.(multistep.StepWrapper); ok {
+		return wrapper.InnerStepName()
+	}
+	return reflect.Indirect(reflect.ValueOf(s.Step)).Type().Name()
+}
+
+func (s *retryStep) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packersdk.Ui)
+
+	for try := 1; ; try++ {
+		action := s.Step.Run(ctx, state)
+		err, _ := state.Get("error").(error)
+		if action != multistep.ActionHalt || !common.IsTransientError(err) || try >= s.tries {
+			return action
+		}
+
+		ui.Say(fmt.Sprintf("%s failed with a transient error, trying again (%d/%d)...",
+			s.InnerStepName(), try+1, s.tries))
+		select {
+		case <-ctx.Done():
+			return action
+		case <-time.After(time.Duration(try) * transientRetryDelay):
+		}
+		state.Remove("error")
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/googleapi"
+)
+
+// failingStep fails with errs, in turn, then succeeds.
+type failingStep struct {
+	errs     []error
+	runs     int
+	cleanups int
+}
+
+func (s *failingStep) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	s.runs++
+	if s.runs <= len(s.errs) {
+		state.Put("error", s.errs[s.runs-1])
+		return multistep.ActionHalt
+	}
+	return multistep.ActionContinue
+}
+
+func (s *failingStep) Cleanup(multistep.StateBag) {
+	s.cleanups++
+}
+
+func TestRetryTransient(t *testing.T) {
+	defer func(delay time.Duration) { transientRetryDelay = delay }(transientRetryDelay)
+	transientRetryDelay = 0
+
+	cases := map[string]struct {
+		errs   []error
+		action multistep.StepAction
+		runs   int
+	}{
+		"success":          {nil, multistep.ActionContinue, 1},
+		"transient":        {[]error{&googleapi.Error{Code: 503}}, multistep.ActionContinue, 2},
+		"not transient":    {[]error{errors.New("invalid")}, multistep.ActionHalt, 1},
+		"always transient": {[]error{&googleapi.Error{Code: 500}, &googleapi.Error{Code: 500}, &googleapi.Error{Code: 500}}, multistep.ActionHalt, 3},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			state := testState(t)
+			inner := &failingStep{errs: tc.errs}
+			step := retryTransient(inner)
+
+			assert.Equal(t, tc.action, step.Run(context.Background(), state))
+			assert.Equal(t, tc.runs, inner.runs)
+			_, failed := state.GetOk("error")
+			assert.Equal(t, tc.action == multistep.ActionHalt, failed)
+			assert.Equal(t, "failingStep", step.(multistep.StepWrapper).InnerStepName())
+		})
+	}
+}
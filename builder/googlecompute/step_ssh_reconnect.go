@@ -0,0 +1,172 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/retry"
+)
+
+// defaultSSHReconnectAttempts is the number of reconnection attempts when
+// ssh_handshake_attempts is not set.
+const defaultSSHReconnectAttempts = 10
+
+// sshProbeTimeout is the time a connection has to run a command to be
+// deemed alive.
+const sshProbeTimeout = 30 * time.Second
+
+// errSSHDisconnected is returned when the connection to the instance is
+// lost, e.g. while it reboots.
+var errSSHDisconnected = errors.New("SSH connection lost")
+
+// reconnectingComm retries the operations of a communicator failing
+// because the connection was lost, e.g. when a provisioner reboots the
+// instance or the host goes through maintenance. The SSH communicator
+// reconnects before opening a session when its connection is closed, so
+// retrying is enough to re-establish it once the instance is back.
+type reconnectingComm struct {
+	packersdk.Communicator
+
+	attempts int
+	delay    time.Duration
+}
+
+var _ packersdk.Communicator = new(reconnectingComm)
+
+// alive tells whether the connection can still run a command.
+func (c *reconnectingComm) alive(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, sshProbeTimeout)
+	defer cancel()
+
+	cmd := &packersdk.RemoteCmd{Command: "true"}
+	if err := c.Communicator.Start(ctx, cmd); err != nil {
+		return false
+	}
+	done := make(chan int, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case status := <-done:
+		return status != packersdk.CmdDisconnect
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// retry runs f until it succeeds, fails while the connection is alive, or
+// the attempts are exhausted. A nil retryable always allows retrying.
+func (c *reconnectingComm) retry(ctx context.Context, name string, retryable func() bool, f func() error) error {
+	return retry.Config{
+		Tries: c.attempts + 1,
+		ShouldRetry: func(err error) bool {
+			return errors.Is(err, errSSHDisconnected)
+		},
+		RetryDelay: (&retry.Backoff{InitialBackoff: c.delay, MaxBackoff: 30 * time.Second, Multiplier: 2}).Linear,
+	}.Run(ctx, func(ctx context.Context) error {
+		err := f()
+		if err == nil || (retryable != nil && !retryable()) || c.alive(ctx) {
+			return err
+		}
+		log.Printf("[WARN] %s failed, reconnecting: %s", name, err)
+		return fmt.Errorf("%w: %s", errSSHDisconnected, err)
+	})
+}
+
+// Start retries to start cmd; a command interrupted by a disconnection is
+// not run again, as it may not be idempotent.
+func (c *reconnectingComm) Start(ctx context.Context, cmd *packersdk.RemoteCmd) error {
+	return c.retry(ctx, "Starting the remote command", nil, func() error {
+		return c.Communicator.Start(ctx, cmd)
+	})
+}
+
+// Upload retries the upload when the input can be read again.
+func (c *reconnectingComm) Upload(dst string, r io.Reader, fi *os.FileInfo) error {
+	seeker, ok := r.(io.Seeker)
+	if !ok {
+		return c.Communicator.Upload(dst, r, fi)
+	}
+	return c.retry(context.TODO(), "Uploading "+dst, nil, func() error {
+		if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		return c.Communicator.Upload(dst, r, fi)
+	})
+}
+
+func (c *reconnectingComm) UploadDir(dst string, src string, exclude []string) error {
+	return c.retry(context.TODO(), "Uploading "+src, nil, func() error {
+		return c.Communicator.UploadDir(dst, src, exclude)
+	})
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+// Download retries the download until some output was written.
+func (c *reconnectingComm) Download(src string, w io.Writer) error {
+	cw := &countingWriter{Writer: w}
+	return c.retry(context.TODO(), "Downloading "+src, func() bool { return cw.n == 0 }, func() error {
+		return c.Communicator.Download(src, cw)
+	})
+}
+
+func (c *reconnectingComm) DownloadDir(src string, dst string, exclude []string) error {
+	return c.retry(context.TODO(), "Downloading "+src, nil, func() error {
+		return c.Communicator.DownloadDir(src, dst, exclude)
+	})
+}
+
+// StepSSHReconnect makes the SSH communicator reconnect when the instance
+// reboots or goes through maintenance.
+type StepSSHReconnect struct {
+	CommConf *communicator.Config
+	Disable  bool
+}
+
+// Run executes the Packer build step that wraps the communicator.
+func (s *StepSSHReconnect) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	if s.Disable || s.CommConf.Type != "ssh" {
+		return multistep.ActionContinue
+	}
+	comm, ok := state.GetOk("communicator")
+	if !ok {
+		return multistep.ActionContinue
+	}
+	if _, serial := comm.(*serialComm); serial {
+		return multistep.ActionContinue
+	}
+
+	attempts := s.CommConf.SSHHandshakeAttempts
+	if attempts <= 0 {
+		attempts = defaultSSHReconnectAttempts
+	}
+	state.Put("communicator", &reconnectingComm{
+		Communicator: comm.(packersdk.Communicator),
+		attempts:     attempts,
+		delay:        5 * time.Second,
+	})
+	return multistep.ActionContinue
+}
+
+// Cleanup does nothing.
+func (s *StepSSHReconnect) Cleanup(state multistep.StateBag) {}
@@ -0,0 +1,97 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyComm is a communicator failing its first down calls, like the SSH
+// communicator while the instance reboots.
+type flakyComm struct {
+	packersdk.MockCommunicator
+
+	down    int
+	calls   int
+	uploads int
+}
+
+func (c *flakyComm) Start(ctx context.Context, cmd *packersdk.RemoteCmd) error {
+	c.calls++
+	if c.calls <= c.down {
+		return errors.New("client not available")
+	}
+	cmd.SetExited(0)
+	return nil
+}
+
+func (c *flakyComm) Upload(dst string, r io.Reader, fi *os.FileInfo) error {
+	c.uploads++
+	return errors.New("permission denied")
+}
+
+func TestReconnectingComm_Start(t *testing.T) {
+	inner := &flakyComm{down: 4}
+	comm := &reconnectingComm{Communicator: inner, attempts: 3, delay: time.Millisecond}
+
+	cmd := &packersdk.RemoteCmd{Command: "echo hello"}
+	err := comm.Start(context.Background(), cmd)
+	assert.NoError(t, err, "The command should start once reconnected.")
+	// Each failure is followed by a probe of the connection.
+	assert.Equal(t, 5, inner.calls)
+
+	inner = &flakyComm{down: 100}
+	comm = &reconnectingComm{Communicator: inner, attempts: 3, delay: time.Millisecond}
+	err = comm.Start(context.Background(), cmd)
+	assert.ErrorContains(t, err, errSSHDisconnected.Error())
+	assert.Equal(t, 8, inner.calls, "The attempts should be exhausted.")
+}
+
+func TestReconnectingComm_Upload(t *testing.T) {
+	inner := new(flakyComm)
+	comm := &reconnectingComm{Communicator: inner, attempts: 3, delay: time.Millisecond}
+
+	err := comm.Upload("/tmp/file", strings.NewReader("content"), nil)
+	assert.EqualError(t, err, "permission denied", "The error should be returned as is when the connection is alive.")
+	assert.Equal(t, 1, inner.uploads)
+
+	inner = &flakyComm{down: 100}
+	comm = &reconnectingComm{Communicator: inner, attempts: 3, delay: time.Millisecond}
+	err = comm.Upload("/tmp/file", io.MultiReader(strings.NewReader("content")), nil)
+	assert.EqualError(t, err, "permission denied", "The upload should not be retried when the input cannot be read again.")
+	assert.Equal(t, 1, inner.uploads)
+}
+
+func TestStepSSHReconnect(t *testing.T) {
+	state := testState(t)
+	c := state.Get("config").(*Config)
+	c.Comm.Type = "ssh"
+	inner := new(flakyComm)
+	state.Put("communicator", inner)
+
+	step := &StepSSHReconnect{CommConf: &c.Comm}
+	defer step.Cleanup(state)
+
+	assert.Equal(t, multistep.ActionContinue, step.Run(context.Background(), state))
+	comm, ok := state.Get("communicator").(*reconnectingComm)
+	if assert.True(t, ok, "The communicator should be wrapped.") {
+		assert.Equal(t, inner, comm.Communicator)
+		assert.Equal(t, defaultSSHReconnectAttempts, comm.attempts)
+	}
+
+	state.Put("communicator", inner)
+	step.Disable = true
+	assert.Equal(t, multistep.ActionContinue, step.Run(context.Background(), state))
+	assert.Equal(t, inner, state.Get("communicator"), "The communicator should not be wrapped when disabled.")
+}
@@ -0,0 +1,67 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// StepStartSSHProxy forwards a local port to the SSH port of the instance
+// through an HTTP proxy, which the SSH communicator cannot dial through.
+type StepStartSSHProxy struct {
+	ProxyURL string
+	CommConf *communicator.Config
+
+	tunnel TunnelDriver
+}
+
+// Run executes the Packer build step that starts the tunnel through the
+// HTTP proxy.
+func (s *StepStartSSHProxy) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	if s.ProxyURL == "" || s.CommConf.Type != "ssh" {
+		return multistep.ActionContinue
+	}
+	u, err := common.ParseProxyURL(s.ProxyURL)
+	if err != nil || u.Scheme == "socks5" {
+		log.Printf("Skipping the SSH proxy tunnel, the communicator dials the proxy itself.")
+		return multistep.ActionContinue
+	}
+
+	ui := state.Get("ui").(packersdk.Ui)
+	instanceIP := state.Get("instance_ip").(string)
+
+	port, err := localPort(ctx, 0)
+	if err != nil {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Say(fmt.Sprintf("Forwarding SSH to %s through the proxy %s...", instanceIP, u.Host))
+	s.tunnel = common.NewProxyTunnel(u, fmt.Sprintf("%s:%d", instanceIP, s.CommConf.SSHPort))
+	// The instance may not accept connections yet, the communicator retries.
+	if err := s.tunnel.StartTunnel(ctx, port, 0); err != nil {
+		err = fmt.Errorf("Error starting the SSH proxy tunnel: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	s.CommConf.SSHPort = port
+
+	return multistep.ActionContinue
+}
+
+// Cleanup stops the tunnel.
+func (s *StepStartSSHProxy) Cleanup(state multistep.StateBag) {
+	if s.tunnel != nil {
+		s.tunnel.StopTunnel()
+	}
+}
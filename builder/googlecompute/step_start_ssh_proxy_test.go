@@ -0,0 +1,49 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+func TestStepStartSSHProxy(t *testing.T) {
+	s := &StepStartSSHProxy{
+		ProxyURL: "http://proxy.example.com:3128",
+		CommConf: &communicator.Config{Type: "ssh", SSH: communicator.SSH{SSHPort: 22}},
+	}
+	state := testState(t)
+	state.Put("instance_ip", "10.0.0.2")
+
+	if action := s.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", state.Get("error"))
+	}
+	defer s.Cleanup(state)
+	if s.tunnel == nil {
+		t.Fatal("the tunnel should be started")
+	}
+	if s.CommConf.SSHPort == 22 {
+		t.Error("the communicator should connect to the local port of the tunnel")
+	}
+}
+
+func TestStepStartSSHProxy_skip(t *testing.T) {
+	for _, proxyURL := range []string{"", "socks5://proxy.example.com"} {
+		s := &StepStartSSHProxy{
+			ProxyURL: proxyURL,
+			CommConf: &communicator.Config{Type: "ssh", SSH: communicator.SSH{SSHPort: 22}},
+		}
+		state := testState(t)
+
+		if action := s.Run(context.Background(), state); action != multistep.ActionContinue {
+			t.Fatalf("bad action: %#v", state.Get("error"))
+		}
+		if s.tunnel != nil || s.CommConf.SSHPort != 22 {
+			t.Errorf("%q should not start a tunnel", proxyURL)
+		}
+	}
+}
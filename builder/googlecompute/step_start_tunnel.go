@@ -2,30 +2,24 @@
 // SPDX-License-Identifier: MPL-2.0
 
 //go:generate packer-sdc struct-markdown
-//go:generate packer-sdc mapstructure-to-hcl2 -type IAPConfig
+//go:generate packer-sdc mapstructure-to-hcl2 -type IAPConfig,IAPPortForward
 
 package googlecompute
 
 import (
-	"bufio"
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"io"
 	"log"
-	"os"
-	"os/exec"
-	"runtime"
-	"strconv"
-	"strings"
 	"time"
 
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
 	"github.com/hashicorp/packer-plugin-sdk/communicator"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	"github.com/hashicorp/packer-plugin-sdk/net"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/packerbuilderdata"
 	"github.com/hashicorp/packer-plugin-sdk/retry"
-	"github.com/hashicorp/packer-plugin-sdk/tmp"
 )
 
 // StepStartTunnel represents a Packer build step that launches an IAP tunnel
@@ -33,122 +27,87 @@ type IAPConfig struct {
 	// Whether to use an IAP proxy.
 	// Prerequisites and limitations for using IAP:
 	// - You must manually enable the IAP API in the Google Cloud console.
-	// - You must have the gcloud sdk installed on the computer running Packer.
 	// - If you use a service account, you must add it to project level IAP permissions
 	//   in https://console.cloud.google.com/security/iap. To do so, click
 	//   "project" > "SSH and TCP resources" > "All Tunnel Resources" >
 	//   "Add Member". Then add your service account and choose the role
 	//   "IAP-secured Tunnel User" and add any conditions you may care about.
+	//
+	// The tunnel is implemented by the plugin, and goes through `proxy_url`
+	// when set: the gcloud SDK is not needed. When the tunnel drops, e.g. on
+	// a network blip or when its token expires, its connections are resumed,
+	// so that provisioning goes on.
 	IAP bool `mapstructure:"use_iap" required:"false"`
 	// Which port to connect the local end of the IAM localhost proxy to. If
 	// left blank, Packer will choose a port for you from available ports.
 	IAPLocalhostPort int `mapstructure:"iap_localhost_port"`
-	// What "hashbang" to use to invoke script that sets up gcloud.
-	// Default: "/bin/sh"
+	// Deprecated: the IAP tunnel no longer runs gcloud through a script, this
+	// is ignored.
 	IAPHashBang string `mapstructure:"iap_hashbang" required:"false"`
-	// What file extension to use for script that sets up gcloud.
-	// Default: ".sh"
+	// Deprecated: the IAP tunnel no longer runs gcloud through a script, this
+	// is ignored.
 	IAPExt string `mapstructure:"iap_ext" required:"false"`
-	// How long to wait, in seconds, before assuming a tunnel launch was
-	// successful. Defaults to 30 seconds for SSH or 40 seconds for WinRM.
+	// How long to wait, in seconds, for the IAP tunnel to connect to the
+	// instance. Defaults to 30 seconds for SSH or 40 seconds
+	// for WinRM.
 	IAPTunnelLaunchWait int `mapstructure:"iap_tunnel_launch_wait" required:"false"`
+	// The service account to impersonate to authenticate the IAP tunnel,
+	// e.g. a dedicated one granted `roles/iap.tunnelResourceAccessor`.
+	// Defaults to the credentials of the build. The build credentials, i.e.
+	// the `impersonate_service_account` or the Vault token when set, need
+	// `roles/iam.serviceAccountTokenCreator` on it.
+	IAPImpersonateServiceAccount string `mapstructure:"iap_impersonate_service_account" required:"false"`
+	// Additional ports of the instance to forward over IAP, e.g. an API the
+	// provisioners poll, or the HTTPS WinRM port alongside the HTTP one. The
+	// local address of each is exposed to the provisioners as the
+	// `IAPForward<remote_port>` generated data, e.g. `build.IAPForward8080`.
+	//
+	// ```hcl
+	// iap_port_forward {
+	//   remote_port = 8080
+	// }
+	// ```
+	IAPPortForwards []IAPPortForward `mapstructure:"iap_port_forward" required:"false"`
 }
 
-type TunnelDriver interface {
-	StartTunnel(context.Context, string, int) error
-	StopTunnel()
+// IAPPortForward is an additional port of the instance forwarded over IAP.
+type IAPPortForward struct {
+	// The port of the instance to forward.
+	RemotePort int `mapstructure:"remote_port" required:"true"`
+	// The local port to listen on. If left blank, Packer will choose a port
+	// for you from available ports.
+	LocalPort int `mapstructure:"local_port" required:"false"`
 }
 
-func RunTunnelCommand(cmd *exec.Cmd, timeout int) error {
-	// set stdout and stderr so we can read what's going on.
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	err := cmd.Start()
-	if err != nil {
-		err := fmt.Errorf("Error calling gcloud sdk to launch IAP tunnel: %s",
-			err)
-		return err
-	}
-
-	// Give tunnel 30 seconds to either launch, or return an error.
-	// Unfortunately, the SDK doesn't provide any official acknowledgment that
-	// the tunnel is launched when it's not being run through a TTY so we
-	// are just trusting here that 30s is enough to know whether the tunnel
-	// launch was going to fail. Yep, feels icky to me too. But I spent an
-	// afternoon trying to figure out how to get the SDK to actually send
-	// the "Listening on port [n]" line I see when I run it manually, and I
-	// can't justify spending more time than that on aesthetics.
-
-	for i := 0; i < timeout; i++ {
-		time.Sleep(1 * time.Second)
-
-		lineStderr, err := stderr.ReadString('\n')
-		if err != nil && err != io.EOF {
-			log.Printf("Err from scanning stderr is %s", err)
-			return fmt.Errorf("Error reading stderr from tunnel launch: %s", err)
-		}
-		if lineStderr != "" {
-			log.Printf("stderr: %s", lineStderr)
-		}
-
-		lineStdout, err := stdout.ReadString('\n')
-		if err != nil && err != io.EOF {
-			log.Printf("Err from scanning stdout is %s", err)
-			return fmt.Errorf("Error reading stdout from tunnel launch: %s", err)
-		}
-		if lineStdout != "" {
-			log.Printf("stdout: %s", lineStdout)
-		}
-
-		if strings.Contains(lineStderr, "ERROR") {
-			// 4033: Either you don't have permission to access the instance,
-			// the instance doesn't exist, or the instance is stopped. The two
-			// sub-errors we may see while the permissions settle are "not
-			// authorized" and "failed to connect to backend," but after about a
-			// minute of retries this goes away and we're able to connect.
-			// 4003: "failed to connect to backend". Network blip.
-			// 4047: "Either the instance doesn't exist, or the instance is
-			// stopped. Ensure that the VM is powered on and has completed its
-			// startup" — Let's wait a little more in this case.
-			if strings.Contains(lineStderr, "4033") || strings.Contains(lineStderr, "4003") || strings.Contains(lineStderr, "4047") {
-				return RetryableTunnelError{lineStderr}
-			} else {
-				log.Printf("NOT RETRYABLE: %s", lineStderr)
-				return fmt.Errorf("Non-retryable tunnel error: %s", lineStderr)
-			}
-		}
-	}
-
-	log.Printf("No error detected after tunnel launch; continuing...")
-	return nil
+// GeneratedDataKey is the name of the generated data holding the local
+// address of the forward.
+func (f IAPPortForward) GeneratedDataKey() string {
+	return fmt.Sprintf("IAPForward%d", f.RemotePort)
 }
 
-type RetryableTunnelError struct {
-	s string
-}
-
-func (e RetryableTunnelError) Error() string {
-	return "Tunnel start: " + e.s
-}
+// TunnelDriver is kept for compatibility, use common.TunnelDriver instead.
+type TunnelDriver = common.TunnelDriver
 
 type StepStartTunnel struct {
-	IAPConf            *IAPConfig
-	CommConf           *communicator.Config
-	AccountFile        string
-	ImpersonateAccount string
-	ProjectId          string
+	IAPConf       *IAPConfig
+	CommConf      *communicator.Config
+	ProjectId     string
+	GeneratedData *packerbuilderdata.GeneratedData
+	Debug         bool
 
 	tunnelDriver TunnelDriver
+	// forwardDrivers are the tunnels of the additional port forwards.
+	forwardDrivers []TunnelDriver
 }
 
-func (s *StepStartTunnel) ConfigureLocalHostPort(ctx context.Context) error {
+// localPort returns port if set, or else an available local port.
+func localPort(ctx context.Context, port int) (int, error) {
 	minPortNumber, maxPortNumber := 8000, 9000
 
-	if s.IAPConf.IAPLocalhostPort != 0 {
-		minPortNumber = s.IAPConf.IAPLocalhostPort
+	if port != 0 {
+		minPortNumber = port
 		maxPortNumber = minPortNumber
-		log.Printf("Using TCP port for %d IAP proxy", s.IAPConf.IAPLocalhostPort)
+		log.Printf("Using TCP port for %d IAP proxy", port)
 	} else {
 		log.Printf("Finding an available TCP port for IAP proxy")
 	}
@@ -162,89 +121,50 @@ func (s *StepStartTunnel) ConfigureLocalHostPort(ctx context.Context) error {
 
 	if err != nil {
 		err := fmt.Errorf("error finding an available port to initiate a session tunnel: %s", err)
-		return err
+		return 0, err
 	}
 
-	s.IAPConf.IAPLocalhostPort = l.Port
 	l.Close()
-	log.Printf("Setting up proxy to listen on localhost at %d",
-		s.IAPConf.IAPLocalhostPort)
-
-	return nil
+	log.Printf("Setting up proxy to listen on localhost at %d", l.Port)
+	return l.Port, nil
 }
 
-func (s *StepStartTunnel) createTempGcloudScript(args []string) (string, error) {
-	// Generate temp script that contains both gcloud auth and gcloud compute
-	// iap launch call.
-
-	// Create temp file.
-	tf, err := tmp.File("gcloud-setup")
+func (s *StepStartTunnel) ConfigureLocalHostPort(ctx context.Context) error {
+	port, err := localPort(ctx, s.IAPConf.IAPLocalhostPort)
 	if err != nil {
-		return "", fmt.Errorf("Error preparing gcloud setup script: %s", err)
-	}
-	defer tf.Close()
-	// Write our contents to it
-	writer := bufio.NewWriter(tf)
-
-	if s.IAPConf.IAPHashBang != "" {
-		s.IAPConf.IAPHashBang = fmt.Sprintf("#!%s\n", s.IAPConf.IAPHashBang)
-		log.Printf("[INFO] (google): Prepending inline gcloud setup script with %s",
-			s.IAPConf.IAPHashBang)
-		_, err = writer.WriteString(s.IAPConf.IAPHashBang)
-		if err != nil {
-			return "", fmt.Errorf("Error preparing inline hashbang: %s", err)
-		}
-	}
-
-	// call command
-	args = append([]string{"gcloud"}, args...)
-	argString := strings.Join(args, " ")
-
-	if s.AccountFile == "" {
-		s.AccountFile = os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
-	}
-
-	if s.AccountFile != "" {
-		if runtime.GOOS == "windows" {
-			_, err = fmt.Fprintf(writer, "set \"CLOUDSDK_AUTH_CREDENTIAL_FILE_OVERRIDE=%s\"\n", s.AccountFile)
-		} else {
-			_, err = fmt.Fprintf(writer, "CLOUDSDK_AUTH_CREDENTIAL_FILE_OVERRIDE='%s'\nexport CLOUDSDK_AUTH_CREDENTIAL_FILE_OVERRIDE\n", s.AccountFile)
-		}
-		if err != nil {
-			return "", fmt.Errorf("error preparing shell script: %s", err)
-		}
+		return err
 	}
+	s.IAPConf.IAPLocalhostPort = port
+	return nil
+}
 
-	if runtime.GOOS == "windows" {
-		_, err = fmt.Fprintf(writer, "call %s\n", argString)
-	} else {
-		_, err = fmt.Fprintf(writer, "%s\n", argString)
-	}
+// startTunnel starts a tunnel from localhost:port to target, retrying while
+// the instance boots or the IAM permissions propagate. A zero timeout skips
+// checking that the tunnel connects.
+func (s *StepStartTunnel) startTunnel(ctx context.Context, d common.IAPDriver, target common.IAPTunnelTarget, port int, timeout time.Duration) (TunnelDriver, error) {
+	tunnel, err := d.NewIAPTunnel(target, s.IAPConf.IAPImpersonateServiceAccount)
 	if err != nil {
-		return "", fmt.Errorf("error preparing shell script: %s", err)
+		return nil, fmt.Errorf("Error creating the IAP tunnel: %s", err)
 	}
 
-	if err := writer.Flush(); err != nil {
-		return "", fmt.Errorf("Error preparing shell script: %s", err)
-	}
-	// Have to close temp file before renaming it or Windows will complain.
-	tf.Close()
-	err = os.Chmod(tf.Name(), 0700)
+	err = retry.Config{
+		Tries: 11,
+		ShouldRetry: func(err error) bool {
+			var tunnelErr *common.IAPTunnelError
+			if errors.As(err, &tunnelErr) && tunnelErr.Temporary() {
+				log.Printf("Retrying to start the IAP tunnel: %s", err)
+				return true
+			}
+			return false
+		},
+		RetryDelay: (&retry.Backoff{InitialBackoff: 200 * time.Millisecond, MaxBackoff: 30 * time.Second, Multiplier: 2}).Linear,
+	}.Run(ctx, func(ctx context.Context) error {
+		return tunnel.StartTunnel(ctx, port, timeout)
+	})
 	if err != nil {
-		log.Printf("[ERROR] (google): error modifying permissions of temp script file: %s", err.Error())
-	}
-
-	// figure out what extension the file should have, and rename it.
-	tempScriptFileName := tf.Name()
-	if s.IAPConf.IAPExt != "" {
-		err := os.Rename(tempScriptFileName, fmt.Sprintf("%s%s", tempScriptFileName, s.IAPConf.IAPExt))
-		if err != nil {
-			return "", fmt.Errorf("Error setting the correct temp file extension: %s", err)
-		}
-		tempScriptFileName = fmt.Sprintf("%s%s", tempScriptFileName, s.IAPConf.IAPExt)
+		return nil, common.EnrichError(fmt.Errorf("Error starting the IAP tunnel to port %d: %w", target.Port, err))
 	}
-
-	return tempScriptFileName, nil
+	return tunnel, nil
 }
 
 // Run executes the Packer build step that creates an IAP tunnel.
@@ -254,8 +174,8 @@ func (s *StepStartTunnel) Run(ctx context.Context, state multistep.StateBag) mul
 		return multistep.ActionContinue
 	}
 
-	// shell out to create the tunnel.
 	ui := state.Get("ui").(packersdk.Ui)
+	d := state.Get("driver").(common.IAPDriver)
 	instanceName := state.Get("instance_name").(string)
 	c := state.Get("config").(*Config)
 
@@ -268,15 +188,11 @@ func (s *StepStartTunnel) Run(ctx context.Context, state multistep.StateBag) mul
 		return multistep.ActionHalt
 	}
 
-	// Generate list of args to use to call gcloud cli.
-	args := []string{"compute", "start-iap-tunnel", instanceName,
-		strconv.Itoa(s.CommConf.Port()),
-		fmt.Sprintf("--local-host-port=localhost:%d", s.IAPConf.IAPLocalhostPort),
-		"--zone", c.Zone, "--project", s.ProjectId,
-	}
-
-	if s.ImpersonateAccount != "" {
-		args = append(args, fmt.Sprintf("--impersonate-service-account='%s'", s.ImpersonateAccount))
+	target := common.IAPTunnelTarget{
+		Project:  s.ProjectId,
+		Zone:     c.Zone,
+		Instance: instanceName,
+		Port:     s.CommConf.Port(),
 	}
 
 	// This is the port the IAP tunnel listens on, on localhost.
@@ -290,39 +206,36 @@ func (s *StepStartTunnel) Run(ctx context.Context, state multistep.StateBag) mul
 		return multistep.ActionHalt
 	}
 
-	log.Printf("Creating tunnel launch script with args %#v", args)
-	// Create temp file that contains both gcloud authentication, and gcloud
-	// proxy setup call.
-	tempScriptFileName, err := s.createTempGcloudScript(args)
+	timeout := time.Duration(s.IAPConf.IAPTunnelLaunchWait) * time.Second
+	s.tunnelDriver, err = s.startTunnel(ctx, d, target, s.IAPConf.IAPLocalhostPort, timeout)
 	if err != nil {
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
 	}
-	defer os.Remove(tempScriptFileName)
 
-	s.tunnelDriver = NewTunnelDriver()
+	for _, forward := range s.IAPConf.IAPPortForwards {
+		port, err := localPort(ctx, forward.LocalPort)
+		if err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
 
-	err = retry.Config{
-		Tries: 11,
-		ShouldRetry: func(err error) bool {
-			switch err.(type) {
-			case RetryableTunnelError:
-				return true
-			default:
-				return false
-			}
-		},
-		RetryDelay: (&retry.Backoff{InitialBackoff: 200 * time.Millisecond, MaxBackoff: 30 * time.Second, Multiplier: 2}).Linear,
-	}.Run(ctx, func(ctx context.Context) error {
-		// tunnel launcher/destroyer has to be different on windows vs. unix.
-		err := s.tunnelDriver.StartTunnel(ctx, tempScriptFileName, s.IAPConf.IAPTunnelLaunchWait)
-		return err
-	})
-	if err != nil {
-		state.Put("error", err)
-		ui.Error(err.Error())
-		return multistep.ActionHalt
+		ui.Say(fmt.Sprintf("Forwarding localhost:%d to port %d of the instance over IAP...", port, forward.RemotePort))
+		target.Port = forward.RemotePort
+		// Nothing may listen on the port yet, until a provisioner starts
+		// the service.
+		tunnel, err := s.startTunnel(ctx, d, target, port, 0)
+		if err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		s.forwardDrivers = append(s.forwardDrivers, tunnel)
+		if s.GeneratedData != nil {
+			s.GeneratedData.Put(forward.GeneratedDataKey(), fmt.Sprintf("localhost:%d", port))
+		}
 	}
 
 	return multistep.ActionContinue
@@ -336,5 +249,14 @@ func (s *StepStartTunnel) Cleanup(state multistep.StateBag) {
 	}
 	if s.tunnelDriver != nil {
 		s.tunnelDriver.StopTunnel()
+		// In debug mode, tell whether slow provisioners were bound by the
+		// tunnel.
+		if stats, ok := s.tunnelDriver.(interface{ Stats() common.IAPTunnelStats }); ok && s.Debug {
+			ui := state.Get("ui").(packersdk.Ui)
+			ui.Message(fmt.Sprintf("IAP tunnel: %s", stats.Stats()))
+		}
+	}
+	for _, tunnel := range s.forwardDrivers {
+		tunnel.StopTunnel()
 	}
 }
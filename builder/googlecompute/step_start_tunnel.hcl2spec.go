@@ -10,11 +10,13 @@ import (
 // FlatIAPConfig is an auto-generated flat version of IAPConfig.
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlatIAPConfig struct {
-	IAP                 *bool   `mapstructure:"use_iap" required:"false" cty:"use_iap" hcl:"use_iap"`
-	IAPLocalhostPort    *int    `mapstructure:"iap_localhost_port" cty:"iap_localhost_port" hcl:"iap_localhost_port"`
-	IAPHashBang         *string `mapstructure:"iap_hashbang" required:"false" cty:"iap_hashbang" hcl:"iap_hashbang"`
-	IAPExt              *string `mapstructure:"iap_ext" required:"false" cty:"iap_ext" hcl:"iap_ext"`
-	IAPTunnelLaunchWait *int    `mapstructure:"iap_tunnel_launch_wait" required:"false" cty:"iap_tunnel_launch_wait" hcl:"iap_tunnel_launch_wait"`
+	IAP                          *bool                `mapstructure:"use_iap" required:"false" cty:"use_iap" hcl:"use_iap"`
+	IAPLocalhostPort             *int                 `mapstructure:"iap_localhost_port" cty:"iap_localhost_port" hcl:"iap_localhost_port"`
+	IAPHashBang                  *string              `mapstructure:"iap_hashbang" required:"false" cty:"iap_hashbang" hcl:"iap_hashbang"`
+	IAPExt                       *string              `mapstructure:"iap_ext" required:"false" cty:"iap_ext" hcl:"iap_ext"`
+	IAPTunnelLaunchWait          *int                 `mapstructure:"iap_tunnel_launch_wait" required:"false" cty:"iap_tunnel_launch_wait" hcl:"iap_tunnel_launch_wait"`
+	IAPImpersonateServiceAccount *string              `mapstructure:"iap_impersonate_service_account" required:"false" cty:"iap_impersonate_service_account" hcl:"iap_impersonate_service_account"`
+	IAPPortForwards              []FlatIAPPortForward `mapstructure:"iap_port_forward" required:"false" cty:"iap_port_forward" hcl:"iap_port_forward"`
 }
 
 // FlatMapstructure returns a new FlatIAPConfig.
@@ -29,11 +31,38 @@ func (*IAPConfig) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spe
 // The decoded values from this spec will then be applied to a FlatIAPConfig.
 func (*FlatIAPConfig) HCL2Spec() map[string]hcldec.Spec {
 	s := map[string]hcldec.Spec{
-		"use_iap":                &hcldec.AttrSpec{Name: "use_iap", Type: cty.Bool, Required: false},
-		"iap_localhost_port":     &hcldec.AttrSpec{Name: "iap_localhost_port", Type: cty.Number, Required: false},
-		"iap_hashbang":           &hcldec.AttrSpec{Name: "iap_hashbang", Type: cty.String, Required: false},
-		"iap_ext":                &hcldec.AttrSpec{Name: "iap_ext", Type: cty.String, Required: false},
-		"iap_tunnel_launch_wait": &hcldec.AttrSpec{Name: "iap_tunnel_launch_wait", Type: cty.Number, Required: false},
+		"use_iap":                         &hcldec.AttrSpec{Name: "use_iap", Type: cty.Bool, Required: false},
+		"iap_localhost_port":              &hcldec.AttrSpec{Name: "iap_localhost_port", Type: cty.Number, Required: false},
+		"iap_hashbang":                    &hcldec.AttrSpec{Name: "iap_hashbang", Type: cty.String, Required: false},
+		"iap_ext":                         &hcldec.AttrSpec{Name: "iap_ext", Type: cty.String, Required: false},
+		"iap_tunnel_launch_wait":          &hcldec.AttrSpec{Name: "iap_tunnel_launch_wait", Type: cty.Number, Required: false},
+		"iap_impersonate_service_account": &hcldec.AttrSpec{Name: "iap_impersonate_service_account", Type: cty.String, Required: false},
+		"iap_port_forward":                &hcldec.BlockListSpec{TypeName: "iap_port_forward", Nested: hcldec.ObjectSpec((*FlatIAPPortForward)(nil).HCL2Spec())},
+	}
+	return s
+}
+
+// FlatIAPPortForward is an auto-generated flat version of IAPPortForward.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatIAPPortForward struct {
+	RemotePort *int `mapstructure:"remote_port" required:"true" cty:"remote_port" hcl:"remote_port"`
+	LocalPort  *int `mapstructure:"local_port" required:"false" cty:"local_port" hcl:"local_port"`
+}
+
+// FlatMapstructure returns a new FlatIAPPortForward.
+// FlatIAPPortForward is an auto-generated flat version of IAPPortForward.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*IAPPortForward) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatIAPPortForward)
+}
+
+// HCL2Spec returns the hcl spec of a IAPPortForward.
+// This spec is used by HCL to read the fields of IAPPortForward.
+// The decoded values from this spec will then be applied to a FlatIAPPortForward.
+func (*FlatIAPPortForward) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"remote_port": &hcldec.AttrSpec{Name: "remote_port", Type: cty.Number, Required: false},
+		"local_port":  &hcldec.AttrSpec{Name: "local_port", Type: cty.Number, Required: false},
 	}
 	return s
 }
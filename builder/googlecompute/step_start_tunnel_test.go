@@ -5,80 +5,173 @@ package googlecompute
 
 import (
 	"context"
-	"io/ioutil"
-	"os"
-	"runtime"
 	"testing"
+	"time"
 
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
 	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/packerbuilderdata"
 )
 
 type MockTunnelDriver struct {
 	StopTunnelCalled   bool
-	StartTunnelCalled  bool
-	StartTunnelTimeout int
+	StartTunnelCalled  int
+	StartTunnelPort    int
+	StartTunnelTimeout time.Duration
+	// StartTunnelErrs are returned by the successive calls to StartTunnel.
+	StartTunnelErrs []error
 }
 
 func (m *MockTunnelDriver) StopTunnel() {
 	m.StopTunnelCalled = true
 }
 
-func (m *MockTunnelDriver) StartTunnel(_ context.Context, _ string, timeout int) error {
-	m.StartTunnelCalled = true
+func (m *MockTunnelDriver) StartTunnel(_ context.Context, localPort int, timeout time.Duration) error {
+	m.StartTunnelCalled++
+	m.StartTunnelPort = localPort
 	m.StartTunnelTimeout = timeout
+	if len(m.StartTunnelErrs) > 0 {
+		err := m.StartTunnelErrs[0]
+		m.StartTunnelErrs = m.StartTunnelErrs[1:]
+		return err
+	}
 	return nil
 }
 
 func getTestStepStartTunnel() *StepStartTunnel {
 	return &StepStartTunnel{
 		IAPConf: &IAPConfig{
-			IAP:              true,
-			IAPLocalhostPort: 0,
-			IAPHashBang:      "/bin/bash",
-			IAPExt:           "",
+			IAP:                 true,
+			IAPLocalhostPort:    0,
+			IAPTunnelLaunchWait: 30,
 		},
 		CommConf: &communicator.Config{
+			Type: "ssh",
 			SSH: communicator.SSH{
 				SSHPort: 1234,
 			},
 		},
-		AccountFile: "/path/to/account_file.json",
-		ProjectId:   "fake-project-123",
+		ProjectId: "fake-project-123",
 	}
 }
 
-func TestStepStartTunnel_CreateTempScript(t *testing.T) {
+func TestStepStartTunnel(t *testing.T) {
 	s := getTestStepStartTunnel()
+	state := testState(t)
+	state.Put("instance_name", "fakeinstance-12345")
+	c := state.Get("config").(*Config)
+	d := state.Get("driver").(*common.DriverMock)
+	td := &MockTunnelDriver{}
+	d.NewIAPTunnelResult = td
 
-	args := []string{"compute", "start-iap-tunnel", "fakeinstance-12345",
-		"1234", "--local-host-port=localhost:8774", "--zone", "us-central-b",
-		"--project", "fake-project-123"}
+	if action := s.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", state.Get("error"))
+	}
 
-	scriptPath, err := s.createTempGcloudScript(args)
-	if err != nil {
-		t.Fatalf("Shouldn't have error building script file.")
+	expected := common.IAPTunnelTarget{
+		Project:  "fake-project-123",
+		Zone:     c.Zone,
+		Instance: "fakeinstance-12345",
+		Port:     1234,
+	}
+	if d.NewIAPTunnelTarget != expected {
+		t.Errorf("bad target: %#v", d.NewIAPTunnelTarget)
 	}
-	defer os.Remove(scriptPath)
+	if d.NewIAPTunnelImpersonateServiceAccount != "" {
+		t.Errorf("should use the build credentials, got %s", d.NewIAPTunnelImpersonateServiceAccount)
+	}
+	if td.StartTunnelCalled != 1 || td.StartTunnelTimeout != 30*time.Second {
+		t.Errorf("bad tunnel start: %#v", td)
+	}
+	if td.StartTunnelPort == 0 || s.CommConf.SSHPort != td.StartTunnelPort {
+		t.Errorf("the communicator should connect to the local port %d, got %d", td.StartTunnelPort, s.CommConf.SSHPort)
+	}
+}
 
-	f, err := ioutil.ReadFile(scriptPath)
-	if err != nil {
-		t.Fatalf("couldn't read created inventoryfile: %s", err)
-	}
-
-	expected := `#!/bin/bash
-CLOUDSDK_AUTH_CREDENTIAL_FILE_OVERRIDE='/path/to/account_file.json'
-export CLOUDSDK_AUTH_CREDENTIAL_FILE_OVERRIDE
-gcloud compute start-iap-tunnel fakeinstance-12345 1234 --local-host-port=localhost:8774 --zone us-central-b --project fake-project-123
-`
-	if runtime.GOOS == "windows" {
-		// in real life you'd not be passing a HashBang here, but GIGO.
-		expected = `#!/bin/bash
-set "CLOUDSDK_AUTH_CREDENTIAL_FILE_OVERRIDE=/path/to/account_file.json"
-call gcloud compute start-iap-tunnel fakeinstance-12345 1234 --local-host-port=localhost:8774 --zone us-central-b --project fake-project-123
-`
-	}
-	if string(f) != expected {
-		t.Fatalf("script didn't match expected:\n\n expected: \n%s\n; recieved: \n%s\n", expected, f)
+func TestStepStartTunnel_impersonate(t *testing.T) {
+	s := getTestStepStartTunnel()
+	s.IAPConf.IAPImpersonateServiceAccount = "iap-tunnel@project.iam.gserviceaccount.com"
+	state := testState(t)
+	state.Put("instance_name", "fakeinstance-12345")
+	d := state.Get("driver").(*common.DriverMock)
+	d.NewIAPTunnelResult = &MockTunnelDriver{}
+
+	if action := s.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", state.Get("error"))
+	}
+	if d.NewIAPTunnelImpersonateServiceAccount != "iap-tunnel@project.iam.gserviceaccount.com" {
+		t.Errorf("bad impersonated service account: %s", d.NewIAPTunnelImpersonateServiceAccount)
+	}
+}
+
+func TestStepStartTunnel_portForwards(t *testing.T) {
+	s := getTestStepStartTunnel()
+	s.IAPConf.IAPPortForwards = []IAPPortForward{{RemotePort: 8080, LocalPort: 8765}}
+	state := testState(t)
+	state.Put("instance_name", "fakeinstance-12345")
+	s.GeneratedData = &packerbuilderdata.GeneratedData{State: state}
+	d := state.Get("driver").(*common.DriverMock)
+	td := &MockTunnelDriver{}
+	d.NewIAPTunnelResult = td
+
+	if action := s.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", state.Get("error"))
+	}
+	if d.NewIAPTunnelTarget.Port != 8080 {
+		t.Errorf("bad forward target: %#v", d.NewIAPTunnelTarget)
+	}
+	if td.StartTunnelCalled != 2 || td.StartTunnelPort != 8765 || td.StartTunnelTimeout != 0 {
+		t.Errorf("bad forward start: %#v", td)
+	}
+	generated := state.Get("generated_data").(map[string]interface{})
+	if generated["IAPForward8080"] != "localhost:8765" {
+		t.Errorf("bad generated data: %#v", generated)
+	}
+
+	s.Cleanup(state)
+	if len(s.forwardDrivers) != 1 || !td.StopTunnelCalled {
+		t.Error("the forwards should be stopped")
+	}
+}
+
+func TestStepStartTunnel_retry(t *testing.T) {
+	s := getTestStepStartTunnel()
+	state := testState(t)
+	state.Put("instance_name", "fakeinstance-12345")
+	d := state.Get("driver").(*common.DriverMock)
+	td := &MockTunnelDriver{StartTunnelErrs: []error{
+		&common.IAPTunnelError{Code: 4033, Reason: "not authorized"},
+		&common.IAPTunnelError{Code: 4047, Reason: "lookup failed"},
+	}}
+	d.NewIAPTunnelResult = td
+
+	if action := s.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", state.Get("error"))
+	}
+	if td.StartTunnelCalled != 3 {
+		t.Errorf("should have retried starting the tunnel, called %d times", td.StartTunnelCalled)
+	}
+}
+
+func TestStepStartTunnel_error(t *testing.T) {
+	s := getTestStepStartTunnel()
+	state := testState(t)
+	state.Put("instance_name", "fakeinstance-12345")
+	d := state.Get("driver").(*common.DriverMock)
+	td := &MockTunnelDriver{StartTunnelErrs: []error{
+		&common.IAPTunnelError{Code: 4000, Reason: "invalid client"},
+	}}
+	d.NewIAPTunnelResult = td
+
+	if action := s.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatal("should halt")
+	}
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have an error")
+	}
+	if td.StartTunnelCalled != 1 {
+		t.Errorf("should not have retried starting the tunnel, called %d times", td.StartTunnelCalled)
 	}
 }
 
@@ -23,7 +23,7 @@ type StepTeardownInstance struct {
 // Run executes the Packer build step that tears down a GCE instance.
 func (s *StepTeardownInstance) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
 	config := state.Get("config").(*Config)
-	driver := state.Get("driver").(common.Driver)
+	driver := state.Get("driver").(common.ComputeDriver)
 	ui := state.Get("ui").(packersdk.Ui)
 
 	name := config.InstanceName
@@ -31,9 +31,23 @@ func (s *StepTeardownInstance) Run(ctx context.Context, state multistep.StateBag
 		return multistep.ActionHalt
 	}
 
-	ui.Say("Deleting instance...")
 	instanceLog, _ := driver.GetSerialPortOutput(config.Zone, name)
 	state.Put("instance_log", instanceLog)
+
+	if pool, _ := state.Get("warm_pool").(string); pool != "" {
+		ui.Say("Returning instance to the warm pool...")
+		kept, err := driver.ReleaseWarmInstance(config.Zone, name, pool, config.WarmPoolSize)
+		if err != nil {
+			ui.Error(fmt.Sprintf("Error returning instance to the warm pool: %s", err))
+		}
+		if kept {
+			ui.Message("Instance has been stopped in the warm pool!")
+			state.Put("instance_name", "")
+			return multistep.ActionContinue
+		}
+	}
+
+	ui.Say("Deleting instance...")
 	errCh, err := driver.DeleteInstance(config.Zone, name)
 	if err == nil {
 		select {
@@ -60,7 +74,7 @@ func (s *StepTeardownInstance) Run(ctx context.Context, state multistep.StateBag
 // the disk.
 func (s *StepTeardownInstance) Cleanup(state multistep.StateBag) {
 	config := state.Get("config").(*Config)
-	driver := state.Get("driver").(common.Driver)
+	driver := state.Get("driver").(common.ComputeDriver)
 	ui := state.Get("ui").(packersdk.Ui)
 
 	var err error
@@ -45,3 +45,36 @@ func TestStepTeardownInstance(t *testing.T) {
 		t.Fatalf("bad zone: %#v", driver.DeleteDiskZone)
 	}
 }
+
+func TestStepTeardownInstance_warmPool(t *testing.T) {
+	state := testState(t)
+	step := new(StepTeardownInstance)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.WarmPoolSize = 2
+	state.Put("warm_pool", "pool")
+	state.Put("instance_name", config.InstanceName)
+	driver := state.Get("driver").(*common.DriverMock)
+	driver.ReleaseWarmInstanceResult = true
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if driver.ReleaseWarmInstanceName != config.InstanceName || driver.ReleaseWarmInstanceSize != 2 {
+		t.Fatalf("should've returned the instance to the pool: %s", driver.ReleaseWarmInstanceName)
+	}
+	if driver.DeleteInstanceName != "" {
+		t.Fatal("should've kept the instance")
+	}
+	if name := state.Get("instance_name"); name != "" {
+		t.Fatalf("the instance should not be deleted on cleanup: %v", name)
+	}
+
+	// A full pool does not keep the instance.
+	driver.ReleaseWarmInstanceResult = false
+	step.Run(context.Background(), state)
+	if driver.DeleteInstanceName != config.InstanceName {
+		t.Fatal("should've deleted instance")
+	}
+}
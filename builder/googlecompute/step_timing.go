@@ -0,0 +1,173 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+// StepTiming is the time a step of the build took, and the Compute Engine
+// operations it waited for.
+type StepTiming struct {
+	Step       string
+	Duration   time.Duration
+	Cleanup    time.Duration
+	Operations []common.Operation
+}
+
+// MarshalJSON encodes the durations in seconds.
+func (t StepTiming) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Step       string             `json:"step"`
+		Duration   float64            `json:"duration_seconds"`
+		Cleanup    float64            `json:"cleanup_seconds,omitempty"`
+		Operations []common.Operation `json:"operations,omitempty"`
+	}{t.Step, t.Duration.Seconds(), t.Cleanup.Seconds(), t.Operations})
+}
+
+// operationIds returns the comma-separated IDs of the operations.
+func (t *StepTiming) operationIds() string {
+	ids := make([]string, 0, len(t.Operations))
+	for _, op := range t.Operations {
+		ids = append(ids, strconv.FormatUint(op.Id, 10))
+	}
+	return strings.Join(ids, ",")
+}
+
+// stepTimings records the timings of the steps of a build, in the order
+// they ran.
+type stepTimings struct {
+	timings []*StepTiming
+}
+
+// wrap returns steps timed into t. The steps disabled with multistep.If are
+// left out of the timings.
+func (t *stepTimings) wrap(steps []multistep.Step) []multistep.Step {
+	wrapped := make([]multistep.Step, 0, len(steps))
+	for _, step := range steps {
+		name := reflect.Indirect(reflect.ValueOf(step)).Type().Name()
+		if wrapper, ok := step.(multistep.StepWrapper); ok {
+			name = wrapper.InnerStepName()
+		}
+		if name == "nullStep" {
+			wrapped = append(wrapped, step)
+			continue
+		}
+		wrapped = append(wrapped, &timedStep{Step: step, name: name, timings: t})
+	}
+	return wrapped
+}
+
+// list returns the timings of the steps which ran.
+func (t *stepTimings) list() []StepTiming {
+	list := make([]StepTiming, 0, len(t.timings))
+	for _, timing := range t.timings {
+		list = append(list, *timing)
+	}
+	return list
+}
+
+// String returns the timings as a table.
+func (t *stepTimings) String() string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STEP\tDURATION\tCLEANUP\tOPERATIONS")
+	var total time.Duration
+	for _, timing := range t.timings {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", timing.Step, formatStepDuration(timing.Duration),
+			formatStepDuration(timing.Cleanup), timing.operationIds())
+		total += timing.Duration + timing.Cleanup
+	}
+	fmt.Fprintf(w, "Total\t%s\t\t\n", formatStepDuration(total))
+	w.Flush()
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// JSON returns the timings as JSON, for the generated data.
+func (t *stepTimings) JSON() string {
+	data, err := json.Marshal(t.list())
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func formatStepDuration(d time.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
+
+// operationsDriver is the part of the driver reporting its operations.
+type operationsDriver interface {
+	Operations() []common.Operation
+}
+
+// timedStep records the time a step takes, and the operations it waits
+// for.
+type timedStep struct {
+	multistep.Step
+
+	name    string
+	timings *stepTimings
+	timing  *StepTiming
+}
+
+var _ multistep.StepWrapper = new(timedStep)
+
+// InnerStepName returns the name of the timed step, for the debug runner.
+func (s *timedStep) InnerStepName() string {
+	return s.name
+}
+
+// measure runs f, and returns its duration and the operations it waited
+// for.
+func measure(state multistep.StateBag, f func()) (time.Duration, []common.Operation) {
+	driver, _ := state.Get("driver").(operationsDriver)
+	before := 0
+	if driver != nil {
+		before = len(driver.Operations())
+	}
+
+	start := time.Now()
+	f()
+	elapsed := time.Since(start)
+
+	if driver == nil {
+		return elapsed, nil
+	}
+	return elapsed, driver.Operations()[before:]
+}
+
+func (s *timedStep) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	var action multistep.StepAction
+	s.timing = &StepTiming{Step: s.name}
+	s.timings.timings = append(s.timings.timings, s.timing)
+	s.timing.Duration, s.timing.Operations = measure(state, func() {
+		action = s.Step.Run(ctx, state)
+	})
+	return action
+}
+
+func (s *timedStep) Cleanup(state multistep.StateBag) {
+	cleanup, operations := measure(state, func() {
+		s.Step.Cleanup(state)
+	})
+	if s.timing == nil {
+		return
+	}
+	s.timing.Cleanup = cleanup
+	s.timing.Operations = append(s.timing.Operations, operations...)
+}
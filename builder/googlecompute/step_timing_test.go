@@ -0,0 +1,62 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/stretchr/testify/assert"
+)
+
+// operationStep is a step waiting for an operation when it runs, and
+// another when it is cleaned up.
+type operationStep struct {
+	driver *common.DriverMock
+}
+
+func (s *operationStep) Run(context.Context, multistep.StateBag) multistep.StepAction {
+	s.driver.OperationsResult = append(s.driver.OperationsResult, common.Operation{Id: 1, Name: "operation-1"})
+	return multistep.ActionContinue
+}
+
+func (s *operationStep) Cleanup(multistep.StateBag) {
+	s.driver.OperationsResult = append(s.driver.OperationsResult, common.Operation{Id: 2, Name: "operation-2"})
+}
+
+func TestStepTimings(t *testing.T) {
+	state := testState(t)
+	driver := state.Get("driver").(*common.DriverMock)
+
+	timings := new(stepTimings)
+	steps := timings.wrap([]multistep.Step{
+		multistep.If(false, new(StepCheckQuotas)),
+		&operationStep{driver: driver},
+	})
+	assert.Len(t, steps, 2)
+	assert.Equal(t, "operationStep", steps[1].(multistep.StepWrapper).InnerStepName())
+
+	runner := &multistep.BasicRunner{Steps: steps}
+	runner.Run(context.Background(), state)
+
+	list := timings.list()
+	if assert.Len(t, list, 1, "the disabled step should not be timed") {
+		assert.Equal(t, "operationStep", list[0].Step)
+		assert.Len(t, list[0].Operations, 2, "the operations of the cleanup should be recorded")
+	}
+
+	table := timings.String()
+	assert.True(t, strings.HasPrefix(table, "STEP"), table)
+	assert.Contains(t, table, "1,2")
+
+	var decoded []map[string]interface{}
+	if assert.NoError(t, json.Unmarshal([]byte(timings.JSON()), &decoded)) {
+		assert.Equal(t, "operationStep", decoded[0]["step"])
+		assert.Contains(t, decoded[0], "duration_seconds")
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/retry"
+)
+
+// errImageNotReady means that the image is still being created.
+var errImageNotReady = errors.New("image not ready yet")
+
+// StepWaitImageReady waits for the images of the build, the image and its
+// replicas, to be READY in their storage locations.
+type StepWaitImageReady struct{}
+
+// Run polls the status of each image until it is READY, failing the build if
+// an image fails or is not ready within image_ready_timeout.
+func (s *StepWaitImageReady) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(common.ImageDriver)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	images, _ := state.Get("images").([]*common.Image)
+	if len(images) == 0 {
+		image, ok := state.Get("image").(*common.Image)
+		if !ok {
+			return multistep.ActionContinue
+		}
+		images = []*common.Image{image}
+	}
+
+	ui.Say("Waiting for the images to be ready...")
+	waitCtx, cancel := context.WithTimeout(ctx, config.ImageReadyTimeout)
+	defer cancel()
+
+	for _, image := range images {
+		locations := strings.Join(image.StorageLocations, ", ")
+		if locations == "" {
+			locations = "the default location"
+		}
+
+		var status string
+		err := retry.Config{
+			ShouldRetry: func(err error) bool {
+				return errors.Is(err, errImageNotReady)
+			},
+			RetryDelay: (&retry.Backoff{InitialBackoff: 2 * time.Second, MaxBackoff: 30 * time.Second, Multiplier: 2}).Linear,
+		}.Run(waitCtx, func(ctx context.Context) error {
+			current, err := driver.GetImageFromProject(config.ImageProjectId, image.Name, false)
+			if err != nil {
+				return err
+			}
+			status = current.Status
+			switch status {
+			case "READY":
+				return nil
+			case "FAILED", "DELETING":
+				return fmt.Errorf("image is %s", status)
+			default:
+				return errImageNotReady
+			}
+		})
+		if err != nil && waitCtx.Err() != nil && ctx.Err() == nil {
+			err = fmt.Errorf("image still %s after %s", status, config.ImageReadyTimeout)
+		}
+		if err != nil {
+			err := common.EnrichError(fmt.Errorf("Error waiting for image %s to be ready in %s: %w",
+				image.Name, locations, err))
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		ui.Message(fmt.Sprintf("Image %s is ready in %s", image.Name, locations))
+	}
+	return multistep.ActionContinue
+}
+
+// Cleanup.
+func (s *StepWaitImageReady) Cleanup(state multistep.StateBag) {}
@@ -0,0 +1,49 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStepWaitImageReady(t *testing.T) {
+	cases := map[string]struct {
+		status string
+		action multistep.StepAction
+	}{
+		"ready":   {"READY", multistep.ActionContinue},
+		"failed":  {"FAILED", multistep.ActionHalt},
+		"pending": {"PENDING", multistep.ActionHalt},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			state := testState(t)
+			c := state.Get("config").(*Config)
+			c.ImageReadyTimeout = 100 * time.Millisecond
+			state.Put("images", []*common.Image{
+				{Name: "image", StorageLocations: []string{"us"}},
+				{Name: "image-eu", StorageLocations: []string{"eu"}},
+			})
+			d := state.Get("driver").(*common.DriverMock)
+			d.GetImageFromProjectResult = &common.Image{Status: tc.status}
+
+			step := new(StepWaitImageReady)
+			defer step.Cleanup(state)
+			assert.Equal(t, tc.action, step.Run(context.Background(), state))
+
+			_, failed := state.GetOk("error")
+			assert.Equal(t, tc.action == multistep.ActionHalt, failed)
+			if tc.action == multistep.ActionContinue {
+				assert.Equal(t, "image-eu", d.GetImageFromProjectName, "every image should be checked")
+				assert.Equal(t, c.ImageProjectId, d.GetImageFromProjectProject)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
@@ -19,6 +20,32 @@ import (
 // setting the set-startup-script metadata status to error.
 var ErrStartupScriptMetadata = errors.New("Startup script exited with error.")
 
+// startupScriptOutputLines is the number of lines of the output of the
+// startup script reported when it fails.
+const startupScriptOutputLines = 20
+
+// startupScriptOutput returns the last lines the startup script printed on
+// the serial port, from the last run of the Packer wrapper, without the
+// prefix of the guest agent.
+func startupScriptOutput(serial string, max int) []string {
+	var lines []string
+	for _, line := range strings.Split(serial, "\n") {
+		_, output, ok := strings.Cut(line, "startup-script: ")
+		if !ok {
+			continue
+		}
+		output = strings.TrimRight(output, "\r")
+		if strings.Contains(output, "Packer startup script starting.") {
+			lines = nil
+		}
+		lines = append(lines, output)
+	}
+	if len(lines) > max {
+		lines = lines[len(lines)-max:]
+	}
+	return lines
+}
+
 // StepWaitStartupScript is a trivial implementation of a Packer multistep
 // It can be used for tracking the set-startup-script metadata status.
 type StepWaitStartupScript int
@@ -27,7 +54,7 @@ type StepWaitStartupScript int
 // indicating the startup script finished.
 func (s *StepWaitStartupScript) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
 	config := state.Get("config").(*Config)
-	driver := state.Get("driver").(common.Driver)
+	driver := state.Get("driver").(common.ComputeDriver)
 	ui := state.Get("ui").(packersdk.Ui)
 	instanceName := state.Get("instance_name").(string)
 
@@ -58,7 +85,16 @@ func (s *StepWaitStartupScript) Run(ctx context.Context, state multistep.StateBa
 		switch status {
 		case StartupScriptStatusError:
 			ui.Message("Startup script in error. Exiting...")
-			return ErrStartupScriptMetadata
+			serial, err := driver.GetSerialPortOutput(config.Zone, instanceName)
+			if err != nil {
+				ui.Message(fmt.Sprintf("Could not read the serial port output for the startup script output: %s", err))
+				return ErrStartupScriptMetadata
+			}
+			lines := startupScriptOutput(serial, startupScriptOutputLines)
+			if len(lines) == 0 {
+				return ErrStartupScriptMetadata
+			}
+			return fmt.Errorf("%w Last lines of its output:\n  %s", ErrStartupScriptMetadata, strings.Join(lines, "\n  "))
 
 		case StartupScriptStatusDone:
 			ui.Message("Startup script successfully finished.")
@@ -71,7 +107,7 @@ func (s *StepWaitStartupScript) Run(ctx context.Context, state multistep.StateBa
 	})
 
 	if err != nil {
-		err := fmt.Errorf("Error waiting for startup script to finish: %s", err)
+		err := fmt.Errorf("Error waiting for startup script to finish: %w", err)
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
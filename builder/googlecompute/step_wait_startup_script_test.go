@@ -5,6 +5,8 @@ package googlecompute
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
@@ -80,3 +82,40 @@ func TestStepWaitStartupScript_withWrapStartupScript(t *testing.T) {
 		})
 	}
 }
+
+func TestStepWaitStartupScript_errorOutput(t *testing.T) {
+	state := testState(t)
+	step := new(StepWaitStartupScript)
+	c := state.Get("config").(*Config)
+	d := state.Get("driver").(*common.DriverMock)
+
+	c.Zone = "test-zone"
+	state.Put("instance_name", "test-instance-name")
+	d.GetInstanceMetadataResult = StartupScriptStatusError
+	d.GetSerialPortOutputResult = strings.Join([]string{
+		"google_metadata_script_runner[512]: startup-script: Packer startup script starting.",
+		"google_metadata_script_runner[512]: startup-script: first boot",
+		"[   12.345678] systemd[1]: Started Google Compute Engine Startup Scripts.",
+		"google_metadata_script_runner[601]: startup-script: Packer startup script starting.",
+		"google_metadata_script_runner[601]: startup-script: Executing user-provided startup script...",
+		"google_metadata_script_runner[601]: startup-script: E: Unable to locate package nginx-full",
+		"google_metadata_script_runner[601]: startup-script: Packer startup script exited with exit code: 100",
+	}, "\r\n")
+
+	assert.Equal(t, multistep.ActionHalt, step.Run(context.Background(), state))
+	err := state.Get("error").(error)
+	assert.ErrorIs(t, err, ErrStartupScriptMetadata)
+	assert.Contains(t, err.Error(), "  E: Unable to locate package nginx-full\n  Packer startup script exited with exit code: 100")
+	assert.NotContains(t, err.Error(), "first boot", "only the output of the last run should be reported")
+	assert.Equal(t, "test-instance-name", d.GetSerialPortOutputName)
+}
+
+func TestStartupScriptOutput(t *testing.T) {
+	var serial []string
+	for i := 0; i < 30; i++ {
+		serial = append(serial, fmt.Sprintf("startup-script: line %d", i))
+	}
+	lines := startupScriptOutput(strings.Join(serial, "\n"), 5)
+	assert.Equal(t, []string{"line 25", "line 26", "line 27", "line 28", "line 29"}, lines)
+	assert.Empty(t, startupScriptOutput("no startup script here\n", 5))
+}
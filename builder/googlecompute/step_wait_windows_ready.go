@@ -0,0 +1,74 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/retry"
+)
+
+// errWindowsNotReady means that the instance did not report it is ready
+// yet.
+var errWindowsNotReady = errors.New("Windows not ready yet.")
+
+// StepWaitWindowsReady waits for a Windows instance to report that its
+// guest agent runs, through the guest attribute WindowsReadyScript
+// publishes, instead of relying on the password and WinRM timeouts.
+type StepWaitWindowsReady struct{}
+
+// Run polls the guest attributes of the instance until it is ready. The
+// build goes on if it does not report it within windows_ready_timeout.
+func (s *StepWaitWindowsReady) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(common.ComputeDriver)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	if wait, _ := state.Get("wait_windows_ready").(bool); !wait {
+		return multistep.ActionContinue
+	}
+	instanceName := state.Get("instance_name").(string)
+
+	ui.Say("Waiting for Windows to be ready...")
+	start := time.Now()
+	waitCtx, cancel := context.WithTimeout(ctx, config.WindowsReadyTimeout)
+	defer cancel()
+
+	err := retry.Config{
+		ShouldRetry: func(err error) bool {
+			return errors.Is(err, errWindowsNotReady)
+		},
+		RetryDelay: (&retry.Backoff{InitialBackoff: 2 * time.Second, MaxBackoff: 10 * time.Second, Multiplier: 2}).Linear,
+	}.Run(waitCtx, func(ctx context.Context) error {
+		attributes, err := driver.GetGuestAttributes(config.Zone, instanceName, WinRMGuestAttributesNamespace+"/")
+		if err != nil {
+			// The guest attributes are not found until the instance sets
+			// one.
+			return fmt.Errorf("%w: %s", errWindowsNotReady, err)
+		}
+		if attributes[WindowsReadyGuestAttribute] == "" {
+			return errWindowsNotReady
+		}
+		return nil
+	})
+
+	switch {
+	case err == nil:
+		ui.Message(fmt.Sprintf("Windows ready after %s.", time.Since(start).Round(time.Second)))
+	case ctx.Err() != nil:
+		return multistep.ActionHalt
+	default:
+		ui.Message(fmt.Sprintf("Windows did not report it is ready within %s, going on.", config.WindowsReadyTimeout))
+	}
+	return multistep.ActionContinue
+}
+
+// Cleanup.
+func (s *StepWaitWindowsReady) Cleanup(state multistep.StateBag) {}
@@ -0,0 +1,58 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStepWaitWindowsReady(t *testing.T) {
+	state := testState(t)
+	state.Put("instance_name", "foo")
+	state.Put("wait_windows_ready", true)
+	driver := state.Get("driver").(*common.DriverMock)
+	driver.GetGuestAttributesResult = map[string]string{WindowsReadyGuestAttribute: "true"}
+
+	step := new(StepWaitWindowsReady)
+	defer step.Cleanup(state)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	assert.Equal(t, "foo", driver.GetGuestAttributesName)
+	assert.Equal(t, WinRMGuestAttributesNamespace+"/", driver.GetGuestAttributesQueryPath)
+}
+
+func TestStepWaitWindowsReady_timeout(t *testing.T) {
+	state := testState(t)
+	state.Put("instance_name", "foo")
+	state.Put("wait_windows_ready", true)
+	state.Get("config").(*Config).WindowsReadyTimeout = time.Second
+	driver := state.Get("driver").(*common.DriverMock)
+	driver.GetGuestAttributesErr = errors.New("The resource 'guestAttributes' was not found")
+
+	// The build goes on as before.
+	if action := new(StepWaitWindowsReady).Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); ok {
+		t.Fatal("should not have error")
+	}
+}
+
+func TestStepWaitWindowsReady_notWindows(t *testing.T) {
+	state := testState(t)
+	driver := state.Get("driver").(*common.DriverMock)
+
+	if action := new(StepWaitWindowsReady).Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	assert.Empty(t, driver.GetGuestAttributesName, "should not poll the guest attributes")
+}
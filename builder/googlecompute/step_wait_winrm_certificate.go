@@ -0,0 +1,107 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/retry"
+)
+
+// errWinRMCertificateNotPublished means that the bootstrap script did not
+// publish the WinRM certificate yet.
+var errWinRMCertificateNotPublished = errors.New("WinRM certificate not published yet.")
+
+// StepWaitWinRMCertificate waits for the WinRM HTTPS bootstrap script to
+// publish the certificate of the instance, and makes the WinRM
+// communicator trust it.
+type StepWaitWinRMCertificate struct{}
+
+// Run reads the certificate from the guest attributes of the instance.
+func (s *StepWaitWinRMCertificate) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(common.ComputeDriver)
+	ui := state.Get("ui").(packersdk.Ui)
+	instanceName := state.Get("instance_name").(string)
+
+	if !config.WinRMHTTPSBootstrap {
+		return multistep.ActionContinue
+	}
+
+	ui.Say("Waiting for the WinRM certificate of the instance...")
+	waitCtx, cancel := context.WithTimeout(ctx, config.Comm.WinRMTimeout)
+	defer cancel()
+
+	var attributes map[string]string
+	err := retry.Config{
+		ShouldRetry: func(err error) bool {
+			return errors.Is(err, errWinRMCertificateNotPublished)
+		},
+		RetryDelay: (&retry.Backoff{InitialBackoff: 5 * time.Second, MaxBackoff: 30 * time.Second, Multiplier: 2}).Linear,
+	}.Run(waitCtx, func(ctx context.Context) error {
+		var err error
+		attributes, err = driver.GetGuestAttributes(config.Zone, instanceName, WinRMGuestAttributesNamespace+"/")
+		if err != nil {
+			// The guest attributes are not found until the instance sets
+			// one.
+			return fmt.Errorf("%w: %s", errWinRMCertificateNotPublished, err)
+		}
+		if attributes[WinRMThumbprintGuestAttribute] == "" || attributes[WinRMCertificateGuestAttribute] == "" {
+			return errWinRMCertificateNotPublished
+		}
+		return nil
+	})
+	if err == nil {
+		err = s.trustCertificate(config, attributes)
+	}
+	if err != nil {
+		err := fmt.Errorf("Error getting the WinRM certificate: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Message(fmt.Sprintf("WinRM certificate thumbprint: %s", attributes[WinRMThumbprintGuestAttribute]))
+	return multistep.ActionContinue
+}
+
+// trustCertificate checks the published certificate against its thumbprint
+// and makes the WinRM communicator only trust it.
+func (s *StepWaitWinRMCertificate) trustCertificate(config *Config, attributes map[string]string) error {
+	der, err := base64.StdEncoding.DecodeString(attributes[WinRMCertificateGuestAttribute])
+	if err != nil {
+		return fmt.Errorf("invalid certificate: %s", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return fmt.Errorf("invalid certificate: %s", err)
+	}
+
+	// Windows identifies the certificates by their SHA-1 thumbprint.
+	sum := sha1.Sum(der)
+	if thumbprint := attributes[WinRMThumbprintGuestAttribute]; !strings.EqualFold(hex.EncodeToString(sum[:]), thumbprint) {
+		return fmt.Errorf("the certificate does not match the thumbprint %s", thumbprint)
+	}
+
+	serverName := cert.Subject.CommonName
+	if len(cert.DNSNames) > 0 {
+		serverName = cert.DNSNames[0]
+	}
+	config.Comm.WinRMTransportDecorator = winrmPinnedTransportDecorator(&config.Comm, der, serverName)
+	return nil
+}
+
+// Cleanup.
+func (s *StepWaitWinRMCertificate) Cleanup(state multistep.StateBag) {}
@@ -0,0 +1,92 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/stretchr/testify/assert"
+)
+
+// testWinRMCertificate returns the guest attributes published for a
+// self-signed certificate, like the bootstrap script does.
+func testWinRMCertificate(t *testing.T) map[string]string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "PACKER-ABC"},
+		DNSNames:     []string{"PACKER-ABC"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha1.Sum(der)
+	return map[string]string{
+		WinRMThumbprintGuestAttribute:  strings.ToUpper(hex.EncodeToString(sum[:])),
+		WinRMCertificateGuestAttribute: base64.StdEncoding.EncodeToString(der),
+	}
+}
+
+func TestStepWaitWinRMCertificate(t *testing.T) {
+	state := testState(t)
+	step := new(StepWaitWinRMCertificate)
+	c := state.Get("config").(*Config)
+	d := state.Get("driver").(*common.DriverMock)
+
+	c.WinRMHTTPSBootstrap = true
+	c.Comm.WinRMTimeout = time.Minute
+	state.Put("instance_name", "test-instance-name")
+	d.GetGuestAttributesResult = testWinRMCertificate(t)
+
+	assert.Equal(t, multistep.ActionContinue, step.Run(context.Background(), state), "Step should have passed and continued.")
+	assert.Equal(t, "test-instance-name", d.GetGuestAttributesName, "Incorrect instance name passed to GetGuestAttributes.")
+	assert.Equal(t, "packer/", d.GetGuestAttributesQueryPath, "Incorrect query path passed to GetGuestAttributes.")
+	assert.NotNil(t, c.Comm.WinRMTransportDecorator, "The WinRM communicator should trust the certificate.")
+}
+
+func TestStepWaitWinRMCertificate_badThumbprint(t *testing.T) {
+	state := testState(t)
+	step := new(StepWaitWinRMCertificate)
+	c := state.Get("config").(*Config)
+	d := state.Get("driver").(*common.DriverMock)
+
+	c.WinRMHTTPSBootstrap = true
+	c.Comm.WinRMTimeout = time.Minute
+	state.Put("instance_name", "test-instance-name")
+	d.GetGuestAttributesResult = testWinRMCertificate(t)
+	d.GetGuestAttributesResult[WinRMThumbprintGuestAttribute] = "0123456789ABCDEF0123456789ABCDEF01234567"
+
+	assert.Equal(t, multistep.ActionHalt, step.Run(context.Background(), state), "Step should have failed.")
+	assert.Nil(t, c.Comm.WinRMTransportDecorator, "The WinRM communicator should not trust the certificate.")
+}
+
+func TestStepWaitWinRMCertificate_disabled(t *testing.T) {
+	state := testState(t)
+	step := new(StepWaitWinRMCertificate)
+	d := state.Get("driver").(*common.DriverMock)
+	state.Put("instance_name", "test-instance-name")
+
+	assert.Equal(t, multistep.ActionContinue, step.Run(context.Background(), state), "Step should have passed and continued.")
+	assert.Equal(t, "", d.GetGuestAttributesName, "GetGuestAttributes should not be called.")
+}
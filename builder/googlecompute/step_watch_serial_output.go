@@ -0,0 +1,122 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// serialWatchInterval is how often the serial port output is checked.
+var serialWatchInterval = 5 * time.Second
+
+// maxSerialLineLength bounds the line kept between two checks, e.g. for
+// progress bars without line breaks.
+const maxSerialLineLength = 4096
+
+// StepWatchSerialOutput watches the serial port output of the instance in
+// the background, and aborts the build as soon as a line matches one of
+// serial_output_failure_patterns.
+type StepWatchSerialOutput struct {
+	// Abort cancels the context of the build.
+	Abort context.CancelFunc
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Run starts watching the serial port output of the instance, until the
+// instance is deleted or the build ends.
+func (s *StepWatchSerialOutput) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(common.ComputeDriver)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	if len(config.serialOutputFailurePatterns) == 0 {
+		return multistep.ActionContinue
+	}
+
+	ui.Say("Watching the serial port output for failures...")
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	go s.watch(state, driver, ui, config.Zone, config.serialOutputFailurePatterns)
+	return multistep.ActionContinue
+}
+
+func (s *StepWatchSerialOutput) watch(state multistep.StateBag, driver common.ComputeDriver, ui packersdk.Ui,
+	zone string, patterns []*regexp.Regexp) {
+	defer close(s.done)
+
+	var start int64
+	var line string
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-time.After(serialWatchInterval):
+		}
+
+		// StepTeardownInstance clears the name once the instance is deleted.
+		name, _ := state.Get("instance_name").(string)
+		if name == "" {
+			return
+		}
+		contents, next, err := driver.GetSerialPortOutputFrom(zone, name, start)
+		if err != nil {
+			log.Printf("[DEBUG] Error reading the serial port output: %s", err)
+			continue
+		}
+		start = next
+
+		// The last line may be incomplete, it is checked again with the
+		// rest of it. A hung instance may never complete it.
+		lines := strings.Split(line+contents, "\n")
+		line = lines[len(lines)-1]
+		if len(line) > maxSerialLineLength {
+			line = line[len(line)-maxSerialLineLength:]
+		}
+		for _, l := range lines {
+			pattern := matchingPattern(patterns, l)
+			if pattern == nil {
+				continue
+			}
+			err := fmt.Errorf("Aborting the build, the serial port output matched %q: %s",
+				pattern.String(), strings.TrimSpace(l))
+			state.Put("error", err)
+			ui.Error(err.Error())
+			if s.Abort != nil {
+				s.Abort()
+			}
+			return
+		}
+	}
+}
+
+// matchingPattern returns the first of patterns matching line, if any.
+func matchingPattern(patterns []*regexp.Regexp, line string) *regexp.Regexp {
+	for _, pattern := range patterns {
+		if pattern.MatchString(line) {
+			return pattern
+		}
+	}
+	return nil
+}
+
+// Cleanup stops watching the serial port output.
+func (s *StepWatchSerialOutput) Cleanup(state multistep.StateBag) {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+	s.stop = nil
+}
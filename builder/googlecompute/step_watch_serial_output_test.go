@@ -0,0 +1,85 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/stretchr/testify/assert"
+)
+
+func testStepWatchSerialOutput(t *testing.T, output string) (multistep.StateBag, *common.DriverMock) {
+	interval := serialWatchInterval
+	serialWatchInterval = time.Millisecond
+	t.Cleanup(func() { serialWatchInterval = interval })
+
+	state := testState(t)
+	state.Put("instance_name", "foo")
+	state.Get("config").(*Config).serialOutputFailurePatterns = []*regexp.Regexp{
+		regexp.MustCompile("Kernel panic"),
+		regexp.MustCompile("cloud-init.*failed"),
+	}
+	driver := state.Get("driver").(*common.DriverMock)
+	driver.GetSerialPortOutputResult = output
+	return state, driver
+}
+
+func TestStepWatchSerialOutput(t *testing.T) {
+	state, _ := testStepWatchSerialOutput(t, "Booting\nKernel panic - not syncing: VFS: Unable to mount root fs")
+
+	ctx, abort := context.WithCancel(context.Background())
+	defer abort()
+	step := &StepWatchSerialOutput{Abort: abort}
+	defer step.Cleanup(state)
+	if action := step.Run(ctx, state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("the build should be aborted")
+	}
+	err, ok := state.GetOk("error")
+	assert.True(t, ok, "the failure should be reported")
+	assert.Contains(t, err.(error).Error(), "Unable to mount root fs")
+}
+
+func TestStepWatchSerialOutput_noMatch(t *testing.T) {
+	state, driver := testStepWatchSerialOutput(t, "Booting\ncloud-init finished\n")
+
+	ctx, abort := context.WithCancel(context.Background())
+	defer abort()
+	step := &StepWatchSerialOutput{Abort: abort}
+	if action := step.Run(ctx, state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	time.Sleep(50 * time.Millisecond)
+	step.Cleanup(state)
+
+	assert.NoError(t, ctx.Err(), "the build should not be aborted")
+	_, ok := state.GetOk("error")
+	assert.False(t, ok, "no failure should be reported")
+	starts := driver.GetSerialPortOutputStarts
+	assert.NotEmpty(t, starts)
+	assert.Equal(t, int64(len(driver.GetSerialPortOutputResult)), starts[len(starts)-1],
+		"the output should only be read once")
+}
+
+func TestStepWatchSerialOutput_deleted(t *testing.T) {
+	state, driver := testStepWatchSerialOutput(t, strings.Repeat("x", 10))
+	state.Put("instance_name", "")
+
+	step := new(StepWatchSerialOutput)
+	step.Run(context.Background(), state)
+	<-step.done
+	step.Cleanup(state)
+	assert.Empty(t, driver.GetSerialPortOutputStarts, "a deleted instance should not be watched")
+}
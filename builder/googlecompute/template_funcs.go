@@ -6,6 +6,8 @@ package googlecompute
 import (
 	"strings"
 	"text/template"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
 )
 
 func isalphanumeric(b byte) bool {
@@ -21,7 +23,7 @@ func isalphanumeric(b byte) bool {
 // Clean up image name by replacing invalid characters with "-"
 // and converting upper cases to lower cases
 func templateCleanImageName(s string) string {
-	if validImageName.MatchString(s) {
+	if common.ValidImageName.MatchString(s) {
 		return s
 	}
 	b := []byte(strings.ToLower(s))
@@ -4,17 +4,97 @@
 package googlecompute
 
 import (
+	"encoding/pem"
+	"fmt"
+
 	"github.com/hashicorp/packer-plugin-sdk/communicator"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/masterzen/winrm"
 )
 
+const WindowsStartupScriptKey string = "windows-startup-script-ps1"
+const EnableGuestAttributesKey string = "enable-guest-attributes"
+
+// The guest attributes the WinRM HTTPS bootstrap script publishes.
+const WinRMGuestAttributesNamespace string = "packer"
+const WinRMThumbprintGuestAttribute string = "winrm-thumbprint"
+const WinRMCertificateGuestAttribute string = "winrm-certificate"
+
+// WindowsReadyGuestAttribute is the guest attribute WindowsReadyScript
+// publishes, in the WinRMGuestAttributesNamespace.
+const WindowsReadyGuestAttribute string = "windows-ready"
+
+// WindowsReadyScript publishes that the guest agent runs the startup
+// scripts, so it handles the password requests.
+var WindowsReadyScript string = fmt.Sprintf(`try {
+Invoke-RestMethod -Method PUT -Headers @{'Metadata-Flavor' = 'Google'} -Uri 'http://metadata.google.internal/computeMetadata/v1/instance/guest-attributes/%s/%s' -Body 'true'
+} catch {}
+`, WinRMGuestAttributesNamespace, WindowsReadyGuestAttribute)
+
+// WinRMHTTPSBootstrapScript generates a self-signed certificate, serves
+// WinRM over HTTPS only with it, and publishes it through guest
+// attributes. It is formatted with the port of the listener.
+var WinRMHTTPSBootstrapScript string = fmt.Sprintf(`& {
+$ErrorActionPreference = 'Stop'
+$cert = New-SelfSignedCertificate -DnsName $env:COMPUTERNAME -CertStoreLocation Cert:\LocalMachine\My
+Get-ChildItem WSMan:\localhost\Listener | Remove-Item -Recurse -Force
+New-Item -Path WSMan:\localhost\Listener -Transport HTTPS -Address * -Port %%[1]d -CertificateThumbPrint $cert.Thumbprint -Force | Out-Null
+Set-Item WSMan:\localhost\Service\AllowUnencrypted -Value $false
+Set-Item WSMan:\localhost\Service\Auth\Basic -Value $true
+New-NetFirewallRule -DisplayName 'Packer WinRM HTTPS' -Direction Inbound -Protocol TCP -LocalPort %%[1]d -Action Allow | Out-Null
+$attributes = 'http://metadata.google.internal/computeMetadata/v1/instance/guest-attributes/%[1]s'
+Invoke-RestMethod -Method PUT -Headers @{'Metadata-Flavor' = 'Google'} -Uri "$attributes/%[2]s" -Body $cert.Thumbprint
+Invoke-RestMethod -Method PUT -Headers @{'Metadata-Flavor' = 'Google'} -Uri "$attributes/%[3]s" -Body ([Convert]::ToBase64String($cert.RawData))
+}
+`, WinRMGuestAttributesNamespace, WinRMThumbprintGuestAttribute, WinRMCertificateGuestAttribute)
+
 // winrmConfig returns the WinRM configuration.
 func winrmConfig(state multistep.StateBag) (*communicator.WinRMConfig, error) {
 	config := state.Get("config").(*Config)
 	password := state.Get("winrm_password").(string)
 
+	username := config.Comm.WinRMUser
+	if config.WinRMDomain != "" {
+		// NTLM takes the domain from the user name.
+		username = config.WinRMDomain + `\` + username
+	}
+
 	return &communicator.WinRMConfig{
-		Username: config.Comm.WinRMUser,
+		Username: username,
 		Password: password,
 	}, nil
 }
+
+// pinnedTransport is a WinRM transport only trusting the certificate
+// generated on the instance by WinRMHTTPSBootstrapScript.
+type pinnedTransport struct {
+	winrm.Transporter
+
+	certificate []byte
+	serverName  string
+}
+
+// winrmPinnedTransportDecorator returns a WinRM transport decorator trusting
+// only the DER certificate, issued for serverName.
+func winrmPinnedTransportDecorator(comm *communicator.Config, certificate []byte, serverName string) func() winrm.Transporter {
+	return func() winrm.Transporter {
+		transport := communicator.ProxyTransportDecorator()
+		if comm.WinRMUseNTLM {
+			transport = communicator.ProxyTransportDecoratorWithNTLM()
+		}
+		return &pinnedTransport{
+			Transporter: transport,
+			certificate: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate}),
+			serverName:  serverName,
+		}
+	}
+}
+
+func (t *pinnedTransport) Transport(endpoint *winrm.Endpoint) error {
+	endpoint.Insecure = false
+	endpoint.CACert = t.certificate
+	// The communicator may connect through a tunnel or to an IP, which the
+	// certificate is not issued for.
+	endpoint.TLSServerName = t.serverName
+	return t.Transporter.Transport(endpoint)
+}
@@ -0,0 +1,29 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecompute
+
+import (
+	"testing"
+)
+
+func TestWinRMConfig(t *testing.T) {
+	state := testState(t)
+	c := state.Get("config").(*Config)
+	c.Comm.WinRMUser = "packer"
+	state.Put("winrm_password", "secret")
+
+	conf, err := winrmConfig(state)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if conf.Username != "packer" || conf.Password != "secret" {
+		t.Errorf("bad WinRM credentials: %#v", conf)
+	}
+
+	c.WinRMDomain = "TEST"
+	conf, _ = winrmConfig(state)
+	if conf.Username != `TEST\packer` {
+		t.Errorf("the user should be qualified with the domain, got %q", conf.Username)
+	}
+}
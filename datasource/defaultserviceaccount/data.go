@@ -0,0 +1,122 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,DatasourceOutput
+
+package defaultserviceaccount
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/zclconf/go-cty/cty"
+)
+
+type Config struct {
+	common.Authentication `mapstructure:",squash"`
+
+	//The project of the service account.
+	ProjectId string `mapstructure:"project_id" required:"true"`
+	//If true, fail unless the service account exists and is enabled. The
+	//default service account can be deleted or disabled, while its email
+	//remains known to Compute Engine.
+	Validate bool `mapstructure:"validate"`
+}
+
+type Datasource struct {
+	config Config
+}
+
+type DatasourceOutput struct {
+	//The email of the default Compute Engine service account, e.g.
+	//`123456789012-compute@developer.gserviceaccount.com`.
+	Email string `mapstructure:"email"`
+	//The IAM member of the service account, e.g.
+	//`serviceAccount:123456789012-compute@developer.gserviceaccount.com`.
+	Member string `mapstructure:"member"`
+}
+
+func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
+	return d.config.FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Configure(raws ...interface{}) error {
+	err := config.Decode(&d.config, nil, raws...)
+	if err != nil {
+		return err
+	}
+
+	errs := new(packersdk.MultiError)
+
+	if d.config.ProjectId == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("project_id must be set"))
+	}
+
+	warns, err := d.config.Authentication.Prepare()
+	if err != nil {
+		errs = packersdk.MultiErrorAppend(errs, err)
+	}
+	for _, warn := range warns {
+		log.Printf("[WARN] - %s", warn)
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
+	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Execute() (cty.Value, error) {
+	cfg := &common.GCEDriverConfig{
+		ProjectId: d.config.ProjectId,
+		Scopes:    []string{common.CloudPlatformScope},
+	}
+	d.config.Authentication.ApplyDriverConfig(cfg)
+
+	driver, err := common.NewDriverGCE(*cfg)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	output, err := d.lookup(driver)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
+}
+
+func (d *Datasource) lookup(driver common.Driver) (DatasourceOutput, error) {
+	email, err := driver.GetDefaultServiceAccount(d.config.ProjectId)
+	if err != nil {
+		return DatasourceOutput{}, common.EnrichError(fmt.Errorf("Error getting project %s: %w", d.config.ProjectId, err))
+	}
+	if email == "" {
+		return DatasourceOutput{}, fmt.Errorf("Project %s has no default service account, is the Compute Engine API enabled?", d.config.ProjectId)
+	}
+
+	if d.config.Validate {
+		sa, err := driver.GetServiceAccount(email)
+		if err != nil {
+			return DatasourceOutput{}, common.EnrichError(fmt.Errorf("Error getting the default service account %s: %w", email, err))
+		}
+		if sa.Disabled {
+			return DatasourceOutput{}, fmt.Errorf("The default service account %s of project %s is disabled", email, d.config.ProjectId)
+		}
+	}
+
+	return DatasourceOutput{
+		Email:  email,
+		Member: "serviceAccount:" + email,
+	}, nil
+}
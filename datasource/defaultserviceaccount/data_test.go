@@ -0,0 +1,71 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package defaultserviceaccount
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"google.golang.org/api/iam/v1"
+)
+
+const email = "123456789012-compute@developer.gserviceaccount.com"
+
+func TestDatasource_lookup(t *testing.T) {
+	var d Datasource
+	if err := d.Configure(map[string]interface{}{"access_token": "ya29.token"}); err == nil {
+		t.Error("should error without project_id")
+	}
+
+	d = Datasource{}
+	if err := d.Configure(map[string]interface{}{"access_token": "ya29.token", "project_id": "my-project"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	driver := &common.DriverMock{}
+	driver.GetDefaultServiceAccountResult = email
+	output, err := d.lookup(driver)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if output.Email != email || output.Member != "serviceAccount:"+email {
+		t.Errorf("bad output: %#v", output)
+	}
+	if driver.GetDefaultServiceAccountProject != "my-project" {
+		t.Errorf("bad project: %s", driver.GetDefaultServiceAccountProject)
+	}
+	if driver.GetServiceAccountEmail != "" {
+		t.Error("should not validate the service account by default")
+	}
+}
+
+func TestDatasource_lookup_validate(t *testing.T) {
+	cases := map[string]struct {
+		account *iam.ServiceAccount
+		err     error
+	}{
+		"deleted":  {err: fmt.Errorf("googleapi: Error 404: Unknown service account")},
+		"disabled": {account: &iam.ServiceAccount{Email: email, Disabled: true}},
+	}
+
+	for name, tc := range cases {
+		var d Datasource
+		err := d.Configure(map[string]interface{}{"access_token": "ya29.token", "project_id": "my-project", "validate": true})
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		driver := &common.DriverMock{}
+		driver.GetDefaultServiceAccountResult = email
+		driver.GetServiceAccountResult = tc.account
+		driver.GetServiceAccountErr = tc.err
+		if _, err := d.lookup(driver); err == nil {
+			t.Errorf("%s: should error", name)
+		}
+		if driver.GetServiceAccountEmail != email {
+			t.Errorf("%s: bad service account: %s", name, driver.GetServiceAccountEmail)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,DatasourceOutput
+
+package gcsobject
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/zclconf/go-cty/cty"
+)
+
+type Config struct {
+	common.Authentication `mapstructure:",squash"`
+
+	//The path of the object, e.g. `gs://my-bucket/manifests/versions.json`.
+	GCSPath string `mapstructure:"gcs_path" required:"true"`
+	//The field to extract from the content of the object, which must be a
+	//JSON object, into `value`. Nested fields are separated by dots, e.g.
+	//`versions.app`.
+	Key string `mapstructure:"key"`
+	//The largest size of the object in bytes, as its content is held in
+	//memory and in the template. Defaults to 1 MiB.
+	MaxSize int64 `mapstructure:"max_size"`
+
+	bucket string
+	object string
+}
+
+type Datasource struct {
+	config Config
+}
+
+type DatasourceOutput struct {
+	//The content of the object.
+	Content string `mapstructure:"content"`
+	//The field `key` of the content, or the content without `key`. String
+	//fields are returned as is, other ones as JSON.
+	Value string `mapstructure:"value"`
+	//The content type of the object.
+	ContentType string `mapstructure:"content_type"`
+	//The generation of the object.
+	Generation string `mapstructure:"generation"`
+	//The base64 encoded MD5 hash of the object.
+	MD5Hash string `mapstructure:"md5_hash"`
+	//The last modification time of the object, in RFC 3339 format.
+	Updated string `mapstructure:"updated"`
+	//The custom metadata of the object.
+	Metadata map[string]string `mapstructure:"metadata"`
+}
+
+func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
+	return d.config.FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Configure(raws ...interface{}) error {
+	err := config.Decode(&d.config, nil, raws...)
+	if err != nil {
+		return err
+	}
+
+	errs := new(packersdk.MultiError)
+
+	if d.config.GCSPath == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("gcs_path must be set"))
+	} else if d.config.bucket, d.config.object, err = common.ParseGCSPath(d.config.GCSPath); err != nil {
+		errs = packersdk.MultiErrorAppend(errs, err)
+	}
+
+	if d.config.MaxSize == 0 {
+		d.config.MaxSize = 1024 * 1024
+	}
+
+	warns, err := d.config.Authentication.Prepare()
+	if err != nil {
+		errs = packersdk.MultiErrorAppend(errs, err)
+	}
+	for _, warn := range warns {
+		log.Printf("[WARN] - %s", warn)
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
+	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Execute() (cty.Value, error) {
+	cfg := &common.GCEDriverConfig{
+		Scopes: common.DriverScopes,
+	}
+	d.config.Authentication.ApplyDriverConfig(cfg)
+
+	driver, err := common.NewDriverGCE(*cfg)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	output, err := d.fetch(driver)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
+}
+
+func (d *Datasource) fetch(driver common.StorageDriver) (DatasourceOutput, error) {
+	object, err := driver.GetBucketObject(d.config.bucket, d.config.object)
+	if err != nil {
+		return DatasourceOutput{}, common.EnrichError(fmt.Errorf("Error getting %s: %w", d.config.GCSPath, err))
+	}
+	if int64(object.Size) > d.config.MaxSize {
+		return DatasourceOutput{}, fmt.Errorf("%s is %d bytes, larger than max_size", d.config.GCSPath, object.Size)
+	}
+
+	content, err := driver.DownloadFromBucket(d.config.bucket, d.config.object, d.config.MaxSize)
+	if err != nil {
+		return DatasourceOutput{}, common.EnrichError(fmt.Errorf("Error downloading %s: %w", d.config.GCSPath, err))
+	}
+
+	value := string(content)
+	if d.config.Key != "" {
+		value, err = common.ExtractJSONField(content, d.config.Key)
+		if err != nil {
+			return DatasourceOutput{}, fmt.Errorf("Error extracting %s from %s: %s", d.config.Key, d.config.GCSPath, err)
+		}
+	}
+
+	metadata := object.Metadata
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	return DatasourceOutput{
+		Content:     string(content),
+		Value:       value,
+		ContentType: object.ContentType,
+		Generation:  fmt.Sprint(object.Generation),
+		MD5Hash:     object.Md5Hash,
+		Updated:     object.Updated,
+		Metadata:    metadata,
+	}, nil
+}
@@ -0,0 +1,78 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package gcsobject
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"google.golang.org/api/storage/v1"
+)
+
+func TestDatasource_Configure(t *testing.T) {
+	for name, c := range map[string]map[string]interface{}{
+		"no path":  {},
+		"bad path": {"gcs_path": "my-bucket/versions.json"},
+	} {
+		var d Datasource
+		c["access_token"] = "ya29.token"
+		if err := d.Configure(c); err == nil {
+			t.Errorf("%s: should error", name)
+		}
+	}
+}
+
+func TestDatasource_fetch(t *testing.T) {
+	content := `{"versions": {"app": "1.4.2", "agents": ["a", "b"]}}`
+
+	cases := map[string]struct {
+		config   map[string]interface{}
+		size     uint64
+		value    string
+		hasError bool
+	}{
+		"content":      {config: map[string]interface{}{}, size: 52, value: content},
+		"string field": {config: map[string]interface{}{"key": "versions.app"}, size: 52, value: "1.4.2"},
+		"list field":   {config: map[string]interface{}{"key": "versions.agents"}, size: 52, value: `["a","b"]`},
+		"too large":    {config: map[string]interface{}{"max_size": 10}, size: 52, hasError: true},
+	}
+
+	for name, tc := range cases {
+		tc.config["access_token"] = "ya29.token"
+		tc.config["gcs_path"] = "gs://my-bucket/manifests/versions.json"
+
+		var d Datasource
+		if err := d.Configure(tc.config); err != nil {
+			t.Fatalf("%s: err: %s", name, err)
+		}
+
+		driver := &common.StorageDriverMock{
+			GetBucketObjectResult: map[string]*storage.Object{
+				"my-bucket/manifests/versions.json": {
+					Size:        tc.size,
+					ContentType: "application/json",
+					Generation:  1704164645000000,
+					Metadata:    map[string]string{"owner": "infra"},
+				},
+			},
+			DownloadFromBucketResult: []byte(content),
+		}
+
+		output, err := d.fetch(driver)
+		if (err != nil) != tc.hasError {
+			t.Errorf("%s: bad error: %v", name, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if driver.DownloadFromBucketBucket != "my-bucket" || driver.DownloadFromBucketObjectName != "manifests/versions.json" {
+			t.Errorf("%s: bad object: %s/%s", name, driver.DownloadFromBucketBucket, driver.DownloadFromBucketObjectName)
+		}
+		if output.Value != tc.value || output.Content != content || output.Generation != "1704164645000000" ||
+			output.ContentType != "application/json" || output.Metadata["owner"] != "infra" {
+			t.Errorf("%s: bad output: %#v", name, output)
+		}
+	}
+}
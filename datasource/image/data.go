@@ -0,0 +1,210 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,DatasourceOutput
+
+package image
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/zclconf/go-cty/cty"
+	"google.golang.org/api/compute/v1"
+)
+
+type Config struct {
+	common.Authentication `mapstructure:",squash"`
+
+	//The projects to search the image in, e.g. `["my-images-project",
+	//"ubuntu-os-cloud"]`.
+	ProjectIds []string `mapstructure:"project_ids" required:"true"`
+	//The family of the image.
+	Family string `mapstructure:"family"`
+	//A regular expression the name of the image must match, e.g.
+	//`^ubuntu-2204-`.
+	NameRegex string `mapstructure:"name_regex"`
+	//Key/value pair labels the image must have, e.g. `{team = "team-x"}`.
+	Labels map[string]string `mapstructure:"labels"`
+	//A filter on the images, in the syntax of the
+	//[Compute Engine API](https://cloud.google.com/compute/docs/reference/rest/v1/images/list),
+	//e.g. `architecture = "ARM64"`. It is combined with `family` and
+	//`labels`.
+	Filter string `mapstructure:"filter"`
+	//If more than one image matches, use the most recently created one.
+	//Without it, the data source fails when more than one image matches.
+	MostRecent bool `mapstructure:"most_recent"`
+	//Also match deprecated and obsolete images, which are left out by
+	//default.
+	IncludeDeprecated bool `mapstructure:"include_deprecated"`
+
+	nameRegex *regexp.Regexp
+}
+
+type Datasource struct {
+	config Config
+}
+
+type DatasourceOutput struct {
+	//The name of the image.
+	Name string `mapstructure:"name"`
+	//The ID of the image.
+	ID string `mapstructure:"id"`
+	//The project of the image.
+	ProjectId string `mapstructure:"project_id"`
+	//The self link of the image.
+	SelfLink string `mapstructure:"self_link"`
+	//The family of the image.
+	Family string `mapstructure:"family"`
+	//The labels of the image.
+	Labels map[string]string `mapstructure:"labels"`
+	//The creation timestamp of the image, in RFC 3339 format.
+	CreationTimestamp string `mapstructure:"creation_timestamp"`
+	//The size of the image in GB.
+	DiskSizeGb int64 `mapstructure:"disk_size_gb"`
+}
+
+func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
+	return d.config.FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Configure(raws ...interface{}) error {
+	err := config.Decode(&d.config, nil, raws...)
+	if err != nil {
+		return err
+	}
+
+	errs := new(packersdk.MultiError)
+
+	if len(d.config.ProjectIds) == 0 {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("project_ids must be set"))
+	}
+
+	if d.config.NameRegex != "" {
+		d.config.nameRegex, err = regexp.Compile(d.config.NameRegex)
+		if err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("invalid name_regex: %s", err))
+		}
+	}
+
+	warns, err := d.config.Authentication.Prepare()
+	if err != nil {
+		errs = packersdk.MultiErrorAppend(errs, err)
+	}
+	for _, warn := range warns {
+		log.Printf("[WARN] - %s", warn)
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
+	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Execute() (cty.Value, error) {
+	cfg := &common.GCEDriverConfig{
+		ProjectId: d.config.ProjectIds[0],
+		Scopes:    common.DriverScopes,
+	}
+	d.config.Authentication.ApplyDriverConfig(cfg)
+
+	driver, err := common.NewDriverGCE(*cfg)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	output, err := d.find(driver)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
+}
+
+// find returns the image matching the configuration.
+func (d *Datasource) find(driver common.ImageDriver) (DatasourceOutput, error) {
+	filter := d.filter()
+
+	var matches []DatasourceOutput
+	for _, project := range d.config.ProjectIds {
+		images, err := driver.ListImages(project, filter)
+		if err != nil {
+			return DatasourceOutput{}, common.EnrichError(fmt.Errorf("Error listing the images of project %s: %w", project, err))
+		}
+		for _, image := range images {
+			if d.match(image) {
+				matches = append(matches, output(project, image))
+			}
+		}
+	}
+
+	switch {
+	case len(matches) == 0:
+		return DatasourceOutput{}, fmt.Errorf("No image found in projects %s", strings.Join(d.config.ProjectIds, ", "))
+	case len(matches) > 1 && !d.config.MostRecent:
+		var names []string
+		for _, m := range matches {
+			names = append(names, m.Name)
+		}
+		return DatasourceOutput{}, fmt.Errorf("%d images found: %s, set most_recent or narrow the search",
+			len(matches), strings.Join(names, ", "))
+	}
+
+	// RFC 3339 timestamps of the same offset sort as strings.
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].CreationTimestamp > matches[j].CreationTimestamp
+	})
+	return matches[0], nil
+}
+
+// filter returns the filter of the images sent to the API.
+func (d *Datasource) filter() string {
+	var family string
+	if d.config.Family != "" {
+		family = fmt.Sprintf("family = %q", d.config.Family)
+	}
+	return common.ListFilter(d.config.Labels, family, d.config.Filter)
+}
+
+// match reports whether image matches the conditions that are not part of
+// the API filter.
+func (d *Datasource) match(image *compute.Image) bool {
+	if image.Status != "" && image.Status != "READY" {
+		return false
+	}
+	if image.Deprecated != nil && image.Deprecated.State != "" && image.Deprecated.State != "ACTIVE" {
+		if image.Deprecated.State == "DELETED" || !d.config.IncludeDeprecated {
+			return false
+		}
+	}
+	if d.config.nameRegex != nil && !d.config.nameRegex.MatchString(image.Name) {
+		return false
+	}
+	return true
+}
+
+func output(project string, image *compute.Image) DatasourceOutput {
+	return DatasourceOutput{
+		Name:              image.Name,
+		ID:                fmt.Sprint(image.Id),
+		ProjectId:         project,
+		SelfLink:          image.SelfLink,
+		Family:            image.Family,
+		Labels:            image.Labels,
+		CreationTimestamp: image.CreationTimestamp,
+		DiskSizeGb:        image.DiskSizeGb,
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package image
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"google.golang.org/api/compute/v1"
+)
+
+func TestDatasource_Configure(t *testing.T) {
+	for name, c := range map[string]map[string]interface{}{
+		"no project": {},
+		"bad regex":  {"project_ids": []string{"my-project"}, "name_regex": "ubuntu-("},
+	} {
+		var d Datasource
+		c["access_token"] = "ya29.token"
+		if err := d.Configure(c); err == nil {
+			t.Errorf("%s: should error", name)
+		}
+	}
+}
+
+func testImages() []*compute.Image {
+	return []*compute.Image{
+		{Name: "base-ubuntu-2204-v2", Family: "ubuntu-2204", Status: "READY", CreationTimestamp: "2024-02-01T00:00:00.000-08:00"},
+		{Name: "base-ubuntu-2204-v3", Family: "ubuntu-2204", Status: "READY", CreationTimestamp: "2024-03-01T00:00:00.000-08:00",
+			Deprecated: &compute.DeprecationStatus{State: "DEPRECATED"}},
+		{Name: "base-ubuntu-2204-v1", Family: "ubuntu-2204", Status: "READY", CreationTimestamp: "2024-01-01T00:00:00.000-08:00"},
+		{Name: "base-debian-12-v1", Family: "ubuntu-2204", Status: "READY", CreationTimestamp: "2024-04-01T00:00:00.000-08:00"},
+		{Name: "base-ubuntu-2204-v4", Family: "ubuntu-2204", Status: "PENDING", CreationTimestamp: "2024-05-01T00:00:00.000-08:00"},
+	}
+}
+
+func TestDatasource_find(t *testing.T) {
+	var d Datasource
+	err := d.Configure(map[string]interface{}{
+		"access_token": "ya29.token",
+		"project_ids":  []string{"my-images"},
+		"family":       "ubuntu-2204",
+		"labels":       map[string]string{"team": "team-x", "built-by": "packer"},
+		"filter":       `architecture = "X86_64"`,
+		"name_regex":   "^base-ubuntu-",
+		"most_recent":  true,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	driver := &common.DriverMock{}
+	driver.ListImagesResult = testImages()
+	image, err := d.find(driver)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if image.Name != "base-ubuntu-2204-v2" || image.ProjectId != "my-images" {
+		t.Errorf("bad image: %#v", image)
+	}
+	if driver.ListImagesProject != "my-images" {
+		t.Errorf("bad project: %s", driver.ListImagesProject)
+	}
+	expected := `(family = "ubuntu-2204") (architecture = "X86_64") (labels.built-by = "packer") (labels.team = "team-x")`
+	if driver.ListImagesFilter != expected {
+		t.Errorf("bad filter:\n%s\nexpected:\n%s", driver.ListImagesFilter, expected)
+	}
+
+	d.config.IncludeDeprecated = true
+	if image, _ := d.find(driver); image.Name != "base-ubuntu-2204-v3" {
+		t.Errorf("bad image with deprecated ones: %s", image.Name)
+	}
+}
+
+func TestDatasource_find_ambiguous(t *testing.T) {
+	var d Datasource
+	err := d.Configure(map[string]interface{}{
+		"access_token": "ya29.token",
+		"project_ids":  []string{"my-images"},
+		"name_regex":   "^base-ubuntu-",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	driver := &common.DriverMock{}
+	driver.ListImagesResult = testImages()
+	if _, err := d.find(driver); err == nil {
+		t.Error("should error with more than one image")
+	}
+
+	driver.ListImagesResult = nil
+	if _, err := d.find(driver); err == nil {
+		t.Error("should error without image")
+	}
+}
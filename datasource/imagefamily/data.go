@@ -0,0 +1,145 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,DatasourceOutput,Image
+
+package imagefamily
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/zclconf/go-cty/cty"
+)
+
+type Config struct {
+	common.Authentication `mapstructure:",squash"`
+
+	//The project of the images.
+	ProjectId string `mapstructure:"project_id" required:"true"`
+	//The family of the images.
+	Family string `mapstructure:"family" required:"true"`
+}
+
+type Datasource struct {
+	config Config
+}
+
+// Image is an image of the family.
+type Image struct {
+	//The name of the image.
+	Name string `mapstructure:"name"`
+	//The self link of the image.
+	SelfLink string `mapstructure:"self_link"`
+	//The creation timestamp of the image, in RFC 3339 format.
+	CreationTimestamp string `mapstructure:"creation_timestamp"`
+	//The deprecation state of the image: `ACTIVE`, `DEPRECATED`, `OBSOLETE`
+	//or `DELETED`.
+	DeprecationState string `mapstructure:"deprecation_state"`
+}
+
+type DatasourceOutput struct {
+	//The images of the family, the most recent first.
+	Images []Image `mapstructure:"images"`
+	//The names of the images of the family, the most recent first.
+	Names []string `mapstructure:"names"`
+}
+
+func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
+	return d.config.FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Configure(raws ...interface{}) error {
+	err := config.Decode(&d.config, nil, raws...)
+	if err != nil {
+		return err
+	}
+
+	errs := new(packersdk.MultiError)
+
+	if d.config.ProjectId == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("project_id must be set"))
+	}
+	if d.config.Family == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("family must be set"))
+	}
+
+	warns, err := d.config.Authentication.Prepare()
+	if err != nil {
+		errs = packersdk.MultiErrorAppend(errs, err)
+	}
+	for _, warn := range warns {
+		log.Printf("[WARN] - %s", warn)
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
+	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Execute() (cty.Value, error) {
+	cfg := &common.GCEDriverConfig{
+		ProjectId: d.config.ProjectId,
+		Scopes:    common.DriverScopes,
+	}
+	d.config.Authentication.ApplyDriverConfig(cfg)
+
+	driver, err := common.NewDriverGCE(*cfg)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	output, err := d.history(driver)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
+}
+
+// history returns the images of the family, the most recent first.
+func (d *Datasource) history(driver common.ImageDriver) (DatasourceOutput, error) {
+	filter := common.ListFilter(nil, fmt.Sprintf("family = %q", d.config.Family))
+	images, err := driver.ListImages(d.config.ProjectId, filter)
+	if err != nil {
+		return DatasourceOutput{}, common.EnrichError(fmt.Errorf("Error listing the images of family %s: %w", d.config.Family, err))
+	}
+
+	output := DatasourceOutput{
+		Images: []Image{},
+		Names:  []string{},
+	}
+	for _, image := range images {
+		state := "ACTIVE"
+		if image.Deprecated != nil && image.Deprecated.State != "" {
+			state = image.Deprecated.State
+		}
+		output.Images = append(output.Images, Image{
+			Name:              image.Name,
+			SelfLink:          image.SelfLink,
+			CreationTimestamp: image.CreationTimestamp,
+			DeprecationState:  state,
+		})
+	}
+
+	// RFC 3339 timestamps of the same offset sort as strings.
+	sort.SliceStable(output.Images, func(i, j int) bool {
+		return output.Images[i].CreationTimestamp > output.Images[j].CreationTimestamp
+	})
+	for _, image := range output.Images {
+		output.Names = append(output.Names, image.Name)
+	}
+	return output, nil
+}
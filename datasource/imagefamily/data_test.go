@@ -0,0 +1,58 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package imagefamily
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	"google.golang.org/api/compute/v1"
+)
+
+func TestDatasource_Configure(t *testing.T) {
+	var d Datasource
+	if err := d.Configure(map[string]interface{}{"access_token": "ya29.token", "project_id": "my-project"}); err == nil {
+		t.Error("should error without family")
+	}
+}
+
+func TestDatasource_history(t *testing.T) {
+	var d Datasource
+	err := d.Configure(map[string]interface{}{
+		"access_token": "ya29.token",
+		"project_id":   "my-project",
+		"family":       "web",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	driver := &common.DriverMock{}
+	driver.ListImagesResult = []*compute.Image{
+		{Name: "web-2", CreationTimestamp: "2024-02-01T00:00:00.000-08:00"},
+		{Name: "web-1", CreationTimestamp: "2024-01-01T00:00:00.000-08:00", Deprecated: &compute.DeprecationStatus{State: "DEPRECATED"}},
+		{Name: "web-3", CreationTimestamp: "2024-03-01T00:00:00.000-08:00"},
+	}
+
+	output, err := d.history(driver)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if driver.ListImagesProject != "my-project" || driver.ListImagesFilter != `(family = "web")` {
+		t.Errorf("bad list: %s %s", driver.ListImagesProject, driver.ListImagesFilter)
+	}
+	if !reflect.DeepEqual(output.Names, []string{"web-3", "web-2", "web-1"}) {
+		t.Errorf("bad names: %v", output.Names)
+	}
+	if output.Images[0].DeprecationState != "ACTIVE" || output.Images[2].DeprecationState != "DEPRECATED" {
+		t.Errorf("bad images: %#v", output.Images)
+	}
+
+	// The output must be convertible to HCL.
+	if v := hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()); !v.IsKnown() || v.GetAttr("images").LengthInt() != 3 {
+		t.Errorf("bad value: %#v", v)
+	}
+}
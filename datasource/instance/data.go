@@ -0,0 +1,181 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,DatasourceOutput
+
+package instance
+
+import (
+	"fmt"
+	"log"
+	"path"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/zclconf/go-cty/cty"
+	"google.golang.org/api/compute/v1"
+)
+
+type Config struct {
+	common.Authentication `mapstructure:",squash"`
+
+	//The project of the instance.
+	ProjectId string `mapstructure:"project_id" required:"true"`
+	//The zone of the instance.
+	Zone string `mapstructure:"zone" required:"true"`
+	//The name of the instance.
+	Name string `mapstructure:"name" required:"true"`
+	//If true, fail unless the instance is running.
+	RequireRunning bool `mapstructure:"require_running"`
+}
+
+type Datasource struct {
+	config Config
+}
+
+type DatasourceOutput struct {
+	//The ID of the instance.
+	ID string `mapstructure:"id"`
+	//The self link of the instance.
+	SelfLink string `mapstructure:"self_link"`
+	//The status of the instance, e.g. `RUNNING` or `TERMINATED`.
+	Status string `mapstructure:"status"`
+	//The machine type of the instance, e.g. `e2-standard-4`.
+	MachineType string `mapstructure:"machine_type"`
+	//The internal IP address of the first network interface.
+	InternalIP string `mapstructure:"internal_ip"`
+	//The external IP address of the first network interface, if any.
+	ExternalIP string `mapstructure:"external_ip"`
+	//The name of the boot disk.
+	BootDisk string `mapstructure:"boot_disk"`
+	//The names of the disks attached to the instance, the boot disk first.
+	Disks []string `mapstructure:"disks"`
+	//The metadata of the instance.
+	Metadata map[string]string `mapstructure:"metadata"`
+	//The labels of the instance.
+	Labels map[string]string `mapstructure:"labels"`
+	//The email of the service account of the instance, if any.
+	ServiceAccountEmail string `mapstructure:"service_account_email"`
+}
+
+func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
+	return d.config.FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Configure(raws ...interface{}) error {
+	err := config.Decode(&d.config, nil, raws...)
+	if err != nil {
+		return err
+	}
+
+	errs := new(packersdk.MultiError)
+
+	if d.config.ProjectId == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("project_id must be set"))
+	}
+	if d.config.Zone == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("zone must be set"))
+	}
+	if d.config.Name == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("name must be set"))
+	}
+
+	warns, err := d.config.Authentication.Prepare()
+	if err != nil {
+		errs = packersdk.MultiErrorAppend(errs, err)
+	}
+	for _, warn := range warns {
+		log.Printf("[WARN] - %s", warn)
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
+	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Execute() (cty.Value, error) {
+	cfg := &common.GCEDriverConfig{
+		ProjectId: d.config.ProjectId,
+		Scopes:    common.DriverScopes,
+	}
+	d.config.Authentication.ApplyDriverConfig(cfg)
+
+	driver, err := common.NewDriverGCE(*cfg)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	output, err := d.lookup(driver)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
+}
+
+func (d *Datasource) lookup(driver common.ComputeDriver) (DatasourceOutput, error) {
+	instance, err := driver.GetInstance(d.config.Zone, d.config.Name)
+	if err != nil {
+		return DatasourceOutput{}, common.EnrichError(fmt.Errorf("Error getting instance %s: %w", d.config.Name, err))
+	}
+	if d.config.RequireRunning && instance.Status != "RUNNING" {
+		return DatasourceOutput{}, fmt.Errorf("Instance %s is %s, not RUNNING", d.config.Name, instance.Status)
+	}
+
+	return output(instance), nil
+}
+
+func output(instance *compute.Instance) DatasourceOutput {
+	out := DatasourceOutput{
+		ID:          fmt.Sprint(instance.Id),
+		SelfLink:    instance.SelfLink,
+		Status:      instance.Status,
+		MachineType: path.Base(instance.MachineType),
+		Metadata:    map[string]string{},
+		Labels:      instance.Labels,
+	}
+
+	if len(instance.NetworkInterfaces) > 0 {
+		ni := instance.NetworkInterfaces[0]
+		out.InternalIP = ni.NetworkIP
+		for _, ac := range ni.AccessConfigs {
+			if ac.NatIP != "" {
+				out.ExternalIP = ac.NatIP
+				break
+			}
+		}
+	}
+
+	for _, disk := range instance.Disks {
+		name := path.Base(disk.Source)
+		if disk.Boot {
+			out.BootDisk = name
+			out.Disks = append([]string{name}, out.Disks...)
+		} else {
+			out.Disks = append(out.Disks, name)
+		}
+	}
+
+	if instance.Metadata != nil {
+		for _, item := range instance.Metadata.Items {
+			if item.Value != nil {
+				out.Metadata[item.Key] = *item.Value
+			}
+		}
+	}
+
+	if len(instance.ServiceAccounts) > 0 {
+		out.ServiceAccountEmail = instance.ServiceAccounts[0].Email
+	}
+
+	return out
+}
@@ -0,0 +1,80 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package instance
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"google.golang.org/api/compute/v1"
+)
+
+func TestDatasource_Configure(t *testing.T) {
+	var d Datasource
+	if err := d.Configure(map[string]interface{}{"access_token": "ya29.token", "project_id": "my-project"}); err == nil {
+		t.Error("should error without zone and name")
+	}
+}
+
+func TestDatasource_lookup(t *testing.T) {
+	const zoneURL = "https://www.googleapis.com/compute/v1/projects/my-project/zones/us-central1-a/"
+	startup := "#!/bin/sh"
+
+	var d Datasource
+	err := d.Configure(map[string]interface{}{
+		"access_token":    "ya29.token",
+		"project_id":      "my-project",
+		"zone":            "us-central1-a",
+		"name":            "golden",
+		"require_running": true,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	driver := &common.DriverMock{}
+	driver.GetInstanceResult = &compute.Instance{
+		Id:          1234,
+		Status:      "RUNNING",
+		MachineType: zoneURL + "machineTypes/e2-standard-4",
+		NetworkInterfaces: []*compute.NetworkInterface{{
+			NetworkIP:     "10.0.0.2",
+			AccessConfigs: []*compute.AccessConfig{{NatIP: "203.0.113.7"}},
+		}},
+		Disks: []*compute.AttachedDisk{
+			{Source: zoneURL + "disks/golden-data"},
+			{Source: zoneURL + "disks/golden", Boot: true},
+		},
+		Metadata:        &compute.Metadata{Items: []*compute.MetadataItems{{Key: "startup-script", Value: &startup}}},
+		ServiceAccounts: []*compute.ServiceAccount{{Email: "builder@my-project.iam.gserviceaccount.com"}},
+	}
+
+	output, err := d.lookup(driver)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := DatasourceOutput{
+		ID:                  "1234",
+		Status:              "RUNNING",
+		MachineType:         "e2-standard-4",
+		InternalIP:          "10.0.0.2",
+		ExternalIP:          "203.0.113.7",
+		BootDisk:            "golden",
+		Disks:               []string{"golden", "golden-data"},
+		Metadata:            map[string]string{"startup-script": startup},
+		ServiceAccountEmail: "builder@my-project.iam.gserviceaccount.com",
+	}
+	if !reflect.DeepEqual(output, expected) {
+		t.Errorf("bad output:\n%#v\nexpected:\n%#v", output, expected)
+	}
+	if driver.GetInstanceZone != "us-central1-a" || driver.GetInstanceName != "golden" {
+		t.Errorf("bad instance: %s/%s", driver.GetInstanceZone, driver.GetInstanceName)
+	}
+
+	driver.GetInstanceResult.Status = "TERMINATED"
+	if _, err := d.lookup(driver); err == nil {
+		t.Error("should error when the instance is not running")
+	}
+}
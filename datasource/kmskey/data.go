@@ -0,0 +1,177 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,DatasourceOutput
+
+package kmskey
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// encrypterDecrypter is the role the Compute Engine service agent needs to
+// encrypt disks and images with a key.
+const encrypterDecrypter = "roles/cloudkms.cryptoKeyEncrypterDecrypter"
+
+type Config struct {
+	common.Authentication `mapstructure:",squash"`
+
+	//The project of the key ring.
+	ProjectId string `mapstructure:"project_id" required:"true"`
+	//The location of the key ring, e.g. `us-central1` or `global`.
+	Location string `mapstructure:"location" required:"true"`
+	//The name of the key ring.
+	KeyRing string `mapstructure:"key_ring" required:"true"`
+	//The name of the key.
+	Key string `mapstructure:"key" required:"true"`
+	//The project whose Compute Engine service agent,
+	//`service-((project_number))@compute-system.iam.gserviceaccount.com`,
+	//encrypts the disks and images with the key. When set, the data source
+	//fails unless the service agent is granted
+	//`roles/cloudkms.cryptoKeyEncrypterDecrypter` on the key, its key ring
+	//or its project.
+	ServiceAgentProject string `mapstructure:"service_agent_project"`
+}
+
+type Datasource struct {
+	config Config
+}
+
+type DatasourceOutput struct {
+	//The resource name of the key, as expected by the `kmsKeyName` of the
+	//encryption keys, e.g.
+	//`projects/my-project/locations/us-central1/keyRings/my-ring/cryptoKeys/my-key`.
+	Id string `mapstructure:"id"`
+	//The resource name of the primary version of the key, e.g.
+	//`projects/my-project/locations/us-central1/keyRings/my-ring/cryptoKeys/my-key/cryptoKeyVersions/3`.
+	PrimaryVersionId string `mapstructure:"primary_version_id"`
+}
+
+func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
+	return d.config.FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Configure(raws ...interface{}) error {
+	err := config.Decode(&d.config, nil, raws...)
+	if err != nil {
+		return err
+	}
+
+	errs := new(packersdk.MultiError)
+
+	if d.config.ProjectId == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("project_id must be set"))
+	}
+	if d.config.Location == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("location must be set"))
+	}
+	if d.config.KeyRing == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("key_ring must be set"))
+	}
+	if d.config.Key == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("key must be set"))
+	}
+
+	warns, err := d.config.Authentication.Prepare()
+	if err != nil {
+		errs = packersdk.MultiErrorAppend(errs, err)
+	}
+	for _, warn := range warns {
+		log.Printf("[WARN] - %s", warn)
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
+	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Execute() (cty.Value, error) {
+	cfg := &common.GCEDriverConfig{
+		ProjectId: d.config.ProjectId,
+		Scopes:    []string{common.CloudPlatformScope},
+	}
+	d.config.Authentication.ApplyDriverConfig(cfg)
+
+	driver, err := common.NewDriverGCE(*cfg)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	output, err := d.resolve(driver)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
+}
+
+func (d *Datasource) resolve(driver common.Driver) (DatasourceOutput, error) {
+	keyRing := fmt.Sprintf("projects/%s/locations/%s/keyRings/%s", d.config.ProjectId, d.config.Location, d.config.KeyRing)
+	name := fmt.Sprintf("%s/cryptoKeys/%s", keyRing, d.config.Key)
+
+	key, err := driver.GetCryptoKey(name)
+	if err != nil {
+		return DatasourceOutput{}, common.EnrichError(fmt.Errorf("Error getting key %s: %w", name, err))
+	}
+	if key.Purpose != "ENCRYPT_DECRYPT" {
+		return DatasourceOutput{}, fmt.Errorf("Key %s is a %s key, disks and images need an ENCRYPT_DECRYPT key", name, key.Purpose)
+	}
+	if key.Primary == nil || key.Primary.State != "ENABLED" {
+		return DatasourceOutput{}, fmt.Errorf("Key %s has no enabled primary version", name)
+	}
+
+	if d.config.ServiceAgentProject != "" {
+		if err := d.checkServiceAgent(driver, keyRing, name); err != nil {
+			return DatasourceOutput{}, err
+		}
+	}
+
+	return DatasourceOutput{
+		Id:               name,
+		PrimaryVersionId: key.Primary.Name,
+	}, nil
+}
+
+// checkServiceAgent checks that the Compute Engine service agent of
+// service_agent_project can use the key name, from the policies of the key,
+// of its key ring and of its project.
+func (d *Datasource) checkServiceAgent(driver common.Driver, keyRing, name string) error {
+	number, err := driver.GetProjectNumber(d.config.ServiceAgentProject)
+	if err != nil {
+		return common.EnrichError(fmt.Errorf("Error getting project %s: %w", d.config.ServiceAgentProject, err))
+	}
+	agent := fmt.Sprintf("serviceAccount:service-%d@compute-system.iam.gserviceaccount.com", number)
+
+	for _, resource := range []string{name, keyRing, "projects/" + d.config.ProjectId} {
+		members, err := driver.GetKMSRoleMembers(resource, encrypterDecrypter)
+		if err != nil {
+			return common.EnrichError(fmt.Errorf("Error getting the IAM policy of %s: %w", resource, err))
+		}
+		for _, m := range members {
+			if strings.EqualFold(m, agent) {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("The Compute Engine service agent of project %s is not granted %s on key %s, its key ring or its project, "+
+		"it will not be able to encrypt disks and images with it. Grant it with:\n\n"+
+		"  gcloud kms keys add-iam-policy-binding %s --keyring %s --location %s --project %s --member %s --role %s",
+		d.config.ServiceAgentProject, encrypterDecrypter, name,
+		d.config.Key, d.config.KeyRing, d.config.Location, d.config.ProjectId, agent, encrypterDecrypter)
+}
@@ -0,0 +1,100 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kmskey
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"google.golang.org/api/cloudkms/v1"
+)
+
+const (
+	keyRing = "projects/kms-project/locations/us-central1/keyRings/images"
+	key     = keyRing + "/cryptoKeys/disks"
+	agent   = "serviceAccount:service-123456789012@compute-system.iam.gserviceaccount.com"
+)
+
+func testDatasource(t *testing.T, extra map[string]interface{}) *Datasource {
+	c := map[string]interface{}{
+		"access_token": "ya29.token",
+		"project_id":   "kms-project",
+		"location":     "us-central1",
+		"key_ring":     "images",
+		"key":          "disks",
+	}
+	for k, v := range extra {
+		c[k] = v
+	}
+
+	var d Datasource
+	if err := d.Configure(c); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return &d
+}
+
+func testDriver() *common.DriverMock {
+	driver := &common.DriverMock{}
+	driver.GetCryptoKeyResult = &cloudkms.CryptoKey{
+		Name:    key,
+		Purpose: "ENCRYPT_DECRYPT",
+		Primary: &cloudkms.CryptoKeyVersion{Name: key + "/cryptoKeyVersions/3", State: "ENABLED"},
+	}
+	driver.GetProjectNumberResult = 123456789012
+	return driver
+}
+
+func TestDatasource_Configure(t *testing.T) {
+	var d Datasource
+	if err := d.Configure(map[string]interface{}{"access_token": "ya29.token", "project_id": "kms-project"}); err == nil {
+		t.Error("should error without key")
+	}
+}
+
+func TestDatasource_resolve(t *testing.T) {
+	d := testDatasource(t, nil)
+	driver := testDriver()
+
+	output, err := d.resolve(driver)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if driver.GetCryptoKeyName != key || output.Id != key || output.PrimaryVersionId != key+"/cryptoKeyVersions/3" {
+		t.Errorf("bad output: %#v", output)
+	}
+
+	driver.GetCryptoKeyResult.Primary.State = "DISABLED"
+	if _, err := d.resolve(driver); err == nil {
+		t.Error("should error without enabled primary version")
+	}
+}
+
+func TestDatasource_resolve_serviceAgent(t *testing.T) {
+	d := testDatasource(t, map[string]interface{}{"service_agent_project": "build-project"})
+
+	driver := testDriver()
+	if _, err := d.resolve(driver); err == nil {
+		t.Error("should error when the service agent is not granted the role")
+	}
+	if driver.GetProjectNumberProject != "build-project" || driver.GetKMSRoleMembersRole != encrypterDecrypter {
+		t.Errorf("bad check: %s %s", driver.GetProjectNumberProject, driver.GetKMSRoleMembersRole)
+	}
+
+	driver.GetKMSRoleMembersResult = map[string][]string{keyRing: {"user:admin@example.com", agent}}
+	if _, err := d.resolve(driver); err != nil {
+		t.Errorf("err: %s", err)
+	}
+}
+
+func TestDatasource_resolve_serviceAgentProjectGrant(t *testing.T) {
+	d := testDatasource(t, map[string]interface{}{"service_agent_project": "build-project"})
+
+	// The role is commonly granted on the project of the key.
+	driver := testDriver()
+	driver.GetKMSRoleMembersResult = map[string][]string{"projects/" + d.config.ProjectId: {agent}}
+	if _, err := d.resolve(driver); err != nil {
+		t.Errorf("err: %s", err)
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,DatasourceOutput
+
+package network
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/zclconf/go-cty/cty"
+	"google.golang.org/api/googleapi"
+)
+
+type Config struct {
+	common.Authentication `mapstructure:",squash"`
+
+	//The project to search the network in. When it is a Shared VPC service
+	//project, and the network is not in it, its host project is searched
+	//too.
+	ProjectId string `mapstructure:"project_id" required:"true"`
+	//The name of the network. Defaults to the network of `subnetwork`.
+	Network string `mapstructure:"network"`
+	//The name of the subnetwork. Either `network` or `subnetwork` must be
+	//set.
+	Subnetwork string `mapstructure:"subnetwork"`
+	//The region of `subnetwork`. Required with `subnetwork`.
+	Region string `mapstructure:"region"`
+}
+
+type Datasource struct {
+	config Config
+}
+
+type DatasourceOutput struct {
+	//The project the network was found in: `project_id`, or its Shared VPC
+	//host project.
+	ProjectId string `mapstructure:"project_id"`
+	//The name of the network.
+	Network string `mapstructure:"network"`
+	//The self link of the network.
+	NetworkSelfLink string `mapstructure:"network_self_link"`
+	//The name of the subnetwork.
+	Subnetwork string `mapstructure:"subnetwork"`
+	//The self link of the subnetwork.
+	SubnetworkSelfLink string `mapstructure:"subnetwork_self_link"`
+	//The primary IP range of the subnetwork, in CIDR notation.
+	IPCidrRange string `mapstructure:"ip_cidr_range"`
+	//The secondary IP ranges of the subnetwork, by name.
+	SecondaryIPRanges map[string]string `mapstructure:"secondary_ip_ranges"`
+	//Whether Private Google Access is enabled on the subnetwork.
+	PrivateIPGoogleAccess bool `mapstructure:"private_ip_google_access"`
+}
+
+func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
+	return d.config.FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Configure(raws ...interface{}) error {
+	err := config.Decode(&d.config, nil, raws...)
+	if err != nil {
+		return err
+	}
+
+	errs := new(packersdk.MultiError)
+
+	if d.config.ProjectId == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("project_id must be set"))
+	}
+	if d.config.Network == "" && d.config.Subnetwork == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("network or subnetwork must be set"))
+	}
+	if d.config.Subnetwork != "" && d.config.Region == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("region must be set with subnetwork"))
+	}
+
+	warns, err := d.config.Authentication.Prepare()
+	if err != nil {
+		errs = packersdk.MultiErrorAppend(errs, err)
+	}
+	for _, warn := range warns {
+		log.Printf("[WARN] - %s", warn)
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
+	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Execute() (cty.Value, error) {
+	cfg := &common.GCEDriverConfig{
+		ProjectId: d.config.ProjectId,
+		Scopes:    common.DriverScopes,
+	}
+	d.config.Authentication.ApplyDriverConfig(cfg)
+
+	driver, err := common.NewDriverGCE(*cfg)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	output, err := d.resolve(driver)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
+}
+
+// resolve finds the network in the project, or else in its Shared VPC host
+// project.
+func (d *Datasource) resolve(driver common.ComputeDriver) (DatasourceOutput, error) {
+	output, err := d.resolveIn(driver, d.config.ProjectId)
+	var gerr *googleapi.Error
+	if err == nil || !errors.As(err, &gerr) || gerr.Code != http.StatusNotFound {
+		return output, common.EnrichError(err)
+	}
+
+	host, hostErr := driver.GetSharedVpcHost(d.config.ProjectId)
+	if hostErr != nil || host == "" {
+		return output, common.EnrichError(err)
+	}
+	log.Printf("[INFO] Searching the Shared VPC host project %s of %s", host, d.config.ProjectId)
+	output, err = d.resolveIn(driver, host)
+	return output, common.EnrichError(err)
+}
+
+func (d *Datasource) resolveIn(driver common.ComputeDriver, project string) (DatasourceOutput, error) {
+	output := DatasourceOutput{
+		ProjectId: project,
+		Network:   d.config.Network,
+	}
+
+	if d.config.Subnetwork != "" {
+		subnetwork, err := driver.GetSubnetwork(project, d.config.Region, d.config.Subnetwork)
+		if err != nil {
+			return output, fmt.Errorf("Error getting subnetwork %s in project %s: %w", d.config.Subnetwork, project, err)
+		}
+		if output.Network == "" {
+			output.Network = path.Base(subnetwork.Network)
+		} else if path.Base(subnetwork.Network) != output.Network {
+			return output, fmt.Errorf("Subnetwork %s is not in network %s, but in %s",
+				d.config.Subnetwork, output.Network, path.Base(subnetwork.Network))
+		}
+
+		output.Subnetwork = subnetwork.Name
+		output.SubnetworkSelfLink = subnetwork.SelfLink
+		output.IPCidrRange = subnetwork.IpCidrRange
+		output.PrivateIPGoogleAccess = subnetwork.PrivateIpGoogleAccess
+		output.SecondaryIPRanges = map[string]string{}
+		for _, r := range subnetwork.SecondaryIpRanges {
+			output.SecondaryIPRanges[r.RangeName] = r.IpCidrRange
+		}
+	}
+
+	network, err := driver.GetNetwork(project, output.Network)
+	if err != nil {
+		return output, fmt.Errorf("Error getting network %s in project %s: %w", output.Network, project, err)
+	}
+	output.NetworkSelfLink = network.SelfLink
+	return output, nil
+}
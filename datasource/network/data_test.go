@@ -0,0 +1,104 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package network
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+)
+
+// sharedVpcDriver serves the subnetworks of the host project only.
+type sharedVpcDriver struct {
+	common.DriverMock
+}
+
+func (d *sharedVpcDriver) GetSubnetwork(project, region, name string) (*compute.Subnetwork, error) {
+	if project != "host-project" {
+		return nil, &googleapi.Error{Code: http.StatusNotFound, Message: "not found"}
+	}
+	return d.DriverMock.GetSubnetwork(project, region, name)
+}
+
+func TestDatasource_Configure(t *testing.T) {
+	for name, c := range map[string]map[string]interface{}{
+		"no project":             {"network": "default"},
+		"no network":             {"project_id": "my-project"},
+		"subnetwork sans region": {"project_id": "my-project", "subnetwork": "apps"},
+	} {
+		var d Datasource
+		c["access_token"] = "ya29.token"
+		if err := d.Configure(c); err == nil {
+			t.Errorf("%s: should error", name)
+		}
+	}
+}
+
+func TestDatasource_resolve(t *testing.T) {
+	var d Datasource
+	err := d.Configure(map[string]interface{}{
+		"access_token": "ya29.token",
+		"project_id":   "service-project",
+		"subnetwork":   "apps",
+		"region":       "us-central1",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	driver := &sharedVpcDriver{}
+	driver.GetSharedVpcHostResult = "host-project"
+	driver.GetSubnetworkResult = &compute.Subnetwork{
+		Name:                  "apps",
+		Network:               "https://www.googleapis.com/compute/v1/projects/host-project/global/networks/shared",
+		SelfLink:              "https://www.googleapis.com/compute/v1/projects/host-project/regions/us-central1/subnetworks/apps",
+		IpCidrRange:           "10.0.0.0/20",
+		PrivateIpGoogleAccess: true,
+		SecondaryIpRanges:     []*compute.SubnetworkSecondaryRange{{RangeName: "pods", IpCidrRange: "10.4.0.0/14"}},
+	}
+	driver.GetNetworkResult = &compute.Network{
+		SelfLink: "https://www.googleapis.com/compute/v1/projects/host-project/global/networks/shared",
+	}
+
+	output, err := d.resolve(driver)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if output.ProjectId != "host-project" || output.Network != "shared" || output.IPCidrRange != "10.0.0.0/20" ||
+		!output.PrivateIPGoogleAccess || output.SecondaryIPRanges["pods"] != "10.4.0.0/14" {
+		t.Errorf("bad output: %#v", output)
+	}
+	if driver.GetNetworkProject != "host-project" || driver.GetNetworkName != "shared" {
+		t.Errorf("bad network: %s/%s", driver.GetNetworkProject, driver.GetNetworkName)
+	}
+
+	d.config.Network = "default"
+	if _, err := d.resolve(driver); err == nil {
+		t.Error("should error when the subnetwork is not in the network")
+	}
+}
+
+func TestDatasource_resolve_notFound(t *testing.T) {
+	var d Datasource
+	err := d.Configure(map[string]interface{}{
+		"access_token": "ya29.token",
+		"project_id":   "my-project",
+		"network":      "missing",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	driver := &common.DriverMock{}
+	driver.GetNetworkErr = &googleapi.Error{Code: http.StatusNotFound, Message: "not found"}
+	if _, err := d.resolve(driver); err == nil {
+		t.Fatal("should error")
+	}
+	if driver.GetSharedVpcHostProject != "my-project" {
+		t.Errorf("should look for the Shared VPC host project")
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,DatasourceOutput
+
+package parametermanager
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/zclconf/go-cty/cty"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	common.Authentication `mapstructure:",squash"`
+
+	//The project of the parameter.
+	ProjectId string `mapstructure:"project_id" required:"true"`
+	//The name of the parameter.
+	Name string `mapstructure:"name" required:"true"`
+	//The location of the parameter, e.g. `us-central1` for regional
+	//parameters. Defaults to `global`.
+	Location string `mapstructure:"location"`
+	//The version of the parameter. Defaults to the most recently created
+	//version which is not disabled.
+	Version string `mapstructure:"version"`
+	//The field to extract from the payload of the parameter into `value`.
+	//Only parameters of the JSON and YAML formats have fields. Nested fields
+	//are separated by dots, e.g. `app.replicas`.
+	Key string `mapstructure:"key"`
+}
+
+type Datasource struct {
+	config Config
+}
+
+type DatasourceOutput struct {
+	//The payload of the parameter version, with the references to secrets
+	//replaced by their values.
+	Payload string `mapstructure:"payload"`
+	//The field `key` of the payload, or the payload without `key`. String
+	//fields are returned as is, other ones in the format of the parameter.
+	Value string `mapstructure:"value"`
+	//The version of the parameter read.
+	Version string `mapstructure:"version"`
+	//The format of the parameter, one of `UNFORMATTED`, `YAML` or `JSON`.
+	Format string `mapstructure:"format"`
+}
+
+func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
+	return d.config.FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Configure(raws ...interface{}) error {
+	err := config.Decode(&d.config, nil, raws...)
+	if err != nil {
+		return err
+	}
+
+	errs := new(packersdk.MultiError)
+
+	if d.config.ProjectId == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("project_id must be set"))
+	}
+	if d.config.Name == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("name must be set"))
+	}
+	if d.config.Location == "" {
+		d.config.Location = "global"
+	}
+
+	warns, err := d.config.Authentication.Prepare()
+	if err != nil {
+		errs = packersdk.MultiErrorAppend(errs, err)
+	}
+	for _, warn := range warns {
+		log.Printf("[WARN] - %s", warn)
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
+	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Execute() (cty.Value, error) {
+	cfg := &common.GCEDriverConfig{
+		ProjectId: d.config.ProjectId,
+		Scopes:    []string{common.CloudPlatformScope},
+	}
+	d.config.Authentication.ApplyDriverConfig(cfg)
+
+	driver, err := common.NewDriverGCE(*cfg)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	output, err := d.read(driver)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
+}
+
+func (d *Datasource) read(driver common.ParameterManagerDriver) (DatasourceOutput, error) {
+	name := fmt.Sprintf("projects/%s/locations/%s/parameters/%s", d.config.ProjectId, d.config.Location, d.config.Name)
+	parameter, err := driver.GetParameter(name)
+	if err != nil {
+		return DatasourceOutput{}, common.EnrichError(fmt.Errorf("Error getting parameter %s: %w", name, err))
+	}
+	if d.config.Key != "" && parameter.Format == "UNFORMATTED" {
+		return DatasourceOutput{}, fmt.Errorf("Parameter %s is unformatted, key needs a JSON or YAML parameter", name)
+	}
+
+	version := name + "/versions/" + d.config.Version
+	if d.config.Version == "" {
+		version, err = latestVersion(driver, name)
+		if err != nil {
+			return DatasourceOutput{}, err
+		}
+	}
+
+	payload, err := driver.RenderParameterVersion(version)
+	if err != nil {
+		return DatasourceOutput{}, common.EnrichError(fmt.Errorf("Error rendering parameter version %s: %w", version, err))
+	}
+
+	value := string(payload)
+	if d.config.Key != "" {
+		value, err = extract(payload, parameter.Format, d.config.Key)
+		if err != nil {
+			return DatasourceOutput{}, fmt.Errorf("Error extracting %s from parameter version %s: %s", d.config.Key, version, err)
+		}
+	}
+
+	return DatasourceOutput{
+		Payload: string(payload),
+		Value:   value,
+		Version: path.Base(version),
+		Format:  parameter.Format,
+	}, nil
+}
+
+// latestVersion returns the name of the most recently created version of
+// parameter which is not disabled.
+func latestVersion(driver common.ParameterManagerDriver, parameter string) (string, error) {
+	versions, err := driver.ListParameterVersions(parameter)
+	if err != nil {
+		return "", common.EnrichError(fmt.Errorf("Error listing the versions of parameter %s: %w", parameter, err))
+	}
+
+	var latest *common.ParameterVersion
+	for _, v := range versions {
+		// RFC 3339 timestamps in UTC sort as strings.
+		if !v.Disabled && (latest == nil || v.CreateTime > latest.CreateTime) {
+			latest = v
+		}
+	}
+	if latest == nil {
+		return "", fmt.Errorf("Parameter %s has no enabled version", parameter)
+	}
+	return latest.Name, nil
+}
+
+// extract returns the field key of payload, in format.
+func extract(payload []byte, format, key string) (string, error) {
+	var doc interface{}
+	unmarshal, marshal := json.Unmarshal, json.Marshal
+	if format == "YAML" {
+		unmarshal, marshal = yaml.Unmarshal, yaml.Marshal
+	}
+	if err := unmarshal(payload, &doc); err != nil {
+		return "", fmt.Errorf("the payload is not valid %s: %s", format, err)
+	}
+
+	v, err := common.LookupField(doc, key)
+	if err != nil {
+		return "", err
+	}
+
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	b, err := marshal(v)
+	return strings.TrimSuffix(string(b), "\n"), err
+}
@@ -0,0 +1,105 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package parametermanager
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+)
+
+func TestDatasource_Configure(t *testing.T) {
+	var d Datasource
+	if err := d.Configure(map[string]interface{}{"access_token": "ya29.token", "project_id": "my-project"}); err == nil {
+		t.Error("should error without name")
+	}
+
+	d = Datasource{}
+	err := d.Configure(map[string]interface{}{"access_token": "ya29.token", "project_id": "my-project", "name": "app"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if d.config.Location != "global" {
+		t.Errorf("bad location: %s", d.config.Location)
+	}
+}
+
+func TestDatasource_read(t *testing.T) {
+	const parameter = "projects/my-project/locations/global/parameters/app"
+
+	cases := map[string]struct {
+		config   map[string]interface{}
+		format   string
+		payload  string
+		version  string
+		value    string
+		hasError bool
+	}{
+		"latest unformatted": {
+			config:  map[string]interface{}{},
+			format:  "UNFORMATTED",
+			payload: "replicas=3",
+			version: parameter + "/versions/v2",
+			value:   "replicas=3",
+		},
+		"json key": {
+			config:  map[string]interface{}{"version": "v1", "key": "app.replicas"},
+			format:  "JSON",
+			payload: `{"app": {"replicas": 3, "name": "web"}}`,
+			version: parameter + "/versions/v1",
+			value:   "3",
+		},
+		"yaml key": {
+			config:  map[string]interface{}{"key": "app.name"},
+			format:  "YAML",
+			payload: "app:\n  replicas: 3\n  name: web\n",
+			version: parameter + "/versions/v2",
+			value:   "web",
+		},
+		"unformatted key": {
+			config:   map[string]interface{}{"key": "app.name"},
+			format:   "UNFORMATTED",
+			payload:  "replicas=3",
+			hasError: true,
+		},
+	}
+
+	for name, tc := range cases {
+		tc.config["access_token"] = "ya29.token"
+		tc.config["project_id"] = "my-project"
+		tc.config["name"] = "app"
+
+		var d Datasource
+		if err := d.Configure(tc.config); err != nil {
+			t.Fatalf("%s: err: %s", name, err)
+		}
+
+		driver := &common.DriverMock{}
+		driver.GetParameterResult = &common.Parameter{Name: parameter, Format: tc.format}
+		driver.ListParameterVersionsResult = []*common.ParameterVersion{
+			{Name: parameter + "/versions/v1", CreateTime: "2024-01-01T00:00:00Z"},
+			{Name: parameter + "/versions/v3", CreateTime: "2024-03-01T00:00:00Z", Disabled: true},
+			{Name: parameter + "/versions/v2", CreateTime: "2024-02-01T00:00:00Z"},
+		}
+		driver.RenderParameterVersionResult = []byte(tc.payload)
+
+		output, err := d.read(driver)
+		if (err != nil) != tc.hasError {
+			t.Errorf("%s: bad error: %v", name, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if driver.GetParameterName != parameter {
+			t.Errorf("%s: bad parameter: %s", name, driver.GetParameterName)
+		}
+		if driver.RenderParameterVersionName != tc.version {
+			t.Errorf("%s: bad version: %s", name, driver.RenderParameterVersionName)
+		}
+		if output.Value != tc.value || output.Payload != tc.payload || output.Format != tc.format {
+			t.Errorf("%s: bad output: %#v", name, output)
+		}
+	}
+}
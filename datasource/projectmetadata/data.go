@@ -0,0 +1,124 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,DatasourceOutput
+
+package projectmetadata
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/zclconf/go-cty/cty"
+)
+
+type Config struct {
+	common.Authentication `mapstructure:",squash"`
+
+	//The project to read the metadata of.
+	ProjectId string `mapstructure:"project_id" required:"true"`
+}
+
+type Datasource struct {
+	config Config
+}
+
+type DatasourceOutput struct {
+	//The common instance metadata of the project.
+	Metadata map[string]string `mapstructure:"metadata"`
+	//True if OS Login is enabled on the project, with the `enable-oslogin`
+	//metadata key. Instances inherit it, so SSH keys set in their metadata
+	//are ignored unless they override it.
+	EnableOSLogin bool `mapstructure:"enable_oslogin"`
+	//True if OS Login 2-step verification is enabled on the project, with
+	//the `enable-oslogin-2fa` metadata key.
+	EnableOSLogin2FA bool `mapstructure:"enable_oslogin_2fa"`
+	//True if SSH keys are set in the metadata of the project, with the
+	//`ssh-keys` or legacy `sshKeys` metadata keys.
+	HasSSHKeys bool `mapstructure:"has_ssh_keys"`
+}
+
+func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
+	return d.config.FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Configure(raws ...interface{}) error {
+	err := config.Decode(&d.config, nil, raws...)
+	if err != nil {
+		return err
+	}
+
+	errs := new(packersdk.MultiError)
+
+	if d.config.ProjectId == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("project_id must be set"))
+	}
+
+	warns, err := d.config.Authentication.Prepare()
+	if err != nil {
+		errs = packersdk.MultiErrorAppend(errs, err)
+	}
+	for _, warn := range warns {
+		log.Printf("[WARN] - %s", warn)
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
+	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Execute() (cty.Value, error) {
+	cfg := &common.GCEDriverConfig{
+		ProjectId: d.config.ProjectId,
+		Scopes:    common.DriverScopes,
+	}
+	d.config.Authentication.ApplyDriverConfig(cfg)
+
+	driver, err := common.NewDriverGCE(*cfg)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	output, err := d.read(driver)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
+}
+
+func (d *Datasource) read(driver common.ComputeDriver) (DatasourceOutput, error) {
+	metadata, err := driver.GetProjectMetadata(d.config.ProjectId)
+	if err != nil {
+		return DatasourceOutput{}, common.EnrichError(fmt.Errorf("Error getting project %s: %w", d.config.ProjectId, err))
+	}
+
+	return DatasourceOutput{
+		Metadata:         metadata,
+		EnableOSLogin:    metadataBool(metadata["enable-oslogin"]),
+		EnableOSLogin2FA: metadataBool(metadata["enable-oslogin-2fa"]),
+		HasSSHKeys:       strings.TrimSpace(metadata["ssh-keys"]) != "" || strings.TrimSpace(metadata["sshKeys"]) != "",
+	}, nil
+}
+
+// metadataBool parses a boolean metadata value the way the guest
+// environment does.
+func metadataBool(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "1", "yes", "y":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package projectmetadata
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+)
+
+func TestDatasource_read(t *testing.T) {
+	var d Datasource
+	if err := d.Configure(map[string]interface{}{"access_token": "ya29.token"}); err == nil {
+		t.Error("should error without project_id")
+	}
+
+	cases := map[string]struct {
+		metadata map[string]string
+		expected DatasourceOutput
+	}{
+		"empty": {
+			metadata: map[string]string{},
+			expected: DatasourceOutput{},
+		},
+		"os login": {
+			metadata: map[string]string{"enable-oslogin": "TRUE", "enable-oslogin-2fa": "false"},
+			expected: DatasourceOutput{EnableOSLogin: true},
+		},
+		"ssh keys": {
+			metadata: map[string]string{"enable-oslogin": "0", "ssh-keys": "packer:ssh-ed25519 AAAA packer"},
+			expected: DatasourceOutput{HasSSHKeys: true},
+		},
+		"legacy ssh keys": {
+			metadata: map[string]string{"sshKeys": "packer:ssh-ed25519 AAAA packer", "enable-oslogin-2fa": "yes"},
+			expected: DatasourceOutput{EnableOSLogin2FA: true, HasSSHKeys: true},
+		},
+	}
+
+	for name, tc := range cases {
+		d = Datasource{}
+		if err := d.Configure(map[string]interface{}{"access_token": "ya29.token", "project_id": "my-project"}); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		driver := &common.DriverMock{}
+		driver.GetProjectMetadataResult = tc.metadata
+		output, err := d.read(driver)
+		if err != nil {
+			t.Fatalf("%s: err: %s", name, err)
+		}
+		if driver.GetProjectMetadataProject != "my-project" {
+			t.Errorf("%s: bad project: %s", name, driver.GetProjectMetadataProject)
+		}
+		if output.EnableOSLogin != tc.expected.EnableOSLogin ||
+			output.EnableOSLogin2FA != tc.expected.EnableOSLogin2FA ||
+			output.HasSSHKeys != tc.expected.HasSSHKeys {
+			t.Errorf("%s: bad output: %#v", name, output)
+		}
+		if len(output.Metadata) != len(tc.metadata) {
+			t.Errorf("%s: bad metadata: %#v", name, output.Metadata)
+		}
+	}
+}
+
+func TestDatasource_read_error(t *testing.T) {
+	var d Datasource
+	if err := d.Configure(map[string]interface{}{"access_token": "ya29.token", "project_id": "my-project"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	driver := &common.DriverMock{}
+	driver.GetProjectMetadataErr = fmt.Errorf("googleapi: Error 403: Required 'compute.projects.get' permission")
+	if _, err := d.read(driver); err == nil {
+		t.Error("should error when the project cannot be read")
+	}
+}
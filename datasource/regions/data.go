@@ -0,0 +1,209 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,DatasourceOutput,Region,Quota
+
+package regions
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/zclconf/go-cty/cty"
+	"google.golang.org/api/compute/v1"
+)
+
+type Config struct {
+	common.Authentication `mapstructure:",squash"`
+
+	//The project to list the regions of.
+	ProjectId string `mapstructure:"project_id" required:"true"`
+	//Only list the regions whose name matches this regular expression, e.g.
+	//`^europe-`.
+	NameRegex string `mapstructure:"name_regex"`
+	//The quota metrics to summarize for each region, e.g. `CPUS` or
+	//`SSD_TOTAL_GB`. Defaults to all of them.
+	QuotaMetrics []string `mapstructure:"quota_metrics"`
+
+	nameRegex *regexp.Regexp
+}
+
+type Datasource struct {
+	config Config
+}
+
+// Region is a region of the project which is up.
+type Region struct {
+	//The name of the region.
+	Name string `mapstructure:"name"`
+	//The names of the zones of the region which are up, sorted.
+	Zones []string `mapstructure:"zones"`
+	//The quotas of the project in the region, sorted by metric.
+	Quotas []Quota `mapstructure:"quotas"`
+}
+
+// Quota is the quota of a metric in a region.
+type Quota struct {
+	//The metric of the quota, e.g. `CPUS`.
+	Metric string `mapstructure:"metric"`
+	//The limit of the quota.
+	Limit float64 `mapstructure:"limit"`
+	//The current usage of the quota.
+	Usage float64 `mapstructure:"usage"`
+	//The remaining quota, `limit - usage`.
+	Available float64 `mapstructure:"available"`
+}
+
+type DatasourceOutput struct {
+	//The regions which are up, sorted by name.
+	Regions []Region `mapstructure:"regions"`
+	//The names of the regions which are up, sorted.
+	Names []string `mapstructure:"names"`
+}
+
+func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
+	return d.config.FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Configure(raws ...interface{}) error {
+	err := config.Decode(&d.config, nil, raws...)
+	if err != nil {
+		return err
+	}
+
+	errs := new(packersdk.MultiError)
+
+	if d.config.ProjectId == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("project_id must be set"))
+	}
+
+	if d.config.NameRegex != "" {
+		d.config.nameRegex, err = regexp.Compile(d.config.NameRegex)
+		if err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("invalid name_regex: %s", err))
+		}
+	}
+
+	warns, err := d.config.Authentication.Prepare()
+	if err != nil {
+		errs = packersdk.MultiErrorAppend(errs, err)
+	}
+	for _, warn := range warns {
+		log.Printf("[WARN] - %s", warn)
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
+	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Execute() (cty.Value, error) {
+	cfg := &common.GCEDriverConfig{
+		ProjectId: d.config.ProjectId,
+		Scopes:    common.DriverScopes,
+	}
+	d.config.Authentication.ApplyDriverConfig(cfg)
+
+	driver, err := common.NewDriverGCE(*cfg)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	output, err := d.list(driver)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
+}
+
+func (d *Datasource) list(driver common.ComputeDriver) (DatasourceOutput, error) {
+	regions, err := driver.ListRegions()
+	if err != nil {
+		return DatasourceOutput{}, common.EnrichError(fmt.Errorf("Error listing the regions of project %s: %w", d.config.ProjectId, err))
+	}
+
+	zones, err := driver.ListZones()
+	if err != nil {
+		return DatasourceOutput{}, common.EnrichError(fmt.Errorf("Error listing the zones of project %s: %w", d.config.ProjectId, err))
+	}
+	upZones := map[string][]string{}
+	for _, z := range zones {
+		if z.Status == "UP" {
+			region := path.Base(z.Region)
+			upZones[region] = append(upZones[region], z.Name)
+		}
+	}
+
+	metrics := map[string]bool{}
+	for _, m := range d.config.QuotaMetrics {
+		metrics[m] = true
+	}
+
+	output := DatasourceOutput{
+		Regions: []Region{},
+		Names:   []string{},
+	}
+	for _, r := range regions {
+		if r.Status != "UP" {
+			continue
+		}
+		if d.config.nameRegex != nil && !d.config.nameRegex.MatchString(r.Name) {
+			continue
+		}
+
+		region := Region{
+			Name:   r.Name,
+			Zones:  upZones[r.Name],
+			Quotas: quotas(r.Quotas, metrics),
+		}
+		if region.Zones == nil {
+			region.Zones = []string{}
+		}
+		sort.Strings(region.Zones)
+		output.Regions = append(output.Regions, region)
+	}
+
+	sort.Slice(output.Regions, func(i, j int) bool {
+		return output.Regions[i].Name < output.Regions[j].Name
+	})
+	for _, r := range output.Regions {
+		output.Names = append(output.Names, r.Name)
+	}
+	return output, nil
+}
+
+// quotas summarizes the quotas of the metrics, or of all the metrics when
+// there are none, sorted by metric.
+func quotas(regionQuotas []*compute.Quota, metrics map[string]bool) []Quota {
+	summary := []Quota{}
+	for _, q := range regionQuotas {
+		if len(metrics) > 0 && !metrics[q.Metric] {
+			continue
+		}
+		summary = append(summary, Quota{
+			Metric:    q.Metric,
+			Limit:     q.Limit,
+			Usage:     q.Usage,
+			Available: q.Limit - q.Usage,
+		})
+	}
+	sort.Slice(summary, func(i, j int) bool {
+		return summary[i].Metric < summary[j].Metric
+	})
+	return summary
+}
@@ -0,0 +1,77 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package regions
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"google.golang.org/api/compute/v1"
+)
+
+const regionPrefix = "https://www.googleapis.com/compute/v1/projects/my-project/regions/"
+
+func TestDatasource_Configure(t *testing.T) {
+	var d Datasource
+	if err := d.Configure(map[string]interface{}{"access_token": "ya29.token"}); err == nil {
+		t.Error("should error without project_id")
+	}
+
+	d = Datasource{}
+	err := d.Configure(map[string]interface{}{"access_token": "ya29.token", "project_id": "my-project", "name_regex": "(europe"})
+	if err == nil {
+		t.Error("should error with an invalid name_regex")
+	}
+}
+
+func TestDatasource_list(t *testing.T) {
+	var d Datasource
+	err := d.Configure(map[string]interface{}{
+		"access_token":  "ya29.token",
+		"project_id":    "my-project",
+		"name_regex":    "^europe-",
+		"quota_metrics": []string{"CPUS", "SSD_TOTAL_GB"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	driver := &common.DriverMock{}
+	driver.ListRegionsResult = []*compute.Region{
+		{Name: "europe-west4", Status: "UP", Quotas: []*compute.Quota{
+			{Metric: "SSD_TOTAL_GB", Limit: 500, Usage: 100},
+			{Metric: "CPUS", Limit: 24, Usage: 8},
+			{Metric: "INSTANCES", Limit: 100, Usage: 2},
+		}},
+		{Name: "europe-west1", Status: "UP"},
+		{Name: "europe-north9", Status: "DOWN"},
+		{Name: "us-central1", Status: "UP"},
+	}
+	driver.ListZonesResult = []*compute.Zone{
+		{Name: "europe-west4-c", Status: "UP", Region: regionPrefix + "europe-west4"},
+		{Name: "europe-west4-a", Status: "UP", Region: regionPrefix + "europe-west4"},
+		{Name: "europe-west4-b", Status: "DOWN", Region: regionPrefix + "europe-west4"},
+		{Name: "us-central1-a", Status: "UP", Region: regionPrefix + "us-central1"},
+	}
+
+	output, err := d.list(driver)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := DatasourceOutput{
+		Regions: []Region{
+			{Name: "europe-west1", Zones: []string{}, Quotas: []Quota{}},
+			{Name: "europe-west4", Zones: []string{"europe-west4-a", "europe-west4-c"}, Quotas: []Quota{
+				{Metric: "CPUS", Limit: 24, Usage: 8, Available: 16},
+				{Metric: "SSD_TOTAL_GB", Limit: 500, Usage: 100, Available: 400},
+			}},
+		},
+		Names: []string{"europe-west1", "europe-west4"},
+	}
+	if !reflect.DeepEqual(output, expected) {
+		t.Errorf("bad output: %#v", output)
+	}
+}
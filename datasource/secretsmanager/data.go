@@ -0,0 +1,144 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,DatasourceOutput
+
+package secretsmanager
+
+import (
+	"fmt"
+	"log"
+	"path"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/zclconf/go-cty/cty"
+)
+
+type Config struct {
+	common.Authentication `mapstructure:",squash"`
+
+	//The project of the secret.
+	ProjectId string `mapstructure:"project_id" required:"true"`
+	//The name of the secret.
+	Name string `mapstructure:"name" required:"true"`
+	//The version of the secret, a number or an alias. Defaults to `latest`.
+	Version string `mapstructure:"version"`
+	//The location of the secret, for regional secrets, e.g. `us-central1`.
+	//Regional secrets are accessed through the endpoint of their location.
+	Location string `mapstructure:"location"`
+	//The field to extract from the payload of the secret, which must be a
+	//JSON object, into `value`. Nested fields are separated by dots, e.g.
+	//`database.password`.
+	Key string `mapstructure:"key"`
+}
+
+type Datasource struct {
+	config Config
+}
+
+type DatasourceOutput struct {
+	//The payload of the secret version.
+	Payload string `mapstructure:"payload"`
+	//The field `key` of the payload, or the payload without `key`. String
+	//fields are returned as is, other ones as JSON.
+	Value string `mapstructure:"value"`
+	//The version of the secret accessed, e.g. `3` when `version` is
+	//`latest`.
+	Version string `mapstructure:"version"`
+}
+
+func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
+	return d.config.FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Configure(raws ...interface{}) error {
+	err := config.Decode(&d.config, nil, raws...)
+	if err != nil {
+		return err
+	}
+
+	errs := new(packersdk.MultiError)
+
+	if d.config.ProjectId == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("project_id must be set"))
+	}
+	if d.config.Name == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("name must be set"))
+	}
+	if d.config.Version == "" {
+		d.config.Version = "latest"
+	}
+
+	warns, err := d.config.Authentication.Prepare()
+	if err != nil {
+		errs = packersdk.MultiErrorAppend(errs, err)
+	}
+	for _, warn := range warns {
+		log.Printf("[WARN] - %s", warn)
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
+	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Execute() (cty.Value, error) {
+	cfg := &common.GCEDriverConfig{
+		ProjectId: d.config.ProjectId,
+		Scopes:    []string{common.CloudPlatformScope},
+	}
+	d.config.Authentication.ApplyDriverConfig(cfg)
+
+	driver, err := common.NewDriverGCE(*cfg)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	output, err := d.access(driver)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
+}
+
+// versionName returns the resource name of the secret version.
+func (d *Datasource) versionName() string {
+	parent := "projects/" + d.config.ProjectId
+	if d.config.Location != "" {
+		parent += "/locations/" + d.config.Location
+	}
+	return fmt.Sprintf("%s/secrets/%s/versions/%s", parent, d.config.Name, d.config.Version)
+}
+
+func (d *Datasource) access(driver common.SecretManagerDriver) (DatasourceOutput, error) {
+	name := d.versionName()
+	payload, version, err := driver.AccessSecretVersion(name)
+	if err != nil {
+		return DatasourceOutput{}, common.EnrichError(fmt.Errorf("Error accessing secret version %s: %w", name, err))
+	}
+
+	value := string(payload)
+	if d.config.Key != "" {
+		value, err = common.ExtractJSONField(payload, d.config.Key)
+		if err != nil {
+			return DatasourceOutput{}, fmt.Errorf("Error extracting %s from secret version %s: %s", d.config.Key, name, err)
+		}
+	}
+
+	return DatasourceOutput{
+		Payload: string(payload),
+		Value:   value,
+		Version: path.Base(version),
+	}, nil
+}
@@ -0,0 +1,88 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package secretsmanager
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+)
+
+func TestDatasource_Configure(t *testing.T) {
+	var d Datasource
+	if err := d.Configure(map[string]interface{}{"access_token": "ya29.token", "name": "db"}); err == nil {
+		t.Error("should error without project_id")
+	}
+
+	d = Datasource{}
+	err := d.Configure(map[string]interface{}{"access_token": "ya29.token", "project_id": "my-project", "name": "db"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if d.config.Version != "latest" {
+		t.Errorf("bad version: %s", d.config.Version)
+	}
+}
+
+func TestDatasource_access(t *testing.T) {
+	cases := map[string]struct {
+		config   map[string]interface{}
+		name     string
+		value    string
+		hasError bool
+	}{
+		"payload": {
+			config: map[string]interface{}{},
+			name:   "projects/my-project/secrets/db/versions/latest",
+			value:  `{"database": {"user": "app", "password": "s3cr3t", "port": 5432}}`,
+		},
+		"regional version": {
+			config: map[string]interface{}{"version": "2", "location": "europe-west1", "key": "database.password"},
+			name:   "projects/my-project/locations/europe-west1/secrets/db/versions/2",
+			value:  "s3cr3t",
+		},
+		"non string field": {
+			config: map[string]interface{}{"key": "database.port"},
+			name:   "projects/my-project/secrets/db/versions/latest",
+			value:  "5432",
+		},
+		"missing field": {
+			config:   map[string]interface{}{"key": "database.host"},
+			name:     "projects/my-project/secrets/db/versions/latest",
+			hasError: true,
+		},
+	}
+
+	for name, tc := range cases {
+		tc.config["access_token"] = "ya29.token"
+		tc.config["project_id"] = "my-project"
+		tc.config["name"] = "db"
+
+		var d Datasource
+		if err := d.Configure(tc.config); err != nil {
+			t.Fatalf("%s: err: %s", name, err)
+		}
+
+		driver := &common.DriverMock{}
+		driver.AccessSecretVersionResult = []byte(`{"database": {"user": "app", "password": "s3cr3t", "port": 5432}}`)
+		driver.AccessSecretVersionVersion = "projects/123/secrets/db/versions/3"
+		output, err := d.access(driver)
+		if (err != nil) != tc.hasError {
+			t.Errorf("%s: bad error: %v", name, err)
+			continue
+		}
+		if driver.AccessSecretVersionName != tc.name {
+			t.Errorf("%s: bad version name: %s", name, driver.AccessSecretVersionName)
+		}
+		if err != nil {
+			continue
+		}
+		if output.Value != tc.value {
+			t.Errorf("%s: bad value: %s", name, output.Value)
+		}
+		if output.Version != "3" {
+			t.Errorf("%s: bad version: %s", name, output.Version)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,DatasourceOutput
+
+package snapshot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/zclconf/go-cty/cty"
+	"google.golang.org/api/compute/v1"
+)
+
+type Config struct {
+	common.Authentication `mapstructure:",squash"`
+
+	//The project to search the snapshot in.
+	ProjectId string `mapstructure:"project_id" required:"true"`
+	//The prefix of the name of the snapshot, e.g. `data-disk-`.
+	NamePrefix string `mapstructure:"name_prefix"`
+	//Key/value pair labels the snapshot must have.
+	Labels map[string]string `mapstructure:"labels"`
+	//A filter on the snapshots, in the syntax of the
+	//[Compute Engine API](https://cloud.google.com/compute/docs/reference/rest/v1/snapshots/list),
+	//e.g. `sourceDisk = "https://www.googleapis.com/compute/v1/projects/my-project/zones/us-central1-a/disks/data"`.
+	//It is combined with `labels`.
+	Filter string `mapstructure:"filter"`
+}
+
+type Datasource struct {
+	config Config
+}
+
+type DatasourceOutput struct {
+	//The name of the snapshot.
+	Name string `mapstructure:"name"`
+	//The ID of the snapshot.
+	ID string `mapstructure:"id"`
+	//The self link of the snapshot.
+	SelfLink string `mapstructure:"self_link"`
+	//The labels of the snapshot.
+	Labels map[string]string `mapstructure:"labels"`
+	//The creation timestamp of the snapshot, in RFC 3339 format.
+	CreationTimestamp string `mapstructure:"creation_timestamp"`
+	//The size of the disk the snapshot was taken from, in GB.
+	DiskSizeGb int64 `mapstructure:"disk_size_gb"`
+	//The URL of the disk the snapshot was taken from.
+	SourceDisk string `mapstructure:"source_disk"`
+}
+
+func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
+	return d.config.FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Configure(raws ...interface{}) error {
+	err := config.Decode(&d.config, nil, raws...)
+	if err != nil {
+		return err
+	}
+
+	errs := new(packersdk.MultiError)
+
+	if d.config.ProjectId == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("project_id must be set"))
+	}
+
+	warns, err := d.config.Authentication.Prepare()
+	if err != nil {
+		errs = packersdk.MultiErrorAppend(errs, err)
+	}
+	for _, warn := range warns {
+		log.Printf("[WARN] - %s", warn)
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
+	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Execute() (cty.Value, error) {
+	cfg := &common.GCEDriverConfig{
+		ProjectId: d.config.ProjectId,
+		Scopes:    common.DriverScopes,
+	}
+	d.config.Authentication.ApplyDriverConfig(cfg)
+
+	driver, err := common.NewDriverGCE(*cfg)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	output, err := d.find(driver)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
+}
+
+// find returns the latest ready snapshot matching the configuration.
+func (d *Datasource) find(driver common.ComputeDriver) (DatasourceOutput, error) {
+	snapshots, err := driver.ListSnapshots(d.config.ProjectId, common.ListFilter(d.config.Labels, d.config.Filter))
+	if err != nil {
+		return DatasourceOutput{}, common.EnrichError(fmt.Errorf("Error listing the snapshots of project %s: %w", d.config.ProjectId, err))
+	}
+
+	var latest *compute.Snapshot
+	for _, s := range snapshots {
+		if s.Status != "READY" || !strings.HasPrefix(s.Name, d.config.NamePrefix) {
+			continue
+		}
+		// RFC 3339 timestamps of the same offset sort as strings.
+		if latest == nil || s.CreationTimestamp > latest.CreationTimestamp {
+			latest = s
+		}
+	}
+	if latest == nil {
+		return DatasourceOutput{}, fmt.Errorf("No snapshot found in project %s", d.config.ProjectId)
+	}
+
+	return DatasourceOutput{
+		Name:              latest.Name,
+		ID:                fmt.Sprint(latest.Id),
+		SelfLink:          latest.SelfLink,
+		Labels:            latest.Labels,
+		CreationTimestamp: latest.CreationTimestamp,
+		DiskSizeGb:        latest.DiskSizeGb,
+		SourceDisk:        latest.SourceDisk,
+	}, nil
+}
@@ -0,0 +1,54 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package snapshot
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"google.golang.org/api/compute/v1"
+)
+
+func TestDatasource_Configure(t *testing.T) {
+	var d Datasource
+	if err := d.Configure(map[string]interface{}{"access_token": "ya29.token"}); err == nil {
+		t.Error("should error without project_id")
+	}
+}
+
+func TestDatasource_find(t *testing.T) {
+	var d Datasource
+	err := d.Configure(map[string]interface{}{
+		"access_token": "ya29.token",
+		"project_id":   "my-project",
+		"name_prefix":  "data-",
+		"labels":       map[string]string{"app": "db"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	driver := &common.DriverMock{}
+	driver.ListSnapshotsResult = []*compute.Snapshot{
+		{Name: "data-1", Status: "READY", CreationTimestamp: "2024-01-01T00:00:00.000-08:00", SelfLink: "link/data-1"},
+		{Name: "data-3", Status: "CREATING", CreationTimestamp: "2024-03-01T00:00:00.000-08:00", SelfLink: "link/data-3"},
+		{Name: "logs-1", Status: "READY", CreationTimestamp: "2024-04-01T00:00:00.000-08:00", SelfLink: "link/logs-1"},
+		{Name: "data-2", Status: "READY", CreationTimestamp: "2024-02-01T00:00:00.000-08:00", SelfLink: "link/data-2"},
+	}
+	snapshot, err := d.find(driver)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if snapshot.SelfLink != "link/data-2" {
+		t.Errorf("bad snapshot: %#v", snapshot)
+	}
+	if driver.ListSnapshotsProject != "my-project" || driver.ListSnapshotsFilter != `(labels.app = "db")` {
+		t.Errorf("bad list: %s %s", driver.ListSnapshotsProject, driver.ListSnapshotsFilter)
+	}
+
+	driver.ListSnapshotsResult = nil
+	if _, err := d.find(driver); err == nil {
+		t.Error("should error without snapshot")
+	}
+}
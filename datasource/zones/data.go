@@ -0,0 +1,154 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,DatasourceOutput
+
+package zones
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/zclconf/go-cty/cty"
+)
+
+type Config struct {
+	common.Authentication `mapstructure:",squash"`
+
+	//The project to list the zones of.
+	ProjectId string `mapstructure:"project_id" required:"true"`
+	//The region to list the zones of, e.g. `us-central1`.
+	Region string `mapstructure:"region" required:"true"`
+	//Only list the zones offering this machine type, e.g. `c3-standard-8`.
+	MachineType string `mapstructure:"machine_type"`
+	//Only list the zones offering this accelerator type, e.g.
+	//`nvidia-tesla-t4`.
+	AcceleratorType string `mapstructure:"accelerator_type"`
+}
+
+type Datasource struct {
+	config Config
+}
+
+type DatasourceOutput struct {
+	//The names of the zones which are up, sorted.
+	Zones []string `mapstructure:"zones"`
+}
+
+func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
+	return d.config.FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Configure(raws ...interface{}) error {
+	err := config.Decode(&d.config, nil, raws...)
+	if err != nil {
+		return err
+	}
+
+	errs := new(packersdk.MultiError)
+
+	if d.config.ProjectId == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("project_id must be set"))
+	}
+	if d.config.Region == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("region must be set"))
+	}
+
+	warns, err := d.config.Authentication.Prepare()
+	if err != nil {
+		errs = packersdk.MultiErrorAppend(errs, err)
+	}
+	for _, warn := range warns {
+		log.Printf("[WARN] - %s", warn)
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
+	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Execute() (cty.Value, error) {
+	cfg := &common.GCEDriverConfig{
+		ProjectId: d.config.ProjectId,
+		Scopes:    common.DriverScopes,
+	}
+	d.config.Authentication.ApplyDriverConfig(cfg)
+
+	driver, err := common.NewDriverGCE(*cfg)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+
+	zones, err := d.zones(driver)
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), err
+	}
+	return hcl2helper.HCL2ValueFromConfig(DatasourceOutput{Zones: zones}, d.OutputSpec()), nil
+}
+
+// zones returns the zones of the region which are up, and offer the machine
+// and accelerator types.
+func (d *Datasource) zones(driver common.ComputeDriver) ([]string, error) {
+	all, err := driver.ListZones()
+	if err != nil {
+		return nil, common.EnrichError(fmt.Errorf("Error listing the zones: %w", err))
+	}
+
+	var zones []string
+	for _, z := range all {
+		if path.Base(z.Region) == d.config.Region && z.Status == "UP" {
+			zones = append(zones, z.Name)
+		}
+	}
+
+	if d.config.MachineType != "" {
+		offering, err := driver.MachineTypeZones(d.config.MachineType)
+		if err != nil {
+			return nil, common.EnrichError(fmt.Errorf("Error listing the zones of machine type %s: %w", d.config.MachineType, err))
+		}
+		zones = intersect(zones, offering)
+	}
+	if d.config.AcceleratorType != "" {
+		offering, err := driver.AcceleratorTypeZones(d.config.AcceleratorType)
+		if err != nil {
+			return nil, common.EnrichError(fmt.Errorf("Error listing the zones of accelerator type %s: %w", d.config.AcceleratorType, err))
+		}
+		zones = intersect(zones, offering)
+	}
+
+	if len(zones) == 0 {
+		return nil, fmt.Errorf("No zone of region %s is up and offers the requested types", d.config.Region)
+	}
+	sort.Strings(zones)
+	return zones, nil
+}
+
+// intersect returns the zones which are also in others.
+func intersect(zones, others []string) []string {
+	keep := map[string]bool{}
+	for _, z := range others {
+		keep[z] = true
+	}
+
+	var result []string
+	for _, z := range zones {
+		if keep[z] {
+			result = append(result, z)
+		}
+	}
+	return result
+}
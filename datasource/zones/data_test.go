@@ -0,0 +1,65 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package zones
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"google.golang.org/api/compute/v1"
+)
+
+func TestDatasource_Configure(t *testing.T) {
+	var d Datasource
+	if err := d.Configure(map[string]interface{}{"access_token": "ya29.token", "project_id": "my-project"}); err == nil {
+		t.Error("should error without region")
+	}
+}
+
+func TestDatasource_zones(t *testing.T) {
+	const regionURL = "https://www.googleapis.com/compute/v1/projects/my-project/regions/"
+
+	driver := &common.DriverMock{}
+	driver.ListZonesResult = []*compute.Zone{
+		{Name: "us-central1-f", Region: regionURL + "us-central1", Status: "UP"},
+		{Name: "us-central1-a", Region: regionURL + "us-central1", Status: "UP"},
+		{Name: "us-central1-b", Region: regionURL + "us-central1", Status: "DOWN"},
+		{Name: "us-central1-c", Region: regionURL + "us-central1", Status: "UP"},
+		{Name: "us-east1-b", Region: regionURL + "us-east1", Status: "UP"},
+	}
+	driver.MachineTypeZonesResult = []string{"us-central1-a", "us-central1-b", "us-central1-c", "us-east1-b"}
+	driver.AcceleratorTypeZonesResult = []string{"us-central1-a", "us-central1-b", "us-central1-f"}
+
+	cases := []struct {
+		config   map[string]interface{}
+		expected []string
+	}{
+		{map[string]interface{}{}, []string{"us-central1-a", "us-central1-c", "us-central1-f"}},
+		{map[string]interface{}{"machine_type": "c3-standard-8"}, []string{"us-central1-a", "us-central1-c"}},
+		{map[string]interface{}{"machine_type": "c3-standard-8", "accelerator_type": "nvidia-tesla-t4"}, []string{"us-central1-a"}},
+	}
+	for _, tc := range cases {
+		tc.config["access_token"] = "ya29.token"
+		tc.config["project_id"] = "my-project"
+		tc.config["region"] = "us-central1"
+
+		var d Datasource
+		if err := d.Configure(tc.config); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		zones, err := d.zones(driver)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if !reflect.DeepEqual(zones, tc.expected) {
+			t.Errorf("%v: bad zones: %v, expected %v", tc.config, zones, tc.expected)
+		}
+	}
+
+	d := Datasource{config: Config{Region: "europe-west1"}}
+	if _, err := d.zones(driver); err == nil {
+		t.Error("should error without zone")
+	}
+}
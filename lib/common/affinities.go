@@ -6,21 +6,114 @@
 
 package common
 
-import compute "google.golang.org/api/compute/v1"
+import (
+	"fmt"
+
+	compute "google.golang.org/api/compute/v1"
+)
+
+// The operators of the node affinities.
+const (
+	NodeAffinityIn    = "IN"
+	NodeAffinityNotIn = "NOT_IN"
+)
 
 // Node affinity label configuration
 type NodeAffinity struct {
-	// Key: Corresponds to the label key of Node resource.
+	// Key: Corresponds to the label key of Node resource, e.g.
+	// `compute.googleapis.com/node-group-name`. Required.
 	Key string `mapstructure:"key" json:"key"`
 
 	// Operator: Defines the operation of node selection. Valid operators are IN for affinity and
-	// NOT_IN for anti-affinity.
+	// NOT_IN for anti-affinity. Required.
 	Operator string `mapstructure:"operator" json:"operator"`
 
-	// Values: Corresponds to the label values of Node resource.
+	// Values: Corresponds to the label values of Node resource. The instance
+	// is placed on a node with one of them with IN, and on none of them with
+	// NOT_IN. At least one value is required.
 	Values []string `mapstructure:"values" json:"values"`
 }
 
+// Prepare validates the affinity.
+func (a *NodeAffinity) Prepare() []error {
+	var errs []error
+	if a.Key == "" {
+		errs = append(errs, fmt.Errorf("key must be set"))
+	}
+
+	switch a.Operator {
+	case NodeAffinityIn, NodeAffinityNotIn:
+	case "":
+		errs = append(errs, fmt.Errorf("operator must be set"))
+	default:
+		errs = append(errs, fmt.Errorf("Invalid operator: %q", a.Operator))
+		errs = append(errs, fmt.Errorf("Valid values are %s or %s", NodeAffinityIn, NodeAffinityNotIn))
+	}
+
+	if len(a.Values) == 0 {
+		errs = append(errs, fmt.Errorf("values must have at least one value"))
+	}
+	for _, value := range a.Values {
+		if value == "" {
+			errs = append(errs, fmt.Errorf("values cannot be empty"))
+			break
+		}
+	}
+	return errs
+}
+
+// CheckNodeAffinities checks that the affinities can all be met together:
+// the instance must be placed on a node matching all of them, so a value
+// cannot be both required and excluded for the same key, and the values
+// required by the IN affinities of a key must overlap.
+func CheckNodeAffinities(affinities []NodeAffinity) []error {
+	var errs []error
+	in := map[string]map[string]bool{}
+	notIn := map[string]map[string]bool{}
+	var keys []string
+	for _, a := range affinities {
+		if a.Operator == NodeAffinityNotIn {
+			if notIn[a.Key] == nil {
+				notIn[a.Key] = map[string]bool{}
+			}
+			for _, value := range a.Values {
+				notIn[a.Key][value] = true
+			}
+			continue
+		}
+
+		values := map[string]bool{}
+		for _, value := range a.Values {
+			values[value] = true
+		}
+		current, ok := in[a.Key]
+		if !ok {
+			in[a.Key] = values
+			keys = append(keys, a.Key)
+			continue
+		}
+		// The values allowed for the key are those of every IN affinity.
+		for value := range current {
+			if !values[value] {
+				delete(current, value)
+			}
+		}
+	}
+
+	for _, key := range keys {
+		allowed := 0
+		for value := range in[key] {
+			if !notIn[key][value] {
+				allowed++
+			}
+		}
+		if allowed == 0 {
+			errs = append(errs, fmt.Errorf("no value of %s meets all of its node affinities", key))
+		}
+	}
+	return errs
+}
+
 func (a *NodeAffinity) ComputeType() *compute.SchedulingNodeAffinity {
 	if a == nil {
 		return nil
@@ -0,0 +1,114 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"testing"
+)
+
+func TestNodeAffinity_Prepare(t *testing.T) {
+	testcases := []struct {
+		name      string
+		config    *NodeAffinity
+		expectErr bool
+	}{
+		{
+			name:   "OK - IN",
+			config: &NodeAffinity{Key: "workload", Operator: "IN", Values: []string{"packer", "ci"}},
+		},
+		{
+			name:   "OK - NOT_IN",
+			config: &NodeAffinity{Key: "workload", Operator: "NOT_IN", Values: []string{"production"}},
+		},
+		{
+			name:      "Error - no key",
+			config:    &NodeAffinity{Operator: "IN", Values: []string{"packer"}},
+			expectErr: true,
+		},
+		{
+			name:      "Error - no operator",
+			config:    &NodeAffinity{Key: "workload", Values: []string{"packer"}},
+			expectErr: true,
+		},
+		{
+			name:      "Error - invalid operator",
+			config:    &NodeAffinity{Key: "workload", Operator: "EQUALS", Values: []string{"packer"}},
+			expectErr: true,
+		},
+		{
+			name:      "Error - no values",
+			config:    &NodeAffinity{Key: "workload", Operator: "IN"},
+			expectErr: true,
+		},
+		{
+			name:      "Error - empty value",
+			config:    &NodeAffinity{Key: "workload", Operator: "IN", Values: []string{""}},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			errs := tc.config.Prepare()
+			if (len(errs) != 0) != tc.expectErr {
+				t.Errorf("expected error: %t, got %v", tc.expectErr, errs)
+			}
+		})
+	}
+}
+
+func TestCheckNodeAffinities(t *testing.T) {
+	testcases := []struct {
+		name       string
+		affinities []NodeAffinity
+		expectErr  bool
+	}{
+		{
+			name: "OK - different keys",
+			affinities: []NodeAffinity{
+				{Key: "workload", Operator: "IN", Values: []string{"packer"}},
+				{Key: "environment", Operator: "NOT_IN", Values: []string{"production"}},
+			},
+		},
+		{
+			name: "OK - excluding some of the values",
+			affinities: []NodeAffinity{
+				{Key: "workload", Operator: "IN", Values: []string{"packer", "ci"}},
+				{Key: "workload", Operator: "NOT_IN", Values: []string{"ci"}},
+			},
+		},
+		{
+			name: "OK - overlapping values",
+			affinities: []NodeAffinity{
+				{Key: "workload", Operator: "IN", Values: []string{"packer", "ci"}},
+				{Key: "workload", Operator: "IN", Values: []string{"ci", "test"}},
+			},
+		},
+		{
+			name: "Error - excluding all of the values",
+			affinities: []NodeAffinity{
+				{Key: "workload", Operator: "IN", Values: []string{"packer"}},
+				{Key: "workload", Operator: "NOT_IN", Values: []string{"packer", "ci"}},
+			},
+			expectErr: true,
+		},
+		{
+			name: "Error - disjoint values",
+			affinities: []NodeAffinity{
+				{Key: "workload", Operator: "IN", Values: []string{"packer"}},
+				{Key: "workload", Operator: "IN", Values: []string{"ci"}},
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			errs := CheckNodeAffinities(tc.affinities)
+			if (len(errs) != 0) != tc.expectErr {
+				t.Errorf("expected error: %t, got %v", tc.expectErr, errs)
+			}
+		})
+	}
+}
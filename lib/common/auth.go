@@ -7,12 +7,13 @@
 package common
 
 import (
-	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
 
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 )
 
@@ -23,14 +24,63 @@ type Authentication struct {
 	// This is an alternative to `account_file`, and ignores the `scopes` field.
 	// If both are specified, `access_token` will be used over the `account_file` field.
 	//
+	// This is useful when tokens are minted by an external system, e.g. a
+	// token broker in an air-gapped CI environment, with
+	// `access_token = env("GOOGLE_OAUTH_ACCESS_TOKEN")`.
+	//
 	// These access tokens cannot be renewed by Packer and thus will only work until they expire.
 	// If you anticipate Packer needing access for longer than a token's lifetime (default `1 hour`),
-	// please use a service account key with `account_file` instead.
+	// please use a service account key with `account_file` instead. Packer warns about this when
+	// `access_token` is set.
 	AccessToken string `mapstructure:"access_token" required:"false"`
 	// The JSON file containing your account credentials. Not required if you
 	// run Packer on a GCE instance with a service account. Instructions for
 	// creating the file or using service accounts are above.
 	AccountFile string `mapstructure:"account_file" required:"false"`
+	// The project to bill, and whose quota is used, for the API calls made by
+	// Packer, sent as the `X-Goog-User-Project` header. This is needed when
+	// authenticating with user credentials, e.g. `gcloud auth
+	// application-default login`, against APIs that require a quota project.
+	// The account needs the `serviceusage.services.use` permission on that
+	// project.
+	BillingProject string `mapstructure:"billing_project" required:"false"`
+	// The OAuth scopes requested for the API calls made by Packer when no
+	// credentials are configured, and the Application Default Credentials are
+	// used, e.g. from the metadata server when running on GCE. Defaults to:
+	//
+	// ```json
+	// [
+	//   "https://www.googleapis.com/auth/compute",
+	//   "https://www.googleapis.com/auth/devstorage.full_control",
+	//   "https://www.googleapis.com/auth/userinfo.email",
+	//   "https://www.googleapis.com/auth/cloud-platform"
+	// ]
+	// ```
+	//
+	// Tokens from the metadata server are limited to the access scopes of the
+	// instance running Packer: if those exclude
+	// `https://www.googleapis.com/auth/devstorage.full_control`, exporting or
+	// importing images fails. The requested scopes still need to be allowed
+	// by the instance's access scopes.
+	AuthScopes []string `mapstructure:"auth_scopes" required:"false"`
+	// Set to `private` or `restricted` to send the API calls made by Packer
+	// to the `private.googleapis.com` or `restricted.googleapis.com` VIP
+	// respectively, rather than to the public endpoints of the APIs, e.g. in
+	// a VPC Service Controls perimeter. The VIP is used regardless of the DNS
+	// configuration of the host running Packer, which needs a route to it.
+	//
+	// The builder also checks that the subnetwork of the instance has Private
+	// Google Access enabled, so that the instance can reach the APIs too.
+	GoogleAccess string `mapstructure:"google_access" required:"false"`
+	// The URL of the proxy to call the Google APIs through, e.g.
+	// `http://proxy.example.com:3128` or `socks5://proxy.example.com:1080`.
+	// Defaults to the proxy set by the `HTTPS_PROXY` environment variable;
+	// hosts listed in `NO_PROXY` are still reached directly.
+	//
+	// The IAP tunnel goes through this proxy too. A
+	// `socks5` proxy is also used for the SSH connection, unless
+	// `ssh_proxy_host` or `ssh_proxy_url` is set; an HTTP proxy is not.
+	ProxyURL string `mapstructure:"proxy_url" required:"false"`
 	// The JSON file containing your account credentials.
 	//
 	// The file's contents may be anything supported by the Google Go client, i.e.:
@@ -39,14 +89,31 @@ type Authentication struct {
 	// * OIDC-provided token for federation
 	// * Gcloud user credentials file (refresh-token JSON)
 	// * A Google Developers Console client_credentials.json
+	// * A [Workload Identity Federation](https://cloud.google.com/iam/docs/workload-identity-federation)
+	//   configuration (`external_account`), as created by `gcloud iam
+	//   workload-identity-pools create-cred-config`. This lets CI systems such as
+	//   GitHub Actions or GitLab CI authenticate without a service account key.
 	CredentialsFile string `mapstructure:"credentials_file" required:"false"`
 	// The raw JSON payload for credentials.
 	//
 	// The accepted data formats are same as those described under
 	// [credentials_file](#credentials_file).
 	CredentialsJSON string `mapstructure:"credentials_json" required:"false"`
-	// This allows service account impersonation as per the [docs](https://cloud.google.com/iam/docs/impersonating-service-accounts).
+	// The email of a service account to impersonate, as per the
+	// [docs](https://cloud.google.com/iam/docs/impersonating-service-accounts).
+	// All the API calls are then made as this service account, through tokens
+	// obtained from the IAM Service Account Credentials API.
+	//
+	// The tokens are requested with the other credentials, if any, or with
+	// the Application Default Credentials. That identity needs the
+	// `roles/iam.serviceAccountTokenCreator` role on the impersonated account.
 	ImpersonateServiceAccount string `mapstructure:"impersonate_service_account" required:"false"`
+	// The chain of service accounts to go through to impersonate
+	// `impersonate_service_account`, as per the
+	// [docs](https://cloud.google.com/iam/docs/create-short-lived-credentials-delegated).
+	// Each account needs the `roles/iam.serviceAccountTokenCreator` role on
+	// the next one in the chain, and the last one on the impersonated account.
+	ImpersonateServiceAccountDelegates []string `mapstructure:"impersonate_service_account_delegates" required:"false"`
 	// Can be set instead of account_file. If set, this builder will use
 	// HashiCorp Vault to generate an Oauth token for authenticating against
 	// Google Cloud. The value should be the path of the token generator
@@ -84,10 +151,6 @@ func (a *Authentication) Prepare() ([]string, error) {
 		authTypes = append(authTypes, "credentials_json")
 	}
 
-	if a.ImpersonateServiceAccount != "" {
-		authTypes = append(authTypes, "impersonate_service_account")
-	}
-
 	if a.VaultGCPOauthEngine != "" {
 		authTypes = append(authTypes, "vault_gcp_oauth_engine")
 	}
@@ -96,6 +159,37 @@ func (a *Authentication) Prepare() ([]string, error) {
 		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("too many authentication methods specified (%s), choose only one", strings.Join(authTypes, ", ")))
 	}
 
+	// Impersonation uses the other credentials, or the Application Default
+	// Credentials, to get tokens for the impersonated account.
+	if a.ImpersonateServiceAccount != "" && a.VaultGCPOauthEngine != "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("impersonate_service_account cannot be used with vault_gcp_oauth_engine"))
+	}
+
+	if len(a.AuthScopes) > 0 && (len(authTypes) > 0 || a.ImpersonateServiceAccount != "") {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("auth_scopes can only be used with the Application Default Credentials"))
+	}
+
+	switch a.GoogleAccess {
+	case "", GoogleAccessPrivate, GoogleAccessRestricted:
+	default:
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("google_access must be one of %q or %q", GoogleAccessPrivate, GoogleAccessRestricted))
+	}
+
+	if a.ProxyURL != "" {
+		if _, err := ParseProxyURL(a.ProxyURL); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, err)
+		}
+	}
+
+	if len(a.ImpersonateServiceAccountDelegates) > 0 && a.ImpersonateServiceAccount == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("impersonate_service_account_delegates requires impersonate_service_account"))
+	}
+
+	if a.AccessToken != "" {
+		warnings = append(warnings, "access_token cannot be refreshed: the build will fail once it expires, "+
+			"make sure the token outlives the build")
+	}
+
 	// Authenticating via an account file
 	if a.AccountFile != "" {
 		warnings = append(warnings, "account_file is deprecated, please use either credentials_json or credentials_file instead")
@@ -118,20 +212,119 @@ func (a *Authentication) Prepare() ([]string, error) {
 	}
 
 	if a.CredentialsJSON != "" {
-		cfg, err := google.CredentialsFromJSON(context.Background(), []byte(a.CredentialsJSON), DriverScopes...)
+		scopes, err := credentialsScopes([]byte(a.CredentialsJSON))
 		if err != nil {
 			errs = packersdk.MultiErrorAppend(errs, err)
+		} else {
+			if a.ImpersonateServiceAccount != "" {
+				// Requesting tokens from the IAM Credentials API requires the
+				// cloud-platform scope.
+				scopes = append([]string{CloudPlatformScope}, scopes...)
+			}
+			ctx := transportContext(a.ProxyURL, a.GoogleAccess)
+			cfg, err := google.CredentialsFromJSON(ctx, []byte(a.CredentialsJSON), scopes...)
+			if err != nil {
+				errs = packersdk.MultiErrorAppend(errs, err)
+			} else {
+				// The token source of the credentials caches its tokens,
+				// the driver refreshes them itself.
+				cfg.TokenSource = uncachedTokenSource(func() (oauth2.TokenSource, error) {
+					cfg, err := google.CredentialsFromJSON(ctx, []byte(a.CredentialsJSON), scopes...)
+					if err != nil {
+						return nil, err
+					}
+					return cfg.TokenSource, nil
+				})
+			}
+			a.credentials = cfg
 		}
-		a.credentials = cfg
 	}
 
 	return warnings, errs
 }
 
+// credentialsScopes returns the scopes to request for the given credentials
+// JSON. External accounts are validated, as their errors otherwise only show
+// up when the first token is exchanged, mid-build.
+func credentialsScopes(js []byte) ([]string, error) {
+	// The fields of an external_account credentials JSON, as used by
+	// Workload Identity Federation, that are checked before use.
+	var ea struct {
+		Type                           string          `json:"type"`
+		Audience                       string          `json:"audience"`
+		SubjectTokenType               string          `json:"subject_token_type"`
+		ServiceAccountImpersonationURL string          `json:"service_account_impersonation_url"`
+		CredentialSource               json.RawMessage `json:"credential_source"`
+	}
+	if err := json.Unmarshal(js, &ea); err != nil || ea.Type != "external_account" {
+		// Let the credentials parser report malformed JSON.
+		return DriverScopes, nil
+	}
+
+	var missing []string
+	if ea.Audience == "" {
+		missing = append(missing, "audience")
+	}
+	if ea.SubjectTokenType == "" {
+		missing = append(missing, "subject_token_type")
+	}
+	if len(ea.CredentialSource) == 0 {
+		missing = append(missing, "credential_source")
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("invalid external_account credentials, missing %s", strings.Join(missing, ", "))
+	}
+
+	if err := checkExecutableSource(ea.CredentialSource); err != nil {
+		return nil, err
+	}
+
+	// Without impersonation, the federated token is used as-is, and the
+	// Security Token Service only grants it the cloud-platform scope.
+	if ea.ServiceAccountImpersonationURL == "" {
+		return []string{CloudPlatformScope}, nil
+	}
+	return append([]string{CloudPlatformScope}, DriverScopes...), nil
+}
+
 // ApplyDriverConfig applies the authentication configuration to the config for the GCE Driver
 func (a Authentication) ApplyDriverConfig(cfg *GCEDriverConfig) {
 	cfg.AccessToken = a.AccessToken
 	cfg.ImpersonateServiceAccountName = a.ImpersonateServiceAccount
+	cfg.ImpersonateServiceAccountDelegates = a.ImpersonateServiceAccountDelegates
 	cfg.VaultOauthEngineName = a.VaultGCPOauthEngine
 	cfg.Credentials = a.credentials
+	cfg.BillingProject = a.BillingProject
+	cfg.AuthScopes = a.AuthScopes
+	cfg.GoogleAccess = a.GoogleAccess
+	cfg.ProxyURL = a.ProxyURL
+}
+
+// checkExecutableSource validates the executable flavor of an external_account
+// credential source, where the subject token is printed by a command, e.g. a
+// site-specific token broker.
+func checkExecutableSource(source json.RawMessage) error {
+	var cs struct {
+		Executable *struct {
+			Command       string `json:"command"`
+			TimeoutMillis *int   `json:"timeout_millis"`
+		} `json:"executable"`
+	}
+	if err := json.Unmarshal(source, &cs); err != nil || cs.Executable == nil {
+		return nil
+	}
+
+	if cs.Executable.Command == "" {
+		return fmt.Errorf("invalid external_account credentials, missing credential_source.executable.command")
+	}
+	if t := cs.Executable.TimeoutMillis; t != nil && (*t < 5000 || *t > 120000) {
+		return fmt.Errorf("invalid external_account credentials, credential_source.executable.timeout_millis must be between 5000 and 120000")
+	}
+	// The Google auth library only runs the command when explicitly allowed
+	// to; fail now rather than when the first token is requested.
+	if os.Getenv("GOOGLE_EXTERNAL_ACCOUNT_ALLOW_EXECUTABLES") != "1" {
+		return fmt.Errorf("external_account credentials sourced from an executable require " +
+			"the GOOGLE_EXTERNAL_ACCOUNT_ALLOW_EXECUTABLES environment variable to be set to 1")
+	}
+	return nil
 }
@@ -10,12 +10,17 @@ import (
 // FlatAuthentication is an auto-generated flat version of Authentication.
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlatAuthentication struct {
-	AccessToken               *string `mapstructure:"access_token" required:"false" cty:"access_token" hcl:"access_token"`
-	AccountFile               *string `mapstructure:"account_file" required:"false" cty:"account_file" hcl:"account_file"`
-	CredentialsFile           *string `mapstructure:"credentials_file" required:"false" cty:"credentials_file" hcl:"credentials_file"`
-	CredentialsJSON           *string `mapstructure:"credentials_json" required:"false" cty:"credentials_json" hcl:"credentials_json"`
-	ImpersonateServiceAccount *string `mapstructure:"impersonate_service_account" required:"false" cty:"impersonate_service_account" hcl:"impersonate_service_account"`
-	VaultGCPOauthEngine       *string `mapstructure:"vault_gcp_oauth_engine" cty:"vault_gcp_oauth_engine" hcl:"vault_gcp_oauth_engine"`
+	AccessToken                        *string  `mapstructure:"access_token" required:"false" cty:"access_token" hcl:"access_token"`
+	AccountFile                        *string  `mapstructure:"account_file" required:"false" cty:"account_file" hcl:"account_file"`
+	BillingProject                     *string  `mapstructure:"billing_project" required:"false" cty:"billing_project" hcl:"billing_project"`
+	AuthScopes                         []string `mapstructure:"auth_scopes" required:"false" cty:"auth_scopes" hcl:"auth_scopes"`
+	GoogleAccess                       *string  `mapstructure:"google_access" required:"false" cty:"google_access" hcl:"google_access"`
+	ProxyURL                           *string  `mapstructure:"proxy_url" required:"false" cty:"proxy_url" hcl:"proxy_url"`
+	CredentialsFile                    *string  `mapstructure:"credentials_file" required:"false" cty:"credentials_file" hcl:"credentials_file"`
+	CredentialsJSON                    *string  `mapstructure:"credentials_json" required:"false" cty:"credentials_json" hcl:"credentials_json"`
+	ImpersonateServiceAccount          *string  `mapstructure:"impersonate_service_account" required:"false" cty:"impersonate_service_account" hcl:"impersonate_service_account"`
+	ImpersonateServiceAccountDelegates []string `mapstructure:"impersonate_service_account_delegates" required:"false" cty:"impersonate_service_account_delegates" hcl:"impersonate_service_account_delegates"`
+	VaultGCPOauthEngine                *string  `mapstructure:"vault_gcp_oauth_engine" cty:"vault_gcp_oauth_engine" hcl:"vault_gcp_oauth_engine"`
 }
 
 // FlatMapstructure returns a new FlatAuthentication.
@@ -30,12 +35,17 @@ func (*Authentication) FlatMapstructure() interface{ HCL2Spec() map[string]hclde
 // The decoded values from this spec will then be applied to a FlatAuthentication.
 func (*FlatAuthentication) HCL2Spec() map[string]hcldec.Spec {
 	s := map[string]hcldec.Spec{
-		"access_token":                &hcldec.AttrSpec{Name: "access_token", Type: cty.String, Required: false},
-		"account_file":                &hcldec.AttrSpec{Name: "account_file", Type: cty.String, Required: false},
-		"credentials_file":            &hcldec.AttrSpec{Name: "credentials_file", Type: cty.String, Required: false},
-		"credentials_json":            &hcldec.AttrSpec{Name: "credentials_json", Type: cty.String, Required: false},
-		"impersonate_service_account": &hcldec.AttrSpec{Name: "impersonate_service_account", Type: cty.String, Required: false},
-		"vault_gcp_oauth_engine":      &hcldec.AttrSpec{Name: "vault_gcp_oauth_engine", Type: cty.String, Required: false},
+		"access_token":                          &hcldec.AttrSpec{Name: "access_token", Type: cty.String, Required: false},
+		"account_file":                          &hcldec.AttrSpec{Name: "account_file", Type: cty.String, Required: false},
+		"billing_project":                       &hcldec.AttrSpec{Name: "billing_project", Type: cty.String, Required: false},
+		"auth_scopes":                           &hcldec.AttrSpec{Name: "auth_scopes", Type: cty.List(cty.String), Required: false},
+		"google_access":                         &hcldec.AttrSpec{Name: "google_access", Type: cty.String, Required: false},
+		"proxy_url":                             &hcldec.AttrSpec{Name: "proxy_url", Type: cty.String, Required: false},
+		"credentials_file":                      &hcldec.AttrSpec{Name: "credentials_file", Type: cty.String, Required: false},
+		"credentials_json":                      &hcldec.AttrSpec{Name: "credentials_json", Type: cty.String, Required: false},
+		"impersonate_service_account":           &hcldec.AttrSpec{Name: "impersonate_service_account", Type: cty.String, Required: false},
+		"impersonate_service_account_delegates": &hcldec.AttrSpec{Name: "impersonate_service_account_delegates", Type: cty.List(cty.String), Required: false},
+		"vault_gcp_oauth_engine":                &hcldec.AttrSpec{Name: "vault_gcp_oauth_engine", Type: cty.String, Required: false},
 	}
 	return s
 }
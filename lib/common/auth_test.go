@@ -0,0 +1,188 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testExternalAccount = `{
+  "type": "external_account",
+  "audience": "//iam.googleapis.com/projects/123456789/locations/global/workloadIdentityPools/github/providers/github",
+  "subject_token_type": "urn:ietf:params:oauth:token-type:jwt",
+  "token_url": "https://sts.googleapis.com/v1/token",
+  "credential_source": {
+    "file": "/tmp/github-oidc-token"
+  }
+}`
+
+func TestAuthenticationPrepare_externalAccount(t *testing.T) {
+	a := &Authentication{
+		CredentialsJSON: testExternalAccount,
+	}
+	_, err := a.Prepare()
+	if err != nil {
+		t.Fatalf("external_account credentials should be accepted: %s", err)
+	}
+	if a.credentials == nil {
+		t.Fatal("credentials should have been loaded")
+	}
+
+	a = &Authentication{
+		CredentialsJSON: `{"type": "external_account", "audience": "foo"}`,
+	}
+	_, err = a.Prepare()
+	if err == nil {
+		t.Fatal("incomplete external_account credentials should be rejected")
+	}
+}
+
+func TestCredentialsScopes(t *testing.T) {
+	scopes, err := credentialsScopes([]byte(testExternalAccount))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(scopes, []string{CloudPlatformScope}) {
+		t.Errorf("federated tokens should only request the cloud-platform scope, got %v", scopes)
+	}
+
+	scopes, err = credentialsScopes([]byte(`{"type": "service_account"}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(scopes, DriverScopes) {
+		t.Errorf("service accounts should request the driver scopes, got %v", scopes)
+	}
+}
+
+func TestAuthenticationPrepare_impersonation(t *testing.T) {
+	a := &Authentication{
+		CredentialsJSON:           `{"type": "authorized_user", "client_id": "foo", "client_secret": "bar", "refresh_token": "baz"}`,
+		ImpersonateServiceAccount: "packer@my-project.iam.gserviceaccount.com",
+	}
+	_, err := a.Prepare()
+	if err != nil {
+		t.Fatalf("impersonation should be usable with explicit credentials: %s", err)
+	}
+	if a.credentials == nil {
+		t.Fatal("credentials should have been loaded")
+	}
+
+	a = &Authentication{
+		VaultGCPOauthEngine:       "gcp/token/my-project",
+		ImpersonateServiceAccount: "packer@my-project.iam.gserviceaccount.com",
+	}
+	_, err = a.Prepare()
+	if err == nil {
+		t.Fatal("impersonation should not be usable with vault_gcp_oauth_engine")
+	}
+}
+
+func TestAuthenticationPrepare_impersonationDelegates(t *testing.T) {
+	a := &Authentication{
+		ImpersonateServiceAccountDelegates: []string{"hop@my-project.iam.gserviceaccount.com"},
+	}
+	_, err := a.Prepare()
+	if err == nil {
+		t.Fatal("delegates should require impersonate_service_account")
+	}
+
+	a.ImpersonateServiceAccount = "packer@my-project.iam.gserviceaccount.com"
+	_, err = a.Prepare()
+	if err != nil {
+		t.Fatalf("delegates should be accepted when impersonating: %s", err)
+	}
+
+	var cfg GCEDriverConfig
+	a.ApplyDriverConfig(&cfg)
+	if !reflect.DeepEqual(cfg.ImpersonateServiceAccountDelegates, a.ImpersonateServiceAccountDelegates) {
+		t.Errorf("delegates should be passed to the driver, got %v", cfg.ImpersonateServiceAccountDelegates)
+	}
+}
+
+func TestAuthenticationPrepare_accessToken(t *testing.T) {
+	a := &Authentication{
+		AccessToken: "ya29.fake-token",
+	}
+	warns, err := a.Prepare()
+	if err != nil {
+		t.Fatalf("access_token should be accepted: %s", err)
+	}
+	if len(warns) != 1 || !strings.Contains(warns[0], "expires") {
+		t.Errorf("expected a warning about the token expiry, got %v", warns)
+	}
+}
+
+func TestCredentialsScopes_executableSource(t *testing.T) {
+	executable := func(source string) []byte {
+		return []byte(`{
+  "type": "external_account",
+  "audience": "//iam.googleapis.com/projects/123456789/locations/global/workloadIdentityPools/ci/providers/broker",
+  "subject_token_type": "urn:ietf:params:oauth:token-type:jwt",
+  "token_url": "https://sts.googleapis.com/v1/token",
+  "credential_source": {"executable": ` + source + `}
+}`)
+	}
+
+	t.Setenv("GOOGLE_EXTERNAL_ACCOUNT_ALLOW_EXECUTABLES", "")
+	if _, err := credentialsScopes(executable(`{"command": "/usr/local/bin/token-broker"}`)); err == nil {
+		t.Error("executables should be rejected unless explicitly allowed")
+	}
+
+	t.Setenv("GOOGLE_EXTERNAL_ACCOUNT_ALLOW_EXECUTABLES", "1")
+	if _, err := credentialsScopes(executable(`{"command": "/usr/local/bin/token-broker", "timeout_millis": 30000}`)); err != nil {
+		t.Errorf("allowed executable should be accepted: %s", err)
+	}
+	if _, err := credentialsScopes(executable(`{"timeout_millis": 30000}`)); err == nil {
+		t.Error("executable without a command should be rejected")
+	}
+	if _, err := credentialsScopes(executable(`{"command": "/usr/local/bin/token-broker", "timeout_millis": 1000}`)); err == nil {
+		t.Error("executable with an out of range timeout should be rejected")
+	}
+}
+
+func TestAuthenticationPrepare_authScopes(t *testing.T) {
+	a := &Authentication{
+		AuthScopes: []string{CloudPlatformScope},
+	}
+	_, err := a.Prepare()
+	if err != nil {
+		t.Fatalf("auth_scopes should be accepted with the Application Default Credentials: %s", err)
+	}
+
+	var cfg GCEDriverConfig
+	a.ApplyDriverConfig(&cfg)
+	if !reflect.DeepEqual(cfg.AuthScopes, a.AuthScopes) {
+		t.Errorf("auth_scopes should be passed to the driver, got %v", cfg.AuthScopes)
+	}
+
+	a = &Authentication{
+		AccessToken: "ya29.fake-token",
+		AuthScopes:  []string{CloudPlatformScope},
+	}
+	_, err = a.Prepare()
+	if err == nil {
+		t.Fatal("auth_scopes should not be usable with explicit credentials")
+	}
+}
+
+func TestAuthenticationPrepare_googleAccess(t *testing.T) {
+	for value, valid := range map[string]bool{
+		"":           true,
+		"private":    true,
+		"restricted": true,
+		"public":     false,
+	} {
+		a := &Authentication{GoogleAccess: value}
+		_, err := a.Prepare()
+		if valid && err != nil {
+			t.Errorf("google_access %q should be accepted: %s", value, err)
+		}
+		if !valid && err == nil {
+			t.Errorf("google_access %q should be rejected", value)
+		}
+	}
+}
@@ -51,6 +51,7 @@ type BlockDevice struct {
 	// Possible values:
 	// * kmsKeyName -  The name of the encryption key that is stored in Google Cloud KMS.
 	// * RawKey: - A 256-bit customer-supplied encryption key, encodes in RFC 4648 base64.
+	// * rsa_encrypted_key: - A 256-bit customer-supplied encryption key, wrapped with the RSA public key of Google.
 	//
 	// Refer to the [Customer Encryption Key](#customer-encryption-key) section for more information on the contents of this block.
 	DiskEncryptionKey CustomerEncryptionKey `mapstructure:"disk_encryption_key"`
@@ -149,6 +150,12 @@ func (bd *BlockDevice) Prepare() []error {
 		errs = append(errs, fmt.Errorf("Scratch volumes may not have create_image enabled"))
 	}
 
+	if bd.DiskEncryptionKey != (CustomerEncryptionKey{}) {
+		for _, err := range bd.DiskEncryptionKey.Prepare() {
+			errs = append(errs, fmt.Errorf("disk_encryption_key: %s", err))
+		}
+	}
+
 	if bd.SourceVolume != "" {
 		bd.KeepDevice = true
 	}
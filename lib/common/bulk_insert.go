@@ -0,0 +1,218 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/filelock"
+)
+
+// ErrBulkInsertClosed means that the batch of instances was already being
+// inserted, the instance has to be inserted on its own.
+var ErrBulkInsertClosed = errors.New("the bulk insert already started")
+
+// BulkInsertCoordinator batches the instances with the same properties that
+// the builds of a Packer run, each in its own plugin process, create at the
+// same time, so that they are inserted in a single request.
+//
+// The batches are files in Dir, locked while they are updated. The first
+// build to join a batch waits for Window, then inserts all the instances
+// which joined it meanwhile, and records the result for the others. A build
+// giving up meanwhile withdraws its instance from the batch, or has it
+// deleted once inserted.
+type BulkInsertCoordinator struct {
+	Dir    string
+	Window time.Duration
+	// Context is the context of the build, the instance is withdrawn when
+	// it is done. Defaults to context.Background().
+	Context context.Context
+	// Timeout is how long the build waits for its instance to be inserted,
+	// e.g. its state_timeout. Defaults to no timeout.
+	Timeout time.Duration
+	// PollInterval is how often the builds check if their batch is
+	// inserted. Defaults to a second.
+	PollInterval time.Duration
+}
+
+// bulkInsertBatch is the state of a batch, stored as JSON.
+type bulkInsertBatch struct {
+	Names  []string `json:"names"`
+	Closed bool     `json:"closed"`
+	Done   bool     `json:"done"`
+	Error  string   `json:"error,omitempty"`
+	// Withdrawn are the instances whose build gave up while the batch was
+	// being inserted, to delete.
+	Withdrawn []string `json:"withdrawn,omitempty"`
+}
+
+// Join adds the instance name to the batch of key, and returns once the
+// batch is inserted. insert is called with the names of the instances of
+// the batch if this instance is the first to join it, and remove with the
+// names of the instances withdrawn while they were inserted.
+// ErrBulkInsertClosed is returned if the batch is already being inserted.
+func (c *BulkInsertCoordinator) Join(ctx context.Context, key, name string, insert func(names []string) error, remove func(name string) error) error {
+	if err := os.MkdirAll(c.Dir, 0700); err != nil {
+		return err
+	}
+	sum := sha256.Sum256([]byte(key))
+	path := filepath.Join(c.Dir, hex.EncodeToString(sum[:8])+".json")
+
+	var leader bool
+	err := c.update(path, func(batch *bulkInsertBatch) error {
+		if batch.Closed {
+			return ErrBulkInsertClosed
+		}
+		leader = len(batch.Names) == 0
+		batch.Names = append(batch.Names, name)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if leader {
+		return c.lead(ctx, path, insert, remove)
+	}
+	return c.follow(ctx, path, name)
+}
+
+// lead waits for the other instances to join the batch, and inserts them.
+func (c *BulkInsertCoordinator) lead(ctx context.Context, path string, insert func(names []string) error, remove func(name string) error) error {
+	select {
+	case <-ctx.Done():
+	case <-time.After(c.Window):
+	}
+
+	var names []string
+	err := c.update(path, func(batch *bulkInsertBatch) error {
+		batch.Closed = true
+		names = batch.Names
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	insertErr := ctx.Err()
+	if insertErr == nil {
+		insertErr = insert(names)
+	}
+	var withdrawn []string
+	err = c.update(path, func(batch *bulkInsertBatch) error {
+		batch.Done = true
+		if insertErr != nil {
+			batch.Error = insertErr.Error()
+		}
+		withdrawn = batch.Withdrawn
+		return nil
+	})
+	if insertErr != nil {
+		return insertErr
+	}
+	for _, name := range withdrawn {
+		log.Printf("[INFO] Deleting the instance %s, whose build gave up waiting for it", name)
+		if err := remove(name); err != nil {
+			log.Printf("[WARN] Error deleting the instance %s, please delete it manually: %s", name, err)
+		}
+	}
+	return err
+}
+
+// follow waits for the first instance of the batch to insert it, and
+// withdraws the instance name if ctx is done first.
+func (c *BulkInsertCoordinator) follow(ctx context.Context, path, name string) error {
+	interval := c.PollInterval
+	if interval == 0 {
+		interval = time.Second
+	}
+	for {
+		var batch bulkInsertBatch
+		err := c.update(path, func(b *bulkInsertBatch) error {
+			batch = *b
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if batch.Done {
+			if batch.Error != "" {
+				return fmt.Errorf("Error inserting the instances %q: %s", batch.Names, batch.Error)
+			}
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return c.withdraw(ctx, path, name)
+		case <-time.After(interval):
+		}
+	}
+}
+
+// withdraw removes the instance name from the batch, or has it deleted if
+// it is being inserted, and returns the error of ctx. If the batch was
+// inserted meanwhile, the instance is kept and its result returned.
+func (c *BulkInsertCoordinator) withdraw(ctx context.Context, path, name string) error {
+	var result error
+	err := c.update(path, func(batch *bulkInsertBatch) error {
+		switch {
+		case batch.Done && batch.Error != "":
+			result = fmt.Errorf("Error inserting the instances %q: %s", batch.Names, batch.Error)
+		case batch.Done:
+		case batch.Closed:
+			batch.Withdrawn = append(batch.Withdrawn, name)
+			result = ctx.Err()
+		default:
+			for i, n := range batch.Names {
+				if n == name {
+					batch.Names = append(batch.Names[:i], batch.Names[i+1:]...)
+					break
+				}
+			}
+			result = ctx.Err()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return result
+}
+
+// update calls f with the batch at path, locked, and saves it.
+func (c *BulkInsertCoordinator) update(path string, f func(*bulkInsertBatch) error) error {
+	lock := filelock.New(path + ".lock")
+	if err := lock.Lock(); err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	var batch bulkInsertBatch
+	data, err := os.ReadFile(path)
+	if err == nil {
+		err = json.Unmarshal(data, &batch)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := f(&batch); err != nil {
+		return err
+	}
+	data, err = json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
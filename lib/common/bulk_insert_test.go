@@ -0,0 +1,188 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testBulkInsertCoordinator(t *testing.T) *BulkInsertCoordinator {
+	return &BulkInsertCoordinator{
+		Dir:          t.TempDir(),
+		Window:       200 * time.Millisecond,
+		PollInterval: 10 * time.Millisecond,
+	}
+}
+
+// joinAll joins the instances to the batch of key concurrently, and returns
+// the names of the instances of each insert, and the errors of each
+// instance.
+func joinAll(t *testing.T, c *BulkInsertCoordinator, key string, names []string, insertErr error) ([][]string, []error) {
+	var mu sync.Mutex
+	var inserts [][]string
+	errs := make([]error, len(names))
+
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			errs[i] = c.Join(context.Background(), key, name, func(names []string) error {
+				mu.Lock()
+				defer mu.Unlock()
+				inserts = append(inserts, names)
+				return insertErr
+			}, func(string) error {
+				t.Error("should not remove")
+				return nil
+			})
+		}(i, name)
+	}
+	wg.Wait()
+	return inserts, errs
+}
+
+func TestBulkInsertCoordinator(t *testing.T) {
+	c := testBulkInsertCoordinator(t)
+	names := []string{"packer-1", "packer-2", "packer-3"}
+
+	inserts, errs := joinAll(t, c, "key", names, nil)
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", names[i], err)
+		}
+	}
+	if len(inserts) != 1 {
+		t.Fatalf("the instances should be inserted at once: %v", inserts)
+	}
+	sort.Strings(inserts[0])
+	if fmt.Sprint(inserts[0]) != fmt.Sprint(names) {
+		t.Errorf("bad instances: %v", inserts[0])
+	}
+
+	// The batch is already inserted.
+	err := c.Join(context.Background(), "key", "packer-4", func([]string) error {
+		t.Fatal("should not insert")
+		return nil
+	}, nil)
+	if !errors.Is(err, ErrBulkInsertClosed) {
+		t.Errorf("bad error: %v", err)
+	}
+
+	// Other properties are another batch.
+	inserts, _ = joinAll(t, c, "other key", []string{"packer-5"}, nil)
+	if len(inserts) != 1 || inserts[0][0] != "packer-5" {
+		t.Errorf("bad inserts: %v", inserts)
+	}
+}
+
+func TestBulkInsertCoordinator_error(t *testing.T) {
+	c := testBulkInsertCoordinator(t)
+
+	_, errs := joinAll(t, c, "key", []string{"packer-1", "packer-2"}, errors.New("quota exceeded"))
+	for _, err := range errs {
+		if err == nil || !strings.Contains(err.Error(), "quota exceeded") {
+			t.Errorf("bad error: %v", err)
+		}
+	}
+}
+
+func TestBulkInsertCoordinator_followerTimeout(t *testing.T) {
+	c := testBulkInsertCoordinator(t)
+
+	var inserted []string
+	leaderErr := make(chan error, 1)
+	go func() {
+		leaderErr <- c.Join(context.Background(), "key", "packer-1", func(names []string) error {
+			inserted = names
+			return nil
+		}, func(name string) error {
+			t.Errorf("should not remove %s", name)
+			return nil
+		})
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	// The follower gives up before the leader inserts the batch.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := c.Join(ctx, "key", "packer-2", func([]string) error {
+		t.Fatal("should not insert")
+		return nil
+	}, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("bad error: %v", err)
+	}
+
+	if err := <-leaderErr; err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if fmt.Sprint(inserted) != "[packer-1]" {
+		t.Errorf("the withdrawn instance should not be inserted: %v", inserted)
+	}
+}
+
+func TestBulkInsertCoordinator_followerTimeoutDuringInsert(t *testing.T) {
+	c := testBulkInsertCoordinator(t)
+
+	var removed []string
+	leaderErr := make(chan error, 1)
+	go func() {
+		leaderErr <- c.Join(context.Background(), "key", "packer-1", func(names []string) error {
+			// The follower gives up while the instances are inserted.
+			time.Sleep(200 * time.Millisecond)
+			return nil
+		}, func(name string) error {
+			removed = append(removed, name)
+			return nil
+		})
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	err := c.Join(ctx, "key", "packer-2", func([]string) error {
+		t.Fatal("should not insert")
+		return nil
+	}, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("bad error: %v", err)
+	}
+
+	if err := <-leaderErr; err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if fmt.Sprint(removed) != "[packer-2]" {
+		t.Errorf("the withdrawn instance should be deleted once inserted: %v", removed)
+	}
+}
+
+func TestBulkInsertIneligibility(t *testing.T) {
+	c := &InstanceConfig{Name: "packer-1", DiskName: "packer-1"}
+	if reason := bulkInsertIneligibility(c); reason != "" {
+		t.Errorf("should be eligible: %s", reason)
+	}
+
+	c.ExtraBlockDevices = []BlockDevice{{VolumeType: LocalScratch}}
+	if reason := bulkInsertIneligibility(c); reason != "" {
+		t.Errorf("should be eligible with a scratch disk: %s", reason)
+	}
+
+	for name, c := range map[string]*InstanceConfig{
+		"address":   {Name: "packer-1", DiskName: "packer-1", Address: "ip"},
+		"disk name": {Name: "packer-1", DiskName: "disk"},
+		"disks":     {Name: "packer-1", DiskName: "packer-1", ExtraBlockDevices: []BlockDevice{{VolumeType: ZonalSSD}}},
+	} {
+		if bulkInsertIneligibility(c) == "" {
+			t.Errorf("%s: should not be eligible", name)
+		}
+	}
+}
@@ -6,7 +6,13 @@
 
 package common
 
-import compute "google.golang.org/api/compute/v1"
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+
+	compute "google.golang.org/api/compute/v1"
+)
 
 type CustomerEncryptionKey struct {
 	// KmsKeyName: The name of the encryption key that is stored in Google
@@ -16,6 +22,58 @@ type CustomerEncryptionKey struct {
 	// RawKey: Specifies a 256-bit customer-supplied encryption key, encoded
 	// in RFC 4648 base64 to either encrypt or decrypt this resource.
 	RawKey string `mapstructure:"rawKey" json:"rawKey,omitempty"`
+
+	// RsaEncryptedKey: Specifies a 256-bit customer-supplied encryption key,
+	// wrapped with the RSA public key certificate of Google, and encoded in
+	// RFC 4648 base64, to either encrypt or decrypt this resource.
+	RsaEncryptedKey string `mapstructure:"rsa_encrypted_key" json:"rsaEncryptedKey,omitempty"`
+
+	// KmsKeyServiceAccount: The service account used for the encryption
+	// request with kmsKeyName, instead of the Compute Engine service agent.
+	KmsKeyServiceAccount string `mapstructure:"kms_key_service_account" json:"kmsKeyServiceAccount,omitempty"`
+}
+
+// Prepare validates the key: exactly one of kmsKeyName, rawKey or
+// rsa_encrypted_key must be set.
+func (k *CustomerEncryptionKey) Prepare() []error {
+	var errs []error
+	set := 0
+	for _, key := range []string{k.KmsKeyName, k.RawKey, k.RsaEncryptedKey} {
+		if key != "" {
+			set++
+		}
+	}
+	switch {
+	case set == 0:
+		errs = append(errs, errors.New("one of kmsKeyName, rawKey or rsa_encrypted_key must be set"))
+	case set > 1:
+		errs = append(errs, errors.New("only one of kmsKeyName, rawKey or rsa_encrypted_key can be set"))
+	}
+	if k.KmsKeyServiceAccount != "" && k.KmsKeyName == "" {
+		errs = append(errs, errors.New("kms_key_service_account can only be set with kmsKeyName"))
+	}
+	return errs
+}
+
+// CustomerSupplied returns whether the key is supplied by the customer,
+// rather than stored in Cloud KMS.
+func (k *CustomerEncryptionKey) CustomerSupplied() bool {
+	return k != nil && (k.RawKey != "" || k.RsaEncryptedKey != "")
+}
+
+// RawKeySha256 returns the SHA-256 of the raw key, encoded in RFC 4648
+// base64 like the digest of the key reported by Compute Engine, or "" if
+// the key is not a valid raw key.
+func (k *CustomerEncryptionKey) RawKeySha256() string {
+	if k == nil || k.RawKey == "" {
+		return ""
+	}
+	key, err := base64.StdEncoding.DecodeString(k.RawKey)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(key)
+	return base64.StdEncoding.EncodeToString(sum[:])
 }
 
 func (k *CustomerEncryptionKey) ComputeType() *compute.CustomerEncryptionKey {
@@ -23,7 +81,9 @@ func (k *CustomerEncryptionKey) ComputeType() *compute.CustomerEncryptionKey {
 		return nil
 	}
 	return &compute.CustomerEncryptionKey{
-		KmsKeyName: k.KmsKeyName,
-		RawKey:     k.RawKey,
+		KmsKeyName:           k.KmsKeyName,
+		KmsKeyServiceAccount: k.KmsKeyServiceAccount,
+		RawKey:               k.RawKey,
+		RsaEncryptedKey:      k.RsaEncryptedKey,
 	}
 }
@@ -10,8 +10,10 @@ import (
 // FlatCustomerEncryptionKey is an auto-generated flat version of CustomerEncryptionKey.
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlatCustomerEncryptionKey struct {
-	KmsKeyName *string `mapstructure:"kmsKeyName" json:"kmsKeyName,omitempty" cty:"kmsKeyName" hcl:"kmsKeyName"`
-	RawKey     *string `mapstructure:"rawKey" json:"rawKey,omitempty" cty:"rawKey" hcl:"rawKey"`
+	KmsKeyName           *string `mapstructure:"kmsKeyName" json:"kmsKeyName,omitempty" cty:"kmsKeyName" hcl:"kmsKeyName"`
+	RawKey               *string `mapstructure:"rawKey" json:"rawKey,omitempty" cty:"rawKey" hcl:"rawKey"`
+	RsaEncryptedKey      *string `mapstructure:"rsa_encrypted_key" json:"rsaEncryptedKey,omitempty" cty:"rsa_encrypted_key" hcl:"rsa_encrypted_key"`
+	KmsKeyServiceAccount *string `mapstructure:"kms_key_service_account" json:"kmsKeyServiceAccount,omitempty" cty:"kms_key_service_account" hcl:"kms_key_service_account"`
 }
 
 // FlatMapstructure returns a new FlatCustomerEncryptionKey.
@@ -26,8 +28,10 @@ func (*CustomerEncryptionKey) FlatMapstructure() interface{ HCL2Spec() map[strin
 // The decoded values from this spec will then be applied to a FlatCustomerEncryptionKey.
 func (*FlatCustomerEncryptionKey) HCL2Spec() map[string]hcldec.Spec {
 	s := map[string]hcldec.Spec{
-		"kmsKeyName": &hcldec.AttrSpec{Name: "kmsKeyName", Type: cty.String, Required: false},
-		"rawKey":     &hcldec.AttrSpec{Name: "rawKey", Type: cty.String, Required: false},
+		"kmsKeyName":              &hcldec.AttrSpec{Name: "kmsKeyName", Type: cty.String, Required: false},
+		"rawKey":                  &hcldec.AttrSpec{Name: "rawKey", Type: cty.String, Required: false},
+		"rsa_encrypted_key":       &hcldec.AttrSpec{Name: "rsa_encrypted_key", Type: cty.String, Required: false},
+		"kms_key_service_account": &hcldec.AttrSpec{Name: "kms_key_service_account", Type: cty.String, Required: false},
 	}
 	return s
 }
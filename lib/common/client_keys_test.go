@@ -0,0 +1,91 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"testing"
+)
+
+func TestCustomerEncryptionKey_Prepare(t *testing.T) {
+	testcases := []struct {
+		name      string
+		key       *CustomerEncryptionKey
+		expectErr bool
+	}{
+		{
+			name: "OK - KMS key",
+			key:  &CustomerEncryptionKey{KmsKeyName: "projects/p/locations/global/keyRings/r/cryptoKeys/k"},
+		},
+		{
+			name: "OK - KMS key with service account",
+			key: &CustomerEncryptionKey{
+				KmsKeyName:           "projects/p/locations/global/keyRings/r/cryptoKeys/k",
+				KmsKeyServiceAccount: "kms@p.iam.gserviceaccount.com",
+			},
+		},
+		{
+			name: "OK - raw key",
+			key:  &CustomerEncryptionKey{RawKey: "SGVsbG8gZnJvbSBHb29nbGUgQ2xvdWQgUGxhdGZvcm0="},
+		},
+		{
+			name: "OK - RSA-wrapped key",
+			key:  &CustomerEncryptionKey{RsaEncryptedKey: "ieCx/NcW06PcT7Ep1X6LUTc/hLvUDYyzSZPPVCVPTVEohpeHASqC8uw5TzyO9U+Fka9JFHz0mBibXUInrC/jEk014kCK/NPjYgEMOyssZ4ZINPKxlUh2zn1bV+MCaTICrdmuSBTWlUUiFoDD6PYznLwh8ZNdaheCeZ8ewEXgFQ8V+sDroLaN3Xs3MDTXQEMMoNUXMCZEIpg9Vtp9x2oeQ5lAbtt7bYAAHf5l+gJWw3sUfs0/Glw5fpdjT8Uggrr+RMZezGrltJEF293rvTIjWOEB3z5OHyHwQkvdrPDFcTqsLfh+8Hr8g+mf+7zVPEC8nEbqpdl3GPv3A7AwpFp7MA=="},
+		},
+		{
+			name:      "Error - no key",
+			key:       &CustomerEncryptionKey{},
+			expectErr: true,
+		},
+		{
+			name: "Error - raw and RSA-wrapped keys",
+			key: &CustomerEncryptionKey{
+				RawKey:          "SGVsbG8gZnJvbSBHb29nbGUgQ2xvdWQgUGxhdGZvcm0=",
+				RsaEncryptedKey: "ieCx",
+			},
+			expectErr: true,
+		},
+		{
+			name: "Error - KMS and raw keys",
+			key: &CustomerEncryptionKey{
+				KmsKeyName: "projects/p/locations/global/keyRings/r/cryptoKeys/k",
+				RawKey:     "SGVsbG8gZnJvbSBHb29nbGUgQ2xvdWQgUGxhdGZvcm0=",
+			},
+			expectErr: true,
+		},
+		{
+			name: "Error - service account without KMS key",
+			key: &CustomerEncryptionKey{
+				RawKey:               "SGVsbG8gZnJvbSBHb29nbGUgQ2xvdWQgUGxhdGZvcm0=",
+				KmsKeyServiceAccount: "kms@p.iam.gserviceaccount.com",
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			errs := tc.key.Prepare()
+			if (len(errs) != 0) != tc.expectErr {
+				t.Errorf("expected error: %t, got %v", tc.expectErr, errs)
+			}
+		})
+	}
+}
+
+func TestCustomerEncryptionKey_ComputeType(t *testing.T) {
+	key := &CustomerEncryptionKey{
+		KmsKeyName:           "projects/p/locations/global/keyRings/r/cryptoKeys/k",
+		KmsKeyServiceAccount: "kms@p.iam.gserviceaccount.com",
+	}
+	got := key.ComputeType()
+	if got.KmsKeyName != key.KmsKeyName || got.KmsKeyServiceAccount != key.KmsKeyServiceAccount {
+		t.Errorf("bad key: %#v", got)
+	}
+	if key.CustomerSupplied() {
+		t.Error("a KMS key is not supplied by the customer")
+	}
+	if !(&CustomerEncryptionKey{RsaEncryptedKey: "ieCx"}).CustomerSupplied() {
+		t.Error("an RSA-wrapped key is supplied by the customer")
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"context"
+	"net/http"
+
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
+)
+
+// WithExtraHeaders returns client options that send headers along with every
+// API call, on top of what opts already configures, e.g. authentication.
+func WithExtraHeaders(opts []option.ClientOption, headers http.Header) ([]option.ClientOption, error) {
+	return withTransport(opts, http.DefaultTransport, headers)
+}
+
+// withTransport returns client options that send the API calls through base,
+// with headers, on top of what opts already configures.
+func withTransport(opts []option.ClientOption, base http.RoundTripper, headers http.Header) ([]option.ClientOption, error) {
+	trans, err := htransport.NewTransport(context.TODO(), base, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(headers) > 0 {
+		trans = &headerTransport{
+			headers: headers,
+			base:    trans,
+		}
+	}
+	return []option.ClientOption{option.WithHTTPClient(&http.Client{Transport: trans})}, nil
+}
+
+// headerTransport is an http.RoundTripper adding headers to every request.
+type headerTransport struct {
+	headers http.Header
+	base    http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A RoundTripper must not modify the request it is given.
+	req = req.Clone(req.Context())
+	for k, vs := range t.headers {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	return t.base.RoundTrip(req)
+}
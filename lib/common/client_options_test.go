@@ -0,0 +1,161 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	compute "google.golang.org/api/compute/v1"
+	"google.golang.org/api/option"
+)
+
+func TestWithExtraHeaders(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	opts := []option.ClientOption{
+		option.WithoutAuthentication(),
+		option.WithQuotaProject("billing-project"),
+	}
+	opts, err := WithExtraHeaders(opts, http.Header{"X-Test": []string{"packer"}})
+	if err != nil {
+		t.Fatalf("failed to add extra headers: %s", err)
+	}
+	opts = append(opts, option.WithEndpoint(srv.URL+"/"))
+
+	service, err := compute.NewService(context.Background(), opts...)
+	if err != nil {
+		t.Fatalf("failed to create service: %s", err)
+	}
+	if _, err := service.Projects.Get("project").Do(); err != nil {
+		t.Fatalf("request failed: %s", err)
+	}
+
+	if v := got.Get("X-Test"); v != "packer" {
+		t.Errorf("expected X-Test header to be %q, got %q", "packer", v)
+	}
+	if v := got.Get("X-Goog-User-Project"); v != "billing-project" {
+		t.Errorf("expected X-Goog-User-Project header to be %q, got %q", "billing-project", v)
+	}
+}
+
+func TestNewClientOptionGoogle_accessToken(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	// The deprecated signature keeps working.
+	opts, err := NewClientOptionGoogle("", "", "ya29.token", nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create the client options: %s", err)
+	}
+	service, err := compute.NewService(context.Background(), append(opts, option.WithEndpoint(srv.URL+"/"))...)
+	if err != nil {
+		t.Fatalf("failed to create service: %s", err)
+	}
+	if _, err := service.Projects.Get("project").Do(); err != nil {
+		t.Fatalf("request failed: %s", err)
+	}
+	if v := got.Get("Authorization"); v != "Bearer ya29.token" {
+		t.Errorf("expected the access token to be used, got %q", v)
+	}
+}
+
+// roundTripperFunc is an http.RoundTripper calling itself.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestNewClientOptionGoogleWithConfig_httpClient(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	// The impersonated token is requested with the HTTP client.
+	var tokenRequests []string
+	client := &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		tokenRequests = append(tokenRequests, r.URL.Host+r.URL.Path)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"accessToken": "ya29.impersonated", "expireTime": "2100-01-01T00:00:00Z"}`)),
+			Request:    r,
+		}, nil
+	})}
+	opts, err := NewClientOptionGoogleWithConfig(ClientOptionGoogleConfig{
+		ImpersonateServiceAccountName:      "packer@my-project.iam.gserviceaccount.com",
+		ImpersonateServiceAccountDelegates: []string{"delegate@my-project.iam.gserviceaccount.com"},
+		AccessToken:                        "ya29.token",
+		HTTPClient:                         client,
+	})
+	if err != nil {
+		t.Fatalf("failed to create the client options: %s", err)
+	}
+	service, err := compute.NewService(context.Background(), append(opts, option.WithEndpoint(srv.URL+"/"))...)
+	if err != nil {
+		t.Fatalf("failed to create service: %s", err)
+	}
+	if _, err := service.Projects.Get("project").Do(); err != nil {
+		t.Fatalf("request failed: %s", err)
+	}
+	if len(tokenRequests) != 1 {
+		t.Errorf("expected a token request through the HTTP client, got %v", tokenRequests)
+	}
+	if v := got.Get("Authorization"); v != "Bearer ya29.impersonated" {
+		t.Errorf("expected the impersonated token to be used, got %q", v)
+	}
+}
+
+func TestIsGoogleAPIsHost(t *testing.T) {
+	for host, expected := range map[string]bool{
+		"compute.googleapis.com":  true,
+		"oauth2.googleapis.com.":  true,
+		"www.googleapis.com":      true,
+		"googleapis.com":          true,
+		"notgoogleapis.com":       false,
+		"accounts.google.com":     false,
+		"vault.example.com":       false,
+		"googleapis.com.evil.com": false,
+	} {
+		if got := isGoogleAPIsHost(host); got != expected {
+			t.Errorf("isGoogleAPIsHost(%q) = %t, expected %t", host, got, expected)
+		}
+	}
+}
+
+func TestGoogleAccessTransport(t *testing.T) {
+	// Hosts other than *.googleapis.com are dialed as usual.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: newTransport("", GoogleAccessPrivate)}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("request failed: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("unexpected status %d", resp.StatusCode)
+	}
+}
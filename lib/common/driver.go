@@ -4,29 +4,50 @@
 package common
 
 import (
+	"context"
 	"crypto/rsa"
 	"io"
 	"time"
 
+	cloudkms "google.golang.org/api/cloudkms/v1"
 	compute "google.golang.org/api/compute/v1"
+	iam "google.golang.org/api/iam/v1"
 	oauth2_svc "google.golang.org/api/oauth2/v2"
+	ondemandscanning "google.golang.org/api/ondemandscanning/v1"
+	osconfig "google.golang.org/api/osconfig/v1"
 	oslogin "google.golang.org/api/oslogin/v1"
+	"google.golang.org/api/storage/v1"
 )
 
 // Driver is the interface that has to be implemented to communicate
 // with GCE. The Driver interface exists mostly to allow a mock implementation
 // to be used to test the steps.
+//
+// Driver is made of one interface per service; code that only needs some of
+// them should depend on those instead, so that it can be tested with a mock
+// of these services only.
 type Driver interface {
+	ComputeDriver
+	IAMDriver
+	IAPDriver
+	ImageDriver
+	InstanceGroupDriver
+	InstanceTemplateDriver
+	KMSDriver
+	OSLoginDriver
+	ParameterManagerDriver
+	PubSubDriver
+	ScanningDriver
+	SecretManagerDriver
+	StorageDriver
+}
+
+// ComputeDriver is the interface to the Compute Engine instances, disks and
+// quotas.
+type ComputeDriver interface {
 	// CreateDisk creates a persistent disk from the specified config.
 	CreateDisk(diskConfig BlockDevice) (<-chan *compute.Disk, <-chan error)
 
-	// CreateImage creates an image from the given disk in Google Compute
-	// Engine.
-	CreateImage(project string, imageSpec *compute.Image) (<-chan *Image, <-chan error)
-
-	// DeleteImage deletes the image with the given name.
-	DeleteImage(project, name string) <-chan error
-
 	// DeleteInstance deletes the given instance, keeping the boot disk.
 	DeleteInstance(zone, name string) (<-chan error, error)
 
@@ -36,62 +57,340 @@ type Driver interface {
 	// GetDisk gets the disk with the given name in a zone/region.
 	GetDisk(zone, name string) (*compute.Disk, error)
 
-	// GetImage gets an image; tries the default and public projects. If
-	// fromFamily is true, name designates an image family instead of a
-	// particular image.
-	GetImage(name string, fromFamily bool) (*Image, error)
-
-	// GetImageFromProject gets an image from a specific projects.
-	// Returns the image from the first project in slice it can find one
-	// If fromFamily is true, name designates an image family instead of a particular image.
-	GetImageFromProjects(project []string, name string, fromFamily bool) (*Image, error)
-
-	// GetImageFromProject gets an image from a specific project. If fromFamily
-	// is true, name designates an image family instead of a particular image.
-	GetImageFromProject(project, name string, fromFamily bool) (*Image, error)
+	// GetInstance gets the instance with the given name in a zone.
+	GetInstance(zone, name string) (*compute.Instance, error)
 
 	// GetInstanceMetadata gets a metadata variable for the instance, name.
 	GetInstanceMetadata(zone, name, key string) (string, error)
 
+	// GetInstanceStatus gets the current status of the instance, e.g. RUNNING.
+	GetInstanceStatus(zone, name string) (string, error)
+
 	// GetInternalIP gets the GCE-internal IP address for the instance.
 	GetInternalIP(zone, name string) (string, error)
 
 	// GetNatIP gets the NAT IP address for the instance.
 	GetNatIP(zone, name string) (string, error)
 
+	// GetMachineType gets the machine type with the given name in a zone.
+	GetMachineType(zone, name string) (*compute.MachineType, error)
+
+	// ListZones lists the zones of the project.
+	ListZones() ([]*compute.Zone, error)
+
+	// ListRegions lists the regions of the project, with their quotas.
+	ListRegions() ([]*compute.Region, error)
+
+	// MachineTypeZones returns the zones offering the machine type name.
+	MachineTypeZones(name string) ([]string, error)
+
+	// AcceleratorTypeZones returns the zones offering the accelerator type
+	// name, e.g. nvidia-tesla-t4.
+	AcceleratorTypeZones(name string) ([]string, error)
+
+	// GetRegionQuotas gets the quotas, and their current usage, of a region.
+	GetRegionQuotas(region string) ([]*compute.Quota, error)
+
+	// GetProjectQuotas gets the project-wide quotas, and their current usage.
+	GetProjectQuotas() ([]*compute.Quota, error)
+
+	// GetSubnetwork gets the subnetwork with the given name in a region.
+	GetSubnetwork(project, region, name string) (*compute.Subnetwork, error)
+
+	// GetDefaultServiceAccount gets the email of the default Compute Engine
+	// service account of project.
+	GetDefaultServiceAccount(project string) (string, error)
+
+	// GetProjectMetadata gets the common instance metadata of project.
+	GetProjectMetadata(project string) (map[string]string, error)
+
+	// GetNetwork gets the network with the given name.
+	GetNetwork(project, name string) (*compute.Network, error)
+
+	// ListFirewalls lists the firewall rules of project.
+	ListFirewalls(project string) ([]*compute.Firewall, error)
+
+	// GetSharedVpcHost gets the Shared VPC host project of project, or ""
+	// if project is not a Shared VPC service project.
+	GetSharedVpcHost(project string) (string, error)
+
+	// ListSnapshots lists the snapshots of project matching filter, in the
+	// syntax of the Compute Engine API. An empty filter lists all the
+	// snapshots.
+	ListSnapshots(project, filter string) ([]*compute.Snapshot, error)
+
+	// CreateSnapshot snapshots the disk with the given name, in a zone or
+	// a region, and waits for the snapshot to be created.
+	CreateSnapshot(zone, disk string, snapshot *compute.Snapshot) error
+
+	// DeleteSnapshot deletes the snapshot with the given name.
+	DeleteSnapshot(name string) error
+
+	// InstancePreemptedSince returns whether the instance with the given
+	// name was preempted since the given time.
+	InstancePreemptedSince(zone, name string, since time.Time) (bool, error)
+
 	// GetSerialPortOutput gets the Serial Port contents for the instance.
 	GetSerialPortOutput(zone, name string) (string, error)
 
-	// GetTokenInfo gets the information about the token used for authentication
-	GetTokenInfo() (*oauth2_svc.Tokeninfo, error)
+	// GetSerialPortOutputFrom gets the Serial Port contents for the instance
+	// from the byte start, and the byte to get the next contents from.
+	GetSerialPortOutputFrom(zone, name string, start int64) (string, int64, error)
 
-	// ImageExists returns true if the specified image exists. If an error
-	// occurs calling the API, this method returns false.
-	ImageExists(project, name string) bool
+	// GetGuestAttributes gets the guest attributes the instance set under
+	// queryPath, e.g. "namespace/", by key.
+	GetGuestAttributes(zone, name, queryPath string) (map[string]string, error)
 
 	// RunInstance takes the given config and launches an instance.
 	RunInstance(*InstanceConfig) (<-chan error, error)
 
+	// ClaimWarmInstance returns the name of an idle instance of the warm
+	// pool, marked as used, or "" if there is none.
+	ClaimWarmInstance(zone, pool string) (string, error)
+
+	// StartWarmInstance starts the claimed warm instance name with a new
+	// boot disk, and the metadata and labels of c.
+	StartWarmInstance(name string, c *InstanceConfig) error
+
+	// ReleaseWarmInstance stops the instance, detaches its boot disk, and
+	// marks it idle in the warm pool, unless the pool already has size
+	// idle instances. It returns whether the instance was kept.
+	ReleaseWarmInstance(zone, name, pool string, size int) (bool, error)
+
 	// WaitForInstance waits for an instance to reach the given state.
 	WaitForInstance(state, zone, name string) <-chan error
 
 	// CreateOrResetWindowsPassword creates or resets the password for a user on an Windows instance.
 	CreateOrResetWindowsPassword(zone, name string, config *WindowsPasswordConfig) (<-chan error, error)
 
+	// AddToInstanceMetadata sets metadata on the existing instance in a
+	// single update, replacing the values of the keys it already has.
+	AddToInstanceMetadata(zone string, name string, metadata map[string]string) error
+
+	// Operations returns the Compute Engine operations the driver waited
+	// for, in order.
+	Operations() []Operation
+}
+
+// IAMDriver is the interface to the IAM policies of the project.
+type IAMDriver interface {
+	// TestProjectPermissions returns the subset of permissions that the
+	// account used by Packer has on the project.
+	TestProjectPermissions(project string, permissions []string) ([]string, error)
+
+	// GetServiceAccount gets the service account with the given email.
+	GetServiceAccount(email string) (*iam.ServiceAccount, error)
+
+	// GetProjectNumber gets the number of project.
+	GetProjectNumber(project string) (int64, error)
+}
+
+type KMSDriver interface {
+	// GetCryptoKey gets the Cloud KMS key name, of the form
+	// projects/*/locations/*/keyRings/*/cryptoKeys/*.
+	GetCryptoKey(name string) (*cloudkms.CryptoKey, error)
+
+	// GetKMSRoleMembers returns the members granted role on resource, a key,
+	// a key ring, or a project of the form projects/*.
+	GetKMSRoleMembers(resource, role string) ([]string, error)
+}
+
+// ImageDriver is the interface to the Compute Engine images.
+type ImageDriver interface {
+	// CreateImage creates an image from the given disk in Google Compute
+	// Engine.
+	CreateImage(project string, imageSpec *compute.Image) (<-chan *Image, <-chan error)
+
+	// ForceCreateImage creates an image like CreateImage, even if its
+	// source disk is attached to a running instance.
+	ForceCreateImage(project string, imageSpec *compute.Image) (<-chan *Image, <-chan error)
+
+	// DeleteImage deletes the image with the given name.
+	DeleteImage(project, name string) <-chan error
+
+	// GetImage gets an image; tries the default and public projects. If
+	// fromFamily is true, name designates an image family instead of a
+	// particular image.
+	GetImage(name string, fromFamily bool) (*Image, error)
+
+	// GetImageFromProject gets an image from a specific projects.
+	// Returns the image from the first project in slice it can find one
+	// If fromFamily is true, name designates an image family instead of a particular image.
+	GetImageFromProjects(project []string, name string, fromFamily bool) (*Image, error)
+
+	// GetImageFromProject gets an image from a specific project. If fromFamily
+	// is true, name designates an image family instead of a particular image.
+	GetImageFromProject(project, name string, fromFamily bool) (*Image, error)
+
+	// ImageExists returns true if the specified image exists. If an error
+	// occurs calling the API, this method returns false.
+	ImageExists(project, name string) bool
+
+	// ListImages lists the images of project matching filter, in the syntax
+	// of the Compute Engine API. An empty filter lists all the images.
+	ListImages(project, filter string) ([]*compute.Image, error)
+
+	// AddImageIamMembers grants role on the image with the given name to
+	// members, keeping the existing bindings.
+	AddImageIamMembers(project, name, role string, members []string) error
+
+	// DeprecateImage sets the deprecation status of the image with the
+	// given name.
+	DeprecateImage(project, name string, status *compute.DeprecationStatus) error
+}
+
+// InstanceGroupDriver is the interface to the Compute Engine managed
+// instance groups. They are either zonal or regional: exactly one of zone
+// and region is set.
+type InstanceGroupDriver interface {
+	// GetInstanceGroupManager gets the managed instance group with the
+	// given name.
+	GetInstanceGroupManager(zone, region, name string) (*compute.InstanceGroupManager, error)
+
+	// PatchInstanceGroupManager updates the managed instance group with the
+	// given name with the fields set in igm.
+	PatchInstanceGroupManager(zone, region, name string, igm *compute.InstanceGroupManager) <-chan error
+
+	// WaitForInstanceGroupManagerStable waits for the managed instance group
+	// with the given name to be stable, i.e. done updating its instances.
+	WaitForInstanceGroupManagerStable(zone, region, name string) <-chan error
+}
+
+// InstanceTemplateDriver is the interface to the Compute Engine instance
+// templates.
+type InstanceTemplateDriver interface {
+	// CreateInstanceTemplate creates an instance template from the given
+	// spec.
+	CreateInstanceTemplate(project string, spec *compute.InstanceTemplate) (<-chan *compute.InstanceTemplate, <-chan error)
+
+	// DeleteInstanceTemplate deletes the instance template with the given
+	// name.
+	DeleteInstanceTemplate(project, name string) <-chan error
+
+	// GetInstanceTemplate gets the instance template with the given name.
+	GetInstanceTemplate(project, name string) (*compute.InstanceTemplate, error)
+}
+
+// OSLoginDriver is the interface to OS Login, and to the identity of the
+// account used by Packer.
+type OSLoginDriver interface {
+	// GetTokenInfo gets the information about the token used for authentication
+	GetTokenInfo() (*oauth2_svc.Tokeninfo, error)
+
 	// ImportOSLoginSSHKey imports SSH public key for OSLogin.
 	ImportOSLoginSSHKey(user, sshPublicKey string) (*oslogin.LoginProfile, error)
 
 	// DeleteOSLoginSSHKey deletes the SSH public key for OSLogin with the given key.
 	DeleteOSLoginSSHKey(user, fingerprint string) error
+}
 
-	// Add to the instance metadata for the existing instance
-	AddToInstanceMetadata(zone string, name string, metadata map[string]string) error
+// PubSubDriver is the interface to Pub/Sub.
+type PubSubDriver interface {
+	// PublishMessage publishes a message to topic, as
+	// projects/<project>/topics/<topic>, and returns its ID.
+	PublishMessage(topic string, data []byte, attributes map[string]string) (string, error)
+}
+
+// ScanningDriver is the interface to the OS inventory of the instances, and
+// to the On-Demand Scanning of their packages for vulnerabilities.
+type ParameterManagerDriver interface {
+	// GetParameter gets the parameter name, of the form
+	// projects/*/locations/*/parameters/*.
+	GetParameter(name string) (*Parameter, error)
+
+	// ListParameterVersions lists the versions of parameter.
+	ListParameterVersions(parameter string) ([]*ParameterVersion, error)
+
+	// RenderParameterVersion gets the payload of the parameter version name,
+	// with its references to secrets replaced by their values.
+	RenderParameterVersion(name string) ([]byte, error)
+}
 
-	// UploadToBucket uploads an artifact to a bucket on GCS.
-	UploadToBucket(bucket, objectName string, data io.Reader) (string, error)
+type SecretManagerDriver interface {
+	// AccessSecretVersion gets the payload of the secret version name, of the
+	// form projects/*/secrets/*/versions/*, or
+	// projects/*/locations/*/secrets/*/versions/* for regional secrets, and
+	// the name of the version accessed, resolving aliases like latest.
+	AccessSecretVersion(name string) ([]byte, string, error)
+}
+
+type ScanningDriver interface {
+	// GetInstanceInventory gets the OS inventory, with the installed
+	// packages, reported by the OS Config agent of the instance.
+	GetInstanceInventory(zone, name string) (*osconfig.Inventory, error)
+
+	// ScanPackages scans packages for vulnerabilities in location, e.g. us,
+	// and returns the vulnerabilities found. resourceURI identifies what the
+	// packages come from.
+	ScanPackages(location, resourceURI string, packages []*ondemandscanning.PackageData) ([]*ondemandscanning.Occurrence, error)
+}
+
+// UploadOptions tune the resumable uploads to Cloud Storage.
+type UploadOptions struct {
+	// ChunkSize is the size of the uploaded chunks, in bytes. It defaults
+	// to googleapi.DefaultUploadChunkSize.
+	ChunkSize int
+	// ChunkRetryDeadline is how long a failed chunk is retried before
+	// giving up on the upload.
+	ChunkRetryDeadline time.Duration
+	// ContentType is the content type of the uploaded object. It is
+	// detected from the data by default.
+	ContentType string
+	// Metadata is the custom metadata of the uploaded object.
+	Metadata map[string]string
+	// CustomTime is the Custom-Time of the uploaded object, which bucket
+	// lifecycle rules can act on with daysSinceCustomTime.
+	CustomTime time.Time
+	// Parallelism is the number of parts uploaded concurrently, then
+	// composed into the object, when the data is an io.ReaderAt of Size
+	// bytes. Each part holds a chunk in memory.
+	Parallelism int
+	// Size is the size of the data, in bytes.
+	Size int64
+}
+
+// StorageDriver is the interface to Cloud Storage.
+type StorageDriver interface {
+	// TestBucketPermissions returns the subset of permissions that the
+	// account used by Packer has on the bucket.
+	TestBucketPermissions(bucket string, permissions []string) ([]string, error)
+
+	// UploadToBucket uploads an artifact to a bucket on GCS, with a
+	// resumable upload.
+	UploadToBucket(bucket, objectName string, data io.Reader, opts UploadOptions) (string, error)
 
 	// DeleteFromBucket deletes an object from a bucket on GCS.
 	DeleteFromBucket(bucket, objectName string) error
+
+	// GetBucketObject returns the metadata of an object in a bucket on GCS.
+	GetBucketObject(bucket, objectName string) (*storage.Object, error)
+
+	// DownloadFromBucket returns the content of an object in a bucket on
+	// GCS, failing if it is larger than maxSize bytes.
+	DownloadFromBucket(bucket, objectName string, maxSize int64) ([]byte, error)
+
+	// SignURL returns a signed URL to download an object in a bucket on GCS
+	// for expires, signed by serviceAccount, or by default by the service
+	// account of the credentials.
+	SignURL(bucket, objectName string, expires time.Duration, serviceAccount string) (string, error)
+}
+
+// TunnelDriver starts and stops the tunnel used to connect to an instance,
+// e.g. through Identity-Aware Proxy.
+type TunnelDriver interface {
+	// StartTunnel forwards the connections to localPort to the instance,
+	// failing if the tunnel does not connect within timeout. A zero timeout
+	// skips the check, e.g. when nothing listens on the instance yet.
+	StartTunnel(ctx context.Context, localPort int, timeout time.Duration) error
+	StopTunnel()
+}
+
+// IAPDriver is the interface to Identity-Aware Proxy TCP forwarding.
+type IAPDriver interface {
+	// NewIAPTunnel returns a tunnel to target through IAP, authenticated as
+	// impersonateServiceAccount when set, or else with the credentials of
+	// the driver.
+	NewIAPTunnel(target IAPTunnelTarget, impersonateServiceAccount string) (TunnelDriver, error)
 }
 
 // WindowsPasswordConfig is the data structure that GCE needs to encrypt the created
@@ -12,17 +12,31 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"log"
+	"net/http"
+	"path"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	cloudkms "google.golang.org/api/cloudkms/v1"
+	cloudresourcemanager "google.golang.org/api/cloudresourcemanager/v1"
 	compute "google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	iam "google.golang.org/api/iam/v1"
 	impersonate "google.golang.org/api/impersonate"
 	oauth2_svc "google.golang.org/api/oauth2/v2"
+	ondemandscanning "google.golang.org/api/ondemandscanning/v1"
 	"google.golang.org/api/option"
+	osconfig "google.golang.org/api/osconfig/v1"
 	oslogin "google.golang.org/api/oslogin/v1"
+	"google.golang.org/api/pubsub/v1"
+	secretmanager "google.golang.org/api/secretmanager/v1"
 	"google.golang.org/api/storage/v1"
+	htransport "google.golang.org/api/transport/http"
 
 	"github.com/hashicorp/packer-plugin-googlecompute/version"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
@@ -37,24 +51,74 @@ import (
 // driverGCE is a Driver implementation that actually talks to GCE.
 // Create an instance using NewDriverGCE.
 type driverGCE struct {
-	projectId      string
-	service        *compute.Service
-	osLoginService *oslogin.Service
-	oauth2Service  *oauth2_svc.Service
-	storageService *storage.Service
-	ui             packersdk.Ui
+	projectId       string
+	service         *compute.Service
+	osLoginService  *oslogin.Service
+	oauth2Service   *oauth2_svc.Service
+	storageService  *storage.Service
+	crmService      *cloudresourcemanager.Service
+	iamService      *iam.Service
+	kmsService      *cloudkms.Service
+	pubsubService   *pubsub.Service
+	osConfigService *osconfig.Service
+	scanningService *ondemandscanning.Service
+	secretService   *secretmanager.Service
+	urlSigner       *urlSigner
+	// clientOpts are the options the services are created with, to create
+	// the ones of regional endpoints on demand.
+	clientOpts []option.ClientOption
+	// config is the configuration the driver was created with, to
+	// authenticate the clients which are not API clients, like IAP tunnels.
+	config GCEDriverConfig
+	ui     packersdk.Ui
+
+	pollMinInterval time.Duration
+	pollMaxInterval time.Duration
+
+	// operations are the operations waited for, for the build reports.
+	operations operationLog
 }
 
 type GCEDriverConfig struct {
 	Ui                            packersdk.Ui
 	ProjectId                     string
 	ImpersonateServiceAccountName string
-	Scopes                        []string
-	AccessToken                   string
-	VaultOauthEngineName          string
-	Credentials                   *google.Credentials
+	// ImpersonateServiceAccountDelegates is the delegation chain to go
+	// through to impersonate ImpersonateServiceAccountName.
+	ImpersonateServiceAccountDelegates []string
+	Scopes                             []string
+	AccessToken                        string
+	VaultOauthEngineName               string
+	Credentials                        *google.Credentials
+	// PollMinInterval and PollMaxInterval bound the interval between two
+	// polls of a long-running operation. They default to
+	// DefaultPollMinInterval and DefaultPollMaxInterval.
+	PollMinInterval time.Duration
+	PollMaxInterval time.Duration
+	// BillingProject is sent as the X-Goog-User-Project header, to bill the
+	// API calls to, and use the quota of, that project.
+	BillingProject string
+	// ExtraHeaders are sent along with every API call.
+	ExtraHeaders http.Header
+	// GoogleAccess, when set to GoogleAccessPrivate or
+	// GoogleAccessRestricted, sends the API calls to the matching VIP.
+	GoogleAccess string
+	// ProxyURL is the proxy the API calls go through, in place of the one
+	// from the HTTPS_PROXY environment variable.
+	ProxyURL string
+	// AuthScopes are the scopes requested when using the Application
+	// Default Credentials. They default to DriverScopes and
+	// CloudPlatformScope.
+	AuthScopes []string
+	// Storage, when set, holds the authentication settings of the Cloud
+	// Storage client, e.g. when the bucket lives in another organization.
+	// Only its authentication settings are used.
+	Storage *GCEDriverConfig
 }
 
+// CloudPlatformScope grants access to all the Google Cloud APIs.
+const CloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
 var DriverScopes = []string{
 	"https://www.googleapis.com/auth/compute",
 	"https://www.googleapis.com/auth/devstorage.full_control",
@@ -94,47 +158,129 @@ func (ots OauthTokenSource) Token() (*oauth2.Token, error) {
 
 }
 
+// ClientOptionGoogleConfig holds the authentication settings of the API
+// clients, in order of precedence.
+type ClientOptionGoogleConfig struct {
+	// VaultOauthEngineName is the path of the Vault OAuth engine issuing the
+	// access tokens.
+	VaultOauthEngineName string
+	// ImpersonateServiceAccountName is the service account impersonated
+	// with the credentials below, or the Application Default Credentials.
+	ImpersonateServiceAccountName string
+	// ImpersonateServiceAccountDelegates are the service accounts of the
+	// delegation chain to ImpersonateServiceAccountName.
+	ImpersonateServiceAccountDelegates []string
+	// AccessToken is a static access token.
+	AccessToken string
+	// Credentials are the credentials of an account file.
+	Credentials *google.Credentials
+	// Scopes are the scopes of the impersonated service account.
+	Scopes []string
+	// AuthScopes are the scopes requested when using the Application
+	// Default Credentials. They default to DriverScopes and
+	// CloudPlatformScope.
+	AuthScopes []string
+	// HTTPClient, when set, sends the OAuth2 token requests, e.g. through a
+	// proxy.
+	HTTPClient *http.Client
+}
+
+// NewClientOptionGoogle returns the options authenticating the API clients.
+//
+// Deprecated: use NewClientOptionGoogleWithConfig, which supports all the
+// authentication settings.
 func NewClientOptionGoogle(vaultOauth string, impersonatesa string, accessToken string, credentials *google.Credentials, scopes []string) ([]option.ClientOption, error) {
+	return NewClientOptionGoogleWithConfig(ClientOptionGoogleConfig{
+		VaultOauthEngineName:          vaultOauth,
+		ImpersonateServiceAccountName: impersonatesa,
+		AccessToken:                   accessToken,
+		Credentials:                   credentials,
+		Scopes:                        scopes,
+	})
+}
+
+// NewClientOptionGoogleWithConfig returns the options authenticating the API
+// clients with c.
+func NewClientOptionGoogleWithConfig(c ClientOptionGoogleConfig) ([]option.ClientOption, error) {
+	ctx := context.Background()
+	if c.HTTPClient != nil {
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, c.HTTPClient)
+	}
 	var err error
 
 	var opts []option.ClientOption
 
-	if vaultOauth != "" {
+	if c.VaultOauthEngineName != "" {
 		// Auth with Vault Oauth
 		log.Printf("Using Vault to generate Oauth token.")
-		ts := OauthTokenSource{vaultOauth}
+		ts := newRefreshingTokenSource(OauthTokenSource{c.VaultOauthEngineName})
 		opts = append(opts, option.WithTokenSource(ts))
 
-	} else if impersonatesa != "" {
+	} else if c.ImpersonateServiceAccountName != "" {
 		log.Printf("[INFO] Using Google Cloud impersonation mechanism")
-		ts, err := impersonate.CredentialsTokenSource(context.Background(), impersonate.CredentialsConfig{
-			TargetPrincipal: impersonatesa,
-			Scopes:          scopes,
-		})
-		if err != nil {
+		scopes := c.Scopes
+		if len(scopes) == 0 {
+			scopes = append([]string{CloudPlatformScope}, DriverScopes...)
+		}
+		// The caller's identity, used to request tokens for the impersonated
+		// account. Defaults to the Application Default Credentials.
+		var callerOpts []option.ClientOption
+		if c.AccessToken != "" {
+			callerOpts = append(callerOpts, option.WithTokenSource(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: c.AccessToken})))
+		} else if c.Credentials != nil {
+			callerOpts = append(callerOpts, option.WithCredentials(c.Credentials))
+		}
+		// The IAM Credentials API is called with its own client, that only
+		// goes through the same transport as the other calls when told to.
+		if client, ok := ctx.Value(oauth2.HTTPClient).(*http.Client); ok {
+			trans, err := htransport.NewTransport(ctx, client.Transport, callerOpts...)
+			if err != nil {
+				return nil, err
+			}
+			callerOpts = []option.ClientOption{option.WithHTTPClient(&http.Client{Transport: trans})}
+		}
+		newSource := func() (oauth2.TokenSource, error) {
+			return impersonate.CredentialsTokenSource(ctx, impersonate.CredentialsConfig{
+				TargetPrincipal: c.ImpersonateServiceAccountName,
+				Delegates:       c.ImpersonateServiceAccountDelegates,
+				Scopes:          scopes,
+			}, callerOpts...)
+		}
+		// The configuration is checked before the first token is needed.
+		if _, err := newSource(); err != nil {
 			return nil, err
 		}
-		opts = append(opts, option.WithTokenSource(ts))
-	} else if accessToken != "" {
+		opts = append(opts, option.WithTokenSource(newRefreshingTokenSource(uncachedTokenSource(newSource))))
+	} else if c.AccessToken != "" {
 		// Auth with static access token
 		log.Printf("[INFO] Using static Google Access Token")
-		token := &oauth2.Token{AccessToken: accessToken}
+		token := &oauth2.Token{AccessToken: c.AccessToken}
 		ts := oauth2.StaticTokenSource(token)
 		opts = append(opts, option.WithTokenSource(ts))
-	} else if credentials != nil {
+	} else if c.Credentials != nil {
 		// Auth with Credentials if provided
 		log.Printf("[INFO] Requesting Google token via credentials...")
 		log.Printf("[INFO]   -- Scopes: %s", DriverScopes)
 
-		opts = append(opts, option.WithCredentials(credentials))
+		opts = append(opts, option.WithCredentials(&google.Credentials{
+			ProjectID:   c.Credentials.ProjectID,
+			TokenSource: newRefreshingTokenSource(c.Credentials.TokenSource),
+			JSON:        c.Credentials.JSON,
+		}))
 	} else {
 		log.Printf("[INFO] Requesting Google token via GCE API Default Client Token Source...")
-		scopes := append(DriverScopes, "https://www.googleapis.com/auth/cloud-platform")
-		ts, err := google.DefaultTokenSource(context.TODO(), scopes...)
-		if err != nil {
+		scopes := c.AuthScopes
+		if len(scopes) == 0 {
+			scopes = append(DriverScopes, CloudPlatformScope)
+		}
+		log.Printf("[INFO]   -- Scopes: %s", scopes)
+		newSource := func() (oauth2.TokenSource, error) {
+			return google.DefaultTokenSource(ctx, scopes...)
+		}
+		if _, err := newSource(); err != nil {
 			return nil, err
 		}
-		opts = append(opts, option.WithTokenSource(ts))
+		opts = append(opts, option.WithTokenSource(newRefreshingTokenSource(uncachedTokenSource(newSource))))
 		// The DefaultClient uses the DefaultTokenSource of the google lib.
 		// The DefaultTokenSource uses the "Application Default Credentials"
 		// It looks for credentials in the following places, preferring the first location found:
@@ -159,13 +305,75 @@ func NewClientOptionGoogle(vaultOauth string, impersonatesa string, accessToken
 	return opts, nil
 }
 
+// clientOptionGoogleConfig returns the authentication settings of config.
+func (config GCEDriverConfig) clientOptionGoogleConfig() ClientOptionGoogleConfig {
+	return ClientOptionGoogleConfig{
+		VaultOauthEngineName:               config.VaultOauthEngineName,
+		ImpersonateServiceAccountName:      config.ImpersonateServiceAccountName,
+		ImpersonateServiceAccountDelegates: config.ImpersonateServiceAccountDelegates,
+		AccessToken:                        config.AccessToken,
+		Credentials:                        config.Credentials,
+		Scopes:                             config.Scopes,
+		AuthScopes:                         config.AuthScopes,
+	}
+}
+
+// driverClientOptions returns the options of the API clients for config.
+func driverClientOptions(config GCEDriverConfig) ([]option.ClientOption, error) {
+	authConfig := config.clientOptionGoogleConfig()
+	authConfig.HTTPClient = transportClient(config.ProxyURL, config.GoogleAccess)
+	opts, err := NewClientOptionGoogleWithConfig(authConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.BillingProject != "" {
+		opts = append(opts, option.WithQuotaProject(config.BillingProject))
+	}
+
+	if config.ProxyURL != "" || config.GoogleAccess != "" || len(config.ExtraHeaders) > 0 {
+		if config.ProxyURL != "" {
+			log.Printf("[INFO] Using proxy %s", config.ProxyURL)
+		}
+		if config.GoogleAccess != "" {
+			log.Printf("[INFO] Using the %s.googleapis.com VIP", config.GoogleAccess)
+		}
+		opts, err = withTransport(opts, newTransport(config.ProxyURL, config.GoogleAccess), config.ExtraHeaders)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return opts, nil
+}
+
 func NewDriverGCE(config GCEDriverConfig) (Driver, error) {
 
-	opts, err := NewClientOptionGoogle(config.VaultOauthEngineName, config.ImpersonateServiceAccountName, config.AccessToken, config.Credentials, config.Scopes)
+	opts, err := driverClientOptions(config)
 	if err != nil {
 		return nil, err
 	}
 
+	storageOpts := opts
+	storageConfig := config
+	if config.Storage != nil {
+		log.Printf("[INFO] Using separate credentials for Cloud Storage")
+		storageConfig = *config.Storage
+		if storageConfig.ExtraHeaders == nil {
+			storageConfig.ExtraHeaders = config.ExtraHeaders
+		}
+		if storageConfig.GoogleAccess == "" {
+			storageConfig.GoogleAccess = config.GoogleAccess
+		}
+		if storageConfig.ProxyURL == "" {
+			storageConfig.ProxyURL = config.ProxyURL
+		}
+		storageOpts, err = driverClientOptions(storageConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	log.Printf("[INFO] Instantiating GCE client...")
 	service, err := compute.NewService(context.TODO(), opts...)
 	if err != nil {
@@ -185,30 +393,107 @@ func NewDriverGCE(config GCEDriverConfig) (Driver, error) {
 	}
 
 	log.Printf("[INFO] Instantiating storage client...")
-	storageService, err := storage.NewService(context.TODO(), opts...)
+	storageService, err := storage.NewService(context.TODO(), storageOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] Instantiating IAM Credentials client...")
+	urlSigner, err := newURLSigner(storageConfig, storageOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] Instantiating Resource Manager client...")
+	crmService, err := cloudresourcemanager.NewService(context.TODO(), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] Instantiating IAM client...")
+	iamService, err := iam.NewService(context.TODO(), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] Instantiating Cloud KMS client...")
+	kmsService, err := cloudkms.NewService(context.TODO(), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] Instantiating Pub/Sub client...")
+	pubsubService, err := pubsub.NewService(context.TODO(), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] Instantiating OS Config client...")
+	osConfigService, err := osconfig.NewService(context.TODO(), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] Instantiating On-Demand Scanning client...")
+	scanningService, err := ondemandscanning.NewService(context.TODO(), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] Instantiating Secret Manager client...")
+	secretService, err := secretmanager.NewService(context.TODO(), opts...)
 	if err != nil {
 		return nil, err
 	}
 
+	if config.PollMinInterval == 0 {
+		config.PollMinInterval = DefaultPollMinInterval
+	}
+	if config.PollMaxInterval == 0 {
+		config.PollMaxInterval = DefaultPollMaxInterval
+	}
+
 	return &driverGCE{
-		projectId:      config.ProjectId,
-		service:        service,
-		osLoginService: osLoginService,
-		oauth2Service:  oauth2Service,
-		storageService: storageService,
-		ui:             config.Ui,
+		projectId:       config.ProjectId,
+		service:         service,
+		osLoginService:  osLoginService,
+		oauth2Service:   oauth2Service,
+		storageService:  storageService,
+		crmService:      crmService,
+		iamService:      iamService,
+		kmsService:      kmsService,
+		pubsubService:   pubsubService,
+		osConfigService: osConfigService,
+		scanningService: scanningService,
+		secretService:   secretService,
+		urlSigner:       urlSigner,
+		clientOpts:      opts,
+		config:          config,
+		ui:              config.Ui,
+		pollMinInterval: config.PollMinInterval,
+		pollMaxInterval: config.PollMaxInterval,
 	}, nil
 }
 
 func (d *driverGCE) CreateImage(project string, imageSpec *compute.Image) (<-chan *Image, <-chan error) {
+	return d.createImage(project, imageSpec, false)
+}
+
+func (d *driverGCE) ForceCreateImage(project string, imageSpec *compute.Image) (<-chan *Image, <-chan error) {
+	return d.createImage(project, imageSpec, true)
+}
+
+func (d *driverGCE) createImage(project string, imageSpec *compute.Image, force bool) (<-chan *Image, <-chan error) {
 	imageCh := make(chan *Image, 1)
 	errCh := make(chan error, 1)
-	op, err := d.service.Images.Insert(project, imageSpec).Do()
+	op, err := doOperation(func(requestId string) (*compute.Operation, error) {
+		return d.service.Images.Insert(project, imageSpec).ForceCreate(force).RequestId(requestId).Do()
+	})
 	if err != nil {
 		errCh <- err
 	} else {
 		go func() {
-			err = waitForState(errCh, "DONE", d.refreshGlobalOp(project, op))
+			err = d.waitForState(errCh, "DONE", d.refreshGlobalOp(project, op))
 			if err != nil {
 				close(imageCh)
 				errCh <- err
@@ -231,12 +516,14 @@ func (d *driverGCE) CreateImage(project string, imageSpec *compute.Image) (<-cha
 
 func (d *driverGCE) DeleteImage(project, name string) <-chan error {
 	errCh := make(chan error, 1)
-	op, err := d.service.Images.Delete(project, name).Do()
+	op, err := doOperation(func(requestId string) (*compute.Operation, error) {
+		return d.service.Images.Delete(project, name).RequestId(requestId).Do()
+	})
 	if err != nil {
 		errCh <- err
 	} else {
 		go func() {
-			_ = waitForState(errCh, "DONE", d.refreshGlobalOp(project, op))
+			_ = d.waitForState(errCh, "DONE", d.refreshGlobalOp(project, op))
 		}()
 
 	}
@@ -244,15 +531,168 @@ func (d *driverGCE) DeleteImage(project, name string) <-chan error {
 	return errCh
 }
 
+func (d *driverGCE) DeprecateImage(project, name string, status *compute.DeprecationStatus) error {
+	op, err := doOperation(func(requestId string) (*compute.Operation, error) {
+		return d.service.Images.Deprecate(project, name, status).RequestId(requestId).Do()
+	})
+	if err != nil {
+		return err
+	}
+	return d.waitForOperation(d.refreshGlobalOp(project, op))
+}
+
+func (d *driverGCE) AddImageIamMembers(project, name, role string, members []string) error {
+	policy, err := d.service.Images.GetIamPolicy(project, name).Do()
+	if err != nil {
+		return err
+	}
+
+	var binding *compute.Binding
+	for _, b := range policy.Bindings {
+		if b.Role == role && b.Condition == nil {
+			binding = b
+			break
+		}
+	}
+	if binding == nil {
+		binding = &compute.Binding{Role: role}
+		policy.Bindings = append(policy.Bindings, binding)
+	}
+	for _, member := range members {
+		found := false
+		for _, m := range binding.Members {
+			found = found || m == member
+		}
+		if !found {
+			binding.Members = append(binding.Members, member)
+		}
+	}
+
+	// The etag of policy makes the update fail if it changed meanwhile.
+	_, err = d.service.Images.SetIamPolicy(project, name, &compute.GlobalSetPolicyRequest{
+		Policy: policy,
+	}).Do()
+	return err
+}
+
+func (d *driverGCE) GetInstanceGroupManager(zone, region, name string) (*compute.InstanceGroupManager, error) {
+	if zone != "" {
+		return d.service.InstanceGroupManagers.Get(d.projectId, zone, name).Do()
+	}
+	return d.service.RegionInstanceGroupManagers.Get(d.projectId, region, name).Do()
+}
+
+func (d *driverGCE) PatchInstanceGroupManager(zone, region, name string, igm *compute.InstanceGroupManager) <-chan error {
+	errCh := make(chan error, 1)
+
+	var op *compute.Operation
+	var err error
+	var refresh stateRefreshFunc
+	if zone != "" {
+		op, err = d.service.InstanceGroupManagers.Patch(d.projectId, zone, name, igm).Do()
+		if err == nil {
+			refresh = d.refreshZoneOp(zone, op)
+		}
+	} else {
+		op, err = d.service.RegionInstanceGroupManagers.Patch(d.projectId, region, name, igm).Do()
+		if err == nil {
+			refresh = d.refreshRegionOp(region, op)
+		}
+	}
+	if err != nil {
+		errCh <- err
+		return errCh
+	}
+
+	go func() {
+		_ = d.waitForState(errCh, "DONE", refresh)
+	}()
+
+	return errCh
+}
+
+func (d *driverGCE) WaitForInstanceGroupManagerStable(zone, region, name string) <-chan error {
+	errCh := make(chan error, 1)
+	go func() {
+		_ = d.waitForState(errCh, "STABLE", func() (string, error) {
+			igm, err := d.GetInstanceGroupManager(zone, region, name)
+			if err != nil {
+				return "", err
+			}
+			if igm.Status != nil && igm.Status.IsStable {
+				return "STABLE", nil
+			}
+			return "UPDATING", nil
+		})
+	}()
+	return errCh
+}
+
+func (d *driverGCE) CreateInstanceTemplate(project string, spec *compute.InstanceTemplate) (<-chan *compute.InstanceTemplate, <-chan error) {
+	templateCh := make(chan *compute.InstanceTemplate, 1)
+	errCh := make(chan error, 1)
+	op, err := doOperation(func(requestId string) (*compute.Operation, error) {
+		return d.service.InstanceTemplates.Insert(project, spec).RequestId(requestId).Do()
+	})
+	if err != nil {
+		errCh <- err
+		close(templateCh)
+		return templateCh, errCh
+	}
+
+	go func() {
+		// As for machine images, only ever send actual errors on errCh.
+		waitCh := make(chan error, 2)
+		_ = d.waitForState(waitCh, "DONE", d.refreshGlobalOp(project, op))
+		err := <-waitCh
+		if err == nil {
+			var template *compute.InstanceTemplate
+			template, err = d.GetInstanceTemplate(project, spec.Name)
+			if err == nil {
+				templateCh <- template
+			}
+		}
+		if err != nil {
+			errCh <- err
+		}
+		close(templateCh)
+	}()
+
+	return templateCh, errCh
+}
+
+func (d *driverGCE) DeleteInstanceTemplate(project, name string) <-chan error {
+	errCh := make(chan error, 1)
+	op, err := doOperation(func(requestId string) (*compute.Operation, error) {
+		return d.service.InstanceTemplates.Delete(project, name).RequestId(requestId).Do()
+	})
+	if err != nil {
+		errCh <- err
+		return errCh
+	}
+
+	go func() {
+		_ = d.waitForState(errCh, "DONE", d.refreshGlobalOp(project, op))
+	}()
+
+	return errCh
+}
+
+func (d *driverGCE) GetInstanceTemplate(project, name string) (*compute.InstanceTemplate, error) {
+	return d.service.InstanceTemplates.Get(project, name).Do()
+}
+
 func (d *driverGCE) DeleteInstance(zone, name string) (<-chan error, error) {
-	op, err := d.service.Instances.Delete(d.projectId, zone, name).Do()
+	op, err := doOperation(func(requestId string) (*compute.Operation, error) {
+		return d.service.Instances.Delete(d.projectId, zone, name).RequestId(requestId).Do()
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	errCh := make(chan error, 1)
 	go func() {
-		_ = waitForState(errCh, "DONE", d.refreshZoneOp(zone, op))
+		_ = d.waitForState(errCh, "DONE", d.refreshZoneOp(zone, op))
 	}()
 	return errCh, nil
 }
@@ -278,7 +718,9 @@ func (d *driverGCE) createRegionalDisk(diskConfig BlockDevice) (<-chan *compute.
 	}
 
 	region, _ := GetRegionFromZone(diskConfig.Zone)
-	op, err := d.service.RegionDisks.Insert(d.projectId, region, computePayload).Do()
+	op, err := doOperation(func(requestId string) (*compute.Operation, error) {
+		return d.service.RegionDisks.Insert(d.projectId, region, computePayload).RequestId(requestId).Do()
+	})
 	if err != nil {
 		errChan <- err
 		close(diskChan)
@@ -292,7 +734,7 @@ func (d *driverGCE) createRegionalDisk(diskConfig BlockDevice) (<-chan *compute.
 			close(diskChan)
 		}()
 
-		err := waitForState(errChan, "DONE", d.refreshRegionOp(region, op))
+		err := d.waitForState(errChan, "DONE", d.refreshRegionOp(region, op))
 		if err != nil {
 			errChan <- err
 			return
@@ -325,7 +767,9 @@ func (d *driverGCE) createZonalDisk(diskConfig BlockDevice) (<-chan *compute.Dis
 		return diskChan, errChan
 	}
 
-	op, err = d.service.Disks.Insert(d.projectId, zone, computePayload).Do()
+	op, err = doOperation(func(requestId string) (*compute.Operation, error) {
+		return d.service.Disks.Insert(d.projectId, zone, computePayload).RequestId(requestId).Do()
+	})
 	if err != nil {
 		errChan <- err
 		close(diskChan)
@@ -339,7 +783,7 @@ func (d *driverGCE) createZonalDisk(diskConfig BlockDevice) (<-chan *compute.Dis
 			close(diskChan)
 		}()
 
-		err := waitForState(errChan, "DONE", d.refreshZoneOp(zone, op))
+		err := d.waitForState(errChan, "DONE", d.refreshZoneOp(zone, op))
 		if err != nil {
 			errChan <- err
 			return
@@ -366,7 +810,9 @@ func (d *driverGCE) DeleteDisk(zoneOrRegion, name string) <-chan error {
 func (d *driverGCE) deleteZonalDisk(zone, name string) <-chan error {
 	errCh := make(chan error, 1)
 
-	op, err := d.service.Disks.Delete(d.projectId, zone, name).Do()
+	op, err := doOperation(func(requestId string) (*compute.Operation, error) {
+		return d.service.Disks.Delete(d.projectId, zone, name).RequestId(requestId).Do()
+	})
 	if err != nil {
 		errCh <- err
 		close(errCh)
@@ -374,7 +820,7 @@ func (d *driverGCE) deleteZonalDisk(zone, name string) <-chan error {
 	}
 
 	go func() {
-		_ = waitForState(errCh, "DONE", d.refreshZoneOp(zone, op))
+		_ = d.waitForState(errCh, "DONE", d.refreshZoneOp(zone, op))
 		close(errCh)
 	}()
 	return errCh
@@ -383,7 +829,9 @@ func (d *driverGCE) deleteZonalDisk(zone, name string) <-chan error {
 func (d *driverGCE) deleteRegionalDisk(region, name string) <-chan error {
 	errCh := make(chan error, 1)
 
-	op, err := d.service.RegionDisks.Delete(d.projectId, region, name).Do()
+	op, err := doOperation(func(requestId string) (*compute.Operation, error) {
+		return d.service.RegionDisks.Delete(d.projectId, region, name).RequestId(requestId).Do()
+	})
 	if err != nil {
 		errCh <- err
 		close(errCh)
@@ -391,7 +839,7 @@ func (d *driverGCE) deleteRegionalDisk(region, name string) <-chan error {
 	}
 
 	go func() {
-		_ = waitForState(errCh, "DONE", d.refreshRegionOp(region, op))
+		_ = d.waitForState(errCh, "DONE", d.refreshRegionOp(region, op))
 		close(errCh)
 	}()
 	return errCh
@@ -472,17 +920,45 @@ func (d *driverGCE) GetImageFromProject(project, name string, fromFamily bool) (
 	} else if image == nil || image.SelfLink == "" {
 		return nil, fmt.Errorf("Image, %s, could not be found in project: %s", name, project)
 	} else {
+		var keySha256 string
+		if image.ImageEncryptionKey != nil {
+			keySha256 = image.ImageEncryptionKey.Sha256
+		}
 		return &Image{
-			GuestOsFeatures: image.GuestOsFeatures,
-			Licenses:        image.Licenses,
-			Name:            image.Name,
-			ProjectId:       project,
-			SelfLink:        image.SelfLink,
-			SizeGb:          image.DiskSizeGb,
+			Deprecation:         image.Deprecated,
+			EncryptionKeySha256: keySha256,
+			Family:              image.Family,
+			GuestOsFeatures:     image.GuestOsFeatures,
+			Id:                  image.Id,
+			Labels:              image.Labels,
+			Licenses:            image.Licenses,
+			Name:                image.Name,
+			ProjectId:           project,
+			SelfLink:            image.SelfLink,
+			SizeGb:              image.DiskSizeGb,
+			Status:              image.Status,
+			StorageLocations:    image.StorageLocations,
 		}, nil
 	}
 }
 
+func (d *driverGCE) ListImages(project, filter string) ([]*compute.Image, error) {
+	var images []*compute.Image
+	call := d.service.Images.List(project)
+	if filter != "" {
+		call = call.Filter(filter)
+	}
+	err := call.Pages(context.TODO(), func(page *compute.ImageList) error {
+		images = append(images, page.Items...)
+		return nil
+	})
+	return images, err
+}
+
+func (d *driverGCE) GetInstance(zone, name string) (*compute.Instance, error) {
+	return d.service.Instances.Get(d.projectId, zone, name).Do()
+}
+
 func (d *driverGCE) GetInstanceMetadata(zone, name, key string) (string, error) {
 	instance, err := d.service.Instances.Get(d.projectId, zone, name).Do()
 	if err != nil {
@@ -498,6 +974,34 @@ func (d *driverGCE) GetInstanceMetadata(zone, name, key string) (string, error)
 	return "", fmt.Errorf("Instance metadata key, %s, not found.", key)
 }
 
+func (d *driverGCE) GetInstanceStatus(zone, name string) (string, error) {
+	instance, err := d.service.Instances.Get(d.projectId, zone, name).Do()
+	if err != nil {
+		return "", err
+	}
+
+	return instance.Status, nil
+}
+
+func (d *driverGCE) InstancePreemptedSince(zone, name string, since time.Time) (bool, error) {
+	var preempted bool
+	err := d.service.ZoneOperations.List(d.projectId, zone).
+		Filter(`operationType="compute.instances.preempted"`).
+		Pages(context.TODO(), func(page *compute.OperationList) error {
+			for _, op := range page.Items {
+				if path.Base(op.TargetLink) != name {
+					continue
+				}
+				inserted, err := time.Parse(time.RFC3339, op.InsertTime)
+				if err == nil && !inserted.Before(since) {
+					preempted = true
+				}
+			}
+			return nil
+		})
+	return preempted, err
+}
+
 func (d *driverGCE) GetNatIP(zone, name string) (string, error) {
 	instance, err := d.service.Instances.Get(d.projectId, zone, name).Do()
 	if err != nil {
@@ -518,6 +1022,170 @@ func (d *driverGCE) GetNatIP(zone, name string) (string, error) {
 	return "", nil
 }
 
+func (d *driverGCE) GetMachineType(zone, name string) (*compute.MachineType, error) {
+	return d.service.MachineTypes.Get(d.projectId, zone, name).Do()
+}
+
+func (d *driverGCE) ListZones() ([]*compute.Zone, error) {
+	var zones []*compute.Zone
+	err := d.service.Zones.List(d.projectId).Pages(context.TODO(), func(page *compute.ZoneList) error {
+		zones = append(zones, page.Items...)
+		return nil
+	})
+	return zones, err
+}
+
+func (d *driverGCE) ListRegions() ([]*compute.Region, error) {
+	var regions []*compute.Region
+	err := d.service.Regions.List(d.projectId).Pages(context.TODO(), func(page *compute.RegionList) error {
+		regions = append(regions, page.Items...)
+		return nil
+	})
+	return regions, err
+}
+
+func (d *driverGCE) MachineTypeZones(name string) ([]string, error) {
+	var zones []string
+	call := d.service.MachineTypes.AggregatedList(d.projectId).Filter(fmt.Sprintf("name = %q", name))
+	err := call.Pages(context.TODO(), func(page *compute.MachineTypeAggregatedList) error {
+		for _, scoped := range page.Items {
+			for _, mt := range scoped.MachineTypes {
+				zones = append(zones, path.Base(mt.Zone))
+			}
+		}
+		return nil
+	})
+	return zones, err
+}
+
+func (d *driverGCE) AcceleratorTypeZones(name string) ([]string, error) {
+	var zones []string
+	call := d.service.AcceleratorTypes.AggregatedList(d.projectId).Filter(fmt.Sprintf("name = %q", name))
+	err := call.Pages(context.TODO(), func(page *compute.AcceleratorTypeAggregatedList) error {
+		for _, scoped := range page.Items {
+			for _, at := range scoped.AcceleratorTypes {
+				zones = append(zones, path.Base(at.Zone))
+			}
+		}
+		return nil
+	})
+	return zones, err
+}
+
+func (d *driverGCE) GetSubnetwork(project, region, name string) (*compute.Subnetwork, error) {
+	return d.service.Subnetworks.Get(project, region, name).Do()
+}
+
+func (d *driverGCE) GetDefaultServiceAccount(project string) (string, error) {
+	p, err := d.service.Projects.Get(project).Do()
+	if err != nil {
+		return "", err
+	}
+	return p.DefaultServiceAccount, nil
+}
+
+func (d *driverGCE) GetProjectMetadata(project string) (map[string]string, error) {
+	p, err := d.service.Projects.Get(project).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := map[string]string{}
+	if p.CommonInstanceMetadata == nil {
+		return metadata, nil
+	}
+	for _, item := range p.CommonInstanceMetadata.Items {
+		if item.Value != nil {
+			metadata[item.Key] = *item.Value
+		} else {
+			metadata[item.Key] = ""
+		}
+	}
+	return metadata, nil
+}
+
+func (d *driverGCE) GetNetwork(project, name string) (*compute.Network, error) {
+	return d.service.Networks.Get(project, name).Do()
+}
+
+func (d *driverGCE) ListFirewalls(project string) ([]*compute.Firewall, error) {
+	var firewalls []*compute.Firewall
+	err := d.service.Firewalls.List(project).Pages(context.TODO(), func(page *compute.FirewallList) error {
+		firewalls = append(firewalls, page.Items...)
+		return nil
+	})
+	return firewalls, err
+}
+
+func (d *driverGCE) GetSharedVpcHost(project string) (string, error) {
+	host, err := d.service.Projects.GetXpnHost(project).Do()
+	if err != nil {
+		return "", err
+	}
+	return host.Name, nil
+}
+
+func (d *driverGCE) ListSnapshots(project, filter string) ([]*compute.Snapshot, error) {
+	var snapshots []*compute.Snapshot
+	call := d.service.Snapshots.List(project)
+	if filter != "" {
+		call = call.Filter(filter)
+	}
+	err := call.Pages(context.TODO(), func(page *compute.SnapshotList) error {
+		snapshots = append(snapshots, page.Items...)
+		return nil
+	})
+	return snapshots, err
+}
+
+func (d *driverGCE) CreateSnapshot(zone, disk string, snapshot *compute.Snapshot) error {
+	if IsZoneARegion(zone) {
+		op, err := doOperation(func(requestId string) (*compute.Operation, error) {
+			return d.service.RegionDisks.CreateSnapshot(d.projectId, zone, disk, snapshot).RequestId(requestId).Do()
+		})
+		if err != nil {
+			return err
+		}
+		return d.waitForOperation(d.refreshRegionOp(zone, op))
+	}
+
+	op, err := doOperation(func(requestId string) (*compute.Operation, error) {
+		return d.service.Disks.CreateSnapshot(d.projectId, zone, disk, snapshot).RequestId(requestId).Do()
+	})
+	if err != nil {
+		return err
+	}
+	return d.waitForOperation(d.refreshZoneOp(zone, op))
+}
+
+func (d *driverGCE) DeleteSnapshot(name string) error {
+	op, err := doOperation(func(requestId string) (*compute.Operation, error) {
+		return d.service.Snapshots.Delete(d.projectId, name).RequestId(requestId).Do()
+	})
+	if err != nil {
+		return err
+	}
+	return d.waitForOperation(d.refreshGlobalOp(d.projectId, op))
+}
+
+func (d *driverGCE) GetRegionQuotas(region string) ([]*compute.Quota, error) {
+	r, err := d.service.Regions.Get(d.projectId, region).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Quotas, nil
+}
+
+func (d *driverGCE) GetProjectQuotas() ([]*compute.Quota, error) {
+	p, err := d.service.Projects.Get(d.projectId).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	return p.Quotas, nil
+}
+
 func (d *driverGCE) GetInternalIP(zone, name string) (string, error) {
 	instance, err := d.service.Instances.Get(d.projectId, zone, name).Do()
 	if err != nil {
@@ -543,6 +1211,30 @@ func (d *driverGCE) GetSerialPortOutput(zone, name string) (string, error) {
 	return output.Contents, nil
 }
 
+func (d *driverGCE) GetSerialPortOutputFrom(zone, name string, start int64) (string, int64, error) {
+	output, err := d.service.Instances.GetSerialPortOutput(d.projectId, zone, name).Start(start).Do()
+	if err != nil {
+		return "", start, err
+	}
+
+	return output.Contents, output.Next, nil
+}
+
+func (d *driverGCE) GetGuestAttributes(zone, name, queryPath string) (map[string]string, error) {
+	attributes, err := d.service.Instances.GetGuestAttributes(d.projectId, zone, name).QueryPath(queryPath).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+	if attributes.QueryValue != nil {
+		for _, item := range attributes.QueryValue.Items {
+			values[item.Key] = item.Value
+		}
+	}
+	return values, nil
+}
+
 func (d *driverGCE) ImageExists(project, name string) bool {
 	_, err := d.GetImageFromProject(project, name, false)
 	// The API may return an error for reasons other than the image not
@@ -650,11 +1342,22 @@ func (d *driverGCE) RunInstance(c *InstanceConfig) (<-chan error, error) {
 			},
 		},
 	}
+	if c.SourceSnapshot != "" {
+		computeDisks[0].InitializeParams.SourceImage = ""
+		computeDisks[0].InitializeParams.SourceSnapshot = c.SourceSnapshot
+	} else {
+		computeDisks[0].InitializeParams.SourceImageEncryptionKey = c.SourceImageEncryptionKey.ComputeType()
+	}
 
 	for _, disk := range c.ExtraBlockDevices {
 		computeDisks = append(computeDisks, disk.GenerateDiskAttachment())
 	}
 
+	labels := c.Labels
+	if c.WarmPool != "" {
+		labels = warmPoolLabels(labels, c.WarmPool, "busy")
+	}
+
 	// Create the instance information
 	instance := compute.Instance{
 		AdvancedMachineFeatures: &compute.AdvancedMachineFeatures{
@@ -663,7 +1366,7 @@ func (d *driverGCE) RunInstance(c *InstanceConfig) (<-chan error, error) {
 		Description:       c.Description,
 		Disks:             computeDisks,
 		GuestAccelerators: guestAccelerators,
-		Labels:            c.Labels,
+		Labels:            labels,
 		MachineType:       machineType.SelfLink,
 		Metadata: &compute.Metadata{
 			Items: metadata,
@@ -680,6 +1383,7 @@ func (d *driverGCE) RunInstance(c *InstanceConfig) (<-chan error, error) {
 		Scheduling: &compute.Scheduling{
 			OnHostMaintenance: c.OnHostMaintenance,
 			Preemptible:       c.Preemptible,
+			ProvisioningModel: c.ProvisioningModel,
 		},
 		ServiceAccounts: []*compute.ServiceAccount{
 			serviceAccount,
@@ -705,6 +1409,12 @@ func (d *driverGCE) RunInstance(c *InstanceConfig) (<-chan error, error) {
 		shieldedUiMessage = " Shielded VM"
 	}
 
+	// Spot instances cannot be automatically restarted by Compute Engine.
+	if c.ProvisioningModel == "SPOT" {
+		automaticRestart := false
+		instance.Scheduling.AutomaticRestart = &automaticRestart
+	}
+
 	// Node affinity configuration. For example, if you want to build on sole
 	// tenancy nodes.
 	if len(c.NodeAffinities) > 0 {
@@ -714,19 +1424,170 @@ func (d *driverGCE) RunInstance(c *InstanceConfig) (<-chan error, error) {
 		}
 	}
 
+	if c.BulkInsert != nil {
+		if reason := bulkInsertIneligibility(c); reason != "" {
+			d.ui.Message(fmt.Sprintf("Not inserting the instance with the others: %s", reason))
+		} else {
+			d.ui.Message(fmt.Sprintf("Requesting%s instance creation with the identical instances...", shieldedUiMessage))
+			return d.bulkInsertInstance(c, zone.Name, &instance), nil
+		}
+	}
+
 	d.ui.Message(fmt.Sprintf("Requesting%s instance creation...", shieldedUiMessage))
-	op, err := d.service.Instances.Insert(d.projectId, zone.Name, &instance).Do()
+	op, err := doOperation(func(requestId string) (*compute.Operation, error) {
+		return d.service.Instances.Insert(d.projectId, zone.Name, &instance).RequestId(requestId).Do()
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	errCh := make(chan error, 1)
 	go func() {
-		_ = waitForState(errCh, "DONE", d.refreshZoneOp(zone.Name, op))
+		_ = d.waitForState(errCh, "DONE", d.refreshZoneOp(zone.Name, op))
 	}()
 	return errCh, nil
 }
 
+// deleteInstanceAndDisk deletes the instance name and its boot disk, named
+// after it.
+func (d *driverGCE) deleteInstanceAndDisk(zone, name string) error {
+	errCh, err := d.DeleteInstance(zone, name)
+	if err == nil {
+		err = <-errCh
+	}
+	if err != nil {
+		return err
+	}
+	return <-d.DeleteDisk(zone, name)
+}
+
+// bulkInsertIneligibility returns why the instance cannot be inserted in
+// bulk, or "" if it can.
+func bulkInsertIneligibility(c *InstanceConfig) string {
+	if c.Address != "" {
+		return "it has a static IP"
+	}
+	if c.DiskName != c.Name {
+		return "its boot disk is not named after it"
+	}
+	for _, disk := range c.ExtraBlockDevices {
+		if disk.VolumeType != LocalScratch {
+			return "it has persistent disk attachments"
+		}
+	}
+	return ""
+}
+
+// bulkInsertInstance inserts the instance along with the identical
+// instances of the other builds, with c.BulkInsert. The SSH keys, which
+// differ between the builds, are added once the instance is created.
+func (d *driverGCE) bulkInsertInstance(c *InstanceConfig, zone string, instance *compute.Instance) <-chan error {
+	var sshKeys map[string]string
+	items := make([]*compute.MetadataItems, 0, len(instance.Metadata.Items))
+	for _, item := range instance.Metadata.Items {
+		switch {
+		case item == nil:
+		case item.Key == "ssh-keys":
+			sshKeys = map[string]string{item.Key: *item.Value}
+		default:
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Key < items[j].Key })
+
+	// The boot disks are named after their instance.
+	disks := append([]*compute.AttachedDisk{}, instance.Disks...)
+	boot := *disks[0]
+	initializeParams := *boot.InitializeParams
+	initializeParams.DiskName = ""
+	initializeParams.DiskType = c.DiskType
+	boot.InitializeParams = &initializeParams
+	disks[0] = &boot
+
+	properties := &compute.InstanceProperties{
+		AdvancedMachineFeatures: instance.AdvancedMachineFeatures,
+		Description:             instance.Description,
+		Disks:                   disks,
+		GuestAccelerators:       instance.GuestAccelerators,
+		Labels:                  instance.Labels,
+		MachineType:             c.MachineType,
+		Metadata:                &compute.Metadata{Items: items},
+		MinCpuPlatform:          instance.MinCpuPlatform,
+		NetworkInterfaces:       instance.NetworkInterfaces,
+		Scheduling:              instance.Scheduling,
+		ServiceAccounts:         instance.ServiceAccounts,
+		ShieldedInstanceConfig:  instance.ShieldedInstanceConfig,
+		Tags:                    instance.Tags,
+	}
+
+	errCh := make(chan error, 1)
+	key, err := json.Marshal(struct {
+		Zone       string
+		Properties *compute.InstanceProperties
+	}{zone, properties})
+	if err != nil {
+		errCh <- err
+		return errCh
+	}
+
+	go func() {
+		ctx := c.BulkInsert.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		if c.BulkInsert.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, c.BulkInsert.Timeout)
+			defer cancel()
+		}
+
+		err := c.BulkInsert.Join(ctx, string(key), c.Name, func(names []string) error {
+			d.ui.Message(fmt.Sprintf("Inserting the instances %s...", strings.Join(names, ", ")))
+			perInstance := make(map[string]compute.BulkInsertInstanceResourcePerInstanceProperties, len(names))
+			for _, name := range names {
+				perInstance[name] = compute.BulkInsertInstanceResourcePerInstanceProperties{}
+			}
+			op, err := doOperation(func(requestId string) (*compute.Operation, error) {
+				return d.service.Instances.BulkInsert(d.projectId, zone, &compute.BulkInsertInstanceResource{
+					Count:                 int64(len(names)),
+					MinCount:              int64(len(names)),
+					InstanceProperties:    properties,
+					PerInstanceProperties: perInstance,
+				}).RequestId(requestId).Do()
+			})
+			if err != nil {
+				return err
+			}
+			return d.waitForOperation(d.refreshZoneOp(zone, op))
+		}, func(name string) error {
+			return d.deleteInstanceAndDisk(zone, name)
+		})
+		if errors.Is(err, ErrBulkInsertClosed) {
+			d.ui.Message("The identical instances are already being inserted, inserting this one on its own...")
+			var op *compute.Operation
+			op, err = doOperation(func(requestId string) (*compute.Operation, error) {
+				return d.service.Instances.Insert(d.projectId, zone, instance).RequestId(requestId).Do()
+			})
+			if err == nil {
+				err = d.waitForOperation(d.refreshZoneOp(zone, op))
+			}
+		} else if err == nil && sshKeys != nil {
+			err = d.setInstanceMetadata(zone, c.Name, sshKeys)
+		}
+		errCh <- err
+	}()
+	return errCh
+}
+
+// waitForOperation waits for the operation refresh reports to be done,
+// and returns its error.
+func (d *driverGCE) waitForOperation(refresh stateRefreshFunc) error {
+	// waitForState sends the error of the operation, then its own.
+	errCh := make(chan error, 2)
+	_ = d.waitForState(errCh, "DONE", refresh)
+	return <-errCh
+}
+
 func (d *driverGCE) CreateOrResetWindowsPassword(instance, zone string, c *WindowsPasswordConfig) (<-chan error, error) {
 
 	errCh := make(chan error, 1)
@@ -745,35 +1606,7 @@ func (d *driverGCE) createWindowsPassword(errCh chan<- error, name, zone string,
 	}
 	dCopy := string(data)
 
-	instance, err := d.service.Instances.Get(d.projectId, zone, name).Do()
-	if err != nil {
-		errCh <- err
-		return
-	}
-	instance.Metadata.Items = append(instance.Metadata.Items, &compute.MetadataItems{Key: "windows-keys", Value: &dCopy})
-
-	op, err := d.service.Instances.SetMetadata(d.projectId, zone, name, &compute.Metadata{
-		Fingerprint: instance.Metadata.Fingerprint,
-		Items:       instance.Metadata.Items,
-	}).Do()
-
-	if err != nil {
-		errCh <- err
-		return
-	}
-
-	newErrCh := make(chan error, 1)
-	go func() {
-		_ = waitForState(newErrCh, "DONE", d.refreshZoneOp(zone, op))
-	}()
-
-	select {
-	case err = <-newErrCh:
-	case <-time.After(time.Second * 30):
-		err = errors.New("time out while waiting for instance to create")
-	}
-
-	if err != nil {
+	if err := d.setInstanceMetadata(zone, name, map[string]string{"windows-keys": dCopy}); err != nil {
 		errCh <- err
 		return
 	}
@@ -863,7 +1696,7 @@ func (d *driverGCE) DeleteOSLoginSSHKey(user, fingerprint string) error {
 func (d *driverGCE) WaitForInstance(state, zone, name string) <-chan error {
 	errCh := make(chan error, 1)
 	go func() {
-		_ = waitForState(errCh, state, d.refreshInstanceState(zone, name))
+		_ = d.waitForState(errCh, state, d.refreshInstanceState(zone, name))
 	}()
 	return errCh
 }
@@ -879,20 +1712,19 @@ func (d *driverGCE) refreshInstanceState(zone, name string) stateRefreshFunc {
 }
 
 func (d *driverGCE) refreshGlobalOp(project string, op *compute.Operation) stateRefreshFunc {
+	d.operations.record("global", op)
+	progress := &operationProgress{ui: d.ui}
 	return func() (string, error) {
 		newOp, err := d.service.GlobalOperations.Get(project, op.Name).Do()
 		if err != nil {
 			return "", err
 		}
+		progress.report(newOp)
 
 		// If the op is done, check for errors
 		err = nil
 		if newOp.Status == "DONE" {
-			if newOp.Error != nil {
-				for _, e := range newOp.Error.Errors {
-					err = packersdk.MultiErrorAppend(err, fmt.Errorf(e.Message))
-				}
-			}
+			err = operationErrors(newOp)
 		}
 
 		return newOp.Status, err
@@ -900,20 +1732,19 @@ func (d *driverGCE) refreshGlobalOp(project string, op *compute.Operation) state
 }
 
 func (d *driverGCE) refreshZoneOp(zone string, op *compute.Operation) stateRefreshFunc {
+	d.operations.record(zone, op)
+	progress := &operationProgress{ui: d.ui}
 	return func() (string, error) {
 		newOp, err := d.service.ZoneOperations.Get(d.projectId, zone, op.Name).Do()
 		if err != nil {
 			return "", err
 		}
+		progress.report(newOp)
 
 		// If the op is done, check for errors
 		err = nil
 		if newOp.Status == "DONE" {
-			if newOp.Error != nil {
-				for _, e := range newOp.Error.Errors {
-					err = packersdk.MultiErrorAppend(err, fmt.Errorf(e.Message))
-				}
-			}
+			err = operationErrors(newOp)
 		}
 
 		return newOp.Status, err
@@ -921,35 +1752,39 @@ func (d *driverGCE) refreshZoneOp(zone string, op *compute.Operation) stateRefre
 }
 
 func (d *driverGCE) refreshRegionOp(region string, op *compute.Operation) stateRefreshFunc {
+	d.operations.record(region, op)
+	progress := &operationProgress{ui: d.ui}
 	return func() (string, error) {
 		newOp, err := d.service.RegionOperations.Get(d.projectId, region, op.Name).Do()
 		if err != nil {
 			return "", err
 		}
+		progress.report(newOp)
 
 		// If the op is done, check for errors
 		err = nil
 		if newOp.Status == "DONE" {
-			if newOp.Error != nil {
-				for _, e := range newOp.Error.Errors {
-					err = packersdk.MultiErrorAppend(err, fmt.Errorf(e.Message))
-				}
-			}
+			err = operationErrors(newOp)
 		}
 
 		return newOp.Status, err
 	}
 }
 
+func (d *driverGCE) Operations() []Operation {
+	return d.operations.list()
+}
+
 // used in conjunction with waitForState.
 type stateRefreshFunc func() (string, error)
 
 // waitForState will spin in a loop forever waiting for state to
-// reach a certain target.
-func waitForState(errCh chan<- error, target string, refresh stateRefreshFunc) error {
+// reach a certain target. The interval between two polls grows
+// exponentially, within the bounds configured for the driver.
+func (d *driverGCE) waitForState(errCh chan<- error, target string, refresh stateRefreshFunc) error {
 	ctx := context.TODO()
 	err := retry.Config{
-		RetryDelay: (&retry.Backoff{InitialBackoff: 2 * time.Second, MaxBackoff: 2 * time.Second, Multiplier: 2}).Linear,
+		RetryDelay: newPollBackoff(d.pollMinInterval, d.pollMaxInterval).Delay,
 	}.Run(ctx, func(ctx context.Context) error {
 		state, err := refresh()
 		if err != nil {
@@ -971,67 +1806,337 @@ func waitForState(errCh chan<- error, target string, refresh stateRefreshFunc) e
 }
 
 func (d *driverGCE) AddToInstanceMetadata(zone string, name string, metadata map[string]string) error {
+	return d.setInstanceMetadata(zone, name, metadata)
+}
 
-	instance, err := d.service.Instances.Get(d.projectId, zone, name).Do()
-	if err != nil {
+// maxSetMetadataAttempts is the number of times the metadata of an instance
+// is read and updated when it changes meanwhile.
+const maxSetMetadataAttempts = 5
+
+// setInstanceMetadata sets metadata on the instance in a single update,
+// keeping its other metadata. The update is made against the fingerprint of
+// the metadata it read, and is made again if the metadata changed meanwhile,
+// e.g. by the guest agent.
+func (d *driverGCE) setInstanceMetadata(zone, name string, metadata map[string]string) error {
+	for attempt := 1; ; attempt++ {
+		instance, err := d.service.Instances.Get(d.projectId, zone, name).Do()
+		if err != nil {
+			return err
+		}
+		current := instance.Metadata
+		if current == nil {
+			current = new(compute.Metadata)
+		}
+
+		op, err := d.service.Instances.SetMetadata(d.projectId, zone, name, &compute.Metadata{
+			Fingerprint: current.Fingerprint,
+			Items:       mergeMetadataItems(current.Items, metadata),
+		}).Do()
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed && attempt < maxSetMetadataAttempts {
+			log.Printf("[DEBUG] The metadata of instance %s changed, updating it again", name)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		errCh := make(chan error, 1)
+		go func() {
+			_ = d.waitForState(errCh, "DONE", d.refreshZoneOp(zone, op))
+		}()
+		select {
+		case err = <-errCh:
+		case <-time.After(time.Minute):
+			err = errors.New("time out while waiting for the metadata of the instance to update")
+		}
 		return err
 	}
+}
 
-	// Build up the metadata
-	metadataForInstance := make([]*compute.MetadataItems, len(metadata))
-	for k, v := range metadata {
-		vCopy := v
-		metadataForInstance = append(metadataForInstance, &compute.MetadataItems{
-			Key:   k,
-			Value: &vCopy,
-		})
+// GetTokenInfo gets the information about the token used for authentication
+func (d *driverGCE) GetTokenInfo() (*oauth2_svc.Tokeninfo, error) {
+	return d.oauth2Service.Tokeninfo().Do()
+}
+
+func (d *driverGCE) TestProjectPermissions(project string, permissions []string) ([]string, error) {
+	resp, err := d.crmService.Projects.TestIamPermissions(project, &cloudresourcemanager.TestIamPermissionsRequest{
+		Permissions: permissions,
+	}).Do()
+	if err != nil {
+		return nil, err
 	}
 
-	instance.Metadata.Items = append(instance.Metadata.Items, metadataForInstance...)
+	return resp.Permissions, nil
+}
 
-	op, err := d.service.Instances.SetMetadata(d.projectId, zone, name, &compute.Metadata{
-		Fingerprint: instance.Metadata.Fingerprint,
-		Items:       instance.Metadata.Items,
-	}).Do()
+func (d *driverGCE) GetServiceAccount(email string) (*iam.ServiceAccount, error) {
+	return d.iamService.Projects.ServiceAccounts.Get("projects/-/serviceAccounts/" + email).Do()
+}
 
+func (d *driverGCE) GetProjectNumber(project string) (int64, error) {
+	p, err := d.crmService.Projects.Get(project).Do()
 	if err != nil {
-		return err
+		return 0, err
 	}
+	return p.ProjectNumber, nil
+}
 
-	newErrCh := make(chan error, 1)
+func (d *driverGCE) GetCryptoKey(name string) (*cloudkms.CryptoKey, error) {
+	return d.kmsService.Projects.Locations.KeyRings.CryptoKeys.Get(name).Do()
+}
 
-	go func() {
-		err = waitForState(newErrCh, "DONE", d.refreshZoneOp(zone, op))
+func (d *driverGCE) GetKMSRoleMembers(resource, role string) ([]string, error) {
+	if project := strings.TrimPrefix(resource, "projects/"); !strings.Contains(project, "/") {
+		policy, err := d.crmService.Projects.GetIamPolicy(project, &cloudresourcemanager.GetIamPolicyRequest{}).Do()
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range policy.Bindings {
+			if b.Role == role {
+				return b.Members, nil
+			}
+		}
+		return nil, nil
+	}
 
-		select {
-		case err = <-newErrCh:
-		case <-time.After(time.Second * 30):
-			err = errors.New("time out while waiting for instance to create")
+	var (
+		policy *cloudkms.Policy
+		err    error
+	)
+	if strings.Contains(resource, "/cryptoKeys/") {
+		policy, err = d.kmsService.Projects.Locations.KeyRings.CryptoKeys.GetIamPolicy(resource).Do()
+	} else {
+		policy, err = d.kmsService.Projects.Locations.KeyRings.GetIamPolicy(resource).Do()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, b := range policy.Bindings {
+		if b.Role == role {
+			return b.Members, nil
 		}
-	}()
+	}
+	return nil, nil
+}
 
+func (d *driverGCE) TestBucketPermissions(bucket string, permissions []string) ([]string, error) {
+	resp, err := d.storageService.Buckets.TestIamPermissions(bucket, permissions).Do()
 	if err != nil {
-		newErrCh <- err
-		return err
+		return nil, err
 	}
 
-	return nil
+	return resp.Permissions, nil
 }
 
-// GetTokenInfo gets the information about the token used for authentication
-func (d *driverGCE) GetTokenInfo() (*oauth2_svc.Tokeninfo, error) {
-	return d.oauth2Service.Tokeninfo().Do()
+func (d *driverGCE) UploadToBucket(bucket, objectName string, data io.Reader, opts UploadOptions) (string, error) {
+	object := &storage.Object{
+		Name:     objectName,
+		Metadata: opts.Metadata,
+	}
+	if !opts.CustomTime.IsZero() {
+		object.CustomTime = opts.CustomTime.UTC().Format(time.RFC3339)
+	}
+	dest := fmt.Sprintf("gs://%s/%s", bucket, objectName)
+
+	dataAt, ok := data.(io.ReaderAt)
+	parts := uploadParts(opts.Size, opts.Parallelism)
+	if !ok || parts == nil {
+		progress := newUploadProgress(d.ui, dest, 1)
+		storageObject, err := d.insertObject(bucket, object, data, opts, progress.updater(0))
+		if err != nil {
+			return "", err
+		}
+		return storageObject.SelfLink, nil
+	}
+
+	// The parts are uploaded concurrently as temporary objects, then
+	// composed into the object.
+	log.Printf("[INFO] Uploading %s in %d parts", dest, len(parts))
+	progress := newUploadProgress(d.ui, dest, len(parts))
+	names := make([]string, len(parts))
+	errs := make([]error, len(parts))
+	var wg sync.WaitGroup
+	for i, part := range parts {
+		names[i] = fmt.Sprintf("%s.part-%02d", objectName, i)
+		wg.Add(1)
+		go func(i int, part uploadPart) {
+			defer wg.Done()
+			section := io.NewSectionReader(dataAt, part.offset, part.size)
+			_, errs[i] = d.insertObject(bucket, &storage.Object{Name: names[i]}, section, opts, progress.updater(i))
+		}(i, part)
+	}
+	wg.Wait()
+	defer func() {
+		for i, name := range names {
+			if errs[i] != nil {
+				continue
+			}
+			if err := d.DeleteFromBucket(bucket, name); err != nil {
+				log.Printf("[WARN] Error deleting the uploaded part gs://%s/%s: %s", bucket, name, err)
+			}
+		}
+	}()
+	for i, err := range errs {
+		if err != nil {
+			return "", fmt.Errorf("Error uploading part %d of %s: %w", i, dest, err)
+		}
+	}
+
+	if opts.ContentType != "" {
+		object.ContentType = opts.ContentType
+	}
+	compose := &storage.ComposeRequest{Destination: object}
+	for _, name := range names {
+		compose.SourceObjects = append(compose.SourceObjects, &storage.ComposeRequestSourceObjects{Name: name})
+	}
+	storageObject, err := d.storageService.Objects.Compose(bucket, objectName, compose).Do()
+	if err != nil {
+		return "", fmt.Errorf("Error composing the parts of %s: %w", dest, err)
+	}
+	return storageObject.SelfLink, nil
+}
+
+// insertObject uploads data to object with a resumable upload, which only
+// holds a chunk of data in memory at a time.
+func (d *driverGCE) insertObject(bucket string, object *storage.Object, data io.Reader, opts UploadOptions, progress googleapi.ProgressUpdater) (*storage.Object, error) {
+	var mediaOpts []googleapi.MediaOption
+	if opts.ChunkSize > 0 {
+		mediaOpts = append(mediaOpts, googleapi.ChunkSize(opts.ChunkSize))
+	}
+	if opts.ChunkRetryDeadline > 0 {
+		mediaOpts = append(mediaOpts, googleapi.ChunkRetryDeadline(opts.ChunkRetryDeadline))
+	}
+	if opts.ContentType != "" {
+		mediaOpts = append(mediaOpts, googleapi.ContentType(opts.ContentType))
+	}
+
+	return d.storageService.Objects.Insert(bucket, object).
+		Media(data, mediaOpts...).
+		ProgressUpdater(progress).
+		Do()
 }
 
-func (d *driverGCE) UploadToBucket(bucket, objectName string, data io.Reader) (string, error) {
-	storageObject, err := d.storageService.Objects.Insert(bucket, &storage.Object{Name: objectName}).Media(data).Do()
+func (d *driverGCE) PublishMessage(topic string, data []byte, attributes map[string]string) (string, error) {
+	resp, err := d.pubsubService.Projects.Topics.Publish(topic, &pubsub.PublishRequest{
+		Messages: []*pubsub.PubsubMessage{{
+			Data:       base64.StdEncoding.EncodeToString(data),
+			Attributes: attributes,
+		}},
+	}).Do()
 	if err != nil {
 		return "", err
 	}
+	if len(resp.MessageIds) == 0 {
+		return "", fmt.Errorf("no message ID returned by topic %s", topic)
+	}
+	return resp.MessageIds[0], nil
+}
 
-	return storageObject.SelfLink, nil
+func (d *driverGCE) GetInstanceInventory(zone, name string) (*osconfig.Inventory, error) {
+	inventory := fmt.Sprintf("projects/%s/locations/%s/instances/%s/inventory", d.projectId, zone, name)
+	return d.osConfigService.Projects.Locations.Instances.Inventories.Get(inventory).View("FULL").Do()
+}
+
+func (d *driverGCE) ScanPackages(location, resourceURI string, packages []*ondemandscanning.PackageData) ([]*ondemandscanning.Occurrence, error) {
+	parent := fmt.Sprintf("projects/%s/locations/%s", d.projectId, location)
+	op, err := d.scanningService.Projects.Locations.Scans.AnalyzePackages(parent, &ondemandscanning.AnalyzePackagesRequestV1{
+		Packages:    packages,
+		ResourceUri: resourceURI,
+	}).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	errCh := make(chan error, 2)
+	err = d.waitForState(errCh, "DONE", func() (string, error) {
+		if op.Done {
+			return "DONE", nil
+		}
+		var err error
+		op, err = d.scanningService.Projects.Locations.Operations.Get(op.Name).Do()
+		if err != nil {
+			return "", err
+		}
+		if op.Done {
+			return "DONE", nil
+		}
+		return "RUNNING", nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if op.Error != nil {
+		return nil, fmt.Errorf("scan failed: %s", op.Error.Message)
+	}
+
+	var resp ondemandscanning.AnalyzePackagesResponseV1
+	if err := json.Unmarshal(op.Response, &resp); err != nil {
+		return nil, fmt.Errorf("unexpected scan response: %s", err)
+	}
+
+	var occurrences []*ondemandscanning.Occurrence
+	err = d.scanningService.Projects.Locations.Scans.Vulnerabilities.List(resp.Scan).Pages(context.TODO(),
+		func(page *ondemandscanning.ListVulnerabilitiesResponseV1) error {
+			occurrences = append(occurrences, page.Occurrences...)
+			return nil
+		})
+	return occurrences, err
+}
+
+func (d *driverGCE) AccessSecretVersion(name string) ([]byte, string, error) {
+	service := d.secretService
+	// Regional secrets are only served by the endpoint of their location.
+	if parts := strings.Split(name, "/"); len(parts) > 3 && parts[2] == "locations" {
+		endpoint := fmt.Sprintf("https://secretmanager.%s.rep.googleapis.com/", parts[3])
+		opts := append(append([]option.ClientOption{}, d.clientOpts...), option.WithEndpoint(endpoint))
+		var err error
+		service, err = secretmanager.NewService(context.TODO(), opts...)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	resp, err := service.Projects.Secrets.Versions.Access(name).Do()
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(resp.Payload.Data)
+	if err != nil {
+		return nil, "", fmt.Errorf("Error decoding the payload of secret version %s: %s", resp.Name, err)
+	}
+	if resp.Payload.DataCrc32c != 0 && int64(crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli))) != resp.Payload.DataCrc32c {
+		return nil, "", fmt.Errorf("The payload of secret version %s is corrupted, its checksum does not match", resp.Name)
+	}
+	return data, resp.Name, nil
 }
 
 func (d *driverGCE) DeleteFromBucket(bucket, objectName string) error {
 	return d.storageService.Objects.Delete(bucket, objectName).Do()
 }
+
+func (d *driverGCE) SignURL(bucket, objectName string, expires time.Duration, serviceAccount string) (string, error) {
+	return d.urlSigner.signURL(bucket, objectName, expires, serviceAccount)
+}
+
+func (d *driverGCE) DownloadFromBucket(bucket, objectName string, maxSize int64) ([]byte, error) {
+	resp, err := d.storageService.Objects.Get(bucket, objectName).Download()
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("gs://%s/%s is larger than %d bytes", bucket, objectName, maxSize)
+	}
+	return data, nil
+}
+
+func (d *driverGCE) GetBucketObject(bucket, objectName string) (*storage.Object, error) {
+	return d.storageService.Objects.Get(bucket, objectName).Do()
+}
@@ -6,30 +6,45 @@ package common
 import (
 	"fmt"
 	"io"
+	"time"
 
+	cloudkms "google.golang.org/api/cloudkms/v1"
 	compute "google.golang.org/api/compute/v1"
+	iam "google.golang.org/api/iam/v1"
 	oauth2_svc "google.golang.org/api/oauth2/v2"
+	ondemandscanning "google.golang.org/api/ondemandscanning/v1"
+	osconfig "google.golang.org/api/osconfig/v1"
 	oslogin "google.golang.org/api/oslogin/v1"
+	"google.golang.org/api/storage/v1"
 )
 
 // DriverMock is a Driver implementation that is a mocked out so that
-// it can be used for tests.
+// it can be used for tests. It is made of one mock per service, which can
+// also be used on their own to test code depending on a single service.
 type DriverMock struct {
+	ComputeDriverMock
+	IAMDriverMock
+	IAPDriverMock
+	ImageDriverMock
+	InstanceGroupDriverMock
+	InstanceTemplateDriverMock
+	KMSDriverMock
+	OSLoginDriverMock
+	ParameterManagerDriverMock
+	PubSubDriverMock
+	ScanningDriverMock
+	SecretManagerDriverMock
+	StorageDriverMock
+}
+
+// ComputeDriverMock is a ComputeDriver implementation that is mocked out
+// so that it can be used for tests.
+type ComputeDriverMock struct {
 	CreateDiskConfig   BlockDevice
+	CreateDiskConfigs  []BlockDevice
 	CreateDiskResultCh <-chan *compute.Disk
 	CreateDiskErrCh    <-chan error
 
-	CreateImageProjectId      string
-	CreateImageSpec           *compute.Image
-	CreateImageReturnDiskSize int64
-	CreateImageReturnSelfLink string
-	CreateImageErrCh          <-chan error
-	CreateImageResultCh       <-chan *Image
-
-	DeleteProjectId  string
-	DeleteImageName  string
-	DeleteImageErrCh <-chan error
-
 	DeleteInstanceZone  string
 	DeleteInstanceName  string
 	DeleteInstanceErrCh <-chan error
@@ -40,41 +55,118 @@ type DriverMock struct {
 	DeleteDiskErrCh chan error
 	DeleteDiskErr   error
 
-	DeleteFromBucketBucket     string
-	DeleteFromBucketObjectName string
-	DeleteFromBucketErr        error
-
 	GetDiskName   string
 	GetDiskZone   string
 	GetDiskResult *compute.Disk
 	GetDiskErr    error
 
-	GetImageName           string
-	GetImageSourceProjects []string
-	GetImageFromFamily     bool
-	GetImageResult         *Image
-	GetImageErr            error
-
-	GetImageFromProjectProject    string
-	GetImageFromProjectName       string
-	GetImageFromProjectFromFamily bool
-	GetImageFromProjectResult     *Image
-	GetImageFromProjectErr        error
-
 	GetInstanceMetadataZone   string
 	GetInstanceMetadataName   string
 	GetInstanceMetadataKey    string
 	GetInstanceMetadataResult string
 	GetInstanceMetadataErr    error
 
-	GetTokenInfoResult *oauth2_svc.Tokeninfo
-	GetTokenInfoErr    error
+	GetInstanceStatusZone   string
+	GetInstanceStatusName   string
+	GetInstanceStatusResult string
+	GetInstanceStatusErr    error
+
+	ClaimWarmInstancePool   string
+	ClaimWarmInstanceResult string
+	ClaimWarmInstanceErr    error
+
+	StartWarmInstanceName   string
+	StartWarmInstanceConfig *InstanceConfig
+	StartWarmInstanceErr    error
+
+	ReleaseWarmInstanceName   string
+	ReleaseWarmInstancePool   string
+	ReleaseWarmInstanceSize   int
+	ReleaseWarmInstanceResult bool
+	ReleaseWarmInstanceErr    error
+
+	InstancePreemptedSinceName   string
+	InstancePreemptedSinceSince  time.Time
+	InstancePreemptedSinceResult bool
+	InstancePreemptedSinceErr    error
 
 	GetNatIPZone   string
 	GetNatIPName   string
 	GetNatIPResult string
 	GetNatIPErr    error
 
+	GetMachineTypeZone   string
+	GetMachineTypeName   string
+	GetMachineTypeResult *compute.MachineType
+	GetMachineTypeErr    error
+
+	GetInstanceZone   string
+	GetInstanceName   string
+	GetInstanceResult *compute.Instance
+	GetInstanceErr    error
+
+	ListZonesResult []*compute.Zone
+	ListZonesErr    error
+
+	ListRegionsResult []*compute.Region
+	ListRegionsErr    error
+
+	MachineTypeZonesName   string
+	MachineTypeZonesResult []string
+	MachineTypeZonesErr    error
+
+	AcceleratorTypeZonesName   string
+	AcceleratorTypeZonesResult []string
+	AcceleratorTypeZonesErr    error
+
+	GetSubnetworkProject string
+	GetSubnetworkRegion  string
+	GetSubnetworkName    string
+	GetSubnetworkResult  *compute.Subnetwork
+	GetSubnetworkErr     error
+
+	GetDefaultServiceAccountProject string
+	GetDefaultServiceAccountResult  string
+	GetDefaultServiceAccountErr     error
+
+	GetProjectMetadataProject string
+	GetProjectMetadataResult  map[string]string
+	GetProjectMetadataErr     error
+
+	GetNetworkProject string
+	GetNetworkName    string
+	GetNetworkResult  *compute.Network
+	GetNetworkErr     error
+
+	ListFirewallsProject string
+	ListFirewallsResult  []*compute.Firewall
+	ListFirewallsErr     error
+
+	GetSharedVpcHostProject string
+	GetSharedVpcHostResult  string
+	GetSharedVpcHostErr     error
+
+	ListSnapshotsProject string
+	ListSnapshotsFilter  string
+	ListSnapshotsResult  []*compute.Snapshot
+	ListSnapshotsErr     error
+
+	CreateSnapshotZone     string
+	CreateSnapshotDisk     string
+	CreateSnapshotSnapshot *compute.Snapshot
+	CreateSnapshotErr      error
+	CreateSnapshotNames    []string
+
+	DeleteSnapshotNames []string
+	DeleteSnapshotErr   error
+
+	GetRegionQuotasRegion string
+	GetRegionQuotasResult []*compute.Quota
+	GetRegionQuotasErr    error
+
+	GetProjectQuotasResult []*compute.Quota
+	GetProjectQuotasErr    error
+
 	GetInternalIPZone   string
 	GetInternalIPName   string
 	GetInternalIPResult string
@@ -84,10 +176,13 @@ type DriverMock struct {
 	GetSerialPortOutputName   string
 	GetSerialPortOutputResult string
 	GetSerialPortOutputErr    error
+	GetSerialPortOutputStarts []int64
 
-	ImageExistsProjectId string
-	ImageExistsName      string
-	ImageExistsResult    bool
+	GetGuestAttributesZone      string
+	GetGuestAttributesName      string
+	GetGuestAttributesQueryPath string
+	GetGuestAttributesResult    map[string]string
+	GetGuestAttributesErr       error
 
 	RunInstanceConfig *InstanceConfig
 	RunInstanceErrCh  <-chan error
@@ -110,86 +205,10 @@ type DriverMock struct {
 	AddToInstanceMetadataErrCh   <-chan error
 	AddToInstanceMetadataErr     error
 
-	UploadToBucketBucket     string
-	UploadToBucketObjectName string
-	UploadToBucketData       io.Reader
-	UploadToBucketResult     string
-	UploadToBucketError      error
+	OperationsResult []Operation
 }
 
-func (d *DriverMock) CreateImage(project string, imageSpec *compute.Image) (<-chan *Image, <-chan error) {
-	d.CreateImageProjectId = project
-	d.CreateImageSpec = imageSpec
-	resultCh := d.CreateImageResultCh
-	if resultCh == nil {
-		ch := make(chan *Image, 1)
-
-		selfLink := d.CreateImageReturnSelfLink
-		if selfLink == "" {
-			selfLink = fmt.Sprintf("http://content.googleapis.com/compute/v1/%s/global/licenses/test", d.CreateImageProjectId)
-		}
-
-		diskSizeGb := d.CreateImageReturnDiskSize
-		if diskSizeGb == 0 {
-			diskSizeGb = 25
-		}
-
-		ch <- &Image{
-			GuestOsFeatures: imageSpec.GuestOsFeatures,
-			Labels:          imageSpec.Labels,
-			Licenses:        imageSpec.Licenses,
-			Name:            imageSpec.Name,
-			ProjectId:       d.CreateImageProjectId,
-			SelfLink:        selfLink,
-			SizeGb:          diskSizeGb,
-		}
-		close(ch)
-		resultCh = ch
-	}
-
-	errCh := d.CreateImageErrCh
-	if errCh == nil {
-		ch := make(chan error)
-		close(ch)
-		errCh = ch
-	}
-
-	return resultCh, errCh
-}
-
-// CreateImageFromRaw is very similar to CreateImage, so we'll merge the two together in a later commit.
-//
-// Let's not spend time mocking it now, we'll make it mockable after merging the two functions.
-func (d *DriverMock) CreateImageFromRaw(
-	project string,
-	rawImageURL string,
-	imageName string,
-	imageDescription string,
-	imageFamily string,
-	imageLabels map[string]string,
-	imageGuestOsFeatures []string,
-	shieldedVMStateConfig *compute.InitialStateConfig,
-	imageStorageLocations []string,
-	imageArchitecture string,
-) (<-chan *Image, <-chan error) {
-	return nil, nil
-}
-
-func (d *DriverMock) DeleteImage(project, name string) <-chan error {
-	d.DeleteProjectId = project
-	d.DeleteImageName = name
-
-	resultCh := d.DeleteImageErrCh
-	if resultCh == nil {
-		ch := make(chan error)
-		close(ch)
-		resultCh = ch
-	}
-
-	return resultCh
-}
-
-func (d *DriverMock) DeleteInstance(zone, name string) (<-chan error, error) {
+func (d *ComputeDriverMock) DeleteInstance(zone, name string) (<-chan error, error) {
 	d.DeleteInstanceZone = zone
 	d.DeleteInstanceName = name
 
@@ -203,15 +222,9 @@ func (d *DriverMock) DeleteInstance(zone, name string) (<-chan error, error) {
 	return resultCh, d.DeleteInstanceErr
 }
 
-func (d *DriverMock) DeleteFromBucket(bucket, objectName string) error {
-	d.DeleteFromBucketBucket = bucket
-	d.DeleteFromBucketObjectName = objectName
-
-	return d.DeleteFromBucketErr
-}
-
-func (d *DriverMock) CreateDisk(diskConfig BlockDevice) (<-chan *compute.Disk, <-chan error) {
+func (d *ComputeDriverMock) CreateDisk(diskConfig BlockDevice) (<-chan *compute.Disk, <-chan error) {
 	d.CreateDiskConfig = diskConfig
+	d.CreateDiskConfigs = append(d.CreateDiskConfigs, diskConfig)
 
 	resultCh := d.CreateDiskResultCh
 	if resultCh == nil {
@@ -221,7 +234,7 @@ func (d *DriverMock) CreateDisk(diskConfig BlockDevice) (<-chan *compute.Disk, <
 	}
 
 	errCh := d.CreateDiskErrCh
-	if errCh != nil {
+	if errCh == nil {
 		ch := make(chan error)
 		close(ch)
 		errCh = ch
@@ -230,7 +243,7 @@ func (d *DriverMock) CreateDisk(diskConfig BlockDevice) (<-chan *compute.Disk, <
 	return resultCh, errCh
 }
 
-func (d *DriverMock) DeleteDisk(zone, name string) <-chan error {
+func (d *ComputeDriverMock) DeleteDisk(zone, name string) <-chan error {
 	d.DeleteDiskZone = zone
 	d.DeleteDiskName = name
 
@@ -249,64 +262,181 @@ func (d *DriverMock) DeleteDisk(zone, name string) <-chan error {
 	return resultCh
 }
 
-func (d *DriverMock) GetDisk(zoneOrRegion, name string) (*compute.Disk, error) {
+func (d *ComputeDriverMock) GetDisk(zoneOrRegion, name string) (*compute.Disk, error) {
 	d.GetDiskZone = zoneOrRegion
 	d.GetDiskName = name
 
 	return d.GetDiskResult, d.GetDiskErr
 }
 
-func (d *DriverMock) GetImage(name string, fromFamily bool) (*Image, error) {
-	d.GetImageName = name
-	d.GetImageFromFamily = fromFamily
-	return d.GetImageResult, d.GetImageErr
+func (d *ComputeDriverMock) GetInstanceMetadata(zone, name, key string) (string, error) {
+	d.GetInstanceMetadataZone = zone
+	d.GetInstanceMetadataName = name
+	d.GetInstanceMetadataKey = key
+	return d.GetInstanceMetadataResult, d.GetInstanceMetadataErr
 }
-func (d *DriverMock) GetImageFromProjects(projects []string, name string, fromFamily bool) (*Image, error) {
-	d.GetImageSourceProjects = projects
-	d.GetImageFromProjectName = name
-	d.GetImageFromProjectFromFamily = fromFamily
-	return d.GetImageFromProjectResult, d.GetImageFromProjectErr
+
+func (d *ComputeDriverMock) GetInstanceStatus(zone, name string) (string, error) {
+	d.GetInstanceStatusZone = zone
+	d.GetInstanceStatusName = name
+	return d.GetInstanceStatusResult, d.GetInstanceStatusErr
 }
 
-func (d *DriverMock) GetImageFromProject(project, name string, fromFamily bool) (*Image, error) {
-	d.GetImageFromProjectProject = project
-	d.GetImageFromProjectName = name
-	d.GetImageFromProjectFromFamily = fromFamily
-	return d.GetImageFromProjectResult, d.GetImageFromProjectErr
+func (d *ComputeDriverMock) ClaimWarmInstance(zone, pool string) (string, error) {
+	d.ClaimWarmInstancePool = pool
+	return d.ClaimWarmInstanceResult, d.ClaimWarmInstanceErr
 }
 
-func (d *DriverMock) GetInstanceMetadata(zone, name, key string) (string, error) {
-	d.GetInstanceMetadataZone = zone
-	d.GetInstanceMetadataName = name
-	d.GetInstanceMetadataKey = key
-	return d.GetInstanceMetadataResult, d.GetInstanceMetadataErr
+func (d *ComputeDriverMock) StartWarmInstance(name string, c *InstanceConfig) error {
+	d.StartWarmInstanceName = name
+	d.StartWarmInstanceConfig = c
+	return d.StartWarmInstanceErr
 }
 
-func (d *DriverMock) GetNatIP(zone, name string) (string, error) {
+func (d *ComputeDriverMock) ReleaseWarmInstance(zone, name, pool string, size int) (bool, error) {
+	d.ReleaseWarmInstanceName = name
+	d.ReleaseWarmInstancePool = pool
+	d.ReleaseWarmInstanceSize = size
+	return d.ReleaseWarmInstanceResult, d.ReleaseWarmInstanceErr
+}
+
+func (d *ComputeDriverMock) InstancePreemptedSince(zone, name string, since time.Time) (bool, error) {
+	d.InstancePreemptedSinceName = name
+	d.InstancePreemptedSinceSince = since
+	return d.InstancePreemptedSinceResult, d.InstancePreemptedSinceErr
+}
+
+func (d *ComputeDriverMock) GetNatIP(zone, name string) (string, error) {
 	d.GetNatIPZone = zone
 	d.GetNatIPName = name
 	return d.GetNatIPResult, d.GetNatIPErr
 }
 
-func (d *DriverMock) GetInternalIP(zone, name string) (string, error) {
+func (d *ComputeDriverMock) GetMachineType(zone, name string) (*compute.MachineType, error) {
+	d.GetMachineTypeZone = zone
+	d.GetMachineTypeName = name
+	return d.GetMachineTypeResult, d.GetMachineTypeErr
+}
+
+func (d *ComputeDriverMock) GetInstance(zone, name string) (*compute.Instance, error) {
+	d.GetInstanceZone = zone
+	d.GetInstanceName = name
+	return d.GetInstanceResult, d.GetInstanceErr
+}
+
+func (d *ComputeDriverMock) ListZones() ([]*compute.Zone, error) {
+	return d.ListZonesResult, d.ListZonesErr
+}
+
+func (d *ComputeDriverMock) ListRegions() ([]*compute.Region, error) {
+	return d.ListRegionsResult, d.ListRegionsErr
+}
+
+func (d *ComputeDriverMock) MachineTypeZones(name string) ([]string, error) {
+	d.MachineTypeZonesName = name
+	return d.MachineTypeZonesResult, d.MachineTypeZonesErr
+}
+
+func (d *ComputeDriverMock) AcceleratorTypeZones(name string) ([]string, error) {
+	d.AcceleratorTypeZonesName = name
+	return d.AcceleratorTypeZonesResult, d.AcceleratorTypeZonesErr
+}
+
+func (d *ComputeDriverMock) GetSubnetwork(project, region, name string) (*compute.Subnetwork, error) {
+	d.GetSubnetworkProject = project
+	d.GetSubnetworkRegion = region
+	d.GetSubnetworkName = name
+	return d.GetSubnetworkResult, d.GetSubnetworkErr
+}
+
+func (d *ComputeDriverMock) GetDefaultServiceAccount(project string) (string, error) {
+	d.GetDefaultServiceAccountProject = project
+	return d.GetDefaultServiceAccountResult, d.GetDefaultServiceAccountErr
+}
+
+func (d *ComputeDriverMock) GetProjectMetadata(project string) (map[string]string, error) {
+	d.GetProjectMetadataProject = project
+	return d.GetProjectMetadataResult, d.GetProjectMetadataErr
+}
+
+func (d *ComputeDriverMock) GetNetwork(project, name string) (*compute.Network, error) {
+	d.GetNetworkProject = project
+	d.GetNetworkName = name
+	return d.GetNetworkResult, d.GetNetworkErr
+}
+
+func (d *ComputeDriverMock) ListFirewalls(project string) ([]*compute.Firewall, error) {
+	d.ListFirewallsProject = project
+	return d.ListFirewallsResult, d.ListFirewallsErr
+}
+
+func (d *ComputeDriverMock) GetSharedVpcHost(project string) (string, error) {
+	d.GetSharedVpcHostProject = project
+	return d.GetSharedVpcHostResult, d.GetSharedVpcHostErr
+}
+
+func (d *ComputeDriverMock) ListSnapshots(project, filter string) ([]*compute.Snapshot, error) {
+	d.ListSnapshotsProject = project
+	d.ListSnapshotsFilter = filter
+	return d.ListSnapshotsResult, d.ListSnapshotsErr
+}
+
+func (d *ComputeDriverMock) CreateSnapshot(zone, disk string, snapshot *compute.Snapshot) error {
+	d.CreateSnapshotZone = zone
+	d.CreateSnapshotDisk = disk
+	d.CreateSnapshotSnapshot = snapshot
+	d.CreateSnapshotNames = append(d.CreateSnapshotNames, snapshot.Name)
+	return d.CreateSnapshotErr
+}
+
+func (d *ComputeDriverMock) DeleteSnapshot(name string) error {
+	d.DeleteSnapshotNames = append(d.DeleteSnapshotNames, name)
+	return d.DeleteSnapshotErr
+}
+
+func (d *ComputeDriverMock) GetRegionQuotas(region string) ([]*compute.Quota, error) {
+	d.GetRegionQuotasRegion = region
+	return d.GetRegionQuotasResult, d.GetRegionQuotasErr
+}
+
+func (d *ComputeDriverMock) GetProjectQuotas() ([]*compute.Quota, error) {
+	return d.GetProjectQuotasResult, d.GetProjectQuotasErr
+}
+
+func (d *ComputeDriverMock) GetInternalIP(zone, name string) (string, error) {
 	d.GetInternalIPZone = zone
 	d.GetInternalIPName = name
 	return d.GetInternalIPResult, d.GetInternalIPErr
 }
 
-func (d *DriverMock) GetSerialPortOutput(zone, name string) (string, error) {
+func (d *ComputeDriverMock) GetSerialPortOutput(zone, name string) (string, error) {
 	d.GetSerialPortOutputZone = zone
 	d.GetSerialPortOutputName = name
 	return d.GetSerialPortOutputResult, d.GetSerialPortOutputErr
 }
 
-func (d *DriverMock) ImageExists(project, name string) bool {
-	d.ImageExistsProjectId = project
-	d.ImageExistsName = name
-	return d.ImageExistsResult
+func (d *ComputeDriverMock) GetSerialPortOutputFrom(zone, name string, start int64) (string, int64, error) {
+	d.GetSerialPortOutputZone = zone
+	d.GetSerialPortOutputName = name
+	d.GetSerialPortOutputStarts = append(d.GetSerialPortOutputStarts, start)
+	if d.GetSerialPortOutputErr != nil {
+		return "", start, d.GetSerialPortOutputErr
+	}
+	output := d.GetSerialPortOutputResult
+	if start > int64(len(output)) {
+		start = int64(len(output))
+	}
+	return output[start:], int64(len(output)), nil
+}
+
+func (d *ComputeDriverMock) GetGuestAttributes(zone, name, queryPath string) (map[string]string, error) {
+	d.GetGuestAttributesZone = zone
+	d.GetGuestAttributesName = name
+	d.GetGuestAttributesQueryPath = queryPath
+	return d.GetGuestAttributesResult, d.GetGuestAttributesErr
 }
 
-func (d *DriverMock) RunInstance(c *InstanceConfig) (<-chan error, error) {
+func (d *ComputeDriverMock) RunInstance(c *InstanceConfig) (<-chan error, error) {
 	d.RunInstanceConfig = c
 
 	resultCh := d.RunInstanceErrCh
@@ -319,7 +449,7 @@ func (d *DriverMock) RunInstance(c *InstanceConfig) (<-chan error, error) {
 	return resultCh, d.RunInstanceErr
 }
 
-func (d *DriverMock) WaitForInstance(state, zone, name string) <-chan error {
+func (d *ComputeDriverMock) WaitForInstance(state, zone, name string) <-chan error {
 	d.WaitForInstanceState = state
 	d.WaitForInstanceZone = zone
 	d.WaitForInstanceName = name
@@ -334,11 +464,11 @@ func (d *DriverMock) WaitForInstance(state, zone, name string) <-chan error {
 	return resultCh
 }
 
-func (d *DriverMock) GetWindowsPassword() (string, error) {
+func (d *ComputeDriverMock) GetWindowsPassword() (string, error) {
 	return "", nil
 }
 
-func (d *DriverMock) CreateOrResetWindowsPassword(instance, zone string, c *WindowsPasswordConfig) (<-chan error, error) {
+func (d *ComputeDriverMock) CreateOrResetWindowsPassword(instance, zone string, c *WindowsPasswordConfig) (<-chan error, error) {
 
 	d.CreateOrResetWindowsPasswordInstance = instance
 	d.CreateOrResetWindowsPasswordZone = zone
@@ -356,33 +486,235 @@ func (d *DriverMock) CreateOrResetWindowsPassword(instance, zone string, c *Wind
 	return resultCh, d.CreateOrResetWindowsPasswordErr
 }
 
-func (d *DriverMock) ImportOSLoginSSHKey(user, key string) (*oslogin.LoginProfile, error) {
-	account := oslogin.PosixAccount{Primary: true, Username: "testing_packer_io"}
-	profile := oslogin.LoginProfile{
-		PosixAccounts: []*oslogin.PosixAccount{&account},
+func (d *ComputeDriverMock) AddToInstanceMetadata(zone string, name string, metadata map[string]string) error {
+	d.AddToInstanceMetadataZone = zone
+	d.AddToInstanceMetadataName = name
+	d.AddToInstanceMetadataKVPairs = metadata
+
+	resultCh := d.AddToInstanceMetadataErrCh
+	if resultCh == nil {
+		ch := make(chan error)
+		close(ch)
 	}
-	return &profile, nil
-}
 
-func (d *DriverMock) DeleteOSLoginSSHKey(user, fingerprint string) error {
 	return nil
 }
 
-func (d *DriverMock) AddToInstanceMetadata(zone string, name string, metadata map[string]string) error {
-	d.AddToInstanceMetadataZone = zone
-	d.AddToInstanceMetadataName = name
-	d.AddToInstanceMetadataKVPairs = metadata
+func (d *ComputeDriverMock) Operations() []Operation {
+	return d.OperationsResult
+}
 
-	resultCh := d.AddToInstanceMetadataErrCh
+// IAPDriverMock is an IAPDriver implementation that is mocked out so that it
+// can be used for tests.
+type IAPDriverMock struct {
+	NewIAPTunnelTarget                    IAPTunnelTarget
+	NewIAPTunnelImpersonateServiceAccount string
+	NewIAPTunnelResult                    TunnelDriver
+	NewIAPTunnelErr                       error
+}
+
+func (d *IAPDriverMock) NewIAPTunnel(target IAPTunnelTarget, impersonateServiceAccount string) (TunnelDriver, error) {
+	d.NewIAPTunnelTarget = target
+	d.NewIAPTunnelImpersonateServiceAccount = impersonateServiceAccount
+	return d.NewIAPTunnelResult, d.NewIAPTunnelErr
+}
+
+// ImageDriverMock is an ImageDriver implementation that is mocked out so
+// that it can be used for tests.
+type ImageDriverMock struct {
+	CreateImageProjectId      string
+	CreateImageSpec           *compute.Image
+	CreateImageSpecs          []*compute.Image
+	CreateImageForced         bool
+	CreateImageReturnDiskSize int64
+	CreateImageReturnSelfLink string
+	CreateImageErrCh          <-chan error
+	CreateImageResultCh       <-chan *Image
+
+	DeleteProjectId  string
+	DeleteImageName  string
+	DeleteImageErrCh <-chan error
+
+	GetImageName           string
+	GetImageSourceProjects []string
+	GetImageFromFamily     bool
+	GetImageResult         *Image
+	GetImageErr            error
+
+	GetImageFromProjectProject    string
+	GetImageFromProjectName       string
+	GetImageFromProjectFromFamily bool
+	GetImageFromProjectResult     *Image
+	GetImageFromProjectErr        error
+
+	ImageExistsProjectId string
+	ImageExistsName      string
+	ImageExistsResult    bool
+
+	AddImageIamMembersProject string
+	AddImageIamMembersName    string
+	AddImageIamMembersRole    string
+	AddImageIamMembersMembers []string
+	AddImageIamMembersErr     error
+
+	ListImagesProject string
+	ListImagesFilter  string
+	ListImagesResult  []*compute.Image
+	ListImagesErr     error
+
+	DeprecateImageProject string
+	DeprecateImageNames   []string
+	DeprecateImageStatus  *compute.DeprecationStatus
+	DeprecateImageErr     error
+}
+
+func (d *ImageDriverMock) ForceCreateImage(project string, imageSpec *compute.Image) (<-chan *Image, <-chan error) {
+	d.CreateImageForced = true
+	return d.CreateImage(project, imageSpec)
+}
+
+func (d *ImageDriverMock) CreateImage(project string, imageSpec *compute.Image) (<-chan *Image, <-chan error) {
+	d.CreateImageProjectId = project
+	d.CreateImageSpec = imageSpec
+	d.CreateImageSpecs = append(d.CreateImageSpecs, imageSpec)
+	resultCh := d.CreateImageResultCh
 	if resultCh == nil {
+		ch := make(chan *Image, 1)
+
+		selfLink := d.CreateImageReturnSelfLink
+		if selfLink == "" {
+			selfLink = fmt.Sprintf("http://content.googleapis.com/compute/v1/%s/global/licenses/test", d.CreateImageProjectId)
+		}
+
+		diskSizeGb := d.CreateImageReturnDiskSize
+		if diskSizeGb == 0 {
+			diskSizeGb = 25
+		}
+
+		ch <- &Image{
+			Family:           imageSpec.Family,
+			GuestOsFeatures:  imageSpec.GuestOsFeatures,
+			Labels:           imageSpec.Labels,
+			Licenses:         imageSpec.Licenses,
+			Name:             imageSpec.Name,
+			ProjectId:        d.CreateImageProjectId,
+			SelfLink:         selfLink,
+			SizeGb:           diskSizeGb,
+			StorageLocations: imageSpec.StorageLocations,
+		}
+		close(ch)
+		resultCh = ch
+	}
+
+	errCh := d.CreateImageErrCh
+	if errCh == nil {
 		ch := make(chan error)
 		close(ch)
+		errCh = ch
 	}
 
+	return resultCh, errCh
+}
+
+// CreateImageFromRaw is very similar to CreateImage, so we'll merge the two together in a later commit.
+//
+// Let's not spend time mocking it now, we'll make it mockable after merging the two functions.
+func (d *ImageDriverMock) CreateImageFromRaw(
+	project string,
+	rawImageURL string,
+	imageName string,
+	imageDescription string,
+	imageFamily string,
+	imageLabels map[string]string,
+	imageGuestOsFeatures []string,
+	shieldedVMStateConfig *compute.InitialStateConfig,
+	imageStorageLocations []string,
+	imageArchitecture string,
+) (<-chan *Image, <-chan error) {
+	return nil, nil
+}
+
+func (d *ImageDriverMock) AddImageIamMembers(project, name, role string, members []string) error {
+	d.AddImageIamMembersProject = project
+	d.AddImageIamMembersName = name
+	d.AddImageIamMembersRole = role
+	d.AddImageIamMembersMembers = members
+	return d.AddImageIamMembersErr
+}
+
+func (d *ImageDriverMock) ListImages(project, filter string) ([]*compute.Image, error) {
+	d.ListImagesProject = project
+	d.ListImagesFilter = filter
+	return d.ListImagesResult, d.ListImagesErr
+}
+
+func (d *ImageDriverMock) DeprecateImage(project, name string, status *compute.DeprecationStatus) error {
+	d.DeprecateImageProject = project
+	d.DeprecateImageNames = append(d.DeprecateImageNames, name)
+	d.DeprecateImageStatus = status
+	return d.DeprecateImageErr
+}
+
+func (d *ImageDriverMock) DeleteImage(project, name string) <-chan error {
+	d.DeleteProjectId = project
+	d.DeleteImageName = name
+
+	resultCh := d.DeleteImageErrCh
+	if resultCh == nil {
+		ch := make(chan error)
+		close(ch)
+		resultCh = ch
+	}
+
+	return resultCh
+}
+
+func (d *ImageDriverMock) GetImage(name string, fromFamily bool) (*Image, error) {
+	d.GetImageName = name
+	d.GetImageFromFamily = fromFamily
+	return d.GetImageResult, d.GetImageErr
+}
+
+func (d *ImageDriverMock) GetImageFromProjects(projects []string, name string, fromFamily bool) (*Image, error) {
+	d.GetImageSourceProjects = projects
+	d.GetImageFromProjectName = name
+	d.GetImageFromProjectFromFamily = fromFamily
+	return d.GetImageFromProjectResult, d.GetImageFromProjectErr
+}
+
+func (d *ImageDriverMock) GetImageFromProject(project, name string, fromFamily bool) (*Image, error) {
+	d.GetImageFromProjectProject = project
+	d.GetImageFromProjectName = name
+	d.GetImageFromProjectFromFamily = fromFamily
+	return d.GetImageFromProjectResult, d.GetImageFromProjectErr
+}
+
+func (d *ImageDriverMock) ImageExists(project, name string) bool {
+	d.ImageExistsProjectId = project
+	d.ImageExistsName = name
+	return d.ImageExistsResult
+}
+
+// OSLoginDriverMock is an OSLoginDriver implementation that is mocked out
+// so that it can be used for tests.
+type OSLoginDriverMock struct {
+	GetTokenInfoResult *oauth2_svc.Tokeninfo
+	GetTokenInfoErr    error
+}
+
+func (d *OSLoginDriverMock) ImportOSLoginSSHKey(user, key string) (*oslogin.LoginProfile, error) {
+	account := oslogin.PosixAccount{Primary: true, Username: "testing_packer_io"}
+	profile := oslogin.LoginProfile{
+		PosixAccounts: []*oslogin.PosixAccount{&account},
+	}
+	return &profile, nil
+}
+
+func (d *OSLoginDriverMock) DeleteOSLoginSSHKey(user, fingerprint string) error {
 	return nil
 }
 
-func (d *DriverMock) GetTokenInfo() (*oauth2_svc.Tokeninfo, error) {
+func (d *OSLoginDriverMock) GetTokenInfo() (*oauth2_svc.Tokeninfo, error) {
 	if d.GetTokenInfoResult == nil {
 		d.GetTokenInfoErr = fmt.Errorf("no token found")
 	}
@@ -390,10 +722,338 @@ func (d *DriverMock) GetTokenInfo() (*oauth2_svc.Tokeninfo, error) {
 	return d.GetTokenInfoResult, d.GetTokenInfoErr
 }
 
-func (d *DriverMock) UploadToBucket(bucket, object string, data io.Reader) (string, error) {
+// PubSubDriverMock is a PubSubDriver implementation that is mocked out so
+// that it can be used for tests.
+type PubSubDriverMock struct {
+	PublishMessageTopic      string
+	PublishMessageData       []byte
+	PublishMessageAttributes map[string]string
+	PublishMessageResult     string
+	PublishMessageErr        error
+}
+
+func (d *PubSubDriverMock) PublishMessage(topic string, data []byte, attributes map[string]string) (string, error) {
+	d.PublishMessageTopic = topic
+	d.PublishMessageData = data
+	d.PublishMessageAttributes = attributes
+	return d.PublishMessageResult, d.PublishMessageErr
+}
+
+// ScanningDriverMock is a ScanningDriver implementation that is mocked out
+// so that it can be used for tests.
+type ScanningDriverMock struct {
+	GetInstanceInventoryZone   string
+	GetInstanceInventoryName   string
+	GetInstanceInventoryResult *osconfig.Inventory
+	GetInstanceInventoryErr    error
+
+	ScanPackagesLocation    string
+	ScanPackagesResourceURI string
+	ScanPackagesPackages    []*ondemandscanning.PackageData
+	ScanPackagesResult      []*ondemandscanning.Occurrence
+	ScanPackagesErr         error
+}
+
+func (d *ScanningDriverMock) GetInstanceInventory(zone, name string) (*osconfig.Inventory, error) {
+	d.GetInstanceInventoryZone = zone
+	d.GetInstanceInventoryName = name
+	return d.GetInstanceInventoryResult, d.GetInstanceInventoryErr
+}
+
+func (d *ScanningDriverMock) ScanPackages(location, resourceURI string, packages []*ondemandscanning.PackageData) ([]*ondemandscanning.Occurrence, error) {
+	d.ScanPackagesLocation = location
+	d.ScanPackagesResourceURI = resourceURI
+	d.ScanPackagesPackages = packages
+	return d.ScanPackagesResult, d.ScanPackagesErr
+}
+
+// IAMDriverMock is an IAMDriver implementation that is mocked out so that
+// it can be used for tests.
+type IAMDriverMock struct {
+	TestProjectPermissionsProject     string
+	TestProjectPermissionsPermissions []string
+	TestProjectPermissionsResult      []string
+	TestProjectPermissionsErr         error
+
+	GetServiceAccountEmail  string
+	GetServiceAccountResult *iam.ServiceAccount
+	GetServiceAccountErr    error
+
+	GetProjectNumberProject string
+	GetProjectNumberResult  int64
+	GetProjectNumberErr     error
+}
+
+func (d *IAMDriverMock) TestProjectPermissions(project string, permissions []string) ([]string, error) {
+	d.TestProjectPermissionsProject = project
+	d.TestProjectPermissionsPermissions = permissions
+
+	return d.TestProjectPermissionsResult, d.TestProjectPermissionsErr
+}
+
+func (d *IAMDriverMock) GetServiceAccount(email string) (*iam.ServiceAccount, error) {
+	d.GetServiceAccountEmail = email
+	return d.GetServiceAccountResult, d.GetServiceAccountErr
+}
+
+func (d *IAMDriverMock) GetProjectNumber(project string) (int64, error) {
+	d.GetProjectNumberProject = project
+	return d.GetProjectNumberResult, d.GetProjectNumberErr
+}
+
+// KMSDriverMock is a KMSDriver implementation that is mocked out so that it
+// can be used for tests.
+type KMSDriverMock struct {
+	GetCryptoKeyName   string
+	GetCryptoKeyResult *cloudkms.CryptoKey
+	GetCryptoKeyErr    error
+
+	// GetKMSRoleMembersResult are the members of the role, by resource.
+	GetKMSRoleMembersRole   string
+	GetKMSRoleMembersResult map[string][]string
+	GetKMSRoleMembersErr    error
+}
+
+func (d *KMSDriverMock) GetCryptoKey(name string) (*cloudkms.CryptoKey, error) {
+	d.GetCryptoKeyName = name
+	return d.GetCryptoKeyResult, d.GetCryptoKeyErr
+}
+
+func (d *KMSDriverMock) GetKMSRoleMembers(resource, role string) ([]string, error) {
+	d.GetKMSRoleMembersRole = role
+	return d.GetKMSRoleMembersResult[resource], d.GetKMSRoleMembersErr
+}
+
+// StorageDriverMock is a StorageDriver implementation that is mocked out
+// so that it can be used for tests.
+type StorageDriverMock struct {
+	DeleteFromBucketBucket     string
+	DeleteFromBucketObjectName string
+	DeleteFromBucketErr        error
+
+	DownloadFromBucketBucket     string
+	DownloadFromBucketObjectName string
+	DownloadFromBucketMaxSize    int64
+	DownloadFromBucketResult     []byte
+	DownloadFromBucketErr        error
+
+	// GetBucketObjectResult holds the objects returned by GetBucketObject,
+	// keyed by "bucket/object". Other objects are not found.
+	GetBucketObjectResult map[string]*storage.Object
+	GetBucketObjectErr    error
+
+	SignURLBucket         string
+	SignURLObjectName     string
+	SignURLExpires        time.Duration
+	SignURLServiceAccount string
+	SignURLResult         string
+	SignURLErr            error
+
+	TestBucketPermissionsBucket      string
+	TestBucketPermissionsPermissions []string
+	TestBucketPermissionsResult      []string
+	TestBucketPermissionsErr         error
+
+	UploadToBucketBucket     string
+	UploadToBucketObjectName string
+	UploadToBucketData       io.Reader
+	UploadToBucketOpts       UploadOptions
+	UploadToBucketResult     string
+	UploadToBucketError      error
+}
+
+func (d *StorageDriverMock) DeleteFromBucket(bucket, objectName string) error {
+	d.DeleteFromBucketBucket = bucket
+	d.DeleteFromBucketObjectName = objectName
+
+	return d.DeleteFromBucketErr
+}
+
+func (d *StorageDriverMock) DownloadFromBucket(bucket, objectName string, maxSize int64) ([]byte, error) {
+	d.DownloadFromBucketBucket = bucket
+	d.DownloadFromBucketObjectName = objectName
+	d.DownloadFromBucketMaxSize = maxSize
+	return d.DownloadFromBucketResult, d.DownloadFromBucketErr
+}
+
+func (d *StorageDriverMock) GetBucketObject(bucket, objectName string) (*storage.Object, error) {
+	if d.GetBucketObjectErr != nil {
+		return nil, d.GetBucketObjectErr
+	}
+	obj, ok := d.GetBucketObjectResult[bucket+"/"+objectName]
+	if !ok {
+		return nil, fmt.Errorf("object %s not found in bucket %s", objectName, bucket)
+	}
+	return obj, nil
+}
+
+func (d *StorageDriverMock) SignURL(bucket, objectName string, expires time.Duration, serviceAccount string) (string, error) {
+	d.SignURLBucket = bucket
+	d.SignURLObjectName = objectName
+	d.SignURLExpires = expires
+	d.SignURLServiceAccount = serviceAccount
+
+	return d.SignURLResult, d.SignURLErr
+}
+
+func (d *StorageDriverMock) TestBucketPermissions(bucket string, permissions []string) ([]string, error) {
+	d.TestBucketPermissionsBucket = bucket
+	d.TestBucketPermissionsPermissions = permissions
+
+	return d.TestBucketPermissionsResult, d.TestBucketPermissionsErr
+}
+
+func (d *StorageDriverMock) UploadToBucket(bucket, object string, data io.Reader, opts UploadOptions) (string, error) {
 	d.UploadToBucketBucket = bucket
 	d.UploadToBucketObjectName = object
 	d.UploadToBucketData = data
+	d.UploadToBucketOpts = opts
 
 	return d.UploadToBucketResult, d.UploadToBucketError
 }
+
+// InstanceGroupDriverMock is an InstanceGroupDriver implementation that is
+// mocked out so that it can be used for tests.
+type InstanceGroupDriverMock struct {
+	GetInstanceGroupManagerZone   string
+	GetInstanceGroupManagerRegion string
+	GetInstanceGroupManagerName   string
+	GetInstanceGroupManagerResult *compute.InstanceGroupManager
+	GetInstanceGroupManagerErr    error
+
+	PatchInstanceGroupManagerZone   string
+	PatchInstanceGroupManagerRegion string
+	PatchInstanceGroupManagerName   string
+	PatchInstanceGroupManagerIgm    *compute.InstanceGroupManager
+	PatchInstanceGroupManagerErr    error
+
+	WaitForInstanceGroupManagerStableZone   string
+	WaitForInstanceGroupManagerStableRegion string
+	WaitForInstanceGroupManagerStableName   string
+	WaitForInstanceGroupManagerStableErr    error
+}
+
+func (d *InstanceGroupDriverMock) GetInstanceGroupManager(zone, region, name string) (*compute.InstanceGroupManager, error) {
+	d.GetInstanceGroupManagerZone = zone
+	d.GetInstanceGroupManagerRegion = region
+	d.GetInstanceGroupManagerName = name
+	return d.GetInstanceGroupManagerResult, d.GetInstanceGroupManagerErr
+}
+
+func (d *InstanceGroupDriverMock) PatchInstanceGroupManager(zone, region, name string, igm *compute.InstanceGroupManager) <-chan error {
+	d.PatchInstanceGroupManagerZone = zone
+	d.PatchInstanceGroupManagerRegion = region
+	d.PatchInstanceGroupManagerName = name
+	d.PatchInstanceGroupManagerIgm = igm
+
+	errCh := make(chan error, 1)
+	errCh <- d.PatchInstanceGroupManagerErr
+	return errCh
+}
+
+func (d *InstanceGroupDriverMock) WaitForInstanceGroupManagerStable(zone, region, name string) <-chan error {
+	d.WaitForInstanceGroupManagerStableZone = zone
+	d.WaitForInstanceGroupManagerStableRegion = region
+	d.WaitForInstanceGroupManagerStableName = name
+
+	errCh := make(chan error, 1)
+	errCh <- d.WaitForInstanceGroupManagerStableErr
+	return errCh
+}
+
+// InstanceTemplateDriverMock is an InstanceTemplateDriver implementation
+// that is mocked out so that it can be used for tests.
+type InstanceTemplateDriverMock struct {
+	CreateInstanceTemplateProjectId string
+	CreateInstanceTemplateSpec      *compute.InstanceTemplate
+	CreateInstanceTemplateErr       error
+
+	DeleteInstanceTemplateProjectId string
+	DeleteInstanceTemplateName      string
+	DeleteInstanceTemplateErr       error
+
+	GetInstanceTemplateProjectId string
+	GetInstanceTemplateName      string
+	GetInstanceTemplateResult    *compute.InstanceTemplate
+	GetInstanceTemplateErr       error
+}
+
+func (d *InstanceTemplateDriverMock) CreateInstanceTemplate(project string, spec *compute.InstanceTemplate) (<-chan *compute.InstanceTemplate, <-chan error) {
+	d.CreateInstanceTemplateProjectId = project
+	d.CreateInstanceTemplateSpec = spec
+
+	resultCh := make(chan *compute.InstanceTemplate, 1)
+	errCh := make(chan error, 1)
+	if d.CreateInstanceTemplateErr != nil {
+		errCh <- d.CreateInstanceTemplateErr
+	} else {
+		resultCh <- spec
+	}
+	close(resultCh)
+
+	return resultCh, errCh
+}
+
+func (d *InstanceTemplateDriverMock) DeleteInstanceTemplate(project, name string) <-chan error {
+	d.DeleteInstanceTemplateProjectId = project
+	d.DeleteInstanceTemplateName = name
+
+	errCh := make(chan error, 1)
+	if d.DeleteInstanceTemplateErr != nil {
+		errCh <- d.DeleteInstanceTemplateErr
+	}
+	close(errCh)
+
+	return errCh
+}
+
+func (d *InstanceTemplateDriverMock) GetInstanceTemplate(project, name string) (*compute.InstanceTemplate, error) {
+	d.GetInstanceTemplateProjectId = project
+	d.GetInstanceTemplateName = name
+	return d.GetInstanceTemplateResult, d.GetInstanceTemplateErr
+}
+
+// ParameterManagerDriverMock is a ParameterManagerDriver implementation that
+// is mocked out so that it can be used for tests.
+type ParameterManagerDriverMock struct {
+	GetParameterName   string
+	GetParameterResult *Parameter
+	GetParameterErr    error
+
+	ListParameterVersionsParameter string
+	ListParameterVersionsResult    []*ParameterVersion
+	ListParameterVersionsErr       error
+
+	RenderParameterVersionName   string
+	RenderParameterVersionResult []byte
+	RenderParameterVersionErr    error
+}
+
+func (d *ParameterManagerDriverMock) GetParameter(name string) (*Parameter, error) {
+	d.GetParameterName = name
+	return d.GetParameterResult, d.GetParameterErr
+}
+
+func (d *ParameterManagerDriverMock) ListParameterVersions(parameter string) ([]*ParameterVersion, error) {
+	d.ListParameterVersionsParameter = parameter
+	return d.ListParameterVersionsResult, d.ListParameterVersionsErr
+}
+
+func (d *ParameterManagerDriverMock) RenderParameterVersion(name string) ([]byte, error) {
+	d.RenderParameterVersionName = name
+	return d.RenderParameterVersionResult, d.RenderParameterVersionErr
+}
+
+// SecretManagerDriverMock is a SecretManagerDriver implementation that is
+// mocked out so that it can be used for tests.
+type SecretManagerDriverMock struct {
+	AccessSecretVersionName    string
+	AccessSecretVersionResult  []byte
+	AccessSecretVersionVersion string
+	AccessSecretVersionErr     error
+}
+
+func (d *SecretManagerDriverMock) AccessSecretVersion(name string) ([]byte, string, error) {
+	d.AccessSecretVersionName = name
+	return d.AccessSecretVersionResult, d.AccessSecretVersionVersion, d.AccessSecretVersionErr
+}
@@ -0,0 +1,15 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import "testing"
+
+func TestDriverMock_impl(t *testing.T) {
+	var _ Driver = new(DriverMock)
+	var _ ComputeDriver = new(ComputeDriverMock)
+	var _ IAMDriver = new(IAMDriverMock)
+	var _ ImageDriver = new(ImageDriverMock)
+	var _ OSLoginDriver = new(OSLoginDriverMock)
+	var _ StorageDriver = new(StorageDriverMock)
+}
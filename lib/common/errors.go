@@ -0,0 +1,132 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// EnrichedError is an error returned by a Google Cloud API, or by a step
+// talking to an instance, along with a hint describing how to fix it.
+type EnrichedError struct {
+	Err  error
+	Hint string
+}
+
+func (e *EnrichedError) Error() string {
+	return fmt.Sprintf("%s\n\n%s", e.Err, e.Hint)
+}
+
+func (e *EnrichedError) Unwrap() error {
+	return e.Err
+}
+
+type errorHint struct {
+	re   *regexp.Regexp
+	hint func(match []string) string
+}
+
+// errorHints are matched, in order, against the message of the errors passed
+// to EnrichError. The first one to match provides the hint.
+var errorHints = []errorHint{
+	{
+		re: regexp.MustCompile(`The user does not have access to service account '([^']+)'\. User: '([^']+)'`),
+		hint: func(m []string) string {
+			return fmt.Sprintf("%s is not allowed to use the service account %s. "+
+				"Grant it the Service Account User role on that service account:\n\n"+
+				"  gcloud iam service-accounts add-iam-policy-binding %s --member=%s --role=roles/iam.serviceAccountUser",
+				m[2], m[1], m[1], iamMember(m[2]))
+		},
+	},
+	{
+		re: regexp.MustCompile(`Required '([\w.]+)' permission for '([^']+)'`),
+		hint: func(m []string) string {
+			return fmt.Sprintf("The account used by Packer is missing the %s permission on %s. "+
+				"Grant it a role that includes this permission; the roles including a given "+
+				"permission are listed at https://cloud.google.com/iam/docs/permissions-reference.",
+				m[1], m[2])
+		},
+	},
+	{
+		re: regexp.MustCompile(`apis/api/([\w.-]+)/overview\?project=([\w-]+)`),
+		hint: func(m []string) string {
+			return fmt.Sprintf("The %s API is disabled in project %s. Enable it, then wait a few minutes before retrying:\n\n"+
+				"  gcloud services enable %s --project %s",
+				m[1], m[2], m[1], m[2])
+		},
+	},
+	{
+		re: regexp.MustCompile(`The resource 'projects/([^/]+)/global/images/family/([^']+)' was not found`),
+		hint: func(m []string) string {
+			return fmt.Sprintf("No image of the family %s was found in project %s. Check the family name, "+
+				"and set source_image_project_id to the project hosting it (e.g. debian-cloud for Debian images).",
+				m[2], m[1])
+		},
+	},
+	{
+		re: regexp.MustCompile(`The resource 'projects/([^/]+)/global/images/([^']+)' was not found`),
+		hint: func(m []string) string {
+			return fmt.Sprintf("The image %s was not found in project %s. Check the image name, "+
+				"and set source_image_project_id to the project hosting it (e.g. debian-cloud for Debian images).",
+				m[2], m[1])
+		},
+	},
+	{
+		re: regexp.MustCompile(`Request had invalid authentication credentials|Invalid Credentials`),
+		hint: func(m []string) string {
+			return "The credentials used by Packer were rejected. An access_token cannot be refreshed, " +
+				"and stops working once it has expired: mint a new one, or use credentials that can be " +
+				"refreshed, e.g. credentials_file."
+		},
+	},
+	{
+		re: regexp.MustCompile(`Timeout waiting for SSH`),
+		hint: func(m []string) string {
+			return "Packer could not reach the instance over SSH. Make sure a firewall rule on the instance's " +
+				"network allows ingress on TCP port 22, or on the configured ssh_port, from the host running " +
+				"Packer. When use_iap is enabled, the source range to allow is 35.235.240.0/20."
+		},
+	},
+	{
+		re: regexp.MustCompile(`Timeout waiting for WinRM`),
+		hint: func(m []string) string {
+			return "Packer could not reach the instance over WinRM. Make sure a firewall rule on the instance's " +
+				"network allows ingress on TCP port 5986 (5985 without winrm_use_ssl), or on the configured " +
+				"winrm_port, from the host running Packer. When use_iap is enabled, the source range to allow " +
+				"is 35.235.240.0/20."
+		},
+	},
+}
+
+// EnrichError returns err with a hint stating how to fix it when its cause is
+// well-known, e.g. a missing IAM permission. Other errors are returned as-is.
+func EnrichError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var enriched *EnrichedError
+	if errors.As(err, &enriched) {
+		return err
+	}
+
+	msg := err.Error()
+	for _, h := range errorHints {
+		if match := h.re.FindStringSubmatch(msg); match != nil {
+			return &EnrichedError{Err: err, Hint: h.hint(match)}
+		}
+	}
+	return err
+}
+
+// iamMember returns the IAM policy member for an account email.
+func iamMember(email string) string {
+	if strings.HasSuffix(email, ".gserviceaccount.com") {
+		return "serviceAccount:" + email
+	}
+	return "user:" + email
+}
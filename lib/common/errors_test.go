@@ -0,0 +1,91 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/googleapi"
+)
+
+func TestEnrichError(t *testing.T) {
+	cases := []struct {
+		err  error
+		hint string
+	}{
+		{
+			err: &googleapi.Error{
+				Code:    400,
+				Message: "The user does not have access to service account 'builder@project-id.iam.gserviceaccount.com'. User: 'ci@project-id.iam.gserviceaccount.com'. Ask a project owner to grant you the iam.serviceAccountUser role on the service account",
+			},
+			hint: "--member=serviceAccount:ci@project-id.iam.gserviceaccount.com --role=roles/iam.serviceAccountUser",
+		},
+		{
+			err: fmt.Errorf("Error creating instance: %w", &googleapi.Error{
+				Code:    403,
+				Message: "Required 'compute.instances.create' permission for 'projects/project-id/zones/us-east1-a/instances/packer-123'",
+			}),
+			hint: "missing the compute.instances.create permission on projects/project-id/zones/us-east1-a/instances/packer-123",
+		},
+		{
+			err:  errors.New("googleapi: Error 403: Compute Engine API has not been used in project 1234 before or it is disabled. Enable it by visiting https://console.developers.google.com/apis/api/compute.googleapis.com/overview?project=1234 then retry."),
+			hint: "gcloud services enable compute.googleapis.com --project 1234",
+		},
+		{
+			err:  errors.New("googleapi: Error 404: The resource 'projects/project-id/global/images/family/debian-12' was not found, notFound"),
+			hint: "No image of the family debian-12 was found in project project-id",
+		},
+		{
+			err:  errors.New("googleapi: Error 404: The resource 'projects/project-id/global/images/debian-12-bookworm-v20240110' was not found, notFound"),
+			hint: "The image debian-12-bookworm-v20240110 was not found in project project-id",
+		},
+		{
+			err:  errors.New("googleapi: Error 401: Request had invalid authentication credentials. Expected OAuth 2 access token, login cookie or other valid authentication credential., unauthorized"),
+			hint: "An access_token cannot be refreshed",
+		},
+		{
+			err:  errors.New("Timeout waiting for SSH."),
+			hint: "TCP port 22",
+		},
+		{
+			err:  errors.New("Timeout waiting for WinRM."),
+			hint: "TCP port 5986",
+		},
+		{
+			err:  errors.New("something else happened"),
+			hint: "",
+		},
+	}
+
+	for _, tc := range cases {
+		err := EnrichError(tc.err)
+		var enriched *EnrichedError
+		if tc.hint == "" {
+			if errors.As(err, &enriched) {
+				t.Errorf("%q should not have been enriched", tc.err)
+			}
+			continue
+		}
+		if !errors.As(err, &enriched) {
+			t.Errorf("%q should have been enriched", tc.err)
+			continue
+		}
+		if !strings.Contains(err.Error(), tc.hint) {
+			t.Errorf("expected hint %q in %q", tc.hint, err)
+		}
+		if !errors.Is(err, tc.err) {
+			t.Errorf("enriched error should wrap %q", tc.err)
+		}
+		if again := EnrichError(err); again != err {
+			t.Errorf("enriching twice should not add another hint: %q", again)
+		}
+	}
+
+	if EnrichError(nil) != nil {
+		t.Fatal("enriching a nil error should return nil")
+	}
+}
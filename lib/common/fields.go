@@ -0,0 +1,46 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// LookupField returns the field key of the decoded JSON or YAML document
+// doc. Nested fields are separated by dots, e.g. database.password.
+func LookupField(doc interface{}, key string) (interface{}, error) {
+	v := doc
+	for _, field := range strings.Split(key, ".") {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%q is not in an object", field)
+		}
+		if v, ok = obj[field]; !ok {
+			return nil, fmt.Errorf("field %q not found", field)
+		}
+	}
+	return v, nil
+}
+
+// ExtractJSONField returns the field key, separated by dots, of the JSON
+// object data. String fields are returned as is, other ones as JSON.
+func ExtractJSONField(data []byte, key string) (string, error) {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return "", fmt.Errorf("not JSON: %s", err)
+	}
+
+	v, err := LookupField(doc, key)
+	if err != nil {
+		return "", err
+	}
+
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	b, err := json.Marshal(v)
+	return string(b), err
+}
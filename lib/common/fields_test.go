@@ -0,0 +1,39 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import "testing"
+
+func TestLookupField(t *testing.T) {
+	doc := map[string]interface{}{
+		"database": map[string]interface{}{"password": "s3cr3t"},
+		"port":     5432,
+	}
+
+	if v, err := LookupField(doc, "database.password"); err != nil || v != "s3cr3t" {
+		t.Errorf("bad nested field: %v, %v", v, err)
+	}
+	if v, err := LookupField(doc, "port"); err != nil || v != 5432 {
+		t.Errorf("bad field: %v, %v", v, err)
+	}
+	for _, key := range []string{"host", "port.number", "database.user"} {
+		if _, err := LookupField(doc, key); err == nil {
+			t.Errorf("%s: should error", key)
+		}
+	}
+}
+
+func TestExtractJSONField(t *testing.T) {
+	data := []byte(`{"app": {"version": "1.4.2", "ports": [80, 443]}}`)
+
+	if v, err := ExtractJSONField(data, "app.version"); err != nil || v != "1.4.2" {
+		t.Errorf("bad string field: %v, %v", v, err)
+	}
+	if v, err := ExtractJSONField(data, "app.ports"); err != nil || v != "[80,443]" {
+		t.Errorf("bad list field: %v, %v", v, err)
+	}
+	if _, err := ExtractJSONField([]byte("version=1.4.2"), "version"); err == nil {
+		t.Error("should error on non JSON data")
+	}
+}
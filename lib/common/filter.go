@@ -0,0 +1,32 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ListFilter returns a filter of the Compute Engine list calls matching the
+// resources with labels, and the extra expressions exprs, in the syntax of
+// the API. Empty expressions are ignored.
+func ListFilter(labels map[string]string, exprs ...string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, expr := range exprs {
+		if expr != "" {
+			parts = append(parts, fmt.Sprintf("(%s)", expr))
+		}
+	}
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("(labels.%s = %q)", k, labels[k]))
+	}
+	return strings.Join(parts, " ")
+}
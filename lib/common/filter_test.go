@@ -0,0 +1,27 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import "testing"
+
+func TestListFilter(t *testing.T) {
+	cases := []struct {
+		labels   map[string]string
+		exprs    []string
+		expected string
+	}{
+		{nil, nil, ""},
+		{nil, []string{"", `family = "web"`}, `(family = "web")`},
+		{
+			map[string]string{"team": "x", "app": "web"},
+			[]string{`status = "READY"`},
+			`(status = "READY") (labels.app = "web") (labels.team = "x")`,
+		},
+	}
+	for _, tc := range cases {
+		if got := ListFilter(tc.labels, tc.exprs...); got != tc.expected {
+			t.Errorf("ListFilter(%v, %q) = %q, expected %q", tc.labels, tc.exprs, got, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	// GoogleAccessPrivate sends the API calls to the private.googleapis.com
+	// VIP, reachable through Private Google Access.
+	GoogleAccessPrivate = "private"
+	// GoogleAccessRestricted sends the API calls to the
+	// restricted.googleapis.com VIP, which only serves the APIs supported by
+	// VPC Service Controls.
+	GoogleAccessRestricted = "restricted"
+)
+
+// googleAccessVIPs are the addresses of the VIP of each Google access mode.
+var googleAccessVIPs = map[string][]string{
+	GoogleAccessPrivate:    {"199.36.153.8", "199.36.153.9", "199.36.153.10", "199.36.153.11"},
+	GoogleAccessRestricted: {"199.36.153.4", "199.36.153.5", "199.36.153.6", "199.36.153.7"},
+}
+
+// googleAccessDial returns a dial function connecting to the VIP of mode for
+// all the *.googleapis.com hosts, in place of their public addresses. This is
+// what the DNS configuration of a VPC using the VIP does, without depending on
+// it. TLS is still negotiated with the original host name, and the other
+// hosts are dialed as usual.
+func googleAccessDial(mode string) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	vips := googleAccessVIPs[mode]
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || !isGoogleAPIsHost(host) {
+			return dialer.DialContext(ctx, network, addr)
+		}
+
+		// Like a resolver returning several addresses, try them in turn.
+		var conn net.Conn
+		for _, vip := range vips {
+			conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(vip, port))
+			if err == nil {
+				return conn, nil
+			}
+		}
+		return nil, err
+	}
+}
+
+func isGoogleAPIsHost(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	return host == "googleapis.com" || strings.HasSuffix(host, ".googleapis.com")
+}
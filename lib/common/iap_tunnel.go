@@ -0,0 +1,697 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hashicorp/packer-plugin-googlecompute/version"
+	"github.com/hashicorp/packer-plugin-sdk/retry"
+	"github.com/hashicorp/packer-plugin-sdk/useragent"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/transport"
+)
+
+// The IAP TCP forwarding protocol, as implemented by
+// `gcloud compute start-iap-tunnel`: every TCP connection is relayed over a
+// WebSocket, whose binary messages hold frames made of a big endian 16 bits
+// tag followed by the tag's payload.
+const (
+	iapTunnelHost     = "tunnel.cloudproxy.app"
+	iapTunnelProtocol = "relay.tunnel.cloudproxy.app"
+	iapTunnelOrigin   = "bot:iap-tunneler"
+
+	// iapTagConnectSuccessSID is sent by IAP once connected to the
+	// instance, with the 32 bits length prefixed ID of the session.
+	iapTagConnectSuccessSID uint16 = 0x0001
+	// iapTagReconnectSuccessAck is sent by IAP once a session is resumed,
+	// with the 64 bits count of bytes it received.
+	iapTagReconnectSuccessAck uint16 = 0x0002
+	// iapTagData holds 32 bits length prefixed data.
+	iapTagData uint16 = 0x0004
+	// iapTagAck holds the 64 bits count of bytes received.
+	iapTagAck uint16 = 0x0007
+
+	// iapMaxDataFrameSize is the largest data a frame holds.
+	iapMaxDataFrameSize = 16384
+
+	// iapStatsInterval is how often the transfer rates are logged while
+	// connections are forwarded.
+	iapStatsInterval = 30 * time.Second
+)
+
+// IAPTunnelTarget is the port of an instance an IAP tunnel connects to.
+type IAPTunnelTarget struct {
+	Project  string
+	Zone     string
+	Instance string
+	// Interface is the network interface of the instance to connect to.
+	// Defaults to nic0.
+	Interface string
+	Port      int
+}
+
+// IAPTunnelError is the error IAP closes a tunnel connection with.
+type IAPTunnelError struct {
+	Code   int
+	Reason string
+}
+
+func (e *IAPTunnelError) Error() string {
+	return fmt.Sprintf("IAP tunnel closed with code %d: %s", e.Code, e.Reason)
+}
+
+// Temporary is true for the errors which usually go away by themselves,
+// while the instance boots or its IAM permissions propagate.
+func (e *IAPTunnelError) Temporary() bool {
+	switch e.Code {
+	case 4003, // Failed to connect to backend.
+		4033, // Not authorized.
+		4047: // Lookup failed: the instance is not found or not running.
+		return true
+	}
+	return false
+}
+
+// iapTunnel is a TunnelDriver forwarding the TCP connections accepted on a
+// local port to an instance through IAP TCP forwarding.
+type iapTunnel struct {
+	// host is the host of the IAP tunnel endpoint.
+	host        string
+	target      IAPTunnelTarget
+	tokenSource oauth2.TokenSource
+	dialer      *websocket.Dialer
+	userAgent   string
+
+	listener net.Listener
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+
+	// The counters of the tunnel, for diagnostics.
+	started                 time.Time
+	connections, reconnects atomic.Int64
+	sent, received          atomic.Int64
+}
+
+// IAPTunnelStats are the counters of an IAP tunnel, telling whether slow
+// transfers are bound by the tunnel.
+type IAPTunnelStats struct {
+	Connections   int64
+	Reconnects    int64
+	BytesSent     int64
+	BytesReceived int64
+	Duration      time.Duration
+}
+
+func (s IAPTunnelStats) String() string {
+	return fmt.Sprintf("%d connections, %d reconnects, sent %s, received %s in %s",
+		s.Connections, s.Reconnects,
+		transferRate(s.BytesSent, s.Duration), transferRate(s.BytesReceived, s.Duration),
+		s.Duration.Round(time.Second))
+}
+
+// transferRate formats n bytes transferred in d, and their rate.
+func transferRate(n int64, d time.Duration) string {
+	rate := 0.0
+	if d > 0 {
+		rate = float64(n) / d.Seconds()
+	}
+	return fmt.Sprintf("%.1f KiB (%.1f KiB/s)", float64(n)/1024, rate/1024)
+}
+
+// Stats returns the counters of the tunnel since it started.
+func (t *iapTunnel) Stats() IAPTunnelStats {
+	var d time.Duration
+	if !t.started.IsZero() {
+		d = time.Since(t.started)
+	}
+	return IAPTunnelStats{
+		Connections:   t.connections.Load(),
+		Reconnects:    t.reconnects.Load(),
+		BytesSent:     t.sent.Load(),
+		BytesReceived: t.received.Load(),
+		Duration:      d,
+	}
+}
+
+func newIAPTunnel(target IAPTunnelTarget, ts oauth2.TokenSource, proxyURL string) (*iapTunnel, error) {
+	if target.Interface == "" {
+		target.Interface = "nic0"
+	}
+
+	dialer := &websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: 30 * time.Second,
+		Subprotocols:     []string{iapTunnelProtocol},
+		ReadBufferSize:   iapMaxDataFrameSize,
+		WriteBufferSize:  iapMaxDataFrameSize,
+	}
+	if proxyURL != "" {
+		u, err := ParseProxyURL(proxyURL)
+		if err != nil {
+			return nil, err
+		}
+		dialer.Proxy = http.ProxyURL(u)
+	}
+
+	return &iapTunnel{
+		host:        iapTunnelHost,
+		target:      target,
+		tokenSource: ts,
+		dialer:      dialer,
+		userAgent:   useragent.String(version.PluginVersion.FormattedVersion()),
+	}, nil
+}
+
+func (d *driverGCE) NewIAPTunnel(target IAPTunnelTarget, impersonateServiceAccount string) (TunnelDriver, error) {
+	ts, err := iapTokenSource(d.config, transportClient(d.config.ProxyURL, ""), impersonateServiceAccount)
+	if err != nil {
+		return nil, err
+	}
+	return newIAPTunnel(target, ts, d.config.ProxyURL)
+}
+
+// iapTokenSource returns the token source authenticating the IAP tunnel: the
+// credentials of the driver, or the impersonateServiceAccount impersonated
+// with them, whether they come from Vault, an impersonation chain or a key.
+// The tokens are requested with httpClient when set.
+func iapTokenSource(config GCEDriverConfig, httpClient *http.Client, impersonateServiceAccount string) (oauth2.TokenSource, error) {
+	ctx := context.Background()
+	if httpClient != nil {
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, httpClient)
+	}
+	authConfig := config.clientOptionGoogleConfig()
+	authConfig.HTTPClient = httpClient
+	opts, err := NewClientOptionGoogleWithConfig(authConfig)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := transport.Creds(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if impersonateServiceAccount == "" {
+		return creds.TokenSource, nil
+	}
+
+	log.Printf("[INFO] Using the IAP tunnel as %s", impersonateServiceAccount)
+	opts, err = NewClientOptionGoogleWithConfig(ClientOptionGoogleConfig{
+		ImpersonateServiceAccountName: impersonateServiceAccount,
+		Credentials:                   &google.Credentials{TokenSource: creds.TokenSource},
+		Scopes:                        []string{CloudPlatformScope},
+		HTTPClient:                    httpClient,
+	})
+	if err != nil {
+		return nil, err
+	}
+	creds, err = transport.Creds(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return creds.TokenSource, nil
+}
+
+// StartTunnel checks that the tunnel connects to the instance within
+// timeout, like gcloud does, so that errors are reported before any
+// connection is forwarded, and starts listening on localhost:localPort.
+func (t *iapTunnel) StartTunnel(ctx context.Context, localPort int, timeout time.Duration) error {
+	if timeout > 0 {
+		connectCtx, cancel := context.WithTimeout(ctx, timeout)
+		conn, err := t.connect(connectCtx)
+		cancel()
+		if err != nil {
+			return err
+		}
+		conn.Close()
+	}
+
+	l, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", localPort))
+	if err != nil {
+		return fmt.Errorf("Error listening on port %d for the IAP tunnel: %s", localPort, err)
+	}
+	log.Printf("IAP tunnel to %s:%d listening on %s", t.target.Instance, t.target.Port, l.Addr())
+
+	serveCtx, serveCancel := context.WithCancel(context.Background())
+	t.listener, t.cancel, t.started = l, serveCancel, time.Now()
+	t.wg.Add(2)
+	go t.serve(serveCtx)
+	go t.logStats(serveCtx)
+	return nil
+}
+
+// StopTunnel stops listening, closes the forwarded connections and waits
+// for them to be done.
+func (t *iapTunnel) StopTunnel() {
+	if t.listener == nil {
+		return
+	}
+	log.Printf("Cleaning up the IAP tunnel...")
+	t.cancel()
+	t.listener.Close()
+	t.wg.Wait()
+	log.Printf("IAP tunnel to %s:%d stopped: %s", t.target.Instance, t.target.Port, t.Stats())
+}
+
+// logStats logs the transfer rates of the tunnel over the last interval,
+// while it transfers data.
+func (t *iapTunnel) logStats(ctx context.Context) {
+	defer t.wg.Done()
+	ticker := time.NewTicker(iapStatsInterval)
+	defer ticker.Stop()
+
+	var last IAPTunnelStats
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		stats := t.Stats()
+		sent, received := stats.BytesSent-last.BytesSent, stats.BytesReceived-last.BytesReceived
+		if sent > 0 || received > 0 {
+			log.Printf("[DEBUG] IAP tunnel to %s:%d: sent %s, received %s in the last %s",
+				t.target.Instance, t.target.Port,
+				transferRate(sent, iapStatsInterval), transferRate(received, iapStatsInterval), iapStatsInterval)
+		}
+		last = stats
+	}
+}
+
+func (t *iapTunnel) serve(ctx context.Context) {
+	defer t.wg.Done()
+	for {
+		local, err := t.listener.Accept()
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("[ERROR] IAP tunnel stopped accepting connections: %s", err)
+			}
+			return
+		}
+		t.wg.Add(1)
+		go func() {
+			defer t.wg.Done()
+			t.forward(ctx, local)
+		}()
+	}
+}
+
+// forward relays local to the instance until either side closes.
+func (t *iapTunnel) forward(ctx context.Context, local net.Conn) {
+	defer local.Close()
+
+	conn, err := t.connect(ctx)
+	if err != nil {
+		log.Printf("[ERROR] IAP tunnel failed to connect: %s", err)
+		return
+	}
+	defer conn.Close()
+
+	t.connections.Add(1)
+	opened := time.Now()
+	log.Printf("[DEBUG] IAP tunnel connection %s opened for %s", conn.sid, local.RemoteAddr())
+	defer func() {
+		d := time.Since(opened)
+		sent, received, reconnects := conn.stats()
+		log.Printf("[DEBUG] IAP tunnel connection %s closed: %d reconnects, sent %s, received %s in %s",
+			conn.sid, reconnects, transferRate(sent, d), transferRate(received, d), d.Round(time.Millisecond))
+	}()
+
+	errc := make(chan error, 2)
+	go func() { errc <- conn.copyFrom(local) }()
+	go func() { errc <- conn.copyTo(local) }()
+
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+	}
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+		log.Printf("[WARN] IAP tunnel connection closed: %s", err)
+	}
+}
+
+// dial opens a WebSocket to the IAP tunnel endpoint at path.
+func (t *iapTunnel) dial(ctx context.Context, path string, query url.Values) (*websocket.Conn, error) {
+	query.Set("newWebsocket", "true")
+	u := url.URL{Scheme: "wss", Host: t.host, Path: path, RawQuery: query.Encode()}
+
+	// The token is refreshed as needed, e.g. when reconnecting after it
+	// expired.
+	token, err := t.tokenSource.Token()
+	if err != nil {
+		return nil, fmt.Errorf("Error getting a token for the IAP tunnel: %s", err)
+	}
+	header := http.Header{
+		"Authorization": {token.Type() + " " + token.AccessToken},
+		"Origin":        {iapTunnelOrigin},
+		"User-Agent":    {t.userAgent},
+	}
+
+	ws, resp, err := t.dialer.DialContext(ctx, u.String(), header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("Error connecting the IAP tunnel: %s: %s", resp.Status, err)
+		}
+		return nil, fmt.Errorf("Error connecting the IAP tunnel: %s", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = ws.SetReadDeadline(deadline)
+	}
+	return ws, nil
+}
+
+// connect opens a tunnel connection to the instance, and waits for IAP to
+// connect it to the instance.
+func (t *iapTunnel) connect(ctx context.Context) (*iapConn, error) {
+	ws, err := t.dial(ctx, "/v4/connect", url.Values{
+		"project":   {t.target.Project},
+		"zone":      {t.target.Zone},
+		"instance":  {t.target.Instance},
+		"interface": {t.target.Interface},
+		"port":      {strconv.Itoa(t.target.Port)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &iapConn{ctx: ctx, tunnel: t, ws: ws}
+	for conn.sid == "" {
+		tag, payload, err := readFrame(ws)
+		if err != nil {
+			ws.Close()
+			return nil, err
+		}
+		if tag != iapTagConnectSuccessSID {
+			log.Printf("[DEBUG] IAP tunnel: ignoring frame %#04x before connection", tag)
+			continue
+		}
+		sid, err := lengthPrefixed(payload)
+		if err != nil {
+			ws.Close()
+			return nil, err
+		}
+		conn.sid = string(sid)
+	}
+	_ = ws.SetReadDeadline(time.Time{})
+	return conn, nil
+}
+
+// reconnect resumes the session sid, telling IAP that received bytes were
+// received, and returns the count of bytes IAP received.
+func (t *iapTunnel) reconnect(ctx context.Context, sid string, received uint64) (*websocket.Conn, uint64, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.dialer.HandshakeTimeout)
+	defer cancel()
+
+	ws, err := t.dial(ctx, "/v4/reconnect", url.Values{
+		"sid":  {sid},
+		"ack":  {strconv.FormatUint(received, 10)},
+		"zone": {t.target.Zone},
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for {
+		tag, payload, err := readFrame(ws)
+		if err != nil {
+			ws.Close()
+			return nil, 0, err
+		}
+		if tag != iapTagReconnectSuccessAck {
+			log.Printf("[DEBUG] IAP tunnel: ignoring frame %#04x before reconnection", tag)
+			continue
+		}
+		if len(payload) < 8 {
+			ws.Close()
+			return nil, 0, fmt.Errorf("IAP tunnel frame too short")
+		}
+		_ = ws.SetReadDeadline(time.Time{})
+		return ws, binary.BigEndian.Uint64(payload), nil
+	}
+}
+
+// iapReconnectable tells whether a tunnel connection lost with err can be
+// resumed: when the network failed, or IAP asks to.
+func iapReconnectable(err error) bool {
+	var tunnelErr *IAPTunnelError
+	if errors.As(err, &tunnelErr) {
+		switch tunnelErr.Code {
+		case websocket.CloseGoingAway,
+			websocket.CloseAbnormalClosure,
+			4004: // Reauthentication required, e.g. when the token expired.
+			return true
+		}
+		return false
+	}
+	return !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed)
+}
+
+// iapConn is a tunnel connection, relaying one TCP connection. When the
+// WebSocket drops, the session is resumed on a new one, and the data IAP did
+// not acknowledge is sent again, so that the relayed connection survives.
+type iapConn struct {
+	ctx    context.Context
+	tunnel *iapTunnel
+	// sid is the ID of the session, sent by IAP once connected.
+	sid string
+
+	// mu guards the fields below, and serializes the writes as the
+	// WebSocket supports a single concurrent writer.
+	mu sync.Mutex
+	ws *websocket.Conn
+	// received is the count of bytes received, and acked the count of
+	// those acknowledged to IAP.
+	received, acked uint64
+	// unacked is the data sent that IAP did not acknowledge yet, and
+	// sentAcked the count of bytes it acknowledged.
+	unacked   []byte
+	sentAcked uint64
+	closed    bool
+	// reconnects is the count of times the session was resumed.
+	reconnects int64
+}
+
+// readFrame reads the next frame sent by IAP on ws.
+func readFrame(ws *websocket.Conn) (uint16, []byte, error) {
+	_, msg, err := ws.ReadMessage()
+	if err != nil {
+		var closeErr *websocket.CloseError
+		if errors.As(err, &closeErr) {
+			if closeErr.Code == websocket.CloseNormalClosure {
+				return 0, nil, io.EOF
+			}
+			return 0, nil, &IAPTunnelError{Code: closeErr.Code, Reason: closeErr.Text}
+		}
+		return 0, nil, err
+	}
+	if len(msg) < 2 {
+		return 0, nil, fmt.Errorf("IAP tunnel frame too short: %d bytes", len(msg))
+	}
+	return binary.BigEndian.Uint16(msg), msg[2:], nil
+}
+
+// lengthPrefixed returns the 32 bits length prefixed bytes of payload.
+func lengthPrefixed(payload []byte) ([]byte, error) {
+	if len(payload) < 4 {
+		return nil, fmt.Errorf("IAP tunnel frame too short")
+	}
+	n := binary.BigEndian.Uint32(payload)
+	if uint64(len(payload)-4) < uint64(n) {
+		return nil, fmt.Errorf("IAP tunnel frame truncated: %d bytes out of %d", len(payload)-4, n)
+	}
+	return payload[4 : 4+n], nil
+}
+
+// stats returns the count of bytes sent and received, and of reconnects.
+func (c *iapConn) stats() (int64, int64, int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return int64(c.sentAcked) + int64(len(c.unacked)), int64(c.received), c.reconnects
+}
+
+// current returns the current WebSocket of the connection.
+func (c *iapConn) current() *websocket.Conn {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ws
+}
+
+// copyTo writes the data received from the instance to w.
+func (c *iapConn) copyTo(w io.Writer) error {
+	for {
+		ws := c.current()
+		tag, payload, err := readFrame(ws)
+		if err != nil {
+			if err := c.resume(ws, err); err != nil {
+				return err
+			}
+			continue
+		}
+		switch tag {
+		case iapTagData:
+			data, err := lengthPrefixed(payload)
+			if err != nil {
+				return err
+			}
+			if _, err := w.Write(data); err != nil {
+				return err
+			}
+			c.tunnel.received.Add(int64(len(data)))
+			if err := c.ackReceived(ws, len(data)); err != nil {
+				return err
+			}
+		case iapTagAck:
+			if len(payload) < 8 {
+				return fmt.Errorf("IAP tunnel frame too short")
+			}
+			c.ackSent(binary.BigEndian.Uint64(payload))
+		default:
+			log.Printf("[DEBUG] IAP tunnel: ignoring unknown frame %#04x", tag)
+		}
+	}
+}
+
+// ackReceived counts n more bytes received on ws, and acknowledges them in
+// batches, like gcloud does.
+func (c *iapConn) ackReceived(ws *websocket.Conn, n int) error {
+	c.mu.Lock()
+	c.received += uint64(n)
+	if c.received-c.acked <= 2*iapMaxDataFrameSize || c.ws != ws {
+		c.mu.Unlock()
+		return nil
+	}
+	frame := make([]byte, 10)
+	binary.BigEndian.PutUint16(frame, iapTagAck)
+	binary.BigEndian.PutUint64(frame[2:], c.received)
+	err := ws.WriteMessage(websocket.BinaryMessage, frame)
+	if err == nil {
+		c.acked = c.received
+	}
+	c.mu.Unlock()
+
+	if err != nil {
+		return c.resume(ws, err)
+	}
+	return nil
+}
+
+// ackSent drops the data IAP acknowledged, up to sent bytes.
+func (c *iapConn) ackSent(sent uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if sent <= c.sentAcked || sent-c.sentAcked > uint64(len(c.unacked)) {
+		return
+	}
+	c.unacked = c.unacked[sent-c.sentAcked:]
+	c.sentAcked = sent
+}
+
+// copyFrom sends the data read from r to the instance.
+func (c *iapConn) copyFrom(r io.Reader) error {
+	buf := make([]byte, iapMaxDataFrameSize)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			c.tunnel.sent.Add(int64(n))
+			c.mu.Lock()
+			c.unacked = append(c.unacked, buf[:n]...)
+			ws := c.ws
+			werr := writeData(ws, buf[:n])
+			c.mu.Unlock()
+			// The data is sent again once the session is resumed.
+			if werr != nil {
+				if err := c.resume(ws, werr); err != nil {
+					return err
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func writeData(ws *websocket.Conn, data []byte) error {
+	frame := make([]byte, 6+len(data))
+	binary.BigEndian.PutUint16(frame, iapTagData)
+	binary.BigEndian.PutUint32(frame[2:], uint32(len(data)))
+	copy(frame[6:], data)
+	return ws.WriteMessage(websocket.BinaryMessage, frame)
+}
+
+// resume replaces failed, which was lost with err, by a WebSocket resuming
+// the session, unless the other direction of the connection already did.
+// Only the errors which cannot be recovered from are returned.
+func (c *iapConn) resume(failed *websocket.Conn, err error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ws != failed {
+		return nil
+	}
+	if c.closed || !iapReconnectable(err) {
+		return err
+	}
+	failed.Close()
+
+	log.Printf("[WARN] IAP tunnel connection lost, reconnecting: %s", err)
+	return retry.Config{
+		Tries: 5,
+		ShouldRetry: func(err error) bool {
+			var tunnelErr *IAPTunnelError
+			return !errors.As(err, &tunnelErr) || tunnelErr.Temporary() || iapReconnectable(err)
+		},
+		RetryDelay: (&retry.Backoff{InitialBackoff: time.Second, MaxBackoff: 15 * time.Second, Multiplier: 2}).Linear,
+	}.Run(c.ctx, func(ctx context.Context) error {
+		ws, sent, err := c.tunnel.reconnect(ctx, c.sid, c.received)
+		if err != nil {
+			log.Printf("[WARN] IAP tunnel failed to reconnect: %s", err)
+			return err
+		}
+		if sent < c.sentAcked || sent-c.sentAcked > uint64(len(c.unacked)) {
+			ws.Close()
+			return fmt.Errorf("IAP tunnel acknowledged %d bytes out of %d sent", sent, c.sentAcked+uint64(len(c.unacked)))
+		}
+		c.unacked = c.unacked[sent-c.sentAcked:]
+		c.sentAcked = sent
+		for data := c.unacked; len(data) > 0; {
+			n := len(data)
+			if n > iapMaxDataFrameSize {
+				n = iapMaxDataFrameSize
+			}
+			if err := writeData(ws, data[:n]); err != nil {
+				ws.Close()
+				return err
+			}
+			data = data[n:]
+		}
+		c.ws, c.acked = ws, c.received
+		c.reconnects++
+		c.tunnel.reconnects.Add(1)
+		log.Printf("IAP tunnel connection %s reconnected, resending %d bytes", c.sid, len(c.unacked))
+		return nil
+	})
+}
+
+// Close closes the connection, telling IAP it is done.
+func (c *iapConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	_ = c.ws.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+	return c.ws.Close()
+}
@@ -0,0 +1,258 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/oauth2"
+)
+
+// fakeIAP is an IAP TCP forwarding endpoint echoing the data it receives,
+// or closing the connections with closeCode when set. With drop, the first
+// WebSocket is dropped after the first data frame, for the session to be
+// resumed.
+func fakeIAP(t *testing.T, closeCode int, drop bool) *httptest.Server {
+	upgrader := websocket.Upgrader{
+		Subprotocols: []string{iapTunnelProtocol},
+		CheckOrigin:  func(r *http.Request) bool { return true },
+	}
+
+	// The state of the session: the count of bytes received, and the data
+	// echoed.
+	var mu sync.Mutex
+	var received uint64
+	var echoed []byte
+
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reconnect := r.URL.Path == "/v4/reconnect"
+		switch {
+		case reconnect:
+			if r.URL.Query().Get("sid") != "session-1" || r.URL.Query().Get("zone") != "us-central1-a" {
+				t.Errorf("bad request: %s", r.URL)
+			}
+		case r.URL.Path != "/v4/connect" || r.URL.Query().Get("instance") != "packer-12345" || r.URL.Query().Get("port") != "22":
+			t.Errorf("bad request: %s", r.URL)
+		}
+		if r.Header.Get("Authorization") != "Bearer ya29.token" || r.Header.Get("Origin") != iapTunnelOrigin {
+			t.Errorf("bad headers: %v", r.Header)
+		}
+
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %s", err)
+			return
+		}
+		defer ws.Close()
+
+		if closeCode != 0 {
+			msg := websocket.FormatCloseMessage(closeCode, "failed to connect to backend")
+			_ = ws.WriteMessage(websocket.CloseMessage, msg)
+			return
+		}
+
+		mu.Lock()
+		if reconnect {
+			frame := make([]byte, 10)
+			binary.BigEndian.PutUint16(frame, iapTagReconnectSuccessAck)
+			binary.BigEndian.PutUint64(frame[2:], received)
+			_ = ws.WriteMessage(websocket.BinaryMessage, frame)
+			// Send again what the client did not receive.
+			ack, _ := strconv.ParseUint(r.URL.Query().Get("ack"), 10, 64)
+			if ack < uint64(len(echoed)) {
+				_ = ws.WriteMessage(websocket.BinaryMessage, dataFrame(echoed[ack:]))
+			}
+		} else {
+			received, echoed = 0, nil
+			sid := []byte("session-1")
+			frame := make([]byte, 6+len(sid))
+			binary.BigEndian.PutUint16(frame, iapTagConnectSuccessSID)
+			binary.BigEndian.PutUint32(frame[2:], uint32(len(sid)))
+			copy(frame[6:], sid)
+			_ = ws.WriteMessage(websocket.BinaryMessage, frame)
+		}
+		mu.Unlock()
+
+		for {
+			_, msg, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			if binary.BigEndian.Uint16(msg) != iapTagData {
+				continue
+			}
+			data := msg[6:]
+
+			mu.Lock()
+			received += uint64(len(data))
+			echoed = append(echoed, data...)
+			mu.Unlock()
+			if err := ws.WriteMessage(websocket.BinaryMessage, msg); err != nil {
+				return
+			}
+
+			if drop && !reconnect {
+				// Drop the connection without closing the WebSocket.
+				ws.UnderlyingConn().Close()
+				return
+			}
+		}
+	}))
+}
+
+func dataFrame(data []byte) []byte {
+	frame := make([]byte, 6+len(data))
+	binary.BigEndian.PutUint16(frame, iapTagData)
+	binary.BigEndian.PutUint32(frame[2:], uint32(len(data)))
+	copy(frame[6:], data)
+	return frame
+}
+
+func testIAPTunnel(t *testing.T, server *httptest.Server) *iapTunnel {
+	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "ya29.token", TokenType: "Bearer"})
+	tunnel, err := newIAPTunnel(IAPTunnelTarget{Project: "project", Zone: "us-central1-a", Instance: "packer-12345", Port: 22}, ts, "")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	tunnel.host = strings.TrimPrefix(server.URL, "https://")
+	tunnel.dialer.TLSClientConfig = server.Client().Transport.(*http.Transport).TLSClientConfig
+	return tunnel
+}
+
+func TestIAPTunnel(t *testing.T) {
+	server := fakeIAP(t, 0, false)
+	defer server.Close()
+
+	tunnel := testIAPTunnel(t, server)
+	if err := tunnel.StartTunnel(context.Background(), 0, 5*time.Second); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer tunnel.StopTunnel()
+
+	conn, err := net.Dial("tcp", tunnel.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer conn.Close()
+
+	// Larger than a frame, to be split.
+	data := []byte(strings.Repeat("SSH-2.0-packer\r\n", 2*iapMaxDataFrameSize/16))
+	go func() { _, _ = conn.Write(data) }()
+
+	received := make([]byte, len(data))
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.ReadFull(conn, received); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(received) != string(data) {
+		t.Error("the data should go through the tunnel")
+	}
+
+	stats := tunnel.Stats()
+	if stats.Connections != 1 || stats.BytesSent != int64(len(data)) || stats.BytesReceived != int64(len(data)) {
+		t.Errorf("bad stats: %s", stats)
+	}
+}
+
+func TestIAPTunnel_closed(t *testing.T) {
+	server := fakeIAP(t, 4003, false)
+	defer server.Close()
+
+	tunnel := testIAPTunnel(t, server)
+	err := tunnel.StartTunnel(context.Background(), 0, 5*time.Second)
+
+	var tunnelErr *IAPTunnelError
+	if !errors.As(err, &tunnelErr) {
+		t.Fatalf("should fail with an IAPTunnelError, got %v", err)
+	}
+	if tunnelErr.Code != 4003 || !tunnelErr.Temporary() {
+		t.Errorf("bad error: %#v", tunnelErr)
+	}
+	if tunnel.listener != nil {
+		t.Error("should not listen when the tunnel cannot connect")
+	}
+}
+
+func TestIAPTunnel_reconnect(t *testing.T) {
+	server := fakeIAP(t, 0, true)
+	defer server.Close()
+
+	tunnel := testIAPTunnel(t, server)
+	if err := tunnel.StartTunnel(context.Background(), 0, 5*time.Second); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer tunnel.StopTunnel()
+
+	conn, err := net.Dial("tcp", tunnel.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer conn.Close()
+
+	// Larger than a frame, to be split.
+	data := []byte(strings.Repeat("SSH-2.0-packer\r\n", 2*iapMaxDataFrameSize/16))
+	go func() { _, _ = conn.Write(data) }()
+
+	received := make([]byte, len(data))
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.ReadFull(conn, received); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(received) != string(data) {
+		t.Error("the data should go through the tunnel once reconnected")
+	}
+	if stats := tunnel.Stats(); stats.Reconnects == 0 {
+		t.Errorf("the reconnection should be counted: %s", stats)
+	}
+}
+
+func TestIAPTokenSource_impersonate(t *testing.T) {
+	// Each service account gets a token named after it, requested with the
+	// token of the previous account of the chain.
+	authorizations := map[string]string{}
+	client := &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		account := strings.TrimSuffix(path.Base(r.URL.Path), ":generateAccessToken")
+		authorizations[account] = r.Header.Get("Authorization")
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"accessToken": "ya29.` + account + `", "expireTime": "2100-01-01T00:00:00Z"}`)),
+			Request:    r,
+		}, nil
+	})}
+
+	ts, err := iapTokenSource(GCEDriverConfig{
+		ImpersonateServiceAccountName: "packer@my-project.iam.gserviceaccount.com",
+		AccessToken:                   "ya29.token",
+	}, client, "iap-tunnel@my-project.iam.gserviceaccount.com")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	token, err := ts.Token()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if token.AccessToken != "ya29.iap-tunnel@my-project.iam.gserviceaccount.com" {
+		t.Errorf("the tunnel account token should be used, got %q", token.AccessToken)
+	}
+	if v := authorizations["iap-tunnel@my-project.iam.gserviceaccount.com"]; v != "Bearer ya29.packer@my-project.iam.gserviceaccount.com" {
+		t.Errorf("the tunnel account should be impersonated by the build account, got %q", v)
+	}
+	if v := authorizations["packer@my-project.iam.gserviceaccount.com"]; v != "Bearer ya29.token" {
+		t.Errorf("the build account should be impersonated with the access token, got %q", v)
+	}
+}
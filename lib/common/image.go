@@ -4,19 +4,41 @@
 package common
 
 import (
+	"regexp"
 	"strings"
 
 	compute "google.golang.org/api/compute/v1"
 )
 
+// ValidImageName matches valid image names and image families.
+var ValidImageName = regexp.MustCompile(`^[a-z]([-a-z0-9]{0,61}[a-z0-9])?$`)
+
 type Image struct {
-	GuestOsFeatures []*compute.GuestOsFeature
-	Labels          map[string]string
-	Licenses        []string
-	Name            string
-	ProjectId       string
-	SelfLink        string
-	SizeGb          int64
+	Deprecation *compute.DeprecationStatus
+	// EncryptionKeySha256 is the SHA-256 of the customer-supplied key
+	// encrypting the image, if any, encoded in RFC 4648 base64.
+	EncryptionKeySha256 string
+	Family              string
+	GuestOsFeatures     []*compute.GuestOsFeature
+	Id                  uint64
+	Labels              map[string]string
+	Licenses            []string
+	Name                string
+	ProjectId           string
+	SelfLink            string
+	SizeGb              int64
+	// Status is the status of the image, e.g. PENDING or READY.
+	Status           string
+	StorageLocations []string
+}
+
+// DeprecationState returns the deprecation state of the image, e.g.
+// DEPRECATED, or ACTIVE when it is not deprecated.
+func (i *Image) DeprecationState() string {
+	if i.Deprecation == nil || i.Deprecation.State == "" {
+		return "ACTIVE"
+	}
+	return i.Deprecation.State
 }
 
 func (i *Image) IsWindows() bool {
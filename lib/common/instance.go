@@ -4,9 +4,12 @@
 package common
 
 type InstanceConfig struct {
-	AcceleratorType              string
-	AcceleratorCount             int64
-	Address                      string
+	AcceleratorType  string
+	AcceleratorCount int64
+	Address          string
+	// BulkInsert, if set, inserts the instance along with the identical
+	// instances of the other builds of the run.
+	BulkInsert                   *BulkInsertCoordinator
 	Description                  string
 	DisableDefaultServiceAccount bool
 	DiskName                     string
@@ -29,11 +32,20 @@ type InstanceConfig struct {
 	OmitExternalIP               bool
 	OnHostMaintenance            string
 	Preemptible                  bool
+	ProvisioningModel            string
 	NodeAffinities               []NodeAffinity
 	Region                       string
 	ServiceAccountEmail          string
 	Scopes                       []string
-	Subnetwork                   string
-	Tags                         []string
-	Zone                         string
+	// SourceImageEncryptionKey is the customer-supplied key decrypting
+	// Image, if it is encrypted with one.
+	SourceImageEncryptionKey *CustomerEncryptionKey
+	// SourceSnapshot, if set, is the snapshot the boot disk is created
+	// from instead of Image.
+	SourceSnapshot string
+	Subnetwork     string
+	Tags           []string
+	// WarmPool, if set, is the key of the warm pool the instance joins.
+	WarmPool string
+	Zone     string
 }
@@ -0,0 +1,37 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"sort"
+
+	compute "google.golang.org/api/compute/v1"
+)
+
+// mergeMetadataItems returns the metadata items with the values of
+// metadata, replacing the items with the same keys. The other items are
+// kept in their order, and the new ones are added sorted by key.
+func mergeMetadataItems(items []*compute.MetadataItems, metadata map[string]string) []*compute.MetadataItems {
+	merged := make([]*compute.MetadataItems, 0, len(items)+len(metadata))
+	for _, item := range items {
+		if item == nil {
+			continue
+		}
+		if _, ok := metadata[item.Key]; ok {
+			continue
+		}
+		merged = append(merged, item)
+	}
+
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := metadata[k]
+		merged = append(merged, &compute.MetadataItems{Key: k, Value: &v})
+	}
+	return merged
+}
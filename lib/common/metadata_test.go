@@ -0,0 +1,50 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"testing"
+
+	compute "google.golang.org/api/compute/v1"
+)
+
+func TestMergeMetadataItems(t *testing.T) {
+	value := func(v string) *string { return &v }
+	items := []*compute.MetadataItems{
+		{Key: "startup-script", Value: value("echo hello")},
+		{Key: "ssh-keys", Value: value("old:key")},
+		nil,
+	}
+
+	merged := mergeMetadataItems(items, map[string]string{
+		"ssh-keys":     "packer:key",
+		"windows-keys": "{}",
+	})
+
+	got := map[string]string{}
+	var order []string
+	for _, item := range merged {
+		if _, dup := got[item.Key]; dup {
+			t.Fatalf("duplicate key %q", item.Key)
+		}
+		got[item.Key] = *item.Value
+		order = append(order, item.Key)
+	}
+	want := map[string]string{
+		"startup-script": "echo hello",
+		"ssh-keys":       "packer:key",
+		"windows-keys":   "{}",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("bad items: %v", got)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("bad %s: %q, expected %q", k, got[k], v)
+		}
+	}
+	if order[0] != "startup-script" || order[1] != "ssh-keys" || order[2] != "windows-keys" {
+		t.Errorf("bad order: %v", order)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"path"
+	"sync"
+
+	compute "google.golang.org/api/compute/v1"
+)
+
+// Operation describes a Compute Engine operation the driver waited for,
+// e.g. to quote it when reaching Google support.
+type Operation struct {
+	// Id is the unique identifier of the operation.
+	Id uint64 `json:"id"`
+	// Name is the name of the operation, to get it with the API.
+	Name string `json:"name"`
+	// Type is the type of the operation, e.g. insert.
+	Type string `json:"type"`
+	// Target is the URL of the resource the operation modifies.
+	Target string `json:"target"`
+	// Scope is the zone or the region of the operation, or "global".
+	Scope string `json:"scope"`
+	// InsertTime is the RFC3339 time the operation was requested at.
+	InsertTime string `json:"insert_time,omitempty"`
+}
+
+// operationLog records the operations of a driver; it is safe for
+// concurrent use.
+type operationLog struct {
+	mu         sync.Mutex
+	operations []Operation
+}
+
+func (l *operationLog) record(scope string, op *compute.Operation) {
+	if op == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.operations = append(l.operations, Operation{
+		Id:         op.Id,
+		Name:       op.Name,
+		Type:       op.OperationType,
+		Target:     op.TargetLink,
+		Scope:      path.Base(scope),
+		InsertTime: op.InsertTime,
+	})
+}
+
+// list returns a copy of the recorded operations, in the order they were
+// waited for.
+func (l *operationLog) list() []Operation {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Operation(nil), l.operations...)
+}
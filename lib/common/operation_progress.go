@@ -0,0 +1,38 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"fmt"
+	"path"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	compute "google.golang.org/api/compute/v1"
+)
+
+// operationProgress reports the progress of a long-running operation through
+// the UI while waiting for it, so that multi-minute waits are not silent.
+// Only changes are reported, and operations that have not reported any
+// progress yet are not, to keep quick operations quiet.
+type operationProgress struct {
+	ui   packersdk.Ui
+	last string
+}
+
+func (p *operationProgress) report(op *compute.Operation) {
+	if p.ui == nil || op.Status == "DONE" || (op.Progress == 0 && op.StatusMessage == "") {
+		return
+	}
+
+	msg := fmt.Sprintf("Operation %s on %s: %d%% done", op.OperationType, path.Base(op.TargetLink), op.Progress)
+	if op.StatusMessage != "" {
+		msg = fmt.Sprintf("%s (%s)", msg, op.StatusMessage)
+	}
+	if msg == p.last {
+		return
+	}
+
+	p.last = msg
+	p.ui.Say(msg)
+}
@@ -0,0 +1,48 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	compute "google.golang.org/api/compute/v1"
+)
+
+func TestOperationProgress(t *testing.T) {
+	ui := &packersdk.MockUi{}
+	p := &operationProgress{ui: ui}
+
+	op := &compute.Operation{
+		OperationType: "insert",
+		TargetLink:    "https://www.googleapis.com/compute/v1/projects/p/global/images/packer-123",
+		Status:        "RUNNING",
+	}
+	p.report(op)
+	if ui.SayCalled {
+		t.Fatalf("operation without progress should not be reported: %#v", ui.SayMessages)
+	}
+
+	op.Progress = 40
+	p.report(op)
+	p.report(op)
+	op.StatusMessage = "Copying disk"
+	p.report(op)
+	op.Status = "DONE"
+	op.Progress = 100
+	p.report(op)
+
+	expected := []string{
+		"Operation insert on packer-123: 40% done",
+		"Operation insert on packer-123: 40% done (Copying disk)",
+	}
+	if len(ui.SayMessages) != len(expected) {
+		t.Fatalf("expected %d messages, got %#v", len(expected), ui.SayMessages)
+	}
+	for i, msg := range expected {
+		if ui.SayMessages[i].Message != msg {
+			t.Errorf("expected %q, got %q", msg, ui.SayMessages[i].Message)
+		}
+	}
+}
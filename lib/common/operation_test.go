@@ -0,0 +1,30 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"testing"
+
+	compute "google.golang.org/api/compute/v1"
+)
+
+func TestOperationLog(t *testing.T) {
+	var l operationLog
+	l.record("us-central1-a", &compute.Operation{Id: 1, Name: "operation-1", OperationType: "insert"})
+	l.record("global", &compute.Operation{Id: 2, Name: "operation-2", OperationType: "delete"})
+	l.record("global", nil)
+
+	ops := l.list()
+	if len(ops) != 2 {
+		t.Fatalf("expected 2 operations, got %d", len(ops))
+	}
+	if ops[0].Scope != "us-central1-a" || ops[0].Type != "insert" || ops[1].Id != 2 {
+		t.Errorf("unexpected operations: %v", ops)
+	}
+
+	ops[0].Name = "changed"
+	if l.list()[0].Name != "operation-1" {
+		t.Errorf("list should return a copy")
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"google.golang.org/api/googleapi"
+	htransport "google.golang.org/api/transport/http"
+)
+
+// The Go client library does not cover Parameter Manager yet, its REST API is
+// called directly.
+
+// Parameter is a Parameter Manager parameter.
+type Parameter struct {
+	Name string `json:"name"`
+	// Format is the format of the versions of the parameter, one of
+	// UNFORMATTED, YAML or JSON.
+	Format string `json:"format"`
+}
+
+// ParameterVersion is a version of a Parameter Manager parameter.
+type ParameterVersion struct {
+	Name       string `json:"name"`
+	CreateTime string `json:"createTime"`
+	Disabled   bool   `json:"disabled"`
+}
+
+// parameterEndpoint returns the endpoint serving name, the one of its
+// location for regional parameters.
+func parameterEndpoint(name string) string {
+	if parts := strings.Split(name, "/"); len(parts) > 3 && parts[2] == "locations" && parts[3] != "global" {
+		return fmt.Sprintf("https://parametermanager.%s.rep.googleapis.com/v1/", parts[3])
+	}
+	return "https://parametermanager.googleapis.com/v1/"
+}
+
+// getParameterResource gets the resource at path, relative to the endpoint of
+// name, and decodes it into v.
+func (d *driverGCE) getParameterResource(name, path string, v interface{}) error {
+	client, _, err := htransport.NewClient(context.TODO(), d.clientOpts...)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Get(parameterEndpoint(name) + path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := googleapi.CheckResponse(resp); err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s getting %s", resp.Status, path)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (d *driverGCE) GetParameter(name string) (*Parameter, error) {
+	parameter := &Parameter{}
+	if err := d.getParameterResource(name, name, parameter); err != nil {
+		return nil, err
+	}
+	if parameter.Format == "" {
+		parameter.Format = "UNFORMATTED"
+	}
+	return parameter, nil
+}
+
+func (d *driverGCE) ListParameterVersions(parameter string) ([]*ParameterVersion, error) {
+	var versions []*ParameterVersion
+	pageToken := ""
+	for {
+		var page struct {
+			ParameterVersions []*ParameterVersion `json:"parameterVersions"`
+			NextPageToken     string              `json:"nextPageToken"`
+		}
+		path := fmt.Sprintf("%s/versions?pageToken=%s", parameter, url.QueryEscape(pageToken))
+		if err := d.getParameterResource(parameter, path, &page); err != nil {
+			return nil, err
+		}
+		versions = append(versions, page.ParameterVersions...)
+		if page.NextPageToken == "" {
+			return versions, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+func (d *driverGCE) RenderParameterVersion(name string) ([]byte, error) {
+	var resp struct {
+		RenderedPayload string `json:"renderedPayload"`
+	}
+	if err := d.getParameterResource(name, name+":render", &resp); err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(resp.RenderedPayload)
+	if err != nil {
+		return nil, fmt.Errorf("Error decoding the payload of parameter version %s: %s", name, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,77 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// PermissionRequirement is a set of IAM permissions needed by a feature of
+// the build, e.g. creating the image.
+type PermissionRequirement struct {
+	Feature     string
+	Permissions []string
+}
+
+// CheckPermissions tests the permissions needed by reqs with test, which
+// returns the subset of the permissions granted, e.g.
+// IAMDriver.TestProjectPermissions. It prints a pass/fail table of the
+// results on resource, and returns an error listing the missing permissions.
+func CheckPermissions(ui packersdk.Ui, resource string, reqs []PermissionRequirement, test func([]string) ([]string, error)) error {
+	var permissions []string
+	seen := map[string]bool{}
+	for _, req := range reqs {
+		for _, p := range req.Permissions {
+			if !seen[p] {
+				seen[p] = true
+				permissions = append(permissions, p)
+			}
+		}
+	}
+	if len(permissions) == 0 {
+		return nil
+	}
+	sort.Strings(permissions)
+
+	granted, err := test(permissions)
+	if err != nil {
+		return fmt.Errorf("Error testing the permissions on %s: %w", resource, err)
+	}
+	isGranted := map[string]bool{}
+	for _, p := range granted {
+		isGranted[p] = true
+	}
+
+	var table bytes.Buffer
+	w := tabwriter.NewWriter(&table, 0, 4, 2, ' ', 0)
+	var missing []string
+	for _, req := range reqs {
+		for _, p := range req.Permissions {
+			result := "PASS"
+			if !isGranted[p] {
+				result = "FAIL"
+				if seen[p] {
+					seen[p] = false
+					missing = append(missing, p)
+				}
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", result, req.Feature, p)
+		}
+	}
+	w.Flush()
+
+	ui.Say(fmt.Sprintf("Permissions on %s:\n%s", resource, strings.TrimRight(table.String(), "\n")))
+
+	if len(missing) > 0 {
+		return fmt.Errorf("The account used by Packer is missing permissions on %s: %s",
+			resource, strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestCheckPermissions(t *testing.T) {
+	reqs := []PermissionRequirement{
+		{Feature: "instance", Permissions: []string{"compute.instances.create", "compute.instances.get"}},
+		{Feature: "IAP tunnel", Permissions: []string{"iap.tunnelInstances.accessViaIAP", "compute.instances.get"}},
+	}
+
+	var tested []string
+	ui := &packersdk.MockUi{}
+	err := CheckPermissions(ui, "project foo", reqs, func(permissions []string) ([]string, error) {
+		tested = permissions
+		return []string{"compute.instances.create", "compute.instances.get"}, nil
+	})
+
+	expected := []string{"compute.instances.create", "compute.instances.get", "iap.tunnelInstances.accessViaIAP"}
+	if !reflect.DeepEqual(tested, expected) {
+		t.Errorf("each permission should be tested once, got %v", tested)
+	}
+	if err == nil || !strings.HasSuffix(err.Error(), ": iap.tunnelInstances.accessViaIAP") {
+		t.Fatalf("expected the missing permission to be reported, got %v", err)
+	}
+
+	if len(ui.SayMessages) != 1 {
+		t.Fatalf("expected a single table, got %#v", ui.SayMessages)
+	}
+	lines := strings.Split(ui.SayMessages[0].Message, "\n")
+	if len(lines) != 5 {
+		t.Fatalf("expected one line per feature and permission, got:\n%s", ui.SayMessages[0].Message)
+	}
+	if !strings.Contains(lines[3], "FAIL") || !strings.Contains(lines[3], "IAP tunnel") {
+		t.Errorf("expected the IAP permission to fail, got %q", lines[3])
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Default bounds of the interval between two polls of a long-running
+// operation.
+const (
+	DefaultPollMinInterval = 2 * time.Second
+	DefaultPollMaxInterval = 30 * time.Second
+)
+
+// pollBackoff computes the delay between two polls of a long-running
+// operation. The delay doubles after each poll, from min up to max, and is
+// randomized so that concurrent builds do not poll in lockstep.
+//
+// A pollBackoff is not thread safe, and must not be shared between waits.
+type pollBackoff struct {
+	min  time.Duration
+	max  time.Duration
+	next time.Duration
+}
+
+func newPollBackoff(min, max time.Duration) *pollBackoff {
+	if min <= 0 {
+		min = DefaultPollMinInterval
+	}
+	if max < min {
+		max = min
+	}
+	return &pollBackoff{min: min, max: max, next: min}
+}
+
+// Delay returns the time to wait before the next poll.
+func (b *pollBackoff) Delay() time.Duration {
+	d := b.next
+	b.next *= 2
+	if b.next > b.max {
+		b.next = b.max
+	}
+
+	// Pick a random delay in [d/2, d], but never poll more often than min.
+	d = d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+	if d < b.min {
+		d = b.min
+	}
+	return d
+}
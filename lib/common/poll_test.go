@@ -0,0 +1,43 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPollBackoff(t *testing.T) {
+	min, max := 2*time.Second, 30*time.Second
+	b := newPollBackoff(min, max)
+
+	var total time.Duration
+	for i := 0; i < 100; i++ {
+		d := b.Delay()
+		if d < min || d > max {
+			t.Fatalf("delay #%d out of [%s, %s]: %s", i, min, max, d)
+		}
+		total += d
+	}
+
+	// Once the ceiling is reached, delays are in [max/2, max].
+	if total < 100*max/2-max {
+		t.Fatalf("delays should have grown up to the ceiling, total was %s", total)
+	}
+}
+
+func TestPollBackoff_bounds(t *testing.T) {
+	b := newPollBackoff(0, 0)
+	if b.min != DefaultPollMinInterval {
+		t.Errorf("min should default to %s, got %s", DefaultPollMinInterval, b.min)
+	}
+	if b.max != b.min {
+		t.Errorf("max should be at least min, got %s", b.max)
+	}
+	for i := 0; i < 10; i++ {
+		if d := b.Delay(); d != DefaultPollMinInterval {
+			t.Fatalf("delay should be fixed when min equals max, got %s", d)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/oauth2"
+)
+
+// ParseProxyURL parses the URL of an HTTP, HTTPS or SOCKS5 proxy.
+func ParseProxyURL(proxyURL string) (*url.URL, error) {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy_url: %s", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("invalid proxy_url %q: the scheme must be one of http, https or socks5", proxyURL)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid proxy_url %q: missing host", proxyURL)
+	}
+	return u, nil
+}
+
+// newTransport returns the HTTP transport used to call the Google APIs. By
+// default, the proxy is taken from the HTTPS_PROXY, HTTP_PROXY and NO_PROXY
+// environment variables, like http.DefaultTransport does; proxyURL
+// overrides the first two. googleAccess, when set, sends the calls to the
+// matching VIP.
+func newTransport(proxyURL, googleAccess string) http.RoundTripper {
+	trans := http.DefaultTransport.(*http.Transport).Clone()
+
+	if proxyURL != "" {
+		cfg := httpproxy.FromEnvironment()
+		cfg.HTTPProxy, cfg.HTTPSProxy = proxyURL, proxyURL
+		proxy := cfg.ProxyFunc()
+		trans.Proxy = func(req *http.Request) (*url.URL, error) {
+			return proxy(req.URL)
+		}
+	}
+
+	if googleAccess != "" {
+		trans.DialContext = googleAccessDial(googleAccess)
+	}
+
+	return trans
+}
+
+// transportContext returns a context making the OAuth2 token requests go
+// through newTransport, like the API calls, when proxyURL or googleAccess
+// is set.
+func transportContext(proxyURL, googleAccess string) context.Context {
+	ctx := context.Background()
+	client := transportClient(proxyURL, googleAccess)
+	if client == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, oauth2.HTTPClient, client)
+}
+
+// transportClient returns a client going through newTransport when proxyURL
+// or googleAccess is set, nil otherwise.
+func transportClient(proxyURL, googleAccess string) *http.Client {
+	if proxyURL == "" && googleAccess == "" {
+		return nil
+	}
+	return &http.Client{Transport: newTransport(proxyURL, googleAccess)}
+}
+
+// ProxyHostPort returns the host and port of a proxy URL, defaulting the port
+// after its scheme.
+func ProxyHostPort(u *url.URL) (string, string) {
+	port := u.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443", "socks5": "1080"}[u.Scheme]
+	}
+	return u.Hostname(), port
+}
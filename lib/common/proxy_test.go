@@ -0,0 +1,52 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseProxyURL(t *testing.T) {
+	for proxyURL, valid := range map[string]bool{
+		"http://proxy.example.com:3128":   true,
+		"https://proxy.example.com":       true,
+		"socks5://proxy.example.com:1080": true,
+		"ftp://proxy.example.com":         false,
+		"proxy.example.com:3128":          false,
+		"http://":                         false,
+	} {
+		_, err := ParseProxyURL(proxyURL)
+		if valid && err != nil {
+			t.Errorf("%q should be accepted: %s", proxyURL, err)
+		}
+		if !valid && err == nil {
+			t.Errorf("%q should be rejected", proxyURL)
+		}
+	}
+}
+
+func TestNewTransport_proxyURL(t *testing.T) {
+	// An HTTP proxy answering every request itself.
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer proxy.Close()
+
+	t.Setenv("NO_PROXY", "")
+	t.Setenv("no_proxy", "")
+	client := &http.Client{Transport: newTransport(proxy.URL, "")}
+	resp, err := client.Get("http://compute.googleapis.com/compute/v1/projects/foo")
+	if err != nil {
+		t.Fatalf("request failed: %s", err)
+	}
+	resp.Body.Close()
+
+	if proxied != "http://compute.googleapis.com/compute/v1/projects/foo" {
+		t.Errorf("the request should have gone through the proxy, got %q", proxied)
+	}
+}
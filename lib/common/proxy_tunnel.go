@@ -0,0 +1,191 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// proxyTunnel forwards the connections to a local port to an address,
+// through an HTTP proxy supporting the CONNECT method.
+type proxyTunnel struct {
+	proxyURL *url.URL
+	address  string
+
+	listener net.Listener
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// NewProxyTunnel returns a tunnel to address through the HTTP or HTTPS
+// proxy at proxyURL, for the clients which cannot go through an HTTP proxy
+// themselves, like the SSH communicator.
+func NewProxyTunnel(proxyURL *url.URL, address string) TunnelDriver {
+	return &proxyTunnel{proxyURL: proxyURL, address: address}
+}
+
+// StartTunnel checks that the proxy connects to the address within timeout,
+// unless timeout is zero, and starts listening on localhost:localPort.
+func (t *proxyTunnel) StartTunnel(ctx context.Context, localPort int, timeout time.Duration) error {
+	if timeout > 0 {
+		connectCtx, cancel := context.WithTimeout(ctx, timeout)
+		conn, err := t.connect(connectCtx)
+		cancel()
+		if err != nil {
+			return err
+		}
+		conn.Close()
+	}
+
+	l, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", localPort))
+	if err != nil {
+		return fmt.Errorf("Error listening on port %d for the proxy tunnel: %s", localPort, err)
+	}
+	log.Printf("Proxy tunnel to %s listening on %s", t.address, l.Addr())
+
+	serveCtx, serveCancel := context.WithCancel(context.Background())
+	t.listener, t.cancel = l, serveCancel
+	t.wg.Add(1)
+	go t.serve(serveCtx)
+	return nil
+}
+
+// StopTunnel stops listening, closes the forwarded connections and waits
+// for them to be done.
+func (t *proxyTunnel) StopTunnel() {
+	if t.listener == nil {
+		return
+	}
+	log.Printf("Cleaning up the proxy tunnel...")
+	t.cancel()
+	t.listener.Close()
+	t.wg.Wait()
+}
+
+func (t *proxyTunnel) serve(ctx context.Context) {
+	defer t.wg.Done()
+	for {
+		local, err := t.listener.Accept()
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("[ERROR] Proxy tunnel stopped accepting connections: %s", err)
+			}
+			return
+		}
+		t.wg.Add(1)
+		go func() {
+			defer t.wg.Done()
+			t.forward(ctx, local)
+		}()
+	}
+}
+
+// forward relays local to the address until either side closes.
+func (t *proxyTunnel) forward(ctx context.Context, local net.Conn) {
+	defer local.Close()
+
+	remote, err := t.connect(ctx)
+	if err != nil {
+		log.Printf("[ERROR] Proxy tunnel failed to connect: %s", err)
+		return
+	}
+	defer remote.Close()
+
+	errc := make(chan error, 2)
+	go func() {
+		_, err := io.Copy(remote, local)
+		errc <- err
+	}()
+	go func() {
+		_, err := io.Copy(local, remote)
+		errc <- err
+	}()
+
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+	}
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		log.Printf("[WARN] Proxy tunnel connection closed: %s", err)
+	}
+}
+
+// connect opens a connection to the address through the proxy.
+func (t *proxyTunnel) connect(ctx context.Context) (net.Conn, error) {
+	host, port := ProxyHostPort(t.proxyURL)
+	proxyAddress := net.JoinHostPort(host, port)
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", proxyAddress)
+	if err != nil {
+		return nil, fmt.Errorf("Error connecting to the proxy: %s", err)
+	}
+	if t.proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: host})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("Error connecting to the proxy: %s", err)
+		}
+		conn = tlsConn
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: t.address},
+		Host:   t.address,
+		Header: http.Header{},
+	}
+	if t.proxyURL.User != nil {
+		password, _ := t.proxyURL.User.Password()
+		credentials := t.proxyURL.User.Username() + ":" + password
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(credentials)))
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("Error connecting through the proxy: %s", err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("Error connecting through the proxy: %s", err)
+	}
+	// The body of a successful response is the tunnel itself, so it is not
+	// closed.
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("Error connecting through the proxy to %s: %s", t.address, resp.Status)
+	}
+
+	_ = conn.SetDeadline(time.Time{})
+	// The server may speak first, e.g. the SSH banner, so keep what was
+	// read past the response.
+	return &bufferedConn{Conn: conn, r: br}, nil
+}
+
+// bufferedConn is a net.Conn reading through a bufio.Reader.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
@@ -0,0 +1,121 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeConnectProxy is an HTTP proxy relaying CONNECT requests.
+func fakeConnectProxy(t *testing.T, auth string) *url.URL {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "CONNECT only", http.StatusMethodNotAllowed)
+			return
+		}
+		if r.Header.Get("Proxy-Authorization") != auth {
+			http.Error(w, "bad credentials", http.StatusProxyAuthRequired)
+			return
+		}
+		remote, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer remote.Close()
+		w.WriteHeader(http.StatusOK)
+		local, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer local.Close()
+		go func() {
+			// Relay what the client sent along with the request first.
+			buffered, _ := buf.Peek(buf.Reader.Buffered())
+			_, _ = remote.Write(buffered)
+			_, _ = io.Copy(remote, local)
+		}()
+		_, _ = io.Copy(local, remote)
+	}))
+	t.Cleanup(server.Close)
+	u, _ := url.Parse(server.URL)
+	return u
+}
+
+// bannerServer greets the clients first, like an SSH server, then echoes
+// a line.
+func bannerServer(t *testing.T) string {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				fmt.Fprintf(conn, "SSH-2.0-fake\r\n")
+				line, _ := bufio.NewReader(conn).ReadString('\n')
+				fmt.Fprint(conn, line)
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+func TestProxyTunnel(t *testing.T) {
+	proxyURL := fakeConnectProxy(t, "Basic cGFja2VyOnNlY3JldA==")
+	proxyURL.User = url.UserPassword("packer", "secret")
+	tunnel := NewProxyTunnel(proxyURL, bannerServer(t))
+
+	l, _ := net.Listen("tcp", "localhost:0")
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	if err := tunnel.StartTunnel(context.Background(), port, 5*time.Second); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer tunnel.StopTunnel()
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	if banner, _ := r.ReadString('\n'); banner != "SSH-2.0-fake\r\n" {
+		t.Errorf("bad banner: %q", banner)
+	}
+	fmt.Fprint(conn, "hello\n")
+	if line, _ := r.ReadString('\n'); line != "hello\n" {
+		t.Errorf("bad echo: %q", line)
+	}
+}
+
+func TestProxyTunnel_error(t *testing.T) {
+	proxyURL := fakeConnectProxy(t, "Basic cGFja2VyOnNlY3JldA==")
+	tunnel := NewProxyTunnel(proxyURL, bannerServer(t))
+
+	err := tunnel.StartTunnel(context.Background(), 0, 5*time.Second)
+	if err == nil {
+		t.Fatal("should fail without the proxy credentials")
+	}
+	if want := "407 Proxy Authentication Required"; !strings.Contains(err.Error(), want) {
+		t.Errorf("the error should contain %q, got %s", want, err)
+	}
+}
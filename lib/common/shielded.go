@@ -0,0 +1,75 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+
+	compute "google.golang.org/api/compute/v1"
+)
+
+// FileContentBuffer reads a Secure Boot key or certificate file. X.509
+// certificates, PEM or DER encoded, are sent as such, other files as binary
+// data, e.g. EFI signature lists.
+func FileContentBuffer(certOrKeyFile string) (*compute.FileContentBuffer, error) {
+	data, err := os.ReadFile(certOrKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read Certificate or Key file %s", certOrKeyFile)
+	}
+	shield := &compute.FileContentBuffer{
+		Content:  base64.StdEncoding.EncodeToString(data),
+		FileType: "X509",
+	}
+	block, _ := pem.Decode(data)
+
+	if block == nil || block.Type != "CERTIFICATE" {
+		_, err = x509.ParseCertificate(data)
+	} else {
+		_, err = x509.ParseCertificate(block.Bytes)
+	}
+	if err != nil {
+		shield.FileType = "BIN"
+	}
+	return shield, nil
+}
+
+// ShieldedInitialState returns the Secure Boot keys an image is created
+// with: its platform key, key exchange keys, and allowed and forbidden
+// signature databases, read from files. It returns nil when none is set, so
+// that the image gets the default Secure Boot keys.
+func ShieldedInitialState(platformKey string, keyExchangeKeys, signaturesDB, forbiddenSignaturesDB []string) (*compute.InitialStateConfig, error) {
+	if platformKey == "" && len(keyExchangeKeys) == 0 && len(signaturesDB) == 0 && len(forbiddenSignaturesDB) == 0 {
+		return nil, nil
+	}
+
+	state := &compute.InitialStateConfig{}
+	if platformKey != "" {
+		pk, err := FileContentBuffer(platformKey)
+		if err != nil {
+			return nil, err
+		}
+		state.Pk = pk
+	}
+	for _, files := range []struct {
+		paths []string
+		dst   *[]*compute.FileContentBuffer
+	}{
+		{keyExchangeKeys, &state.Keks},
+		{signaturesDB, &state.Dbs},
+		{forbiddenSignaturesDB, &state.Dbxs},
+	} {
+		for _, path := range files.paths {
+			buf, err := FileContentBuffer(path)
+			if err != nil {
+				return nil, err
+			}
+			*files.dst = append(*files.dst, buf)
+		}
+	}
+	return state, nil
+}
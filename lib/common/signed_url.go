@@ -0,0 +1,86 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	gcs "cloud.google.com/go/storage"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/iamcredentials/v1"
+	"google.golang.org/api/option"
+)
+
+// MaxSignedURLDuration is the longest validity of a V4 signed URL.
+const MaxSignedURLDuration = 7 * 24 * time.Hour
+
+// urlSigner signs the URLs of Cloud Storage objects, as the service account
+// of the storage credentials: with its key when they are a service account
+// key file, or else with the IAM Credentials API.
+type urlSigner struct {
+	// email is the service account signing the URLs by default: the one of
+	// the key file, or the impersonated one.
+	email string
+	// privateKey is the key of email, when known.
+	privateKey []byte
+
+	iamService *iamcredentials.Service
+}
+
+func newURLSigner(config GCEDriverConfig, opts []option.ClientOption) (*urlSigner, error) {
+	s := &urlSigner{email: config.ImpersonateServiceAccountName}
+	if s.email == "" && config.Credentials != nil && len(config.Credentials.JSON) > 0 {
+		// Only service account key files hold a private key, other
+		// credentials fail to parse.
+		if jwt, err := google.JWTConfigFromJSON(config.Credentials.JSON); err == nil {
+			s.email, s.privateKey = jwt.Email, jwt.PrivateKey
+		}
+	}
+
+	var err error
+	s.iamService, err = iamcredentials.NewService(context.TODO(), opts...)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// signURL returns a V4 signed URL to GET object in bucket for expires,
+// signed by serviceAccount, or by default by the service account of the
+// credentials.
+func (s *urlSigner) signURL(bucket, object string, expires time.Duration, serviceAccount string) (string, error) {
+	opts := &gcs.SignedURLOptions{
+		GoogleAccessID: s.email,
+		Method:         "GET",
+		Expires:        time.Now().Add(expires),
+		Scheme:         gcs.SigningSchemeV4,
+	}
+
+	switch {
+	case serviceAccount == "" && s.privateKey != nil:
+		opts.PrivateKey = s.privateKey
+	case serviceAccount == "" && s.email == "":
+		return "", fmt.Errorf("cannot sign the URL of gs://%s/%s: the credentials are not a service account, "+
+			"set the service account signing the URLs", bucket, object)
+	default:
+		if serviceAccount != "" {
+			opts.GoogleAccessID = serviceAccount
+		}
+		name := "projects/-/serviceAccounts/" + opts.GoogleAccessID
+		opts.SignBytes = func(b []byte) ([]byte, error) {
+			resp, err := s.iamService.Projects.ServiceAccounts.SignBlob(name, &iamcredentials.SignBlobRequest{
+				Payload: base64.StdEncoding.EncodeToString(b),
+			}).Do()
+			if err != nil {
+				return nil, err
+			}
+			return base64.StdEncoding.DecodeString(resp.SignedBlob)
+		}
+	}
+
+	return gcs.SignedURL(bucket, object, opts)
+}
@@ -0,0 +1,70 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/option"
+)
+
+func TestURLSigner_privateKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	keyFile, _ := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "packer@my-project.iam.gserviceaccount.com",
+		"private_key":  string(keyPEM),
+		"token_uri":    "https://oauth2.googleapis.com/token",
+	})
+
+	s, err := newURLSigner(GCEDriverConfig{
+		Credentials: &google.Credentials{JSON: keyFile},
+	}, []option.ClientOption{option.WithoutAuthentication()})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	signed, err := s.signURL("my-bucket", "path/to/disk.tar.gz", time.Hour, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatalf("bad URL %s: %s", signed, err)
+	}
+	if !strings.HasSuffix(u.Path, "/my-bucket/path/to/disk.tar.gz") {
+		t.Errorf("bad path: %s", u.Path)
+	}
+	q := u.Query()
+	if !strings.HasPrefix(q.Get("X-Goog-Credential"), "packer@my-project.iam.gserviceaccount.com/") {
+		t.Errorf("the URL should be signed by the key file account, got %s", q.Get("X-Goog-Credential"))
+	}
+	if expires, _ := strconv.Atoi(q.Get("X-Goog-Expires")); expires < 3590 || expires > 3600 {
+		t.Errorf("bad expiration: %s", q.Get("X-Goog-Expires"))
+	}
+}
+
+func TestURLSigner_noServiceAccount(t *testing.T) {
+	s, err := newURLSigner(GCEDriverConfig{AccessToken: "ya29.token"}, []option.ClientOption{option.WithoutAuthentication()})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := s.signURL("my-bucket", "disk.tar.gz", time.Hour, ""); err == nil {
+		t.Error("signing without a service account should fail")
+	}
+}
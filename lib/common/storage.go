@@ -0,0 +1,26 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/api/storage/v1"
+)
+
+// ParseGCSPath splits a gs://bucket/object path.
+func ParseGCSPath(path string) (string, string, error) {
+	parts := strings.SplitN(strings.TrimPrefix(path, "gs://"), "/", 2)
+	if !strings.HasPrefix(path, "gs://") || len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid GCS path %q, expected gs://bucket/object", path)
+	}
+	return parts[0], parts[1], nil
+}
+
+// IsEncryptedWith returns whether obj is encrypted with a version of the
+// kmsKeyName key.
+func IsEncryptedWith(obj *storage.Object, kmsKeyName string) bool {
+	return obj.KmsKeyName == kmsKeyName || strings.HasPrefix(obj.KmsKeyName, kmsKeyName+"/cryptoKeyVersions/")
+}
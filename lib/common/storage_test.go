@@ -0,0 +1,22 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import "testing"
+
+func TestParseGCSPath(t *testing.T) {
+	bucket, object, err := ParseGCSPath("gs://mybucket/path/to/disk.tar.gz")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if bucket != "mybucket" || object != "path/to/disk.tar.gz" {
+		t.Errorf("bad bucket %q or object %q", bucket, object)
+	}
+
+	for _, path := range []string{"mybucket/disk.tar.gz", "gs://mybucket", "gs:///disk.tar.gz"} {
+		if _, _, err := ParseGCSPath(path); err == nil {
+			t.Errorf("%s should be rejected", path)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// tokenRefreshMargin is how long before its expiry a token is refreshed, so
+// that a token handed to a long API call, e.g. an image creation, doesn't
+// expire while the call is in flight.
+const tokenRefreshMargin = 5 * time.Minute
+
+// refreshingTokenSource caches the tokens of src, and refreshes them
+// tokenRefreshMargin before they expire. It is safe for concurrent use, and
+// meant to be shared by all the API clients of a driver, so that they all
+// see the same token and a build lasting longer than a token's lifetime
+// keeps working. The margin only applies if src mints a new token on each
+// call: the sources of the Google libraries cache theirs until 10s before
+// they expire, and are wrapped with uncachedTokenSource.
+type refreshingTokenSource struct {
+	src    oauth2.TokenSource
+	margin time.Duration
+	now    func() time.Time
+
+	mu    sync.Mutex
+	token *oauth2.Token
+}
+
+func newRefreshingTokenSource(src oauth2.TokenSource) *refreshingTokenSource {
+	return &refreshingTokenSource{
+		src:    src,
+		margin: tokenRefreshMargin,
+		now:    time.Now,
+	}
+}
+
+func (s *refreshingTokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != nil && !s.expiresSoon(s.token) {
+		return s.token, nil
+	}
+
+	token, err := s.src.Token()
+	if err != nil {
+		// A failed refresh is not fatal while the current token is valid,
+		// it is retried on the next call.
+		if s.token != nil && s.now().Before(s.token.Expiry) {
+			log.Printf("[WARN] Failed to refresh the access token, using the current one until it expires at %s: %s", s.token.Expiry, err)
+			return s.token, nil
+		}
+		return nil, err
+	}
+
+	s.token = token
+	return token, nil
+}
+
+// expiresSoon reports whether the token expires within the refresh margin.
+// Tokens without an expiry never do.
+func (s *refreshingTokenSource) expiresSoon(token *oauth2.Token) bool {
+	if token.Expiry.IsZero() {
+		return false
+	}
+	return !s.now().Add(s.margin).Before(token.Expiry)
+}
+
+// uncachedTokenSource gets a new token from a new token source on each call,
+// for the sources caching their tokens to be refreshed by
+// refreshingTokenSource.
+type uncachedTokenSource func() (oauth2.TokenSource, error)
+
+func (f uncachedTokenSource) Token() (*oauth2.Token, error) {
+	ts, err := f()
+	if err != nil {
+		return nil, err
+	}
+	return ts.Token()
+}
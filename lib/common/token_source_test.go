@@ -0,0 +1,158 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// fakeTokenSource mints a new token, valid for lifetime, on each call.
+type fakeTokenSource struct {
+	now      func() time.Time
+	lifetime time.Duration
+	err      error
+	calls    int
+}
+
+func (f *fakeTokenSource) Token() (*oauth2.Token, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &oauth2.Token{
+		AccessToken: fmt.Sprintf("token-%d", f.calls),
+		Expiry:      f.now().Add(f.lifetime),
+	}, nil
+}
+
+func TestRefreshingTokenSource(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	src := &fakeTokenSource{now: clock, lifetime: time.Hour}
+	ts := newRefreshingTokenSource(src)
+	ts.now = clock
+
+	first, err := ts.Token()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// A 3h build: the token is reused while valid, and refreshed before it
+	// expires, so no call is ever made with an expired token.
+	for i := 0; i < 180; i++ {
+		now = now.Add(time.Minute)
+		token, err := ts.Token()
+		if err != nil {
+			t.Fatalf("unexpected error after %d minutes: %s", i+1, err)
+		}
+		if !now.Add(tokenRefreshMargin).Before(token.Expiry) {
+			t.Fatalf("token expiring at %s handed out at %s", token.Expiry, now)
+		}
+	}
+
+	if src.calls < 3 || src.calls > 4 {
+		t.Errorf("expected the token to be refreshed about once an hour, got %d calls", src.calls)
+	}
+	if first.AccessToken != "token-1" {
+		t.Errorf("unexpected first token %q", first.AccessToken)
+	}
+}
+
+func TestRefreshingTokenSource_refreshFailure(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	src := &fakeTokenSource{now: clock, lifetime: time.Hour}
+	ts := newRefreshingTokenSource(src)
+	ts.now = clock
+
+	if _, err := ts.Token(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Within the refresh margin, a failed refresh falls back to the current,
+	// still valid, token.
+	src.err = errors.New("token endpoint unavailable")
+	now = now.Add(time.Hour - time.Minute)
+	token, err := ts.Token()
+	if err != nil {
+		t.Fatalf("a valid token should be returned when refreshing fails: %s", err)
+	}
+	if token.AccessToken != "token-1" {
+		t.Errorf("expected the current token, got %q", token.AccessToken)
+	}
+
+	// Once it has expired, the error is returned.
+	now = now.Add(2 * time.Minute)
+	if _, err := ts.Token(); err == nil {
+		t.Fatal("an expired token should not be returned")
+	}
+
+	// And the next successful refresh recovers.
+	src.err = nil
+	if _, err := ts.Token(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestRefreshingTokenSource_noExpiry(t *testing.T) {
+	src := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "static"})
+	ts := newRefreshingTokenSource(src)
+	for i := 0; i < 3; i++ {
+		token, err := ts.Token()
+		if err != nil || token.AccessToken != "static" {
+			t.Fatalf("unexpected token %v, err %v", token, err)
+		}
+	}
+}
+
+func TestRefreshingTokenSource_concurrent(t *testing.T) {
+	src := &fakeTokenSource{now: time.Now, lifetime: time.Hour}
+	ts := newRefreshingTokenSource(src)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := ts.Token(); err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if src.calls != 1 {
+		t.Errorf("concurrent callers should share a single token, got %d calls", src.calls)
+	}
+}
+
+func TestRefreshingTokenSource_cachingSource(t *testing.T) {
+	// Like the sources of the Google libraries, the source caches its
+	// tokens until 10s before they expire.
+	src := &fakeTokenSource{now: time.Now, lifetime: time.Hour}
+	ts := newRefreshingTokenSource(uncachedTokenSource(func() (oauth2.TokenSource, error) {
+		return oauth2.ReuseTokenSource(nil, src), nil
+	}))
+
+	first, err := ts.Token()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Within the refresh margin, a new token is minted.
+	ts.now = func() time.Time { return time.Now().Add(time.Hour - tokenRefreshMargin + time.Minute) }
+	token, err := ts.Token()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if token.AccessToken == first.AccessToken {
+		t.Errorf("the token should be refreshed %s before it expires", tokenRefreshMargin)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofrs/uuid"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/retry"
+	compute "google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+)
+
+// TransientErrorTries is the number of times a request, or a step, failing
+// with a transient error is tried.
+const TransientErrorTries = 3
+
+// OperationError is an error of a Compute Engine operation.
+type OperationError struct {
+	Code    string
+	Message string
+}
+
+func (e *OperationError) Error() string {
+	return e.Message
+}
+
+// operationErrors returns the errors of the operation, or nil.
+func operationErrors(op *compute.Operation) error {
+	if op.Error == nil {
+		return nil
+	}
+	var err error
+	for _, e := range op.Error.Errors {
+		err = packersdk.MultiErrorAppend(err, &OperationError{Code: e.Code, Message: e.Message})
+	}
+	return err
+}
+
+// IsTransientError returns whether err is an internal error of the Compute
+// Engine API, which is worth trying again.
+func IsTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var multi *packersdk.MultiError
+	if errors.As(err, &multi) {
+		if len(multi.Errors) == 0 {
+			return false
+		}
+		for _, err := range multi.Errors {
+			if !IsTransientError(err) {
+				return false
+			}
+		}
+		return true
+	}
+
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code >= 500
+	}
+	var opErr *OperationError
+	if errors.As(err, &opErr) {
+		return opErr.Code == "INTERNAL_ERROR"
+	}
+	return false
+}
+
+// doOperation does the request starting an operation, trying it again on
+// transient errors. The request is always made with the same request ID,
+// so that Compute Engine does it only once even if it failed to answer.
+func doOperation(request func(requestId string) (*compute.Operation, error)) (*compute.Operation, error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	var op *compute.Operation
+	err = retry.Config{
+		Tries:       TransientErrorTries,
+		ShouldRetry: IsTransientError,
+		RetryDelay:  (&retry.Backoff{InitialBackoff: 2 * time.Second, MaxBackoff: 30 * time.Second, Multiplier: 2}).Linear,
+	}.Run(context.TODO(), func(context.Context) error {
+		var err error
+		op, err = request(id.String())
+		return err
+	})
+	return op, err
+}
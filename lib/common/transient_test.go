@@ -0,0 +1,85 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	compute "google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+)
+
+func TestIsTransientError(t *testing.T) {
+	internal := &OperationError{Code: "INTERNAL_ERROR", Message: "Internal error."}
+	quota := &OperationError{Code: "QUOTA_EXCEEDED", Message: "Quota exceeded."}
+
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"plain", errors.New("failed"), false},
+		{"500", &googleapi.Error{Code: 500}, true},
+		{"503 wrapped", fmt.Errorf("Error creating image: %w", &googleapi.Error{Code: 503}), true},
+		{"404", &googleapi.Error{Code: 404}, false},
+		{"enriched", EnrichError(&googleapi.Error{Code: 502}), true},
+		{"internal operation error", packersdk.MultiErrorAppend(nil, internal), true},
+		{"operation errors", packersdk.MultiErrorAppend(nil, internal, quota), false},
+		{"empty multi error", &packersdk.MultiError{}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsTransientError(tc.err); got != tc.want {
+				t.Errorf("IsTransientError(%v) = %t, expected %t", tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestOperationErrors(t *testing.T) {
+	if err := operationErrors(&compute.Operation{}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	err := operationErrors(&compute.Operation{Error: &compute.OperationError{
+		Errors: []*compute.OperationErrorErrors{{Code: "INTERNAL_ERROR", Message: "Internal error."}},
+	}})
+	var opErr *OperationError
+	if !errors.As(err.(*packersdk.MultiError).Errors[0], &opErr) || opErr.Code != "INTERNAL_ERROR" {
+		t.Fatalf("bad error: %#v", err)
+	}
+}
+
+func TestDoOperation(t *testing.T) {
+	var requestIds []string
+	op, err := doOperation(func(requestId string) (*compute.Operation, error) {
+		requestIds = append(requestIds, requestId)
+		if len(requestIds) == 1 {
+			return nil, &googleapi.Error{Code: 503}
+		}
+		return &compute.Operation{Name: "operation"}, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if op.Name != "operation" {
+		t.Errorf("bad operation: %#v", op)
+	}
+	if len(requestIds) != 2 || requestIds[0] == "" || requestIds[0] != requestIds[1] {
+		t.Errorf("the request should be tried again with the same ID: %v", requestIds)
+	}
+
+	tries := 0
+	_, err = doOperation(func(string) (*compute.Operation, error) {
+		tries++
+		return nil, &googleapi.Error{Code: 409}
+	})
+	if err == nil || tries != 1 {
+		t.Errorf("the request should not be tried again: %d tries, %v", tries, err)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"google.golang.org/api/googleapi"
+)
+
+// MaxUploadParallelism is the most parts an object can be uploaded in, the
+// number of objects Cloud Storage composes at once.
+const MaxUploadParallelism = 32
+
+// minUploadPartSize is the smallest part worth uploading on its own.
+const minUploadPartSize = 64 * 1024 * 1024
+
+// uploadPart is a part of the data of a parallel upload.
+type uploadPart struct {
+	offset int64
+	size   int64
+}
+
+// uploadParts splits size bytes in up to parallelism parts of at least
+// minUploadPartSize bytes. It returns nil if the data is not worth splitting.
+func uploadParts(size int64, parallelism int) []uploadPart {
+	if parallelism > MaxUploadParallelism {
+		parallelism = MaxUploadParallelism
+	}
+	n := int64(parallelism)
+	if n > size/minUploadPartSize {
+		n = size / minUploadPartSize
+	}
+	if n < 2 {
+		return nil
+	}
+
+	partSize := (size + n - 1) / n
+	var parts []uploadPart
+	for offset := int64(0); offset < size; offset += partSize {
+		part := uploadPart{offset: offset, size: partSize}
+		if offset+partSize > size {
+			part.size = size - offset
+		}
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// uploadProgress reports the progress of the parts of an upload, every 30
+// seconds at most.
+type uploadProgress struct {
+	ui   packersdk.Ui
+	dest string
+
+	mu         sync.Mutex
+	uploaded   []int64
+	lastReport time.Time
+}
+
+func newUploadProgress(ui packersdk.Ui, dest string, parts int) *uploadProgress {
+	return &uploadProgress{ui: ui, dest: dest, uploaded: make([]int64, parts)}
+}
+
+// updater returns the progress updater of the part i.
+func (p *uploadProgress) updater(i int) googleapi.ProgressUpdater {
+	return func(current, total int64) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.uploaded[i] = current
+		if p.ui == nil || time.Since(p.lastReport) < 30*time.Second {
+			return
+		}
+		p.lastReport = time.Now()
+
+		var uploaded int64
+		for _, n := range p.uploaded {
+			uploaded += n
+		}
+		p.ui.Message(fmt.Sprintf("Uploaded %d MiB to %s", uploaded/(1024*1024), p.dest))
+	}
+}
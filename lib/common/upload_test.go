@@ -0,0 +1,40 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"testing"
+)
+
+func TestUploadParts(t *testing.T) {
+	const mib = 1024 * 1024
+	if parts := uploadParts(100*mib, 1); parts != nil {
+		t.Errorf("a single part should not be split: %v", parts)
+	}
+	if parts := uploadParts(100*mib, 4); parts != nil {
+		t.Errorf("small data should not be split: %v", parts)
+	}
+
+	parts := uploadParts(1000*mib+1, 4)
+	if len(parts) != 4 {
+		t.Fatalf("bad parts: %v", parts)
+	}
+	var offset int64
+	for _, part := range parts {
+		if part.offset != offset {
+			t.Errorf("the parts should be contiguous: %v", parts)
+		}
+		offset += part.size
+	}
+	if offset != 1000*mib+1 {
+		t.Errorf("the parts should cover the data: %v", parts)
+	}
+
+	if parts := uploadParts(200*mib, 8); len(parts) != 3 {
+		t.Errorf("the parts should be at least 64 MiB: %v", parts)
+	}
+	if parts := uploadParts(100*1024*mib, 64); len(parts) != MaxUploadParallelism {
+		t.Errorf("the parts should be composed at once: %d", len(parts))
+	}
+}
@@ -0,0 +1,261 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+)
+
+// The labels of the instances of the warm pools: the key of their pool, and
+// whether they are idle, used by a build, or being returned to the pool.
+const (
+	WarmPoolLabel      = "packer-warm-pool"
+	WarmPoolStateLabel = "packer-warm-pool-state"
+)
+
+// WarmPoolKey returns the key of the warm pool of the instances created with
+// c. The instances of a pool only differ by their boot disk and metadata.
+func WarmPoolKey(c *InstanceConfig) string {
+	shape := []interface{}{
+		c.Zone, c.MachineType, c.Image.SelfLink, c.MinCpuPlatform,
+		c.AcceleratorType, c.AcceleratorCount,
+		c.Network, c.NetworkProjectId, c.Subnetwork, c.OmitExternalIP, c.Tags,
+		c.ServiceAccountEmail, c.Scopes, c.DisableDefaultServiceAccount,
+		c.OnHostMaintenance, c.Preemptible, c.ProvisioningModel, c.NodeAffinities,
+		c.EnableNestedVirtualization, c.EnableSecureBoot, c.EnableVtpm, c.EnableIntegrityMonitoring,
+	}
+	data, _ := json.Marshal(shape)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+// warmPoolLabels returns the labels of an instance of pool in state.
+func warmPoolLabels(labels map[string]string, pool, state string) map[string]string {
+	res := map[string]string{}
+	for k, v := range labels {
+		res[k] = v
+	}
+	res[WarmPoolLabel] = pool
+	res[WarmPoolStateLabel] = state
+	return res
+}
+
+func (d *driverGCE) ClaimWarmInstance(zone, pool string) (string, error) {
+	filter := fmt.Sprintf(`(labels.%s = "%s") AND (labels.%s = "idle") AND (status = "TERMINATED")`,
+		WarmPoolLabel, pool, WarmPoolStateLabel)
+	var idle []*compute.Instance
+	err := d.service.Instances.List(d.projectId, zone).Filter(filter).
+		Pages(context.TODO(), func(page *compute.InstanceList) error {
+			idle = append(idle, page.Items...)
+			return nil
+		})
+	if err != nil {
+		return "", err
+	}
+
+	for _, instance := range idle {
+		// The labels are set against their fingerprint, so that a single
+		// build claims the instance.
+		op, err := d.service.Instances.SetLabels(d.projectId, zone, instance.Name, &compute.InstancesSetLabelsRequest{
+			Labels:           warmPoolLabels(instance.Labels, pool, "busy"),
+			LabelFingerprint: instance.LabelFingerprint,
+		}).Do()
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed {
+			log.Printf("[DEBUG] Warm instance %s claimed by another build", instance.Name)
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if err := d.waitForOperation(d.refreshZoneOp(zone, op)); err != nil {
+			return "", err
+		}
+		return instance.Name, nil
+	}
+	return "", nil
+}
+
+func (d *driverGCE) StartWarmInstance(name string, c *InstanceConfig) error {
+	disk := &compute.Disk{
+		Name:        c.DiskName,
+		SizeGb:      c.DiskSizeGb,
+		SourceImage: c.Image.SelfLink,
+		Type:        fmt.Sprintf("zones/%s/diskTypes/%s", c.Zone, c.DiskType),
+	}
+	if c.SourceSnapshot != "" {
+		disk.SourceImage = ""
+		disk.SourceSnapshot = c.SourceSnapshot
+	} else {
+		disk.SourceImageEncryptionKey = c.SourceImageEncryptionKey.ComputeType()
+	}
+	var diskEncryptionKey *compute.CustomerEncryptionKey
+	if c.DiskEncryptionKey != nil {
+		diskEncryptionKey = c.DiskEncryptionKey.ComputeType()
+		disk.DiskEncryptionKey = diskEncryptionKey
+	}
+	d.ui.Message(fmt.Sprintf("Creating boot disk %s...", c.DiskName))
+	op, err := doOperation(func(requestId string) (*compute.Operation, error) {
+		return d.service.Disks.Insert(d.projectId, c.Zone, disk).RequestId(requestId).Do()
+	})
+	if err == nil {
+		err = d.waitForOperation(d.refreshZoneOp(c.Zone, op))
+	}
+	if err != nil {
+		return fmt.Errorf("Error creating boot disk: %w", err)
+	}
+
+	d.ui.Message(fmt.Sprintf("Attaching boot disk to warm instance %s...", name))
+	op, err = doOperation(func(requestId string) (*compute.Operation, error) {
+		return d.service.Instances.AttachDisk(d.projectId, c.Zone, name, &compute.AttachedDisk{
+			Boot:              true,
+			AutoDelete:        false,
+			DiskEncryptionKey: diskEncryptionKey,
+			Mode:              "READ_WRITE",
+			Source:            fmt.Sprintf("projects/%s/zones/%s/disks/%s", d.projectId, c.Zone, c.DiskName),
+			Type:              "PERSISTENT",
+		}).RequestId(requestId).Do()
+	})
+	if err == nil {
+		err = d.waitForOperation(d.refreshZoneOp(c.Zone, op))
+	}
+	if err != nil {
+		return fmt.Errorf("Error attaching boot disk: %w", err)
+	}
+
+	// The metadata and labels of the previous build are replaced.
+	instance, err := d.service.Instances.Get(d.projectId, c.Zone, name).Do()
+	if err != nil {
+		return err
+	}
+	err = d.setWarmPoolLabels(c.Zone, name, warmPoolLabels(c.Labels, c.WarmPool, "busy"), instance.LabelFingerprint)
+	if err != nil {
+		return fmt.Errorf("Error setting labels: %w", err)
+	}
+	metadata := &compute.Metadata{Items: mergeMetadataItems(nil, c.Metadata)}
+	if instance.Metadata != nil {
+		metadata.Fingerprint = instance.Metadata.Fingerprint
+	}
+	op, err = doOperation(func(requestId string) (*compute.Operation, error) {
+		return d.service.Instances.SetMetadata(d.projectId, c.Zone, name, metadata).RequestId(requestId).Do()
+	})
+	if err == nil {
+		err = d.waitForOperation(d.refreshZoneOp(c.Zone, op))
+	}
+	if err != nil {
+		return fmt.Errorf("Error setting metadata: %w", err)
+	}
+
+	d.ui.Message(fmt.Sprintf("Starting warm instance %s...", name))
+	op, err = doOperation(func(requestId string) (*compute.Operation, error) {
+		return d.service.Instances.Start(d.projectId, c.Zone, name).RequestId(requestId).Do()
+	})
+	if err == nil {
+		err = d.waitForOperation(d.refreshZoneOp(c.Zone, op))
+	}
+	if err != nil {
+		return fmt.Errorf("Error starting instance: %w", err)
+	}
+	return nil
+}
+
+func (d *driverGCE) ReleaseWarmInstance(zone, name, pool string, size int) (bool, error) {
+	// The instance takes a slot of the pool before counting the others, so
+	// that concurrent releases see each other. They may then all leave the
+	// pool, but never grow it above size.
+	instance, err := d.service.Instances.Get(d.projectId, zone, name).Do()
+	if err != nil {
+		return false, err
+	}
+	err = d.setWarmPoolLabels(zone, name, warmPoolLabels(instance.Labels, pool, "releasing"), instance.LabelFingerprint)
+	if err != nil {
+		return false, fmt.Errorf("Error returning instance to the pool: %w", err)
+	}
+
+	members := 0
+	err = d.service.Instances.List(d.projectId, zone).Filter(fmt.Sprintf(`labels.%s = "%s"`, WarmPoolLabel, pool)).
+		Pages(context.TODO(), func(page *compute.InstanceList) error {
+			for _, member := range page.Items {
+				// The busy instances are deleted or released by their build.
+				switch member.Labels[WarmPoolStateLabel] {
+				case "idle", "releasing":
+					if member.Name != name {
+						members++
+					}
+				}
+			}
+			return nil
+		})
+	if err != nil {
+		return false, err
+	}
+	if members >= size {
+		return false, nil
+	}
+
+	op, err := doOperation(func(requestId string) (*compute.Operation, error) {
+		return d.service.Instances.Stop(d.projectId, zone, name).RequestId(requestId).Do()
+	})
+	if err == nil {
+		err = d.waitForOperation(d.refreshZoneOp(zone, op))
+	}
+	if err != nil {
+		return false, fmt.Errorf("Error stopping instance: %w", err)
+	}
+
+	// The boot disk is kept for the image, and deleted along with it.
+	instance, err = d.service.Instances.Get(d.projectId, zone, name).Do()
+	if err != nil {
+		return false, err
+	}
+	for _, disk := range instance.Disks {
+		if !disk.Boot {
+			continue
+		}
+		op, err := doOperation(func(requestId string) (*compute.Operation, error) {
+			return d.service.Instances.DetachDisk(d.projectId, zone, name, disk.DeviceName).RequestId(requestId).Do()
+		})
+		if err == nil {
+			err = d.waitForOperation(d.refreshZoneOp(zone, op))
+		}
+		if err != nil {
+			return false, fmt.Errorf("Error detaching boot disk: %w", err)
+		}
+	}
+
+	instance, err = d.service.Instances.Get(d.projectId, zone, name).Do()
+	if err != nil {
+		return false, err
+	}
+	err = d.setWarmPoolLabels(zone, name, warmPoolLabels(instance.Labels, pool, "idle"), instance.LabelFingerprint)
+	if err != nil {
+		return false, fmt.Errorf("Error returning instance to the pool: %w", err)
+	}
+	return true, nil
+}
+
+// setWarmPoolLabels sets the labels of the instance against their
+// fingerprint.
+func (d *driverGCE) setWarmPoolLabels(zone, name string, labels map[string]string, fingerprint string) error {
+	op, err := doOperation(func(requestId string) (*compute.Operation, error) {
+		return d.service.Instances.SetLabels(d.projectId, zone, name, &compute.InstancesSetLabelsRequest{
+			Labels:           labels,
+			LabelFingerprint: fingerprint,
+		}).RequestId(requestId).Do()
+	})
+	if err != nil {
+		return err
+	}
+	return d.waitForOperation(d.refreshZoneOp(zone, op))
+}
@@ -0,0 +1,52 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package common
+
+import (
+	"testing"
+)
+
+func TestWarmPoolKey(t *testing.T) {
+	c := &InstanceConfig{
+		Zone:        "us-central1-a",
+		MachineType: "e2-standard-2",
+		Image:       &Image{SelfLink: "https://compute/images/debian-12"},
+		Name:        "packer-1",
+		DiskName:    "packer-1",
+		Metadata:    map[string]string{"startup-script": "echo 1"},
+	}
+	key := WarmPoolKey(c)
+	if len(key) > 63 {
+		t.Errorf("the key should be a valid label value: %s", key)
+	}
+
+	other := *c
+	other.Name = "packer-2"
+	other.DiskName = "packer-2"
+	other.Metadata = map[string]string{"startup-script": "echo 2"}
+	if WarmPoolKey(&other) != key {
+		t.Error("instances differing by their disk and metadata should share a pool")
+	}
+
+	other.MachineType = "e2-standard-4"
+	if WarmPoolKey(&other) == key {
+		t.Error("instances of another machine type should not share a pool")
+	}
+	other = *c
+	other.Image = &Image{SelfLink: "https://compute/images/debian-11"}
+	if WarmPoolKey(&other) == key {
+		t.Error("instances of another source image should not share a pool")
+	}
+}
+
+func TestWarmPoolLabels(t *testing.T) {
+	labels := map[string]string{"team": "web"}
+	got := warmPoolLabels(labels, "abc", "busy")
+	if got["team"] != "web" || got[WarmPoolLabel] != "abc" || got[WarmPoolStateLabel] != "busy" {
+		t.Errorf("bad labels: %v", got)
+	}
+	if len(labels) != 1 {
+		t.Errorf("the labels of the build should not be modified: %v", labels)
+	}
+}
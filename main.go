@@ -11,15 +11,55 @@ import (
 	"github.com/hashicorp/packer-plugin-sdk/plugin"
 
 	googlecompute "github.com/hashicorp/packer-plugin-googlecompute/builder/googlecompute"
+	googlecomputedisk "github.com/hashicorp/packer-plugin-googlecompute/builder/googlecompute-disk"
+	googlecomputeinstancetemplatebuilder "github.com/hashicorp/packer-plugin-googlecompute/builder/googlecompute-instance-template"
+	googlecomputedefaultserviceaccount "github.com/hashicorp/packer-plugin-googlecompute/datasource/defaultserviceaccount"
+	googlecomputegcsobject "github.com/hashicorp/packer-plugin-googlecompute/datasource/gcsobject"
+	googlecomputeimage "github.com/hashicorp/packer-plugin-googlecompute/datasource/image"
+	googlecomputeimagefamily "github.com/hashicorp/packer-plugin-googlecompute/datasource/imagefamily"
+	googlecomputeinstance "github.com/hashicorp/packer-plugin-googlecompute/datasource/instance"
+	googlecomputekmskey "github.com/hashicorp/packer-plugin-googlecompute/datasource/kmskey"
+	googlecomputenetwork "github.com/hashicorp/packer-plugin-googlecompute/datasource/network"
+	googlecomputeparametermanager "github.com/hashicorp/packer-plugin-googlecompute/datasource/parametermanager"
+	googlecomputeprojectmetadata "github.com/hashicorp/packer-plugin-googlecompute/datasource/projectmetadata"
+	googlecomputeregions "github.com/hashicorp/packer-plugin-googlecompute/datasource/regions"
+	googlecomputesecretsmanager "github.com/hashicorp/packer-plugin-googlecompute/datasource/secretsmanager"
+	googlecomputesnapshot "github.com/hashicorp/packer-plugin-googlecompute/datasource/snapshot"
+	googlecomputezones "github.com/hashicorp/packer-plugin-googlecompute/datasource/zones"
+	googlecomputecatalog "github.com/hashicorp/packer-plugin-googlecompute/post-processor/googlecompute-catalog"
 	googlecomputeexport "github.com/hashicorp/packer-plugin-googlecompute/post-processor/googlecompute-export"
 	googlecomputeimport "github.com/hashicorp/packer-plugin-googlecompute/post-processor/googlecompute-import"
+	googlecomputeinstancetemplate "github.com/hashicorp/packer-plugin-googlecompute/post-processor/googlecompute-instance-template"
+	googlecomputemigupdate "github.com/hashicorp/packer-plugin-googlecompute/post-processor/googlecompute-mig-update"
+	googlecomputepublish "github.com/hashicorp/packer-plugin-googlecompute/post-processor/googlecompute-publish"
+	googlecomputevulnerabilityscan "github.com/hashicorp/packer-plugin-googlecompute/post-processor/googlecompute-vulnerability-scan"
 )
 
 func main() {
 	pps := plugin.NewSet()
 	pps.RegisterBuilder(plugin.DEFAULT_NAME, new(googlecompute.Builder))
+	pps.RegisterBuilder("disk", new(googlecomputedisk.Builder))
+	pps.RegisterBuilder("instance-template", new(googlecomputeinstancetemplatebuilder.Builder))
 	pps.RegisterPostProcessor("import", new(googlecomputeimport.PostProcessor))
 	pps.RegisterPostProcessor("export", new(googlecomputeexport.PostProcessor))
+	pps.RegisterPostProcessor("instance-template", new(googlecomputeinstancetemplate.PostProcessor))
+	pps.RegisterPostProcessor("mig-update", new(googlecomputemigupdate.PostProcessor))
+	pps.RegisterPostProcessor("publish", new(googlecomputepublish.PostProcessor))
+	pps.RegisterPostProcessor("catalog", new(googlecomputecatalog.PostProcessor))
+	pps.RegisterPostProcessor("vulnerability-scan", new(googlecomputevulnerabilityscan.PostProcessor))
+	pps.RegisterDatasource("image", new(googlecomputeimage.Datasource))
+	pps.RegisterDatasource("snapshot", new(googlecomputesnapshot.Datasource))
+	pps.RegisterDatasource("secretsmanager", new(googlecomputesecretsmanager.Datasource))
+	pps.RegisterDatasource("parametermanager", new(googlecomputeparametermanager.Datasource))
+	pps.RegisterDatasource("network", new(googlecomputenetwork.Datasource))
+	pps.RegisterDatasource("zones", new(googlecomputezones.Datasource))
+	pps.RegisterDatasource("default-service-account", new(googlecomputedefaultserviceaccount.Datasource))
+	pps.RegisterDatasource("kms-key", new(googlecomputekmskey.Datasource))
+	pps.RegisterDatasource("instance", new(googlecomputeinstance.Datasource))
+	pps.RegisterDatasource("image-family", new(googlecomputeimagefamily.Datasource))
+	pps.RegisterDatasource("gcs-object", new(googlecomputegcsobject.Datasource))
+	pps.RegisterDatasource("project-metadata", new(googlecomputeprojectmetadata.Datasource))
+	pps.RegisterDatasource("regions", new(googlecomputeregions.Datasource))
 	pps.SetVersion(version.PluginVersion)
 	err := pps.Run()
 	if err != nil {
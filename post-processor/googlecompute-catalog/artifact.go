@@ -0,0 +1,67 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecomputecatalog
+
+import (
+	"fmt"
+	"strings"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+const BuilderId = "packer.post-processor.googlecompute-catalog"
+
+// Artifact represents the descriptor of an image published to a catalog.
+type Artifact struct {
+	imageName string
+	// gcsPath is the GCS URL of the descriptor, when written to GCS.
+	gcsPath string
+	// messageId is the ID of the Pub/Sub message, when published to a topic.
+	messageId string
+	// StateData should store data such as GeneratedData
+	// to be shared with post-processors
+	StateData map[string]interface{}
+}
+
+var _ packersdk.Artifact = new(Artifact)
+
+func (*Artifact) BuilderId() string {
+	return BuilderId
+}
+
+// Id returns the name of the image described.
+func (a *Artifact) Id() string {
+	return a.imageName
+}
+
+func (*Artifact) Files() []string {
+	return nil
+}
+
+func (a *Artifact) String() string {
+	var targets []string
+	if a.gcsPath != "" {
+		targets = append(targets, a.gcsPath)
+	}
+	if a.messageId != "" {
+		targets = append(targets, "Pub/Sub message "+a.messageId)
+	}
+	return fmt.Sprintf("The descriptor of image %v was published to: %v", a.imageName, strings.Join(targets, ", "))
+}
+
+func (a *Artifact) State(name string) interface{} {
+	switch name {
+	case "GCSPath":
+		return a.gcsPath
+	case "MessageId":
+		return a.messageId
+	}
+	return a.StateData[name]
+}
+
+// Destroy does nothing: catalog consumers may already have read the
+// descriptor.
+func (a *Artifact) Destroy() error {
+	return nil
+}
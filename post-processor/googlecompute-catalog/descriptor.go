@@ -0,0 +1,83 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecomputecatalog
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/version"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"gopkg.in/yaml.v3"
+)
+
+// descriptor describes a built image for the consumers of the catalog.
+type descriptor struct {
+	Name       string            `json:"name" yaml:"name"`
+	Project    string            `json:"project" yaml:"project"`
+	SelfLink   string            `json:"self_link,omitempty" yaml:"self_link,omitempty"`
+	Family     string            `json:"family,omitempty" yaml:"family,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Checksums  map[string]string `json:"checksums,omitempty" yaml:"checksums,omitempty"`
+	Provenance provenance        `json:"provenance" yaml:"provenance"`
+	Metadata   map[string]string `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+}
+
+// provenance describes how an image was built.
+type provenance struct {
+	BuildName     string `json:"build_name,omitempty" yaml:"build_name,omitempty"`
+	SourceImage   string `json:"source_image,omitempty" yaml:"source_image,omitempty"`
+	BuildZone     string `json:"build_zone,omitempty" yaml:"build_zone,omitempty"`
+	PluginVersion string `json:"plugin_version" yaml:"plugin_version"`
+	PublishedAt   string `json:"published_at" yaml:"published_at"`
+	// Attestation is the GCS URL of the SLSA provenance of the image.
+	Attestation string `json:"attestation,omitempty" yaml:"attestation,omitempty"`
+}
+
+// newDescriptor returns the descriptor of the image of artifact.
+func (p *PostProcessor) newDescriptor(artifact packersdk.Artifact, now time.Time) *descriptor {
+	project := stateString(artifact, "ImageProjectId")
+	if project == "" {
+		project = stateString(artifact, "ProjectId")
+	}
+	labels, _ := artifact.State("ImageLabels").(map[string]string)
+	checksums, _ := artifact.State("checksums").(map[string]string)
+
+	sourceImage := ""
+	if data, ok := artifact.State("generated_data").(map[string]interface{}); ok {
+		sourceImage, _ = data["SourceImageName"].(string)
+	}
+
+	return &descriptor{
+		Name:      artifact.Id(),
+		Project:   project,
+		SelfLink:  stateString(artifact, "ImageSelfLink"),
+		Family:    stateString(artifact, "ImageFamily"),
+		Labels:    labels,
+		Checksums: checksums,
+		Provenance: provenance{
+			BuildName:     p.config.PackerBuildName,
+			SourceImage:   sourceImage,
+			BuildZone:     stateString(artifact, "BuildZone"),
+			PluginVersion: version.PluginVersion.FormattedVersion(),
+			PublishedAt:   now.UTC().Format(time.RFC3339),
+		},
+		Metadata: p.config.ExtraMetadata,
+	}
+}
+
+// marshal encodes d in format, and returns its content type.
+func (d *descriptor) marshal(format string) ([]byte, string, error) {
+	if format == "yaml" {
+		data, err := yaml.Marshal(d)
+		return data, "application/yaml", err
+	}
+	data, err := json.MarshalIndent(d, "", "  ")
+	return data, "application/json", err
+}
+
+func stateString(artifact packersdk.Artifact, name string) string {
+	s, _ := artifact.State(name).(string)
+	return s
+}
@@ -0,0 +1,238 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+package googlecomputecatalog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-googlecompute/builder/googlecompute"
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	sdk_common "github.com/hashicorp/packer-plugin-sdk/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
+
+type Config struct {
+	sdk_common.PackerConfig `mapstructure:",squash"`
+	common.Authentication   `mapstructure:",squash"`
+
+	//The GCS URL the descriptor of the image is written to, e.g.
+	//`gs://my-catalog/images/{{ .SourceImageName }}.json`. This is treated as
+	//a [template engine](/packer/docs/templates/legacy_json_templates/engine),
+	//rendered with the generated data of the build.
+	GCSPath string `mapstructure:"gcs_path"`
+	//The GCS URL the provenance of the image is written to, e.g.
+	//`gs://my-catalog/images/{{ .SourceImageName }}.intoto.json`, rendered
+	//like `gcs_path`. The provenance is the SLSA statement the
+	//`googlecompute` builder attests the image with, see its
+	//`provenance_file` option. The descriptor links to it in
+	//`provenance.attestation`.
+	ProvenanceGCSPath string `mapstructure:"provenance_gcs_path"`
+	//The Pub/Sub topic the descriptor is published to, as
+	//`projects/<project>/topics/<topic>`, or only the name of the topic in the
+	//project of the image. The message has the `image_name`, `image_family`
+	//and `image_project` attributes, to filter subscriptions on.
+	PubSubTopic string `mapstructure:"pubsub_topic"`
+	//The format of the descriptor, `json` or `yaml`. Defaults to `json`.
+	Format string `mapstructure:"format"`
+	//Additional key/value pairs written to the `metadata` field of the
+	//descriptor, e.g. the owner of the image or a link to the pipeline run.
+	ExtraMetadata map[string]string `mapstructure:"extra_metadata"`
+
+	ctx interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+}
+
+func (p *PostProcessor) ConfigSpec() hcldec.ObjectSpec { return p.config.FlatMapstructure().HCL2Spec() }
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		PluginType:         BuilderId,
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{
+				"gcs_path",
+				"provenance_gcs_path",
+			},
+		},
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	errs := new(packersdk.MultiError)
+
+	if p.config.GCSPath == "" && p.config.PubSubTopic == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("at least one of gcs_path or pubsub_topic must be set"))
+	}
+	if p.config.GCSPath != "" {
+		if err = interpolate.Validate(p.config.GCSPath, &p.config.ctx); err != nil {
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("Error parsing gcs_path template: %s", err))
+		}
+		if !strings.HasPrefix(p.config.GCSPath, "gs://") {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("invalid gcs_path %q, expected gs://bucket/object", p.config.GCSPath))
+		}
+	}
+
+	if p.config.ProvenanceGCSPath != "" {
+		if err = interpolate.Validate(p.config.ProvenanceGCSPath, &p.config.ctx); err != nil {
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("Error parsing provenance_gcs_path template: %s", err))
+		}
+		if !strings.HasPrefix(p.config.ProvenanceGCSPath, "gs://") {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("invalid provenance_gcs_path %q, expected gs://bucket/object", p.config.ProvenanceGCSPath))
+		}
+	}
+
+	p.config.Format = strings.ToLower(p.config.Format)
+	switch p.config.Format {
+	case "":
+		p.config.Format = "json"
+	case "json", "yaml":
+	default:
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("format must be json or yaml, got %q", p.config.Format))
+	}
+
+	warns, err := p.config.Authentication.Prepare()
+	if err != nil {
+		errs = packersdk.MultiErrorAppend(errs, err)
+	}
+	for _, warn := range warns {
+		log.Printf("[WARN] - %s", warn)
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifact packersdk.Artifact) (packersdk.Artifact, bool, bool, error) {
+	if artifact.BuilderId() != googlecompute.BuilderId {
+		err := fmt.Errorf(
+			"Unknown artifact type: %s\nCan only publish the descriptor of Google Compute Engine builder artifacts.",
+			artifact.BuilderId())
+		return nil, false, false, err
+	}
+
+	generatedData := artifact.State("generated_data")
+	if generatedData == nil {
+		generatedData = make(map[string]interface{})
+	}
+	p.config.ctx.Data = generatedData
+
+	desc := p.newDescriptor(artifact, time.Now())
+
+	cfg := &common.GCEDriverConfig{
+		Ui:        ui,
+		ProjectId: desc.Project,
+		Scopes:    []string{common.CloudPlatformScope},
+	}
+	p.config.Authentication.ApplyDriverConfig(cfg)
+
+	driver, err := common.NewDriverGCE(*cfg)
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	statement, _ := artifact.State("Provenance").(string)
+	result, err := p.publish(ui, driver, desc, statement)
+	if err != nil {
+		return nil, false, false, err
+	}
+	result.StateData = map[string]interface{}{"generated_data": generatedData}
+
+	return result, true, false, nil
+}
+
+// publishDriver is the part of the driver publishing descriptors.
+type publishDriver interface {
+	common.PubSubDriver
+	common.StorageDriver
+}
+
+// publish writes the provenance statement to its GCS path, and desc to the
+// GCS path and the Pub/Sub topic.
+func (p *PostProcessor) publish(ui packersdk.Ui, driver publishDriver, desc *descriptor, statement string) (*Artifact, error) {
+	if p.config.ProvenanceGCSPath != "" {
+		if statement == "" {
+			return nil, fmt.Errorf("The artifact has no provenance to write to provenance_gcs_path")
+		}
+		path, err := interpolate.Render(p.config.ProvenanceGCSPath, &p.config.ctx)
+		if err != nil {
+			return nil, fmt.Errorf("Error rendering provenance_gcs_path template: %s", err)
+		}
+		bucket, object, err := common.ParseGCSPath(path)
+		if err != nil {
+			return nil, err
+		}
+
+		ui.Say(fmt.Sprintf("Writing the provenance of image %s to %s...", desc.Name, path))
+		_, err = driver.UploadToBucket(bucket, object, strings.NewReader(statement), common.UploadOptions{ContentType: "application/json"})
+		if err != nil {
+			return nil, common.EnrichError(fmt.Errorf("Error writing %s: %w", path, err))
+		}
+		desc.Provenance.Attestation = path
+	}
+
+	data, contentType, err := desc.marshal(p.config.Format)
+	if err != nil {
+		return nil, fmt.Errorf("Error encoding the image descriptor: %s", err)
+	}
+
+	result := &Artifact{imageName: desc.Name}
+
+	if p.config.GCSPath != "" {
+		path, err := interpolate.Render(p.config.GCSPath, &p.config.ctx)
+		if err != nil {
+			return nil, fmt.Errorf("Error rendering gcs_path template: %s", err)
+		}
+		bucket, object, err := common.ParseGCSPath(path)
+		if err != nil {
+			return nil, err
+		}
+
+		ui.Say(fmt.Sprintf("Writing the descriptor of image %s to %s...", desc.Name, path))
+		_, err = driver.UploadToBucket(bucket, object, bytes.NewReader(data), common.UploadOptions{ContentType: contentType})
+		if err != nil {
+			return nil, common.EnrichError(fmt.Errorf("Error writing %s: %w", path, err))
+		}
+		result.gcsPath = path
+	}
+
+	if p.config.PubSubTopic != "" {
+		topic := p.config.PubSubTopic
+		if !strings.HasPrefix(topic, "projects/") {
+			topic = fmt.Sprintf("projects/%s/topics/%s", desc.Project, topic)
+		}
+
+		ui.Say(fmt.Sprintf("Publishing the descriptor of image %s to %s...", desc.Name, topic))
+		result.messageId, err = driver.PublishMessage(topic, data, map[string]string{
+			"image_name":    desc.Name,
+			"image_family":  desc.Family,
+			"image_project": desc.Project,
+		})
+		if err != nil {
+			return nil, common.EnrichError(fmt.Errorf("Error publishing to %s: %w", topic, err))
+		}
+	}
+
+	return result, nil
+}
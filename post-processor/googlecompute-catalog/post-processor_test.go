@@ -0,0 +1,151 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecomputecatalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/builder/googlecompute"
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+type testDriver struct {
+	common.PubSubDriverMock
+	common.StorageDriverMock
+}
+
+func testArtifact() *packersdk.MockArtifact {
+	return &packersdk.MockArtifact{
+		BuilderIdValue: googlecompute.BuilderId,
+		IdValue:        "my-image-123",
+		StateValues: map[string]interface{}{
+			"ImageProjectId": "images-project",
+			"ImageFamily":    "web",
+			"ImageLabels":    map[string]string{"team": "infra"},
+			"BuildZone":      "us-central1-a",
+			"generated_data": map[string]interface{}{"SourceImageName": "debian-12-bookworm-v20240110"},
+		},
+	}
+}
+
+func TestPostProcessor_Configure(t *testing.T) {
+	for name, c := range map[string]map[string]interface{}{
+		"no target":           {},
+		"bad path":            {"gcs_path": "my-catalog/image.json"},
+		"bad format":          {"pubsub_topic": "images", "format": "xml"},
+		"bad provenance path": {"pubsub_topic": "images", "provenance_gcs_path": "my-catalog/image.intoto.json"},
+	} {
+		var p PostProcessor
+		c["access_token"] = "ya29.token"
+		if err := p.Configure(c); err == nil {
+			t.Errorf("%s: should error", name)
+		}
+	}
+}
+
+func TestPostProcessor_publish(t *testing.T) {
+	var p PostProcessor
+	err := p.Configure(map[string]interface{}{
+		"access_token":      "ya29.token",
+		"gcs_path":          "gs://my-catalog/images/{{ .SourceImageName }}.json",
+		"pubsub_topic":      "images",
+		"extra_metadata":    map[string]string{"owner": "infra"},
+		"packer_build_name": "web",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	artifact := testArtifact()
+	p.config.ctx.Data = artifact.State("generated_data")
+	desc := p.newDescriptor(artifact, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	driver := &testDriver{}
+	driver.PublishMessageResult = "42"
+	result, err := p.publish(packersdk.TestUi(t), driver, desc, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if driver.UploadToBucketBucket != "my-catalog" || driver.UploadToBucketObjectName != "images/debian-12-bookworm-v20240110.json" {
+		t.Errorf("bad upload: gs://%s/%s", driver.UploadToBucketBucket, driver.UploadToBucketObjectName)
+	}
+	if driver.UploadToBucketOpts.ContentType != "application/json" {
+		t.Errorf("bad content type: %s", driver.UploadToBucketOpts.ContentType)
+	}
+	if driver.PublishMessageTopic != "projects/images-project/topics/images" || driver.PublishMessageAttributes["image_family"] != "web" {
+		t.Errorf("bad message: %s %v", driver.PublishMessageTopic, driver.PublishMessageAttributes)
+	}
+	if result.State("MessageId") != "42" || result.State("GCSPath") != "gs://my-catalog/images/debian-12-bookworm-v20240110.json" {
+		t.Errorf("bad artifact: %s", result)
+	}
+
+	var got descriptor
+	if err := json.Unmarshal(driver.PublishMessageData, &got); err != nil {
+		t.Fatalf("the descriptor should be JSON: %s", err)
+	}
+	if got.Name != "my-image-123" || got.Project != "images-project" || got.Labels["team"] != "infra" ||
+		got.Metadata["owner"] != "infra" {
+		t.Errorf("bad descriptor: %#v", got)
+	}
+	if got.Provenance.SourceImage != "debian-12-bookworm-v20240110" || got.Provenance.BuildName != "web" ||
+		got.Provenance.PublishedAt != "2024-01-02T03:04:05Z" {
+		t.Errorf("bad provenance: %#v", got.Provenance)
+	}
+}
+
+func TestDescriptor_marshalYAML(t *testing.T) {
+	desc := &descriptor{Name: "my-image-123", Project: "images-project"}
+	data, contentType, err := desc.marshal("yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if contentType != "application/yaml" || !bytes.Contains(data, []byte("name: my-image-123")) {
+		t.Errorf("bad YAML descriptor %s: %s", contentType, data)
+	}
+	if strings.Contains(string(data), "family") {
+		t.Errorf("empty fields should be omitted: %s", data)
+	}
+}
+
+func TestPostProcessor_publishProvenance(t *testing.T) {
+	var p PostProcessor
+	err := p.Configure(map[string]interface{}{
+		"access_token":        "ya29.token",
+		"pubsub_topic":        "images",
+		"provenance_gcs_path": "gs://my-catalog/images/{{ .SourceImageName }}.intoto.json",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	artifact := testArtifact()
+	p.config.ctx.Data = artifact.State("generated_data")
+	desc := p.newDescriptor(artifact, time.Now())
+
+	driver := &testDriver{}
+	if _, err := p.publish(packersdk.TestUi(t), driver, desc, ""); err == nil {
+		t.Errorf("should error without provenance")
+	}
+
+	if _, err := p.publish(packersdk.TestUi(t), driver, desc, `{"_type": "https://in-toto.io/Statement/v1"}`); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if driver.UploadToBucketBucket != "my-catalog" || driver.UploadToBucketObjectName != "images/debian-12-bookworm-v20240110.intoto.json" {
+		t.Errorf("bad upload: gs://%s/%s", driver.UploadToBucketBucket, driver.UploadToBucketObjectName)
+	}
+
+	var got descriptor
+	if err := json.Unmarshal(driver.PublishMessageData, &got); err != nil {
+		t.Fatalf("the descriptor should be JSON: %s", err)
+	}
+	if got.Provenance.Attestation != "gs://my-catalog/images/debian-12-bookworm-v20240110.intoto.json" {
+		t.Errorf("bad attestation: %q", got.Provenance.Attestation)
+	}
+}
@@ -13,8 +13,23 @@ import (
 
 const BuilderId = "packer.post-processor.googlecompute-export"
 
+// sha256MetadataKey is the custom metadata of the exported objects holding
+// their SHA-256, set by the export instance.
+const sha256MetadataKey = "sha256"
+
+// Checksum holds the hex encoded checksums of an exported object.
+type Checksum struct {
+	SHA256 string
+	CRC32C string
+}
+
 type Artifact struct {
 	paths []string
+	// checksums holds the checksums of the exported objects, keyed by path.
+	checksums map[string]Checksum
+	// signedURLs holds the signed URLs of the exported objects, keyed by
+	// path.
+	signedURLs map[string]string
 	// StateData should store data such as GeneratedData
 	// to be shared with post-processors
 	StateData map[string]interface{}
@@ -44,6 +59,12 @@ func (a *Artifact) State(name string) interface{} {
 	if name == registryimage.ArtifactStateURI {
 		return a.hcpPackerRegistryMetadata()
 	}
+	if name == "checksums" {
+		return a.checksums
+	}
+	if name == "signed_urls" {
+		return a.signedURLs
+	}
 	return nil
 }
 
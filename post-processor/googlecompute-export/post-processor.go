@@ -8,8 +8,11 @@ package googlecomputeexport
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"log"
+	"net/http"
 	"strings"
 	"time"
 
@@ -23,6 +26,7 @@ import (
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer-plugin-sdk/template/config"
 	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/storage/v1"
 )
 
@@ -50,23 +54,76 @@ type Config struct {
 	MachineType string `mapstructure:"machine_type"`
 	//The Google Compute network id or URL to use for the export instance.
 	//Defaults to `"default"`. If the value is not a URL, it
-	//will be interpolated to `projects/((builder_project_id))/global/networks/((network))`.
+	//will be interpolated to `projects/((network_project_id))/global/networks/((network))`.
 	//This value is not required if a `subnet` is specified.
 	Network string `mapstructure:"network"`
+	//The project ID of the network and subnetwork of the export instance,
+	//e.g. the host project of a Shared VPC. Defaults to the project of the
+	//exported image.
+	NetworkProjectId string `mapstructure:"network_project_id"`
+	//If true, the export instance will not have an external IP address,
+	//e.g. in VPCs where they are forbidden. The subnetwork must then have
+	//Private Google Access enabled, for the instance to reach Cloud Storage
+	//and the Compute Engine API.
+	OmitExternalIP bool `mapstructure:"omit_external_ip"`
+	//Network tags applied to the export instance, e.g. to match the firewall
+	//rules or routes of a restricted VPC.
+	Tags []string `mapstructure:"tags"`
 	//A list of GCS paths where the image will be exported.
 	//For example `'gs://mybucket/path/to/file.tar.gz'`
+	//
+	//The image is exported to the first path, then copied to the other ones
+	//concurrently, e.g. to buckets in other regions for disaster recovery.
+	//Each destination is checked once the export is done, and only the ones
+	//that exist are part of the artifact.
 	Paths []string `mapstructure:"paths" required:"true"`
+	//The Cloud KMS key used to encrypt the exported objects, in the form
+	//`projects/((project))/locations/((location))/keyRings/((keyring))/cryptoKeys/((key))`.
+	//The Cloud Storage service agent of the bucket's project needs the
+	//`roles/cloudkms.cryptoKeyEncrypterDecrypter` role on the key. Once the
+	//export is done, each destination is checked to be encrypted with this
+	//key, which is also how to enforce the default key of the buckets.
+	//By default, the objects are encrypted with the default key of their
+	//bucket, if any, or with a Google-managed key.
+	KmsKeyName string `mapstructure:"kms_key_name"`
+	//If set, signed URLs valid for this duration are generated for the
+	//exported objects, e.g. `72h`, so that they can be downloaded without
+	//Google credentials, by vendors or from other clouds. The URLs are
+	//only available in the `signed_urls` state of the artifact, and are not
+	//printed since they grant access to the objects. Must not exceed `168h`,
+	//7 days.
+	SignedURLDuration time.Duration `mapstructure:"signed_url_duration"`
+	//The service account signing the URLs, which needs read access to the
+	//exported objects. The account used by Packer needs the
+	//`iam.serviceAccounts.signBlob` permission on it, e.g. with the
+	//`roles/iam.serviceAccountTokenCreator` role. Defaults to the service
+	//account of the credentials, which signs the URLs with its key when
+	//given as a key file.
+	SignedURLServiceAccount string `mapstructure:"signed_url_service_account"`
+	//The disk format of the exported image, one of `vmdk` (vSphere),
+	//`vhdx` (Hyper-V), `vpc` (VHD, Azure and Hyper-V) or `qcow2` (OpenStack,
+	//KVM). By default, the image is exported as a gzipped tarball of the raw
+	//disk, which can be imported back into GCE. The `vmdk` format is
+	//stream-optimized, as expected by vSphere.
+	//
+	//The conversion is done on the boot disk of the export instance, so
+	//`disk_size` must fit the converted image.
+	Format string `mapstructure:"format"`
 	//The Google Compute subnetwork id or URL to use for
 	//the export instance. Only required if the `network` has been created with
 	//custom subnetting. Note, the region of the subnetwork must match the
 	//`zone` in which the VM is launched. If the value is not a URL,
 	//it will be interpolated to
-	//`projects/((builder_project_id))/regions/((region))/subnetworks/((subnetwork))`
+	//`projects/((network_project_id))/regions/((region))/subnetworks/((subnetwork))`
 	Subnetwork string `mapstructure:"subnetwork"`
 	//The zone in which to launch the export instance. Defaults
 	//to `googlecompute` builder zone. Example: `"us-central1-a"`
-	Zone                string `mapstructure:"zone"`
-	IAP                 bool   `mapstructure-to-hcl2:",skip"`
+	Zone string `mapstructure:"zone"`
+	IAP  bool   `mapstructure-to-hcl2:",skip"`
+	//The service account of the export instance, which writes the image to
+	//`paths`. Only this account needs write access to the buckets, which may
+	//therefore live in another project or organization than the image.
+	//Defaults to the project's default service account.
 	ServiceAccountEmail string `mapstructure:"service_account_email"`
 
 	ctx interpolate.Context
@@ -95,6 +152,11 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 		errs = packersdk.MultiErrorAppend(
 			errs, fmt.Errorf("paths must be specified"))
 	}
+	for _, path := range p.config.Paths {
+		if _, _, err := common.ParseGCSPath(path); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, err)
+		}
+	}
 
 	// Set defaults.
 	if p.config.DiskSizeGb == 0 {
@@ -113,6 +175,22 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 		p.config.Network = "default"
 	}
 
+	if p.config.SignedURLDuration < 0 || p.config.SignedURLDuration > common.MaxSignedURLDuration {
+		errs = packersdk.MultiErrorAppend(errs,
+			fmt.Errorf("signed_url_duration must be between 0 and %s", common.MaxSignedURLDuration))
+	}
+	if p.config.SignedURLServiceAccount != "" && p.config.SignedURLDuration == 0 {
+		errs = packersdk.MultiErrorAppend(errs,
+			fmt.Errorf("signed_url_service_account needs signed_url_duration"))
+	}
+
+	switch p.config.Format {
+	case "", "vmdk", "vhdx", "vpc", "qcow2":
+	default:
+		errs = packersdk.MultiErrorAppend(errs,
+			fmt.Errorf("format must be one of vmdk, vhdx, vpc or qcow2, got %q", p.config.Format))
+	}
+
 	warns, err := p.config.Authentication.Prepare()
 	if err != nil {
 		errs = packersdk.MultiErrorAppend(errs, err)
@@ -160,7 +238,9 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifa
 	// Set up exporter instance configuration.
 	exporterName := fmt.Sprintf("%s-exporter", artifact.Id())
 	exporterMetadata := map[string]string{
+		"format":         p.config.Format,
 		"image_name":     builderImageName,
+		"kms_key_name":   p.config.KmsKeyName,
 		"name":           exporterName,
 		"paths":          strings.Join(p.config.Paths, " "),
 		"startup-script": StartupScript,
@@ -169,30 +249,7 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifa
 		googlecompute.StartupScriptStatusKey: googlecompute.StartupScriptStatusNotDone,
 	}
 
-	exporterConfig := googlecompute.Config{
-		DiskName:             exporterName,
-		DiskSizeGb:           p.config.DiskSizeGb,
-		DiskType:             p.config.DiskType,
-		InstanceName:         exporterName,
-		MachineType:          p.config.MachineType,
-		Metadata:             exporterMetadata,
-		Network:              p.config.Network,
-		NetworkProjectId:     builderProjectId,
-		StateTimeout:         5 * time.Minute,
-		SourceImageFamily:    "debian-9-worker",
-		SourceImageProjectId: []string{"compute-image-tools"},
-		Subnetwork:           p.config.Subnetwork,
-		Zone:                 p.config.Zone,
-		Scopes: []string{
-			"https://www.googleapis.com/auth/compute",
-			"https://www.googleapis.com/auth/devstorage.full_control",
-			"https://www.googleapis.com/auth/userinfo.email",
-			"https://www.googleapis.com/auth/logging.write",
-		},
-	}
-	if p.config.ServiceAccountEmail != "" {
-		exporterConfig.ServiceAccountEmail = p.config.ServiceAccountEmail
-	}
+	exporterConfig := p.exporterConfig(exporterName, builderProjectId, exporterMetadata)
 	cfg := &common.GCEDriverConfig{
 		Ui:        ui,
 		ProjectId: builderProjectId,
@@ -233,10 +290,135 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifa
 	p.runner = commonsteps.NewRunner(steps, p.config.PackerConfig, ui)
 	p.runner.Run(ctx, state)
 
+	if rawErr, ok := state.GetOk("error"); ok {
+		return nil, false, false, rawErr.(error)
+	}
+
+	paths, checksums, err := verifyExports(ui, driver, p.config.Paths, p.config.KmsKeyName)
+
 	result := &Artifact{
-		paths:     p.config.Paths,
+		paths:     paths,
+		checksums: checksums,
 		StateData: map[string]interface{}{"generated_data": state.Get("generated_data")},
 	}
 
-	return result, false, false, nil
+	if p.config.SignedURLDuration > 0 {
+		urls, signErr := signExports(ui, driver, paths, p.config.SignedURLDuration, p.config.SignedURLServiceAccount)
+		result.signedURLs = urls
+		if err == nil {
+			err = signErr
+		}
+	}
+
+	return result, false, false, err
+}
+
+// signExports generates signed URLs valid for expires for each of paths. The
+// URLs grant access to the objects, and are not printed.
+func signExports(ui packersdk.Ui, driver common.StorageDriver, paths []string, expires time.Duration, serviceAccount string) (map[string]string, error) {
+	urls := map[string]string{}
+	expiry := time.Now().Add(expires).UTC().Format(time.RFC3339)
+	for _, path := range paths {
+		bucket, object, err := common.ParseGCSPath(path)
+		if err != nil {
+			return urls, err
+		}
+		url, err := driver.SignURL(bucket, object, expires, serviceAccount)
+		if err != nil {
+			return urls, fmt.Errorf("Error signing the URL of %s: %s", path, err)
+		}
+		ui.Say(fmt.Sprintf("Signed URL of %s, valid until %s", path, expiry))
+		urls[path] = url
+	}
+	return urls, nil
+}
+
+// verifyExports checks that the exported image exists in each of paths, and
+// reports the status of each destination. It returns the paths that exist
+// with their checksums, and an error listing the ones that do not.
+// Destinations that Packer is not allowed to read are assumed to exist,
+// without checksums. When kmsKeyName is set, the objects must be encrypted
+// with it.
+func verifyExports(ui packersdk.Ui, driver common.StorageDriver, paths []string, kmsKeyName string) ([]string, map[string]Checksum, error) {
+	var exported, failed []string
+	checksums := map[string]Checksum{}
+	for _, path := range paths {
+		var obj *storage.Object
+		bucket, object, err := common.ParseGCSPath(path)
+		if err == nil {
+			obj, err = driver.GetBucketObject(bucket, object)
+		}
+		if gerr, ok := err.(*googleapi.Error); ok && gerr.Code == http.StatusForbidden {
+			// Only the export instance may have access to the bucket.
+			ui.Say(fmt.Sprintf("Exported to %s, cannot be checked: %s", path, err))
+			exported = append(exported, path)
+			continue
+		}
+		if err == nil && kmsKeyName != "" && !common.IsEncryptedWith(obj, kmsKeyName) {
+			err = fmt.Errorf("the object is not encrypted with %s", kmsKeyName)
+		}
+		if err != nil {
+			ui.Error(fmt.Sprintf("Export to %s failed: %s", path, err))
+			failed = append(failed, path)
+			continue
+		}
+
+		checksum := objectChecksum(obj)
+		ui.Say(fmt.Sprintf("Exported to %s (sha256: %s, crc32c: %s)", path, checksum.SHA256, checksum.CRC32C))
+		exported = append(exported, path)
+		checksums[path] = checksum
+	}
+
+	if len(failed) > 0 {
+		return exported, checksums, fmt.Errorf("Failed to export the image to %s, see the exporter logs next to the destinations",
+			strings.Join(failed, ", "))
+	}
+	return exported, checksums, nil
+}
+
+// objectChecksum returns the checksums of an exported object: its CRC32C,
+// computed by GCS, and its SHA-256, computed by the export instance and
+// stored in the object metadata.
+func objectChecksum(obj *storage.Object) Checksum {
+	var checksum Checksum
+	if crc, err := base64.StdEncoding.DecodeString(obj.Crc32c); err == nil {
+		checksum.CRC32C = hex.EncodeToString(crc)
+	}
+	checksum.SHA256 = obj.Metadata[sha256MetadataKey]
+	return checksum
+}
+
+// exporterConfig returns the configuration of the instance exporting the
+// image, in the network of projectId unless network_project_id is set.
+func (p *PostProcessor) exporterConfig(exporterName, projectId string, metadata map[string]string) googlecompute.Config {
+	exporterConfig := googlecompute.Config{
+		DiskName:             exporterName,
+		DiskSizeGb:           p.config.DiskSizeGb,
+		DiskType:             p.config.DiskType,
+		InstanceName:         exporterName,
+		MachineType:          p.config.MachineType,
+		Metadata:             metadata,
+		Network:              p.config.Network,
+		NetworkProjectId:     projectId,
+		OmitExternalIP:       p.config.OmitExternalIP,
+		StateTimeout:         5 * time.Minute,
+		SourceImageFamily:    "debian-9-worker",
+		SourceImageProjectId: []string{"compute-image-tools"},
+		Subnetwork:           p.config.Subnetwork,
+		Tags:                 p.config.Tags,
+		Zone:                 p.config.Zone,
+		Scopes: []string{
+			"https://www.googleapis.com/auth/compute",
+			"https://www.googleapis.com/auth/devstorage.full_control",
+			"https://www.googleapis.com/auth/userinfo.email",
+			"https://www.googleapis.com/auth/logging.write",
+		},
+	}
+	if p.config.ServiceAccountEmail != "" {
+		exporterConfig.ServiceAccountEmail = p.config.ServiceAccountEmail
+	}
+	if p.config.NetworkProjectId != "" {
+		exporterConfig.NetworkProjectId = p.config.NetworkProjectId
+	}
+	return exporterConfig
 }
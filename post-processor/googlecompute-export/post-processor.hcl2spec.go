@@ -10,29 +10,41 @@ import (
 // FlatConfig is an auto-generated flat version of Config.
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlatConfig struct {
-	PackerBuildName           *string           `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
-	PackerBuilderType         *string           `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
-	PackerCoreVersion         *string           `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
-	PackerDebug               *bool             `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
-	PackerForce               *bool             `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
-	PackerOnError             *string           `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
-	PackerUserVars            map[string]string `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
-	PackerSensitiveVars       []string          `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
-	AccessToken               *string           `mapstructure:"access_token" required:"false" cty:"access_token" hcl:"access_token"`
-	AccountFile               *string           `mapstructure:"account_file" required:"false" cty:"account_file" hcl:"account_file"`
-	CredentialsFile           *string           `mapstructure:"credentials_file" required:"false" cty:"credentials_file" hcl:"credentials_file"`
-	CredentialsJSON           *string           `mapstructure:"credentials_json" required:"false" cty:"credentials_json" hcl:"credentials_json"`
-	ImpersonateServiceAccount *string           `mapstructure:"impersonate_service_account" required:"false" cty:"impersonate_service_account" hcl:"impersonate_service_account"`
-	VaultGCPOauthEngine       *string           `mapstructure:"vault_gcp_oauth_engine" cty:"vault_gcp_oauth_engine" hcl:"vault_gcp_oauth_engine"`
-	Scopes                    []string          `mapstructure:"scopes" required:"false" cty:"scopes" hcl:"scopes"`
-	DiskSizeGb                *int64            `mapstructure:"disk_size" cty:"disk_size" hcl:"disk_size"`
-	DiskType                  *string           `mapstructure:"disk_type" cty:"disk_type" hcl:"disk_type"`
-	MachineType               *string           `mapstructure:"machine_type" cty:"machine_type" hcl:"machine_type"`
-	Network                   *string           `mapstructure:"network" cty:"network" hcl:"network"`
-	Paths                     []string          `mapstructure:"paths" required:"true" cty:"paths" hcl:"paths"`
-	Subnetwork                *string           `mapstructure:"subnetwork" cty:"subnetwork" hcl:"subnetwork"`
-	Zone                      *string           `mapstructure:"zone" cty:"zone" hcl:"zone"`
-	ServiceAccountEmail       *string           `mapstructure:"service_account_email" cty:"service_account_email" hcl:"service_account_email"`
+	PackerBuildName                    *string           `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
+	PackerBuilderType                  *string           `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
+	PackerCoreVersion                  *string           `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
+	PackerDebug                        *bool             `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
+	PackerForce                        *bool             `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
+	PackerOnError                      *string           `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
+	PackerUserVars                     map[string]string `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
+	PackerSensitiveVars                []string          `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
+	AccessToken                        *string           `mapstructure:"access_token" required:"false" cty:"access_token" hcl:"access_token"`
+	AccountFile                        *string           `mapstructure:"account_file" required:"false" cty:"account_file" hcl:"account_file"`
+	BillingProject                     *string           `mapstructure:"billing_project" required:"false" cty:"billing_project" hcl:"billing_project"`
+	AuthScopes                         []string          `mapstructure:"auth_scopes" required:"false" cty:"auth_scopes" hcl:"auth_scopes"`
+	GoogleAccess                       *string           `mapstructure:"google_access" required:"false" cty:"google_access" hcl:"google_access"`
+	ProxyURL                           *string           `mapstructure:"proxy_url" required:"false" cty:"proxy_url" hcl:"proxy_url"`
+	CredentialsFile                    *string           `mapstructure:"credentials_file" required:"false" cty:"credentials_file" hcl:"credentials_file"`
+	CredentialsJSON                    *string           `mapstructure:"credentials_json" required:"false" cty:"credentials_json" hcl:"credentials_json"`
+	ImpersonateServiceAccount          *string           `mapstructure:"impersonate_service_account" required:"false" cty:"impersonate_service_account" hcl:"impersonate_service_account"`
+	ImpersonateServiceAccountDelegates []string          `mapstructure:"impersonate_service_account_delegates" required:"false" cty:"impersonate_service_account_delegates" hcl:"impersonate_service_account_delegates"`
+	VaultGCPOauthEngine                *string           `mapstructure:"vault_gcp_oauth_engine" cty:"vault_gcp_oauth_engine" hcl:"vault_gcp_oauth_engine"`
+	Scopes                             []string          `mapstructure:"scopes" required:"false" cty:"scopes" hcl:"scopes"`
+	DiskSizeGb                         *int64            `mapstructure:"disk_size" cty:"disk_size" hcl:"disk_size"`
+	DiskType                           *string           `mapstructure:"disk_type" cty:"disk_type" hcl:"disk_type"`
+	MachineType                        *string           `mapstructure:"machine_type" cty:"machine_type" hcl:"machine_type"`
+	Network                            *string           `mapstructure:"network" cty:"network" hcl:"network"`
+	NetworkProjectId                   *string           `mapstructure:"network_project_id" cty:"network_project_id" hcl:"network_project_id"`
+	OmitExternalIP                     *bool             `mapstructure:"omit_external_ip" cty:"omit_external_ip" hcl:"omit_external_ip"`
+	Tags                               []string          `mapstructure:"tags" cty:"tags" hcl:"tags"`
+	Paths                              []string          `mapstructure:"paths" required:"true" cty:"paths" hcl:"paths"`
+	KmsKeyName                         *string           `mapstructure:"kms_key_name" cty:"kms_key_name" hcl:"kms_key_name"`
+	SignedURLDuration                  *string           `mapstructure:"signed_url_duration" cty:"signed_url_duration" hcl:"signed_url_duration"`
+	SignedURLServiceAccount            *string           `mapstructure:"signed_url_service_account" cty:"signed_url_service_account" hcl:"signed_url_service_account"`
+	Format                             *string           `mapstructure:"format" cty:"format" hcl:"format"`
+	Subnetwork                         *string           `mapstructure:"subnetwork" cty:"subnetwork" hcl:"subnetwork"`
+	Zone                               *string           `mapstructure:"zone" cty:"zone" hcl:"zone"`
+	ServiceAccountEmail                *string           `mapstructure:"service_account_email" cty:"service_account_email" hcl:"service_account_email"`
 }
 
 // FlatMapstructure returns a new FlatConfig.
@@ -47,29 +59,41 @@ func (*Config) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec }
 // The decoded values from this spec will then be applied to a FlatConfig.
 func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 	s := map[string]hcldec.Spec{
-		"packer_build_name":           &hcldec.AttrSpec{Name: "packer_build_name", Type: cty.String, Required: false},
-		"packer_builder_type":         &hcldec.AttrSpec{Name: "packer_builder_type", Type: cty.String, Required: false},
-		"packer_core_version":         &hcldec.AttrSpec{Name: "packer_core_version", Type: cty.String, Required: false},
-		"packer_debug":                &hcldec.AttrSpec{Name: "packer_debug", Type: cty.Bool, Required: false},
-		"packer_force":                &hcldec.AttrSpec{Name: "packer_force", Type: cty.Bool, Required: false},
-		"packer_on_error":             &hcldec.AttrSpec{Name: "packer_on_error", Type: cty.String, Required: false},
-		"packer_user_variables":       &hcldec.AttrSpec{Name: "packer_user_variables", Type: cty.Map(cty.String), Required: false},
-		"packer_sensitive_variables":  &hcldec.AttrSpec{Name: "packer_sensitive_variables", Type: cty.List(cty.String), Required: false},
-		"access_token":                &hcldec.AttrSpec{Name: "access_token", Type: cty.String, Required: false},
-		"account_file":                &hcldec.AttrSpec{Name: "account_file", Type: cty.String, Required: false},
-		"credentials_file":            &hcldec.AttrSpec{Name: "credentials_file", Type: cty.String, Required: false},
-		"credentials_json":            &hcldec.AttrSpec{Name: "credentials_json", Type: cty.String, Required: false},
-		"impersonate_service_account": &hcldec.AttrSpec{Name: "impersonate_service_account", Type: cty.String, Required: false},
-		"vault_gcp_oauth_engine":      &hcldec.AttrSpec{Name: "vault_gcp_oauth_engine", Type: cty.String, Required: false},
-		"scopes":                      &hcldec.AttrSpec{Name: "scopes", Type: cty.List(cty.String), Required: false},
-		"disk_size":                   &hcldec.AttrSpec{Name: "disk_size", Type: cty.Number, Required: false},
-		"disk_type":                   &hcldec.AttrSpec{Name: "disk_type", Type: cty.String, Required: false},
-		"machine_type":                &hcldec.AttrSpec{Name: "machine_type", Type: cty.String, Required: false},
-		"network":                     &hcldec.AttrSpec{Name: "network", Type: cty.String, Required: false},
-		"paths":                       &hcldec.AttrSpec{Name: "paths", Type: cty.List(cty.String), Required: false},
-		"subnetwork":                  &hcldec.AttrSpec{Name: "subnetwork", Type: cty.String, Required: false},
-		"zone":                        &hcldec.AttrSpec{Name: "zone", Type: cty.String, Required: false},
-		"service_account_email":       &hcldec.AttrSpec{Name: "service_account_email", Type: cty.String, Required: false},
+		"packer_build_name":                     &hcldec.AttrSpec{Name: "packer_build_name", Type: cty.String, Required: false},
+		"packer_builder_type":                   &hcldec.AttrSpec{Name: "packer_builder_type", Type: cty.String, Required: false},
+		"packer_core_version":                   &hcldec.AttrSpec{Name: "packer_core_version", Type: cty.String, Required: false},
+		"packer_debug":                          &hcldec.AttrSpec{Name: "packer_debug", Type: cty.Bool, Required: false},
+		"packer_force":                          &hcldec.AttrSpec{Name: "packer_force", Type: cty.Bool, Required: false},
+		"packer_on_error":                       &hcldec.AttrSpec{Name: "packer_on_error", Type: cty.String, Required: false},
+		"packer_user_variables":                 &hcldec.AttrSpec{Name: "packer_user_variables", Type: cty.Map(cty.String), Required: false},
+		"packer_sensitive_variables":            &hcldec.AttrSpec{Name: "packer_sensitive_variables", Type: cty.List(cty.String), Required: false},
+		"access_token":                          &hcldec.AttrSpec{Name: "access_token", Type: cty.String, Required: false},
+		"account_file":                          &hcldec.AttrSpec{Name: "account_file", Type: cty.String, Required: false},
+		"billing_project":                       &hcldec.AttrSpec{Name: "billing_project", Type: cty.String, Required: false},
+		"auth_scopes":                           &hcldec.AttrSpec{Name: "auth_scopes", Type: cty.List(cty.String), Required: false},
+		"google_access":                         &hcldec.AttrSpec{Name: "google_access", Type: cty.String, Required: false},
+		"proxy_url":                             &hcldec.AttrSpec{Name: "proxy_url", Type: cty.String, Required: false},
+		"credentials_file":                      &hcldec.AttrSpec{Name: "credentials_file", Type: cty.String, Required: false},
+		"credentials_json":                      &hcldec.AttrSpec{Name: "credentials_json", Type: cty.String, Required: false},
+		"impersonate_service_account":           &hcldec.AttrSpec{Name: "impersonate_service_account", Type: cty.String, Required: false},
+		"impersonate_service_account_delegates": &hcldec.AttrSpec{Name: "impersonate_service_account_delegates", Type: cty.List(cty.String), Required: false},
+		"vault_gcp_oauth_engine":                &hcldec.AttrSpec{Name: "vault_gcp_oauth_engine", Type: cty.String, Required: false},
+		"scopes":                                &hcldec.AttrSpec{Name: "scopes", Type: cty.List(cty.String), Required: false},
+		"disk_size":                             &hcldec.AttrSpec{Name: "disk_size", Type: cty.Number, Required: false},
+		"disk_type":                             &hcldec.AttrSpec{Name: "disk_type", Type: cty.String, Required: false},
+		"machine_type":                          &hcldec.AttrSpec{Name: "machine_type", Type: cty.String, Required: false},
+		"network":                               &hcldec.AttrSpec{Name: "network", Type: cty.String, Required: false},
+		"network_project_id":                    &hcldec.AttrSpec{Name: "network_project_id", Type: cty.String, Required: false},
+		"omit_external_ip":                      &hcldec.AttrSpec{Name: "omit_external_ip", Type: cty.Bool, Required: false},
+		"tags":                                  &hcldec.AttrSpec{Name: "tags", Type: cty.List(cty.String), Required: false},
+		"paths":                                 &hcldec.AttrSpec{Name: "paths", Type: cty.List(cty.String), Required: false},
+		"kms_key_name":                          &hcldec.AttrSpec{Name: "kms_key_name", Type: cty.String, Required: false},
+		"signed_url_duration":                   &hcldec.AttrSpec{Name: "signed_url_duration", Type: cty.String, Required: false},
+		"signed_url_service_account":            &hcldec.AttrSpec{Name: "signed_url_service_account", Type: cty.String, Required: false},
+		"format":                                &hcldec.AttrSpec{Name: "format", Type: cty.String, Required: false},
+		"subnetwork":                            &hcldec.AttrSpec{Name: "subnetwork", Type: cty.String, Required: false},
+		"zone":                                  &hcldec.AttrSpec{Name: "zone", Type: cty.String, Required: false},
+		"service_account_email":                 &hcldec.AttrSpec{Name: "service_account_email", Type: cty.String, Required: false},
 	}
 	return s
 }
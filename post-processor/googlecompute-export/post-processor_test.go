@@ -0,0 +1,172 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecomputeexport
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/storage/v1"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"paths": []string{"gs://mybucket/path/to/disk.tar.gz"},
+	}
+}
+
+func TestPostProcessorConfigure_format(t *testing.T) {
+	cases := []struct {
+		format string
+		ok     bool
+	}{
+		{"", true},
+		{"vmdk", true},
+		{"vhdx", true},
+		{"vpc", true},
+		{"qcow2", true},
+		{"ova", false},
+	}
+
+	for _, tc := range cases {
+		raw := testConfig()
+		raw["format"] = tc.format
+
+		var p PostProcessor
+		err := p.Configure(raw)
+		if tc.ok && err != nil {
+			t.Errorf("format %q: unexpected error: %s", tc.format, err)
+		}
+		if !tc.ok && err == nil {
+			t.Errorf("format %q: expected an error", tc.format)
+		}
+	}
+}
+
+func TestVerifyExports(t *testing.T) {
+	driver := &common.StorageDriverMock{
+		GetBucketObjectResult: map[string]*storage.Object{
+			"bucket-us/path/to/disk.tar.gz": {
+				Name:     "path/to/disk.tar.gz",
+				Crc32c:   "AAAAAA==",
+				Metadata: map[string]string{"sha256": "e3b0c442"},
+			},
+		},
+	}
+
+	paths, checksums, err := verifyExports(&packersdk.MockUi{}, driver, []string{
+		"gs://bucket-us/path/to/disk.tar.gz",
+		"gs://bucket-eu/path/to/disk.tar.gz",
+	}, "")
+	if err == nil || !strings.Contains(err.Error(), "gs://bucket-eu/path/to/disk.tar.gz") {
+		t.Errorf("expected an error for the missing destination, got %v", err)
+	}
+	if len(paths) != 1 || paths[0] != "gs://bucket-us/path/to/disk.tar.gz" {
+		t.Errorf("only the existing destinations should be returned, got %v", paths)
+	}
+	expected := Checksum{SHA256: "e3b0c442", CRC32C: "00000000"}
+	if checksums["gs://bucket-us/path/to/disk.tar.gz"] != expected {
+		t.Errorf("expected checksums %v, got %v", expected, checksums)
+	}
+}
+
+func TestVerifyExports_forbidden(t *testing.T) {
+	driver := &common.StorageDriverMock{
+		GetBucketObjectErr: &googleapi.Error{Code: 403},
+	}
+
+	paths, _, err := verifyExports(&packersdk.MockUi{}, driver, []string{"gs://bucket/disk.tar.gz"}, "")
+	if err != nil {
+		t.Fatalf("unreadable destinations should not fail the export: %s", err)
+	}
+	if len(paths) != 1 {
+		t.Errorf("unreadable destinations should be kept, got %v", paths)
+	}
+}
+
+func TestVerifyExports_kmsKeyName(t *testing.T) {
+	key := "projects/my-project/locations/us/keyRings/ring/cryptoKeys/key"
+	driver := &common.StorageDriverMock{
+		GetBucketObjectResult: map[string]*storage.Object{
+			"bucket-us/disk.tar.gz": {KmsKeyName: key + "/cryptoKeyVersions/1"},
+			"bucket-eu/disk.tar.gz": {},
+		},
+	}
+
+	paths, _, err := verifyExports(&packersdk.MockUi{}, driver, []string{
+		"gs://bucket-us/disk.tar.gz",
+		"gs://bucket-eu/disk.tar.gz",
+	}, key)
+	if err == nil || !strings.Contains(err.Error(), "gs://bucket-eu/disk.tar.gz") {
+		t.Errorf("expected an error for the object not encrypted with the key, got %v", err)
+	}
+	if len(paths) != 1 || paths[0] != "gs://bucket-us/disk.tar.gz" {
+		t.Errorf("only the encrypted destinations should be returned, got %v", paths)
+	}
+}
+
+func TestSignExports(t *testing.T) {
+	driver := &common.StorageDriverMock{SignURLResult: "https://storage.googleapis.com/bucket/disk.tar.gz?X-Goog-Signature=abc"}
+
+	ui := &packersdk.MockUi{}
+	urls, err := signExports(ui, driver, []string{"gs://bucket/disk.tar.gz"}, 24*time.Hour, "signer@my-project.iam.gserviceaccount.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if urls["gs://bucket/disk.tar.gz"] != driver.SignURLResult {
+		t.Errorf("bad signed URLs: %v", urls)
+	}
+	if driver.SignURLBucket != "bucket" || driver.SignURLObjectName != "disk.tar.gz" ||
+		driver.SignURLExpires != 24*time.Hour || driver.SignURLServiceAccount != "signer@my-project.iam.gserviceaccount.com" {
+		t.Errorf("bad signing request: %#v", driver)
+	}
+	if strings.Contains(ui.SayMessages[0].Message, "X-Goog-Signature") {
+		t.Errorf("the signed URL should not be printed: %s", ui.SayMessages[0].Message)
+	}
+}
+
+func TestPostProcessorConfigure_signedURLDuration(t *testing.T) {
+	raw := testConfig()
+	raw["signed_url_duration"] = "200h"
+
+	var p PostProcessor
+	if err := p.Configure(raw); err == nil {
+		t.Error("signed URLs cannot be valid for more than 7 days")
+	}
+}
+
+func TestPostProcessor_exporterConfig(t *testing.T) {
+	var p PostProcessor
+	if err := p.Configure(testConfig()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	c := p.exporterConfig("image-exporter", "build-project", nil)
+	if c.NetworkProjectId != "build-project" || c.OmitExternalIP || len(c.Tags) != 0 {
+		t.Errorf("bad defaults: %#v", c)
+	}
+
+	raw := testConfig()
+	raw["network_project_id"] = "host-project"
+	raw["omit_external_ip"] = true
+	raw["subnetwork"] = "exporters"
+	raw["tags"] = []string{"allow-iap", "exporter"}
+	p = PostProcessor{}
+	if err := p.Configure(raw); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	c = p.exporterConfig("image-exporter", "build-project", nil)
+	if c.NetworkProjectId != "host-project" {
+		t.Errorf("the exporter should use the network of network_project_id, got %s", c.NetworkProjectId)
+	}
+	if !c.OmitExternalIP {
+		t.Error("the exporter should have no external IP")
+	}
+	if strings.Join(c.Tags, ",") != "allow-iap,exporter" {
+		t.Errorf("bad tags: %v", c.Tags)
+	}
+}
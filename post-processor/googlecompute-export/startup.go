@@ -34,6 +34,13 @@ IMAGENAME=$(GetMetadata image_name)
 NAME=$(GetMetadata name)
 DISKNAME=${NAME}-toexport
 PATHS=($(GetMetadata paths))
+FORMAT=$(GetMetadata format)
+KMSKEYNAME=$(GetMetadata kms_key_name)
+
+GSUTILOPTS=(-o GSUtil:parallel_composite_upload_threshold=100M)
+if [ -n "${KMSKEYNAME}" ]; then
+  GSUTILOPTS+=(-o "GSUtil:encryption_key=${KMSKEYNAME}")
+fi
 
 Exit () {
   for i in ${PATHS[@]}; do
@@ -50,6 +57,8 @@ echo "Instance name - ${NAME}"
 echo "Instance zone - ${ZONE}"
 echo "Disk name - ${DISKNAME}"
 echo "Export paths - ${PATHS}"
+echo "Export format - ${FORMAT:-tar.gz}"
+echo "KMS key - ${KMSKEYNAME:-none}"
 echo "####################################"
 
 echo "Creating disk from image to be exported..."
@@ -64,11 +73,39 @@ if ! gcloud compute instances attach-disk ${NAME} --disk ${DISKNAME} --device-na
   Exit 1
 fi
 
-echo "GCEExport: Running export tool."
-gce_export -gcs_path "${PATHS[0]}" -disk /dev/disk/by-id/google-toexport -y
-if [ $? -ne 0 ]; then
-  echo "ExportFailed: Failed to export disk source to ${PATHS[0]}."
-  Exit 1
+if [ -z "${FORMAT}" ]; then
+  echo "GCEExport: Running export tool."
+  gce_export -gcs_path "${PATHS[0]}" -disk /dev/disk/by-id/google-toexport -y
+  if [ $? -ne 0 ]; then
+    echo "ExportFailed: Failed to export disk source to ${PATHS[0]}."
+    Exit 1
+  fi
+  if [ -n "${KMSKEYNAME}" ]; then
+    echo "GCEExport: Encrypting ${PATHS[0]} with ${KMSKEYNAME}."
+    if ! gsutil "${GSUTILOPTS[@]}" rewrite -k ${PATHS[0]}; then
+      echo "ExportFailed: Failed to encrypt ${PATHS[0]}."
+      Exit 1
+    fi
+  fi
+else
+  OPTIONS=""
+  if [ "${FORMAT}" = "vmdk" ]; then
+    OPTIONS="-o subformat=streamOptimized"
+  fi
+  CONVERTED=/var/tmp/${DISKNAME}.${FORMAT}
+
+  echo "GCEExport: Converting disk to ${FORMAT}."
+  if ! qemu-img convert -p -O ${FORMAT} ${OPTIONS} /dev/disk/by-id/google-toexport ${CONVERTED}; then
+    echo "ExportFailed: Failed to convert disk to ${FORMAT}."
+    Exit 1
+  fi
+
+  echo "GCEExport: Uploading ${FORMAT} image to ${PATHS[0]}."
+  if ! gsutil "${GSUTILOPTS[@]}" cp ${CONVERTED} ${PATHS[0]}; then
+    echo "ExportFailed: Failed to upload disk to ${PATHS[0]}."
+    Exit 1
+  fi
+  rm -f ${CONVERTED}
 fi
 
 echo "ExportSuccess"
@@ -79,6 +116,14 @@ if ! gcloud compute instances detach-disk ${NAME} --disk ${DISKNAME} --zone ${ZO
   echo "Failed to detach disk."
 fi
 
+echo "Computing SHA-256 of ${PATHS[0]}..."
+if SHA256=$(set -o pipefail; gsutil cat ${PATHS[0]} | sha256sum | cut -d' ' -f1); then
+  echo "SHA-256 - ${SHA256}"
+  gsutil setmeta -h "x-goog-meta-%s:${SHA256}" ${PATHS[0]}
+else
+  echo "Failed to compute SHA-256 of ${PATHS[0]}."
+fi
+
 FAIL=0
 echo "Deleting disk..."
 if ! gcloud compute disks delete ${DISKNAME} --zone ${ZONE}; then
@@ -86,10 +131,19 @@ if ! gcloud compute disks delete ${DISKNAME} --zone ${ZONE}; then
   FAIL=1
 fi
 
+PIDS=()
 for i in ${PATHS[@]:1}; do
   echo "Copying archive image to ${i}..."
-  if ! gsutil -o GSUtil:parallel_composite_upload_threshold=100M cp ${PATHS[0]} ${i}; then
-    echo "Failed to copy image to ${i}."
+  gsutil "${GSUTILOPTS[@]}" cp ${PATHS[0]} ${i} &
+  PIDS+=($!)
+done
+
+for n in ${!PIDS[@]}; do
+  DEST=${PATHS[$((n+1))]}
+  if wait ${PIDS[$n]}; then
+    echo "CopySuccess: ${DEST}"
+  else
+    echo "CopyFailed: Failed to copy image to ${DEST}."
     FAIL=1
   fi
 done
@@ -97,4 +151,4 @@ done
 SetMetadata %s %s
 
 Exit ${FAIL}
-`, googlecompute.StartupWrappedScriptKey, googlecompute.StartupScriptStatusKey, googlecompute.StartupScriptStatusDone)
+`, googlecompute.StartupWrappedScriptKey, sha256MetadataKey, googlecompute.StartupScriptStatusKey, googlecompute.StartupScriptStatusDone)
@@ -15,6 +15,9 @@ const BuilderId = "packer.post-processor.googlecompute-import"
 
 type Artifact struct {
 	paths []string
+	// stagingObject is the GCS URL of the uploaded disk image, when it is
+	// kept with skip_clean.
+	stagingObject string
 }
 
 var _ packersdk.Artifact = new(Artifact)
@@ -38,8 +41,11 @@ func (a *Artifact) String() string {
 }
 
 func (a *Artifact) State(name string) interface{} {
-	if name == registryimage.ArtifactStateURI {
+	switch name {
+	case registryimage.ArtifactStateURI:
 		return a.hcpPackerRegistryMetadata()
+	case "StagingObject":
+		return a.stagingObject
 	}
 	return nil
 }
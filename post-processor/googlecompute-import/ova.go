@@ -0,0 +1,232 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecomputeimport
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// ovfEnvelope is the subset of an OVF descriptor needed to find the disks of
+// the virtual machine.
+type ovfEnvelope struct {
+	Files []struct {
+		ID   string `xml:"id,attr"`
+		Href string `xml:"href,attr"`
+	} `xml:"References>File"`
+	Disks []struct {
+		FileRef string `xml:"fileRef,attr"`
+	} `xml:"DiskSection>Disk"`
+}
+
+// ovfDisks returns the paths of the disk files referenced by the OVF
+// descriptor at path, in the order of its disk section. The first one is the
+// boot disk.
+func ovfDisks(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var env ovfEnvelope
+	if err := xml.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("Error parsing OVF descriptor %s: %s", path, err)
+	}
+
+	hrefs := map[string]string{}
+	for _, f := range env.Files {
+		hrefs[f.ID] = f.Href
+	}
+
+	dir := filepath.Dir(path)
+	var disks []string
+	for _, d := range env.Disks {
+		href, ok := hrefs[d.FileRef]
+		if !ok {
+			return nil, fmt.Errorf("OVF descriptor %s references an unknown file %q", path, d.FileRef)
+		}
+		disks = append(disks, filepath.Join(dir, filepath.FromSlash(href)))
+	}
+	if len(disks) == 0 {
+		return nil, fmt.Errorf("No disk found in OVF descriptor %s", path)
+	}
+	return disks, nil
+}
+
+// extractOVA unpacks the OVA archive at path into dir, and returns the path
+// of its OVF descriptor.
+func extractOVA(path, dir string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	ovf := ""
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("Error reading OVA %s: %s", path, err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		// OVA archives are flat, ignore any directory to stay within dir.
+		name := filepath.Base(filepath.FromSlash(hdr.Name))
+		if err := writeFile(filepath.Join(dir, name), tr); err != nil {
+			return "", err
+		}
+		if ovf == "" && strings.HasSuffix(strings.ToLower(name), ".ovf") {
+			ovf = filepath.Join(dir, name)
+		}
+	}
+	if ovf == "" {
+		return "", fmt.Errorf("No OVF descriptor found in OVA %s", path)
+	}
+	return ovf, nil
+}
+
+func writeFile(path string, r io.Reader) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// rawDiskTarball converts disk to a raw image with qemu-img, and packs it into
+// dir/disk.tar.gz as expected by the GCE image import.
+func rawDiskTarball(disk, dir string) (string, error) {
+	raw := filepath.Join(dir, "disk.raw")
+	out, err := exec.Command("qemu-img", "convert", "-O", "raw", disk, raw).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("Error converting %s to a raw disk, qemu-img is needed to convert disk images: %s\n%s", disk, err, out)
+	}
+	defer os.Remove(raw)
+
+	tarball := filepath.Join(dir, "disk.tar.gz")
+	if err := writeRawTarball(raw, tarball); err != nil {
+		return "", fmt.Errorf("Error packing %s: %s", raw, err)
+	}
+	return tarball, nil
+}
+
+func writeRawTarball(raw, tarball string) error {
+	f, err := os.Create(tarball)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := packRawTarball(raw, f); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// streamRawTarball returns a compressed raw disk image of raw, packed as it
+// is read instead of in a file.
+func streamRawTarball(raw string) io.ReadCloser {
+	r, w := io.Pipe()
+	go func() {
+		w.CloseWithError(packRawTarball(raw, w))
+	}()
+	return r
+}
+
+// packRawTarball writes a compressed raw disk image of raw to w.
+func packRawTarball(raw string, w io.Writer) error {
+	in, err := os.Open(raw)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	err = tw.WriteHeader(&tar.Header{
+		Name:    "disk.raw",
+		Mode:    0644,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		Format:  tar.FormatGNU,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tw, in); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// tarballFromOVF converts the boot disk of the OVF or OVA at path to a
+// compressed raw disk image in dir.
+func tarballFromOVF(ui packersdk.Ui, path, dir string) (string, error) {
+	ovf := path
+	if strings.HasSuffix(strings.ToLower(path), ".ova") {
+		ui.Say(fmt.Sprintf("Extracting %s...", path))
+		var err error
+		ovf, err = extractOVA(path, dir)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	disks, err := ovfDisks(ovf)
+	if err != nil {
+		return "", err
+	}
+	if len(disks) > 1 {
+		ui.Say(fmt.Sprintf("Warning: %s has %d disks, only the boot disk %s is imported", path, len(disks), disks[0]))
+	}
+
+	ui.Say(fmt.Sprintf("Converting %s to a raw disk image...", disks[0]))
+	return rawDiskTarball(disks[0], dir)
+}
+
+// sourceTarball returns a compressed raw disk image of source, converting it
+// in dir unless it already is one.
+func sourceTarball(ui packersdk.Ui, source, dir string) (string, error) {
+	lower := strings.ToLower(source)
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz"):
+		return source, nil
+	case strings.HasSuffix(lower, ".ova"), strings.HasSuffix(lower, ".ovf"):
+		return tarballFromOVF(ui, source, dir)
+	case strings.HasSuffix(lower, ".raw"):
+		ui.Say(fmt.Sprintf("Compressing %s...", source))
+		tarball := filepath.Join(dir, "disk.tar.gz")
+		if err := writeRawTarball(source, tarball); err != nil {
+			return "", fmt.Errorf("Error packing %s: %s", source, err)
+		}
+		return tarball, nil
+	default:
+		ui.Say(fmt.Sprintf("Converting %s to a raw disk image...", source))
+		return rawDiskTarball(source, dir)
+	}
+}
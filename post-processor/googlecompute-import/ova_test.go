@@ -0,0 +1,163 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecomputeimport
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+const testOVF = `<?xml version="1.0" encoding="UTF-8"?>
+<Envelope xmlns="http://schemas.dmtf.org/ovf/envelope/1" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1">
+  <References>
+    <File ovf:id="file2" ovf:href="vm-disk2.vmdk"/>
+    <File ovf:id="file1" ovf:href="vm-disk1.vmdk"/>
+  </References>
+  <DiskSection>
+    <Disk ovf:capacity="10" ovf:diskId="vmdisk1" ovf:fileRef="file1"/>
+    <Disk ovf:capacity="10" ovf:diskId="vmdisk2" ovf:fileRef="file2"/>
+  </DiskSection>
+</Envelope>
+`
+
+func TestExtractOVA(t *testing.T) {
+	dir := t.TempDir()
+
+	ova := filepath.Join(dir, "vm.ova")
+	f, err := os.Create(ova)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(f)
+	for name, content := range map[string]string{
+		"vm.ovf":        testOVF,
+		"vm-disk1.vmdk": "disk1",
+		"vm-disk2.vmdk": "disk2",
+	} {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw.Close()
+	f.Close()
+
+	out := filepath.Join(dir, "out")
+	if err := os.Mkdir(out, 0755); err != nil {
+		t.Fatal(err)
+	}
+	ovf, err := extractOVA(ova, out)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if ovf != filepath.Join(out, "vm.ovf") {
+		t.Errorf("bad OVF descriptor: %s", ovf)
+	}
+
+	disks, err := ovfDisks(ovf)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := []string{filepath.Join(out, "vm-disk1.vmdk"), filepath.Join(out, "vm-disk2.vmdk")}
+	if len(disks) != 2 || disks[0] != expected[0] || disks[1] != expected[1] {
+		t.Errorf("expected disks %v in the disk section order, got %v", expected, disks)
+	}
+	if data, _ := os.ReadFile(disks[0]); string(data) != "disk1" {
+		t.Errorf("bad boot disk content: %q", data)
+	}
+}
+
+func TestWriteRawTarball(t *testing.T) {
+	dir := t.TempDir()
+	raw := filepath.Join(dir, "disk.raw")
+	if err := os.WriteFile(raw, []byte("raw disk"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tarball := filepath.Join(dir, "disk.tar.gz")
+	if err := writeRawTarball(raw, tarball); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	f, err := os.Open(tarball)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	hdr, err := tr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.Name != "disk.raw" {
+		t.Errorf("the image must be named disk.raw, got %s", hdr.Name)
+	}
+	if data, _ := io.ReadAll(tr); string(data) != "raw disk" {
+		t.Errorf("bad content: %q", data)
+	}
+}
+
+func TestSourceTarball(t *testing.T) {
+	dir := t.TempDir()
+	ui := &packersdk.MockUi{}
+
+	tarball, err := sourceTarball(ui, "/path/to/disk.tar.gz", dir)
+	if err != nil || tarball != "/path/to/disk.tar.gz" {
+		t.Errorf("compressed raw disk images should be uploaded as is, got %s, %v", tarball, err)
+	}
+
+	raw := filepath.Join(dir, "my-disk.raw")
+	if err := os.WriteFile(raw, []byte("raw disk"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tarball, err = sourceTarball(ui, raw, dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if tarball != filepath.Join(dir, "disk.tar.gz") {
+		t.Errorf("raw disks should be compressed in the temporary directory, got %s", tarball)
+	}
+}
+
+func TestStreamRawTarball(t *testing.T) {
+	raw := filepath.Join(t.TempDir(), "disk.raw")
+	if err := os.WriteFile(raw, []byte("raw disk"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := streamRawTarball(raw)
+	defer r.Close()
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	hdr, err := tr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.Name != "disk.raw" {
+		t.Errorf("the image must be named disk.raw, got %s", hdr.Name)
+	}
+	if data, _ := io.ReadAll(tr); string(data) != "raw disk" {
+		t.Errorf("bad content: %q", data)
+	}
+
+	r = streamRawTarball(filepath.Join(t.TempDir(), "missing.raw"))
+	if _, err := io.ReadAll(r); err == nil {
+		t.Error("a missing image should fail the stream")
+	}
+}
@@ -8,15 +8,12 @@ package googlecomputeimport
 
 import (
 	"context"
-	"crypto/x509"
-	"encoding/base64"
-	"encoding/pem"
 	"fmt"
-	"io"
-	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"google.golang.org/api/compute/v1"
 	"google.golang.org/api/storage/v1"
@@ -46,22 +43,48 @@ type Config struct {
 	ProjectId string `mapstructure:"project_id" required:"true"`
 	IAP       bool   `mapstructure-to-hcl:",skip"`
 	//The name of the GCS bucket where the raw disk image will be uploaded.
+	//Not needed with `source_gcs_path`.
 	Bucket string `mapstructure:"bucket" required:"true"`
+	//The credentials used to upload to, and clean up, `bucket`, when they
+	//differ from the ones used to create the image, e.g. when the bucket lives
+	//in another project or organization. This block accepts the same
+	//authentication options as the post-processor, e.g. `credentials_file` or
+	//`impersonate_service_account`. Defaults to the post-processor's
+	//credentials.
+	//
+	//The account creating the image still needs read access to the uploaded
+	//object.
+	StorageAuthentication *common.Authentication `mapstructure:"storage_authentication"`
 	//The name of the GCS object in `bucket` where
 	//the RAW disk image will be copied for import. This is treated as a
 	//[template engine](/packer/docs/templates/legacy_json_templates/engine). Therefore, you
 	//may use user variables and template functions in this field. Defaults to
 	//`packer-import-{{timestamp}}.tar.gz`.
 	GCSObjectName string `mapstructure:"gcs_object_name"`
+	//Custom metadata set on the object uploaded to `bucket`, e.g. to trace it
+	//back to a pipeline run in audited environments. The object is always
+	//tagged with the `packer-image-name` and `packer-build-name` metadata,
+	//and its Custom-Time is set to the upload time, so that a bucket
+	//lifecycle rule with a `daysSinceCustomTime` condition can delete the
+	//objects kept with `skip_clean`.
+	StagingObjectMetadata map[string]string `mapstructure:"staging_object_metadata"`
 	// Specifies the architecture or processor type that this image can support. Must be one of: `arm64` or `x86_64`. Defaults to `ARCHITECTURE_UNSPECIFIED`.
 	ImageArchitecture string `mapstructure:"image_architecture"`
-	//The description of the resulting image.
+	//The description of the resulting image. Defaults to `Imported by Packer`.
 	ImageDescription string `mapstructure:"image_description"`
-	//The name of the image family to which the resulting image belongs.
+	//The name of the image family to which the resulting image belongs. The
+	//family always resolves to its latest image that is not deprecated, so
+	//the imported image can be rolled out like the ones built by the
+	//`googlecompute` builder.
 	ImageFamily string `mapstructure:"image_family"`
 	//A list of features to enable on the guest operating system. Applicable only for bootable images. Valid
-	//values are `MULTI_IP_SUBNET`, `UEFI_COMPATIBLE`,
-	//`VIRTIO_SCSI_MULTIQUEUE`, `GVNIC` and `WINDOWS` currently.
+	//values are `MULTI_IP_SUBNET`, `UEFI_COMPATIBLE`, `VIRTIO_SCSI_MULTIQUEUE`,
+	//`GVNIC`, `IDPF`, `SEV_CAPABLE`, `SEV_SNP_CAPABLE`, `SEV_LIVE_MIGRATABLE`,
+	//`SEV_LIVE_MIGRATABLE_V2`, `TDX_CAPABLE`, `SUSPEND_RESUME_COMPATIBLE`,
+	//`SECURE_BOOT` and `WINDOWS` currently.
+	//
+	//`UEFI_COMPATIBLE` is needed by UEFI-only images, and is always added for
+	//`arm64` images, which only boot with UEFI.
 	ImageGuestOsFeatures []string `mapstructure:"image_guest_os_features"`
 	//Key/value pair labels to apply to the created image.
 	ImageLabels map[string]string `mapstructure:"image_labels"`
@@ -69,11 +92,53 @@ type Config struct {
 	ImageName string `mapstructure:"image_name" required:"true"`
 	//Specifies a Cloud Storage location, either regional or multi-regional, where image content is to be stored. If not specified, the multi-region location closest to the source is chosen automatically.
 	ImageStorageLocations []string `mapstructure:"image_storage_locations"`
+	//A local disk image to import, in place of the input artifact. It can
+	//be a compressed raw disk image (`.tar.gz`), a raw disk (`.raw`), an OVA
+	//or OVF, or any disk image `qemu-img` can convert, e.g. `.vmdk`, `.vhd`,
+	//`.vhdx` or `.qcow2`. Other than compressed raw disk images are converted
+	//and compressed in a temporary directory before being uploaded.
+	SourceFile string `mapstructure:"source_file"`
+	//A compressed raw disk image (`.tar.gz`) already in GCS to import, as
+	//`gs://bucket/object`, in place of the input artifact. Nothing is uploaded,
+	//and the object is never deleted. The bucket may live in another project
+	//or organization: the object is checked with the
+	//`storage_authentication` credentials, if set, while the image is
+	//created with the post-processor's credentials, which need read access
+	//to the object too.
+	SourceGCSPath string `mapstructure:"source_gcs_path"`
+	//The Cloud KMS key the object of `source_gcs_path` is encrypted with, in
+	//the form
+	//`projects/((project))/locations/((location))/keyRings/((keyring))/cryptoKeys/((key))`.
+	//The object is checked to be encrypted with it before importing, and
+	//failing to read it points at the IAM bindings needed on the key: both
+	//the account creating the image and the Compute Engine service agent of
+	//`project_id` need the `roles/cloudkms.cryptoKeyDecrypter` role.
+	SourceKmsKey string `mapstructure:"source_kms_key"`
+	//The size, in MiB, of the chunks of the resumable upload to `bucket`.
+	//Larger chunks are faster, but more data is uploaded again when a chunk
+	//fails. Defaults to `16`.
+	UploadChunkSize int `mapstructure:"upload_chunk_size"`
+	//How long a failed chunk of the upload is retried, resuming the upload
+	//where it stopped, before giving up, e.g. `10m`. The upload is only
+	//resumed within a Packer run: once it gave up, running Packer again
+	//uploads the image from the start. Defaults to `32s`.
+	UploadRetryTimeout time.Duration `mapstructure:"upload_retry_timeout"`
+	//The number of parts of the image uploaded to `bucket` concurrently,
+	//then composed into `gcs_object_name`, up to `32`. Each part holds a
+	//chunk of `upload_chunk_size` in memory. Images smaller than 64 MiB per
+	//part are split in fewer parts. Defaults to `1`: a `.raw` image is then
+	//compressed as it is uploaded, instead of in a temporary file.
+	UploadParallelism int `mapstructure:"upload_parallelism"`
 	//Skip removing the TAR file uploaded to the GCS
 	//bucket after the import process has completed. "true" means that we should
 	//leave it in the GCS bucket, "false" means to clean it out. Defaults to
 	//`false`.
 	SkipClean bool `mapstructure:"skip_clean"`
+	//If true, check that the account used by Packer has the IAM permissions
+	//needed to upload to `bucket` and to create the image, before uploading
+	//anything. The result of each check is printed, and the import fails early
+	//if any is missing. Defaults to `false`.
+	PermissionsPrecheck bool `mapstructure:"permissions_precheck"`
 	//A key used to establish the trust relationship between the platform owner and the firmware. You may only specify one platform key, and it must be a valid X.509 certificate.
 	ImagePlatformKey string `mapstructure:"image_platform_key"`
 	//A key used to establish a trust relationship between the firmware and the OS. You may specify multiple comma-separated keys for this value.
@@ -114,6 +179,33 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 		p.config.GCSObjectName = "packer-import-{{timestamp}}.tar.gz"
 	}
 
+	if p.config.UploadChunkSize < 0 {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("upload_chunk_size must be positive"))
+	}
+	if p.config.UploadParallelism == 0 {
+		p.config.UploadParallelism = 1
+	}
+	if p.config.UploadParallelism < 0 || p.config.UploadParallelism > common.MaxUploadParallelism {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("upload_parallelism must be between 1 and %d",
+			common.MaxUploadParallelism))
+	}
+	if p.config.SourceFile != "" {
+		if _, err := os.Stat(p.config.SourceFile); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("source_file: %s", err))
+		}
+	}
+	if p.config.SourceGCSPath != "" {
+		if p.config.SourceFile != "" {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("source_file and source_gcs_path are mutually exclusive"))
+		}
+		if _, _, err := common.ParseGCSPath(p.config.SourceGCSPath); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("source_gcs_path: %s", err))
+		}
+	}
+	if p.config.SourceKmsKey != "" && p.config.SourceGCSPath == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("source_kms_key needs source_gcs_path"))
+	}
+
 	// Check and render gcs_object_name
 	if err = interpolate.Validate(p.config.GCSObjectName, &p.config.ctx); err != nil {
 		errs = packersdk.MultiErrorAppend(
@@ -133,6 +225,23 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 		}
 	}
 
+	features, err := guestOsFeatures(p.config.ImageGuestOsFeatures, p.config.ImageArchitecture)
+	if err != nil {
+		errs = packersdk.MultiErrorAppend(errs, err)
+	}
+	p.config.ImageGuestOsFeatures = features
+
+	hasUEFI := false
+	for _, f := range features {
+		hasUEFI = hasUEFI || f == "UEFI_COMPATIBLE"
+	}
+	if !hasUEFI && (p.config.ImagePlatformKey != "" || len(p.config.ImageKeyExchangeKey) > 0 ||
+		len(p.config.ImageSignaturesDB) > 0 || len(p.config.ImageForbiddenSignaturesDB) > 0) {
+		errs = packersdk.MultiErrorAppend(errs,
+			fmt.Errorf("image_platform_key, image_key_exchange_key, image_signatures_db and "+
+				"image_forbidden_signatures_db need the UEFI_COMPATIBLE guest OS feature"))
+	}
+
 	warns, err := p.config.Authentication.Prepare()
 	if err != nil {
 		errs = packersdk.MultiErrorAppend(errs, err)
@@ -141,6 +250,16 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 		log.Printf("[WARN] - %s", warn)
 	}
 
+	if p.config.StorageAuthentication != nil {
+		warns, err := p.config.StorageAuthentication.Prepare()
+		if err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("storage_authentication: %s", err))
+		}
+		for _, warn := range warns {
+			log.Printf("[WARN] - storage_authentication: %s", warn)
+		}
+	}
+
 	if len(p.config.Scopes) == 0 {
 		p.config.Scopes = []string{
 			storage.CloudPlatformScope,
@@ -148,10 +267,12 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 	}
 
 	templates := map[string]*string{
-		"bucket":     &p.config.Bucket,
 		"image_name": &p.config.ImageName,
 		"project_id": &p.config.ProjectId,
 	}
+	if p.config.SourceGCSPath == "" {
+		templates["bucket"] = &p.config.Bucket
+	}
 	for key, ptr := range templates {
 		if *ptr == "" {
 			errs = packersdk.MultiErrorAppend(
@@ -159,6 +280,19 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 		}
 	}
 
+	if p.config.ImageDescription == "" {
+		p.config.ImageDescription = "Imported by Packer"
+	}
+
+	if p.config.ImageName != "" && !common.ValidImageName.MatchString(p.config.ImageName) {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("Invalid image name %q: The first character must be a lowercase letter, "+
+			"and all following characters must be a dash, lowercase letter, or digit, except the last character, which cannot be a dash", p.config.ImageName))
+	}
+	if p.config.ImageFamily != "" && !common.ValidImageName.MatchString(p.config.ImageFamily) {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("Invalid image family %q: The first character must be a lowercase letter, "+
+			"and all following characters must be a dash, lowercase letter, or digit, except the last character, which cannot be a dash", p.config.ImageFamily))
+	}
+
 	if len(errs.Errors) > 0 {
 		return errs
 	}
@@ -180,19 +314,29 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifa
 		Scopes: p.config.Scopes,
 	}
 	p.config.Authentication.ApplyDriverConfig(cfg)
+	if p.config.StorageAuthentication != nil {
+		cfg.Storage = &common.GCEDriverConfig{
+			Scopes: p.config.Scopes,
+		}
+		p.config.StorageAuthentication.ApplyDriverConfig(cfg.Storage)
+	}
 	driver, err := common.NewDriverGCE(*cfg)
 	if err != nil {
 		return nil, false, false, err
 	}
 
+	ovf := findOVFFromArtifact(artifact)
 	switch artifact.BuilderId() {
 	// TODO: uncomment when Packer core stops importing this plugin.
 	// case compress.BuilderId, artifice.BuilderId:
 	case "packer.post-processor.compress", "packer.post-processor.artifice":
 		break
 	default:
+		if ovf != "" || p.config.SourceFile != "" || p.config.SourceGCSPath != "" {
+			break
+		}
 		err := fmt.Errorf(
-			"Unknown artifact type: %s\nCan only import from Compress post-processor and Artifice post-processor artifacts.",
+			"Unknown artifact type: %s\nCan only import from Compress post-processor and Artifice post-processor artifacts, or from artifacts containing an OVF or OVA.",
 			artifact.BuilderId())
 		return nil, false, false, err
 	}
@@ -202,14 +346,25 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifa
 		return nil, false, false, fmt.Errorf("Error rendering gcs_object_name template: %s", err)
 	}
 
-	tarball, err := p.findTarballFromArtifact(artifact)
-	if err != nil {
-		return nil, false, false, err
+	if p.config.PermissionsPrecheck {
+		ui.Say("Checking permissions...")
+		if err := p.checkPermissions(ui, driver); err != nil {
+			return nil, false, false, err
+		}
 	}
 
-	rawImageGcsPath, err := driver.UploadToBucket(p.config.Bucket, p.config.GCSObjectName, tarball)
-	if err != nil {
-		return nil, false, false, err
+	var rawImageGcsPath string
+	if p.config.SourceGCSPath != "" {
+		ui.Say(fmt.Sprintf("Checking source object %s...", p.config.SourceGCSPath))
+		rawImageGcsPath, err = p.checkSourceObject(driver)
+		if err != nil {
+			return nil, false, false, err
+		}
+	} else {
+		rawImageGcsPath, err = p.upload(ui, driver, artifact, ovf)
+		if err != nil {
+			return nil, false, false, err
+		}
 	}
 
 	shieldedVMStateConfig, err := CreateShieldedVMStateConfig(p.config.ImageGuestOsFeatures, p.config.ImagePlatformKey, p.config.ImageKeyExchangeKey, p.config.ImageSignaturesDB, p.config.ImageForbiddenSignaturesDB)
@@ -247,99 +402,244 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifa
 				img.SelfLink,
 			},
 		}
+		if p.config.SkipClean && p.config.SourceGCSPath == "" {
+			retArtifact.stagingObject = fmt.Sprintf("gs://%s/%s", p.config.Bucket, p.config.GCSObjectName)
+		}
 	case err := <-errCh:
 		retErr = err
 	}
 
-	if err != nil {
-		ui.Say(fmt.Sprintf("failed to create image from raw disk: %s", err))
+	if retErr != nil {
+		ui.Say(fmt.Sprintf("failed to create image from raw disk: %s", retErr))
+		if p.config.SourceGCSPath != "" {
+			retErr = fmt.Errorf("%w\nThe account creating the image needs read access to %s, "+
+				"and to its KMS key, if any, as well as the Compute Engine service agent of project %s",
+				retErr, p.config.SourceGCSPath, p.config.ProjectId)
+		}
 	}
 
-	if !p.config.SkipClean {
+	switch {
+	case p.config.SourceGCSPath != "":
+		// The source object is not ours to delete.
+	case !p.config.SkipClean:
 		ui.Say(fmt.Sprintf("deleting %s from bucket %s", p.config.GCSObjectName, p.config.Bucket))
 		err = driver.DeleteFromBucket(p.config.Bucket, p.config.GCSObjectName)
 		if err != nil {
 			return nil, false, false, err
 		}
+	default:
+		ui.Say(fmt.Sprintf("Keeping gs://%s/%s, as skip_clean is set", p.config.Bucket, p.config.GCSObjectName))
 	}
 
-	return retArtifact, false, false, retErr
+	return retArtifact, false, false, common.EnrichError(retErr)
 }
 
-func (p PostProcessor) findTarballFromArtifact(artifact packersdk.Artifact) (io.Reader, error) {
-	source := ""
-	for _, path := range artifact.Files() {
-		if strings.HasSuffix(path, ".tar.gz") {
-			source = path
-			break
+// upload uploads the disk image of artifact, or source_file, to bucket, and
+// returns its URL.
+func (p *PostProcessor) upload(ui packersdk.Ui, driver common.StorageDriver, artifact packersdk.Artifact, ovf string) (string, error) {
+	var err error
+	source := p.config.SourceFile
+	if source == "" {
+		source = ovf
+	}
+	if source == "" {
+		source, err = p.findTarballFromArtifact(artifact)
+		if err != nil {
+			return "", err
 		}
 	}
 
-	if source == "" {
-		return nil, fmt.Errorf("No tar.gz file found in list of artifacts")
+	opts := p.uploadOptions(time.Now())
+	if opts.Parallelism == 1 && strings.HasSuffix(strings.ToLower(source), ".raw") {
+		// A single upload reads the image in order, it is compressed on
+		// the fly.
+		ui.Say(fmt.Sprintf("Compressing and uploading %s to gs://%s/%s...", source, p.config.Bucket, p.config.GCSObjectName))
+		tarball := streamRawTarball(source)
+		defer tarball.Close()
+		return driver.UploadToBucket(p.config.Bucket, p.config.GCSObjectName, tarball, opts)
+	}
+
+	dir, err := os.MkdirTemp("", "packer-import")
+	if err != nil {
+		return "", err
 	}
+	defer os.RemoveAll(dir)
 
-	return os.Open(source)
+	tarballPath, err := sourceTarball(ui, source, dir)
+	if err != nil {
+		return "", err
+	}
+	tarball, err := os.Open(tarballPath)
+	if err != nil {
+		return "", err
+	}
+	defer tarball.Close()
+	info, err := tarball.Stat()
+	if err != nil {
+		return "", err
+	}
+	opts.Size = info.Size()
+
+	ui.Say(fmt.Sprintf("Uploading %s to gs://%s/%s...", tarballPath, p.config.Bucket, p.config.GCSObjectName))
+	return driver.UploadToBucket(p.config.Bucket, p.config.GCSObjectName, tarball, opts)
 }
 
-func FillFileContentBuffer(certOrKeyFile string) (*compute.FileContentBuffer, error) {
-	data, err := ioutil.ReadFile(certOrKeyFile)
+// checkSourceObject checks that the object of source_gcs_path can be read,
+// and is encrypted with source_kms_key, if set. It returns the URL of the
+// object.
+func (p *PostProcessor) checkSourceObject(driver common.StorageDriver) (string, error) {
+	bucket, object, err := common.ParseGCSPath(p.config.SourceGCSPath)
+	if err != nil {
+		return "", err
+	}
+
+	obj, err := driver.GetBucketObject(bucket, object)
 	if err != nil {
-		err := fmt.Errorf("Unable to read Certificate or Key file %s", certOrKeyFile)
-		return nil, err
+		return "", common.EnrichError(fmt.Errorf("Error reading source object %s, "+
+			"check that the storage credentials can read it: %w", p.config.SourceGCSPath, err))
 	}
-	shield := &compute.FileContentBuffer{
-		Content:  base64.StdEncoding.EncodeToString(data),
-		FileType: "X509",
+	if p.config.SourceKmsKey != "" && !common.IsEncryptedWith(obj, p.config.SourceKmsKey) {
+		return "", fmt.Errorf("Source object %s is not encrypted with source_kms_key %s, but with %q",
+			p.config.SourceGCSPath, p.config.SourceKmsKey, obj.KmsKeyName)
 	}
-	block, _ := pem.Decode(data)
 
-	if block == nil || block.Type != "CERTIFICATE" {
-		_, err = x509.ParseCertificate(data)
-	} else {
-		_, err = x509.ParseCertificate(block.Bytes)
+	return fmt.Sprintf("https://storage.googleapis.com/%s/%s", bucket, object), nil
+}
+
+// uploadOptions returns the options of the upload to bucket at now, tagging
+// the staging object so that it can be traced and expired by lifecycle rules.
+func (p *PostProcessor) uploadOptions(now time.Time) common.UploadOptions {
+	metadata := map[string]string{
+		"packer-image-name": p.config.ImageName,
+	}
+	if p.config.PackerBuildName != "" {
+		metadata["packer-build-name"] = p.config.PackerBuildName
+	}
+	for k, v := range p.config.StagingObjectMetadata {
+		metadata[k] = v
+	}
+
+	return common.UploadOptions{
+		ChunkSize:          p.config.UploadChunkSize * 1024 * 1024,
+		ChunkRetryDeadline: p.config.UploadRetryTimeout,
+		Parallelism:        p.config.UploadParallelism,
+		Metadata:           metadata,
+		CustomTime:         now,
+	}
+}
+
+// knownGuestOsFeatures are the guest OS features that can be set on an image.
+var knownGuestOsFeatures = map[string]bool{
+	"GVNIC":                     true,
+	"IDPF":                      true,
+	"MULTI_IP_SUBNET":           true,
+	"SECURE_BOOT":               true,
+	"SEV_CAPABLE":               true,
+	"SEV_LIVE_MIGRATABLE":       true,
+	"SEV_LIVE_MIGRATABLE_V2":    true,
+	"SEV_SNP_CAPABLE":           true,
+	"SUSPEND_RESUME_COMPATIBLE": true,
+	"TDX_CAPABLE":               true,
+	"UEFI_COMPATIBLE":           true,
+	"VIRTIO_SCSI_MULTIQUEUE":    true,
+	"WINDOWS":                   true,
+}
+
+// guestOsFeatures validates and upper cases features, and adds
+// UEFI_COMPATIBLE on arm64 images, which cannot boot without it.
+func guestOsFeatures(features []string, architecture string) ([]string, error) {
+	var res []string
+	var unknown []string
+	seen := map[string]bool{}
+	for _, f := range features {
+		f = strings.ToUpper(f)
+		if seen[f] {
+			continue
+		}
+		seen[f] = true
+		if !knownGuestOsFeatures[f] {
+			unknown = append(unknown, f)
+		}
+		res = append(res, f)
+	}
+	if architecture == "arm64" && !seen["UEFI_COMPATIBLE"] {
+		res = append(res, "UEFI_COMPATIBLE")
 	}
+	if len(unknown) > 0 {
+		return res, fmt.Errorf("Invalid image guest OS features: %s", strings.Join(unknown, ", "))
+	}
+	return res, nil
+}
+
+// checkPermissions checks the permissions needed on the bucket and on the
+// project to import the image.
+func (p PostProcessor) checkPermissions(ui packersdk.Ui, driver common.Driver) error {
+	name, feature := p.config.Bucket, "upload"
+	bucket := []string{"storage.objects.create", "storage.objects.get"}
+	// The parts of a parallel upload are deleted once composed.
+	if !p.config.SkipClean || p.config.UploadParallelism > 1 {
+		bucket = append(bucket, "storage.objects.delete")
+	}
+	if p.config.SourceGCSPath != "" {
+		name, _, _ = common.ParseGCSPath(p.config.SourceGCSPath)
+		feature, bucket = "source_gcs_path", []string{"storage.objects.get"}
+	}
+	err := common.CheckPermissions(ui, "bucket "+name,
+		[]common.PermissionRequirement{{Feature: feature, Permissions: bucket}},
+		func(permissions []string) ([]string, error) {
+			return driver.TestBucketPermissions(name, permissions)
+		})
 	if err != nil {
-		shield.FileType = "BIN"
+		return err
 	}
-	return shield, nil
 
+	return common.CheckPermissions(ui, "project "+p.config.ProjectId,
+		[]common.PermissionRequirement{{Feature: "image", Permissions: []string{"compute.globalOperations.get", "compute.images.create"}}},
+		func(permissions []string) ([]string, error) {
+			return driver.TestProjectPermissions(p.config.ProjectId, permissions)
+		})
 }
 
+// findOVFFromArtifact returns the OVA, or else the OVF descriptor, of
+// artifact, e.g. from the vsphere or virtualbox builders. It returns an
+// empty string when artifact contains neither.
+func findOVFFromArtifact(artifact packersdk.Artifact) string {
+	ovf := ""
+	for _, path := range artifact.Files() {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".ova":
+			return path
+		case ".ovf":
+			if ovf == "" {
+				ovf = path
+			}
+		}
+	}
+	return ovf
+}
+
+func (p PostProcessor) findTarballFromArtifact(artifact packersdk.Artifact) (string, error) {
+	for _, path := range artifact.Files() {
+		if strings.HasSuffix(path, ".tar.gz") {
+			return path, nil
+		}
+	}
+
+	return "", fmt.Errorf("No tar.gz file found in list of artifacts")
+}
+
+// FillFileContentBuffer reads a Secure Boot key or certificate file.
+func FillFileContentBuffer(certOrKeyFile string) (*compute.FileContentBuffer, error) {
+	return common.FileContentBuffer(certOrKeyFile)
+}
+
+// CreateShieldedVMStateConfig returns the Secure Boot keys of the image, which
+// are only set on UEFI_COMPATIBLE images.
 func CreateShieldedVMStateConfig(imageGuestOsFeatures []string, imagePlatformKey string, imageKeyExchangeKey []string, imageSignaturesDB []string, imageForbiddenSignaturesDB []string) (*compute.InitialStateConfig, error) {
-	shieldedVMStateConfig := &compute.InitialStateConfig{}
 	for _, v := range imageGuestOsFeatures {
 		if v == "UEFI_COMPATIBLE" {
-			if imagePlatformKey != "" {
-				shieldedData, err := FillFileContentBuffer(imagePlatformKey)
-				if err != nil {
-					return nil, err
-				}
-				shieldedVMStateConfig.Pk = shieldedData
-			}
-			for _, v := range imageKeyExchangeKey {
-				shieldedData, err := FillFileContentBuffer(v)
-				if err != nil {
-					return nil, err
-				}
-				shieldedVMStateConfig.Keks = append(shieldedVMStateConfig.Keks, shieldedData)
-			}
-			for _, v := range imageSignaturesDB {
-				shieldedData, err := FillFileContentBuffer(v)
-				if err != nil {
-					return nil, err
-				}
-				shieldedVMStateConfig.Dbs = append(shieldedVMStateConfig.Dbs, shieldedData)
-			}
-			for _, v := range imageForbiddenSignaturesDB {
-				shieldedData, err := FillFileContentBuffer(v)
-				if err != nil {
-					return nil, err
-				}
-				shieldedVMStateConfig.Dbxs = append(shieldedVMStateConfig.Dbxs, shieldedData)
-			}
-
+			return common.ShieldedInitialState(imagePlatformKey, imageKeyExchangeKey, imageSignaturesDB, imageForbiddenSignaturesDB)
 		}
 	}
-	return shieldedVMStateConfig, nil
+	return nil, nil
 }
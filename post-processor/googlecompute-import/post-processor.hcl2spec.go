@@ -4,43 +4,58 @@ package googlecomputeimport
 
 import (
 	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
 	"github.com/zclconf/go-cty/cty"
 )
 
 // FlatConfig is an auto-generated flat version of Config.
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlatConfig struct {
-	PackerBuildName            *string           `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
-	PackerBuilderType          *string           `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
-	PackerCoreVersion          *string           `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
-	PackerDebug                *bool             `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
-	PackerForce                *bool             `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
-	PackerOnError              *string           `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
-	PackerUserVars             map[string]string `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
-	PackerSensitiveVars        []string          `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
-	AccessToken                *string           `mapstructure:"access_token" required:"false" cty:"access_token" hcl:"access_token"`
-	AccountFile                *string           `mapstructure:"account_file" required:"false" cty:"account_file" hcl:"account_file"`
-	CredentialsFile            *string           `mapstructure:"credentials_file" required:"false" cty:"credentials_file" hcl:"credentials_file"`
-	CredentialsJSON            *string           `mapstructure:"credentials_json" required:"false" cty:"credentials_json" hcl:"credentials_json"`
-	ImpersonateServiceAccount  *string           `mapstructure:"impersonate_service_account" required:"false" cty:"impersonate_service_account" hcl:"impersonate_service_account"`
-	VaultGCPOauthEngine        *string           `mapstructure:"vault_gcp_oauth_engine" cty:"vault_gcp_oauth_engine" hcl:"vault_gcp_oauth_engine"`
-	Scopes                     []string          `mapstructure:"scopes" required:"false" cty:"scopes" hcl:"scopes"`
-	ProjectId                  *string           `mapstructure:"project_id" required:"true" cty:"project_id" hcl:"project_id"`
-	IAP                        *bool             `mapstructure-to-hcl:",skip" cty:"iap" hcl:"iap"`
-	Bucket                     *string           `mapstructure:"bucket" required:"true" cty:"bucket" hcl:"bucket"`
-	GCSObjectName              *string           `mapstructure:"gcs_object_name" cty:"gcs_object_name" hcl:"gcs_object_name"`
-	ImageArchitecture          *string           `mapstructure:"image_architecture" cty:"image_architecture" hcl:"image_architecture"`
-	ImageDescription           *string           `mapstructure:"image_description" cty:"image_description" hcl:"image_description"`
-	ImageFamily                *string           `mapstructure:"image_family" cty:"image_family" hcl:"image_family"`
-	ImageGuestOsFeatures       []string          `mapstructure:"image_guest_os_features" cty:"image_guest_os_features" hcl:"image_guest_os_features"`
-	ImageLabels                map[string]string `mapstructure:"image_labels" cty:"image_labels" hcl:"image_labels"`
-	ImageName                  *string           `mapstructure:"image_name" required:"true" cty:"image_name" hcl:"image_name"`
-	ImageStorageLocations      []string          `mapstructure:"image_storage_locations" cty:"image_storage_locations" hcl:"image_storage_locations"`
-	SkipClean                  *bool             `mapstructure:"skip_clean" cty:"skip_clean" hcl:"skip_clean"`
-	ImagePlatformKey           *string           `mapstructure:"image_platform_key" cty:"image_platform_key" hcl:"image_platform_key"`
-	ImageKeyExchangeKey        []string          `mapstructure:"image_key_exchange_key" cty:"image_key_exchange_key" hcl:"image_key_exchange_key"`
-	ImageSignaturesDB          []string          `mapstructure:"image_signatures_db" cty:"image_signatures_db" hcl:"image_signatures_db"`
-	ImageForbiddenSignaturesDB []string          `mapstructure:"image_forbidden_signatures_db" cty:"image_forbidden_signatures_db" hcl:"image_forbidden_signatures_db"`
+	PackerBuildName                    *string                    `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
+	PackerBuilderType                  *string                    `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
+	PackerCoreVersion                  *string                    `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
+	PackerDebug                        *bool                      `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
+	PackerForce                        *bool                      `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
+	PackerOnError                      *string                    `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
+	PackerUserVars                     map[string]string          `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
+	PackerSensitiveVars                []string                   `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
+	AccessToken                        *string                    `mapstructure:"access_token" required:"false" cty:"access_token" hcl:"access_token"`
+	AccountFile                        *string                    `mapstructure:"account_file" required:"false" cty:"account_file" hcl:"account_file"`
+	BillingProject                     *string                    `mapstructure:"billing_project" required:"false" cty:"billing_project" hcl:"billing_project"`
+	AuthScopes                         []string                   `mapstructure:"auth_scopes" required:"false" cty:"auth_scopes" hcl:"auth_scopes"`
+	GoogleAccess                       *string                    `mapstructure:"google_access" required:"false" cty:"google_access" hcl:"google_access"`
+	ProxyURL                           *string                    `mapstructure:"proxy_url" required:"false" cty:"proxy_url" hcl:"proxy_url"`
+	CredentialsFile                    *string                    `mapstructure:"credentials_file" required:"false" cty:"credentials_file" hcl:"credentials_file"`
+	CredentialsJSON                    *string                    `mapstructure:"credentials_json" required:"false" cty:"credentials_json" hcl:"credentials_json"`
+	ImpersonateServiceAccount          *string                    `mapstructure:"impersonate_service_account" required:"false" cty:"impersonate_service_account" hcl:"impersonate_service_account"`
+	ImpersonateServiceAccountDelegates []string                   `mapstructure:"impersonate_service_account_delegates" required:"false" cty:"impersonate_service_account_delegates" hcl:"impersonate_service_account_delegates"`
+	VaultGCPOauthEngine                *string                    `mapstructure:"vault_gcp_oauth_engine" cty:"vault_gcp_oauth_engine" hcl:"vault_gcp_oauth_engine"`
+	Scopes                             []string                   `mapstructure:"scopes" required:"false" cty:"scopes" hcl:"scopes"`
+	ProjectId                          *string                    `mapstructure:"project_id" required:"true" cty:"project_id" hcl:"project_id"`
+	IAP                                *bool                      `mapstructure-to-hcl:",skip" cty:"iap" hcl:"iap"`
+	Bucket                             *string                    `mapstructure:"bucket" required:"true" cty:"bucket" hcl:"bucket"`
+	StorageAuthentication              *common.FlatAuthentication `mapstructure:"storage_authentication" cty:"storage_authentication" hcl:"storage_authentication"`
+	GCSObjectName                      *string                    `mapstructure:"gcs_object_name" cty:"gcs_object_name" hcl:"gcs_object_name"`
+	StagingObjectMetadata              map[string]string          `mapstructure:"staging_object_metadata" cty:"staging_object_metadata" hcl:"staging_object_metadata"`
+	ImageArchitecture                  *string                    `mapstructure:"image_architecture" cty:"image_architecture" hcl:"image_architecture"`
+	ImageDescription                   *string                    `mapstructure:"image_description" cty:"image_description" hcl:"image_description"`
+	ImageFamily                        *string                    `mapstructure:"image_family" cty:"image_family" hcl:"image_family"`
+	ImageGuestOsFeatures               []string                   `mapstructure:"image_guest_os_features" cty:"image_guest_os_features" hcl:"image_guest_os_features"`
+	ImageLabels                        map[string]string          `mapstructure:"image_labels" cty:"image_labels" hcl:"image_labels"`
+	ImageName                          *string                    `mapstructure:"image_name" required:"true" cty:"image_name" hcl:"image_name"`
+	ImageStorageLocations              []string                   `mapstructure:"image_storage_locations" cty:"image_storage_locations" hcl:"image_storage_locations"`
+	SourceFile                         *string                    `mapstructure:"source_file" cty:"source_file" hcl:"source_file"`
+	SourceGCSPath                      *string                    `mapstructure:"source_gcs_path" cty:"source_gcs_path" hcl:"source_gcs_path"`
+	SourceKmsKey                       *string                    `mapstructure:"source_kms_key" cty:"source_kms_key" hcl:"source_kms_key"`
+	UploadChunkSize                    *int                       `mapstructure:"upload_chunk_size" cty:"upload_chunk_size" hcl:"upload_chunk_size"`
+	UploadRetryTimeout                 *string                    `mapstructure:"upload_retry_timeout" cty:"upload_retry_timeout" hcl:"upload_retry_timeout"`
+	UploadParallelism                  *int                       `mapstructure:"upload_parallelism" cty:"upload_parallelism" hcl:"upload_parallelism"`
+	SkipClean                          *bool                      `mapstructure:"skip_clean" cty:"skip_clean" hcl:"skip_clean"`
+	PermissionsPrecheck                *bool                      `mapstructure:"permissions_precheck" cty:"permissions_precheck" hcl:"permissions_precheck"`
+	ImagePlatformKey                   *string                    `mapstructure:"image_platform_key" cty:"image_platform_key" hcl:"image_platform_key"`
+	ImageKeyExchangeKey                []string                   `mapstructure:"image_key_exchange_key" cty:"image_key_exchange_key" hcl:"image_key_exchange_key"`
+	ImageSignaturesDB                  []string                   `mapstructure:"image_signatures_db" cty:"image_signatures_db" hcl:"image_signatures_db"`
+	ImageForbiddenSignaturesDB         []string                   `mapstructure:"image_forbidden_signatures_db" cty:"image_forbidden_signatures_db" hcl:"image_forbidden_signatures_db"`
 }
 
 // FlatMapstructure returns a new FlatConfig.
@@ -55,37 +70,51 @@ func (*Config) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec }
 // The decoded values from this spec will then be applied to a FlatConfig.
 func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 	s := map[string]hcldec.Spec{
-		"packer_build_name":             &hcldec.AttrSpec{Name: "packer_build_name", Type: cty.String, Required: false},
-		"packer_builder_type":           &hcldec.AttrSpec{Name: "packer_builder_type", Type: cty.String, Required: false},
-		"packer_core_version":           &hcldec.AttrSpec{Name: "packer_core_version", Type: cty.String, Required: false},
-		"packer_debug":                  &hcldec.AttrSpec{Name: "packer_debug", Type: cty.Bool, Required: false},
-		"packer_force":                  &hcldec.AttrSpec{Name: "packer_force", Type: cty.Bool, Required: false},
-		"packer_on_error":               &hcldec.AttrSpec{Name: "packer_on_error", Type: cty.String, Required: false},
-		"packer_user_variables":         &hcldec.AttrSpec{Name: "packer_user_variables", Type: cty.Map(cty.String), Required: false},
-		"packer_sensitive_variables":    &hcldec.AttrSpec{Name: "packer_sensitive_variables", Type: cty.List(cty.String), Required: false},
-		"access_token":                  &hcldec.AttrSpec{Name: "access_token", Type: cty.String, Required: false},
-		"account_file":                  &hcldec.AttrSpec{Name: "account_file", Type: cty.String, Required: false},
-		"credentials_file":              &hcldec.AttrSpec{Name: "credentials_file", Type: cty.String, Required: false},
-		"credentials_json":              &hcldec.AttrSpec{Name: "credentials_json", Type: cty.String, Required: false},
-		"impersonate_service_account":   &hcldec.AttrSpec{Name: "impersonate_service_account", Type: cty.String, Required: false},
-		"vault_gcp_oauth_engine":        &hcldec.AttrSpec{Name: "vault_gcp_oauth_engine", Type: cty.String, Required: false},
-		"scopes":                        &hcldec.AttrSpec{Name: "scopes", Type: cty.List(cty.String), Required: false},
-		"project_id":                    &hcldec.AttrSpec{Name: "project_id", Type: cty.String, Required: false},
-		"iap":                           &hcldec.AttrSpec{Name: "iap", Type: cty.Bool, Required: false},
-		"bucket":                        &hcldec.AttrSpec{Name: "bucket", Type: cty.String, Required: false},
-		"gcs_object_name":               &hcldec.AttrSpec{Name: "gcs_object_name", Type: cty.String, Required: false},
-		"image_architecture":            &hcldec.AttrSpec{Name: "image_architecture", Type: cty.String, Required: false},
-		"image_description":             &hcldec.AttrSpec{Name: "image_description", Type: cty.String, Required: false},
-		"image_family":                  &hcldec.AttrSpec{Name: "image_family", Type: cty.String, Required: false},
-		"image_guest_os_features":       &hcldec.AttrSpec{Name: "image_guest_os_features", Type: cty.List(cty.String), Required: false},
-		"image_labels":                  &hcldec.AttrSpec{Name: "image_labels", Type: cty.Map(cty.String), Required: false},
-		"image_name":                    &hcldec.AttrSpec{Name: "image_name", Type: cty.String, Required: false},
-		"image_storage_locations":       &hcldec.AttrSpec{Name: "image_storage_locations", Type: cty.List(cty.String), Required: false},
-		"skip_clean":                    &hcldec.AttrSpec{Name: "skip_clean", Type: cty.Bool, Required: false},
-		"image_platform_key":            &hcldec.AttrSpec{Name: "image_platform_key", Type: cty.String, Required: false},
-		"image_key_exchange_key":        &hcldec.AttrSpec{Name: "image_key_exchange_key", Type: cty.List(cty.String), Required: false},
-		"image_signatures_db":           &hcldec.AttrSpec{Name: "image_signatures_db", Type: cty.List(cty.String), Required: false},
-		"image_forbidden_signatures_db": &hcldec.AttrSpec{Name: "image_forbidden_signatures_db", Type: cty.List(cty.String), Required: false},
+		"packer_build_name":                     &hcldec.AttrSpec{Name: "packer_build_name", Type: cty.String, Required: false},
+		"packer_builder_type":                   &hcldec.AttrSpec{Name: "packer_builder_type", Type: cty.String, Required: false},
+		"packer_core_version":                   &hcldec.AttrSpec{Name: "packer_core_version", Type: cty.String, Required: false},
+		"packer_debug":                          &hcldec.AttrSpec{Name: "packer_debug", Type: cty.Bool, Required: false},
+		"packer_force":                          &hcldec.AttrSpec{Name: "packer_force", Type: cty.Bool, Required: false},
+		"packer_on_error":                       &hcldec.AttrSpec{Name: "packer_on_error", Type: cty.String, Required: false},
+		"packer_user_variables":                 &hcldec.AttrSpec{Name: "packer_user_variables", Type: cty.Map(cty.String), Required: false},
+		"packer_sensitive_variables":            &hcldec.AttrSpec{Name: "packer_sensitive_variables", Type: cty.List(cty.String), Required: false},
+		"access_token":                          &hcldec.AttrSpec{Name: "access_token", Type: cty.String, Required: false},
+		"account_file":                          &hcldec.AttrSpec{Name: "account_file", Type: cty.String, Required: false},
+		"billing_project":                       &hcldec.AttrSpec{Name: "billing_project", Type: cty.String, Required: false},
+		"auth_scopes":                           &hcldec.AttrSpec{Name: "auth_scopes", Type: cty.List(cty.String), Required: false},
+		"google_access":                         &hcldec.AttrSpec{Name: "google_access", Type: cty.String, Required: false},
+		"proxy_url":                             &hcldec.AttrSpec{Name: "proxy_url", Type: cty.String, Required: false},
+		"credentials_file":                      &hcldec.AttrSpec{Name: "credentials_file", Type: cty.String, Required: false},
+		"credentials_json":                      &hcldec.AttrSpec{Name: "credentials_json", Type: cty.String, Required: false},
+		"impersonate_service_account":           &hcldec.AttrSpec{Name: "impersonate_service_account", Type: cty.String, Required: false},
+		"impersonate_service_account_delegates": &hcldec.AttrSpec{Name: "impersonate_service_account_delegates", Type: cty.List(cty.String), Required: false},
+		"vault_gcp_oauth_engine":                &hcldec.AttrSpec{Name: "vault_gcp_oauth_engine", Type: cty.String, Required: false},
+		"scopes":                                &hcldec.AttrSpec{Name: "scopes", Type: cty.List(cty.String), Required: false},
+		"project_id":                            &hcldec.AttrSpec{Name: "project_id", Type: cty.String, Required: false},
+		"iap":                                   &hcldec.AttrSpec{Name: "iap", Type: cty.Bool, Required: false},
+		"bucket":                                &hcldec.AttrSpec{Name: "bucket", Type: cty.String, Required: false},
+		"storage_authentication":                &hcldec.BlockSpec{TypeName: "storage_authentication", Nested: hcldec.ObjectSpec((*common.FlatAuthentication)(nil).HCL2Spec())},
+		"gcs_object_name":                       &hcldec.AttrSpec{Name: "gcs_object_name", Type: cty.String, Required: false},
+		"staging_object_metadata":               &hcldec.AttrSpec{Name: "staging_object_metadata", Type: cty.Map(cty.String), Required: false},
+		"image_architecture":                    &hcldec.AttrSpec{Name: "image_architecture", Type: cty.String, Required: false},
+		"image_description":                     &hcldec.AttrSpec{Name: "image_description", Type: cty.String, Required: false},
+		"image_family":                          &hcldec.AttrSpec{Name: "image_family", Type: cty.String, Required: false},
+		"image_guest_os_features":               &hcldec.AttrSpec{Name: "image_guest_os_features", Type: cty.List(cty.String), Required: false},
+		"image_labels":                          &hcldec.AttrSpec{Name: "image_labels", Type: cty.Map(cty.String), Required: false},
+		"image_name":                            &hcldec.AttrSpec{Name: "image_name", Type: cty.String, Required: false},
+		"image_storage_locations":               &hcldec.AttrSpec{Name: "image_storage_locations", Type: cty.List(cty.String), Required: false},
+		"source_file":                           &hcldec.AttrSpec{Name: "source_file", Type: cty.String, Required: false},
+		"source_gcs_path":                       &hcldec.AttrSpec{Name: "source_gcs_path", Type: cty.String, Required: false},
+		"source_kms_key":                        &hcldec.AttrSpec{Name: "source_kms_key", Type: cty.String, Required: false},
+		"upload_chunk_size":                     &hcldec.AttrSpec{Name: "upload_chunk_size", Type: cty.Number, Required: false},
+		"upload_retry_timeout":                  &hcldec.AttrSpec{Name: "upload_retry_timeout", Type: cty.String, Required: false},
+		"upload_parallelism":                    &hcldec.AttrSpec{Name: "upload_parallelism", Type: cty.Number, Required: false},
+		"skip_clean":                            &hcldec.AttrSpec{Name: "skip_clean", Type: cty.Bool, Required: false},
+		"permissions_precheck":                  &hcldec.AttrSpec{Name: "permissions_precheck", Type: cty.Bool, Required: false},
+		"image_platform_key":                    &hcldec.AttrSpec{Name: "image_platform_key", Type: cty.String, Required: false},
+		"image_key_exchange_key":                &hcldec.AttrSpec{Name: "image_key_exchange_key", Type: cty.List(cty.String), Required: false},
+		"image_signatures_db":                   &hcldec.AttrSpec{Name: "image_signatures_db", Type: cty.List(cty.String), Required: false},
+		"image_forbidden_signatures_db":         &hcldec.AttrSpec{Name: "image_forbidden_signatures_db", Type: cty.List(cty.String), Required: false},
 	}
 	return s
 }
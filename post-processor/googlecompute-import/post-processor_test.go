@@ -0,0 +1,220 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecomputeimport
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"google.golang.org/api/storage/v1"
+)
+
+func TestPostProcessor_checkPermissions(t *testing.T) {
+	p := PostProcessor{config: Config{
+		ProjectId: "my-project",
+		Bucket:    "my-bucket",
+		SkipClean: true,
+	}}
+	driver := &common.DriverMock{}
+	driver.TestBucketPermissionsResult = []string{"storage.objects.create", "storage.objects.get"}
+	driver.TestProjectPermissionsResult = []string{"compute.globalOperations.get"}
+
+	err := p.checkPermissions(&packersdk.MockUi{}, driver)
+	if err == nil {
+		t.Fatal("missing compute.images.create should be reported")
+	}
+	if driver.TestBucketPermissionsBucket != "my-bucket" {
+		t.Errorf("bad bucket: %s", driver.TestBucketPermissionsBucket)
+	}
+	if !reflect.DeepEqual(driver.TestBucketPermissionsPermissions, []string{"storage.objects.create", "storage.objects.get"}) {
+		t.Errorf("storage.objects.delete should not be needed with skip_clean, got %v", driver.TestBucketPermissionsPermissions)
+	}
+	if driver.TestProjectPermissionsProject != "my-project" {
+		t.Errorf("bad project: %s", driver.TestProjectPermissionsProject)
+	}
+}
+
+func TestPostProcessorConfigure_storageAuthentication(t *testing.T) {
+	raw := func(storageAuth map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"access_token":           "ya29.compute-token",
+			"project_id":             "my-project",
+			"bucket":                 "my-bucket",
+			"image_name":             "my-image",
+			"storage_authentication": storageAuth,
+		}
+	}
+
+	var p PostProcessor
+	err := p.Configure(raw(map[string]interface{}{
+		"access_token": "ya29.storage-token",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if p.config.StorageAuthentication == nil || p.config.StorageAuthentication.AccessToken != "ya29.storage-token" {
+		t.Errorf("storage credentials should have been decoded, got %#v", p.config.StorageAuthentication)
+	}
+
+	p = PostProcessor{}
+	err = p.Configure(raw(map[string]interface{}{
+		"access_token":           "ya29.storage-token",
+		"vault_gcp_oauth_engine": "gcp/token/storage",
+	}))
+	if err == nil {
+		t.Fatal("invalid storage credentials should be rejected")
+	}
+}
+
+func TestPostProcessorConfigure_guestOsFeatures(t *testing.T) {
+	raw := map[string]interface{}{
+		"access_token":            "ya29.token",
+		"project_id":              "my-project",
+		"bucket":                  "my-bucket",
+		"image_name":              "my-image",
+		"image_architecture":      "ARM64",
+		"image_guest_os_features": []string{"gvnic", "GVNIC"},
+	}
+
+	var p PostProcessor
+	if err := p.Configure(raw); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := []string{"GVNIC", "UEFI_COMPATIBLE"}
+	if !reflect.DeepEqual(p.config.ImageGuestOsFeatures, expected) {
+		t.Errorf("expected %v on an arm64 image, got %v", expected, p.config.ImageGuestOsFeatures)
+	}
+
+	raw["image_guest_os_features"] = []string{"UEFI"}
+	p = PostProcessor{}
+	if err := p.Configure(raw); err == nil {
+		t.Error("unknown guest OS features should be rejected")
+	}
+
+	raw["image_architecture"] = "x86_64"
+	raw["image_guest_os_features"] = []string{"GVNIC"}
+	raw["image_platform_key"] = "pk.pem"
+	p = PostProcessor{}
+	if err := p.Configure(raw); err == nil {
+		t.Error("a platform key without UEFI_COMPATIBLE should be rejected")
+	}
+}
+
+func TestPostProcessorConfigure_imageFamily(t *testing.T) {
+	raw := map[string]interface{}{
+		"access_token": "ya29.token",
+		"project_id":   "my-project",
+		"bucket":       "my-bucket",
+		"image_name":   "my-image",
+		"image_family": "my-family",
+		"image_labels": map[string]string{"team": "infra"},
+	}
+
+	var p PostProcessor
+	if err := p.Configure(raw); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if p.config.ImageDescription != "Imported by Packer" {
+		t.Errorf("bad default description: %q", p.config.ImageDescription)
+	}
+
+	raw["image_family"] = "My_Family"
+	p = PostProcessor{}
+	if err := p.Configure(raw); err == nil {
+		t.Error("invalid image families should be rejected")
+	}
+}
+
+func TestPostProcessorConfigure_sourceFile(t *testing.T) {
+	var p PostProcessor
+	err := p.Configure(map[string]interface{}{
+		"access_token": "ya29.token",
+		"project_id":   "my-project",
+		"bucket":       "my-bucket",
+		"image_name":   "my-image",
+		"source_file":  "/does/not/exist.vmdk",
+	})
+	if err == nil {
+		t.Error("a missing source_file should be rejected")
+	}
+}
+
+func TestPostProcessor_uploadOptions(t *testing.T) {
+	var p PostProcessor
+	err := p.Configure(map[string]interface{}{
+		"access_token":            "ya29.token",
+		"project_id":              "my-project",
+		"bucket":                  "my-bucket",
+		"image_name":              "my-image",
+		"skip_clean":              true,
+		"upload_chunk_size":       8,
+		"staging_object_metadata": map[string]string{"pipeline-run": "1234"},
+		"packer_build_name":       "web",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	opts := p.uploadOptions(now)
+	if opts.ChunkSize != 8*1024*1024 {
+		t.Errorf("bad chunk size: %d", opts.ChunkSize)
+	}
+	if opts.Parallelism != 1 {
+		t.Errorf("the upload should not be parallel by default: %d", opts.Parallelism)
+	}
+	if !opts.CustomTime.Equal(now) {
+		t.Errorf("the custom time should be the upload time, got %s", opts.CustomTime)
+	}
+	expected := map[string]string{
+		"packer-image-name": "my-image",
+		"packer-build-name": "web",
+		"pipeline-run":      "1234",
+	}
+	if !reflect.DeepEqual(opts.Metadata, expected) {
+		t.Errorf("bad metadata: %v", opts.Metadata)
+	}
+}
+
+func TestPostProcessor_checkSourceObject(t *testing.T) {
+	var p PostProcessor
+	err := p.Configure(map[string]interface{}{
+		"access_token":    "ya29.token",
+		"project_id":      "my-project",
+		"image_name":      "my-image",
+		"source_gcs_path": "gs://other-bucket/disks/disk.tar.gz",
+		"source_kms_key":  "projects/kms/locations/us/keyRings/ring/cryptoKeys/key",
+	})
+	if err != nil {
+		t.Fatalf("bucket should not be needed with source_gcs_path: %s", err)
+	}
+
+	driver := &common.StorageDriverMock{
+		GetBucketObjectResult: map[string]*storage.Object{
+			"other-bucket/disks/disk.tar.gz": {
+				KmsKeyName: "projects/kms/locations/us/keyRings/ring/cryptoKeys/key/cryptoKeyVersions/1",
+			},
+		},
+	}
+	url, err := p.checkSourceObject(driver)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if url != "https://storage.googleapis.com/other-bucket/disks/disk.tar.gz" {
+		t.Errorf("bad URL: %s", url)
+	}
+
+	p.config.SourceKmsKey = "projects/kms/locations/us/keyRings/ring/cryptoKeys/other"
+	if _, err := p.checkSourceObject(driver); err == nil {
+		t.Error("an object encrypted with another key should be rejected")
+	}
+
+	p.config.SourceGCSPath = "gs://other-bucket/missing.tar.gz"
+	if _, err := p.checkSourceObject(driver); err == nil {
+		t.Error("a missing object should be rejected")
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecomputeinstancetemplate
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+const BuilderId = "packer.post-processor.googlecompute-instance-template"
+
+// Artifact represents a GCE instance template created from the image of a
+// Packer build.
+type Artifact struct {
+	template  string
+	selfLink  string
+	projectId string
+	imageName string
+	driver    common.InstanceTemplateDriver
+	// StateData should store data such as GeneratedData
+	// to be shared with post-processors
+	StateData map[string]interface{}
+}
+
+var _ packersdk.Artifact = new(Artifact)
+
+func (*Artifact) BuilderId() string {
+	return BuilderId
+}
+
+// Id returns the name of the instance template.
+func (a *Artifact) Id() string {
+	return a.template
+}
+
+func (*Artifact) Files() []string {
+	return nil
+}
+
+func (a *Artifact) String() string {
+	return fmt.Sprintf("An instance template was created in the '%v' project: %v", a.projectId, a.template)
+}
+
+func (a *Artifact) State(name string) interface{} {
+	switch name {
+	case "TemplateName":
+		return a.template
+	case "TemplateSelfLink":
+		return a.selfLink
+	case "ProjectId":
+		return a.projectId
+	case "ImageName":
+		return a.imageName
+	}
+	return a.StateData[name]
+}
+
+// Destroy deletes the instance template.
+func (a *Artifact) Destroy() error {
+	log.Printf("Destroying instance template: %s", a.template)
+	return <-a.driver.DeleteInstanceTemplate(a.projectId, a.template)
+}
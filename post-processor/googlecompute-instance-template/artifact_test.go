@@ -0,0 +1,27 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecomputeinstancetemplate
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func TestArtifact_impl(t *testing.T) {
+	var _ packersdk.Artifact = new(Artifact)
+}
+
+func TestArtifact_Destroy(t *testing.T) {
+	driver := &common.InstanceTemplateDriverMock{}
+	a := &Artifact{template: "my-template", projectId: "my-project", driver: driver}
+
+	if err := a.Destroy(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if driver.DeleteInstanceTemplateProjectId != "my-project" || driver.DeleteInstanceTemplateName != "my-template" {
+		t.Errorf("bad deletion: %s/%s", driver.DeleteInstanceTemplateProjectId, driver.DeleteInstanceTemplateName)
+	}
+}
@@ -0,0 +1,316 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+package googlecomputeinstancetemplate
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-googlecompute/builder/googlecompute"
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	sdk_common "github.com/hashicorp/packer-plugin-sdk/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+	"google.golang.org/api/compute/v1"
+)
+
+type Config struct {
+	sdk_common.PackerConfig `mapstructure:",squash"`
+	common.Authentication   `mapstructure:",squash"`
+
+	//The project ID where the instance template is created. Defaults to the
+	//project of the image.
+	ProjectId string `mapstructure:"project_id"`
+	//The name of the instance template. Instance templates cannot be
+	//updated, so a new one is created for every image: this is treated as a
+	//[template engine](/packer/docs/templates/legacy_json_templates/engine),
+	//and defaults to the name of the image, which is unique.
+	TemplateName string `mapstructure:"template_name"`
+	//The description of the instance template. Defaults to
+	//`Created by Packer from image ((image_name))`.
+	Description string `mapstructure:"description"`
+	//The machine type of the instances. Defaults to the machine type used to
+	//build the image.
+	MachineType string `mapstructure:"machine_type"`
+	//The size of the boot disk of the instances in GB. Defaults to the size
+	//of the image.
+	DiskSizeGb int64 `mapstructure:"disk_size"`
+	//The type of the boot disk of the instances, like `pd-ssd` or
+	//`pd-balanced`. Defaults to `pd-balanced`.
+	DiskType string `mapstructure:"disk_type"`
+	//The network of the instances. Defaults to the network used to build the
+	//image. If the value is not a URL, it will be interpolated to
+	//`projects/((network_project_id))/global/networks/((network))`.
+	Network string `mapstructure:"network"`
+	//The project ID of the network and subnetwork. Defaults to the one used
+	//to build the image.
+	NetworkProjectId string `mapstructure:"network_project_id"`
+	//The subnetwork of the instances. Defaults to the subnetwork used to
+	//build the image. If the value is not a URL, it will be interpolated to
+	//`projects/((network_project_id))/regions/((region))/subnetworks/((subnetwork))`.
+	Subnetwork string `mapstructure:"subnetwork"`
+	//The region of `subnetwork`. Defaults to the region the image was built
+	//in.
+	Region string `mapstructure:"region"`
+	//If true, the instances do not get an external IP address.
+	OmitExternalIP bool `mapstructure:"omit_external_ip"`
+	//Network tags applied to the instances.
+	Tags []string `mapstructure:"tags"`
+	//Key/value pair labels applied to the instances. Defaults to the labels
+	//of the image.
+	Labels map[string]string `mapstructure:"labels"`
+	//Metadata applied to the instances.
+	Metadata map[string]string `mapstructure:"metadata"`
+	//The service account of the instances. Defaults to the default service
+	//account of the project.
+	ServiceAccountEmail string `mapstructure:"service_account_email"`
+	//The service account scopes of the instances. Defaults to:
+	//
+	//```json
+	//[
+	//  "https://www.googleapis.com/auth/cloud-platform"
+	//]
+	//```
+	InstanceScopes []string `mapstructure:"instance_scopes"`
+
+	ctx interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+}
+
+func (p *PostProcessor) ConfigSpec() hcldec.ObjectSpec { return p.config.FlatMapstructure().HCL2Spec() }
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		PluginType:         BuilderId,
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{
+				"template_name",
+			},
+		},
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	errs := new(packersdk.MultiError)
+
+	if p.config.TemplateName != "" {
+		if err = interpolate.Validate(p.config.TemplateName, &p.config.ctx); err != nil {
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("Error parsing template_name template: %s", err))
+		}
+	}
+
+	if p.config.DiskType == "" {
+		p.config.DiskType = "pd-balanced"
+	}
+
+	if len(p.config.InstanceScopes) == 0 {
+		p.config.InstanceScopes = []string{common.CloudPlatformScope}
+	}
+
+	warns, err := p.config.Authentication.Prepare()
+	if err != nil {
+		errs = packersdk.MultiErrorAppend(errs, err)
+	}
+	for _, warn := range warns {
+		log.Printf("[WARN] - %s", warn)
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifact packersdk.Artifact) (packersdk.Artifact, bool, bool, error) {
+	if artifact.BuilderId() != googlecompute.BuilderId {
+		err := fmt.Errorf(
+			"Unknown artifact type: %s\nCan only create instance templates from Google Compute Engine builder artifacts.",
+			artifact.BuilderId())
+		return nil, false, false, err
+	}
+
+	generatedData := artifact.State("generated_data")
+	if generatedData == nil {
+		generatedData = make(map[string]interface{})
+	}
+	p.config.ctx.Data = generatedData
+
+	spec, project, err := p.templateSpec(artifact)
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	cfg := &common.GCEDriverConfig{
+		Ui:        ui,
+		ProjectId: project,
+		Scopes:    common.DriverScopes,
+	}
+	p.config.Authentication.ApplyDriverConfig(cfg)
+
+	driver, err := common.NewDriverGCE(*cfg)
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	ui.Say(fmt.Sprintf("Creating instance template %s from image %s...", spec.Name, artifact.Id()))
+	templateCh, errCh := driver.CreateInstanceTemplate(project, spec)
+	// templateCh is closed once the error, if any, has been sent.
+	template, ok := <-templateCh
+	if !ok {
+		err = <-errCh
+	}
+	if err != nil {
+		return nil, false, false, common.EnrichError(fmt.Errorf("Error creating instance template: %w", err))
+	}
+
+	result := &Artifact{
+		template:  template.Name,
+		selfLink:  template.SelfLink,
+		projectId: project,
+		imageName: artifact.Id(),
+		driver:    driver,
+		StateData: map[string]interface{}{"generated_data": generatedData},
+	}
+
+	// The instance template needs the image.
+	return result, true, true, nil
+}
+
+// templateSpec returns the instance template to create from the image of
+// artifact, and the project to create it in.
+func (p *PostProcessor) templateSpec(artifact packersdk.Artifact) (*compute.InstanceTemplate, string, error) {
+	imageName := artifact.Id()
+	imageProject := stateString(artifact, "ImageProjectId")
+	if imageProject == "" {
+		imageProject = stateString(artifact, "ProjectId")
+	}
+
+	project := p.config.ProjectId
+	if project == "" {
+		project = imageProject
+	}
+
+	name := imageName
+	if p.config.TemplateName != "" {
+		var err error
+		name, err = interpolate.Render(p.config.TemplateName, &p.config.ctx)
+		if err != nil {
+			return nil, "", fmt.Errorf("Error rendering template_name template: %s", err)
+		}
+	}
+
+	description := p.config.Description
+	if description == "" {
+		description = fmt.Sprintf("Created by Packer from image %s", imageName)
+	}
+
+	machineType := p.config.MachineType
+	if machineType == "" {
+		machineType = stateString(artifact, "MachineType")
+	}
+	if machineType == "" {
+		return nil, "", fmt.Errorf("machine_type must be set")
+	}
+
+	labels := p.config.Labels
+	if labels == nil {
+		labels, _ = artifact.State("ImageLabels").(map[string]string)
+	}
+
+	instanceConfig := &common.InstanceConfig{
+		Network:          p.config.Network,
+		NetworkProjectId: p.config.NetworkProjectId,
+		Subnetwork:       p.config.Subnetwork,
+		Region:           p.config.Region,
+	}
+	if instanceConfig.Network == "" && instanceConfig.Subnetwork == "" {
+		instanceConfig.Network = stateString(artifact, "Network")
+		instanceConfig.Subnetwork = stateString(artifact, "Subnetwork")
+	}
+	if instanceConfig.NetworkProjectId == "" {
+		instanceConfig.NetworkProjectId = stateString(artifact, "NetworkProjectId")
+	}
+	if instanceConfig.NetworkProjectId == "" {
+		instanceConfig.NetworkProjectId = project
+	}
+	if instanceConfig.Region == "" {
+		zone := stateString(artifact, "BuildZone")
+		if i := strings.LastIndex(zone, "-"); i > 0 {
+			instanceConfig.Region = zone[:i]
+		}
+	}
+	network, subnetwork, err := common.GetNetworking(instanceConfig)
+	if err != nil {
+		return nil, "", err
+	}
+
+	networkInterface := &compute.NetworkInterface{
+		Network:    network,
+		Subnetwork: subnetwork,
+	}
+	if !p.config.OmitExternalIP {
+		networkInterface.AccessConfigs = []*compute.AccessConfig{{
+			Name: "External NAT",
+			Type: "ONE_TO_ONE_NAT",
+		}}
+	}
+
+	var metadata []*compute.MetadataItems
+	for k, v := range p.config.Metadata {
+		v := v
+		metadata = append(metadata, &compute.MetadataItems{Key: k, Value: &v})
+	}
+
+	serviceAccount := p.config.ServiceAccountEmail
+	if serviceAccount == "" {
+		serviceAccount = "default"
+	}
+
+	spec := &compute.InstanceTemplate{
+		Name:        name,
+		Description: description,
+		Properties: &compute.InstanceProperties{
+			MachineType: machineType,
+			Disks: []*compute.AttachedDisk{{
+				AutoDelete: true,
+				Boot:       true,
+				InitializeParams: &compute.AttachedDiskInitializeParams{
+					DiskSizeGb:  p.config.DiskSizeGb,
+					DiskType:    p.config.DiskType,
+					SourceImage: fmt.Sprintf("projects/%s/global/images/%s", imageProject, imageName),
+				},
+			}},
+			Labels:            labels,
+			Metadata:          &compute.Metadata{Items: metadata},
+			NetworkInterfaces: []*compute.NetworkInterface{networkInterface},
+			ServiceAccounts: []*compute.ServiceAccount{{
+				Email:  serviceAccount,
+				Scopes: p.config.InstanceScopes,
+			}},
+			Tags: &compute.Tags{Items: p.config.Tags},
+		},
+	}
+
+	return spec, project, nil
+}
+
+func stateString(artifact packersdk.Artifact, name string) string {
+	s, _ := artifact.State(name).(string)
+	return s
+}
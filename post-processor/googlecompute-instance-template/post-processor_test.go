@@ -0,0 +1,108 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecomputeinstancetemplate
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/builder/googlecompute"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func testArtifact() *packersdk.MockArtifact {
+	return &packersdk.MockArtifact{
+		BuilderIdValue: googlecompute.BuilderId,
+		IdValue:        "my-image-123",
+		StateValues: map[string]interface{}{
+			"ImageProjectId":   "images-project",
+			"ProjectId":        "build-project",
+			"ImageLabels":      map[string]string{"team": "infra"},
+			"MachineType":      "e2-standard-2",
+			"Network":          "my-network",
+			"NetworkProjectId": "host-project",
+			"Subnetwork":       "my-subnet",
+			"BuildZone":        "us-central1-a",
+		},
+	}
+}
+
+func TestPostProcessor_templateSpec(t *testing.T) {
+	var p PostProcessor
+	if err := p.Configure(map[string]interface{}{"access_token": "ya29.token"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	spec, project, err := p.templateSpec(testArtifact())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if project != "images-project" {
+		t.Errorf("the template should be created in the image project, got %s", project)
+	}
+	if spec.Name != "my-image-123" {
+		t.Errorf("the template should be named after the image, got %s", spec.Name)
+	}
+
+	props := spec.Properties
+	if props.MachineType != "e2-standard-2" {
+		t.Errorf("bad machine type: %s", props.MachineType)
+	}
+	if props.Labels["team"] != "infra" {
+		t.Errorf("the image labels should be carried through, got %v", props.Labels)
+	}
+	if src := props.Disks[0].InitializeParams.SourceImage; src != "projects/images-project/global/images/my-image-123" {
+		t.Errorf("bad source image: %s", src)
+	}
+	nic := props.NetworkInterfaces[0]
+	if nic.Network != "projects/host-project/global/networks/my-network" {
+		t.Errorf("bad network: %s", nic.Network)
+	}
+	if nic.Subnetwork != "projects/host-project/regions/us-central1/subnetworks/my-subnet" {
+		t.Errorf("bad subnetwork: %s", nic.Subnetwork)
+	}
+	if len(nic.AccessConfigs) != 1 {
+		t.Errorf("the instances should get an external IP by default")
+	}
+}
+
+func TestPostProcessor_templateSpecOverrides(t *testing.T) {
+	var p PostProcessor
+	err := p.Configure(map[string]interface{}{
+		"access_token":     "ya29.token",
+		"project_id":       "app-project",
+		"template_name":    "web-{{timestamp}}",
+		"machine_type":     "n2-standard-4",
+		"network":          "default",
+		"omit_external_ip": true,
+		"labels":           map[string]string{"app": "web"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	spec, project, err := p.templateSpec(testArtifact())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if project != "app-project" {
+		t.Errorf("bad project: %s", project)
+	}
+	if spec.Name == "web-{{timestamp}}" || spec.Name == "my-image-123" {
+		t.Errorf("template_name should be rendered, got %s", spec.Name)
+	}
+	props := spec.Properties
+	if props.MachineType != "n2-standard-4" {
+		t.Errorf("bad machine type: %s", props.MachineType)
+	}
+	if props.Labels["app"] != "web" || props.Labels["team"] != "" {
+		t.Errorf("labels should override the image labels, got %v", props.Labels)
+	}
+	nic := props.NetworkInterfaces[0]
+	if nic.Network != "global/networks/default" || nic.Subnetwork != "" {
+		t.Errorf("network should override the build network and subnetwork, got %s and %s", nic.Network, nic.Subnetwork)
+	}
+	if len(nic.AccessConfigs) != 0 {
+		t.Errorf("omit_external_ip should drop the access config")
+	}
+}
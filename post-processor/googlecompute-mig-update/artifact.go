@@ -0,0 +1,64 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecomputemigupdate
+
+import (
+	"fmt"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+const BuilderId = "packer.post-processor.googlecompute-mig-update"
+
+// Artifact represents a managed instance group updated to the instance
+// template of a Packer build.
+type Artifact struct {
+	instanceGroupManager string
+	location             string
+	projectId            string
+	template             string
+	// StateData should store data such as GeneratedData
+	// to be shared with post-processors
+	StateData map[string]interface{}
+}
+
+var _ packersdk.Artifact = new(Artifact)
+
+func (*Artifact) BuilderId() string {
+	return BuilderId
+}
+
+// Id returns the name of the managed instance group.
+func (a *Artifact) Id() string {
+	return a.instanceGroupManager
+}
+
+func (*Artifact) Files() []string {
+	return nil
+}
+
+func (a *Artifact) String() string {
+	return fmt.Sprintf("The managed instance group %v in %v of the '%v' project was updated to the instance template: %v",
+		a.instanceGroupManager, a.location, a.projectId, a.template)
+}
+
+func (a *Artifact) State(name string) interface{} {
+	switch name {
+	case "InstanceGroupManager":
+		return a.instanceGroupManager
+	case "Location":
+		return a.location
+	case "ProjectId":
+		return a.projectId
+	case "TemplateName":
+		return a.template
+	}
+	return a.StateData[name]
+}
+
+// Destroy does nothing: the rollout of the new template cannot be undone by
+// Packer, roll back by updating the group to the previous template.
+func (a *Artifact) Destroy() error {
+	return nil
+}
@@ -0,0 +1,267 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+package googlecomputemigupdate
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	googlecomputeinstancetemplatebuilder "github.com/hashicorp/packer-plugin-googlecompute/builder/googlecompute-instance-template"
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	googlecomputeinstancetemplate "github.com/hashicorp/packer-plugin-googlecompute/post-processor/googlecompute-instance-template"
+	sdk_common "github.com/hashicorp/packer-plugin-sdk/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+	"google.golang.org/api/compute/v1"
+)
+
+type Config struct {
+	sdk_common.PackerConfig `mapstructure:",squash"`
+	common.Authentication   `mapstructure:",squash"`
+
+	//The name of the managed instance group to update.
+	InstanceGroupManager string `mapstructure:"instance_group_manager" required:"true"`
+	//The zone of a zonal managed instance group. Exactly one of `zone` and
+	//`region` must be set.
+	Zone string `mapstructure:"zone"`
+	//The region of a regional managed instance group.
+	Region string `mapstructure:"region"`
+	//The project ID of the managed instance group. Defaults to the project of
+	//the instance template.
+	ProjectId string `mapstructure:"project_id"`
+	//If true, a rolling update replaces the instances of the group with new
+	//ones from the template. Otherwise, the update policy of the group
+	//applies: with the default opportunistic one, only the instances created
+	//from now on, e.g. when scaling out or autohealing, use it.
+	RollingUpdate bool `mapstructure:"rolling_update"`
+	//The maximum number of instances created above the target size during the
+	//rolling update, either fixed, e.g. `3`, or a percentage of the target
+	//size, e.g. `20%`. Defaults to the setting of the group.
+	MaxSurge string `mapstructure:"max_surge"`
+	//The maximum number of instances unavailable during the rolling update,
+	//fixed or a percentage like `max_surge`. Defaults to the setting of the
+	//group.
+	MaxUnavailable string `mapstructure:"max_unavailable"`
+	//The minimal action applied to the instances during the rolling update,
+	//one of `REPLACE`, `RESTART` or `REFRESH`. Defaults to `REPLACE`, which is
+	//needed for the instances to boot the new image.
+	MinimalAction string `mapstructure:"minimal_action"`
+	//If true, wait for the managed instance group to be stable, i.e. for all
+	//its instances to run the new template, and fail the build if it does not
+	//within `stable_timeout`.
+	WaitForStable bool `mapstructure:"wait_for_stable"`
+	//How long to wait for the managed instance group to be stable. Defaults
+	//to `30m`.
+	StableTimeout time.Duration `mapstructure:"stable_timeout"`
+
+	ctx interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+}
+
+func (p *PostProcessor) ConfigSpec() hcldec.ObjectSpec { return p.config.FlatMapstructure().HCL2Spec() }
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		PluginType:         BuilderId,
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	errs := new(packersdk.MultiError)
+
+	if p.config.InstanceGroupManager == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("instance_group_manager must be set"))
+	}
+
+	if (p.config.Zone == "") == (p.config.Region == "") {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("exactly one of zone or region must be set"))
+	}
+
+	for name, value := range map[string]string{
+		"max_surge":       p.config.MaxSurge,
+		"max_unavailable": p.config.MaxUnavailable,
+	} {
+		if _, err := fixedOrPercent(value); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("invalid %s: %s", name, err))
+		}
+	}
+
+	p.config.MinimalAction = strings.ToUpper(p.config.MinimalAction)
+	switch p.config.MinimalAction {
+	case "":
+		p.config.MinimalAction = "REPLACE"
+	case "REPLACE", "RESTART", "REFRESH":
+	default:
+		errs = packersdk.MultiErrorAppend(errs,
+			fmt.Errorf("minimal_action must be one of REPLACE, RESTART or REFRESH, got %q", p.config.MinimalAction))
+	}
+
+	if p.config.StableTimeout == 0 {
+		p.config.StableTimeout = 30 * time.Minute
+	}
+
+	warns, err := p.config.Authentication.Prepare()
+	if err != nil {
+		errs = packersdk.MultiErrorAppend(errs, err)
+	}
+	for _, warn := range warns {
+		log.Printf("[WARN] - %s", warn)
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifact packersdk.Artifact) (packersdk.Artifact, bool, bool, error) {
+	switch artifact.BuilderId() {
+	case googlecomputeinstancetemplate.BuilderId, googlecomputeinstancetemplatebuilder.BuilderId:
+	default:
+		err := fmt.Errorf(
+			"Unknown artifact type: %s\nCan only update managed instance groups from Google Compute Instance Template post-processor or builder artifacts.",
+			artifact.BuilderId())
+		return nil, false, false, err
+	}
+
+	generatedData := artifact.State("generated_data")
+	if generatedData == nil {
+		generatedData = make(map[string]interface{})
+	}
+
+	template, _ := artifact.State("TemplateSelfLink").(string)
+	if template == "" {
+		return nil, false, false, fmt.Errorf("The artifact has no instance template")
+	}
+
+	project := p.config.ProjectId
+	if project == "" {
+		project, _ = artifact.State("ProjectId").(string)
+	}
+	location := p.config.Zone
+	if location == "" {
+		location = p.config.Region
+	}
+
+	cfg := &common.GCEDriverConfig{
+		Ui:        ui,
+		ProjectId: project,
+		Scopes:    common.DriverScopes,
+	}
+	p.config.Authentication.ApplyDriverConfig(cfg)
+
+	driver, err := common.NewDriverGCE(*cfg)
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	name := p.config.InstanceGroupManager
+	if _, err := driver.GetInstanceGroupManager(p.config.Zone, p.config.Region, name); err != nil {
+		return nil, false, false, common.EnrichError(fmt.Errorf("Error getting managed instance group %s: %w", name, err))
+	}
+
+	patch, err := p.patch(template)
+	if err != nil {
+		return nil, false, false, err
+	}
+	if p.config.RollingUpdate {
+		ui.Say(fmt.Sprintf("Starting a rolling update of managed instance group %s to instance template %s...", name, artifact.Id()))
+	} else {
+		ui.Say(fmt.Sprintf("Setting the instance template of managed instance group %s to %s...", name, artifact.Id()))
+	}
+	if err := <-driver.PatchInstanceGroupManager(p.config.Zone, p.config.Region, name, patch); err != nil {
+		return nil, false, false, common.EnrichError(fmt.Errorf("Error updating managed instance group %s: %w", name, err))
+	}
+
+	if p.config.WaitForStable {
+		ui.Say(fmt.Sprintf("Waiting for managed instance group %s to be stable...", name))
+		select {
+		case err := <-driver.WaitForInstanceGroupManagerStable(p.config.Zone, p.config.Region, name):
+			if err != nil {
+				return nil, false, false, fmt.Errorf("Error waiting for managed instance group %s: %w", name, err)
+			}
+		case <-time.After(p.config.StableTimeout):
+			return nil, false, false, fmt.Errorf("Timed out after %s waiting for managed instance group %s to be stable", p.config.StableTimeout, name)
+		case <-ctx.Done():
+			return nil, false, false, ctx.Err()
+		}
+	}
+
+	result := &Artifact{
+		instanceGroupManager: name,
+		location:             location,
+		projectId:            project,
+		template:             artifact.Id(),
+		StateData:            map[string]interface{}{"generated_data": generatedData},
+	}
+
+	// The instance group needs the instance template.
+	return result, true, true, nil
+}
+
+// patch returns the update of the managed instance group to template.
+func (p *PostProcessor) patch(template string) (*compute.InstanceGroupManager, error) {
+	// Setting a single version also ends any canary update in progress.
+	// Without a rolling update, the update policy of the group is kept.
+	igm := &compute.InstanceGroupManager{
+		InstanceTemplate: template,
+		Versions: []*compute.InstanceGroupManagerVersion{{
+			InstanceTemplate: template,
+		}},
+	}
+	if !p.config.RollingUpdate {
+		return igm, nil
+	}
+
+	policy := &compute.InstanceGroupManagerUpdatePolicy{
+		Type:          "PROACTIVE",
+		MinimalAction: p.config.MinimalAction,
+	}
+	igm.UpdatePolicy = policy
+
+	var err error
+	if policy.MaxSurge, err = fixedOrPercent(p.config.MaxSurge); err != nil {
+		return nil, err
+	}
+	if policy.MaxUnavailable, err = fixedOrPercent(p.config.MaxUnavailable); err != nil {
+		return nil, err
+	}
+	return igm, nil
+}
+
+// fixedOrPercent parses a number of instances, e.g. 3, or a percentage of the
+// target size, e.g. 20%. It returns nil for an empty value.
+func fixedOrPercent(value string) (*compute.FixedOrPercent, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	percent := strings.HasSuffix(value, "%")
+	n, err := strconv.ParseInt(strings.TrimSuffix(value, "%"), 10, 64)
+	if err != nil || n < 0 || (percent && n > 100) {
+		return nil, fmt.Errorf("%q is neither a number of instances nor a percentage", value)
+	}
+
+	// Zero is a meaningful value, it must be sent.
+	if percent {
+		return &compute.FixedOrPercent{Percent: n, ForceSendFields: []string{"Percent"}}, nil
+	}
+	return &compute.FixedOrPercent{Fixed: n, ForceSendFields: []string{"Fixed"}}, nil
+}
@@ -0,0 +1,83 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecomputemigupdate
+
+import (
+	"testing"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"access_token":           "ya29.token",
+		"instance_group_manager": "web",
+		"zone":                   "us-central1-a",
+	}
+}
+
+func TestPostProcessor_Configure(t *testing.T) {
+	var p PostProcessor
+	if err := p.Configure(testConfig()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if p.config.MinimalAction != "REPLACE" {
+		t.Errorf("minimal_action should default to REPLACE, got %s", p.config.MinimalAction)
+	}
+
+	for name, c := range map[string]map[string]interface{}{
+		"no group":        {"instance_group_manager": ""},
+		"zone and region": {"region": "us-central1"},
+		"bad surge":       {"max_surge": "a few"},
+		"bad percent":     {"max_unavailable": "120%"},
+		"bad action":      {"minimal_action": "reboot"},
+	} {
+		var p PostProcessor
+		if err := p.Configure(testConfig(), c); err == nil {
+			t.Errorf("%s: should error", name)
+		}
+	}
+}
+
+func TestPostProcessor_patch(t *testing.T) {
+	var p PostProcessor
+	if err := p.Configure(testConfig()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	template := "projects/my-project/global/instanceTemplates/web-123"
+
+	igm, err := p.patch(template)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if igm.InstanceTemplate != template || len(igm.Versions) != 1 || igm.Versions[0].InstanceTemplate != template {
+		t.Errorf("the group should be set to the template, got %#v", igm)
+	}
+	if igm.UpdatePolicy != nil {
+		t.Errorf("the update policy of the group should be kept by default, got %#v", igm.UpdatePolicy)
+	}
+
+	p = PostProcessor{}
+	err = p.Configure(testConfig(), map[string]interface{}{
+		"rolling_update":  true,
+		"max_surge":       "20%",
+		"max_unavailable": "0",
+		"minimal_action":  "restart",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	igm, err = p.patch(template)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	policy := igm.UpdatePolicy
+	if policy.Type != "PROACTIVE" || policy.MinimalAction != "RESTART" {
+		t.Errorf("bad update policy: %#v", policy)
+	}
+	if policy.MaxSurge.Percent != 20 {
+		t.Errorf("bad max surge: %#v", policy.MaxSurge)
+	}
+	if policy.MaxUnavailable.Fixed != 0 || len(policy.MaxUnavailable.ForceSendFields) == 0 {
+		t.Errorf("a max unavailable of 0 should be sent, got %#v", policy.MaxUnavailable)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecomputepublish
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+const BuilderId = "packer.post-processor.googlecompute-publish"
+
+// Artifact represents the copies of an image published to other projects.
+type Artifact struct {
+	imageName string
+	// images are the self links of the copies, by project.
+	images map[string]string
+	driver common.ImageDriver
+	// StateData should store data such as GeneratedData
+	// to be shared with post-processors
+	StateData map[string]interface{}
+}
+
+var _ packersdk.Artifact = new(Artifact)
+
+func (*Artifact) BuilderId() string {
+	return BuilderId
+}
+
+// Id returns the name of the published image.
+func (a *Artifact) Id() string {
+	return a.imageName
+}
+
+func (*Artifact) Files() []string {
+	return nil
+}
+
+func (a *Artifact) projects() []string {
+	var projects []string
+	for project := range a.images {
+		projects = append(projects, project)
+	}
+	sort.Strings(projects)
+	return projects
+}
+
+func (a *Artifact) String() string {
+	if len(a.images) == 0 {
+		return fmt.Sprintf("The image %v was published", a.imageName)
+	}
+	return fmt.Sprintf("The image %v was copied to the projects: %v", a.imageName, strings.Join(a.projects(), ", "))
+}
+
+func (a *Artifact) State(name string) interface{} {
+	switch name {
+	case "ImageName":
+		return a.imageName
+	case "Images":
+		return a.images
+	}
+	return a.StateData[name]
+}
+
+// Destroy deletes the copies of the image.
+func (a *Artifact) Destroy() error {
+	errs := new(packersdk.MultiError)
+	for _, project := range a.projects() {
+		log.Printf("Destroying image copy: %s/%s", project, a.imageName)
+		if err := <-a.driver.DeleteImage(project, a.imageName); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("%s: %s", project, err))
+		}
+	}
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+package googlecomputepublish
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-googlecompute/builder/googlecompute"
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	sdk_common "github.com/hashicorp/packer-plugin-sdk/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+	"google.golang.org/api/compute/v1"
+)
+
+// imageUserRole is the role needed to create disks and instances from an
+// image.
+const imageUserRole = "roles/compute.imageUser"
+
+type Config struct {
+	sdk_common.PackerConfig `mapstructure:",squash"`
+	common.Authentication   `mapstructure:",squash"`
+
+	//The projects to copy the image to. The copies are independent of the
+	//built image, and are named after it.
+	TargetProjects []string `mapstructure:"target_projects"`
+	//The image family of the copies, so that they can be rolled out in the
+	//target projects. Defaults to no family.
+	ImageFamily string `mapstructure:"image_family"`
+	//The description of the copies. Defaults to
+	//`Published by Packer from ((source image))`.
+	ImageDescription string `mapstructure:"image_description"`
+	//Key/value pair labels applied to the copies. Defaults to the labels of
+	//the built image.
+	ImageLabels map[string]string `mapstructure:"image_labels"`
+	//Specifies a Cloud Storage location, either regional or multi-regional,
+	//where the content of the copies is stored.
+	ImageStorageLocations []string `mapstructure:"image_storage_locations"`
+	//The principals granted `roles/compute.imageUser` on the built image, so
+	//that they can use it without a copy, e.g. `group:devs@example.com`,
+	//`domain:example.com` or
+	//`serviceAccount:123456789@cloudservices.gserviceaccount.com` for the
+	//managed instance groups of another project.
+	ImageUsers []string `mapstructure:"image_users"`
+	//How long to wait for each copy. Defaults to `10m`.
+	StateTimeout time.Duration `mapstructure:"state_timeout"`
+
+	ctx interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+}
+
+func (p *PostProcessor) ConfigSpec() hcldec.ObjectSpec { return p.config.FlatMapstructure().HCL2Spec() }
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		PluginType:         BuilderId,
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	errs := new(packersdk.MultiError)
+
+	if len(p.config.TargetProjects) == 0 && len(p.config.ImageUsers) == 0 {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("at least one of target_projects or image_users must be set"))
+	}
+
+	if p.config.ImageFamily != "" && !common.ValidImageName.MatchString(p.config.ImageFamily) {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("Invalid image family %q: The first character must be a lowercase letter, "+
+			"and all following characters must be a dash, lowercase letter, or digit, except the last character, which cannot be a dash", p.config.ImageFamily))
+	}
+
+	if p.config.StateTimeout == 0 {
+		p.config.StateTimeout = 10 * time.Minute
+	}
+
+	warns, err := p.config.Authentication.Prepare()
+	if err != nil {
+		errs = packersdk.MultiErrorAppend(errs, err)
+	}
+	for _, warn := range warns {
+		log.Printf("[WARN] - %s", warn)
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifact packersdk.Artifact) (packersdk.Artifact, bool, bool, error) {
+	if artifact.BuilderId() != googlecompute.BuilderId {
+		err := fmt.Errorf(
+			"Unknown artifact type: %s\nCan only publish Google Compute Engine builder artifacts.",
+			artifact.BuilderId())
+		return nil, false, false, err
+	}
+
+	generatedData := artifact.State("generated_data")
+	if generatedData == nil {
+		generatedData = make(map[string]interface{})
+	}
+
+	imageProject, _ := artifact.State("ImageProjectId").(string)
+	if imageProject == "" {
+		imageProject, _ = artifact.State("ProjectId").(string)
+	}
+
+	cfg := &common.GCEDriverConfig{
+		Ui:        ui,
+		ProjectId: imageProject,
+		Scopes:    common.DriverScopes,
+	}
+	p.config.Authentication.ApplyDriverConfig(cfg)
+
+	driver, err := common.NewDriverGCE(*cfg)
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	images, err := p.publish(ui, driver, artifact, imageProject)
+	result := &Artifact{
+		imageName: artifact.Id(),
+		images:    images,
+		driver:    driver,
+		StateData: map[string]interface{}{"generated_data": generatedData},
+	}
+	// The copies that succeeded are returned along with the failures.
+	return result, true, false, err
+}
+
+// publish copies the image of artifact to the target projects, and grants
+// the image users access to it. All the targets are attempted, the error
+// lists the ones that failed. It returns the self links of the copies that
+// succeeded, by project.
+func (p *PostProcessor) publish(ui packersdk.Ui, driver common.ImageDriver, artifact packersdk.Artifact, imageProject string) (map[string]string, error) {
+	imageName := artifact.Id()
+	source := fmt.Sprintf("projects/%s/global/images/%s", imageProject, imageName)
+	errs := new(packersdk.MultiError)
+
+	description := p.config.ImageDescription
+	if description == "" {
+		description = fmt.Sprintf("Published by Packer from %s", source)
+	}
+	labels := p.config.ImageLabels
+	if labels == nil {
+		labels, _ = artifact.State("ImageLabels").(map[string]string)
+	}
+
+	// Start all the copies before waiting for them, they run concurrently.
+	type copyOp struct {
+		project string
+		imageCh <-chan *common.Image
+		errCh   <-chan error
+	}
+	var ops []copyOp
+	for _, project := range p.config.TargetProjects {
+		ui.Say(fmt.Sprintf("Copying image %s to project %s...", imageName, project))
+		imageCh, errCh := driver.CreateImage(project, &compute.Image{
+			Name:             imageName,
+			Description:      description,
+			Family:           p.config.ImageFamily,
+			Labels:           labels,
+			SourceImage:      source,
+			StorageLocations: p.config.ImageStorageLocations,
+		})
+		ops = append(ops, copyOp{project, imageCh, errCh})
+	}
+
+	images := map[string]string{}
+	for _, op := range ops {
+		var err error
+		select {
+		case err = <-op.errCh:
+		case <-time.After(p.config.StateTimeout):
+			err = fmt.Errorf("time out while waiting for the image copy")
+		}
+		if err != nil {
+			err = common.EnrichError(err)
+			ui.Error(fmt.Sprintf("Copy to project %s failed: %s", op.project, err))
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("copy to project %s: %w", op.project, err))
+			continue
+		}
+		if image := <-op.imageCh; image != nil {
+			images[op.project] = image.SelfLink
+		}
+		ui.Say(fmt.Sprintf("Copy to project %s succeeded", op.project))
+	}
+
+	if len(p.config.ImageUsers) > 0 {
+		ui.Say(fmt.Sprintf("Granting %s on image %s to %v...", imageUserRole, imageName, p.config.ImageUsers))
+		if err := driver.AddImageIamMembers(imageProject, imageName, imageUserRole, p.config.ImageUsers); err != nil {
+			err = common.EnrichError(err)
+			ui.Error(fmt.Sprintf("Granting access to the image failed: %s", err))
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("image users: %w", err))
+		} else {
+			ui.Say("Granting access to the image succeeded")
+		}
+	}
+
+	if len(errs.Errors) > 0 {
+		return images, errs
+	}
+	return images, nil
+}
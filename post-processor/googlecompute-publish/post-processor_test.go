@@ -0,0 +1,104 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecomputepublish
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/builder/googlecompute"
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func testArtifact() *packersdk.MockArtifact {
+	return &packersdk.MockArtifact{
+		BuilderIdValue: googlecompute.BuilderId,
+		IdValue:        "my-image-123",
+		StateValues: map[string]interface{}{
+			"ImageProjectId": "images-project",
+			"ImageLabels":    map[string]string{"team": "infra"},
+		},
+	}
+}
+
+func TestPostProcessor_Configure(t *testing.T) {
+	var p PostProcessor
+	if err := p.Configure(map[string]interface{}{"access_token": "ya29.token"}); err == nil {
+		t.Error("a target should be required")
+	}
+
+	p = PostProcessor{}
+	err := p.Configure(map[string]interface{}{
+		"access_token":    "ya29.token",
+		"target_projects": []string{"app-project"},
+		"image_family":    "Bad_Family",
+	})
+	if err == nil {
+		t.Error("invalid image families should be rejected")
+	}
+}
+
+func TestPostProcessor_publish(t *testing.T) {
+	var p PostProcessor
+	err := p.Configure(map[string]interface{}{
+		"access_token":    "ya29.token",
+		"target_projects": []string{"app-project"},
+		"image_family":    "web",
+		"image_users":     []string{"domain:example.com"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	driver := &common.ImageDriverMock{CreateImageReturnSelfLink: "https://compute/projects/app-project/global/images/my-image-123"}
+	ui := packersdk.TestUi(t)
+	images, err := p.publish(ui, driver, testArtifact(), "images-project")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	spec := driver.CreateImageSpec
+	if driver.CreateImageProjectId != "app-project" || spec.Name != "my-image-123" || spec.Family != "web" {
+		t.Errorf("bad copy: %s %#v", driver.CreateImageProjectId, spec)
+	}
+	if spec.SourceImage != "projects/images-project/global/images/my-image-123" {
+		t.Errorf("bad source image: %s", spec.SourceImage)
+	}
+	if spec.Labels["team"] != "infra" {
+		t.Errorf("the labels of the image should be carried through, got %v", spec.Labels)
+	}
+	if images["app-project"] != driver.CreateImageReturnSelfLink {
+		t.Errorf("bad images: %v", images)
+	}
+
+	if driver.AddImageIamMembersProject != "images-project" || driver.AddImageIamMembersRole != imageUserRole ||
+		!reflect.DeepEqual(driver.AddImageIamMembersMembers, []string{"domain:example.com"}) {
+		t.Errorf("bad grant: %s %s %v", driver.AddImageIamMembersProject, driver.AddImageIamMembersRole, driver.AddImageIamMembersMembers)
+	}
+}
+
+func TestPostProcessor_publishFailure(t *testing.T) {
+	var p PostProcessor
+	err := p.Configure(map[string]interface{}{
+		"access_token":    "ya29.token",
+		"target_projects": []string{"app-project"},
+		"image_users":     []string{"domain:example.com"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	driver := &common.ImageDriverMock{AddImageIamMembersErr: errors.New("permission denied")}
+	ui := packersdk.TestUi(t)
+	images, err := p.publish(ui, driver, testArtifact(), "images-project")
+	if err == nil || !strings.Contains(err.Error(), "image users") {
+		t.Fatalf("the failed grant should be reported, got %v", err)
+	}
+	if _, ok := images["app-project"]; !ok {
+		t.Errorf("the successful copy should still be returned, got %v", images)
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecomputevulnerabilityscan
+
+import (
+	"fmt"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+const BuilderId = "packer.post-processor.googlecompute-vulnerability-scan"
+
+// Artifact is the scanned image artifact, passed through unchanged so that
+// the next post-processors, e.g. googlecompute-export, accept it, along with
+// the results of the scan.
+type Artifact struct {
+	packersdk.Artifact
+
+	// findings are the number of vulnerabilities found, by severity.
+	findings map[string]int
+}
+
+func (a *Artifact) String() string {
+	return fmt.Sprintf("%s\nVulnerabilities found: %v", a.Artifact.String(), a.findings)
+}
+
+func (a *Artifact) State(name string) interface{} {
+	if name == "vulnerabilities" {
+		return a.findings
+	}
+	return a.Artifact.State(name)
+}
@@ -0,0 +1,242 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+package googlecomputevulnerabilityscan
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-googlecompute/builder/googlecompute"
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	sdk_common "github.com/hashicorp/packer-plugin-sdk/common"
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/multistep/commonsteps"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
+
+type Config struct {
+	sdk_common.PackerConfig `mapstructure:",squash"`
+	common.Authentication   `mapstructure:",squash"`
+
+	//The lowest severity of the vulnerabilities failing the build, one of
+	//`LOW`, `MEDIUM`, `HIGH` or `CRITICAL`. Defaults to `CRITICAL`.
+	SeverityThreshold string `mapstructure:"severity_threshold"`
+	//The number of vulnerabilities at or above `severity_threshold` that are
+	//tolerated. Defaults to `0`.
+	MaxFindings int `mapstructure:"max_findings"`
+	//The location of the On-Demand Scanning API the packages are scanned in,
+	//one of `us`, `europe` or `asia`. Defaults to `us`.
+	ScanLocation string `mapstructure:"scan_location"`
+	//How long to wait for the OS Config agent of the scanner instance to
+	//report its OS inventory. Defaults to `15m`.
+	InventoryTimeout time.Duration `mapstructure:"inventory_timeout"`
+	//The zone of the scanner instance. Defaults to the zone the image was
+	//built in.
+	Zone string `mapstructure:"zone"`
+	//The machine type of the scanner instance. Defaults to `e2-medium`.
+	MachineType string `mapstructure:"machine_type"`
+	//The network of the scanner instance. Defaults to `default`, unless
+	//`subnetwork` is set.
+	Network string `mapstructure:"network"`
+	//The project ID of the network and subnetwork of the scanner instance.
+	//Defaults to the project of the image.
+	NetworkProjectId string `mapstructure:"network_project_id"`
+	//The subnetwork of the scanner instance.
+	Subnetwork string `mapstructure:"subnetwork"`
+	//If true, the scanner instance does not get an external IP address. The
+	//subnetwork must then have Private Google Access enabled, for the OS
+	//Config agent to reach its API.
+	OmitExternalIP bool `mapstructure:"omit_external_ip"`
+	//Network tags applied to the scanner instance.
+	Tags []string `mapstructure:"tags"`
+	//The service account of the scanner instance. Defaults to the default
+	//service account of the project.
+	ServiceAccountEmail string `mapstructure:"service_account_email"`
+
+	ctx interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+	runner multistep.Runner
+}
+
+func (p *PostProcessor) ConfigSpec() hcldec.ObjectSpec { return p.config.FlatMapstructure().HCL2Spec() }
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		PluginType:         BuilderId,
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	errs := new(packersdk.MultiError)
+
+	p.config.SeverityThreshold = strings.ToUpper(p.config.SeverityThreshold)
+	switch p.config.SeverityThreshold {
+	case "":
+		p.config.SeverityThreshold = "CRITICAL"
+	case "LOW", "MEDIUM", "HIGH", "CRITICAL":
+	default:
+		errs = packersdk.MultiErrorAppend(errs,
+			fmt.Errorf("severity_threshold must be one of LOW, MEDIUM, HIGH or CRITICAL, got %q", p.config.SeverityThreshold))
+	}
+
+	if p.config.MaxFindings < 0 {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("max_findings must be positive"))
+	}
+
+	if p.config.ScanLocation == "" {
+		p.config.ScanLocation = "us"
+	}
+	if p.config.InventoryTimeout == 0 {
+		p.config.InventoryTimeout = 15 * time.Minute
+	}
+	if p.config.MachineType == "" {
+		p.config.MachineType = "e2-medium"
+	}
+	if p.config.Network == "" && p.config.Subnetwork == "" {
+		p.config.Network = "default"
+	}
+
+	warns, err := p.config.Authentication.Prepare()
+	if err != nil {
+		errs = packersdk.MultiErrorAppend(errs, err)
+	}
+	for _, warn := range warns {
+		log.Printf("[WARN] - %s", warn)
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifact packersdk.Artifact) (packersdk.Artifact, bool, bool, error) {
+	if artifact.BuilderId() != googlecompute.BuilderId {
+		err := fmt.Errorf(
+			"Unknown artifact type: %s\nCan only scan Google Compute Engine builder artifacts.",
+			artifact.BuilderId())
+		return nil, false, false, err
+	}
+
+	imageName := artifact.Id()
+	imageProject, _ := artifact.State("ImageProjectId").(string)
+	if imageProject == "" {
+		imageProject, _ = artifact.State("ProjectId").(string)
+	}
+	zone := p.config.Zone
+	if zone == "" {
+		zone, _ = artifact.State("BuildZone").(string)
+	}
+	diskSize, _ := artifact.State("ImageSizeGb").(int64)
+	region, err := common.GetRegionFromZone(zone)
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	scannerName := scannerName(imageName)
+	scannerConfig := googlecompute.Config{
+		DiskName:             scannerName,
+		DiskSizeGb:           diskSize,
+		DiskType:             "pd-balanced",
+		InstanceName:         scannerName,
+		MachineType:          p.config.MachineType,
+		Metadata:             map[string]string{"enable-osconfig": "TRUE", "enable-guest-attributes": "TRUE"},
+		Network:              p.config.Network,
+		NetworkProjectId:     imageProject,
+		OmitExternalIP:       p.config.OmitExternalIP,
+		Region:               region,
+		StateTimeout:         5 * time.Minute,
+		SourceImage:          imageName,
+		SourceImageProjectId: []string{imageProject},
+		Subnetwork:           p.config.Subnetwork,
+		Tags:                 p.config.Tags,
+		Zone:                 zone,
+		Scopes:               []string{common.CloudPlatformScope},
+		ServiceAccountEmail:  p.config.ServiceAccountEmail,
+	}
+	if p.config.NetworkProjectId != "" {
+		scannerConfig.NetworkProjectId = p.config.NetworkProjectId
+	}
+
+	cfg := &common.GCEDriverConfig{
+		Ui:        ui,
+		ProjectId: imageProject,
+		Scopes:    []string{common.CloudPlatformScope},
+	}
+	p.config.Authentication.ApplyDriverConfig(cfg)
+
+	driver, err := common.NewDriverGCE(*cfg)
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	resourceURI, _ := artifact.State("ImageSelfLink").(string)
+	if resourceURI == "" {
+		resourceURI = fmt.Sprintf("https://compute.googleapis.com/compute/v1/projects/%s/global/images/%s", imageProject, imageName)
+	}
+
+	state := new(multistep.BasicStateBag)
+	state.Put("config", &scannerConfig)
+	state.Put("driver", driver)
+	state.Put("ui", ui)
+
+	steps := []multistep.Step{
+		&communicator.StepSSHKeyGen{
+			CommConf: &scannerConfig.Comm,
+		},
+		&googlecompute.StepCreateInstance{
+			Debug: p.config.PackerDebug,
+		},
+		&stepScan{
+			config:       &p.config,
+			resourceURI:  resourceURI,
+			pollInterval: 30 * time.Second,
+		},
+		new(googlecompute.StepTeardownInstance),
+	}
+
+	ui.Say(fmt.Sprintf("Scanning image %s for vulnerabilities...", imageName))
+	p.runner = commonsteps.NewRunner(steps, p.config.PackerConfig, ui)
+	p.runner.Run(ctx, state)
+
+	if rawErr, ok := state.GetOk("error"); ok {
+		return nil, false, false, rawErr.(error)
+	}
+
+	findings, _ := state.Get("vulnerabilities").(map[string]int)
+	result := &Artifact{
+		Artifact: artifact,
+		findings: findings,
+	}
+
+	// The result is the scanned image itself.
+	return result, true, true, nil
+}
+
+// scannerName returns the name of the instance scanning imageName, and of its
+// disk, within the 63 characters allowed.
+func scannerName(imageName string) string {
+	const suffix = "-scanner"
+	if len(imageName) > 63-len(suffix) {
+		imageName = imageName[:63-len(suffix)]
+	}
+	return imageName + suffix
+}
@@ -0,0 +1,206 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecomputevulnerabilityscan
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/builder/googlecompute"
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"google.golang.org/api/ondemandscanning/v1"
+	"google.golang.org/api/osconfig/v1"
+)
+
+func TestPostProcessor_Configure(t *testing.T) {
+	var p PostProcessor
+	err := p.Configure(map[string]interface{}{
+		"access_token":       "ya29.token",
+		"severity_threshold": "high",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p.config.SeverityThreshold != "HIGH" {
+		t.Errorf("bad severity_threshold: %s", p.config.SeverityThreshold)
+	}
+	if p.config.ScanLocation != "us" || p.config.InventoryTimeout != 15*time.Minute || p.config.Network != "default" {
+		t.Errorf("bad defaults: %#v", p.config)
+	}
+
+	for name, c := range map[string]map[string]interface{}{
+		"bad threshold":    {"severity_threshold": "MINIMAL"},
+		"bad max findings": {"max_findings": -1},
+	} {
+		var p PostProcessor
+		c["access_token"] = "ya29.token"
+		if err := p.Configure(c); err == nil {
+			t.Errorf("%s: should error", name)
+		}
+	}
+}
+
+func TestPostProcessor_PostProcess_badArtifact(t *testing.T) {
+	var p PostProcessor
+	if err := p.Configure(map[string]interface{}{"access_token": "ya29.token"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	_, _, _, err := p.PostProcess(context.Background(), packersdk.TestUi(t), &packersdk.MockArtifact{BuilderIdValue: "foo"})
+	if err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestScannerName(t *testing.T) {
+	if got := scannerName("packer-image"); got != "packer-image-scanner" {
+		t.Errorf("bad scanner name: %s", got)
+	}
+	got := scannerName(strings.Repeat("a", 63))
+	if len(got) != 63 || !strings.HasSuffix(got, "-scanner") {
+		t.Errorf("the scanner name should be truncated to 63 characters: %s", got)
+	}
+}
+
+func testInventory() *osconfig.Inventory {
+	return &osconfig.Inventory{
+		OsInfo: &osconfig.InventoryOsInfo{ShortName: "debian", Version: "12"},
+		Items: map[string]osconfig.InventoryItem{
+			"installedPackage-openssl": {InstalledPackage: &osconfig.InventorySoftwarePackage{
+				AptPackage: &osconfig.InventoryVersionedPackage{PackageName: "openssl", Version: "3.0.11-1"},
+			}},
+			"installedPackage-bash": {InstalledPackage: &osconfig.InventorySoftwarePackage{
+				AptPackage: &osconfig.InventoryVersionedPackage{PackageName: "bash", Version: "5.2.15-2"},
+			}},
+			"availablePackage-bash": {AvailablePackage: &osconfig.InventorySoftwarePackage{
+				AptPackage: &osconfig.InventoryVersionedPackage{PackageName: "bash", Version: "5.2.15-3"},
+			}},
+		},
+	}
+}
+
+func testOccurrence(cve, severity, pkg string) *ondemandscanning.Occurrence {
+	return &ondemandscanning.Occurrence{
+		NoteName: "projects/goog-vulnz/notes/" + cve,
+		Vulnerability: &ondemandscanning.VulnerabilityOccurrence{
+			EffectiveSeverity: severity,
+			PackageIssue:      []*ondemandscanning.PackageIssue{{AffectedPackage: pkg}},
+		},
+	}
+}
+
+func TestPackagesFromInventory(t *testing.T) {
+	packages := packagesFromInventory(testInventory())
+	if len(packages) != 2 {
+		t.Fatalf("bad packages: %#v", packages)
+	}
+	if p := packages[0]; p.Package != "bash" || p.Version != "5.2.15-2" || p.Os != "debian" || p.OsVersion != "12" || p.PackageType != "OS" {
+		t.Errorf("bad package: %#v", p)
+	}
+	if packages[1].Package != "openssl" {
+		t.Errorf("bad package: %#v", packages[1])
+	}
+}
+
+func TestStepScan(t *testing.T) {
+	for name, tc := range map[string]struct {
+		maxFindings int
+		halt        bool
+	}{
+		"over threshold":  {maxFindings: 0, halt: true},
+		"within findings": {maxFindings: 1},
+	} {
+		d := &common.DriverMock{}
+		d.GetInstanceInventoryResult = testInventory()
+		d.ScanPackagesResult = []*ondemandscanning.Occurrence{
+			testOccurrence("CVE-2024-0001", "CRITICAL", "openssl"),
+			testOccurrence("CVE-2024-0002", "LOW", "bash"),
+			testOccurrence("CVE-2024-0003", "MEDIUM", "bash"),
+		}
+
+		state := new(multistep.BasicStateBag)
+		state.Put("config", &googlecompute.Config{Zone: "us-central1-a", InstanceName: "my-image-scanner"})
+		state.Put("driver", d)
+		state.Put("ui", packersdk.TestUi(t))
+
+		step := &stepScan{
+			config: &Config{
+				SeverityThreshold: "HIGH",
+				MaxFindings:       tc.maxFindings,
+				ScanLocation:      "europe",
+				InventoryTimeout:  time.Minute,
+			},
+			resourceURI: "https://compute.googleapis.com/compute/v1/projects/p/global/images/my-image",
+		}
+		action := step.Run(context.Background(), state)
+		if halted := action == multistep.ActionHalt; halted != tc.halt {
+			t.Errorf("%s: bad action: %v, error: %v", name, action, state.Get("error"))
+		}
+
+		if d.GetInstanceInventoryZone != "us-central1-a" || d.GetInstanceInventoryName != "my-image-scanner" {
+			t.Errorf("%s: bad instance: %s/%s", name, d.GetInstanceInventoryZone, d.GetInstanceInventoryName)
+		}
+		if d.ScanPackagesLocation != "europe" || d.ScanPackagesResourceURI != step.resourceURI || len(d.ScanPackagesPackages) != 2 {
+			t.Errorf("%s: bad scan: %s %s %d", name, d.ScanPackagesLocation, d.ScanPackagesResourceURI, len(d.ScanPackagesPackages))
+		}
+		findings := state.Get("vulnerabilities").(map[string]int)
+		if findings["CRITICAL"] != 1 || findings["LOW"] != 1 || findings["MEDIUM"] != 1 {
+			t.Errorf("%s: bad findings: %v", name, findings)
+		}
+	}
+}
+
+func TestStepScan_inventoryTimeout(t *testing.T) {
+	d := &common.DriverMock{}
+	d.GetInstanceInventoryResult = &osconfig.Inventory{}
+
+	state := new(multistep.BasicStateBag)
+	state.Put("config", &googlecompute.Config{Zone: "us-central1-a", InstanceName: "my-image-scanner"})
+	state.Put("driver", d)
+	state.Put("ui", packersdk.TestUi(t))
+
+	step := &stepScan{
+		config:       &Config{SeverityThreshold: "CRITICAL", InventoryTimeout: time.Millisecond},
+		pollInterval: time.Millisecond,
+	}
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %v", action)
+	}
+	if d.ScanPackagesLocation != "" {
+		t.Error("should not scan without inventory")
+	}
+}
+
+func TestStepScan_unsupportedPackages(t *testing.T) {
+	d := &common.DriverMock{}
+	d.GetInstanceInventoryResult = &osconfig.Inventory{
+		OsInfo: &osconfig.InventoryOsInfo{ShortName: "windows", Version: "10.0.20348"},
+		Items: map[string]osconfig.InventoryItem{
+			"installedPackage-KB5034129": {InstalledPackage: &osconfig.InventorySoftwarePackage{
+				QfePackage: &osconfig.InventoryWindowsQuickFixEngineeringPackage{HotFixId: "KB5034129"},
+			}},
+			"installedPackage-googet": {InstalledPackage: &osconfig.InventorySoftwarePackage{
+				GoogetPackage: &osconfig.InventoryVersionedPackage{PackageName: "googet", Version: "2.18.5"},
+			}},
+		},
+	}
+
+	state := new(multistep.BasicStateBag)
+	state.Put("config", &googlecompute.Config{Zone: "us-central1-a", InstanceName: "my-image-scanner"})
+	state.Put("driver", d)
+	state.Put("ui", packersdk.TestUi(t))
+
+	step := &stepScan{
+		config: &Config{SeverityThreshold: "CRITICAL", InventoryTimeout: time.Minute},
+	}
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %v", action)
+	}
+	if d.ScanPackagesLocation != "" {
+		t.Error("should not scan without supported packages")
+	}
+}
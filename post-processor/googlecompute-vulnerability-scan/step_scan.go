@@ -0,0 +1,166 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package googlecomputevulnerabilityscan
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-googlecompute/builder/googlecompute"
+	"github.com/hashicorp/packer-plugin-googlecompute/lib/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"google.golang.org/api/ondemandscanning/v1"
+	"google.golang.org/api/osconfig/v1"
+)
+
+// severities ranks the severities of the vulnerabilities.
+var severities = map[string]int{
+	"MINIMAL":  1,
+	"LOW":      2,
+	"MEDIUM":   3,
+	"HIGH":     4,
+	"CRITICAL": 5,
+}
+
+// stepScan waits for the OS inventory of the scanner instance, scans its
+// packages for vulnerabilities, and fails if too many are at or above the
+// severity threshold.
+type stepScan struct {
+	config      *Config
+	resourceURI string
+	// pollInterval is the interval between two checks of the inventory.
+	pollInterval time.Duration
+}
+
+func (s *stepScan) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c := state.Get("config").(*googlecompute.Config)
+	driver := state.Get("driver").(common.ScanningDriver)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	halt := func(err error) multistep.StepAction {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Say("Waiting for the OS inventory of the scanner instance...")
+	var inventory *osconfig.Inventory
+	deadline := time.Now().Add(s.config.InventoryTimeout)
+	for {
+		var err error
+		inventory, err = driver.GetInstanceInventory(c.Zone, c.InstanceName)
+		if err == nil && inventory != nil && len(inventory.Items) > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			return halt(fmt.Errorf("Timed out after %s waiting for the OS inventory of the scanner instance, "+
+				"the image needs the OS Config agent, and the OS Config API must be enabled: %v", s.config.InventoryTimeout, err))
+		}
+		select {
+		case <-ctx.Done():
+			return halt(ctx.Err())
+		case <-time.After(s.pollInterval):
+		}
+	}
+
+	packages := packagesFromInventory(inventory)
+	if len(packages) == 0 {
+		// Scanning no packages finds no vulnerabilities, do not let the image
+		// pass unscanned.
+		return halt(fmt.Errorf("The OS inventory of the scanner instance has no supported OS packages, " +
+			"only apt, yum and zypper packages can be scanned; cannot scan"))
+	}
+	ui.Say(fmt.Sprintf("Scanning %d packages for vulnerabilities...", len(packages)))
+	occurrences, err := driver.ScanPackages(s.config.ScanLocation, s.resourceURI, packages)
+	if err != nil {
+		return halt(common.EnrichError(fmt.Errorf("Error scanning the packages: %w", err)))
+	}
+
+	findings, blocking := evaluate(occurrences, s.config.SeverityThreshold)
+	state.Put("vulnerabilities", findings)
+	ui.Say(fmt.Sprintf("Vulnerabilities found: %v", findings))
+	for _, finding := range blocking {
+		ui.Message(finding)
+	}
+
+	if len(blocking) > s.config.MaxFindings {
+		return halt(fmt.Errorf("Found %d vulnerabilities of severity %s or above, more than the %d allowed",
+			len(blocking), s.config.SeverityThreshold, s.config.MaxFindings))
+	}
+	return multistep.ActionContinue
+}
+
+func (s *stepScan) Cleanup(state multistep.StateBag) {}
+
+// packagesFromInventory returns the OS packages installed according to
+// inventory. Only the apt, yum and zypper packages are supported, the
+// Windows items, e.g. WUA, QFE or GooGet packages, are ignored.
+func packagesFromInventory(inventory *osconfig.Inventory) []*ondemandscanning.PackageData {
+	var osName, osVersion string
+	if inventory.OsInfo != nil {
+		osName, osVersion = inventory.OsInfo.ShortName, inventory.OsInfo.Version
+	}
+
+	var packages []*ondemandscanning.PackageData
+	for _, item := range inventory.Items {
+		if item.InstalledPackage == nil {
+			continue
+		}
+		pkg := item.InstalledPackage.AptPackage
+		if pkg == nil {
+			pkg = item.InstalledPackage.YumPackage
+		}
+		if pkg == nil {
+			pkg = item.InstalledPackage.ZypperPackage
+		}
+		if pkg == nil {
+			continue
+		}
+		packages = append(packages, &ondemandscanning.PackageData{
+			Os:          osName,
+			OsVersion:   osVersion,
+			Package:     pkg.PackageName,
+			PackageType: "OS",
+			Version:     pkg.Version,
+		})
+	}
+
+	// The inventory is a map, sort for stable requests.
+	sort.Slice(packages, func(i, j int) bool { return packages[i].Package < packages[j].Package })
+	return packages
+}
+
+// evaluate counts the vulnerabilities of occurrences by severity, and
+// describes the ones at or above threshold.
+func evaluate(occurrences []*ondemandscanning.Occurrence, threshold string) (map[string]int, []string) {
+	findings := map[string]int{}
+	var blocking []string
+	for _, o := range occurrences {
+		v := o.Vulnerability
+		if v == nil {
+			continue
+		}
+		severity := v.EffectiveSeverity
+		if severity == "" || severity == "SEVERITY_UNSPECIFIED" {
+			severity = v.Severity
+		}
+		findings[severity]++
+		if severities[severity] < severities[threshold] {
+			continue
+		}
+
+		var pkgs []string
+		for _, issue := range v.PackageIssue {
+			pkgs = append(pkgs, issue.AffectedPackage)
+		}
+		blocking = append(blocking, fmt.Sprintf("%s (%s) in %s", path.Base(o.NoteName), severity, strings.Join(pkgs, ", ")))
+	}
+	sort.Strings(blocking)
+	return findings, blocking
+}